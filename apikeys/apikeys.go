@@ -0,0 +1,254 @@
+// Package apikeys issues and verifies API keys for machine clients.
+//
+// A key looks like "flk_1a2B3c4D5e6F...": a configurable prefix that makes
+// leaked keys easy to spot by secret scanners, a public key ID, a random
+// secret and a CRC32 checksum that rejects mistyped or made-up keys without a
+// store lookup. Only a SHA-256 hash of the key is stored, so a leaked store
+// does not leak usable keys; the plaintext is returned once, by Create.
+//
+// Example:
+//
+//	m := apikeys.New(apikeys.Config{Store: apikeys.NewMemoryStore(), Prefix: "acme"})
+//
+//	// issue a key (show the plaintext to the user once)
+//	plain, key, err := m.Create(ctx, apikeys.Key{Name: "CI", Owner: "org_42", Scopes: []string{"builds:write"}})
+//
+//	// authenticate requests with X-API-Key or Authorization: Bearer
+//	api := app.Group("/api", m.Middleware())
+//	api.POST("/builds", startBuild, apikeys.RequireScopes("builds:write"))
+//	api.GET("/whoami", func(c flash.Ctx) error {
+//		k, _ := apikeys.KeyFromCtx(c)
+//		return c.JSON(map[string]any{"owner": k.Owner, "scopes": k.Scopes})
+//	})
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+// Errors returned by Verify.
+var (
+	// ErrMissing means the request carries no API key.
+	ErrMissing = errors.New("apikeys: missing key")
+	// ErrMalformed means the key has the wrong prefix, length or checksum.
+	ErrMalformed = errors.New("apikeys: malformed key")
+	// ErrInvalid means the key is well-formed but unknown, revoked or wrong.
+	ErrInvalid = errors.New("apikeys: invalid key")
+	// ErrExpired means the key is past its ExpiresAt.
+	ErrExpired = errors.New("apikeys: key expired")
+	// ErrForbidden means the key lacks a scope required by RequireScopes.
+	ErrForbidden = errors.New("apikeys: insufficient scope")
+	// ErrNotFound is returned by stores for unknown key IDs.
+	ErrNotFound = errors.New("apikeys: key not found")
+)
+
+const (
+	idLen       = 12
+	secretLen   = 32
+	checksumLen = 6
+	base62      = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// Key is a stored API key. Hash is set by Manager.Create; the plaintext key
+// is never stored.
+type Key struct {
+	ID         string            // public key ID, part of the plaintext key
+	Name       string            // human label, e.g. "CI deploy key"
+	Owner      string            // user, team or organization the key acts for
+	Scopes     []string          // permissions checked by RequireScopes
+	Hash       string            // hex SHA-256 of the plaintext key
+	CreatedAt  time.Time         // set by Create
+	ExpiresAt  time.Time         // zero means the key does not expire
+	LastUsedAt time.Time         // updated by Verify, at most every Config.TouchInterval
+	Metadata   map[string]string // free-form application data
+}
+
+// HasScope reports whether k grants scope. The scope "*" grants everything.
+func (k Key) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope) || slices.Contains(k.Scopes, "*")
+}
+
+// Store persists keys. Implementations must be safe for concurrent use.
+type Store interface {
+	// Save creates or replaces the key with k.ID.
+	Save(ctx context.Context, k Key) error
+	// Get returns the key with id, or ErrNotFound.
+	Get(ctx context.Context, id string) (Key, error)
+	// Delete removes a key. Missing keys are not an error.
+	Delete(ctx context.Context, id string) error
+	// List returns the keys of owner.
+	List(ctx context.Context, owner string) ([]Key, error)
+	// Touch records that the key id was used at t.
+	Touch(ctx context.Context, id string, t time.Time) error
+}
+
+// Config configures a Manager.
+type Config struct {
+	// Store persists keys. Required.
+	Store Store
+
+	// Prefix starts every key, followed by "_". Defaults to "flk". Use
+	// distinct prefixes per environment (e.g. "acme_live", "acme_test").
+	Prefix string
+
+	// Header carries the key. Defaults to "X-API-Key". Keys are also accepted
+	// as Authorization: Bearer tokens.
+	Header string
+
+	// TouchInterval is how often LastUsedAt is written for a key in use.
+	// Defaults to one minute; negative disables last-used tracking.
+	TouchInterval time.Duration
+
+	// OnError handles requests rejected by Middleware. By default it answers
+	// 403 for ErrForbidden and 401 otherwise; store failures are returned to
+	// the app's error handler.
+	OnError func(c flash.Ctx, err error) error
+}
+
+// Manager creates, verifies and revokes API keys.
+type Manager struct {
+	cfg    Config
+	prefix string
+	now    func() time.Time
+}
+
+// New returns a Manager. It panics if cfg.Store is nil.
+func New(cfg Config) *Manager {
+	if cfg.Store == nil {
+		panic("apikeys: Config.Store is required")
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "flk"
+	}
+	if cfg.Header == "" {
+		cfg.Header = "X-API-Key"
+	}
+	if cfg.TouchInterval == 0 {
+		cfg.TouchInterval = time.Minute
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = defaultOnError
+	}
+	return &Manager{cfg: cfg, prefix: cfg.Prefix + "_", now: time.Now}
+}
+
+// Create stores a new key with the Name, Owner, Scopes, ExpiresAt and
+// Metadata of tmpl and returns its plaintext, which cannot be recovered
+// later, together with the stored Key.
+func (m *Manager) Create(ctx context.Context, tmpl Key) (string, Key, error) {
+	body := randomBase62(idLen) + randomBase62(secretLen)
+	plain := m.prefix + body + checksum(m.prefix+body)
+	k := tmpl
+	k.ID = body[:idLen]
+	k.Hash = hashKey(plain)
+	k.CreatedAt = m.now()
+	k.LastUsedAt = time.Time{}
+	if err := m.cfg.Store.Save(ctx, k); err != nil {
+		return "", Key{}, err
+	}
+	return plain, k, nil
+}
+
+// Verify checks a plaintext key and returns the stored Key. Malformed keys
+// are rejected before the store is consulted. Store failures are returned as
+// is.
+func (m *Manager) Verify(ctx context.Context, plain string) (Key, error) {
+	if plain == "" {
+		return Key{}, ErrMissing
+	}
+	id, ok := m.parse(plain)
+	if !ok {
+		return Key{}, ErrMalformed
+	}
+	k, err := m.cfg.Store.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return Key{}, ErrInvalid
+	}
+	if err != nil {
+		return Key{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashKey(plain)), []byte(k.Hash)) != 1 {
+		return Key{}, ErrInvalid
+	}
+	now := m.now()
+	if !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt) {
+		return Key{}, ErrExpired
+	}
+	if m.cfg.TouchInterval > 0 && now.Sub(k.LastUsedAt) >= m.cfg.TouchInterval {
+		if err := m.cfg.Store.Touch(ctx, id, now); err == nil {
+			k.LastUsedAt = now
+		}
+	}
+	return k, nil
+}
+
+// Revoke deletes the key with id; it stops working immediately.
+func (m *Manager) Revoke(ctx context.Context, id string) error {
+	return m.cfg.Store.Delete(ctx, id)
+}
+
+// List returns the keys of owner, e.g. for a key management page.
+func (m *Manager) List(ctx context.Context, owner string) ([]Key, error) {
+	return m.cfg.Store.List(ctx, owner)
+}
+
+// parse checks the prefix, length, alphabet and checksum of plain and
+// returns its key ID.
+func (m *Manager) parse(plain string) (string, bool) {
+	body, ok := strings.CutPrefix(plain, m.prefix)
+	if !ok || len(body) != idLen+secretLen+checksumLen {
+		return "", false
+	}
+	for i := 0; i < len(body); i++ {
+		if strings.IndexByte(base62, body[i]) < 0 {
+			return "", false
+		}
+	}
+	split := len(plain) - checksumLen
+	if subtle.ConstantTimeCompare([]byte(checksum(plain[:split])), []byte(plain[split:])) != 1 {
+		return "", false
+	}
+	return body[:idLen], true
+}
+
+// checksum returns the CRC32 of s as checksumLen base62 digits.
+func checksum(s string) string {
+	n := crc32.ChecksumIEEE([]byte(s))
+	b := make([]byte, checksumLen)
+	for i := checksumLen - 1; i >= 0; i-- {
+		b[i] = base62[n%62]
+		n /= 62
+	}
+	return string(b)
+}
+
+// randomBase62 returns n uniformly random base62 characters.
+func randomBase62(n int) string {
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(base62)))
+	for i := range b {
+		v, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			panic("apikeys: failed to generate random bytes: " + err.Error())
+		}
+		b[i] = base62[v.Int64()]
+	}
+	return string(b)
+}
+
+func hashKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,177 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+func TestCreateAndVerify(t *testing.T) {
+	store := NewMemoryStore()
+	m := New(Config{Store: store, Prefix: "acme_test"})
+	ctx := context.Background()
+	plain, k, err := m.Create(ctx, Key{Name: "CI", Owner: "org1", Scopes: []string{"builds:write"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(plain, "acme_test_"+k.ID) || len(plain) != len("acme_test_")+idLen+secretLen+checksumLen {
+		t.Fatalf("plain=%q id=%q", plain, k.ID)
+	}
+	stored, _ := store.Get(ctx, k.ID)
+	if stored.Hash == "" || strings.Contains(stored.Hash, plain) || stored.Hash != hashKey(plain) {
+		t.Fatalf("stored hash=%q", stored.Hash)
+	}
+
+	got, err := m.Verify(ctx, plain)
+	if err != nil || got.ID != k.ID || got.Owner != "org1" || !got.HasScope("builds:write") || got.HasScope("admin") {
+		t.Fatalf("verify=%+v err=%v", got, err)
+	}
+
+	// Checksum failures never reach the store.
+	last := "0"
+	if strings.HasSuffix(plain, "0") {
+		last = "1"
+	}
+	tampered := plain[:len(plain)-1] + last
+	if _, err := m.Verify(ctx, tampered); !errors.Is(err, ErrMalformed) {
+		t.Fatalf("tampered checksum err=%v", err)
+	}
+	for _, bad := range []string{"other_" + plain[len("acme_test_"):], plain + "x", "acme_test_!!"} {
+		if _, err := m.Verify(ctx, bad); !errors.Is(err, ErrMalformed) {
+			t.Fatalf("%q err=%v", bad, err)
+		}
+	}
+	// A well-formed key that was never issued.
+	body := k.ID + strings.Repeat("a", secretLen)
+	forged := "acme_test_" + body + checksum("acme_test_"+body)
+	if _, err := m.Verify(ctx, forged); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("forged err=%v", err)
+	}
+	if _, err := m.Verify(ctx, ""); !errors.Is(err, ErrMissing) {
+		t.Fatalf("empty err=%v", err)
+	}
+
+	if err := m.Revoke(ctx, k.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Verify(ctx, plain); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("revoked err=%v", err)
+	}
+}
+
+func TestExpiryAndLastUsed(t *testing.T) {
+	store := NewMemoryStore()
+	m := New(Config{Store: store})
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	ctx := context.Background()
+	plain, k, _ := m.Create(ctx, Key{Owner: "u1", ExpiresAt: now.Add(time.Hour)})
+
+	if _, err := m.Verify(ctx, plain); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := store.Get(ctx, k.ID); !s.LastUsedAt.Equal(now) {
+		t.Fatalf("LastUsedAt=%v", s.LastUsedAt)
+	}
+	// Within TouchInterval the store is not written again.
+	now = now.Add(30 * time.Second)
+	if _, err := m.Verify(ctx, plain); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := store.Get(ctx, k.ID); s.LastUsedAt.Equal(now) {
+		t.Fatalf("touched within interval")
+	}
+	now = now.Add(time.Minute)
+	if got, _ := m.Verify(ctx, plain); !got.LastUsedAt.Equal(now) {
+		t.Fatalf("LastUsedAt=%v", got.LastUsedAt)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := m.Verify(ctx, plain); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expired err=%v", err)
+	}
+
+	m.Create(ctx, Key{Owner: "u1", Name: "second"})
+	m.Create(ctx, Key{Owner: "u2"})
+	keys, _ := m.List(ctx, "u1")
+	if len(keys) != 2 {
+		t.Fatalf("list=%+v", keys)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	m := New(Config{Store: NewMemoryStore()})
+	ctx := context.Background()
+	reader, rk, _ := m.Create(ctx, Key{Owner: "o", Scopes: []string{"read"}})
+	admin, _, _ := m.Create(ctx, Key{Owner: "o", Scopes: []string{"*"}})
+
+	if cfg, ok := m.Middleware().Config().(Config); !ok || cfg.Store != nil {
+		t.Fatalf("middleware config exposes the store: %#v", cfg)
+	}
+
+	a := flash.New()
+	api := a.Group("/api", m.Middleware())
+	api.GET("/whoami", func(c flash.Ctx) error {
+		k, _ := KeyFromCtx(c)
+		return c.String(http.StatusOK, k.ID+" "+RateLimitKey(c))
+	})
+	api.DELETE("/things", func(c flash.Ctx) error { return c.String(http.StatusOK, "deleted") }, RequireScopes("things:delete"))
+	a.GET("/write", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") }, m.Middleware("write"))
+
+	do := func(method, path string, hdr ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for i := 0; i+1 < len(hdr); i += 2 {
+			req.Header.Set(hdr[i], hdr[i+1])
+		}
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodGet, "/api/whoami", "X-API-Key", reader)
+	if rec.Code != http.StatusOK || rec.Body.String() != rk.ID+" apikey:"+rk.ID {
+		t.Fatalf("code=%d body=%q", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodGet, "/api/whoami", "Authorization", "Bearer "+reader); rec.Code != http.StatusOK {
+		t.Fatalf("bearer code=%d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/whoami"); rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("missing key code=%d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/whoami", "X-API-Key", "flk_nope"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("bad key code=%d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/api/things", "X-API-Key", reader); rec.Code != http.StatusForbidden {
+		t.Fatalf("missing scope code=%d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/api/things", "X-API-Key", admin); rec.Code != http.StatusOK {
+		t.Fatalf("wildcard scope code=%d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/write", "X-API-Key", reader); rec.Code != http.StatusForbidden {
+		t.Fatalf("middleware scopes code=%d", rec.Code)
+	}
+}
+
+type failingStore struct{ *MemoryStore }
+
+func (failingStore) Get(context.Context, string) (Key, error) { return Key{}, errors.New("db down") }
+
+func TestMiddleware_StoreError(t *testing.T) {
+	m := New(Config{Store: failingStore{NewMemoryStore()}})
+	plain, _, _ := m.Create(context.Background(), Key{})
+	a := flash.New()
+	a.GET("/", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") }, m.Middleware())
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-API-Key", plain)
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("code=%d", rec.Code)
+	}
+}
@@ -0,0 +1,117 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/goflash/flash/v2"
+)
+
+type keyContextKey struct{}
+
+// Middleware returns middleware that authenticates requests by API key, read
+// from Config.Header or an Authorization: Bearer token. Requests without a
+// valid key are rejected through Config.OnError; otherwise the key is stored
+// for KeyFromCtx. When scopes are given, the key must grant all of them.
+func (m *Manager) Middleware(scopes ...string) flash.Middleware {
+	described := m.cfg
+	described.Store = nil
+	return flash.Describe("apikeys", described, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			r := c.Request()
+			k, err := m.Verify(r.Context(), m.extract(r))
+			if err == nil {
+				err = checkScopes(k, scopes)
+			}
+			if err != nil {
+				return m.cfg.OnError(c, err)
+			}
+			c.SetRequest(r.WithContext(context.WithValue(r.Context(), keyContextKey{}, k)))
+			return next(c)
+		}
+	})
+}
+
+// extract returns the key sent with r, or "".
+func (m *Manager) extract(r *http.Request) string {
+	if v := r.Header.Get(m.cfg.Header); v != "" {
+		return strings.TrimSpace(v)
+	}
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// RequireScopes returns route middleware that rejects requests whose key,
+// authenticated by an earlier Manager.Middleware, lacks any of scopes. It
+// answers 403 Forbidden, or 401 when the request was not authenticated.
+//
+// Example:
+//
+//	api := app.Group("/api", m.Middleware())
+//	api.DELETE("/projects/:id", deleteProject, apikeys.RequireScopes("projects:admin"))
+func RequireScopes(scopes ...string) flash.Middleware {
+	return flash.Describe("apikeys.require_scopes", scopes, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			k, ok := KeyFromCtx(c)
+			if !ok {
+				return defaultOnError(c, ErrMissing)
+			}
+			if err := checkScopes(k, scopes); err != nil {
+				return defaultOnError(c, err)
+			}
+			return next(c)
+		}
+	})
+}
+
+// KeyFromCtx returns the key of a request authenticated by Middleware.
+func KeyFromCtx(c flash.Ctx) (Key, bool) {
+	k, ok := c.Context().Value(keyContextKey{}).(Key)
+	return k, ok
+}
+
+// RateLimitKey returns "apikey:<ID>" for requests authenticated by
+// Middleware and "ip:<remote address>" otherwise, so rate limits apply per
+// key.
+//
+// Example:
+//
+//	api := app.Group("/api", m.Middleware(), middleware.RateLimit(
+//		middleware.WithStrategy(middleware.NewTokenBucketStrategy(100, time.Second)),
+//		middleware.WithKeyFunc(apikeys.RateLimitKey),
+//	))
+func RateLimitKey(c flash.Ctx) string {
+	if k, ok := KeyFromCtx(c); ok {
+		return "apikey:" + k.ID
+	}
+	host, _, err := net.SplitHostPort(c.Request().RemoteAddr)
+	if err != nil {
+		host = c.Request().RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func checkScopes(k Key, scopes []string) error {
+	for _, s := range scopes {
+		if !k.HasScope(s) {
+			return ErrForbidden
+		}
+	}
+	return nil
+}
+
+func defaultOnError(c flash.Ctx, err error) error {
+	if errors.Is(err, ErrForbidden) {
+		return c.String(http.StatusForbidden, http.StatusText(http.StatusForbidden))
+	}
+	if errors.Is(err, ErrMissing) || errors.Is(err, ErrMalformed) || errors.Is(err, ErrInvalid) || errors.Is(err, ErrExpired) {
+		c.Header("WWW-Authenticate", `Bearer realm="api"`)
+		return c.String(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+	}
+	return err
+}
@@ -0,0 +1,75 @@
+package apikeys
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-memory Store for development and tests. Keys are lost
+// on restart and not shared between instances.
+type MemoryStore struct {
+	mu   sync.Mutex
+	keys map[string]Key
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{keys: make(map[string]Key)}
+}
+
+// Save implements Store.
+func (s *MemoryStore) Save(_ context.Context, k Key) error {
+	k.Scopes = slices.Clone(k.Scopes)
+	s.mu.Lock()
+	s.keys[k.ID] = k
+	s.mu.Unlock()
+	return nil
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(_ context.Context, id string) (Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.keys[id]
+	if !ok {
+		return Key{}, ErrNotFound
+	}
+	return k, nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	delete(s.keys, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// List implements Store. Keys are ordered by creation time.
+func (s *MemoryStore) List(_ context.Context, owner string) ([]Key, error) {
+	s.mu.Lock()
+	var out []Key
+	for _, k := range s.keys {
+		if k.Owner == owner {
+			out = append(out, k)
+		}
+	}
+	s.mu.Unlock()
+	slices.SortFunc(out, func(a, b Key) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return out, nil
+}
+
+// Touch implements Store.
+func (s *MemoryStore) Touch(_ context.Context, id string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.keys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	k.LastUsedAt = t
+	s.keys[id] = k
+	return nil
+}
@@ -5,9 +5,9 @@ import (
 	"net/http"
 	"os"
 	"sync"
+	"sync/atomic"
 
 	"github.com/goflash/flash/v2/ctx"
-	"github.com/julienschmidt/httprouter"
 )
 
 // Handler is the function signature for goflash route handlers (and the output
@@ -78,13 +78,42 @@ type Ctx = ctx.Ctx
 // from the pool and returns it after completion. This pattern is safe for
 // concurrent use and reduces GC pressure.
 type DefaultApp struct {
-	router     *httprouter.Router // underlying router
-	middleware []Middleware       // global middleware
-	pool       sync.Pool          // context pooling for allocation reduction
-	OnError    ErrorHandler       // error handler
-	NotFound   http.Handler       // handler for 404 Not Found
-	MethodNA   http.Handler       // handler for 405 Method Not Allowed
-	logger     *slog.Logger       // application logger
+	router          Router                      // underlying router
+	middleware      []Middleware                // global middleware
+	mwPhases        []Phase                     // phase of each global middleware (parallel to middleware)
+	prefixes        []prefixMiddleware          // middleware by route prefix (see UsePrefix)
+	registered      []Route                     // method and pattern of every composed or mounted route
+	pool            sync.Pool                   // context pooling for allocation reduction
+	OnError         ErrorHandler                // error handler
+	OnErrorV2       ErrorHandlerV2              // error handler with response state; takes precedence over OnError
+	NotFound        http.Handler                // handler for 404 Not Found
+	MethodNA        http.Handler                // handler for 405 Method Not Allowed
+	logger          *slog.Logger                // application logger
+	bindOpts        *ctx.BindJSONOptions        // default binding options (nil = built-in strict defaults)
+	tempLimits      *ctx.TempLimits             // per-request temp file limits (nil = unlimited)
+	draining        atomic.Bool                 // set by BeginDrain; fails readiness
+	drainClose      bool                        // send "Connection: close" while draining
+	noJSONEscape    bool                        // disable HTML escaping in c.JSON by default
+	flashStore      ctx.FlashStore              // default flash message store (nil = none)
+	routes          []*Route                    // registered routes, for Routes and DocsHandler
+	modules         map[string]bool             // names of modules registered via RegisterModules
+	hooksMu         sync.Mutex                  // guards healthChecks and shutdownHooks
+	healthChecks    []namedCheck                // readiness checks (see AddHealthCheck)
+	shutdownHooks   []ShutdownHook              // run by Shutdown in reverse order
+	responseHooks   sync.WaitGroup              // running OnCommit/AfterResponse hooks
+	config          *Config                     // configuration from NewFromConfig (nil otherwise)
+	metrics         *Metrics                    // framework metrics (nil = disabled, see WithMetrics)
+	profilingLabels bool                        // run handlers under pprof labels (see WithProfilingLabels)
+	mountMiddleware bool                        // run global middleware around Mount/HandleHTTP (see WithMountMiddleware)
+	assets          *Assets                     // fingerprinted static assets for Ctx.AssetPath (see Assets)
+	errorPages      *errorTemplates             // HTML error pages (see SetErrorTemplates)
+	devMode         bool                        // development mode (see WithDevMode)
+	kubernetes      *KubernetesConfig           // lifecycle settings (nil = off, see KubernetesPreset)
+	timing          *TimingConfig               // per-layer latency breakdown (nil = off, see WithTimingBreakdown)
+	trace           *TraceConfig                // request trace timeline reporting (nil = off, see WithRequestTrace)
+	sanitizers      map[string]ctx.Sanitizer    // policies for Ctx.ParamSanitized (see RegisterSanitizer)
+	eventsMu        sync.Mutex                  // serializes OnEvent subscription changes
+	events          atomic.Pointer[[]*eventSub] // event subscribers (nil = none, see OnEvent)
 }
 
 // New creates a new DefaultApp with sensible defaults and returns it as the App
@@ -96,6 +125,8 @@ type DefaultApp struct {
 //   - MethodNotAllowed handling enabled on the router
 //   - Context pooling for performance
 //
+// Options (e.g., WithRouter) are applied after the defaults.
+//
 // Example:
 //
 //	func main() {
@@ -105,26 +136,46 @@ type DefaultApp struct {
 //		})
 //		_ = http.ListenAndServe(":8080", a)
 //	}
-func New() App {
+func New(opts ...Option) App {
 	app := &DefaultApp{
-		router: httprouter.New(),
+		router: NewHTTPRouter(),
 	}
 	// Use sync.Pool to minimize allocations for context objects (hot path optimization)
 	app.pool.New = func() any { return &ctx.DefaultContext{} }
 
 	// Set up default handlers and logger
-	app.router.HandleMethodNotAllowed = true
 	app.SetErrorHandler(defaultErrorHandler)
 	app.SetNotFoundHandler(http.NotFoundHandler())
 	app.SetMethodNotAllowedHandler(methodNotAllowedHandler())
 	app.SetLogger(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo})))
 
-	app.router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		app.NotFoundHandler().ServeHTTP(w, r)
-	})
-	app.router.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		app.MethodNotAllowedHandler().ServeHTTP(w, r)
-	})
+	for _, opt := range opts {
+		if opt != nil {
+			opt(app)
+		}
+	}
+
+	if m := app.metrics; m != nil {
+		app.router = &meteredRouter{Router: app.router, m: m}
+		app.pool.New = func() any {
+			m.poolMisses.Add(1)
+			return &ctx.DefaultContext{}
+		}
+	}
+
+	if app.devMode {
+		app.enableDevMode()
+	}
+	if app.kubernetes != nil {
+		app.enableKubernetes()
+	}
+
+	app.router.SetNotFound(http.HandlerFunc(app.notFound))
+	app.router.SetMethodNotAllowed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.renderErrorPage(w, r, http.StatusMethodNotAllowed) {
+			app.MethodNotAllowedHandler().ServeHTTP(w, r)
+		}
+	}))
 
 	return app
 }
@@ -147,20 +198,67 @@ func (a *DefaultApp) Logger() *slog.Logger {
 	return slog.Default()
 }
 
+// SetBindDefaults sets the binding options applied when handlers call BindJSON,
+// BindAny and the other Bind* helpers without explicit options. Groups may
+// override these via Group.SetBindDefaults; options passed at the call site
+// always win.
+//
+// Example:
+//
+//	a.SetBindDefaults(ctx.BindJSONOptions{WeaklyTypedInput: true, ErrorUnused: true})
+//	a.GET("/search", func(c app.Ctx) error {
+//		var q Query
+//		return c.BindQuery(&q) // "page=2" coerced into int without passing options
+//	})
+func (a *DefaultApp) SetBindDefaults(o ctx.BindJSONOptions) { a.bindOpts = &o }
+
+// BindDefaults returns the app-wide binding options and whether they were set.
+func (a *DefaultApp) BindDefaults() (ctx.BindJSONOptions, bool) {
+	if a.bindOpts == nil {
+		return ctx.BindJSONOptions{ErrorUnused: true}, false
+	}
+	return *a.bindOpts, true
+}
+
+// RegisterSanitizer registers fn as the sanitizer policy name for
+// Ctx.ParamSanitized and Ctx.QuerySanitized, replacing a built-in policy of
+// the same name (see ctx.SanitizeUUID); a nil fn removes the registration.
+// Register policies before serving requests.
+//
+// Example:
+//
+//	a.RegisterSanitizer("sku", func(s string) string {
+//		s = strings.ToUpper(s)
+//		if !skuPattern.MatchString(s) {
+//			return "" // rejected
+//		}
+//		return s
+//	})
+//	a.GET("/products/:sku", func(c app.Ctx) error {
+//		sku := c.ParamSanitized("sku", "sku")
+//		// ...
+//	})
+func (a *DefaultApp) RegisterSanitizer(name string, fn ctx.Sanitizer) {
+	if fn == nil {
+		delete(a.sanitizers, name)
+		return
+	}
+	if a.sanitizers == nil {
+		a.sanitizers = make(map[string]ctx.Sanitizer)
+	}
+	a.sanitizers[name] = fn
+}
+
 // Use registers global middleware, applied to all routes in the order added.
 // Route-specific middleware passed at registration time is applied after global
-// middleware.
+// middleware. Use registers in PhaseBusiness, so middleware added with
+// UseOrdered in an earlier phase always runs first.
 //
 // Example:
 //
 //	a.Use(Log, Recover)
 //	a.GET("/", Home, Auth) // execution order: Log -> Recover -> Auth -> Home
-func (a *DefaultApp) Use(mw ...Middleware) {
-	if len(mw) == 0 {
-		return
-	}
-	a.middleware = append(a.middleware, mw...)
-}
+func (a *DefaultApp) Use(mw ...Middleware) { a.UseOrdered(PhaseBusiness, mw...) }
 
 // ServeHTTP implements http.Handler by delegating to the internal router.
 // Typically you pass the App itself to http.ListenAndServe.
@@ -169,13 +267,21 @@ func (a *DefaultApp) Use(mw ...Middleware) {
 //
 //	_ = http.ListenAndServe(":8080", a)
 func (a *DefaultApp) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if a.timing != nil {
+		r = startTiming(r)
+	}
+	if a.trace != nil || a.devMode {
+		r = a.startTrace(r)
+	}
 	a.router.ServeHTTP(w, r)
 }
 
 // Configuration setters.
 // These set the error, not found, and method-not-allowed handlers used by the app.
-func (a *DefaultApp) SetErrorHandler(h ErrorHandler)    { a.OnError = h }
-func (a *DefaultApp) SetNotFoundHandler(h http.Handler) { a.NotFound = h }
+// SetErrorHandlerV2 takes precedence over SetErrorHandler; pass nil to fall back.
+func (a *DefaultApp) SetErrorHandler(h ErrorHandler)     { a.OnError = h }
+func (a *DefaultApp) SetErrorHandlerV2(h ErrorHandlerV2) { a.OnErrorV2 = h }
+func (a *DefaultApp) SetNotFoundHandler(h http.Handler)  { a.NotFound = h }
 func (a *DefaultApp) SetMethodNotAllowedHandler(h http.Handler) {
 	a.MethodNA = h
 }
@@ -183,5 +289,6 @@ func (a *DefaultApp) SetMethodNotAllowedHandler(h http.Handler) {
 // Getters mirror the setters and are useful when holding App as an interface.
 // They expose the currently configured handlers without exporting struct fields.
 func (a *DefaultApp) ErrorHandler() ErrorHandler            { return a.OnError }
+func (a *DefaultApp) ErrorHandlerV2() ErrorHandlerV2        { return a.OnErrorV2 }
 func (a *DefaultApp) NotFoundHandler() http.Handler         { return a.NotFound }
 func (a *DefaultApp) MethodNotAllowedHandler() http.Handler { return a.MethodNA }
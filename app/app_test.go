@@ -1,13 +1,18 @@
 package app
 
 import (
+	"errors"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+
+	"github.com/goflash/flash/v2/ctx"
 )
 
 func TestUseNoArgsNoop(t *testing.T) {
@@ -196,3 +201,165 @@ func TestUseNoopOnEmpty(t *testing.T) {
 		t.Fatalf("Use() with no args should be no-op")
 	}
 }
+
+func TestSetBindDefaultsAndGetter(t *testing.T) {
+	a := New()
+	if o, ok := a.BindDefaults(); ok || !o.ErrorUnused {
+		t.Fatalf("expected built-in strict defaults, got %+v ok=%v", o, ok)
+	}
+	a.SetBindDefaults(ctx.BindJSONOptions{WeaklyTypedInput: true})
+	o, ok := a.BindDefaults()
+	if !ok || !o.WeaklyTypedInput || o.ErrorUnused {
+		t.Fatalf("unexpected defaults %+v ok=%v", o, ok)
+	}
+	a.POST("/", func(c Ctx) error {
+		var in struct {
+			Age int `json:"age"`
+		}
+		if err := c.BindJSON(&in); err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, strconv.Itoa(in.Age))
+	})
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"age":"7","extra":1}`)))
+	if rec.Code != http.StatusOK || rec.Body.String() != "7" {
+		t.Fatalf("code=%d body=%q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestWithTempLimits_AppliedPerRequestAndCleanedUp(t *testing.T) {
+	a := New(WithTempLimits(ctx.TempLimits{Dir: t.TempDir(), MaxFiles: 1}))
+	var name string
+	a.GET("/t", func(c Ctx) error {
+		f, err := c.TempFile("req-*")
+		if err != nil {
+			return err
+		}
+		name = f.Name()
+		if _, err := c.TempDir(); !errors.Is(err, ctx.ErrTooManyTempFiles) {
+			t.Errorf("expected limit error, got %v", err)
+		}
+		return io.ErrUnexpectedEOF
+	})
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/t", nil))
+	if name == "" {
+		t.Fatalf("handler did not create a temp file")
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Fatalf("temp file not removed after error: %v", err)
+	}
+}
+
+func TestWithFlashStore_CookieRoundTrip(t *testing.T) {
+	a := New(WithFlashStore(ctx.NewCookieFlashStore([]byte("secret"))), WithFlashStore(nil))
+	a.POST("/save", func(c Ctx) error { return c.Flash("success", "Saved!") })
+	a.GET("/", func(c Ctx) error {
+		fs := c.Flashes()
+		if len(fs) != 1 {
+			return c.String(http.StatusOK, "none")
+		}
+		return c.String(http.StatusOK, fs[0].Kind+":"+fs[0].Message)
+	})
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/save", nil))
+	if rec.Code != http.StatusOK || len(rec.Result().Cookies()) != 1 {
+		t.Fatalf("code=%d cookies=%v", rec.Code, rec.Result().Cookies())
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(rec.Result().Cookies()[0])
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	if rec.Body.String() != "success:Saved!" {
+		t.Fatalf("body=%q", rec.Body.String())
+	}
+}
+
+func TestNewOptions_ConfigureAllKnobs(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	notFound := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusGone) })
+	methodNA := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusConflict) })
+	var gotErr error
+	a := New(
+		WithLogger(logger),
+		WithErrorHandler(func(c Ctx, err error) { gotErr = err; _ = c.String(http.StatusTeapot, "handled") }),
+		WithNotFoundHandler(notFound),
+		WithMethodNotAllowedHandler(methodNA),
+		WithBindDefaults(ctx.BindJSONOptions{MaxBodyBytes: 8}),
+		WithJSONEscapeHTML(false),
+		// nil values keep the defaults instead of panicking later
+		WithLogger(nil), WithErrorHandler(nil), WithNotFoundHandler(nil), WithMethodNotAllowedHandler(nil),
+	)
+	if a.Logger() != logger {
+		t.Fatalf("logger option not applied")
+	}
+	if o, ok := a.BindDefaults(); !ok || o.MaxBodyBytes != 8 {
+		t.Fatalf("bind defaults option not applied: %+v %v", o, ok)
+	}
+	a.GET("/html", func(c Ctx) error { return c.JSON(map[string]string{"h": "<b>"}) })
+	a.GET("/fail", func(c Ctx) error { return io.ErrUnexpectedEOF })
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/html", nil))
+	if !strings.Contains(rec.Body.String(), "<b>") {
+		t.Fatalf("expected unescaped HTML, got %q", rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	if rec.Code != http.StatusTeapot || gotErr != io.ErrUnexpectedEOF {
+		t.Fatalf("error handler option not applied: %d %v", rec.Code, gotErr)
+	}
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if rec.Code != http.StatusGone {
+		t.Fatalf("not found option not applied: %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/html", nil))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("method not allowed option not applied: %d", rec.Code)
+	}
+}
+
+func TestNewOptions_ErrorHandlerV2AndEscapeDefault(t *testing.T) {
+	var info ErrorInfo
+	a := New(WithErrorHandlerV2(func(c Ctx, i ErrorInfo) { info = i }))
+	a.GET("/e", func(c Ctx) error { return io.EOF })
+	a.GET("/html", func(c Ctx) error { return c.JSON("<b>") })
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/e", nil))
+	if info.Err != io.EOF || info.Route != "/e" {
+		t.Fatalf("v2 option not applied: %+v", info)
+	}
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/html", nil))
+	if strings.Contains(rec.Body.String(), "<b>") {
+		t.Fatalf("HTML should be escaped by default, got %q", rec.Body.String())
+	}
+}
+
+func TestRegisterSanitizer(t *testing.T) {
+	a := New()
+	a.RegisterSanitizer("sku", strings.ToUpper)
+	a.RegisterSanitizer("gone", strings.ToLower)
+	a.RegisterSanitizer("gone", nil)
+	var sku, slug string
+	var panicked bool
+	a.GET("/p/:sku", func(c Ctx) error {
+		sku = c.ParamSanitized("sku", "sku")
+		slug = c.QuerySanitized("title", ctx.SanitizeSlug)
+		func() {
+			defer func() { panicked = recover() != nil }()
+			c.ParamSanitized("sku", "gone")
+		}()
+		return c.String(http.StatusOK, "ok")
+	})
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/p/ab-12?title=Red+Shoes", nil))
+	if sku != "AB-12" || slug != "red-shoes" {
+		t.Fatalf("sku=%q slug=%q", sku, slug)
+	}
+	if !panicked {
+		t.Fatalf("removed policy still registered")
+	}
+}
@@ -0,0 +1,210 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AssetConfig configures DefaultApp.Assets.
+type AssetConfig struct {
+	// FS holds the asset files, e.g. os.DirFS("./public") or a sub-tree of an
+	// embed.FS. Required.
+	FS fs.FS
+
+	// Manifest is the path within FS of a bundler manifest mapping logical
+	// names to fingerprinted files, e.g. "manifest.json" (webpack, esbuild
+	// plugins: {"app.js": "app.3f9a1c.js"}) or ".vite/manifest.json" (Vite:
+	// {"src/main.ts": {"file": "assets/main-4f2b.js"}}). File paths are
+	// relative to FS; a leading slash or the URL prefix is stripped. When
+	// empty, files are fingerprinted by content hash at startup instead.
+	Manifest string
+
+	// MaxAge is the max-age of fingerprinted assets. Defaults to one year.
+	MaxAge time.Duration
+}
+
+// Assets serves fingerprinted static files and resolves logical asset names
+// to their URL paths. Create it with DefaultApp.Assets.
+type Assets struct {
+	prefix string
+	paths  map[string]string // logical name -> fingerprinted file
+	files  map[string]string // fingerprinted file -> file in FS
+	cache  string            // Cache-Control of fingerprinted files
+	next   http.Handler
+}
+
+// Assets serves the files of cfg.FS under prefix for GET and HEAD requests,
+// like StaticFS, and fingerprints them for cache busting: each file is
+// reachable under a name containing a hash of its content ("app.js" becomes
+// "app.3f9a1c2b7d.js"), or under the name a bundler manifest assigns it.
+// Fingerprinted files are served with "Cache-Control: public, max-age=...,
+// immutable", since a new version always gets a new name; other files are
+// served without caching headers. Precompressed ".br" and ".gz" siblings are
+// served as with StaticFS.
+//
+// Ctx.AssetPath and the "asset" template function of Funcs return the
+// fingerprinted URL path of a logical name. Assets reads the manifest or
+// hashes the files once; restart the app (or call Assets again on a new app)
+// to pick up rebuilt assets.
+//
+// Example:
+//
+//	assets, err := a.Assets("/assets", flash.AssetConfig{FS: os.DirFS("./public")})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	tmpl := template.Must(template.New("page").Funcs(assets.Funcs()).ParseFS(views, "*.html"))
+//	// <script src="{{asset "app.js"}}"></script>
+//	a.GET("/", func(c flash.Ctx) error {
+//		c.Header("Link", "<"+c.AssetPath("app.css")+">; rel=preload; as=style")
+//		return tmpl.Execute(c.ResponseWriter(), nil)
+//	})
+func (a *DefaultApp) Assets(prefix string, cfg AssetConfig) (*Assets, error) {
+	if cfg.FS == nil {
+		return nil, errors.New("flash: Assets requires an FS")
+	}
+	prefix = cleanPath(prefix)
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	maxAge := cfg.MaxAge
+	if maxAge <= 0 {
+		maxAge = 365 * 24 * time.Hour
+	}
+	s := &Assets{
+		prefix: prefix,
+		cache:  "public, max-age=" + strconv.FormatInt(int64(maxAge/time.Second), 10) + ", immutable",
+	}
+	var err error
+	if cfg.Manifest != "" {
+		err = s.loadManifest(cfg.FS, cfg.Manifest)
+	} else {
+		err = s.hashFiles(cfg.FS)
+	}
+	if err != nil {
+		return nil, err
+	}
+	fsys := http.FS(cfg.FS)
+	s.next = staticHandler{fs: fsys, next: http.FileServer(fsys)}
+
+	h := httpHandle(http.StripPrefix(prefix, s))
+	a.router.Handle(http.MethodGet, prefix+"*filepath", h)
+	a.router.Handle(http.MethodHead, prefix+"*filepath", h)
+	a.assets = s
+	return s, nil
+}
+
+// loadManifest reads a flat or Vite-style bundler manifest.
+func (s *Assets) loadManifest(fsys fs.FS, name string) error {
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return fmt.Errorf("flash: assets manifest: %w", err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return fmt.Errorf("flash: assets manifest %s: %w", name, err)
+	}
+	s.paths = make(map[string]string, len(raw))
+	s.files = make(map[string]string, len(raw))
+	for key, v := range raw {
+		var file string
+		if json.Unmarshal(v, &file) != nil {
+			var entry struct {
+				File string `json:"file"`
+			}
+			if json.Unmarshal(v, &entry) != nil || entry.File == "" {
+				continue // not an asset entry, e.g. webpack's "entrypoints"
+			}
+			file = entry.File
+		}
+		file = strings.TrimPrefix(strings.TrimPrefix(file, s.prefix), "/")
+		s.paths[strings.TrimPrefix(key, "/")] = file
+		s.files[file] = file
+	}
+	return nil
+}
+
+// hashFiles fingerprints every file in fsys with a hash of its content.
+// Precompressed siblings are skipped; they are found through the file they
+// belong to.
+func (s *Assets) hashFiles(fsys fs.FS) error {
+	s.paths = map[string]string{}
+	s.files = map[string]string{}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		for _, pc := range precompressedEncodings {
+			if base, ok := strings.CutSuffix(name, pc.ext); ok {
+				if _, err := fs.Stat(fsys, base); err == nil {
+					return nil
+				}
+			}
+		}
+		f, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		ext := path.Ext(name)
+		hashed := strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(h.Sum(nil))[:10] + ext
+		s.paths[name] = hashed
+		s.files[hashed] = name
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("flash: assets: %w", err)
+	}
+	return nil
+}
+
+// AssetPath returns the URL path of the fingerprinted file for name, e.g.
+// "/assets/app.3f9a1c2b7d.js" for "app.js". Unknown names resolve to their
+// plain, uncached path under the prefix.
+func (s *Assets) AssetPath(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if p, ok := s.paths[name]; ok {
+		return s.prefix + p
+	}
+	return s.prefix + name
+}
+
+// Funcs returns html/template functions resolving asset paths:
+//
+//	asset "app.js" -> "/assets/app.3f9a1c2b7d.js"
+func (s *Assets) Funcs() template.FuncMap {
+	return template.FuncMap{"asset": s.AssetPath}
+}
+
+// ServeHTTP serves the asset at r.URL.Path, relative to the prefix, adding
+// immutable caching for fingerprinted files.
+func (s *Assets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if file, ok := s.files[name]; ok {
+		w.Header().Set("Cache-Control", s.cache)
+		if file != name {
+			r2 := new(http.Request)
+			*r2 = *r
+			u := *r.URL
+			u.Path, u.RawPath = "/"+file, ""
+			r2.URL = &u
+			r = r2
+		}
+	}
+	s.next.ServeHTTP(w, r)
+}
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+func TestAssets_HashedPathsAndCaching(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":         {Data: []byte("console.log(1)")},
+		"app.js.gz":      {Data: []byte("gz")},
+		"css/site.css":   {Data: []byte("body{}")},
+		"img/logo.v.png": {Data: []byte("png")},
+	}
+	a := New()
+	assets, err := a.Assets("/assets", AssetConfig{FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	js := assets.AssetPath("app.js")
+	if !strings.HasPrefix(js, "/assets/app.") || !strings.HasSuffix(js, ".js") || len(js) != len("/assets/app..js")+10 {
+		t.Fatalf("fingerprinted path %q", js)
+	}
+	if p := assets.AssetPath("/css/site.css"); !strings.HasPrefix(p, "/assets/css/site.") {
+		t.Fatalf("nested path %q", p)
+	}
+	if p := assets.AssetPath("app.js.gz"); p != "/assets/app.js.gz" {
+		t.Fatalf("precompressed siblings must not be fingerprinted: %q", p)
+	}
+	if p := assets.AssetPath("missing.js"); p != "/assets/missing.js" {
+		t.Fatalf("unknown name %q", p)
+	}
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, js, nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "console.log(1)" {
+		t.Fatalf("fingerprinted GET: %d %q", rec.Code, rec.Body.String())
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "public, max-age=31536000, immutable" {
+		t.Fatalf("Cache-Control %q", cc)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, js, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Body.String() != "gz" {
+		t.Fatalf("precompressed sibling not served: %v %q", rec.Header(), rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/assets/app.js", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "" {
+		t.Fatalf("plain GET: %d %q", rec.Code, rec.Header().Get("Cache-Control"))
+	}
+}
+
+func TestAssets_ContentChangesFingerprint(t *testing.T) {
+	p1, _ := New().Assets("/a", AssetConfig{FS: fstest.MapFS{"x.js": {Data: []byte("1")}}})
+	p2, _ := New().Assets("/a", AssetConfig{FS: fstest.MapFS{"x.js": {Data: []byte("2")}}})
+	if p1.AssetPath("x.js") == p2.AssetPath("x.js") {
+		t.Fatalf("fingerprint must depend on content")
+	}
+}
+
+func TestAssets_Manifest(t *testing.T) {
+	fsys := fstest.MapFS{
+		"manifest.json":       {Data: []byte(`{"app.js": "/static/app.3f9a.js", "vendor.js": "vendor.77.js", "entrypoints": ["app"]}`)},
+		".vite/manifest.json": {Data: []byte(`{"src/main.ts": {"file": "assets/main-4f2b.js", "isEntry": true}}`)},
+		"app.3f9a.js":         {Data: []byte("app")},
+		"assets/main-4f2b.js": {Data: []byte("main")},
+	}
+	a := New()
+	assets, err := a.Assets("/static/", AssetConfig{FS: fsys, Manifest: "manifest.json", MaxAge: 3600e9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := assets.AssetPath("app.js"); p != "/static/app.3f9a.js" {
+		t.Fatalf("flat manifest path %q", p)
+	}
+	if p := assets.AssetPath("vendor.js"); p != "/static/vendor.77.js" {
+		t.Fatalf("relative manifest path %q", p)
+	}
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/app.3f9a.js", nil))
+	if rec.Body.String() != "app" || rec.Header().Get("Cache-Control") != "public, max-age=3600, immutable" {
+		t.Fatalf("manifest asset: %q %v", rec.Body.String(), rec.Header())
+	}
+
+	vite, err := New().Assets("/", AssetConfig{FS: fsys, Manifest: ".vite/manifest.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := vite.AssetPath("src/main.ts"); p != "/assets/main-4f2b.js" {
+		t.Fatalf("vite manifest path %q", p)
+	}
+}
+
+func TestAssets_Errors(t *testing.T) {
+	if _, err := New().Assets("/a", AssetConfig{}); err == nil {
+		t.Fatalf("expected error without FS")
+	}
+	if _, err := New().Assets("/a", AssetConfig{FS: fstest.MapFS{}, Manifest: "manifest.json"}); err == nil {
+		t.Fatalf("expected error for missing manifest")
+	}
+	bad := fstest.MapFS{"manifest.json": {Data: []byte("{")}}
+	if _, err := New().Assets("/a", AssetConfig{FS: bad, Manifest: "manifest.json"}); err == nil {
+		t.Fatalf("expected error for invalid manifest")
+	}
+}
+
+func TestAssets_CtxAndTemplate(t *testing.T) {
+	a := New()
+	assets, err := a.Assets("/assets", AssetConfig{FS: fstest.MapFS{"app.js": {Data: []byte("x")}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.GET("/", func(c ctx.Ctx) error { return c.String(http.StatusOK, c.AssetPath("app.js")) })
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Body.String() != assets.AssetPath("app.js") {
+		t.Fatalf("c.AssetPath = %q, want %q", rec.Body.String(), assets.AssetPath("app.js"))
+	}
+
+	var buf bytes.Buffer
+	tmpl := template.Must(template.New("t").Funcs(assets.Funcs()).Parse(`<script src="{{asset "app.js"}}"></script>`))
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := `<script src="` + assets.AssetPath("app.js") + `"></script>`; buf.String() != want {
+		t.Fatalf("template output %q, want %q", buf.String(), want)
+	}
+}
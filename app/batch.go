@@ -0,0 +1,263 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+// BatchConfig tunes BatchHandler.
+type BatchConfig struct {
+	// MaxItems is the largest accepted batch. Default 20.
+	MaxItems int
+	// Concurrency is how many items of one batch run at a time. Default 4.
+	Concurrency int
+	// Headers are copied from the batch request to every item that does not
+	// set them itself, so each item is authenticated like the batch request.
+	// Default Authorization, Cookie, Accept, Accept-Language and
+	// X-Request-ID.
+	Headers []string
+	// ProxyHeaders adds headers set by trusted reverse proxies, such as the
+	// header of a customized ClientCert middleware. Items always get the
+	// batch request's values of proxy headers, and an item setting one is
+	// rejected with 400, so a client cannot forge its IP or client
+	// certificate per item. Forwarded, X-Real-IP and X-Forwarded-* (which
+	// includes X-Forwarded-Client-Cert) are always proxy headers.
+	ProxyHeaders []string
+	// MaxResponseSize caps each item's response body; longer bodies are
+	// replaced by a 502 result. Default 1MB.
+	MaxResponseSize int
+}
+
+// BatchItem is one sub-request of a batch.
+type BatchItem struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"` // path and optional query, e.g. "/users/1?fields=name"
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"` // sent as JSON unless Headers sets Content-Type; a JSON string with another Content-Type is sent as its text
+}
+
+// BatchResult is the response to one BatchItem.
+type BatchResult struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"` // JSON responses inline, others as a JSON string
+}
+
+// batchKey marks the context of batch items, so batches cannot nest.
+type batchKey struct{}
+
+// BatchHandler returns a handler that runs a JSON array of BatchItem through
+// a's router in-process and answers with the array of BatchResult in the same
+// order. Every item passes through the full middleware chain of its route,
+// so authentication, rate limits and validation apply per item; the batch
+// request's credentials are forwarded (see BatchConfig.Headers). Items share
+// the batch request's context, so canceling it stops them. Batches nested in
+// a batch are rejected.
+//
+// Example:
+//
+//	a.POST("/batch", app.BatchHandler(a, app.BatchConfig{MaxItems: 50}))
+//
+//	// POST /batch
+//	// [{"method":"GET","path":"/users/1"},
+//	//  {"method":"POST","path":"/orders","body":{"sku":"A1"}}]
+//	// => [{"status":200,"headers":{...},"body":{"id":1}},
+//	//     {"status":201,"headers":{...},"body":{"id":9}}]
+func BatchHandler(a App, cfg ...BatchConfig) Handler {
+	var c BatchConfig
+	if len(cfg) > 0 {
+		c = cfg[0]
+	}
+	if c.MaxItems <= 0 {
+		c.MaxItems = 20
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = 4
+	}
+	if c.Headers == nil {
+		c.Headers = []string{"Authorization", "Cookie", "Accept", "Accept-Language", "X-Request-ID"}
+	}
+	if c.MaxResponseSize <= 0 {
+		c.MaxResponseSize = 1 << 20
+	}
+	return func(cx Ctx) error {
+		parent := cx.Request()
+		if parent.Context().Value(batchKey{}) != nil {
+			return ctx.NewError(http.StatusBadRequest, "batch: nested batch requests are not allowed")
+		}
+		var items []BatchItem
+		if err := cx.BindJSON(&items); err != nil {
+			return ctx.NewError(http.StatusBadRequest, "batch: "+err.Error())
+		}
+		if len(items) == 0 {
+			return ctx.NewError(http.StatusBadRequest, "batch: no items")
+		}
+		if len(items) > c.MaxItems {
+			return ctx.NewError(http.StatusRequestEntityTooLarge, "batch: more than "+strconv.Itoa(c.MaxItems)+" items")
+		}
+
+		bctx := context.WithValue(parent.Context(), batchKey{}, true)
+		results := make([]BatchResult, len(items))
+		sem := make(chan struct{}, c.Concurrency)
+		var wg sync.WaitGroup
+		for i := range items {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int) {
+				defer func() { <-sem; wg.Done() }()
+				results[i] = c.run(a, bctx, parent, items[i])
+			}(i)
+		}
+		wg.Wait()
+		return cx.JSON(results)
+	}
+}
+
+// run serves one item through a and records its response.
+func (c BatchConfig) run(a App, bctx context.Context, parent *http.Request, it BatchItem) BatchResult {
+	method := strings.ToUpper(it.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	if !strings.HasPrefix(it.Path, "/") || strings.HasPrefix(it.Path, "//") {
+		return batchError(http.StatusBadRequest, "path must start with a single /")
+	}
+	for k := range it.Headers {
+		if c.isProxyHeader(k) {
+			return batchError(http.StatusBadRequest, "header "+http.CanonicalHeaderKey(k)+" is set by the proxy")
+		}
+	}
+	body, contentType := batchBody(it)
+	req, err := http.NewRequestWithContext(bctx, method, it.Path, bytes.NewReader(body))
+	if err != nil {
+		return batchError(http.StatusBadRequest, err.Error())
+	}
+	req.Host, req.RemoteAddr, req.TLS, req.Proto = parent.Host, parent.RemoteAddr, parent.TLS, parent.Proto
+	for _, h := range c.Headers {
+		if v, ok := parent.Header[http.CanonicalHeaderKey(h)]; ok {
+			req.Header[http.CanonicalHeaderKey(h)] = v
+		}
+	}
+	for k, v := range it.Headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range parent.Header {
+		if c.isProxyHeader(k) {
+			req.Header[k] = v
+		}
+	}
+	if contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	rec := &batchRecorder{header: http.Header{}, max: c.MaxResponseSize}
+	a.ServeHTTP(rec, req)
+	if rec.overflow {
+		return batchError(http.StatusBadGateway, "response larger than "+strconv.Itoa(c.MaxResponseSize)+" bytes")
+	}
+	res := BatchResult{Status: rec.status(), Headers: make(map[string]string, len(rec.header))}
+	for k := range rec.header {
+		res.Headers[k] = rec.header.Get(k)
+	}
+	if rec.body.Len() > 0 {
+		b := rec.body.Bytes()
+		if isJSON(rec.header.Get("Content-Type")) && json.Valid(b) {
+			res.Body = json.RawMessage(b)
+		} else {
+			res.Body, _ = json.Marshal(string(b))
+		}
+	}
+	return res
+}
+
+// isProxyHeader reports whether name is set by a reverse proxy (see
+// BatchConfig.ProxyHeaders).
+func (c BatchConfig) isProxyHeader(name string) bool {
+	if len(name) >= len("X-Forwarded-") && strings.EqualFold(name[:len("X-Forwarded-")], "X-Forwarded-") ||
+		strings.EqualFold(name, "Forwarded") || strings.EqualFold(name, "X-Real-IP") {
+		return true
+	}
+	for _, h := range c.ProxyHeaders {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// batchBody returns the request body of it and its default Content-Type.
+func batchBody(it BatchItem) ([]byte, string) {
+	if len(it.Body) == 0 {
+		return nil, ""
+	}
+	if ct := headerValue(it.Headers, "Content-Type"); ct != "" && !isJSON(ct) {
+		var s string
+		if json.Unmarshal(it.Body, &s) == nil {
+			return []byte(s), ""
+		}
+	}
+	return it.Body, "application/json"
+}
+
+// headerValue looks up name in h case-insensitively.
+func headerValue(h map[string]string, name string) string {
+	for k, v := range h {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+func isJSON(contentType string) bool {
+	mt, _, _ := strings.Cut(contentType, ";")
+	mt = strings.TrimSpace(strings.ToLower(mt))
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+func batchError(status int, msg string) BatchResult {
+	body, _ := json.Marshal(map[string]string{"error": msg})
+	return BatchResult{Status: status, Headers: map[string]string{"Content-Type": "application/json"}, Body: body}
+}
+
+// batchRecorder captures an item's response.
+type batchRecorder struct {
+	header   http.Header
+	code     int
+	body     bytes.Buffer
+	max      int
+	overflow bool
+}
+
+func (r *batchRecorder) Header() http.Header { return r.header }
+
+func (r *batchRecorder) WriteHeader(code int) {
+	if r.code == 0 && code >= 200 {
+		r.code = code
+	}
+}
+
+func (r *batchRecorder) Write(p []byte) (int, error) {
+	if r.code == 0 {
+		r.code = http.StatusOK
+	}
+	if r.body.Len()+len(p) > r.max {
+		r.overflow = true
+		return 0, http.ErrContentLength
+	}
+	return r.body.Write(p)
+}
+
+func (r *batchRecorder) status() int {
+	if r.code == 0 {
+		return http.StatusOK
+	}
+	return r.code
+}
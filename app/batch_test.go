@@ -0,0 +1,164 @@
+package app
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func serveBatch(t *testing.T, a App, body string, hdr map[string]string) (*httptest.ResponseRecorder, []BatchResult) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range hdr {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	var out []BatchResult
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+			t.Fatalf("decode %q: %v", rec.Body.String(), err)
+		}
+	}
+	return rec, out
+}
+
+func TestBatchHandler_RunsItemsInOrder(t *testing.T) {
+	a := New()
+	a.GET("/users/:id", func(c Ctx) error {
+		return c.JSON(map[string]string{"id": c.Param("id"), "q": c.Query("fields")})
+	})
+	a.POST("/echo", func(c Ctx) error {
+		b, _ := io.ReadAll(c.Request().Body)
+		return c.String(http.StatusCreated, c.Request().Header.Get("Content-Type")+" "+string(b))
+	})
+	a.POST("/batch", BatchHandler(a))
+
+	rec, res := serveBatch(t, a, `[
+		{"method":"GET","path":"/users/7?fields=name"},
+		{"method":"POST","path":"/echo","body":{"sku":"A1"}},
+		{"method":"POST","path":"/echo","headers":{"Content-Type":"text/plain"},"body":"hi"},
+		{"method":"GET","path":"/missing"},
+		{"method":"GET","path":"http://evil/"}
+	]`, nil)
+	if rec.Code != http.StatusOK || len(res) != 5 {
+		t.Fatalf("code=%d body=%s", rec.Code, rec.Body.String())
+	}
+	if res[0].Status != 200 || string(res[0].Body) != `{"id":"7","q":"name"}` {
+		t.Fatalf("item 0=%+v body=%s", res[0], res[0].Body)
+	}
+	if res[1].Status != 201 || string(res[1].Body) != `"application/json {\"sku\":\"A1\"}"` {
+		t.Fatalf("item 1 body=%s", res[1].Body)
+	}
+	if string(res[2].Body) != `"text/plain hi"` {
+		t.Fatalf("item 2 body=%s", res[2].Body)
+	}
+	if res[3].Status != http.StatusNotFound {
+		t.Fatalf("item 3 status=%d", res[3].Status)
+	}
+	if res[4].Status != http.StatusBadRequest {
+		t.Fatalf("absolute URL item status=%d", res[4].Status)
+	}
+}
+
+func TestBatchHandler_PerItemAuth(t *testing.T) {
+	a := New()
+	auth := func(next Handler) Handler {
+		return func(c Ctx) error {
+			if c.Request().Header.Get("Authorization") != "Bearer ok" {
+				return c.String(http.StatusUnauthorized, "no")
+			}
+			return next(c)
+		}
+	}
+	a.GET("/private", func(c Ctx) error { return c.String(http.StatusOK, "secret") }, auth)
+	a.POST("/batch", BatchHandler(a))
+
+	_, res := serveBatch(t, a, `[{"path":"/private"},{"path":"/private","headers":{"Authorization":"Bearer bad"}}]`,
+		map[string]string{"Authorization": "Bearer ok"})
+	if res[0].Status != http.StatusOK || res[1].Status != http.StatusUnauthorized {
+		t.Fatalf("statuses=%d,%d", res[0].Status, res[1].Status)
+	}
+	_, res = serveBatch(t, a, `[{"path":"/private"}]`, nil)
+	if res[0].Status != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated batch item status=%d", res[0].Status)
+	}
+}
+
+func TestBatchHandler_ProxyHeaders(t *testing.T) {
+	a := New()
+	a.GET("/whoami", func(c Ctx) error {
+		h := c.Request().Header
+		return c.String(http.StatusOK, h.Get("X-Forwarded-For")+"|"+h.Get("X-Forwarded-Client-Cert")+"|"+h.Get("X-Ssl-Cert"))
+	})
+	a.POST("/batch", BatchHandler(a, BatchConfig{ProxyHeaders: []string{"X-SSL-Cert"}}))
+
+	parent := map[string]string{"X-Forwarded-For": "203.0.113.9", "X-Forwarded-Client-Cert": "URI=spiffe://example.org/user", "X-SSL-Cert": "real"}
+	_, res := serveBatch(t, a, `[
+		{"path":"/whoami"},
+		{"path":"/whoami","headers":{"x-forwarded-client-cert":"URI=spiffe://example.org/admin"}},
+		{"path":"/whoami","headers":{"X-Forwarded-For":"10.0.0.1"}},
+		{"path":"/whoami","headers":{"X-Real-IP":"10.0.0.1"}},
+		{"path":"/whoami","headers":{"Forwarded":"for=10.0.0.1"}},
+		{"path":"/whoami","headers":{"X-SSL-Cert":"forged"}}
+	]`, parent)
+	if res[0].Status != http.StatusOK || string(res[0].Body) != `"203.0.113.9|URI=spiffe://example.org/user|real"` {
+		t.Fatalf("item 0=%d %s", res[0].Status, res[0].Body)
+	}
+	for i, r := range res[1:] {
+		if r.Status != http.StatusBadRequest || !strings.Contains(string(r.Body), "set by the proxy") {
+			t.Fatalf("forged item %d=%d %s", i+1, r.Status, r.Body)
+		}
+	}
+}
+
+func TestBatchHandler_Limits(t *testing.T) {
+	a := New()
+	var running, peak atomic.Int32
+	a.GET("/slow", func(c Ctx) error {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		running.Add(-1)
+		return c.String(http.StatusOK, "ok")
+	})
+	a.GET("/big", func(c Ctx) error { return c.String(http.StatusOK, strings.Repeat("x", 100)) })
+	a.POST("/batch", BatchHandler(a, BatchConfig{MaxItems: 6, Concurrency: 2, MaxResponseSize: 50}))
+
+	_, res := serveBatch(t, a, `[{"path":"/slow"},{"path":"/slow"},{"path":"/slow"},{"path":"/slow"},{"path":"/big"}]`, nil)
+	if peak.Load() > 2 {
+		t.Fatalf("concurrency peak=%d", peak.Load())
+	}
+	if res[4].Status != http.StatusBadGateway {
+		t.Fatalf("oversized item status=%d", res[4].Status)
+	}
+	rec, _ := serveBatch(t, a, `[`+strings.Repeat(`{"path":"/slow"},`, 6)+`{"path":"/slow"}]`, nil)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("too many items code=%d", rec.Code)
+	}
+	for _, body := range []string{`[]`, `{"path":"/slow"}`, `not json`} {
+		if rec, _ := serveBatch(t, a, body, nil); rec.Code != http.StatusBadRequest {
+			t.Fatalf("body %q code=%d", body, rec.Code)
+		}
+	}
+}
+
+func TestBatchHandler_NoNesting(t *testing.T) {
+	a := New()
+	a.POST("/batch", BatchHandler(a))
+	_, res := serveBatch(t, a, `[{"method":"POST","path":"/batch","body":[{"path":"/batch"}]}]`, nil)
+	if res[0].Status != http.StatusBadRequest || !strings.Contains(string(res[0].Body), "nested") {
+		t.Fatalf("nested batch=%+v body=%s", res[0], res[0].Body)
+	}
+}
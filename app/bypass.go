@@ -0,0 +1,45 @@
+package app
+
+// Bypass opts the route out of the named middleware and returns the route.
+// Only middleware that check Ctx.Bypassed honor it: those wrapped with
+// Bypassable, and built-in middleware that document a bypass name (for
+// example "buffer" and "timeout"). Call it at registration time, before the
+// app serves requests.
+//
+// Example:
+//
+//	a.Use(app.Bypassable("gzip", Gzip()), middleware.Timeout(cfg))
+//	a.GET("/events", StreamEvents).Bypass("gzip", "timeout") // SSE: no compression, no deadline
+func (r *Route) Bypass(names ...string) *Route {
+	r.bypass = append(r.bypass, names...)
+	return r
+}
+
+// Bypassable wraps mw so that it is skipped for requests where
+// c.Bypassed(name) is true, because the route opted out with Route.Bypass or
+// an earlier middleware called c.BypassMiddleware(name). The check is a scan
+// of a few strings per request; routes without bypasses pay nothing else.
+// The result keeps mw's Describe name and config, and is named name otherwise.
+//
+// Example:
+//
+//	a.Use(app.Bypassable("cache", ResponseCache(store)))
+//	a.GET("/me", CurrentUser).Bypass("cache")
+func Bypassable(name string, mw Middleware) Middleware {
+	if mw == nil {
+		panic(registrationError("", "", "nil middleware passed to Bypassable(\""+name+"\")"))
+	}
+	described := name
+	if _, ok := mw.description(); ok {
+		described = mw.Name()
+	}
+	return Describe(described, mw.Config(), func(next Handler) Handler {
+		wrapped := mw(next)
+		return func(c Ctx) error {
+			if c.Bypassed(name) {
+				return next(c)
+			}
+			return wrapped(c)
+		}
+	})
+}
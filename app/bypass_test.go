@@ -0,0 +1,82 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteBypassSkipsBypassableMiddleware(t *testing.T) {
+	a := New()
+	var hits []string
+	tag := func(name string) Middleware {
+		return Bypassable(name, func(next Handler) Handler {
+			return func(c Ctx) error {
+				hits = append(hits, name)
+				return next(c)
+			}
+		})
+	}
+	a.Use(tag("gzip"), tag("cache"))
+	ok := func(c Ctx) error { return c.String(http.StatusOK, "ok") }
+	a.GET("/page", ok)
+	a.GET("/events", ok).Bypass("gzip", "cache")
+	g := a.Group("/api")
+	g.GET("/stream", ok).Bypass("cache")
+	a.ANY("/any", ok).Bypass("gzip")
+
+	cases := []struct {
+		method, path string
+		want         string
+	}{
+		{http.MethodGet, "/page", "gzip,cache"},
+		{http.MethodGet, "/events", ""},
+		{http.MethodGet, "/api/stream", "gzip"},
+		{http.MethodPost, "/any", "cache"},
+		{http.MethodGet, "/page", "gzip,cache"}, // pooled contexts must not leak bypasses
+	}
+	for _, tc := range cases {
+		hits = hits[:0]
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s %s: status %d", tc.method, tc.path, rec.Code)
+		}
+		got := ""
+		for i, h := range hits {
+			if i > 0 {
+				got += ","
+			}
+			got += h
+		}
+		if got != tc.want {
+			t.Fatalf("%s %s: ran %q, want %q", tc.method, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestBypassMiddlewareAtRuntime(t *testing.T) {
+	a := New()
+	ran := false
+	optOut := func(next Handler) Handler {
+		return func(c Ctx) error {
+			if c.Query("raw") == "1" {
+				c.BypassMiddleware("wrap")
+			}
+			return next(c)
+		}
+	}
+	wrap := Bypassable("wrap", func(next Handler) Handler {
+		return func(c Ctx) error { ran = true; return next(c) }
+	})
+	a.GET("/x", func(c Ctx) error { return c.String(http.StatusOK, "ok") }, optOut, wrap)
+
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/x?raw=1", nil))
+	if ran {
+		t.Fatalf("expected wrap to be bypassed")
+	}
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/x", nil))
+	if !ran {
+		t.Fatalf("expected wrap to run")
+	}
+}
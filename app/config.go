@@ -0,0 +1,318 @@
+package app
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds deployment-tunable framework settings. It can be loaded from a
+// YAML or JSON file (LoadConfigFile), from environment variables
+// (LoadConfigEnv), or both, and turned into an app with NewFromConfig.
+//
+// Server settings are applied with Config.Server; CORS, rate limiting,
+// trusted proxies and compression are turned into middleware by
+// middleware.FromConfig (kept in the middleware package to avoid an import
+// cycle).
+//
+// Example (config.yaml):
+//
+//	addr: ":8080"
+//	read_timeout: 5s
+//	write_timeout: 10s
+//	trusted_proxies: ["10.0.0.0/8"]
+//	log: {level: debug, format: text}
+//	cors: {enabled: true, origins: ["https://app.example.com"]}
+//	rate_limit: {enabled: true, strategy: token_bucket, limit: 100, window: 1m}
+//	compression: {enabled: true, level: 6, min_length: 1024}
+//	runtime: {gogc: 200, memory_limit_percent: 90}
+//
+// Example (loading):
+//
+//	cfg, err := app.LoadConfigFile("config.yaml")
+//	if err == nil {
+//		cfg, err = app.LoadConfigEnv("FLASH", cfg) // FLASH_ADDR, FLASH_LOG_LEVEL, ...
+//	}
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	a, err := app.NewFromConfig(cfg)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	mws, err := middleware.FromConfig(cfg)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	a.Use(mws...)
+//	log.Fatal(cfg.Server(a).ListenAndServe())
+type Config struct {
+	Addr              string   `json:"addr" yaml:"addr" env:"ADDR"`
+	ReadTimeout       Duration `json:"read_timeout" yaml:"read_timeout" env:"READ_TIMEOUT"`
+	ReadHeaderTimeout Duration `json:"read_header_timeout" yaml:"read_header_timeout" env:"READ_HEADER_TIMEOUT"`
+	WriteTimeout      Duration `json:"write_timeout" yaml:"write_timeout" env:"WRITE_TIMEOUT"`
+	IdleTimeout       Duration `json:"idle_timeout" yaml:"idle_timeout" env:"IDLE_TIMEOUT"`
+	ShutdownTimeout   Duration `json:"shutdown_timeout" yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	MaxHeaderBytes    int      `json:"max_header_bytes" yaml:"max_header_bytes" env:"MAX_HEADER_BYTES"`
+
+	// TrustedProxies lists CIDRs whose X-Forwarded-For headers are honored.
+	TrustedProxies []string `json:"trusted_proxies" yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+
+	Log         LogSettings         `json:"log" yaml:"log" env:"LOG"`
+	Compression CompressionSettings `json:"compression" yaml:"compression" env:"COMPRESSION"`
+	CORS        CORSSettings        `json:"cors" yaml:"cors" env:"CORS"`
+	RateLimit   RateLimitSettings   `json:"rate_limit" yaml:"rate_limit" env:"RATE_LIMIT"`
+
+	// Runtime tunes the garbage collector; NewFromConfig applies it with
+	// TuneRuntime.
+	Runtime RuntimeConfig `json:"runtime" yaml:"runtime" env:"RUNTIME"`
+}
+
+// LogSettings configures the application logger built by NewFromConfig.
+type LogSettings struct {
+	Level  string `json:"level" yaml:"level" env:"LEVEL"`    // debug, info (default), warn, error
+	Format string `json:"format" yaml:"format" env:"FORMAT"` // json (default) or text
+}
+
+// CompressionSettings configures the middleware.Compress middleware built by
+// middleware.FromConfig.
+type CompressionSettings struct {
+	Enabled   bool `json:"enabled" yaml:"enabled" env:"ENABLED"`
+	Level     int  `json:"level" yaml:"level" env:"LEVEL"`                // gzip level; 0 means the default
+	MinLength int  `json:"min_length" yaml:"min_length" env:"MIN_LENGTH"` // smallest response compressed, in bytes
+}
+
+// CORSSettings mirrors middleware.CORSConfig in a serializable form.
+type CORSSettings struct {
+	Enabled     bool     `json:"enabled" yaml:"enabled" env:"ENABLED"`
+	Origins     []string `json:"origins" yaml:"origins" env:"ORIGINS"`
+	Methods     []string `json:"methods" yaml:"methods" env:"METHODS"`
+	Headers     []string `json:"headers" yaml:"headers" env:"HEADERS"`
+	Expose      []string `json:"expose" yaml:"expose" env:"EXPOSE"`
+	Credentials bool     `json:"credentials" yaml:"credentials" env:"CREDENTIALS"`
+	MaxAge      int      `json:"max_age" yaml:"max_age" env:"MAX_AGE"`
+}
+
+// RateLimitSettings selects a rate limiting strategy in a serializable form.
+// Strategy is one of token_bucket (default), fixed_window or sliding_window;
+// Limit requests are allowed per Window.
+type RateLimitSettings struct {
+	Enabled  bool     `json:"enabled" yaml:"enabled" env:"ENABLED"`
+	Strategy string   `json:"strategy" yaml:"strategy" env:"STRATEGY"`
+	Limit    int      `json:"limit" yaml:"limit" env:"LIMIT"`
+	Window   Duration `json:"window" yaml:"window" env:"WINDOW"`
+}
+
+// Duration is a time.Duration that reads and writes as a string such as "5s"
+// or "1m30s" in YAML, JSON and environment variables.
+type Duration time.Duration
+
+// UnmarshalText parses a duration string (time.ParseDuration); an empty string is zero.
+func (d *Duration) UnmarshalText(b []byte) error {
+	if len(b) == 0 {
+		*d = 0
+		return nil
+	}
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText formats the duration like time.Duration.String.
+func (d Duration) MarshalText() ([]byte, error) { return []byte(time.Duration(d).String()), nil }
+
+// Std returns the value as a time.Duration.
+func (d Duration) Std() time.Duration { return time.Duration(d) }
+
+// LoadConfigFile reads a Config from a YAML (.yaml, .yml) or JSON (.json) file.
+// Unknown keys are rejected so typos surface at startup.
+func LoadConfigFile(path string) (Config, error) {
+	var cfg Config
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return cfg, fmt.Errorf("flash: config %s: %w", path, err)
+		}
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&cfg); err != nil {
+			return cfg, fmt.Errorf("flash: config %s: %w", path, err)
+		}
+	default:
+		return cfg, fmt.Errorf("flash: config %s: unsupported extension (want .yaml, .yml or .json)", path)
+	}
+	return cfg, nil
+}
+
+// LoadConfigEnv overlays environment variables onto base and returns the result.
+// Variable names are the prefix plus the upper-snake field path, e.g. with
+// prefix "FLASH": FLASH_ADDR, FLASH_READ_TIMEOUT, FLASH_LOG_LEVEL,
+// FLASH_CORS_ORIGINS, FLASH_RATE_LIMIT_WINDOW. Lists are comma-separated.
+// Unset variables leave base untouched.
+//
+// Example:
+//
+//	// FLASH_ADDR=:9090 FLASH_CORS_ENABLED=true FLASH_CORS_ORIGINS=https://a.com,https://b.com
+//	cfg, err := app.LoadConfigEnv("FLASH", app.Config{Addr: ":8080"})
+func LoadConfigEnv(prefix string, base Config) (Config, error) {
+	cfg := base
+	err := loadEnv(reflect.ValueOf(&cfg).Elem(), strings.TrimSuffix(prefix, "_"))
+	return cfg, err
+}
+
+// loadEnv assigns struct fields from environment variables named by env tags.
+func loadEnv(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("env")
+		if tag == "" {
+			continue
+		}
+		name := tag
+		if prefix != "" {
+			name = prefix + "_" + tag
+		}
+		f := v.Field(i)
+		if f.Kind() == reflect.Struct {
+			if err := loadEnv(f, name); err != nil {
+				return err
+			}
+			continue
+		}
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setEnvField(f, raw); err != nil {
+			return fmt.Errorf("flash: env %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// setEnvField parses raw into f according to its type.
+func setEnvField(f reflect.Value, raw string) error {
+	if u, ok := f.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(raw))
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, s := range strings.Split(raw, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				items = append(items, s)
+			}
+		}
+		f.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported kind %s", f.Kind())
+	}
+	return nil
+}
+
+// Logger builds the slog.Logger described by Log, writing to stdout.
+func (c Config) Logger() (*slog.Logger, error) {
+	var level slog.Level
+	if c.Log.Level != "" {
+		if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+			return nil, fmt.Errorf("flash: log level %q: %w", c.Log.Level, err)
+		}
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(c.Log.Format) {
+	case "", "json":
+		return slog.New(slog.NewJSONHandler(os.Stdout, opts)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(os.Stdout, opts)), nil
+	default:
+		return nil, fmt.Errorf("flash: log format %q: want json or text", c.Log.Format)
+	}
+}
+
+// Server returns an *http.Server for h using the address, timeouts and header
+// limit from c.
+//
+// Example:
+//
+//	log.Fatal(cfg.Server(a).ListenAndServe())
+func (c Config) Server(h http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              c.Addr,
+		Handler:           h,
+		ReadTimeout:       c.ReadTimeout.Std(),
+		ReadHeaderTimeout: c.ReadHeaderTimeout.Std(),
+		WriteTimeout:      c.WriteTimeout.Std(),
+		IdleTimeout:       c.IdleTimeout.Std(),
+		MaxHeaderBytes:    c.MaxHeaderBytes,
+	}
+}
+
+// NewFromConfig creates an app configured from cfg (the logger and the
+// runtime tuning in Runtime, applied with TuneRuntime), then applies opts. It
+// returns an error for invalid settings instead of panicking. The config is
+// available afterwards via Config.
+//
+// Example:
+//
+//	a, err := app.NewFromConfig(cfg, app.WithRouter(app.NewTreeRouter()))
+func NewFromConfig(cfg Config, opts ...Option) (App, error) {
+	logger, err := cfg.Logger()
+	if err != nil {
+		return nil, err
+	}
+	if err := TuneRuntime(cfg.Runtime); err != nil {
+		return nil, err
+	}
+	all := append([]Option{WithLogger(logger), withConfig(cfg)}, opts...)
+	return New(all...), nil
+}
+
+// withConfig records the configuration the app was built from.
+func withConfig(cfg Config) Option {
+	return func(a *DefaultApp) { a.config = &cfg }
+}
+
+// Config returns the configuration passed to NewFromConfig, if any.
+func (a *DefaultApp) Config() (Config, bool) {
+	if a.config == nil {
+		return Config{}, false
+	}
+	return *a.config, true
+}
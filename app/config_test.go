@@ -0,0 +1,164 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, name, body string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoadConfigFile_YAMLAndJSON(t *testing.T) {
+	want := Config{
+		Addr:           ":8080",
+		ReadTimeout:    Duration(5 * time.Second),
+		WriteTimeout:   Duration(90 * time.Second),
+		TrustedProxies: []string{"10.0.0.0/8"},
+		Log:            LogSettings{Level: "debug", Format: "text"},
+		Compression:    CompressionSettings{Enabled: true, Level: 5},
+		CORS:           CORSSettings{Enabled: true, Origins: []string{"https://a.com"}, MaxAge: 60},
+		RateLimit:      RateLimitSettings{Enabled: true, Strategy: "fixed_window", Limit: 10, Window: Duration(time.Minute)},
+	}
+	yml := writeConfig(t, "c.yaml", `
+addr: ":8080"
+read_timeout: 5s
+write_timeout: 1m30s
+trusted_proxies: ["10.0.0.0/8"]
+log: {level: debug, format: text}
+compression: {enabled: true, level: 5}
+cors: {enabled: true, origins: ["https://a.com"], max_age: 60}
+rate_limit: {enabled: true, strategy: fixed_window, limit: 10, window: 1m}
+`)
+	js := writeConfig(t, "c.json", `{
+	"addr": ":8080", "read_timeout": "5s", "write_timeout": "90s",
+	"trusted_proxies": ["10.0.0.0/8"],
+	"log": {"level": "debug", "format": "text"},
+	"compression": {"enabled": true, "level": 5},
+	"cors": {"enabled": true, "origins": ["https://a.com"], "max_age": 60},
+	"rate_limit": {"enabled": true, "strategy": "fixed_window", "limit": 10, "window": "1m"}
+}`)
+	for _, p := range []string{yml, js} {
+		got, err := LoadConfigFile(p)
+		if err != nil {
+			t.Fatalf("%s: %v", p, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s:\n got %+v\nwant %+v", p, got, want)
+		}
+	}
+}
+
+func TestLoadConfigFile_Errors(t *testing.T) {
+	if cfg, err := LoadConfigFile(writeConfig(t, "empty.yml", "")); err != nil || cfg.Addr != "" {
+		t.Fatalf("empty YAML should yield zero config: %+v %v", cfg, err)
+	}
+	cases := map[string]string{
+		"typo.yaml": "adr: x",
+		"typo.json": `{"adr": "x"}`,
+		"dur.yaml":  "read_timeout: soon",
+		"conf.toml": "addr = 1",
+		"bad.json":  "{",
+	}
+	for name, body := range cases {
+		if _, err := LoadConfigFile(writeConfig(t, name, body)); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+	if _, err := LoadConfigFile(filepath.Join(t.TempDir(), "missing.yaml")); !os.IsNotExist(err) {
+		t.Fatalf("expected not-exist error, got %v", err)
+	}
+}
+
+func TestLoadConfigEnv_OverlaysBase(t *testing.T) {
+	t.Setenv("APP_ADDR", ":9090")
+	t.Setenv("APP_IDLE_TIMEOUT", "2m")
+	t.Setenv("APP_MAX_HEADER_BYTES", "4096")
+	t.Setenv("APP_TRUSTED_PROXIES", "10.0.0.0/8, 192.168.0.0/16,")
+	t.Setenv("APP_LOG_LEVEL", "warn")
+	t.Setenv("APP_CORS_ENABLED", "true")
+	t.Setenv("APP_RATE_LIMIT_WINDOW", "10s")
+
+	base := Config{Addr: ":8080", Log: LogSettings{Format: "text"}, RateLimit: RateLimitSettings{Limit: 5}}
+	cfg, err := LoadConfigEnv("APP_", base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != ":9090" || cfg.IdleTimeout.Std() != 2*time.Minute || cfg.MaxHeaderBytes != 4096 ||
+		strings.Join(cfg.TrustedProxies, "|") != "10.0.0.0/8|192.168.0.0/16" ||
+		cfg.Log.Level != "warn" || cfg.Log.Format != "text" || !cfg.CORS.Enabled ||
+		cfg.RateLimit.Limit != 5 || cfg.RateLimit.Window.Std() != 10*time.Second {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+	if base.Addr != ":8080" {
+		t.Fatalf("base must not be modified")
+	}
+
+	for name, val := range map[string]string{
+		"APP_MAX_HEADER_BYTES": "lots",
+		"APP_CORS_ENABLED":     "maybe",
+		"APP_READ_TIMEOUT":     "5 parsecs",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, val)
+			if _, err := LoadConfigEnv("APP", Config{}); err == nil || !strings.Contains(err.Error(), name) {
+				t.Fatalf("expected error naming %s, got %v", name, err)
+			}
+		})
+	}
+}
+
+func TestConfig_ServerAndLogger(t *testing.T) {
+	cfg := Config{Addr: ":1", ReadTimeout: Duration(time.Second), ReadHeaderTimeout: Duration(2 * time.Second),
+		WriteTimeout: Duration(3 * time.Second), IdleTimeout: Duration(4 * time.Second), MaxHeaderBytes: 512}
+	a := New()
+	srv := cfg.Server(a)
+	if srv.Addr != ":1" || srv.Handler != a || srv.ReadTimeout != time.Second || srv.ReadHeaderTimeout != 2*time.Second ||
+		srv.WriteTimeout != 3*time.Second || srv.IdleTimeout != 4*time.Second || srv.MaxHeaderBytes != 512 {
+		t.Fatalf("unexpected server: %+v", srv)
+	}
+	for _, lg := range []LogSettings{{}, {Level: "DEBUG", Format: "JSON"}, {Level: "error", Format: "text"}} {
+		if _, err := (Config{Log: lg}).Logger(); err != nil {
+			t.Fatalf("%+v: %v", lg, err)
+		}
+	}
+	for _, lg := range []LogSettings{{Level: "loud"}, {Format: "xml"}} {
+		if _, err := (Config{Log: lg}).Logger(); err == nil {
+			t.Fatalf("%+v: expected error", lg)
+		}
+	}
+	if b, _ := Duration(90 * time.Second).MarshalText(); string(b) != "1m30s" {
+		t.Fatalf("MarshalText = %s", b)
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	cfg := Config{Addr: ":7", Log: LogSettings{Level: "debug"}}
+	a, err := NewFromConfig(cfg, WithJSONEscapeHTML(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := a.Config(); !ok || got.Addr != ":7" {
+		t.Fatalf("config not recorded: %+v %v", got, ok)
+	}
+	if !a.Logger().Enabled(context.Background(), slog.LevelDebug) {
+		t.Fatalf("debug level not applied")
+	}
+	if _, ok := New().Config(); ok {
+		t.Fatalf("plain New should report no config")
+	}
+	if _, err := NewFromConfig(Config{Log: LogSettings{Level: "nope"}}); err == nil {
+		t.Fatalf("expected invalid level error")
+	}
+}
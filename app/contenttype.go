@@ -0,0 +1,112 @@
+package app
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+// ErrUnsupportedMediaType is wrapped by the 415 errors of AllowedContentTypes,
+// so error handlers can recognize them with errors.Is.
+var ErrUnsupportedMediaType = errors.New("flash: unsupported media type")
+
+// ContentTypeConfig configures AllowedContentTypes.
+type ContentTypeConfig struct {
+	// Types lists the accepted media types. Parameters such as charset are
+	// ignored and matching is case-insensitive. Besides exact types, entries
+	// may be a type wildcard ("image/*"), "*/*", or a structured syntax
+	// suffix starting with "+" ("+json" accepts application/problem+json and
+	// application/vnd.api+json).
+	Types []string
+
+	// Message is the client-facing message of the 415 error. Defaults to
+	// "unsupported content type; accepted: " followed by Types.
+	Message string
+}
+
+// WithAllowedContentTypes returns route middleware that rejects request
+// bodies whose Content-Type is not one of types with 415 Unsupported Media
+// Type, before later middleware, binders and the handler run. It is shorthand
+// for AllowedContentTypes(ContentTypeConfig{Types: types}).
+//
+// Example:
+//
+//	a.POST("/users", CreateUser, flash.WithAllowedContentTypes("application/json", "+json"))
+//	a.PUT("/avatar", UploadAvatar, flash.WithAllowedContentTypes("image/*"))
+func WithAllowedContentTypes(types ...string) Middleware {
+	return AllowedContentTypes(ContentTypeConfig{Types: types})
+}
+
+// AllowedContentTypes returns middleware enforcing cfg.Types on request
+// bodies. Requests without a body pass, so it can also guard groups that mix
+// GET and POST routes. Rejected requests fail with a 415 *ctx.HTTPError
+// wrapping ErrUnsupportedMediaType, rendered by the app's error handler like
+// any other error; POST and PATCH rejections also carry the Accept-Post or
+// Accept-Patch header listing the accepted types. It panics if Types is
+// empty.
+//
+// Example (custom message):
+//
+//	api := a.Group("/api", flash.AllowedContentTypes(flash.ContentTypeConfig{
+//		Types:   []string{"application/json"},
+//		Message: "this API only accepts JSON",
+//	}))
+func AllowedContentTypes(cfg ContentTypeConfig) Middleware {
+	if len(cfg.Types) == 0 {
+		panic("flash: AllowedContentTypes requires at least one type")
+	}
+	types := make([]string, len(cfg.Types))
+	for i, t := range cfg.Types {
+		types[i] = strings.ToLower(strings.TrimSpace(t))
+	}
+	accepted := strings.Join(cfg.Types, ", ")
+	if cfg.Message == "" {
+		cfg.Message = "unsupported content type; accepted: " + accepted
+	}
+
+	return Describe("contenttype", cfg, func(next Handler) Handler {
+		return func(c ctx.Ctx) error {
+			r := c.Request()
+			if r.ContentLength == 0 || contentTypeAllowed(r.Header.Get("Content-Type"), types) {
+				return next(c)
+			}
+			switch r.Method {
+			case http.MethodPost:
+				c.Header("Accept-Post", accepted)
+			case http.MethodPatch:
+				c.Header("Accept-Patch", accepted)
+			}
+			return &ctx.HTTPError{Code: http.StatusUnsupportedMediaType, Message: cfg.Message, Err: ErrUnsupportedMediaType}
+		}
+	})
+}
+
+// contentTypeAllowed reports whether the Content-Type header value matches one
+// of the lower-cased patterns.
+func contentTypeAllowed(header string, patterns []string) bool {
+	if header == "" {
+		return false
+	}
+	mt, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return false
+	}
+	for _, p := range patterns {
+		switch {
+		case p == "*/*" || p == mt:
+			return true
+		case strings.HasPrefix(p, "+"):
+			if strings.HasSuffix(mt, p) {
+				return true
+			}
+		case strings.HasSuffix(p, "/*"):
+			if strings.HasPrefix(mt, p[:len(p)-1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestContentTypeAllowed(t *testing.T) {
+	patterns := []string{"application/json", "+json", "image/*"}
+	cases := map[string]bool{
+		"application/json":                true,
+		"Application/JSON; charset=utf-8": true,
+		"application/problem+json":        true,
+		"application/vnd.api+json":        true,
+		"image/png":                       true,
+		"text/plain":                      false,
+		"application/xml":                 false,
+		"application/jsonx":               false,
+		"":                                false,
+		"invalid;;":                       false,
+	}
+	for header, want := range cases {
+		if got := contentTypeAllowed(header, patterns); got != want {
+			t.Fatalf("%q: got %v, want %v", header, got, want)
+		}
+	}
+	if !contentTypeAllowed("text/csv", []string{"*/*"}) {
+		t.Fatalf("*/* should accept any type")
+	}
+}
+
+func TestWithAllowedContentTypes(t *testing.T) {
+	a := New()
+	called := false
+	a.POST("/users", func(c Ctx) error {
+		called = true
+		return c.String(http.StatusCreated, "ok")
+	}, WithAllowedContentTypes("application/json", "+json"))
+
+	serve := func(ct, body string) *httptest.ResponseRecorder {
+		called = false
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+		if ct != "" {
+			req.Header.Set("Content-Type", ct)
+		}
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve("application/json; charset=utf-8", `{}`); rec.Code != http.StatusCreated || !called {
+		t.Fatalf("json: %d called=%v", rec.Code, called)
+	}
+	if rec := serve("application/merge-patch+json", `{}`); rec.Code != http.StatusCreated {
+		t.Fatalf("+json: %d", rec.Code)
+	}
+	rec := serve("text/plain", "hi")
+	if rec.Code != http.StatusUnsupportedMediaType || called {
+		t.Fatalf("text: %d called=%v", rec.Code, called)
+	}
+	if rec.Header().Get("Accept-Post") != "application/json, +json" || !strings.Contains(rec.Body.String(), "accepted: application/json, +json") {
+		t.Fatalf("415 response: %v %q", rec.Header(), rec.Body.String())
+	}
+	if rec := serve("", "hi"); rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("missing content type with body: %d", rec.Code)
+	}
+	if rec := serve("", ""); rec.Code != http.StatusCreated {
+		t.Fatalf("empty body: %d", rec.Code)
+	}
+}
+
+func TestAllowedContentTypesCentralError(t *testing.T) {
+	a := New()
+	var got error
+	a.SetErrorHandler(func(c Ctx, err error) {
+		got = err
+		_ = c.JSON(map[string]string{"error": err.Error()})
+	})
+	api := a.Group("/api", AllowedContentTypes(ContentTypeConfig{Types: []string{"application/json"}, Message: "JSON only"}))
+	api.PATCH("/items/:id", func(c Ctx) error { return nil })
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/items/1", strings.NewReader("a=b"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	if !errors.Is(got, ErrUnsupportedMediaType) || got.Error() != "JSON only: "+ErrUnsupportedMediaType.Error() {
+		t.Fatalf("error = %v", got)
+	}
+	if rec.Header().Get("Accept-Patch") != "application/json" {
+		t.Fatalf("headers = %v", rec.Header())
+	}
+}
+
+func TestAllowedContentTypesRequiresTypes(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic for empty Types")
+		}
+	}()
+	WithAllowedContentTypes()
+}
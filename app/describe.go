@@ -0,0 +1,214 @@
+package app
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+// NamedMiddleware is implemented by middleware that can describe itself. Every
+// Middleware value implements it: middleware built with Describe, including
+// all built-in middleware, report the name and configuration given there;
+// other middleware report the name of the Go function that created them (for
+// example "main.RequireAdmin") and a nil Config. Route tables, tracing and
+// ordering checks use it to show the actual stack instead of anonymous
+// closures.
+type NamedMiddleware interface {
+	// Name returns a short, stable identifier such as "logger" or "cors".
+	Name() string
+	// Config returns the configuration the middleware was built with, or nil.
+	Config() any
+}
+
+// described is a middleware returned by Describe: its wrap method value is
+// the Middleware, so the metadata travels with the func value itself.
+type described struct {
+	name   string
+	config any
+	mw     Middleware
+}
+
+// wrap applies the described middleware. Called with describeProbe, it
+// returns a handler that reports d instead (see Middleware.description).
+func (d *described) wrap(next Handler) Handler {
+	if next != nil && reflect.ValueOf(next).Pointer() == describeProbePC {
+		return func(c ctx.Ctx) error {
+			c.(*describeProbeCtx).d = d
+			return nil
+		}
+	}
+	return d.mw(next)
+}
+
+// describeProbe is passed to described middleware to retrieve their metadata.
+func describeProbe(ctx.Ctx) error { return nil }
+
+// describeProbeCtx receives the metadata of a probed described middleware.
+// Only the probe handler returned by described.wrap ever sees it.
+type describeProbeCtx struct {
+	ctx.Ctx
+	d *described
+}
+
+var (
+	describeProbePC = reflect.ValueOf(describeProbe).Pointer()
+	// describedPC is the code pointer shared by every wrap method value, which
+	// identifies middleware returned by Describe without calling them.
+	describedPC = reflect.ValueOf(Middleware((&described{}).wrap)).Pointer()
+)
+
+// Describe returns mw with a name and configuration attached, reported by
+// its Name and Config methods. Built-in middleware call it with the name they
+// honor for Route.Bypass (e.g. "timeout"), so custom middleware should pick
+// names that do not clash with them. Describe wraps mw in one function call
+// at registration time; requests pay nothing.
+//
+// The configuration is shown by MiddlewareStack and route tables, so leave
+// out secrets such as keys, passwords and stores; built-in middleware report
+// their configuration with such fields removed.
+//
+// Example:
+//
+//	func RequireRole(role string) flash.Middleware {
+//		return flash.Describe("require_role", role, func(next flash.Handler) flash.Handler {
+//			return func(c flash.Ctx) error { ... }
+//		})
+//	}
+func Describe(name string, config any, mw Middleware) Middleware {
+	if mw == nil {
+		panic(registrationError("", "", "nil middleware passed to Describe(\""+name+"\")"))
+	}
+	return (&described{name: name, config: config, mw: mw}).wrap
+}
+
+// description returns the metadata of a middleware returned by Describe.
+// Other middleware are recognized by their code pointer and never called.
+func (m Middleware) description() (*described, bool) {
+	if m == nil || reflect.ValueOf(m).Pointer() != describedPC {
+		return nil, false
+	}
+	var probe describeProbeCtx
+	_ = m(describeProbe)(&probe)
+	return probe.d, probe.d != nil
+}
+
+// Name returns the name given with Describe or, for other middleware, the
+// package-qualified name of the function that created m with closure
+// suffixes removed, e.g. "main.RequireAdmin".
+func (m Middleware) Name() string {
+	if d, ok := m.description(); ok {
+		return d.name
+	}
+	if m == nil {
+		return ""
+	}
+	fn := runtime.FuncForPC(reflect.ValueOf(m).Pointer())
+	if fn == nil {
+		return "anonymous"
+	}
+	return funcName(fn.Name())
+}
+
+// Config returns the configuration given with Describe, or nil.
+func (m Middleware) Config() any {
+	if d, ok := m.description(); ok {
+		return d.config
+	}
+	return nil
+}
+
+// funcName shortens a runtime function name such as
+// "github.com/acme/api/auth.Require.func1.2" to "auth.Require".
+func funcName(full string) string {
+	name := full[strings.LastIndexByte(full, '/')+1:]
+	for {
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			return name
+		}
+		last := name[i+1:]
+		if !strings.HasPrefix(last, "func") && strings.Trim(last, "0123456789") != "" {
+			return name
+		}
+		name = name[:i]
+	}
+}
+
+// MiddlewareInfo describes one middleware of a stack.
+type MiddlewareInfo struct {
+	Name   string
+	Phase  Phase
+	Config any
+}
+
+// MiddlewareStack returns the global middleware in execution order (outermost
+// first) with their phases, as registered with Use and UseOrdered.
+//
+// Example:
+//
+//	for _, m := range a.MiddlewareStack() {
+//		fmt.Printf("%-10s %s\n", m.Phase, m.Name)
+//	}
+func (a *DefaultApp) MiddlewareStack() []MiddlewareInfo {
+	out := make([]MiddlewareInfo, len(a.middleware))
+	for i, m := range a.middleware {
+		out[i] = MiddlewareInfo{Name: m.Name(), Phase: a.mwPhases[i], Config: m.Config()}
+	}
+	return out
+}
+
+// Middleware returns the names of the middleware wrapping the route's handler
+// in execution order: global, then UsePrefix, then group, then
+// route-specific middleware.
+func (r *Route) Middleware() []string {
+	return append([]string(nil), r.middleware...)
+}
+
+// middlewareNames lists the names of the middleware in global followed by mws.
+func middlewareNames(global, mws []Middleware) []string {
+	if len(global)+len(mws) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(global)+len(mws))
+	for _, m := range global {
+		names = append(names, m.Name())
+	}
+	for _, m := range mws {
+		names = append(names, m.Name())
+	}
+	return names
+}
+
+// orderRules lists middleware that must run before (wrap) others. "*" means
+// every other middleware.
+var orderRules = []struct{ outer, inner, why string }{
+	{"recover", "*", "panics in it are not recovered"},
+	{"requestid", "logger", "its log lines lack the request ID"},
+	{"cors", "csrf", "cross-origin preflight requests are rejected"},
+}
+
+// orderWarnings checks a middleware stack (outermost first) against
+// orderRules.
+func orderWarnings(names []string) []string {
+	var out []string
+	pos := make(map[string]int, len(names))
+	for i, n := range names {
+		if _, ok := pos[n]; !ok {
+			pos[n] = i
+		}
+	}
+	for _, r := range orderRules {
+		o, ok := pos[r.outer]
+		if !ok {
+			continue
+		}
+		for i, n := range names[:o] {
+			if r.inner == "*" || n == r.inner {
+				out = append(out, names[i]+" runs before "+r.outer+"; "+r.why)
+			}
+		}
+	}
+	return out
+}
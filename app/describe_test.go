@@ -0,0 +1,181 @@
+package app
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func identityMW(next Handler) Handler { return next }
+
+func namedMW(name string) Middleware { return Describe(name, nil, identityMW) }
+
+func requireAdmin() Middleware {
+	return func(next Handler) Handler { return next }
+}
+
+func TestDescribe_NameAndConfig(t *testing.T) {
+	type cfg struct{ Limit int }
+	mw := Describe("limit", cfg{Limit: 3}, identityMW)
+	if mw.Name() != "limit" {
+		t.Fatalf("name=%q", mw.Name())
+	}
+	if c, ok := mw.Config().(cfg); !ok || c.Limit != 3 {
+		t.Fatalf("config=%#v", mw.Config())
+	}
+	var named NamedMiddleware = mw
+	if named.Name() != "limit" {
+		t.Fatalf("interface name=%q", named.Name())
+	}
+	// Closures from the same literal keep distinct descriptions.
+	a, b := namedMW("a"), namedMW("b")
+	if a.Name() != "a" || b.Name() != "b" {
+		t.Fatalf("names=%q %q", a.Name(), b.Name())
+	}
+}
+
+func TestDescribe_ProbeOnlyCallsDescribed(t *testing.T) {
+	calls := 0
+	plain := Middleware(func(next Handler) Handler {
+		calls++
+		return next
+	})
+	if plain.Name() == "" || plain.Config() != nil || calls != 0 {
+		t.Fatalf("undescribed middleware probed: calls=%d", calls)
+	}
+	mw := Describe("counted", 1, plain)
+	if mw.Name() != "counted" || mw.Config() != 1 || calls != 0 {
+		t.Fatalf("describing called the middleware: calls=%d", calls)
+	}
+	mw(func(c Ctx) error { return nil })
+	if calls != 1 {
+		t.Fatalf("calls=%d", calls)
+	}
+}
+
+func TestDescribe_FallbackName(t *testing.T) {
+	if got := requireAdmin().Name(); got != "app.requireAdmin" {
+		t.Fatalf("closure name=%q", got)
+	}
+	if got := Middleware(identityMW).Name(); got != "app.identityMW" {
+		t.Fatalf("func name=%q", got)
+	}
+	if requireAdmin().Config() != nil {
+		t.Fatalf("undescribed middleware has config")
+	}
+	var nilMW Middleware
+	if nilMW.Name() != "" || nilMW.Config() != nil {
+		t.Fatalf("nil middleware described")
+	}
+}
+
+func TestDescribe_NilPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic")
+		}
+	}()
+	Describe("x", nil, nil)
+}
+
+func TestFuncName(t *testing.T) {
+	cases := map[string]string{
+		"github.com/acme/api/auth.Require.func1.2": "auth.Require",
+		"main.main.func3":                          "main.main",
+		"main.Logger":                              "main.Logger",
+		"github.com/acme/api.(*T).Wrap-fm":         "api.(*T).Wrap-fm",
+	}
+	for in, want := range cases {
+		if got := funcName(in); got != want {
+			t.Fatalf("funcName(%q)=%q want %q", in, got, want)
+		}
+	}
+}
+
+func TestMiddlewareStack(t *testing.T) {
+	a := New().(*DefaultApp)
+	a.Use(namedMW("logger"))
+	a.UseOrdered(PhaseRecover, namedMW("recover"))
+	a.UseOrdered(PhaseSecurity, Describe("cors", "cfg", identityMW))
+
+	got := a.MiddlewareStack()
+	want := []MiddlewareInfo{
+		{Name: "recover", Phase: PhaseRecover},
+		{Name: "cors", Phase: PhaseSecurity, Config: "cfg"},
+		{Name: "logger", Phase: PhaseBusiness},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("stack=%+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("stack[%d]=%+v want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRouteMiddleware(t *testing.T) {
+	a := New().(*DefaultApp)
+	a.Use(namedMW("requestid"))
+	g := a.Group("/api", namedMW("auth"))
+	ok := func(c Ctx) error { return c.String(http.StatusOK, "ok") }
+	g.GET("/users", ok, requireAdmin())
+	a.GET("/plain", ok)
+
+	routes := a.Routes()
+	if got := strings.Join(routes[0].Middleware(), ","); got != "requestid,auth,app.requireAdmin" {
+		t.Fatalf("group route middleware=%q", got)
+	}
+	if got := strings.Join(routes[1].Middleware(), ","); got != "requestid" {
+		t.Fatalf("plain route middleware=%q", got)
+	}
+
+	rec := httptest.NewRecorder()
+	a.DocsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !strings.Contains(rec.Body.String(), "requestid → auth → app.requireAdmin") {
+		t.Fatalf("docs page lacks middleware: %s", rec.Body.String())
+	}
+}
+
+func TestBypassableKeepsDescription(t *testing.T) {
+	if got := Bypassable("cache", requireAdmin()).Name(); got != "cache" {
+		t.Fatalf("undescribed bypassable name=%q", got)
+	}
+	mw := Bypassable("gzip", Describe("compress", 5, identityMW))
+	if mw.Name() != "compress" || mw.Config() != 5 {
+		t.Fatalf("described bypassable=%q %v", mw.Name(), mw.Config())
+	}
+}
+
+func TestOrderWarnings(t *testing.T) {
+	if w := orderWarnings([]string{"recover", "requestid", "logger", "cors", "csrf"}); len(w) != 0 {
+		t.Fatalf("unexpected warnings: %v", w)
+	}
+	w := orderWarnings([]string{"logger", "recover", "csrf", "requestid", "cors"})
+	want := []string{
+		"logger runs before recover; panics in it are not recovered",
+		"logger runs before requestid; its log lines lack the request ID",
+		"csrf runs before cors; cross-origin preflight requests are rejected",
+	}
+	if strings.Join(w, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("warnings=%q", w)
+	}
+}
+
+func TestUseOrdered_WarnsInDevMode(t *testing.T) {
+	var logs bytes.Buffer
+	a := New(DevMode(), WithLogger(slog.New(slog.NewTextHandler(&logs, nil)))).(*DefaultApp)
+	a.UseOrdered(PhaseTelemetry, namedMW("logger"))
+	a.UseOrdered(PhaseBusiness, namedMW("requestid"))
+	if !strings.Contains(logs.String(), "logger runs before requestid") {
+		t.Fatalf("missing order warning: %s", logs.String())
+	}
+	logs.Reset()
+	a.UseOrdered(PhaseBusiness, namedMW("audit"))
+	if strings.Contains(logs.String(), "middleware order") {
+		t.Fatalf("warning repeated: %s", logs.String())
+	}
+}
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+// DevRoutesPath is the path of the route table served in development mode.
+const DevRoutesPath = "/_flash/routes"
+
+// DevMode enables development mode; it is shorthand for WithDevMode(true).
+//
+// Example:
+//
+//	a := flash.New(flash.DevMode())
+func DevMode() Option { return WithDevMode(true) }
+
+// WithDevMode enables or disables development mode, which is off by default.
+// In development mode the app:
+//   - answers browsers (see SetErrorTemplates for how they are detected) with
+//     a detailed page for 5xx handler errors, showing the error chain, the
+//     panic stack trace when middleware.Recover captured one (EnableStack),
+//     and a dump of the request headers;
+//   - answers browsers' 404s with a page listing the route table;
+//   - serves the route table (DocsHandler) at DevRoutesPath;
+//   - re-parses SetErrorTemplates templates on every render, so edits show
+//     up without a restart, and fills ErrorPageData.Details;
+//   - logs warnings about insecure settings of the Config passed to
+//     NewFromConfig, such as wildcard CORS origins with credentials.
+//
+// Development mode exposes internals; never enable it in production. Tying
+// it to an environment variable keeps it off unless explicitly requested.
+//
+// Example:
+//
+//	a := app.New(app.WithDevMode(os.Getenv("APP_ENV") == "dev"))
+func WithDevMode(enabled bool) Option {
+	return func(a *DefaultApp) { a.devMode = enabled }
+}
+
+// enableDevMode registers the development routes and logs configuration
+// warnings. New calls it after applying options.
+func (a *DefaultApp) enableDevMode() {
+	a.router.Handle(http.MethodGet, DevRoutesPath, httpHandle(a.DocsHandler()))
+	l := a.Logger()
+	l.Warn("flash: development mode is enabled; do not use it in production", "routes", DevRoutesPath)
+	for _, w := range a.configWarnings() {
+		l.Warn("flash: insecure configuration", "warning", w)
+	}
+}
+
+// configWarnings lists insecure settings of the app's Config.
+func (a *DefaultApp) configWarnings() []string {
+	cfg := a.config
+	if cfg == nil {
+		return nil
+	}
+	var out []string
+	if cfg.CORS.Enabled {
+		for _, o := range cfg.CORS.Origins {
+			if o == "*" && cfg.CORS.Credentials {
+				out = append(out, "cors: credentials are allowed for any origin (\"*\"); list trusted origins instead")
+			} else if o == "*" {
+				out = append(out, "cors: any origin (\"*\") may call the API")
+			}
+		}
+	}
+	for _, p := range cfg.TrustedProxies {
+		if _, n, err := net.ParseCIDR(p); err == nil {
+			if ones, _ := n.Mask.Size(); ones == 0 {
+				out = append(out, "trusted_proxies: "+p+" trusts X-Forwarded-For from any client")
+			}
+		}
+	}
+	if cfg.ReadHeaderTimeout == 0 && cfg.ReadTimeout == 0 {
+		out = append(out, "read_header_timeout: unset, slow clients can hold connections open indefinitely")
+	}
+	if host, _, err := net.SplitHostPort(cfg.Addr); err == nil && !isLoopback(host) {
+		out = append(out, "addr: "+cfg.Addr+" is reachable from other hosts while development mode exposes stack traces and the route table")
+	}
+	return out
+}
+
+// isLoopback reports whether host is "localhost" or a loopback IP.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// devPageData is the data of the development error page.
+type devPageData struct {
+	Status     int
+	StatusText string
+	Method     string
+	Path       string
+	Route      string
+	Errors     []string
+	Stack      string
+	Request    string
+	Routes     []*Route
+	Trace      []ctx.TraceEvent
+}
+
+// devErrorPage renders the development page for a handler error with status
+// >= 500 or a 404, or reports false for other statuses.
+func (a *DefaultApp) devErrorPage(r *http.Request, route string, status int, err error) ([]byte, bool) {
+	if status < 500 && status != http.StatusNotFound {
+		return nil, false
+	}
+	d := devPageData{
+		Status:     status,
+		StatusText: http.StatusText(status),
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Route:      route,
+	}
+	if err != nil {
+		for _, e := range (ErrorInfo{Err: err}).Chain() {
+			d.Errors = append(d.Errors, fmt.Sprintf("%s (%T)", e, e))
+		}
+		var pe *ctx.PanicError
+		if errors.As(err, &pe) {
+			d.Stack = string(pe.Stack)
+		}
+	}
+	if status == http.StatusNotFound {
+		d.Routes = a.routes
+	}
+	d.Trace, _ = ctx.TraceEventsFromContext(r.Context())
+	if dump, derr := httputil.DumpRequest(r, false); derr == nil {
+		d.Request = strings.TrimSpace(string(dump))
+	}
+	var buf bytes.Buffer
+	if terr := devPageTemplate.Execute(&buf, d); terr != nil {
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
+
+var devPageTemplate = template.Must(template.New("dev").Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><title>{{.Status}} {{.StatusText}}</title>
+<style>
+body{font-family:system-ui,sans-serif;max-width:1100px;margin:2em auto;padding:0 1em;color:#222}
+h1{color:#b00020}
+pre{background:#f6f8fa;padding:.75em;overflow:auto;font-size:.85em}
+table{border-collapse:collapse}td{padding:.2em 1em .2em 0;font-family:ui-monospace,monospace}
+.note{color:#666;font-size:.85em}
+</style></head><body>
+<h1>{{.Status}} {{.StatusText}}</h1>
+<p><code>{{.Method}} {{.Path}}</code>{{with .Route}} matched <code>{{.}}</code>{{end}}</p>
+{{with .Errors}}<h2>Error</h2><pre>{{range $i, $e := .}}{{if $i}}
+caused by: {{end}}{{$e}}{{end}}</pre>{{end}}
+{{with .Stack}}<h2>Stack trace</h2><pre>{{.}}</pre>{{end}}
+{{with .Trace}}<h2>Trace</h2><table>{{range .}}<tr><td>{{.Name}}</td><td>{{.Duration}}</td><td>{{range $i, $a := .Attrs}}{{if $i}} {{end}}{{$a}}{{end}}</td></tr>{{end}}</table>{{end}}
+{{if .Routes}}<h2>Routes</h2><table>{{range .Routes}}<tr><td>{{.Method}}</td><td>{{.Path}}</td><td>{{.Name}}</td></tr>{{end}}</table>{{end}}
+{{with .Request}}<h2>Request</h2><pre>{{.}}</pre>{{end}}
+<p class="note">Shown because development mode is enabled (flash.DevMode).</p>
+</body></html>
+`))
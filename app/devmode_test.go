@@ -0,0 +1,133 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+func quietLogger() *slog.Logger { return slog.New(slog.NewTextHandler(io.Discard, nil)) }
+
+func TestDevMode_ErrorPageWithStackAndRequest(t *testing.T) {
+	a := New(DevMode(), WithLogger(quietLogger()))
+	a.GET("/boom/:id", func(c ctx.Ctx) error {
+		return &ctx.PanicError{Value: errors.New("nil map <write>"), Stack: []byte("goroutine 1 [running]:\nmain.handler()")}
+	})
+	rec := get(a, "/boom/7", browserAccept)
+	body := rec.Body.String()
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{
+		"matched <code>/boom/:id</code>",
+		"panic: nil map &lt;write&gt; (*ctx.PanicError)",
+		"caused by: nil map &lt;write&gt; (*errors.errorString)",
+		"goroutine 1 [running]:",
+		"X-Request-Id: req-1",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("dev page lacks %q:\n%s", want, body)
+		}
+	}
+
+	// API clients still get the error handler's response.
+	if rec := get(a, "/boom/7", "application/json"); rec.Body.String() != http.StatusText(http.StatusInternalServerError) {
+		t.Fatalf("API client got %q", rec.Body.String())
+	}
+}
+
+func TestDevMode_NotFoundListsRoutes(t *testing.T) {
+	a := New(DevMode(), WithLogger(quietLogger()))
+	a.GET("/users/:id", func(c ctx.Ctx) error { return nil }).Named("users.show")
+	rec := get(a, "/nope", browserAccept)
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "<td>/users/:id</td><td>users.show</td>") {
+		t.Fatalf("404 page: %d\n%s", rec.Code, rec.Body.String())
+	}
+	// 4xx handler errors are not replaced by the dev page.
+	a.GET("/bad", func(c ctx.Ctx) error { return ctx.NewError(http.StatusBadRequest, "bad input") })
+	if rec := get(a, "/bad", browserAccept); rec.Body.String() != "bad input" {
+		t.Fatalf("400: %q", rec.Body.String())
+	}
+}
+
+func TestDevMode_RoutesEndpoint(t *testing.T) {
+	a := New(DevMode(), WithLogger(quietLogger()))
+	a.GET("/late", func(c ctx.Ctx) error { return nil })
+	rec := get(a, DevRoutesPath, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "/late") {
+		t.Fatalf("routes endpoint: %d", rec.Code)
+	}
+	if rec := get(New(WithLogger(quietLogger())), DevRoutesPath, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("routes endpoint must not exist outside dev mode: %d", rec.Code)
+	}
+}
+
+func TestDevMode_TemplateHotReload(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "404.html")
+	if err := os.WriteFile(page, []byte("v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, dev := range []bool{false, true} {
+		a := New(WithDevMode(dev), WithLogger(quietLogger()))
+		if err := a.SetErrorTemplates(os.DirFS(dir), map[int]string{http.StatusGone: "404.html"}); err != nil {
+			t.Fatal(err)
+		}
+		a.GET("/gone", func(c ctx.Ctx) error { return ctx.NewError(http.StatusGone, "") })
+		if err := os.WriteFile(page, []byte("v2"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		want := map[bool]string{false: "v1", true: "v2"}[dev]
+		if rec := get(a, "/gone", browserAccept); rec.Body.String() != want {
+			t.Fatalf("dev=%v: got %q, want %q", dev, rec.Body.String(), want)
+		}
+		_ = os.WriteFile(page, []byte("v1"), 0o644)
+	}
+}
+
+func TestDevMode_ConfigWarnings(t *testing.T) {
+	var logs bytes.Buffer
+	cfg := Config{Addr: ":8080", TrustedProxies: []string{"10.0.0.0/8", "0.0.0.0/0"}}
+	cfg.CORS.Enabled, cfg.CORS.Origins, cfg.CORS.Credentials = true, []string{"*"}, true
+	a, err := NewFromConfig(cfg, DevMode(), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = a
+	for _, want := range []string{"development mode is enabled", "cors: credentials", "0.0.0.0/0", "read_header_timeout", "addr: :8080"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Fatalf("missing warning %q in:\n%s", want, logs.String())
+		}
+	}
+	if strings.Contains(logs.String(), "10.0.0.0/8") {
+		t.Fatalf("narrow proxy range must not warn:\n%s", logs.String())
+	}
+
+	safe := Config{Addr: "127.0.0.1:8080", ReadHeaderTimeout: Duration(1e9)}
+	if w := (&DefaultApp{config: &safe}).configWarnings(); len(w) != 0 {
+		t.Fatalf("unexpected warnings: %v", w)
+	}
+	if w := (&DefaultApp{}).configWarnings(); w != nil {
+		t.Fatalf("no config, got %v", w)
+	}
+}
+
+func TestDevMode_OffByDefault(t *testing.T) {
+	a := New(WithLogger(quietLogger()))
+	if err := a.SetErrorTemplates(fstest.MapFS{"e.html": {Data: []byte("{{.Details}}")}}, map[int]string{0: "e.html"}); err != nil {
+		t.Fatal(err)
+	}
+	a.GET("/boom", func(c ctx.Ctx) error { return errors.New("secret") })
+	if rec := get(a, "/boom", browserAccept); rec.Body.String() != "" {
+		t.Fatalf("details leaked outside dev mode: %q", rec.Body.String())
+	}
+}
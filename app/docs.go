@@ -0,0 +1,237 @@
+package app
+
+import (
+	"encoding/json"
+	"html"
+	"html/template"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// DocInfo is lightweight, human-oriented documentation attached to a route with
+// Route.Doc. It powers the page served by DocsHandler; it is not an OpenAPI
+// description and is never consulted when serving the route itself.
+//
+// Example:
+//
+//	a.POST("/users", CreateUser).Doc(app.DocInfo{
+//		Summary:         "Creates a user. Returns **409** when the email is taken.",
+//		RequestExample:  map[string]any{"name": "Ada", "email": "ada@example.com"},
+//		ResponseExample: map[string]any{"id": 42, "name": "Ada"},
+//	})
+type DocInfo struct {
+	// Summary describes the route. A small Markdown subset is rendered:
+	// paragraphs, "- " lists, `code` and **bold**.
+	Summary string
+	// RequestExample is an example request body. Strings and []byte are shown
+	// verbatim; other values are rendered as indented JSON.
+	RequestExample any
+	// ResponseExample is an example response body, rendered like RequestExample.
+	ResponseExample any
+}
+
+// Route describes a registered route. It is returned by the route registration
+// methods so documentation can be attached fluently.
+type Route struct {
+	Method   string // HTTP method, or "ANY" for routes registered with ANY
+	Path     string // full route pattern, including any group prefix
+	Name     string // optional name, e.g. "users.show" (set by Named or Resource)
+	doc      *DocInfo
+	bypass   []string
+	meta     map[string]any
+	produces []string
+	reqType  reflect.Type
+	respType reflect.Type
+	// middleware names the middleware around the handler (see Middleware).
+	middleware []string
+	// mounted marks Mount/HandleHTTP handlers, which skip global middleware
+	// without WithMountMiddleware.
+	mounted bool
+}
+
+// Named sets the route's name and returns the route.
+//
+// Example:
+//
+//	a.GET("/users/:id", ShowUser).Named("users.show")
+func (r *Route) Named(name string) *Route {
+	r.Name = name
+	return r
+}
+
+// Doc attaches documentation to the route and returns the route.
+//
+// Example:
+//
+//	a.GET("/users/:id", ShowUser).Doc(app.DocInfo{Summary: "Fetches a user by `id`."})
+func (r *Route) Doc(d DocInfo) *Route {
+	r.doc = &d
+	return r
+}
+
+// DocInfo returns the documentation attached with Doc, if any.
+func (r *Route) DocInfo() (DocInfo, bool) {
+	if r.doc == nil {
+		return DocInfo{}, false
+	}
+	return *r.doc, true
+}
+
+// addRoute records a route in registration order.
+func (a *DefaultApp) addRoute(r *Route) *Route {
+	a.routes = append(a.routes, r)
+	if subs := a.eventSubs(); subs != nil {
+		a.emit(subs, RouteRegisteredEvent{Route: r})
+	}
+	return r
+}
+
+// Routes returns the routes registered with the route methods (GET, Handle,
+// ANY, group routes, ...) in registration order. Routes added with HandleHTTP,
+// Mount or the Static helpers are not included.
+func (a *DefaultApp) Routes() []*Route {
+	return append([]*Route(nil), a.routes...)
+}
+
+// DocsHandler returns an http.Handler serving a human-readable HTML page that
+// lists every route with its DocInfo: rendered summaries, example bodies and a
+// ready-to-run curl command. It is meant for development; mount it only in
+// non-production builds since it exposes the full route table.
+//
+// Example:
+//
+//	if os.Getenv("APP_ENV") == "dev" {
+//		a.Mount("/_docs", a.DocsHandler())
+//	}
+func (a *DefaultApp) DocsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		base := scheme + "://" + r.Host
+		entries := make([]docEntry, 0, len(a.routes))
+		for _, rt := range a.routes {
+			e := docEntry{Method: rt.Method, Path: rt.Path, Produces: strings.Join(rt.produces, ", "), Middleware: strings.Join(rt.middleware, " → ")}
+			if d, ok := rt.DocInfo(); ok {
+				e.Summary = template.HTML(renderMarkdown(d.Summary))
+				e.Request = docExample(d.RequestExample)
+				e.Response = docExample(d.ResponseExample)
+			}
+			e.Curl = curlCommand(rt.Method, base+rt.Path, e.Request)
+			entries = append(entries, e)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = docsTemplate.Execute(w, entries)
+	})
+}
+
+type docEntry struct {
+	Method, Path      string
+	Produces          string
+	Middleware        string
+	Summary           template.HTML
+	Request, Response string
+	Curl              string
+}
+
+// docExample renders an example body for display.
+func docExample(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "<unrenderable example: " + err.Error() + ">"
+	}
+	return string(b)
+}
+
+// curlCommand builds a copy-pasteable curl invocation for the route.
+func curlCommand(method, url, body string) string {
+	var b strings.Builder
+	b.WriteString("curl")
+	switch method {
+	case http.MethodGet, "ANY":
+	case http.MethodHead:
+		b.WriteString(" -I")
+	default:
+		b.WriteString(" -X " + method)
+	}
+	b.WriteString(" " + shellQuote(url))
+	if body != "" {
+		if json.Valid([]byte(body)) {
+			b.WriteString(" -H 'Content-Type: application/json'")
+		}
+		b.WriteString(" -d " + shellQuote(body))
+	}
+	return b.String()
+}
+
+// shellQuote single-quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+var (
+	mdCode = regexp.MustCompile("`([^`]+)`")
+	mdBold = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+)
+
+// renderMarkdown renders the small Markdown subset documented on DocInfo.
+// Input is HTML-escaped first, so summaries cannot inject markup.
+func renderMarkdown(src string) string {
+	inline := func(s string) string {
+		s = html.EscapeString(s)
+		s = mdCode.ReplaceAllString(s, "<code>$1</code>")
+		return mdBold.ReplaceAllString(s, "<strong>$1</strong>")
+	}
+	var out strings.Builder
+	for _, block := range strings.Split(strings.TrimSpace(src), "\n\n") {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+		if lines[0] == "" {
+			continue
+		}
+		if strings.HasPrefix(lines[0], "- ") {
+			out.WriteString("<ul>")
+			for _, l := range lines {
+				out.WriteString("<li>" + inline(strings.TrimPrefix(strings.TrimSpace(l), "- ")) + "</li>")
+			}
+			out.WriteString("</ul>")
+			continue
+		}
+		out.WriteString("<p>" + inline(strings.Join(lines, " ")) + "</p>")
+	}
+	return out.String()
+}
+
+var docsTemplate = template.Must(template.New("docs").Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><title>API routes</title>
+<style>
+body{font-family:system-ui,sans-serif;max-width:960px;margin:2em auto;padding:0 1em;color:#222}
+section{border-top:1px solid #ddd;padding:.5em 0}
+h2{font-size:1.05em;font-family:ui-monospace,monospace}
+.m{display:inline-block;min-width:5em;color:#fff;background:#555;border-radius:3px;padding:0 .4em;text-align:center}
+pre{background:#f5f5f5;padding:.6em;overflow-x:auto}
+</style></head><body>
+<h1>API routes</h1>
+{{range .}}<section>
+<h2><span class="m">{{.Method}}</span> {{.Path}}</h2>
+{{.Summary}}
+{{if .Produces}}<p>Produces: <code>{{.Produces}}</code></p>{{end}}
+{{if .Middleware}}<p>Middleware: <code>{{.Middleware}}</code></p>{{end}}
+{{if .Request}}<h3>Request</h3><pre>{{.Request}}</pre>{{end}}
+{{if .Response}}<h3>Response</h3><pre>{{.Response}}</pre>{{end}}
+<pre>{{.Curl}}</pre>
+</section>
+{{else}}<p>No routes registered.</p>{{end}}
+</body></html>
+`))
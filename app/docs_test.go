@@ -0,0 +1,96 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRoutes_RecordedWithDocs(t *testing.T) {
+	a := New().(*DefaultApp)
+	h := func(c Ctx) error { return nil }
+	a.GET("/users/:id", h).Doc(DocInfo{Summary: "Fetch"})
+	a.POST("/users", h)
+	a.ANY("/hook", h)
+	a.Handle("REPORT", "/r", h)
+	api := a.Group("/api")
+	api.DELETE("/items/:id", h).Doc(DocInfo{Summary: "Delete"})
+	a.HandleHTTP(http.MethodGet, "/raw", http.NotFoundHandler()) // not recorded
+
+	got := a.Routes()
+	want := []string{"GET /users/:id", "POST /users", "ANY /hook", "REPORT /r", "DELETE /api/items/:id"}
+	if len(got) != len(want) {
+		t.Fatalf("routes=%d want %d", len(got), len(want))
+	}
+	for i, r := range got {
+		if r.Method+" "+r.Path != want[i] {
+			t.Fatalf("route %d = %s %s, want %s", i, r.Method, r.Path, want[i])
+		}
+	}
+	if d, ok := got[0].DocInfo(); !ok || d.Summary != "Fetch" {
+		t.Fatalf("doc not attached: %+v %v", d, ok)
+	}
+	if _, ok := got[1].DocInfo(); ok {
+		t.Fatalf("undocumented route reports docs")
+	}
+	if d, _ := got[4].DocInfo(); d.Summary != "Delete" {
+		t.Fatalf("group route doc missing")
+	}
+	// Routes returns a copy.
+	got[0] = nil
+	if a.Routes()[0] == nil {
+		t.Fatalf("Routes exposed internal slice")
+	}
+}
+
+func TestDocsHandler_RendersPage(t *testing.T) {
+	a := New()
+	h := func(c Ctx) error { return nil }
+	a.POST("/users", h).Doc(DocInfo{
+		Summary:         "Creates a **user** with `name`.\n\n- returns 201\n- <script>x</script>",
+		RequestExample:  map[string]any{"name": "O'Brien"},
+		ResponseExample: `{"id":1}`,
+	})
+	a.HEAD("/health", h)
+	a.GET("/plain", h).Doc(DocInfo{RequestExample: []byte("raw body")})
+	a.Mount("/_docs", a.DocsHandler())
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_docs", nil))
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("code=%d ct=%q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{
+		"<strong>user</strong>", "<code>name</code>", "<li>returns 201</li>",
+		"&lt;script&gt;x&lt;/script&gt;",
+		"curl -X POST &#39;http://example.com/users&#39; -H &#39;Content-Type: application/json&#39;",
+		`O&#39;\&#39;&#39;Brien`,
+		"{&#34;id&#34;:1}",
+		"curl -I &#39;http://example.com/health&#39;",
+		"raw body",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("page missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "<script>") {
+		t.Fatalf("summary markup not escaped")
+	}
+}
+
+func TestDocsHandler_Empty(t *testing.T) {
+	a := New()
+	rec := httptest.NewRecorder()
+	a.DocsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !strings.Contains(rec.Body.String(), "No routes registered") {
+		t.Fatalf("unexpected body: %s", rec.Body.String())
+	}
+}
+
+func TestDocExample_Unrenderable(t *testing.T) {
+	if got := docExample(make(chan int)); !strings.HasPrefix(got, "<unrenderable example") {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,178 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// HealthCheck reports whether a dependency (database, queue, ...) is usable.
+// A non-nil error fails the readiness probe.
+type HealthCheck func(ctx context.Context) error
+
+// ShutdownHook releases resources during Shutdown, after the server stopped
+// accepting requests.
+type ShutdownHook func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check HealthCheck
+}
+
+// BeginDrain marks the app as draining ahead of a shutdown or blue/green
+// cut-over. While draining:
+//   - ReadinessHandler responds 503 so load balancers stop routing new traffic
+//   - LivenessHandler keeps responding 200 so the process is not restarted
+//   - responses carry "Connection: close" when WithConnectionCloseOnDrain is set,
+//     nudging keep-alive clients onto other instances
+//
+// Draining cannot be undone; it is safe to call more than once and from any goroutine.
+//
+// Example (manual trigger from an admin endpoint):
+//
+//	admin.POST("/drain", func(c app.Ctx) error {
+//		a.BeginDrain()
+//		return c.String(http.StatusAccepted, "draining")
+//	})
+func (a *DefaultApp) BeginDrain() { a.draining.Store(true) }
+
+// Draining reports whether BeginDrain (or Shutdown) has been called.
+func (a *DefaultApp) Draining() bool { return a.draining.Load() }
+
+// LivenessHandler returns a handler that always responds 200 "ok", including
+// while draining. Mount it for liveness probes.
+//
+// Example:
+//
+//	a.HandleHTTP(http.MethodGet, "/livez", a.LivenessHandler())
+func (a *DefaultApp) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeProbe(w, http.StatusOK, "ok")
+	})
+}
+
+// ReadinessHandler returns a handler that responds 200 "ok" until the app starts
+// draining and 503 "draining" afterwards. Health checks added with
+// AddHealthCheck run on every probe; the first failure responds 503
+// "unhealthy: <name>". Mount it for readiness probes.
+//
+// Example:
+//
+//	a.HandleHTTP(http.MethodGet, "/readyz", a.ReadinessHandler())
+func (a *DefaultApp) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.Draining() {
+			writeProbe(w, http.StatusServiceUnavailable, "draining")
+			return
+		}
+		if name, err := a.checkHealth(r.Context()); err != nil {
+			writeProbe(w, http.StatusServiceUnavailable, "unhealthy: "+name)
+			return
+		}
+		writeProbe(w, http.StatusOK, "ok")
+	})
+}
+
+// checkHealth runs the health checks in registration order and returns the
+// name and error of the first failing one.
+func (a *DefaultApp) checkHealth(ctx context.Context) (string, error) {
+	a.hooksMu.Lock()
+	checks := a.healthChecks
+	a.hooksMu.Unlock()
+	for _, hc := range checks {
+		if err := hc.check(ctx); err != nil {
+			return hc.name, err
+		}
+	}
+	return "", nil
+}
+
+// AddHealthCheck registers a named check consulted by ReadinessHandler.
+// Checks run in registration order with the probe request's context.
+//
+// Example:
+//
+//	a.AddHealthCheck("postgres", func(ctx context.Context) error { return db.PingContext(ctx) })
+func (a *DefaultApp) AddHealthCheck(name string, check HealthCheck) {
+	if check == nil {
+		return
+	}
+	a.hooksMu.Lock()
+	a.healthChecks = append(a.healthChecks[:len(a.healthChecks):len(a.healthChecks)], namedCheck{name, check})
+	a.hooksMu.Unlock()
+}
+
+// OnShutdown registers a hook run by Shutdown after the server has stopped.
+// Hooks run in reverse registration order, so resources are released in the
+// opposite order they were set up.
+//
+// Example:
+//
+//	a.OnShutdown(func(ctx context.Context) error { return db.Close() })
+func (a *DefaultApp) OnShutdown(hook ShutdownHook) {
+	if hook == nil {
+		return
+	}
+	a.hooksMu.Lock()
+	a.shutdownHooks = append(a.shutdownHooks, hook)
+	a.hooksMu.Unlock()
+}
+
+// writeProbe writes a small uncached plain-text probe response.
+func writeProbe(w http.ResponseWriter, status int, body string) {
+	h := w.Header()
+	h.Set("Content-Type", "text/plain; charset=utf-8")
+	h.Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(body))
+}
+
+// Shutdown drains and gracefully stops srv. It calls BeginDrain (and tells
+// systemd STOPPING=1 under a Type=notify service), waits for
+// delay (giving load balancers time to observe the failing readiness probe),
+// then calls srv.Shutdown(ctx), waits for pending response hooks (see
+// WaitResponseHooks) and finally runs the OnShutdown hooks. If ctx
+// ends during the delay, shutdown starts immediately. Hooks run even if the
+// server shutdown fails; all errors are joined.
+//
+// Example:
+//
+//	srv := &http.Server{Addr: ":8080", Handler: a}
+//	go func() { _ = srv.ListenAndServe() }()
+//	<-sigCh
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	_ = a.Shutdown(ctx, srv, 5*time.Second)
+func (a *DefaultApp) Shutdown(ctx context.Context, srv *http.Server, delay time.Duration) error {
+	a.BeginDrain()
+	_ = SystemdNotify("STOPPING=1")
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+		}
+	}
+	err := srv.Shutdown(ctx)
+	return errors.Join(err, a.finishShutdown(ctx))
+}
+
+// finishShutdown waits for pending response hooks, emits ShutdownEvent and
+// then runs the OnShutdown hooks in reverse registration order, joining their
+// errors.
+func (a *DefaultApp) finishShutdown(ctx context.Context) error {
+	hookErr := a.WaitResponseHooks(ctx)
+	if subs := a.eventSubs(); subs != nil {
+		a.emit(subs, ShutdownEvent{Time: time.Now()})
+	}
+	a.hooksMu.Lock()
+	hooks := a.shutdownHooks
+	a.hooksMu.Unlock()
+	errs := []error{hookErr}
+	for i := len(hooks) - 1; i >= 0; i-- {
+		errs = append(errs, hooks[i](ctx))
+	}
+	return errors.Join(errs...)
+}
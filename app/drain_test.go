@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDrain_ReadinessFlipsLivenessStays(t *testing.T) {
+	a := New()
+	a.HandleHTTP(http.MethodGet, "/livez", a.LivenessHandler())
+	a.HandleHTTP(http.MethodGet, "/readyz", a.ReadinessHandler())
+	probe := func(path string) (int, string) {
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code, rec.Body.String()
+	}
+	if code, body := probe("/readyz"); code != http.StatusOK || body != "ok" {
+		t.Fatalf("ready before drain: %d %q", code, body)
+	}
+	a.BeginDrain()
+	a.BeginDrain() // idempotent
+	if !a.Draining() {
+		t.Fatalf("expected draining")
+	}
+	if code, body := probe("/readyz"); code != http.StatusServiceUnavailable || body != "draining" {
+		t.Fatalf("ready during drain: %d %q", code, body)
+	}
+	if code, body := probe("/livez"); code != http.StatusOK || body != "ok" {
+		t.Fatalf("live during drain: %d %q", code, body)
+	}
+}
+
+func TestDrain_ConnectionCloseOption(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		a := New(WithConnectionCloseOnDrain(enabled))
+		a.GET("/", func(c Ctx) error { return c.String(http.StatusOK, "x") })
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Header().Get("Connection") != "" {
+			t.Fatalf("Connection header set before drain")
+		}
+		a.BeginDrain()
+		rec = httptest.NewRecorder()
+		a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if got := rec.Header().Get("Connection") == "close"; got != enabled {
+			t.Fatalf("enabled=%v: Connection=%q", enabled, rec.Header().Get("Connection"))
+		}
+	}
+}
+
+func TestDrain_ShutdownDrainsThenStopsServer(t *testing.T) {
+	a := New()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("listen: %v", err)
+	}
+	srv := &http.Server{Handler: a}
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ln) }()
+
+	start := time.Now()
+	if err := a.Shutdown(context.Background(), srv, 20*time.Millisecond); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Fatalf("Shutdown returned before the drain delay")
+	}
+	if !a.Draining() {
+		t.Fatalf("Shutdown should begin draining")
+	}
+	if err := <-done; !errors.Is(err, http.ErrServerClosed) {
+		t.Fatalf("Serve returned %v", err)
+	}
+}
+
+func TestDrain_ShutdownDelayCutShortByContext(t *testing.T) {
+	a := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	_ = a.Shutdown(ctx, &http.Server{}, time.Hour)
+	if time.Since(start) > time.Second {
+		t.Fatalf("canceled context should skip the delay")
+	}
+}
+
+func TestDrain_HealthChecksGateReadiness(t *testing.T) {
+	a := New()
+	var dbErr error
+	a.AddHealthCheck("cache", func(context.Context) error { return nil })
+	a.AddHealthCheck("db", func(context.Context) error { return dbErr })
+	a.AddHealthCheck("nil", nil) // ignored
+	probe := func() (int, string) {
+		rec := httptest.NewRecorder()
+		a.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code, rec.Body.String()
+	}
+	if code, body := probe(); code != http.StatusOK || body != "ok" {
+		t.Fatalf("healthy: %d %q", code, body)
+	}
+	dbErr = errors.New("down")
+	if code, body := probe(); code != http.StatusServiceUnavailable || body != "unhealthy: db" {
+		t.Fatalf("unhealthy: %d %q", code, body)
+	}
+}
+
+func TestDrain_ShutdownRunsHooksInReverse(t *testing.T) {
+	a := New()
+	var order []string
+	a.OnShutdown(func(context.Context) error { order = append(order, "first"); return errors.New("close failed") })
+	a.OnShutdown(func(context.Context) error { order = append(order, "second"); return nil })
+	a.OnShutdown(nil) // ignored
+	err := a.Shutdown(context.Background(), &http.Server{}, 0)
+	if len(order) != 2 || order[0] != "second" || order[1] != "first" {
+		t.Fatalf("hook order = %v", order)
+	}
+	if err == nil || err.Error() != "close failed" {
+		t.Fatalf("expected joined hook error, got %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+// ErrorPageData is the data passed to error page templates.
+type ErrorPageData struct {
+	Status     int    // HTTP status code, e.g. 404
+	StatusText string // standard status text, e.g. "Not Found"
+	Message    string // client-facing message: the HTTPError message or StatusText
+	Path       string // request path
+	RequestID  string // X-Request-ID of the response or request, if any
+	Details    string // error text; only set with WithDevMode
+}
+
+// SetErrorTemplates renders HTML error pages for browsers. pages maps status
+// codes to template files in fsys; key 0 is the page for statuses without
+// their own entry. Templates are html/template files executed with
+// ErrorPageData, parsed once here so mistakes surface at startup.
+//
+// A page is rendered instead of the usual response when the request's Accept
+// header prefers text/html over JSON, as browsers' does, and the response has
+// not started: for handler errors (the status of a *ctx.HTTPError, else 500)
+// and for 404 Not Found and 405 Method Not Allowed responses. API clients keep
+// getting the configured error and NotFound handlers' responses. The error
+// handler still runs after a page is rendered, so errors are logged as
+// before; it sees the response as written.
+//
+// Example:
+//
+//	//go:embed errors/*.html
+//	var errorPages embed.FS
+//
+//	err := a.SetErrorTemplates(errorPages, map[int]string{
+//		404: "errors/404.html",
+//		0:   "errors/500.html", // everything else
+//	})
+//	// errors/404.html: <h1>{{.StatusText}}</h1><p>{{.Message}}</p><small>{{.RequestID}}</small>
+func (a *DefaultApp) SetErrorTemplates(fsys fs.FS, pages map[int]string) error {
+	et := &errorTemplates{fsys: fsys, files: pages, parsed: make(map[int]*template.Template, len(pages))}
+	for status, name := range pages {
+		t, err := template.ParseFS(fsys, name)
+		if err != nil {
+			return fmt.Errorf("flash: error template for %d: %w", status, err)
+		}
+		et.parsed[status] = t
+	}
+	a.errorPages = et
+	return nil
+}
+
+// errorTemplates holds the templates registered with SetErrorTemplates.
+type errorTemplates struct {
+	fsys   fs.FS
+	files  map[int]string
+	parsed map[int]*template.Template
+}
+
+// lookup returns the template for status, or the default (key 0). With
+// reload, the file is parsed again, falling back to the startup version if
+// that fails.
+func (et *errorTemplates) lookup(status int, reload bool) *template.Template {
+	if _, ok := et.parsed[status]; !ok {
+		status = 0
+	}
+	t := et.parsed[status]
+	if t != nil && reload {
+		if fresh, err := template.ParseFS(et.fsys, et.files[status]); err == nil {
+			t = fresh
+		}
+	}
+	return t
+}
+
+// errorPage renders the error page for status if r prefers HTML: the
+// development page (see WithDevMode) or the template set with
+// SetErrorTemplates. hdr is the response header, consulted for the request
+// ID; route is the matched route pattern, if any.
+func (a *DefaultApp) errorPage(r *http.Request, hdr http.Header, route string, status int, message string, err error) ([]byte, bool) {
+	if a.errorPages == nil && !a.devMode || !prefersHTML(r.Header.Get("Accept")) {
+		return nil, false
+	}
+	if a.devMode {
+		if page, ok := a.devErrorPage(r, route, status, err); ok {
+			hdr.Set("X-Content-Type-Options", "nosniff")
+			return page, true
+		}
+	}
+	if a.errorPages == nil {
+		return nil, false
+	}
+	t := a.errorPages.lookup(status, a.devMode)
+	if t == nil {
+		return nil, false
+	}
+	data := ErrorPageData{
+		Status:     status,
+		StatusText: http.StatusText(status),
+		Message:    message,
+		Path:       r.URL.Path,
+		RequestID:  hdr.Get("X-Request-ID"),
+	}
+	if data.Message == "" {
+		data.Message = data.StatusText
+	}
+	if data.RequestID == "" {
+		data.RequestID = r.Header.Get("X-Request-ID")
+	}
+	if a.devMode && err != nil {
+		data.Details = err.Error()
+	}
+	var buf bytes.Buffer
+	if terr := t.Execute(&buf, data); terr != nil {
+		a.Logger().Error("flash: error template failed", "status", status, "err", terr)
+		return nil, false
+	}
+	hdr.Set("X-Content-Type-Options", "nosniff")
+	return buf.Bytes(), true
+}
+
+// renderErrorPage writes the error page for status to w, reporting whether
+// there was one. Used for 404 and 405 responses.
+func (a *DefaultApp) renderErrorPage(w http.ResponseWriter, r *http.Request, status int) bool {
+	page, ok := a.errorPage(r, w.Header(), "", status, "", nil)
+	if !ok {
+		return false
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(page)
+	return true
+}
+
+// renderErrorPageCtx writes the error page for err on c, if there is one.
+func (a *DefaultApp) renderErrorPageCtx(c ctx.Ctx, err error) {
+	if a.errorPages == nil && !a.devMode || c.WroteHeader() {
+		return
+	}
+	status, message := http.StatusInternalServerError, ""
+	var he *ctx.HTTPError
+	if errors.As(err, &he) && he.Code > 0 {
+		status, message = he.Code, he.Message
+	}
+	if page, ok := a.errorPage(c.Request(), c.ResponseWriter().Header(), c.Route(), status, message, err); ok {
+		_, _ = c.Send(status, "text/html; charset=utf-8", page)
+	}
+}
+
+// prefersHTML reports whether an Accept header names text/html and ranks it
+// at least as high as JSON. Wildcard-only headers ("*/*", as sent by curl and
+// most HTTP libraries) do not count.
+func prefersHTML(accept string) bool {
+	if !strings.Contains(strings.ToLower(accept), "text/html") {
+		return false
+	}
+	html := acceptQuality(accept, "text/html")
+	return html > 0 && html >= acceptQuality(accept, "application/json")
+}
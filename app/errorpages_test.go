@@ -0,0 +1,148 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+const browserAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+
+var testErrorTemplates = fstest.MapFS{
+	"errors/404.html": {Data: []byte(`<h1>{{.Status}} {{.StatusText}}</h1><p>{{.Message}}</p><i>{{.Path}}</i><small>{{.RequestID}}</small>`)},
+	"errors/500.html": {Data: []byte(`<h1>{{.Status}}</h1><p>{{.Message}}</p>{{with .Details}}<pre>{{.}}</pre>{{end}}`)},
+}
+
+func errorPagesApp(t *testing.T, opts ...Option) App {
+	t.Helper()
+	a := New(opts...)
+	if err := a.SetErrorTemplates(testErrorTemplates, map[int]string{404: "errors/404.html", 0: "errors/500.html"}); err != nil {
+		t.Fatal(err)
+	}
+	a.GET("/boom", func(c ctx.Ctx) error { return errors.New("db: <secret> down") })
+	a.GET("/gone", func(c ctx.Ctx) error { return ctx.NewError(http.StatusGone, "moved away") })
+	return a
+}
+
+func get(a http.Handler, path, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	req.Header.Set("X-Request-ID", "req-1")
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestErrorTemplates_BrowserGetsPages(t *testing.T) {
+	a := errorPagesApp(t)
+
+	rec := get(a, "/missing", browserAccept)
+	if rec.Code != http.StatusNotFound || rec.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("404: %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if want := `<h1>404 Not Found</h1><p>Not Found</p><i>/missing</i><small>req-1</small>`; rec.Body.String() != want {
+		t.Fatalf("404 body %q", rec.Body.String())
+	}
+
+	rec = get(a, "/gone", browserAccept)
+	if rec.Code != http.StatusGone || rec.Body.String() != `<h1>410</h1><p>moved away</p>` {
+		t.Fatalf("410: %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = get(a, "/boom", browserAccept)
+	if rec.Code != http.StatusInternalServerError || rec.Body.String() != `<h1>500</h1><p>Internal Server Error</p>` {
+		t.Fatalf("500 without dev mode must hide details: %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestErrorTemplates_DevModeDetails(t *testing.T) {
+	a := errorPagesApp(t, WithDevMode(true), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	a.GET("/taken", func(c ctx.Ctx) error {
+		return ctx.NewError(http.StatusConflict, "taken").WithErr(errors.New("unique <email>"))
+	})
+	rec := get(a, "/taken", browserAccept)
+	if rec.Body.String() != "<h1>409</h1><p>taken</p><pre>taken: unique &lt;email&gt;</pre>" {
+		t.Fatalf("dev mode details missing or unescaped: %q", rec.Body.String())
+	}
+}
+
+func TestErrorTemplates_APIClientsUnchanged(t *testing.T) {
+	a := errorPagesApp(t)
+	for _, accept := range []string{"", "*/*", "application/json", "application/json, text/html;q=0.5"} {
+		rec := get(a, "/gone", accept)
+		if rec.Code != http.StatusGone || rec.Body.String() != "moved away" {
+			t.Fatalf("Accept %q: %d %q", accept, rec.Code, rec.Body.String())
+		}
+		if rec = get(a, "/missing", accept); strings.Contains(rec.Body.String(), "<h1>") {
+			t.Fatalf("Accept %q: got HTML 404 %q", accept, rec.Body.String())
+		}
+	}
+}
+
+func TestErrorTemplates_ErrorHandlerStillRuns(t *testing.T) {
+	a := errorPagesApp(t)
+	var info ErrorInfo
+	a.SetErrorHandlerV2(func(c ctx.Ctx, i ErrorInfo) { info = i })
+	rec := get(a, "/gone", browserAccept)
+	if rec.Code != http.StatusGone || !info.Written || info.Status != http.StatusGone || info.Err == nil {
+		t.Fatalf("handler saw %+v, response %d", info, rec.Code)
+	}
+}
+
+func TestErrorTemplates_MethodNotAllowedAndFallback(t *testing.T) {
+	a := errorPagesApp(t)
+	req := httptest.NewRequest(http.MethodPost, "/boom", nil)
+	req.Header.Set("Accept", browserAccept)
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed || rec.Body.String() != `<h1>405</h1><p>Method Not Allowed</p>` {
+		t.Fatalf("405: %d %q", rec.Code, rec.Body.String())
+	}
+
+	// Without a page for the status (and no default), the handlers respond.
+	b := New()
+	if err := b.SetErrorTemplates(testErrorTemplates, map[int]string{404: "errors/404.html"}); err != nil {
+		t.Fatal(err)
+	}
+	b.GET("/gone", func(c ctx.Ctx) error { return ctx.NewError(http.StatusGone, "moved away") })
+	if rec := get(b, "/gone", browserAccept); rec.Body.String() != "moved away" {
+		t.Fatalf("fallback: %q", rec.Body.String())
+	}
+}
+
+func TestSetErrorTemplates_Errors(t *testing.T) {
+	if err := New().SetErrorTemplates(testErrorTemplates, map[int]string{500: "nope.html"}); err == nil {
+		t.Fatalf("expected error for missing template")
+	}
+	bad := fstest.MapFS{"bad.html": {Data: []byte("{{.Status")}}
+	if err := New().SetErrorTemplates(bad, map[int]string{500: "bad.html"}); err == nil {
+		t.Fatalf("expected parse error")
+	}
+}
+
+func TestPrefersHTML(t *testing.T) {
+	cases := map[string]bool{
+		"":                                  false,
+		"*/*":                               false,
+		"text/html":                         true,
+		browserAccept:                       true,
+		"text/html;q=0":                     false,
+		"application/json":                  false,
+		"application/json, text/html;q=0.5": false,
+		"text/html, application/json":       true,
+	}
+	for accept, want := range cases {
+		if got := prefersHTML(accept); got != want {
+			t.Fatalf("prefersHTML(%q) = %v, want %v", accept, got, want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/goflash/flash/v2/ctx"
@@ -13,6 +14,9 @@ import (
 // Behavior:
 //   - If the handler/middleware already wrote the header, this function does nothing
 //     to avoid corrupting a streaming or partially-sent response.
+//   - If err is (or wraps) a *ctx.HTTPError, its Code and Message are written.
+//   - If err is a *ctx.PanicError, a generic 500 is written with
+//     X-Content-Type-Options: nosniff; the panic value is never exposed.
 //   - Otherwise, it writes status 500 with a plain text body of
 //     http.StatusText(http.StatusInternalServerError).
 //
@@ -37,6 +41,19 @@ func defaultErrorHandler(c ctx.Ctx, err error) {
 	if c.WroteHeader() {
 		return
 	}
+	var he *ctx.HTTPError
+	if errors.As(err, &he) && he.Code > 0 {
+		msg := he.Message
+		if msg == "" {
+			msg = http.StatusText(he.Code)
+		}
+		_ = c.String(he.Code, msg)
+		return
+	}
+	var pe *ctx.PanicError
+	if errors.As(err, &pe) {
+		c.Header("X-Content-Type-Options", "nosniff")
+	}
 	_ = c.String(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
 }
 
@@ -60,3 +77,85 @@ func methodNotAllowedHandler() http.Handler {
 		_, _ = w.Write([]byte(http.StatusText(http.StatusMethodNotAllowed)))
 	})
 }
+
+// ErrorInfo describes a failed request to an ErrorHandlerV2. It is built only on
+// the error path, so successful requests pay nothing for it.
+type ErrorInfo struct {
+	// Err is the error returned by the handler chain.
+	Err error
+	// Method is the request method.
+	Method string
+	// Route is the matched route pattern (e.g., "/users/:id").
+	Route string
+	// Written reports whether the response header was already sent. Handlers
+	// should not attempt to write a new status when it is true.
+	Written bool
+	// Status is the status code already written, or 0 when Written is false.
+	Status int
+}
+
+// Chain returns Err followed by every error it wraps, depth-first. Errors
+// joined with errors.Join (or wrapped with multiple %w verbs) are all visited.
+//
+// Example:
+//
+//	for _, e := range info.Chain() {
+//		logger.Debug("cause", "type", fmt.Sprintf("%T", e), "err", e)
+//	}
+func (i ErrorInfo) Chain() []error {
+	var out []error
+	var walk func(error)
+	walk = func(err error) {
+		if err == nil {
+			return
+		}
+		out = append(out, err)
+		switch u := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, e := range u.Unwrap() {
+				walk(e)
+			}
+		case interface{ Unwrap() error }:
+			walk(u.Unwrap())
+		}
+	}
+	walk(i.Err)
+	return out
+}
+
+// ErrorHandlerV2 handles errors returned from handlers and receives an ErrorInfo
+// with the response state and matched route. When set via SetErrorHandlerV2 it
+// takes precedence over the ErrorHandler.
+//
+// Example:
+//
+//	a.SetErrorHandlerV2(func(c app.Ctx, info app.ErrorInfo) {
+//		logger := ctx.LoggerFromContext(c.Context())
+//		logger.Error("request failed", "route", info.Route, "err", info.Err)
+//		if info.Written {
+//			return // response already started; avoid superfluous WriteHeader
+//		}
+//		_ = c.String(http.StatusInternalServerError, "internal error")
+//	})
+type ErrorHandlerV2 func(c ctx.Ctx, info ErrorInfo)
+
+// newErrorInfo captures the request and response state for err.
+func newErrorInfo(c ctx.Ctx, err error) ErrorInfo {
+	info := ErrorInfo{Err: err, Method: c.Method(), Route: c.Route(), Written: c.WroteHeader()}
+	if info.Written {
+		info.Status = c.StatusCode()
+	}
+	return info
+}
+
+// handleError renders an HTML error page when configured (see
+// SetErrorTemplates) and dispatches err to the configured ErrorHandlerV2,
+// falling back to the ErrorHandler.
+func (a *DefaultApp) handleError(c ctx.Ctx, err error) {
+	a.renderErrorPageCtx(c, err)
+	if h := a.OnErrorV2; h != nil {
+		h(c, newErrorInfo(c, err))
+		return
+	}
+	a.ErrorHandler()(c, err)
+}
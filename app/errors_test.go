@@ -1,10 +1,14 @@
 package app
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/goflash/flash/v2/ctx"
 )
 
 func TestDefaultErrorHandlerNoDoubleWrite(t *testing.T) {
@@ -31,3 +35,79 @@ func TestMethodNotAllowedHandler(t *testing.T) {
 		t.Fatalf("expected 405, got %d", rec.Code)
 	}
 }
+
+func TestDefaultErrorHandler_HTTPError(t *testing.T) {
+	a := New()
+	a.GET("/forbidden", func(c Ctx) error {
+		return fmt.Errorf("wrapped: %w", ctx.NewError(http.StatusForbidden, "nope"))
+	})
+	a.GET("/empty", func(c Ctx) error { return &ctx.HTTPError{Code: http.StatusConflict} })
+	for path, want := range map[string]struct {
+		code int
+		body string
+	}{
+		"/forbidden": {http.StatusForbidden, "nope"},
+		"/empty":     {http.StatusConflict, "Conflict"},
+	} {
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want.code || rec.Body.String() != want.body {
+			t.Fatalf("%s: code=%d body=%q", path, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestErrorHandlerV2_ReceivesResponseState(t *testing.T) {
+	a := New()
+	var got ErrorInfo
+	a.SetErrorHandler(func(c Ctx, err error) { t.Fatalf("v1 handler must not run") })
+	a.SetErrorHandlerV2(func(c Ctx, info ErrorInfo) {
+		got = info
+		if !info.Written {
+			_ = c.String(http.StatusBadGateway, "v2")
+		}
+	})
+	if a.ErrorHandlerV2() == nil {
+		t.Fatalf("getter returned nil")
+	}
+	a.GET("/fresh/:id", func(c Ctx) error { return io.ErrUnexpectedEOF })
+	a.POST("/written", func(c Ctx) error {
+		_ = c.String(http.StatusAccepted, "partial")
+		return io.ErrClosedPipe
+	})
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fresh/1", nil))
+	if rec.Code != http.StatusBadGateway || got.Written || got.Status != 0 ||
+		got.Route != "/fresh/:id" || got.Method != http.MethodGet || got.Err != io.ErrUnexpectedEOF {
+		t.Fatalf("unexpected: code=%d info=%+v", rec.Code, got)
+	}
+
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/written", nil))
+	if rec.Code != http.StatusAccepted || !got.Written || got.Status != http.StatusAccepted || got.Route != "/written" {
+		t.Fatalf("unexpected: code=%d info=%+v", rec.Code, got)
+	}
+
+	// nil falls back to the v1 handler
+	a.SetErrorHandlerV2(nil)
+	a.SetErrorHandler(defaultErrorHandler)
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fresh/1", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected fallback 500, got %d", rec.Code)
+	}
+}
+
+func TestErrorInfo_Chain(t *testing.T) {
+	he := ctx.NewError(http.StatusForbidden, "nope")
+	joined := errors.Join(io.EOF, fmt.Errorf("ctx: %w", he))
+	top := fmt.Errorf("top: %w", joined)
+	chain := ErrorInfo{Err: top}.Chain()
+	if len(chain) != 5 || chain[0] != top || chain[1] != joined || chain[2] != io.EOF || chain[4] != he {
+		t.Fatalf("unexpected chain: %v", chain)
+	}
+	if (ErrorInfo{}).Chain() != nil {
+		t.Fatalf("expected nil chain for nil error")
+	}
+}
@@ -0,0 +1,188 @@
+package app
+
+import (
+	"errors"
+	"runtime/debug"
+	"slices"
+	"time"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+// Names of the events the framework emits, returned by Event.EventName and
+// used to filter subscriptions in OnEvent.
+const (
+	EventRequestStart    = "request.start"
+	EventRequestEnd      = "request.end"
+	EventError           = "error"
+	EventPanic           = "panic"
+	EventRouteRegistered = "route.registered"
+	EventShutdown        = "app.shutdown"
+)
+
+// Event is a framework lifecycle event delivered to the handlers registered
+// with OnEvent. The concrete types are RequestStartEvent, RequestEndEvent,
+// ErrorEvent, PanicEvent, RouteRegisteredEvent and ShutdownEvent; use a type
+// switch to read their payloads.
+type Event interface {
+	EventName() string
+}
+
+// EventHandler receives framework events. Handlers run synchronously on the
+// goroutine emitting the event (for request events, the request's goroutine),
+// so they should be fast and hand slow work off to another goroutine. A
+// panicking handler is logged and does not affect the request or the other
+// handlers.
+type EventHandler func(e Event)
+
+// RequestStartEvent is emitted when a request matched a route, before any
+// middleware runs. Ctx is only valid during the handler call.
+type RequestStartEvent struct {
+	Ctx    ctx.Ctx
+	Method string
+	Route  string // route pattern, e.g. "/users/:id"
+	Time   time.Time
+}
+
+// RequestEndEvent is emitted after a request's handler chain and error
+// handling finished, before its context is returned to the pool. Status is
+// the response status (0 if nothing was written) and Err the error returned
+// by the chain, if any. Ctx is only valid during the handler call.
+type RequestEndEvent struct {
+	Ctx      ctx.Ctx
+	Method   string
+	Route    string
+	Status   int
+	Duration time.Duration
+	Err      error
+}
+
+// ErrorEvent is emitted when a handler chain returns an error other than a
+// panic, before the error handler runs. Ctx is only valid during the handler
+// call.
+type ErrorEvent struct {
+	Ctx ctx.Ctx
+	ErrorInfo
+}
+
+// PanicEvent is emitted instead of ErrorEvent when the chain returns a
+// *ctx.PanicError, i.e. a panic recovered by the Recover middleware. Stack is
+// only set when Recover captures stacks. Ctx is only valid during the handler
+// call.
+type PanicEvent struct {
+	Ctx    ctx.Ctx
+	Method string
+	Route  string
+	Value  any
+	Stack  []byte
+	Err    *ctx.PanicError
+}
+
+// RouteRegisteredEvent is emitted for each route added with the route methods
+// (GET, Handle, ANY, group routes, ...), i.e. the routes listed by Routes.
+// Only routes registered after the subscription are reported; use Routes for
+// the earlier ones.
+type RouteRegisteredEvent struct {
+	Route *Route
+}
+
+// ShutdownEvent is emitted when Shutdown (or ServeFCGI) has stopped the
+// server and is about to run the OnShutdown hooks.
+type ShutdownEvent struct {
+	Time time.Time
+}
+
+func (RequestStartEvent) EventName() string    { return EventRequestStart }
+func (RequestEndEvent) EventName() string      { return EventRequestEnd }
+func (ErrorEvent) EventName() string           { return EventError }
+func (PanicEvent) EventName() string           { return EventPanic }
+func (RouteRegisteredEvent) EventName() string { return EventRouteRegistered }
+func (ShutdownEvent) EventName() string        { return EventShutdown }
+
+// eventSub is a handler registered with OnEvent.
+type eventSub struct {
+	names []string // event names to deliver; empty means all
+	h     EventHandler
+}
+
+// OnEvent subscribes h to the framework events with the given names (see the
+// Event* constants), or to every event when no names are given, and returns a
+// function removing the subscription. It lets plugins and observability code
+// follow the request and application lifecycle without depending on where
+// their middleware sits in the chain. Requests cost nothing extra while
+// there are no subscribers.
+//
+// Example:
+//
+//	a.OnEvent(func(e app.Event) {
+//		switch e := e.(type) {
+//		case app.RequestEndEvent:
+//			latency.WithLabelValues(e.Route).Observe(e.Duration.Seconds())
+//		case app.PanicEvent:
+//			alerts.Notify(e.Route, e.Value)
+//		}
+//	}, app.EventRequestEnd, app.EventPanic)
+func (a *DefaultApp) OnEvent(h EventHandler, names ...string) (unsubscribe func()) {
+	if h == nil {
+		return func() {}
+	}
+	sub := &eventSub{names: append([]string(nil), names...), h: h}
+	a.eventsMu.Lock()
+	subs := a.eventSubs()
+	next := append(subs[:len(subs):len(subs)], sub)
+	a.events.Store(&next)
+	a.eventsMu.Unlock()
+	return func() {
+		a.eventsMu.Lock()
+		defer a.eventsMu.Unlock()
+		subs := a.eventSubs()
+		i := slices.Index(subs, sub)
+		if i < 0 {
+			return
+		}
+		if len(subs) == 1 {
+			a.events.Store(nil)
+			return
+		}
+		next := slices.Delete(slices.Clone(subs), i, i+1)
+		a.events.Store(&next)
+	}
+}
+
+// eventSubs returns the current subscribers, or nil when there are none.
+func (a *DefaultApp) eventSubs() []*eventSub {
+	if p := a.events.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// emit delivers e to the subscribers interested in it.
+func (a *DefaultApp) emit(subs []*eventSub, e Event) {
+	name := e.EventName()
+	for _, s := range subs {
+		if len(s.names) == 0 || slices.Contains(s.names, name) {
+			a.runEventHandler(s.h, e)
+		}
+	}
+}
+
+// runEventHandler runs h, logging a panic instead of failing the emitter.
+func (a *DefaultApp) runEventHandler(h EventHandler, e Event) {
+	defer func() {
+		if v := recover(); v != nil {
+			a.Logger().Error("event handler panicked", "event", e.EventName(), "panic", v, "stack", string(debug.Stack()))
+		}
+	}()
+	h(e)
+}
+
+// emitError emits the PanicEvent or ErrorEvent for err returned by the chain.
+func (a *DefaultApp) emitError(subs []*eventSub, c ctx.Ctx, err error) {
+	var pe *ctx.PanicError
+	if errors.As(err, &pe) {
+		a.emit(subs, PanicEvent{Ctx: c, Method: c.Method(), Route: c.Route(), Value: pe.Value, Stack: pe.Stack, Err: pe})
+		return
+	}
+	a.emit(subs, ErrorEvent{Ctx: c, ErrorInfo: newErrorInfo(c, err)})
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+func TestOnEvent_RequestLifecycle(t *testing.T) {
+	a := New()
+	var got []Event
+	a.OnEvent(func(e Event) { got = append(got, e) })
+
+	a.GET("/ok/:id", func(c Ctx) error { return c.String(http.StatusCreated, "hi") })
+	a.GET("/fail", func(c Ctx) error { return ctx.NewError(http.StatusTeapot, "nope") })
+	a.GET("/panic", func(c Ctx) error { return &ctx.PanicError{Value: "boom", Stack: []byte("stack")} })
+
+	if len(got) != 3 {
+		t.Fatalf("got %d route events", len(got))
+	}
+	if rr, ok := got[0].(RouteRegisteredEvent); !ok || rr.Route.Path != "/ok/:id" || rr.Route.Method != http.MethodGet {
+		t.Fatalf("route event = %#v", got[0])
+	}
+
+	got = nil
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok/7", nil))
+	if len(got) != 2 {
+		t.Fatalf("events = %#v", got)
+	}
+	start, ok := got[0].(RequestStartEvent)
+	if !ok || start.Method != http.MethodGet || start.Route != "/ok/:id" || start.Time.IsZero() {
+		t.Fatalf("start = %#v", got[0])
+	}
+	end, ok := got[1].(RequestEndEvent)
+	if !ok || end.Route != "/ok/:id" || end.Status != http.StatusCreated || end.Err != nil || end.Duration <= 0 {
+		t.Fatalf("end = %#v", got[1])
+	}
+
+	got = nil
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	if len(got) != 3 {
+		t.Fatalf("events = %#v", got)
+	}
+	ee, ok := got[1].(ErrorEvent)
+	if !ok || ee.Route != "/fail" || ee.Written || !strings.Contains(ee.Err.Error(), "nope") {
+		t.Fatalf("error = %#v", got[1])
+	}
+	if end := got[2].(RequestEndEvent); end.Status != http.StatusTeapot || end.Err == nil {
+		t.Fatalf("end = %#v", end)
+	}
+
+	got = nil
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+	pe, ok := got[1].(PanicEvent)
+	if len(got) != 3 || !ok || pe.Value != "boom" || string(pe.Stack) != "stack" || pe.Route != "/panic" {
+		t.Fatalf("events = %#v", got)
+	}
+}
+
+func TestOnEvent_FilterAndUnsubscribe(t *testing.T) {
+	a := New()
+	var names []string
+	unsub := a.OnEvent(func(e Event) { names = append(names, e.EventName()) }, EventRequestEnd, EventShutdown)
+	var all int
+	unsubAll := a.OnEvent(func(Event) { all++ })
+	if a.OnEvent(nil) == nil {
+		t.Fatalf("OnEvent(nil) should return a no-op unsubscribe")
+	}
+
+	a.GET("/", func(c Ctx) error { return nil })
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if strings.Join(names, ",") != EventRequestEnd || all != 3 {
+		t.Fatalf("names = %v, all = %d", names, all)
+	}
+
+	unsub()
+	unsub() // idempotent
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if len(names) != 1 || all != 5 {
+		t.Fatalf("after unsubscribe: names = %v, all = %d", names, all)
+	}
+	unsubAll()
+	if a.(*DefaultApp).events.Load() != nil {
+		t.Fatalf("subscribers should be cleared")
+	}
+}
+
+func TestOnEvent_Shutdown(t *testing.T) {
+	a := New()
+	var order []string
+	a.OnShutdown(func(context.Context) error { order = append(order, "hook"); return nil })
+	a.OnEvent(func(e Event) {
+		if _, ok := e.(ShutdownEvent); ok {
+			order = append(order, e.EventName())
+		}
+	})
+	if err := a.Shutdown(context.Background(), &http.Server{}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(order, ",") != "app.shutdown,hook" {
+		t.Fatalf("order = %v", order)
+	}
+}
+
+func TestOnEvent_HandlerPanic(t *testing.T) {
+	a := New()
+	var after int
+	a.OnEvent(func(Event) { panic(errors.New("observer bug")) })
+	a.OnEvent(func(Event) { after++ })
+	a.GET("/", func(c Ctx) error { return c.String(http.StatusOK, "ok") })
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok" || after != 3 {
+		t.Fatalf("code = %d body = %q after = %d", rec.Code, rec.Body.String(), after)
+	}
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"context"
+	"net"
+	"net/http/cgi"
+	"net/http/fcgi"
+)
+
+// ServeFCGI serves the app over FastCGI on ln, for hosting setups where
+// Apache or nginx talk FastCGI to the application (common when migrating from
+// PHP-FPM). Requests go through the same router, middleware, error handling
+// and hooks as with net/http. Pass a nil ln when the web server spawns the
+// process and hands it the listening socket on stdin (mod_fcgid, spawn-fcgi).
+//
+// The web server's CGI parameters become the request: REQUEST_URI is the URL,
+// REMOTE_ADDR the client address, and HTTPS=on marks the request as TLS, so
+// c.Request().TLS and scheme-dependent middleware work as behind a proxy.
+//
+// ServeFCGI returns when ln is closed. net/http/fcgi has no graceful shutdown:
+// call BeginDrain first and give in-flight requests time to finish before
+// closing ln.
+//
+// Example (nginx: fastcgi_pass 127.0.0.1:9000; include fastcgi_params;):
+//
+//	ln, err := net.Listen("tcp", "127.0.0.1:9000")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	log.Fatal(a.ServeFCGI(ln))
+func (a *DefaultApp) ServeFCGI(ln net.Listener) error {
+	return fcgi.Serve(ln, a)
+}
+
+// ServeCGI serves the single request of a CGI invocation, read from the
+// process environment and stdin, and then runs the same final steps as
+// Shutdown: it waits for response hooks and runs the OnShutdown hooks, since
+// the process exits after the request. It fails without running the hooks
+// when the process was not started by a CGI-capable web server.
+//
+// Example:
+//
+//	func main() {
+//		a := flash.New()
+//		a.GET("/hello", hello)
+//		if err := a.ServeCGI(); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+func (a *DefaultApp) ServeCGI() error {
+	if err := cgi.Serve(a); err != nil {
+		return err
+	}
+	return a.finishShutdown(context.Background())
+}
@@ -0,0 +1,172 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+// fcgiRecord writes a FastCGI record of type typ for request 1.
+func fcgiRecord(w io.Writer, typ byte, content []byte) {
+	h := []byte{1, typ, 0, 1, 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(h[4:], uint16(len(content)))
+	w.Write(h)
+	w.Write(content)
+}
+
+// fcgiParams encodes name-value pairs shorter than 128 bytes.
+func fcgiParams(kv map[string]string) []byte {
+	var b bytes.Buffer
+	for k, v := range kv {
+		b.WriteByte(byte(len(k)))
+		b.WriteByte(byte(len(v)))
+		b.WriteString(k)
+		b.WriteString(v)
+	}
+	return b.Bytes()
+}
+
+// fcgiDo sends one request over a FastCGI connection and returns the raw
+// CGI response (headers and body) the app wrote to stdout.
+func fcgiDo(t *testing.T, addr string, params map[string]string, body string) string {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var req bytes.Buffer
+	fcgiRecord(&req, 1, []byte{0, 1, 0, 0, 0, 0, 0, 0}) // BEGIN_REQUEST, responder, no keep-conn
+	fcgiRecord(&req, 4, fcgiParams(params))
+	fcgiRecord(&req, 4, nil)
+	if body != "" {
+		fcgiRecord(&req, 5, []byte(body))
+	}
+	fcgiRecord(&req, 5, nil)
+	if _, err := conn.Write(req.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	var stdout bytes.Buffer
+	r := bufio.NewReader(conn)
+	for {
+		h := make([]byte, 8)
+		if _, err := io.ReadFull(r, h); err != nil {
+			t.Fatalf("read record: %v", err)
+		}
+		content := make([]byte, int(binary.BigEndian.Uint16(h[4:]))+int(h[6]))
+		if _, err := io.ReadFull(r, content); err != nil {
+			t.Fatal(err)
+		}
+		switch h[1] {
+		case 6: // STDOUT
+			stdout.Write(content[:len(content)-int(h[6])])
+		case 3: // END_REQUEST
+			return stdout.String()
+		}
+	}
+}
+
+func TestServeFCGI(t *testing.T) {
+	a := New()
+	a.Use(func(next Handler) Handler {
+		return func(c ctx.Ctx) error {
+			c.Header("X-MW", "1")
+			return next(c)
+		}
+	})
+	a.POST("/users/:id", func(c ctx.Ctx) error {
+		body, _ := io.ReadAll(c.Request().Body)
+		tls := c.Request().TLS != nil
+		return c.String(http.StatusCreated, c.Param("id")+" "+c.Query("q")+" "+string(body)+" "+c.Request().RemoteAddr+" "+map[bool]string{true: "tls", false: "plain"}[tls])
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- a.ServeFCGI(ln) }()
+
+	resp := fcgiDo(t, ln.Addr().String(), map[string]string{
+		"REQUEST_METHOD":  "POST",
+		"REQUEST_URI":     "/users/7?q=x",
+		"SERVER_PROTOCOL": "HTTP/1.1",
+		"HTTP_HOST":       "example.com",
+		"CONTENT_LENGTH":  "5",
+		"REMOTE_ADDR":     "203.0.113.9",
+		"REMOTE_PORT":     "5555",
+		"HTTPS":           "on",
+	}, "hello")
+	if !strings.HasPrefix(resp, "Status: 201 Created\r\n") || !strings.Contains(resp, "X-Mw: 1\r\n") {
+		t.Fatalf("response = %q", resp)
+	}
+	if !strings.HasSuffix(resp, "\r\n\r\n7 x hello 203.0.113.9:5555 tls") {
+		t.Fatalf("response = %q", resp)
+	}
+
+	resp = fcgiDo(t, ln.Addr().String(), map[string]string{
+		"REQUEST_METHOD": "GET", "REQUEST_URI": "/missing", "SERVER_PROTOCOL": "HTTP/1.1",
+	}, "")
+	if !strings.HasPrefix(resp, "Status: 404") {
+		t.Fatalf("response = %q", resp)
+	}
+
+	ln.Close()
+	if err := <-done; err == nil {
+		t.Fatalf("ServeFCGI should return an error once the listener is closed")
+	}
+}
+
+func TestServeCGI(t *testing.T) {
+	a := New()
+	a.GET("/hello", func(c ctx.Ctx) error { return c.String(http.StatusOK, "hi "+c.Query("name")) })
+	var hooks []string
+	a.OnShutdown(func(context.Context) error { hooks = append(hooks, "shutdown"); return nil })
+
+	t.Setenv("REQUEST_METHOD", "GET")
+	t.Setenv("REQUEST_URI", "/hello?name=ada")
+	t.Setenv("SERVER_PROTOCOL", "HTTP/1.1")
+	t.Setenv("HTTP_HOST", "example.com")
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	err = a.ServeCGI()
+	os.Stdout = stdout
+	w.Close()
+	out, _ := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(out), "Status: 200 OK\r\n") || !strings.HasSuffix(string(out), "\r\n\r\nhi ada") {
+		t.Fatalf("output = %q", out)
+	}
+	if len(hooks) != 1 {
+		t.Fatalf("shutdown hooks = %v", hooks)
+	}
+}
+
+func TestServeCGI_NotCGI(t *testing.T) {
+	a := New()
+	ran := false
+	a.OnShutdown(func(context.Context) error { ran = true; return nil })
+	t.Setenv("REQUEST_METHOD", "")
+	if err := a.ServeCGI(); err == nil {
+		t.Fatalf("expected error outside CGI")
+	}
+	if ran {
+		t.Fatalf("hooks must not run when no request was served")
+	}
+}
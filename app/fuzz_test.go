@@ -0,0 +1,59 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/goflash/flash/v2/flashtest"
+)
+
+// fuzzApp registers a route table exercising static, parameter and
+// catch-all segments on r.
+func fuzzApp(r Router) App {
+	a := New(WithRouter(r))
+	echo := func(c Ctx) error { return c.String(http.StatusOK, c.Route()+"|"+c.Param("id")+"|"+c.Param("path")) }
+	a.GET("/", echo)
+	a.GET("/users", echo)
+	a.GET("/users/:id", echo)
+	a.POST("/users/:id/posts", echo)
+	a.ANY("/files/*path", echo)
+	g := a.Group("/api/v1")
+	g.GET("/items/:id", echo)
+	g.DELETE("/items/:id", echo)
+	return a
+}
+
+func FuzzRouter(f *testing.F) {
+	for _, seed := range []string{
+		"\x00/",
+		"\x00/users/42",
+		"\x01/users/42/posts",
+		"\x04/api/v1/items/9?x=1",
+		"\x00/files/a/b/../c",
+		"\x00/users/%2F",
+		"\x00//users",
+		"\x06/api/v1/items/",
+		"\x00/users/42/",
+	} {
+		f.Add([]byte(seed))
+	}
+	routers := map[string]App{
+		"httprouter": fuzzApp(NewHTTPRouter()),
+		"tree":       fuzzApp(NewTreeRouter()),
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// HEAD drops the body and OPTIONS may be answered by the router.
+		method := flashtest.FuzzRequest(data).Method
+		echoed := method != http.MethodHead && method != http.MethodOptions
+		for name, a := range routers {
+			rec := flashtest.FuzzServe(a, data)
+			if rec.Code >= http.StatusInternalServerError {
+				t.Fatalf("%s: status %d for %q", name, rec.Code, data)
+			}
+			if echoed && rec.Code == http.StatusOK && !strings.Contains(rec.Body.String(), "|") {
+				t.Fatalf("%s: unexpected 200 body %q for %q", name, rec.Body.String(), data)
+			}
+		}
+	})
+}
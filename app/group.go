@@ -1,6 +1,11 @@
 package app
 
-import "net/http"
+import (
+	"maps"
+	"net/http"
+
+	"github.com/goflash/flash/v2/ctx"
+)
 
 // Group defines a group of routes with a common URL prefix and optional
 // middleware. Groups allow modular organization of related routes and sharing of
@@ -29,9 +34,12 @@ import "net/http"
 // follow internal cleanPath/joinPath semantics). Route parameters (":id")
 // defined in the path are available to handlers via c.Param("id").
 type Group struct {
-	app        *DefaultApp  // parent app
-	prefix     string       // route prefix
-	middleware []Middleware // group-level middleware
+	app        *DefaultApp          // parent app
+	prefix     string               // route prefix
+	middleware []Middleware         // group-level middleware
+	phases     []Phase              // phase of each group middleware (parallel to middleware)
+	bindOpts   *ctx.BindJSONOptions // group-level binding defaults (nil = inherit app defaults)
+	meta       map[string]any       // metadata copied to routes registered afterwards (see Meta)
 }
 
 // Group creates a new route group with the given prefix and optional middleware.
@@ -53,7 +61,9 @@ type Group struct {
 //	api.Use(Auth)
 //	api.GET("/me", Me)    // now global -> Auth applies
 func (a *DefaultApp) Group(prefix string, mw ...Middleware) *Group {
-	return &Group{app: a, prefix: cleanPath(prefix), middleware: mw}
+	g := &Group{app: a, prefix: cleanPath(prefix)}
+	g.Use(mw...)
+	return g
 }
 
 // Use adds middleware to the group. Middleware is applied in the order added.
@@ -63,7 +73,18 @@ func (a *DefaultApp) Group(prefix string, mw ...Middleware) *Group {
 //	api := a.Group("/api")
 //	api.Use(Auth, Audit)
 //	api.GET("/users", ListUsers) // order: global -> Auth -> Audit -> handler
-func (g *Group) Use(mw ...Middleware) { g.middleware = append(g.middleware, mw...) }
+func (g *Group) Use(mw ...Middleware) { g.UseOrdered(PhaseBusiness, mw...) }
+
+// SetBindDefaults overrides the app-wide binding defaults for routes registered
+// on this group afterwards. Nested groups created after this call inherit the
+// override.
+//
+// Example:
+//
+//	legacy := a.Group("/legacy")
+//	legacy.SetBindDefaults(ctx.BindJSONOptions{WeaklyTypedInput: true})
+//	legacy.POST("/orders", CreateOrder) // c.BindJSON(&o) coerces "10" -> 10 and ignores unknown keys
+func (g *Group) SetBindDefaults(o ctx.BindJSONOptions) { g.bindOpts = &o }
 
 // Group creates a nested route group inheriting the parent's prefix and
 // middleware. Additional middleware can be provided for the nested group.
@@ -80,11 +101,10 @@ func (g *Group) Use(mw ...Middleware) { g.middleware = append(g.middleware, mw..
 //	admin.GET("/stats", Stats, Trace)
 //	// order: global -> Auth -> Audit -> AdminOnly -> Trace -> Stats
 func (g *Group) Group(prefix string, mw ...Middleware) *Group {
-	child := &Group{app: g.app, prefix: joinPath(g.prefix, prefix)}
+	child := &Group{app: g.app, prefix: joinPath(g.prefix, prefix), bindOpts: g.bindOpts, meta: maps.Clone(g.meta)}
 	child.middleware = append(child.middleware, g.middleware...)
-	if len(mw) > 0 {
-		child.middleware = append(child.middleware, mw...)
-	}
+	child.phases = append(child.phases, g.phases...)
+	child.Use(mw...)
 	return child
 }
 
@@ -99,10 +119,14 @@ func (g *Group) Group(prefix string, mw ...Middleware) *Group {
 //
 //	g.handle(http.MethodDelete, "/users/:id", DeleteUser)
 //	// is equivalent to g.DELETE("/users/:id", DeleteUser)
-func (g *Group) handle(method, p string, h Handler, mws ...Middleware) {
+func (g *Group) handle(method, p string, h Handler, mws ...Middleware) *Route {
+	path := joinPath(g.prefix, p)
+	checkHandler(method, path, h, mws)
 	all := append([]Middleware{}, g.middleware...)
 	all = append(all, mws...)
-	g.app.handle(method, joinPath(g.prefix, p), h, all...)
+	rt := &Route{Method: method, Path: path, meta: maps.Clone(g.meta)}
+	g.app.route(method, path, h, g.bindOpts, rt, all...)
+	return g.app.addRoute(rt)
 }
 
 // GET registers a handler for HTTP GET requests on the group's prefix + path.
@@ -117,7 +141,9 @@ func (g *Group) handle(method, p string, h Handler, mws ...Middleware) {
 //
 //	api.GET("/users/:id", ShowUser, Trace)
 //	// handler sees c.Param("id"); order: global -> group -> Trace -> ShowUser
-func (g *Group) GET(p string, h Handler, mws ...Middleware) { g.handle(http.MethodGet, p, h, mws...) }
+func (g *Group) GET(p string, h Handler, mws ...Middleware) *Route {
+	return g.handle(http.MethodGet, p, h, mws...)
+}
 
 // POST registers a handler for HTTP POST requests on the group's prefix + path.
 // Optionally accepts route-specific middleware.
@@ -127,7 +153,9 @@ func (g *Group) GET(p string, h Handler, mws ...Middleware) { g.handle(http.Meth
 //
 //	api.POST("/users", CreateUser, CSRF)
 //	// order: global -> group -> CSRF -> CreateUser
-func (g *Group) POST(p string, h Handler, mws ...Middleware) { g.handle(http.MethodPost, p, h, mws...) }
+func (g *Group) POST(p string, h Handler, mws ...Middleware) *Route {
+	return g.handle(http.MethodPost, p, h, mws...)
+}
 
 // PUT registers a handler for HTTP PUT requests on the group's prefix + path.
 // Optionally accepts route-specific middleware.
@@ -136,7 +164,9 @@ func (g *Group) POST(p string, h Handler, mws ...Middleware) { g.handle(http.Met
 // Example:
 //
 //	api.PUT("/users/:id", ReplaceUser)
-func (g *Group) PUT(p string, h Handler, mws ...Middleware) { g.handle(http.MethodPut, p, h, mws...) }
+func (g *Group) PUT(p string, h Handler, mws ...Middleware) *Route {
+	return g.handle(http.MethodPut, p, h, mws...)
+}
 
 // PATCH registers a handler for HTTP PATCH requests on the group's prefix + path.
 // Optionally accepts route-specific middleware.
@@ -145,8 +175,8 @@ func (g *Group) PUT(p string, h Handler, mws ...Middleware) { g.handle(http.Meth
 // Example:
 //
 //	api.PATCH("/users/:id", UpdateUserEmail)
-func (g *Group) PATCH(p string, h Handler, mws ...Middleware) {
-	g.handle(http.MethodPatch, p, h, mws...)
+func (g *Group) PATCH(p string, h Handler, mws ...Middleware) *Route {
+	return g.handle(http.MethodPatch, p, h, mws...)
 }
 
 // DELETE registers a handler for HTTP DELETE requests on the group's prefix + path.
@@ -155,8 +185,8 @@ func (g *Group) PATCH(p string, h Handler, mws ...Middleware) {
 // Example:
 //
 //	api.DELETE("/users/:id", DeleteUser, Audit)
-func (g *Group) DELETE(p string, h Handler, mws ...Middleware) {
-	g.handle(http.MethodDelete, p, h, mws...)
+func (g *Group) DELETE(p string, h Handler, mws ...Middleware) *Route {
+	return g.handle(http.MethodDelete, p, h, mws...)
 }
 
 // OPTIONS registers a handler for HTTP OPTIONS requests on the group's prefix + path.
@@ -166,8 +196,8 @@ func (g *Group) DELETE(p string, h Handler, mws ...Middleware) {
 // Example:
 //
 //	api.OPTIONS("/users", Preflight)
-func (g *Group) OPTIONS(p string, h Handler, mws ...Middleware) {
-	g.handle(http.MethodOptions, p, h, mws...)
+func (g *Group) OPTIONS(p string, h Handler, mws ...Middleware) *Route {
+	return g.handle(http.MethodOptions, p, h, mws...)
 }
 
 // HEAD registers a handler for HTTP HEAD requests on the group's prefix + path.
@@ -177,4 +207,6 @@ func (g *Group) OPTIONS(p string, h Handler, mws ...Middleware) {
 // Example:
 //
 //	api.HEAD("/health", HeadHealth)
-func (g *Group) HEAD(p string, h Handler, mws ...Middleware) { g.handle(http.MethodHead, p, h, mws...) }
+func (g *Group) HEAD(p string, h Handler, mws ...Middleware) *Route {
+	return g.handle(http.MethodHead, p, h, mws...)
+}
@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/goflash/flash/v2/ctx"
 )
 
 func TestGroupAndNestedMiddleware(t *testing.T) {
@@ -67,3 +69,37 @@ func TestGroupMethodHelpersCoverage(t *testing.T) {
 		}
 	}
 }
+
+func TestGroupSetBindDefaults_OverridesAppDefaults(t *testing.T) {
+	a := New()
+	a.SetBindDefaults(ctx.BindJSONOptions{ErrorUnused: true})
+	type Q struct {
+		Page int `json:"page"`
+	}
+	bind := func(c Ctx) error {
+		var q Q
+		if err := c.BindQuery(&q); err != nil {
+			return c.String(http.StatusBadRequest, "bad")
+		}
+		return c.String(http.StatusOK, "ok")
+	}
+	a.GET("/strict", bind)
+	g := a.Group("/loose")
+	g.SetBindDefaults(ctx.BindJSONOptions{WeaklyTypedInput: true})
+	g.Group("/nested").GET("/q", bind)
+	g.GET("/q", bind)
+
+	for path, want := range map[string]int{
+		"/strict?page=2":          http.StatusBadRequest,
+		"/loose/q?page=2":         http.StatusOK,
+		"/loose/q?page=2&x=1":     http.StatusOK,
+		"/loose/nested/q?page=2":  http.StatusOK,
+		"/strict?page=2&unknown=": http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Fatalf("%s: code=%d want %d", path, rec.Code, want)
+		}
+	}
+}
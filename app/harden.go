@@ -0,0 +1,75 @@
+package app
+
+import (
+	"net/http"
+	"time"
+)
+
+// HardenConfig tunes the *http.Server returned by HardenedServer. Zero fields
+// take the defaults listed below, which are chosen to blunt slowloris-style
+// attacks (clients trickling headers or holding idle connections open) without
+// affecting normal traffic.
+type HardenConfig struct {
+	// Addr is the listen address, e.g. ":8080".
+	Addr string
+
+	// ReadHeaderTimeout bounds the time to read request headers. Default 5s.
+	ReadHeaderTimeout time.Duration
+
+	// ReadTimeout bounds the time to read the whole request including the body.
+	// Default 0 (no limit) so large uploads are governed by middleware instead.
+	ReadTimeout time.Duration
+
+	// WriteTimeout bounds the time to write the response. Default 0 (no limit)
+	// so streaming responses are not cut off; set it for purely request/response APIs.
+	WriteTimeout time.Duration
+
+	// IdleTimeout bounds how long keep-alive connections may stay idle. Default 60s.
+	IdleTimeout time.Duration
+
+	// MaxHeaderBytes bounds the size of request headers. Default 64KB
+	// (Go's own default is 1MB).
+	MaxHeaderBytes int
+}
+
+// Default hardening values applied by HardenedServer for zero HardenConfig fields.
+const (
+	DefaultReadHeaderTimeout = 5 * time.Second
+	DefaultIdleTimeout       = 60 * time.Second
+	DefaultMaxHeaderBytes    = 64 << 10
+)
+
+// HardenedServer returns an *http.Server for h with conservative timeouts and
+// header limits. The standard library's zero-value server has no header read
+// timeout and no idle timeout, which lets a single client tie up connections
+// indefinitely by sending headers one byte at a time.
+//
+// Pair it with middleware.HeaderLimits to also cap the number and size of
+// individual headers before any binding happens.
+//
+// Example:
+//
+//	a := flash.New()
+//	a.Use(middleware.HeaderLimits(middleware.HeaderLimitsConfig{MaxHeaders: 50}))
+//	srv := flash.HardenedServer(a, flash.HardenConfig{Addr: ":8080", WriteTimeout: 30 * time.Second})
+//	log.Fatal(srv.ListenAndServe())
+func HardenedServer(h http.Handler, cfg HardenConfig) *http.Server {
+	if cfg.ReadHeaderTimeout <= 0 {
+		cfg.ReadHeaderTimeout = DefaultReadHeaderTimeout
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = DefaultIdleTimeout
+	}
+	if cfg.MaxHeaderBytes <= 0 {
+		cfg.MaxHeaderBytes = DefaultMaxHeaderBytes
+	}
+	return &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           h,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
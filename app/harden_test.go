@@ -0,0 +1,30 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHardenedServer_Defaults(t *testing.T) {
+	a := New()
+	srv := HardenedServer(a, HardenConfig{Addr: ":1"})
+	if srv.Addr != ":1" || srv.Handler != a || srv.ReadHeaderTimeout != DefaultReadHeaderTimeout ||
+		srv.IdleTimeout != DefaultIdleTimeout || srv.MaxHeaderBytes != DefaultMaxHeaderBytes ||
+		srv.ReadTimeout != 0 || srv.WriteTimeout != 0 {
+		t.Fatalf("unexpected defaults: %+v", srv)
+	}
+}
+
+func TestHardenedServer_Overrides(t *testing.T) {
+	srv := HardenedServer(New(), HardenConfig{
+		ReadHeaderTimeout: time.Second,
+		ReadTimeout:       2 * time.Second,
+		WriteTimeout:      3 * time.Second,
+		IdleTimeout:       4 * time.Second,
+		MaxHeaderBytes:    1024,
+	})
+	if srv.ReadHeaderTimeout != time.Second || srv.ReadTimeout != 2*time.Second ||
+		srv.WriteTimeout != 3*time.Second || srv.IdleTimeout != 4*time.Second || srv.MaxHeaderBytes != 1024 {
+		t.Fatalf("overrides not applied: %+v", srv)
+	}
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"context"
+	"runtime/debug"
+)
+
+// runResponseHooks runs the OnCommit/AfterResponse hooks of a finished request
+// in order on a background goroutine tracked for WaitResponseHooks. Hooks get
+// the request's context values without its cancellation.
+func (a *DefaultApp) runResponseHooks(parent context.Context, hooks []func(context.Context)) {
+	ctx := context.WithoutCancel(parent)
+	a.responseHooks.Add(1)
+	go func() {
+		defer a.responseHooks.Done()
+		for _, h := range hooks {
+			a.runResponseHook(ctx, h)
+		}
+	}()
+}
+
+// runResponseHook runs h, logging a panic instead of crashing the process or
+// skipping the request's remaining hooks.
+func (a *DefaultApp) runResponseHook(ctx context.Context, h func(context.Context)) {
+	defer func() {
+		if v := recover(); v != nil {
+			a.Logger().ErrorContext(ctx, "response hook panicked", "panic", v, "stack", string(debug.Stack()))
+		}
+	}()
+	h(ctx)
+}
+
+// WaitResponseHooks blocks until every hook registered with Ctx.OnCommit or
+// Ctx.AfterResponse by finished requests has run, or ctx ends. Shutdown calls
+// it after the server has stopped and before the OnShutdown hooks, so hooks
+// can still use the resources those release.
+//
+// Example (tests):
+//
+//	a.ServeHTTP(rec, req)
+//	_ = a.WaitResponseHooks(context.Background())
+//	// assert on the messages published by OnCommit
+func (a *DefaultApp) WaitResponseHooks(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		a.responseHooks.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
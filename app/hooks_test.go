@@ -0,0 +1,112 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type requestIDKey struct{}
+
+func TestResponseHooksRunAfterResponse(t *testing.T) {
+	a := New().(*DefaultApp)
+	var mu sync.Mutex
+	var events []string
+	record := func(ev string) {
+		mu.Lock()
+		events = append(events, ev)
+		mu.Unlock()
+	}
+	a.POST("/ok", func(c Ctx) error {
+		c.Set(requestIDKey{}, "req-1")
+		c.OnCommit(func(ctx context.Context) {
+			if ctx.Err() != nil {
+				t.Errorf("hook context must not be canceled")
+			}
+			record("commit:" + ctx.Value(requestIDKey{}).(string))
+		})
+		c.AfterResponse(func(_ context.Context, status int) { record("after:" + http.StatusText(status)) })
+		return c.String(http.StatusCreated, "ok")
+	})
+	a.POST("/fail", func(c Ctx) error {
+		c.OnCommit(func(context.Context) { record("commit:fail") })
+		c.AfterResponse(func(_ context.Context, status int) { record("after:" + http.StatusText(status)) })
+		return errors.New("boom")
+	})
+
+	for _, p := range []string{"/ok", "/fail"} {
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, p, nil))
+		if err := a.WaitResponseHooks(context.Background()); err != nil {
+			t.Fatalf("WaitResponseHooks: %v", err)
+		}
+	}
+	got := strings.Join(events, ",")
+	if got != "commit:req-1,after:Created,after:Internal Server Error" {
+		t.Fatalf("unexpected hook events %q", got)
+	}
+}
+
+func TestResponseHookPanicIsolated(t *testing.T) {
+	a := New().(*DefaultApp)
+	var buf bytes.Buffer
+	a.SetLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+	ran := false
+	a.GET("/", func(c Ctx) error {
+		c.OnCommit(func(context.Context) { panic("broker down") })
+		c.OnCommit(func(context.Context) { ran = true })
+		return c.String(http.StatusOK, "ok")
+	})
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	_ = a.WaitResponseHooks(context.Background())
+	if !ran || !strings.Contains(buf.String(), "response hook panicked") {
+		t.Fatalf("expected later hook to run and panic to be logged, ran=%v log=%q", ran, buf.String())
+	}
+}
+
+func TestShutdownWaitsForResponseHooks(t *testing.T) {
+	a := New().(*DefaultApp)
+	release := make(chan struct{})
+	var order []string
+	var mu sync.Mutex
+	a.GET("/", func(c Ctx) error {
+		c.OnCommit(func(context.Context) {
+			<-release
+			mu.Lock()
+			order = append(order, "hook")
+			mu.Unlock()
+		})
+		return c.String(http.StatusOK, "ok")
+	})
+	a.OnShutdown(func(context.Context) error {
+		mu.Lock()
+		order = append(order, "shutdown")
+		mu.Unlock()
+		return nil
+	})
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := a.WaitResponseHooks(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline while the hook blocks, got %v", err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	if err := a.Shutdown(context.Background(), &http.Server{}, 0); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if strings.Join(order, ",") != "hook,shutdown" {
+		t.Fatalf("expected hooks before shutdown hooks, got %v", order)
+	}
+}
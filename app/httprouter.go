@@ -0,0 +1,59 @@
+package app
+
+import (
+	"net/http"
+	"unsafe"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// httpRouter adapts *httprouter.Router to the Router interface.
+type httpRouter struct {
+	r *httprouter.Router
+}
+
+// NewHTTPRouter returns the default Router, backed by httprouter with
+// MethodNotAllowed handling enabled.
+//
+// Example:
+//
+//	a := app.New(app.WithRouter(app.NewHTTPRouter())) // same as app.New()
+func NewHTTPRouter() Router {
+	r := httprouter.New()
+	r.HandleMethodNotAllowed = true
+	return &httpRouter{r: r}
+}
+
+func (h *httpRouter) Handle(method, pattern string, fn RouteHandler) {
+	h.r.Handle(method, pattern, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		fn(w, r, fromHTTPRouterParams(ps))
+	})
+}
+
+func (h *httpRouter) SetNotFound(nf http.Handler)          { h.r.NotFound = nf }
+func (h *httpRouter) SetMethodNotAllowed(mna http.Handler) { h.r.MethodNotAllowed = mna }
+func (h *httpRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.r.ServeHTTP(w, r)
+}
+
+// Param and httprouter.Param are both a Key and a Value string; the conversion
+// below only compiles while their underlying types are identical, which keeps
+// the zero-copy slice conversions safe.
+var _ = Param(httprouter.Param{})
+
+// fromHTTPRouterParams reinterprets ps as Params without copying, so the
+// default router adds no allocation per request.
+func fromHTTPRouterParams(ps httprouter.Params) Params {
+	if len(ps) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*Param)(unsafe.Pointer(unsafe.SliceData(ps))), len(ps))
+}
+
+// toHTTPRouterParams is the inverse of fromHTTPRouterParams.
+func toHTTPRouterParams(ps Params) httprouter.Params {
+	if len(ps) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*httprouter.Param)(unsafe.Pointer(unsafe.SliceData(ps))), len(ps))
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+// JSONError is the default body written by WithJSONErrors, e.g.
+// {"code":404,"message":"Not Found","request_id":"4f1c..."}.
+type JSONError struct {
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"` // X-Request-ID of the response or request
+}
+
+// JSONErrorConfig configures WithJSONErrors.
+type JSONErrorConfig struct {
+	// ContentType of the JSON bodies. Defaults to
+	// "application/json; charset=utf-8".
+	ContentType string
+
+	// Body maps the error to the value encoded as the response body.
+	// Defaults to e itself.
+	Body func(e JSONError) any
+}
+
+// WithJSONErrors makes the default error, 404 and 405 handlers answer with a
+// JSON body (see JSONError) instead of plain text, so APIs get structured
+// errors without replacing all three handlers. Clients whose Accept header
+// ranks text/plain or text/html above JSON still get plain text; HTML error
+// pages (SetErrorTemplates) keep precedence for browsers. Handlers set after
+// this option, with Set*Handler or later options, replace the JSON ones.
+//
+// Example:
+//
+//	a := app.New(app.WithJSONErrors())
+//	// GET /missing -> 404 {"code":404,"message":"Not Found","request_id":"..."}
+//
+// Example (custom shape):
+//
+//	a := app.New(app.WithJSONErrors(app.JSONErrorConfig{
+//		ContentType: "application/problem+json",
+//		Body: func(e app.JSONError) any {
+//			return map[string]any{"status": e.Code, "title": e.Message, "instance": e.RequestID}
+//		},
+//	}))
+func WithJSONErrors(cfg ...JSONErrorConfig) Option {
+	var c JSONErrorConfig
+	if len(cfg) > 0 {
+		c = cfg[0]
+	}
+	if c.ContentType == "" {
+		c.ContentType = "application/json; charset=utf-8"
+	}
+	if c.Body == nil {
+		c.Body = func(e JSONError) any { return e }
+	}
+	return func(a *DefaultApp) {
+		a.SetErrorHandler(c.errorHandler)
+		a.SetNotFoundHandler(c.statusHandler(http.StatusNotFound))
+		a.SetMethodNotAllowedHandler(c.statusHandler(http.StatusMethodNotAllowed))
+	}
+}
+
+// errorHandler is defaultErrorHandler with JSON bodies.
+func (cfg JSONErrorConfig) errorHandler(c ctx.Ctx, err error) {
+	if c.WroteHeader() {
+		return
+	}
+	if !wantsJSON(c.Request().Header.Get("Accept")) {
+		defaultErrorHandler(c, err)
+		return
+	}
+	status, msg := http.StatusInternalServerError, ""
+	var he *ctx.HTTPError
+	if errors.As(err, &he) && he.Code > 0 {
+		status, msg = he.Code, he.Message
+	}
+	var pe *ctx.PanicError
+	if errors.As(err, &pe) {
+		c.Header("X-Content-Type-Options", "nosniff")
+	}
+	body, ok := cfg.encode(c.Request(), c.ResponseWriter().Header(), status, msg)
+	if !ok {
+		defaultErrorHandler(c, err)
+		return
+	}
+	_, _ = c.Send(status, cfg.ContentType, body)
+}
+
+// statusHandler answers every request with status, as JSON when wanted.
+func (cfg JSONErrorConfig) statusHandler(status int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []byte
+		ok := wantsJSON(r.Header.Get("Accept"))
+		if ok {
+			body, ok = cfg.encode(r, w.Header(), status, "")
+		}
+		if !ok {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		w.Header().Set("Content-Type", cfg.ContentType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(status)
+		_, _ = w.Write(body)
+	})
+}
+
+// encode builds the JSON body for status.
+func (cfg JSONErrorConfig) encode(r *http.Request, hdr http.Header, status int, msg string) ([]byte, bool) {
+	if msg == "" {
+		msg = http.StatusText(status)
+	}
+	e := JSONError{Code: status, Message: msg, RequestID: hdr.Get("X-Request-ID")}
+	if e.RequestID == "" {
+		e.RequestID = r.Header.Get("X-Request-ID")
+	}
+	b, err := json.Marshal(cfg.Body(e))
+	return b, err == nil
+}
+
+// wantsJSON reports whether a client should get JSON: unless its Accept
+// header ranks text/plain or text/html above JSON. Missing and wildcard-only
+// headers get JSON.
+func wantsJSON(accept string) bool {
+	if !strings.Contains(strings.ToLower(accept), "text/") {
+		return true
+	}
+	j := acceptQuality(accept, "application/json")
+	return j >= acceptQuality(accept, "text/plain") && j >= acceptQuality(accept, "text/html")
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+func serveJSONErrors(a App, method, target, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	req.Header.Set("X-Request-ID", "rid-1")
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestWithJSONErrors_Defaults(t *testing.T) {
+	a := New(WithJSONErrors())
+	a.GET("/teapot", func(c Ctx) error { return ctx.NewError(http.StatusTeapot, "short and stout") })
+	a.GET("/boom", func(c Ctx) error { return errors.New("db password leaked") })
+
+	for _, tc := range []struct {
+		method, path string
+		code         int
+		msg          string
+	}{
+		{http.MethodGet, "/missing", http.StatusNotFound, "Not Found"},
+		{http.MethodPost, "/teapot", http.StatusMethodNotAllowed, "Method Not Allowed"},
+		{http.MethodGet, "/teapot", http.StatusTeapot, "short and stout"},
+		{http.MethodGet, "/boom", http.StatusInternalServerError, "Internal Server Error"},
+	} {
+		rec := serveJSONErrors(a, tc.method, tc.path, "application/json, text/plain, */*")
+		if rec.Code != tc.code || !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+			t.Fatalf("%s %s: code=%d ct=%q", tc.method, tc.path, rec.Code, rec.Header().Get("Content-Type"))
+		}
+		var e JSONError
+		if err := json.Unmarshal(rec.Body.Bytes(), &e); err != nil {
+			t.Fatalf("%s: %v %q", tc.path, err, rec.Body.String())
+		}
+		if e != (JSONError{Code: tc.code, Message: tc.msg, RequestID: "rid-1"}) {
+			t.Fatalf("%s: body=%+v", tc.path, e)
+		}
+	}
+}
+
+func TestWithJSONErrors_Negotiation(t *testing.T) {
+	a := New(WithJSONErrors())
+	for _, accept := range []string{"", "*/*", "application/json"} {
+		if rec := serveJSONErrors(a, http.MethodGet, "/x", accept); !strings.HasPrefix(rec.Body.String(), "{") {
+			t.Fatalf("accept %q: %q", accept, rec.Body.String())
+		}
+	}
+	for _, accept := range []string{"text/plain", "text/html,application/xhtml+xml,*/*;q=0.8", "text/*"} {
+		rec := serveJSONErrors(a, http.MethodGet, "/x", accept)
+		if rec.Code != http.StatusNotFound || strings.HasPrefix(rec.Body.String(), "{") {
+			t.Fatalf("accept %q: %d %q", accept, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestWithJSONErrors_CustomBodyAndOverrides(t *testing.T) {
+	a := New(WithJSONErrors(JSONErrorConfig{
+		ContentType: "application/problem+json",
+		Body: func(e JSONError) any {
+			return map[string]any{"status": e.Code, "title": e.Message}
+		},
+	}), WithMethodNotAllowedHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_, _ = w.Write([]byte("custom"))
+	})))
+	a.GET("/x", func(c Ctx) error { return nil })
+
+	rec := serveJSONErrors(a, http.MethodGet, "/missing", "")
+	if rec.Header().Get("Content-Type") != "application/problem+json" || rec.Body.String() != `{"status":404,"title":"Not Found"}` {
+		t.Fatalf("ct=%q body=%q", rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	if rec := serveJSONErrors(a, http.MethodPut, "/x", ""); rec.Body.String() != "custom" {
+		t.Fatalf("later option not kept: %q", rec.Body.String())
+	}
+}
+
+func TestWithJSONErrors_SoftNotFound(t *testing.T) {
+	a := New(WithJSONErrors())
+	a.GET("/docs/*page", func(c Ctx) error { return ctx.ErrNotFound })
+	rec := serveJSONErrors(a, http.MethodGet, "/docs/nope", "")
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), `"code":404`) {
+		t.Fatalf("code=%d body=%q", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,206 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// KubernetesConfig tunes KubernetesPreset. Zero fields take their defaults,
+// and the durations can be set from the pod spec through environment
+// variables.
+type KubernetesConfig struct {
+	// GracePeriod is the pod's terminationGracePeriodSeconds: the time between
+	// the start of termination and SIGKILL. Default FLASH_TERMINATION_GRACE_PERIOD
+	// (seconds or a duration such as "45s"), else 30s, the Kubernetes default.
+	GracePeriod time.Duration
+	// PreStopDelay is the time a preStop hook (e.g. "sleep 5") spends before
+	// SIGTERM arrives. It counts against GracePeriod. Default
+	// FLASH_PRESTOP_DELAY, else 0.
+	PreStopDelay time.Duration
+	// DrainDelay is how long the app keeps serving after SIGTERM while its
+	// readiness probe fails, so endpoint controllers and load balancers stop
+	// routing to the pod before connections close. Default FLASH_DRAIN_DELAY,
+	// else 5s minus PreStopDelay (a preStop sleep already covers it). Negative
+	// values disable the delay.
+	DrainDelay time.Duration
+	// LivenessPath, ReadinessPath and MetricsPath are where the probes and the
+	// Prometheus metrics are served. Defaults "/livez", "/readyz" and
+	// "/metrics"; "-" disables an endpoint.
+	LivenessPath  string
+	ReadinessPath string
+	MetricsPath   string
+}
+
+// shutdownMargin is kept free at the end of the grace period so the process
+// exits on its own before the kubelet sends SIGKILL.
+const shutdownMargin = time.Second
+
+// KubernetesPreset configures the app as a well-behaved Kubernetes workload
+// in one call:
+//   - serves LivenessHandler at /livez, ReadinessHandler at /readyz and the
+//     framework metrics (WithMetrics) at /metrics, outside the middleware
+//     chain so probes are not logged, authenticated or rate limited;
+//   - sets WithConnectionCloseOnDrain, so keep-alive clients reconnect to
+//     other pods during a rollout;
+//   - adds the pod metadata exposed through the downward API (POD_NAME,
+//     falling back to HOSTNAME, POD_NAMESPACE, NODE_NAME and POD_IP) to
+//     every log record as k8s.pod.name, k8s.namespace.name, k8s.node.name and
+//     k8s.pod.ip;
+//   - makes Serve handle SIGTERM: readiness flips to 503 at once, the app
+//     keeps serving for DrainDelay, then Shutdown stops the server and runs
+//     the shutdown hooks within what is left of the grace period.
+//
+// Pass the logger with WithLogger before the preset, or set it later with
+// SetLogger and lose the pod attributes.
+//
+// Example:
+//
+//	a := flash.New(flash.WithLogger(logger), flash.KubernetesPreset())
+//	a.AddHealthCheck("postgres", db.PingContext)
+//	log.Fatal(flash.Serve(&http.Server{Addr: ":8080", Handler: a}))
+//
+// with, in the pod spec:
+//
+//	env:
+//	- name: POD_NAMESPACE
+//	  valueFrom: {fieldRef: {fieldPath: metadata.namespace}}
+//	- name: FLASH_TERMINATION_GRACE_PERIOD
+//	  value: "60"
+//	readinessProbe: {httpGet: {path: /readyz, port: 8080}}
+//	livenessProbe: {httpGet: {path: /livez, port: 8080}}
+func KubernetesPreset(cfg ...KubernetesConfig) Option {
+	var c KubernetesConfig
+	if len(cfg) > 0 {
+		c = cfg[0]
+	}
+	return func(a *DefaultApp) {
+		a.kubernetes = &c
+		a.metrics = DefaultMetrics
+		a.drainClose = true
+	}
+}
+
+// withDefaults fills in zero fields from the environment and the defaults.
+func (c KubernetesConfig) withDefaults() KubernetesConfig {
+	if c.GracePeriod <= 0 {
+		c.GracePeriod = envDuration("FLASH_TERMINATION_GRACE_PERIOD", 30*time.Second)
+	}
+	if c.PreStopDelay <= 0 {
+		c.PreStopDelay = envDuration("FLASH_PRESTOP_DELAY", 0)
+	}
+	switch {
+	case c.DrainDelay < 0:
+		c.DrainDelay = 0
+	case c.DrainDelay == 0:
+		c.DrainDelay = envDuration("FLASH_DRAIN_DELAY", max(5*time.Second-c.PreStopDelay, 0))
+	}
+	if c.LivenessPath == "" {
+		c.LivenessPath = "/livez"
+	}
+	if c.ReadinessPath == "" {
+		c.ReadinessPath = "/readyz"
+	}
+	if c.MetricsPath == "" {
+		c.MetricsPath = "/metrics"
+	}
+	return c
+}
+
+// shutdownBudget is the time from SIGTERM until the process must have exited:
+// the grace period minus the preStop hook and a safety margin, at least one
+// second.
+func (c KubernetesConfig) shutdownBudget() time.Duration {
+	return max(c.GracePeriod-c.PreStopDelay-shutdownMargin, time.Second)
+}
+
+// envDuration parses the environment variable key as a duration or a number
+// of seconds, returning def when it is unset or invalid.
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+		return time.Duration(n) * time.Second
+	}
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		return d
+	}
+	return def
+}
+
+// enableKubernetes mounts the probe and metrics endpoints and enriches the
+// logger. New calls it after applying options.
+func (a *DefaultApp) enableKubernetes() {
+	cfg := a.kubernetes.withDefaults()
+	a.kubernetes = &cfg
+	for _, ep := range []struct {
+		path string
+		h    http.Handler
+	}{
+		{cfg.LivenessPath, a.LivenessHandler()},
+		{cfg.ReadinessPath, a.ReadinessHandler()},
+		{cfg.MetricsPath, a.metrics.Handler()},
+	} {
+		if ep.path != "-" {
+			a.router.Handle(http.MethodGet, ep.path, httpHandle(ep.h))
+		}
+	}
+
+	var attrs []any
+	for _, kv := range [][3]string{
+		{"k8s.pod.name", "POD_NAME", "HOSTNAME"},
+		{"k8s.namespace.name", "POD_NAMESPACE", ""},
+		{"k8s.node.name", "NODE_NAME", ""},
+		{"k8s.pod.ip", "POD_IP", ""},
+	} {
+		v := os.Getenv(kv[1])
+		if v == "" && kv[2] != "" {
+			v = os.Getenv(kv[2])
+		}
+		if v != "" {
+			attrs = append(attrs, kv[0], v)
+		}
+	}
+	if len(attrs) > 0 {
+		a.SetLogger(a.Logger().With(attrs...))
+	}
+}
+
+// serveUntilSignal serves srv on ln until SIGTERM or SIGINT, then drains and
+// shuts down within the Kubernetes grace period. It returns nil after a
+// signal-initiated shutdown that completed in time.
+func (a *DefaultApp) serveUntilSignal(srv *http.Server, ln net.Listener) error {
+	cfg := *a.kubernetes
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(sig)
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+	select {
+	case err := <-errc:
+		return err
+	case s := <-sig:
+		budget := cfg.shutdownBudget()
+		a.Logger().Info("flash: shutting down", "signal", s.String(), "drain_delay", cfg.DrainDelay.String(), "budget", budget.String())
+		ctx, cancel := context.WithTimeout(context.Background(), budget)
+		defer cancel()
+		err := a.Shutdown(ctx, srv, cfg.DrainDelay)
+		if serveErr := <-errc; !errors.Is(serveErr, http.ErrServerClosed) {
+			err = errors.Join(err, serveErr)
+		}
+		if err != nil {
+			return fmt.Errorf("flash: shutdown after %s: %w", s, err)
+		}
+		return nil
+	}
+}
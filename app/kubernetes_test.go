@@ -0,0 +1,180 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestKubernetesConfig_Defaults(t *testing.T) {
+	for _, k := range []string{"FLASH_TERMINATION_GRACE_PERIOD", "FLASH_PRESTOP_DELAY", "FLASH_DRAIN_DELAY"} {
+		t.Setenv(k, "")
+	}
+	c := KubernetesConfig{}.withDefaults()
+	if c.GracePeriod != 30*time.Second || c.PreStopDelay != 0 || c.DrainDelay != 5*time.Second {
+		t.Fatalf("defaults = %+v", c)
+	}
+	if c.LivenessPath != "/livez" || c.ReadinessPath != "/readyz" || c.MetricsPath != "/metrics" {
+		t.Fatalf("paths = %+v", c)
+	}
+	if got := c.shutdownBudget(); got != 29*time.Second {
+		t.Fatalf("budget = %s", got)
+	}
+
+	t.Setenv("FLASH_TERMINATION_GRACE_PERIOD", "60")
+	t.Setenv("FLASH_PRESTOP_DELAY", "3s")
+	c = KubernetesConfig{}.withDefaults()
+	if c.GracePeriod != time.Minute || c.PreStopDelay != 3*time.Second || c.DrainDelay != 2*time.Second {
+		t.Fatalf("from env = %+v", c)
+	}
+	if got := c.shutdownBudget(); got != 56*time.Second {
+		t.Fatalf("budget = %s", got)
+	}
+
+	t.Setenv("FLASH_DRAIN_DELAY", "bogus")
+	t.Setenv("FLASH_PRESTOP_DELAY", "10s")
+	c = KubernetesConfig{GracePeriod: 5 * time.Second}.withDefaults()
+	if c.GracePeriod != 5*time.Second || c.DrainDelay != 0 || c.shutdownBudget() != time.Second {
+		t.Fatalf("config = %+v budget %s", c, c.shutdownBudget())
+	}
+	if c := (KubernetesConfig{DrainDelay: -1}).withDefaults(); c.DrainDelay != 0 {
+		t.Fatalf("negative drain delay = %s", c.DrainDelay)
+	}
+}
+
+func TestKubernetesPreset_Endpoints(t *testing.T) {
+	a := New(WithLogger(quietLogger()), KubernetesPreset(KubernetesConfig{MetricsPath: "-"})).(*DefaultApp)
+	a.Use(func(next Handler) Handler {
+		return func(c Ctx) error { return c.String(http.StatusUnauthorized, "no") }
+	})
+	for path, want := range map[string]int{"/livez": 200, "/readyz": 200, "/metrics": 404} {
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Fatalf("%s: code %d, want %d", path, rec.Code, want)
+		}
+	}
+	if !a.drainClose || a.metrics == nil {
+		t.Fatalf("preset should enable drain close and metrics")
+	}
+
+	a = New(WithLogger(quietLogger()), KubernetesPreset()).(*DefaultApp)
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "flash_") {
+		t.Fatalf("metrics: %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestKubernetesPreset_PodLogAttrs(t *testing.T) {
+	t.Setenv("POD_NAME", "")
+	t.Setenv("HOSTNAME", "api-7d9f-abcde")
+	t.Setenv("POD_NAMESPACE", "shop")
+	t.Setenv("NODE_NAME", "")
+	t.Setenv("POD_IP", "10.0.0.7")
+	var buf bytes.Buffer
+	a := New(WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))), KubernetesPreset())
+	a.Logger().Info("hello")
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec["k8s.pod.name"] != "api-7d9f-abcde" || rec["k8s.namespace.name"] != "shop" || rec["k8s.pod.ip"] != "10.0.0.7" {
+		t.Fatalf("record = %v", rec)
+	}
+	if _, ok := rec["k8s.node.name"]; ok {
+		t.Fatalf("unset NODE_NAME must not be logged: %v", rec)
+	}
+}
+
+func TestKubernetesPreset_SIGTERM(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGTERM cannot be sent on Windows")
+	}
+	t.Setenv(DevNotifyEnv, "")
+	t.Setenv("NOTIFY_SOCKET", "")
+	a := New(WithLogger(quietLogger()), KubernetesPreset(KubernetesConfig{GracePeriod: 5 * time.Second, DrainDelay: 300 * time.Millisecond})).(*DefaultApp)
+	a.GET("/", func(c Ctx) error { return c.String(http.StatusOK, "ok") })
+	hookRan := make(chan struct{})
+	a.OnShutdown(func(context.Context) error { close(hookRan); return nil })
+
+	ln, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: a}
+	errc := make(chan error, 1)
+	go func() { errc <- a.serveUntilSignal(srv, ln) }()
+	base := "http://" + ln.Addr().String()
+	// Without keep-alives no spare connection lingers in StateNew, which
+	// srv.Shutdown would wait for.
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	waitStatus := func(path string, want int) {
+		t.Helper()
+		deadline := time.Now().Add(3 * time.Second)
+		for {
+			resp, err := client.Get(base + path)
+			if err == nil {
+				resp.Body.Close()
+				if resp.StatusCode == want {
+					return
+				}
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s never returned %d (last err %v)", path, want, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitStatus("/readyz", http.StatusOK)
+
+	p, _ := os.FindProcess(os.Getpid())
+	if err := p.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	waitStatus("/readyz", http.StatusServiceUnavailable)
+	resp, err := client.Get(base + "/")
+	if err != nil {
+		t.Fatalf("app must keep serving during the drain delay: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !resp.Close {
+		t.Fatalf("during drain: status %d, close %v", resp.StatusCode, resp.Close)
+	}
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatalf("serve returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no shutdown after SIGTERM")
+	}
+	select {
+	case <-hookRan:
+	default:
+		t.Fatal("shutdown hooks did not run")
+	}
+}
+
+func TestKubernetesPreset_ServeError(t *testing.T) {
+	a := New(WithLogger(quietLogger()), KubernetesPreset()).(*DefaultApp)
+	ln, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.Close()
+	if err := a.serveUntilSignal(&http.Server{Handler: a}, ln); err == nil || errors.Is(err, http.ErrServerClosed) {
+		t.Fatalf("expected listener error, got %v", err)
+	}
+}
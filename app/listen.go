@@ -0,0 +1,115 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Environment variables through which the `flash dev` supervisor (see
+// cmd/flash) talks to the app process it runs.
+const (
+	DevAddrEnv   = "FLASH_DEV_ADDR"   // address the app must listen on instead of its own
+	DevNotifyEnv = "FLASH_DEV_NOTIFY" // supervisor address that NotifyReady reports to
+)
+
+// Listen opens a TCP listener on addr, or on the address in FLASH_DEV_ADDR
+// when the app runs under `flash dev`. The supervisor keeps the public port
+// and proxies to the app, so restarted processes can listen on new ports
+// while the previous one is still draining.
+//
+// Under systemd socket activation (LISTEN_FDS) Listen ignores addr and
+// returns the next inherited socket not yet handed out (see SystemdListeners),
+// so the .socket unit decides where the app listens and connections queue up
+// while the service restarts. Once all inherited sockets are taken it listens
+// on addr as usual.
+//
+// Example:
+//
+//	ln, err := flash.Listen(":8080")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	_ = flash.NotifyReady(ln)
+//	log.Fatal(srv.Serve(ln))
+func Listen(addr string) (net.Listener, error) {
+	if ln, err := nextSystemdListener(); ln != nil || err != nil {
+		return ln, err
+	}
+	if dev := os.Getenv(DevAddrEnv); dev != "" {
+		addr = dev
+	}
+	if addr == "" {
+		addr = ":http"
+	}
+	return net.Listen("tcp", addr)
+}
+
+// NotifyReady tells the `flash dev` supervisor that the app accepts requests
+// on ln, so it can switch traffic to this process and stop the previous one.
+// Under a systemd Type=notify service it sends READY=1 (see SystemdNotify), so
+// dependent units start only once the app serves. Call it once initialization
+// is done. Outside both (FLASH_DEV_NOTIFY and NOTIFY_SOCKET unset) it does
+// nothing.
+func NotifyReady(ln net.Listener) error {
+	if err := SystemdNotify("READY=1"); err != nil {
+		return err
+	}
+	notify := os.Getenv(DevNotifyEnv)
+	if notify == "" {
+		return nil
+	}
+	conn, err := net.DialTimeout("tcp", notify, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("flash: notify dev supervisor: %w", err)
+	}
+	defer conn.Close()
+	if _, err := fmt.Fprintln(conn, ln.Addr().String()); err != nil {
+		return fmt.Errorf("flash: notify dev supervisor: %w", err)
+	}
+	return nil
+}
+
+// Serve listens on srv.Addr with Listen, signals readiness with NotifyReady
+// and serves srv until it is shut down, like srv.ListenAndServe. Apps started
+// this way work unchanged under `flash dev` and as socket-activated or
+// Type=notify systemd services; when srv.Handler is the app, Serve also feeds
+// the systemd watchdog (see DefaultApp.Watchdog) until it returns. Stop it
+// with Shutdown on SIGINT/SIGTERM so restarts are graceful; apps created with
+// KubernetesPreset do this themselves and Serve returns nil once the
+// signal-initiated shutdown completes.
+//
+// Example:
+//
+//	srv := cfg.Server(a)
+//	go func() {
+//		if err := flash.Serve(srv); err != nil && !errors.Is(err, http.ErrServerClosed) {
+//			log.Fatal(err)
+//		}
+//	}()
+//	sig, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+//	defer stop()
+//	<-sig.Done()
+//	_ = a.Shutdown(context.Background(), srv, 0)
+func Serve(srv *http.Server) error {
+	ln, err := Listen(srv.Addr)
+	if err != nil {
+		return err
+	}
+	if err := NotifyReady(ln); err != nil {
+		_ = ln.Close()
+		return err
+	}
+	if a, ok := srv.Handler.(*DefaultApp); ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() { _ = a.Watchdog(ctx) }()
+		if a.kubernetes != nil {
+			return a.serveUntilSignal(srv, ln)
+		}
+	}
+	return srv.Serve(ln)
+}
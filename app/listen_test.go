@@ -0,0 +1,79 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListen_DevAddrOverrides(t *testing.T) {
+	t.Setenv(DevAddrEnv, "127.0.0.1:0")
+	ln, err := Listen(":1") // privileged port; must be ignored
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if !strings.HasPrefix(ln.Addr().String(), "127.0.0.1:") {
+		t.Fatalf("listening on %s", ln.Addr())
+	}
+}
+
+func TestNotifyReady(t *testing.T) {
+	t.Setenv(DevNotifyEnv, "")
+	if err := NotifyReady(nil); err != nil {
+		t.Fatalf("outside the supervisor NotifyReady must be a no-op: %v", err)
+	}
+
+	sup, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sup.Close()
+	t.Setenv(DevNotifyEnv, sup.Addr().String())
+	t.Setenv(DevAddrEnv, "127.0.0.1:0")
+
+	got := make(chan string, 1)
+	go func() {
+		conn, err := sup.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		got <- strings.TrimSpace(line)
+	}()
+
+	a := New(WithLogger(quietLogger()))
+	srv := &http.Server{Addr: ":1", Handler: a}
+	errc := make(chan error, 1)
+	go func() { errc <- Serve(srv) }()
+
+	var addr string
+	select {
+	case addr = <-got:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no readiness notification")
+	}
+	resp, err := http.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	_ = srv.Shutdown(context.Background())
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		t.Fatalf("Serve returned %v", err)
+	}
+
+	t.Setenv(DevNotifyEnv, "127.0.0.1:1")
+	if err := Serve(&http.Server{}); err == nil {
+		t.Fatalf("expected error when the supervisor is unreachable")
+	}
+}
@@ -0,0 +1,41 @@
+package app
+
+// Meta attaches a metadata value to the route and returns the route.
+// Middleware read it per request with Ctx.RouteMeta, so policies such as
+// latency budgets can be declared next to the route instead of in a separate
+// table. Call it at registration time, before the app serves requests.
+//
+// Example:
+//
+//	a.GET("/search", Search).Meta("slo", "100ms").Meta("team", "discovery")
+func (r *Route) Meta(key string, value any) *Route {
+	if r.meta == nil {
+		r.meta = make(map[string]any, 1)
+	}
+	r.meta[key] = value
+	return r
+}
+
+// MetaValue returns the metadata value attached with Meta, if any.
+func (r *Route) MetaValue(key string) (any, bool) {
+	v, ok := r.meta[key]
+	return v, ok
+}
+
+// Meta attaches a metadata value to the routes registered on the group
+// afterwards, including those of nested groups created afterwards, e.g. to
+// declare one policy for a whole API section. Routes can override it with
+// Route.Meta.
+//
+// Example:
+//
+//	api := a.Group("/api").Meta("team", "platform")
+//	api.GET("/users", ListUsers)                          // team=platform
+//	api.GET("/billing", Billing).Meta("team", "payments") // team=payments
+func (g *Group) Meta(key string, value any) *Group {
+	if g.meta == nil {
+		g.meta = make(map[string]any, 1)
+	}
+	g.meta[key] = value
+	return g
+}
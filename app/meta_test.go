@@ -0,0 +1,66 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteMeta(t *testing.T) {
+	a := New()
+	var got any
+	var found bool
+	show := func(c Ctx) error {
+		got, found = c.RouteMeta("owner")
+		return nil
+	}
+	rt := a.GET("/a", show).Meta("owner", "payments").Meta("slo", "50ms")
+	a.Group("/g").GET("/b", show)
+
+	if v, ok := rt.MetaValue("slo"); !ok || v != "50ms" {
+		t.Fatalf("MetaValue = %v %v", v, ok)
+	}
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/a", nil))
+	if !found || got != "payments" {
+		t.Fatalf("RouteMeta = %v %v", got, found)
+	}
+	// Pooled contexts must not leak metadata into other routes.
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/g/b", nil))
+	if found {
+		t.Fatalf("route without metadata saw %v", got)
+	}
+}
+
+func TestGroupMeta(t *testing.T) {
+	a := New()
+	api := a.Group("/api")
+	early := api.GET("/early", func(Ctx) error { return nil })
+	api.Meta("team", "platform").Meta("tier", 1)
+	users := api.GET("/users", func(Ctx) error { return nil })
+	billing := api.GET("/billing", func(Ctx) error { return nil }).Meta("team", "payments")
+	v1 := api.Group("/v1").Meta("tier", 2)
+	nested := v1.GET("/x", func(Ctx) error { return nil })
+	api.Meta("late", true)
+
+	if _, ok := early.MetaValue("team"); ok {
+		t.Fatalf("route registered before Meta got metadata")
+	}
+	if v, _ := users.MetaValue("team"); v != "platform" {
+		t.Fatalf("users team = %v", v)
+	}
+	if v, _ := billing.MetaValue("team"); v != "payments" {
+		t.Fatalf("route override = %v", v)
+	}
+	if v, _ := nested.MetaValue("team"); v != "platform" {
+		t.Fatalf("nested team = %v", v)
+	}
+	if v, _ := nested.MetaValue("tier"); v != 2 {
+		t.Fatalf("nested tier = %v", v)
+	}
+	if v, _ := users.MetaValue("tier"); v != 1 {
+		t.Fatalf("child group leaked into parent: %v", v)
+	}
+	if _, ok := users.MetaValue("late"); ok {
+		t.Fatalf("later group Meta changed a registered route")
+	}
+}
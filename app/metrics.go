@@ -0,0 +1,260 @@
+package app
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// lookupBuckets are the upper bounds of the router lookup latency histogram.
+var lookupBuckets = [...]time.Duration{
+	100 * time.Nanosecond,
+	250 * time.Nanosecond,
+	500 * time.Nanosecond,
+	time.Microsecond,
+	2500 * time.Nanosecond,
+	5 * time.Microsecond,
+	10 * time.Microsecond,
+	25 * time.Microsecond,
+	100 * time.Microsecond,
+}
+
+// Metrics counts framework internals so operators can see the health of the
+// framework itself, not just application traffic. Counters are process-wide:
+// use DefaultMetrics, which every app enabled with WithMetrics and the
+// built-in middleware (Recover, rate limiter strategies) report to.
+//
+// Request-path counters (requests, context pool, router lookups, 404/405,
+// middleware chain depth) are only recorded by apps created with
+// WithMetrics, keeping the default hot path free of shared atomics. Panics
+// recovered by middleware.Recover and rate limiter key evictions are always
+// counted since they are rare.
+//
+// Export the counters with expvar or as a Prometheus scrape endpoint:
+//
+//	a := flash.New(flash.WithMetrics())
+//	flash.DefaultMetrics.PublishExpvar("flash") // GET /debug/vars
+//	a.HandleHTTP(http.MethodGet, "/metrics", flash.DefaultMetrics.Handler())
+type Metrics struct {
+	requests         atomic.Uint64
+	poolGets         atomic.Uint64
+	poolMisses       atomic.Uint64
+	lookups          atomic.Uint64
+	lookupNanos      atomic.Uint64
+	lookupBuckets    [len(lookupBuckets)]atomic.Uint64 // non-cumulative counts
+	notFound         atomic.Uint64
+	methodNotAllowed atomic.Uint64
+	panics           atomic.Uint64
+	evictions        atomic.Uint64
+	routes           atomic.Uint64
+	chainDepthSum    atomic.Uint64
+	chainDepthMax    atomic.Uint64
+}
+
+// DefaultMetrics is the process-wide Metrics instance.
+var DefaultMetrics = &Metrics{}
+
+// MetricsSnapshot is a point-in-time copy of Metrics.
+type MetricsSnapshot struct {
+	Requests             uint64 `json:"requests"`
+	ContextPoolGets      uint64 `json:"context_pool_gets"`
+	ContextPoolHits      uint64 `json:"context_pool_hits"`
+	ContextPoolMisses    uint64 `json:"context_pool_misses"`
+	RouterLookups        uint64 `json:"router_lookups"`
+	RouterLookupNanos    uint64 `json:"router_lookup_ns_total"`
+	NotFound             uint64 `json:"not_found"`
+	MethodNotAllowed     uint64 `json:"method_not_allowed"`
+	PanicsRecovered      uint64 `json:"panics_recovered"`
+	RateLimitEvictions   uint64 `json:"ratelimit_evictions"`
+	Routes               uint64 `json:"routes"`
+	MiddlewareChainMax   uint64 `json:"middleware_chain_depth_max"`
+	MiddlewareChainTotal uint64 `json:"middleware_chain_depth_total"`
+}
+
+// Snapshot returns the current counter values.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	s := MetricsSnapshot{
+		Requests:             m.requests.Load(),
+		ContextPoolGets:      m.poolGets.Load(),
+		ContextPoolMisses:    m.poolMisses.Load(),
+		RouterLookups:        m.lookups.Load(),
+		RouterLookupNanos:    m.lookupNanos.Load(),
+		NotFound:             m.notFound.Load(),
+		MethodNotAllowed:     m.methodNotAllowed.Load(),
+		PanicsRecovered:      m.panics.Load(),
+		RateLimitEvictions:   m.evictions.Load(),
+		Routes:               m.routes.Load(),
+		MiddlewareChainMax:   m.chainDepthMax.Load(),
+		MiddlewareChainTotal: m.chainDepthSum.Load(),
+	}
+	if s.ContextPoolGets > s.ContextPoolMisses {
+		s.ContextPoolHits = s.ContextPoolGets - s.ContextPoolMisses
+	}
+	return s
+}
+
+// PanicRecovered records a panic recovered by middleware.
+func (m *Metrics) PanicRecovered() { m.panics.Add(1) }
+
+// RateLimitEvicted records n keys evicted by a rate limiter at capacity.
+func (m *Metrics) RateLimitEvicted(n int) { m.evictions.Add(uint64(n)) }
+
+// observeLookup records the time from ServeHTTP to handler dispatch.
+func (m *Metrics) observeLookup(d time.Duration) {
+	m.lookups.Add(1)
+	m.lookupNanos.Add(uint64(d))
+	for i, b := range lookupBuckets {
+		if d <= b {
+			m.lookupBuckets[i].Add(1)
+			return
+		}
+	}
+}
+
+// observeChain records the middleware chain depth of a registered route.
+func (m *Metrics) observeChain(depth int) {
+	m.routes.Add(1)
+	m.chainDepthSum.Add(uint64(depth))
+	for {
+		cur := m.chainDepthMax.Load()
+		if uint64(depth) <= cur || m.chainDepthMax.CompareAndSwap(cur, uint64(depth)) {
+			return
+		}
+	}
+}
+
+// PublishExpvar publishes the snapshot under name in expvar (served at
+// /debug/vars by expvar's handler). Like expvar.Publish, it panics if name is
+// already published.
+func (m *Metrics) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() any { return m.Snapshot() }))
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	s := m.Snapshot()
+	var err error
+	counter := func(name, help string, v uint64) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, v)
+		}
+	}
+	gauge := func(name, help string, v uint64) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %d\n", name, help, name, name, v)
+		}
+	}
+	counter("flash_requests_total", "Requests served by apps with metrics enabled.", s.Requests)
+	counter("flash_context_pool_gets_total", "Request contexts taken from the pool.", s.ContextPoolGets)
+	counter("flash_context_pool_misses_total", "Request contexts allocated because the pool was empty.", s.ContextPoolMisses)
+	counter("flash_not_found_total", "Requests that matched no route (404).", s.NotFound)
+	counter("flash_method_not_allowed_total", "Requests whose path matched only other methods (405).", s.MethodNotAllowed)
+	counter("flash_panics_recovered_total", "Panics recovered by the Recover middleware.", s.PanicsRecovered)
+	counter("flash_ratelimit_evictions_total", "Keys evicted by rate limiters at capacity.", s.RateLimitEvictions)
+	gauge("flash_routes", "Registered routes.", s.Routes)
+	gauge("flash_middleware_chain_depth_max", "Deepest middleware chain of any route.", s.MiddlewareChainMax)
+	if err != nil {
+		return err
+	}
+
+	const name = "flash_router_lookup_duration_seconds"
+	_, err = fmt.Fprintf(w, "# HELP %s Time from ServeHTTP to handler dispatch.\n# TYPE %s histogram\n", name, name)
+	var cum uint64
+	for i, b := range lookupBuckets {
+		cum += m.lookupBuckets[i].Load()
+		if err == nil {
+			_, err = fmt.Fprintf(w, "%s_bucket{le=%q} %d\n", name, strconv.FormatFloat(b.Seconds(), 'g', -1, 64), cum)
+		}
+	}
+	if err == nil {
+		_, err = fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n%s_sum %s\n%s_count %d\n", name, s.RouterLookups,
+			name, strconv.FormatFloat(time.Duration(s.RouterLookupNanos).Seconds(), 'g', -1, 64), name, s.RouterLookups)
+	}
+	return err
+}
+
+// Handler returns an http.Handler serving the metrics in the Prometheus text
+// exposition format.
+//
+// Example:
+//
+//	a.HandleHTTP(http.MethodGet, "/metrics", flash.DefaultMetrics.Handler())
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = m.WritePrometheus(w)
+	})
+}
+
+// WithMetrics records request-path metrics (requests, context pool hits and
+// misses, router lookup latency, 404/405 counts and middleware chain depth)
+// into DefaultMetrics. It costs a few atomic operations and a clock read per
+// request, so it is off by default.
+//
+// Example:
+//
+//	a := app.New(app.WithMetrics())
+//	a.HandleHTTP(http.MethodGet, "/metrics", app.DefaultMetrics.Handler())
+func WithMetrics() Option {
+	return func(a *DefaultApp) { a.metrics = DefaultMetrics }
+}
+
+// meteredRouter wraps a Router to time lookups and count 404/405 responses.
+// ServeHTTP hands the router a dispatchWriter carrying the start time; every
+// handler registered through it unwraps the writer before running, so
+// handlers always see the original http.ResponseWriter.
+type meteredRouter struct {
+	Router
+	m *Metrics
+}
+
+// dispatchWriter carries the lookup start time through the router.
+type dispatchWriter struct {
+	http.ResponseWriter
+	start time.Time
+}
+
+var dispatchWriters = sync.Pool{New: func() any { return new(dispatchWriter) }}
+
+func (mr *meteredRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mr.m.requests.Add(1)
+	dw := dispatchWriters.Get().(*dispatchWriter)
+	dw.ResponseWriter, dw.start = w, time.Now()
+	mr.Router.ServeHTTP(dw, r)
+	dw.ResponseWriter = nil
+	dispatchWriters.Put(dw)
+}
+
+// dispatched records the lookup latency and returns the original writer.
+func (mr *meteredRouter) dispatched(w http.ResponseWriter) http.ResponseWriter {
+	if dw, ok := w.(*dispatchWriter); ok {
+		mr.m.observeLookup(time.Since(dw.start))
+		return dw.ResponseWriter
+	}
+	return w
+}
+
+func (mr *meteredRouter) Handle(method, pattern string, h RouteHandler) {
+	mr.Router.Handle(method, pattern, func(w http.ResponseWriter, r *http.Request, ps Params) {
+		h(mr.dispatched(w), r, ps)
+	})
+}
+
+func (mr *meteredRouter) SetNotFound(h http.Handler) {
+	mr.Router.SetNotFound(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mr.m.notFound.Add(1)
+		h.ServeHTTP(mr.dispatched(w), r)
+	}))
+}
+
+func (mr *meteredRouter) SetMethodNotAllowed(h http.Handler) {
+	mr.Router.SetMethodNotAllowed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mr.m.methodNotAllowed.Add(1)
+		h.ServeHTTP(mr.dispatched(w), r)
+	}))
+}
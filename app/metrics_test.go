@@ -0,0 +1,101 @@
+package app
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsRecordsRequestPath(t *testing.T) {
+	before := DefaultMetrics.Snapshot()
+	a := New(WithMetrics(), WithRouter(NewTreeRouter()))
+	noop := func(next Handler) Handler { return next }
+	a.Use(noop, noop)
+	var flushable bool
+	a.GET("/x", func(c Ctx) error {
+		_, flushable = c.ResponseWriter().(http.Flusher)
+		return c.String(http.StatusOK, "ok")
+	}, noop)
+	a.HandleHTTP(http.MethodGet, "/raw", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if _, ok := w.(*dispatchWriter); ok {
+			t.Errorf("handlers must see the original writer")
+		}
+	}))
+
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/x"}, {http.MethodGet, "/x"}, {http.MethodGet, "/raw"},
+		{http.MethodGet, "/missing"}, {http.MethodPost, "/x"},
+	} {
+		a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, nil))
+	}
+	if !flushable {
+		t.Fatalf("handler writer should keep http.Flusher")
+	}
+
+	after := DefaultMetrics.Snapshot()
+	for name, got := range map[string][2]uint64{
+		"requests":  {after.Requests - before.Requests, 5},
+		"pool gets": {after.ContextPoolGets - before.ContextPoolGets, 2},
+		"lookups":   {after.RouterLookups - before.RouterLookups, 5},
+		"404":       {after.NotFound - before.NotFound, 1},
+		"405":       {after.MethodNotAllowed - before.MethodNotAllowed, 1},
+		"routes":    {after.Routes - before.Routes, 1},
+	} {
+		if got[0] != got[1] {
+			t.Fatalf("%s: got %d, want %d", name, got[0], got[1])
+		}
+	}
+	if after.MiddlewareChainMax < 3 || after.ContextPoolHits+after.ContextPoolMisses != after.ContextPoolGets {
+		t.Fatalf("unexpected snapshot %+v", after)
+	}
+}
+
+func TestMetricsDisabledByDefault(t *testing.T) {
+	before := DefaultMetrics.Snapshot().Requests
+	a := New()
+	a.GET("/x", func(c Ctx) error { return nil })
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/x", nil))
+	if _, ok := a.(*DefaultApp).router.(*meteredRouter); ok || DefaultMetrics.Snapshot().Requests != before {
+		t.Fatalf("metrics must be opt-in")
+	}
+}
+
+func TestMetricsExport(t *testing.T) {
+	m := &Metrics{}
+	m.PanicRecovered()
+	m.RateLimitEvicted(3)
+	m.observeLookup(300)
+	m.observeLookup(50000)
+	m.observeChain(4)
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE flash_panics_recovered_total counter\nflash_panics_recovered_total 1\n",
+		"flash_ratelimit_evictions_total 3\n",
+		"flash_middleware_chain_depth_max 4\n",
+		"# TYPE flash_router_lookup_duration_seconds histogram\n",
+		`flash_router_lookup_duration_seconds_bucket{le="2.5e-07"} 0` + "\n",
+		`flash_router_lookup_duration_seconds_bucket{le="5e-07"} 1` + "\n",
+		`flash_router_lookup_duration_seconds_bucket{le="0.0001"} 2` + "\n",
+		`flash_router_lookup_duration_seconds_bucket{le="+Inf"} 2` + "\n",
+		"flash_router_lookup_duration_seconds_sum 5.03e-05\nflash_router_lookup_duration_seconds_count 2\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("missing %q in\n%s", want, body)
+		}
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("content type %q", ct)
+	}
+
+	m.PublishExpvar("flash_test_metrics")
+	var snap MetricsSnapshot
+	if err := json.Unmarshal([]byte(expvar.Get("flash_test_metrics").String()), &snap); err != nil || snap.PanicsRecovered != 1 || snap.RouterLookups != 2 {
+		t.Fatalf("expvar snapshot %+v %v", snap, err)
+	}
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sentinel causes returned by RegisterModules. Use errors.Is to detect them.
+var (
+	// ErrDuplicateModule is returned when a module name is registered twice.
+	ErrDuplicateModule = errors.New("flash: duplicate module")
+	// ErrModuleDependency is returned for missing or cyclic module dependencies.
+	ErrModuleDependency = errors.New("flash: module dependency")
+)
+
+// Module bundles reusable functionality (an auth module, an admin console...)
+// that installs itself on an app: routes, middleware, health checks
+// (AddHealthCheck) and shutdown hooks (OnShutdown).
+//
+// Example:
+//
+//	type AdminModule struct{ db *sql.DB }
+//
+//	func (m *AdminModule) Name() string        { return "admin" }
+//	func (m *AdminModule) DependsOn() []string { return []string{"auth"} }
+//	func (m *AdminModule) Register(a flash.App) error {
+//		g := a.Group("/admin", RequireAdmin)
+//		g.GET("/", m.Dashboard)
+//		a.AddHealthCheck("admin-db", func(ctx context.Context) error { return m.db.PingContext(ctx) })
+//		a.OnShutdown(func(context.Context) error { return m.db.Close() })
+//		return nil
+//	}
+type Module interface {
+	// Name uniquely identifies the module within an app.
+	Name() string
+	// Register installs the module on a.
+	Register(a App) error
+}
+
+// ModuleDependent is implemented by modules that must be registered after
+// other modules, identified by name.
+type ModuleDependent interface {
+	DependsOn() []string
+}
+
+// RegisterModules registers modules so that every module comes after the
+// modules it depends on; independent modules keep the given order.
+// Dependencies may be satisfied by modules registered in an earlier call.
+//
+// Before registering anything, it validates the whole set: a name seen twice
+// (in this or an earlier call) yields ErrDuplicateModule, and a missing or
+// cyclic dependency yields ErrModuleDependency. If a module's Register fails,
+// the error is returned and the remaining modules are not registered; modules
+// registered before it stay registered.
+//
+// Example:
+//
+//	if err := a.RegisterModules(&AdminModule{db}, auth.Module(cfg)); err != nil {
+//		log.Fatal(err) // auth is registered first: admin depends on it
+//	}
+func (a *DefaultApp) RegisterModules(mods ...Module) error {
+	order, err := a.sortModules(mods)
+	if err != nil {
+		return err
+	}
+	if a.modules == nil {
+		a.modules = make(map[string]bool, len(order))
+	}
+	for _, m := range order {
+		if err := m.Register(a); err != nil {
+			return fmt.Errorf("flash: module %s: %w", m.Name(), err)
+		}
+		a.modules[m.Name()] = true
+	}
+	return nil
+}
+
+// sortModules validates mods and returns them in dependency order.
+func (a *DefaultApp) sortModules(mods []Module) ([]Module, error) {
+	byName := make(map[string]Module, len(mods))
+	for _, m := range mods {
+		name := m.Name()
+		if a.modules[name] || byName[name] != nil {
+			return nil, fmt.Errorf("%w %q", ErrDuplicateModule, name)
+		}
+		byName[name] = m
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(mods))
+	order := make([]Module, 0, len(mods))
+	var visit func(m Module, path []string) error
+	visit = func(m Module, path []string) error {
+		name := m.Name()
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w cycle: %s", ErrModuleDependency, strings.Join(append(path, name), " -> "))
+		}
+		state[name] = visiting
+		if d, ok := m.(ModuleDependent); ok {
+			for _, dep := range d.DependsOn() {
+				if a.modules[dep] {
+					continue
+				}
+				dm := byName[dep]
+				if dm == nil {
+					return fmt.Errorf("%w: %s requires %q, which is not registered", ErrModuleDependency, name, dep)
+				}
+				if err := visit(dm, append(path, name)); err != nil {
+					return err
+				}
+			}
+		}
+		state[name] = done
+		order = append(order, m)
+		return nil
+	}
+	for _, m := range mods {
+		if err := visit(m, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testModule struct {
+	name string
+	deps []string
+	err  error
+	log  *[]string
+}
+
+func (m testModule) Name() string        { return m.name }
+func (m testModule) DependsOn() []string { return m.deps }
+func (m testModule) Register(a App) error {
+	*m.log = append(*m.log, m.name)
+	if m.err != nil {
+		return m.err
+	}
+	a.GET("/"+m.name, func(c Ctx) error { return c.String(http.StatusOK, m.name) })
+	return nil
+}
+
+func TestRegisterModules_DependencyOrder(t *testing.T) {
+	var log []string
+	a := New()
+	err := a.RegisterModules(
+		testModule{name: "admin", deps: []string{"auth", "db"}, log: &log},
+		testModule{name: "auth", deps: []string{"db"}, log: &log},
+		testModule{name: "db", log: &log},
+		testModule{name: "metrics", log: &log},
+	)
+	if err != nil {
+		t.Fatalf("RegisterModules: %v", err)
+	}
+	if got := strings.Join(log, ","); got != "db,auth,admin,metrics" {
+		t.Fatalf("order = %s", got)
+	}
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin", nil))
+	if rec.Body.String() != "admin" {
+		t.Fatalf("module route not registered: %q", rec.Body.String())
+	}
+
+	// Dependencies may come from an earlier call; names must stay unique.
+	if err := a.RegisterModules(testModule{name: "billing", deps: []string{"db"}, log: &log}); err != nil {
+		t.Fatalf("dependency on earlier module: %v", err)
+	}
+	if err := a.RegisterModules(testModule{name: "db", log: &log}); !errors.Is(err, ErrDuplicateModule) {
+		t.Fatalf("expected ErrDuplicateModule, got %v", err)
+	}
+}
+
+func TestRegisterModules_ValidationErrors(t *testing.T) {
+	var log []string
+	cases := map[string]struct {
+		mods []Module
+		want error
+		msg  string
+	}{
+		"duplicate": {[]Module{testModule{name: "a", log: &log}, testModule{name: "a", log: &log}}, ErrDuplicateModule, `"a"`},
+		"missing":   {[]Module{testModule{name: "a", deps: []string{"zz"}, log: &log}}, ErrModuleDependency, `requires "zz"`},
+		"cycle": {[]Module{
+			testModule{name: "a", deps: []string{"b"}, log: &log},
+			testModule{name: "b", deps: []string{"a"}, log: &log},
+		}, ErrModuleDependency, "a -> b -> a"},
+	}
+	for name, tc := range cases {
+		err := New().RegisterModules(tc.mods...)
+		if !errors.Is(err, tc.want) || !strings.Contains(err.Error(), tc.msg) {
+			t.Fatalf("%s: got %v", name, err)
+		}
+	}
+	if len(log) != 0 {
+		t.Fatalf("modules registered despite validation errors: %v", log)
+	}
+}
+
+func TestRegisterModules_RegisterErrorStops(t *testing.T) {
+	var log []string
+	boom := errors.New("boom")
+	a := New()
+	err := a.RegisterModules(
+		testModule{name: "a", err: boom, log: &log},
+		testModule{name: "b", log: &log},
+	)
+	if !errors.Is(err, boom) || !strings.Contains(err.Error(), "module a") {
+		t.Fatalf("got %v", err)
+	}
+	if len(log) != 1 {
+		t.Fatalf("registration continued after error: %v", log)
+	}
+	// The failed module was not recorded, so it can be retried.
+	if err := a.RegisterModules(testModule{name: "a", log: &log}); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+}
@@ -1,9 +1,15 @@
 package app
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
 	"net/http"
 	"os"
-	"strings"
+
+	"github.com/goflash/flash/v2/ctx"
+	"github.com/julienschmidt/httprouter"
 )
 
 // HandleHTTP mounts a net/http.Handler on a specific HTTP method and path.
@@ -11,7 +17,9 @@ import (
 // http.Handler implementations without adapting to app.Handler.
 //
 // The handler receives the raw http.ResponseWriter and *http.Request. Use this
-// method when you want to pass through to an existing handler as-is.
+// method when you want to pass through to an existing handler as-is. Global
+// middleware does not run for it unless the app was created with
+// WithMountMiddleware; UsePrefix middleware matching path always does.
 //
 // Example:
 //
@@ -19,9 +27,94 @@ import (
 //	a.HandleHTTP(http.MethodGet, "/metrics", promhttp.Handler())
 //	_ = http.ListenAndServe(":8080", a)
 func (a *DefaultApp) HandleHTTP(method, path string, h http.Handler) {
-	a.router.Handler(method, path, h)
+	checkHTTPHandler(method, path, h)
+	a.router.Handle(method, path, a.mountHandle(method, path, h, &Route{Method: method, Path: path}))
+}
+
+// mountHandle returns the RouteHandler for a mounted net/http handler: h
+// as-is, or wrapped in the global middleware chain with WithMountMiddleware
+// and in any UsePrefix middleware matching path.
+func (a *DefaultApp) mountHandle(method, path string, h http.Handler, rt *Route) RouteHandler {
+	a.noteRegistered(method, path)
+	if !a.mountMiddleware && a.prefixMiddlewareFor(path) == nil {
+		return httpHandle(h)
+	}
+	rt.mounted = true
+	return withParams(a.compose(method, path, WrapHTTP(h), nil, rt))
+}
+
+// httpHandle adapts an http.Handler to a RouteHandler. Matched params are made
+// available via httprouter.ParamsFromContext, as httprouter's own Handler does.
+func httpHandle(h http.Handler) RouteHandler {
+	return withParams(func(w http.ResponseWriter, r *http.Request, _ Params) { h.ServeHTTP(w, r) })
+}
+
+// withParams stores matched params in the request context, as
+// httprouter.Params, before calling next.
+func withParams(next RouteHandler) RouteHandler {
+	return func(w http.ResponseWriter, r *http.Request, ps Params) {
+		if len(ps) > 0 {
+			r = r.WithContext(context.WithValue(r.Context(), httprouter.ParamsKey, toHTTPRouterParams(ps)))
+		}
+		next(w, r, ps)
+	}
+}
+
+// WrapHTTP adapts an http.Handler to a Handler, so it can be registered as a
+// regular route and run inside the app and route middleware. The status the
+// handler writes is reported to the Ctx, so middleware such as Logger sees it.
+//
+// Example:
+//
+//	a.GET("/debug/vars", app.WrapHTTP(expvar.Handler()), requireAdmin)
+func WrapHTTP(h http.Handler) Handler {
+	return func(c ctx.Ctx) error {
+		h.ServeHTTP(&mountWriter{ResponseWriter: c.ResponseWriter(), c: c}, c.Request())
+		return nil
+	}
+}
+
+// mountWriter records the status written by a mounted handler on its Ctx.
+type mountWriter struct {
+	http.ResponseWriter
+	c     ctx.Ctx
+	wrote bool
 }
 
+func (w *mountWriter) WriteHeader(code int) {
+	if !w.wrote && code >= 200 {
+		w.wrote = true
+		w.c.Status(code)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *mountWriter) Write(b []byte) (int, error) {
+	if !w.wrote {
+		w.wrote = true
+		w.c.Status(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher when the underlying writer does.
+func (w *mountWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker when the underlying writer does.
+func (w *mountWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("app: hijacking not supported")
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *mountWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
 // Mount mounts a net/http.Handler for all common HTTP methods (GET, POST, PUT,
 // PATCH, DELETE, OPTIONS, HEAD) under the given path.
 //
@@ -35,9 +128,15 @@ func (a *DefaultApp) HandleHTTP(method, path string, h http.Handler) {
 //	a := app.New()
 //	a.Mount("/admin", sr)
 //	// Now /admin/health is served by sr for GET/POST/PUT/PATCH/DELETE/OPTIONS/HEAD
+//
+// As with HandleHTTP, global middleware only runs for mounted handlers with
+// WithMountMiddleware, which also lets CORS middleware answer preflight
+// OPTIONS requests for them.
 func (a *DefaultApp) Mount(path string, h http.Handler) {
+	checkHTTPHandler("ANY", path, h)
+	rt := &Route{Method: "ANY", Path: path}
 	for _, m := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions, http.MethodHead} {
-		a.router.Handler(m, path, h)
+		a.router.Handle(m, path, a.mountHandle(m, path, h, rt))
 	}
 }
 
@@ -61,6 +160,12 @@ func (a *DefaultApp) Static(prefix, dir string) { a.StaticDirs(prefix, dir) }
 // pattern "*filepath" is registered under that prefix. The underlying handler
 // strips the prefix before serving from the merged filesystem.
 //
+// Precompressed assets: when a request for "app.js" finds an "app.js.br" or
+// "app.js.gz" sibling and the client's Accept-Encoding allows it, the sibling
+// is served as-is with Content-Encoding and the original file's Content-Type,
+// instead of compressing on the fly. "Vary: Accept-Encoding" is added whenever
+// a sibling exists. Generate siblings at build time (e.g., brotli/gzip -k).
+//
 // Security considerations:
 //   - Ensure you only expose directories meant to be public
 //   - Avoid serving dotfiles if not intended (http.FileServer will serve them)
@@ -75,10 +180,6 @@ func (a *DefaultApp) Static(prefix, dir string) { a.StaticDirs(prefix, dir) }
 //	a.StaticDirs("/assets", "./public", "./themes/default")
 //	// /assets/logo.png will be served from the first directory that contains it
 func (a *DefaultApp) StaticDirs(prefix string, dirs ...string) {
-	prefix = cleanPath(prefix)
-	if !strings.HasSuffix(prefix, "/") {
-		prefix += "/"
-	}
 	// Build a multi-filesystem from the provided directories
 	mfs := multiFS{}
 	for _, d := range dirs {
@@ -92,10 +193,7 @@ func (a *DefaultApp) StaticDirs(prefix string, dirs ...string) {
 		return
 	}
 
-	fs := http.FileServer(mfs)
-	h := http.StripPrefix(prefix, fs)
-	a.router.Handler(http.MethodGet, prefix+"*filepath", h)
-	a.router.Handler(http.MethodHead, prefix+"*filepath", h)
+	a.serveStatic(prefix, mfs)
 }
 
 // multiFS is an http.FileSystem that tries multiple underlying filesystems in
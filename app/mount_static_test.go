@@ -1,13 +1,18 @@
 package app
 
 import (
+	"bufio"
 	"errors"
 	"io/fs"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/goflash/flash/v2/ctx"
+	"github.com/julienschmidt/httprouter"
 )
 
 type fakeFS struct{ err error }
@@ -157,3 +162,119 @@ func TestMultiFS_Open_Behavior(t *testing.T) {
 		}
 	}
 }
+
+// preflightMiddleware is a minimal CORS-like global middleware: it answers
+// preflight requests itself and sets the allow-origin header otherwise.
+func preflightMiddleware(next Handler) Handler {
+	return func(c ctx.Ctx) error {
+		r := c.Request()
+		c.Header("Access-Control-Allow-Origin", "*")
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			c.Header("Access-Control-Allow-Methods", "GET, POST")
+			return c.String(http.StatusNoContent, "")
+		}
+		return next(c)
+	}
+}
+
+func TestMountMiddleware_PreflightReachesGlobalChain(t *testing.T) {
+	reached := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/users", func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.WriteHeader(http.StatusTeapot)
+	})
+	a := New(WithMountMiddleware())
+	a.Use(preflightMiddleware)
+	a.Mount("/admin/*path", mux)
+
+	req := httptest.NewRequest(http.MethodOptions, "/admin/users", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Methods") == "" {
+		t.Fatalf("preflight: got %d %v", rec.Code, rec.Header())
+	}
+	if reached {
+		t.Fatalf("preflight should not reach the mounted mux")
+	}
+
+	// Non-preflight requests pass through to the mux with CORS headers.
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/users", nil))
+	if rec.Code != http.StatusTeapot || !reached || rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatalf("get: got %d reached=%v %v", rec.Code, reached, rec.Header())
+	}
+}
+
+func TestMountMiddleware_StatusAndParams(t *testing.T) {
+	var status int
+	a := New(WithMountMiddleware())
+	a.Use(func(next Handler) Handler {
+		return func(c ctx.Ctx) error {
+			err := next(c)
+			status = c.StatusCode()
+			return err
+		}
+	})
+	a.HandleHTTP(http.MethodGet, "/files/:name", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(httprouter.ParamsFromContext(r.Context()).ByName("name")))
+	}))
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/files/a.txt", nil))
+	if rec.Code != http.StatusAccepted || rec.Body.String() != "a.txt" {
+		t.Fatalf("got %d %q", rec.Code, rec.Body.String())
+	}
+	if status != http.StatusAccepted {
+		t.Fatalf("middleware saw status %d, want %d", status, http.StatusAccepted)
+	}
+}
+
+func TestMountMiddleware_DefaultBypassesGlobalChain(t *testing.T) {
+	a := New()
+	a.Use(preflightMiddleware)
+	a.Mount("/admin/*path", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodOptions, "/admin/users", nil)
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("without the option mounted handlers must bypass middleware: got %d %v", rec.Code, rec.Header())
+	}
+}
+
+// hijackRecorder is a ResponseRecorder that can be hijacked.
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	conn net.Conn
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return h.conn, bufio.NewReadWriter(bufio.NewReader(h.conn), bufio.NewWriter(h.conn)), nil
+}
+
+// checkHijack asserts that wrap(w) forwards Hijack to a hijackable w and
+// fails cleanly on one that is not.
+func checkHijack(t *testing.T, wrap func(http.ResponseWriter) http.ResponseWriter) {
+	t.Helper()
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	conn, _, err := wrap(&hijackRecorder{ResponseRecorder: httptest.NewRecorder(), conn: server}).(http.Hijacker).Hijack()
+	if err != nil || conn != server {
+		t.Fatalf("hijack = %v, %v", conn, err)
+	}
+	if _, _, err := wrap(httptest.NewRecorder()).(http.Hijacker).Hijack(); err == nil {
+		t.Fatalf("expected error when the underlying writer cannot hijack")
+	}
+}
+
+func TestMountWriter_Hijack(t *testing.T) {
+	checkHijack(t, func(w http.ResponseWriter) http.ResponseWriter { return &mountWriter{ResponseWriter: w} })
+}
@@ -0,0 +1,32 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+// notFound answers a request that matched no route: the 404 error page when
+// one is configured, otherwise the NotFound handler.
+func (a *DefaultApp) notFound(w http.ResponseWriter, r *http.Request) {
+	if !a.renderErrorPage(w, r, http.StatusNotFound) {
+		a.NotFoundHandler().ServeHTTP(w, r)
+	}
+}
+
+// softNotFound answers a matched route whose resource does not exist (see
+// ctx.ErrNotFound) exactly like an unmatched route, including the not-found
+// metric.
+func (a *DefaultApp) softNotFound(w http.ResponseWriter, r *http.Request) {
+	if a.metrics != nil {
+		a.metrics.notFound.Add(1)
+	}
+	a.notFound(w, r)
+}
+
+// isSoftNotFound reports whether a handler error should be answered by
+// softNotFound: it is ctx.ErrNotFound and nothing was written yet.
+func isSoftNotFound(c *ctx.DefaultContext, err error) bool {
+	return errors.Is(err, ctx.ErrNotFound) && !c.WroteHeader()
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+func TestSoftNotFound_HandlerError(t *testing.T) {
+	before := DefaultMetrics.Snapshot().NotFound
+	var seen []string
+	var handled error
+	a := New(WithMetrics())
+	a.SetNotFoundHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.URL.Path)
+		http.Error(w, "custom 404", http.StatusNotFound)
+	}))
+	a.SetErrorHandler(func(c Ctx, err error) { handled = err })
+	a.GET("/docs/*page", func(c Ctx) error {
+		if c.Param("page") == "/started" {
+			_ = c.String(http.StatusOK, "partial")
+			return ctx.ErrNotFound
+		}
+		return fmt.Errorf("page %s: %w", c.Param("page"), ctx.ErrNotFound)
+	})
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/missing", nil))
+	if rec.Code != http.StatusNotFound || rec.Body.String() != "custom 404\n" {
+		t.Fatalf("code=%d body=%q", rec.Code, rec.Body.String())
+	}
+	if len(seen) != 1 || seen[0] != "/docs/missing" || handled != nil {
+		t.Fatalf("seen=%v handled=%v", seen, handled)
+	}
+	if got := DefaultMetrics.Snapshot().NotFound - before; got != 1 {
+		t.Fatalf("not found metric += %d", got)
+	}
+
+	// Once the response started, the error handler gets it as usual.
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/started", nil))
+	if len(seen) != 1 || handled != ctx.ErrNotFound || rec.Body.String() != "partial" {
+		t.Fatalf("seen=%v handled=%v body=%q", seen, handled, rec.Body.String())
+	}
+}
+
+func TestErrNotFound_Is404HTTPError(t *testing.T) {
+	var he *ctx.HTTPError
+	if !errors.As(ctx.ErrNotFound, &he) || he.Code != http.StatusNotFound {
+		t.Fatalf("ErrNotFound = %#v", ctx.ErrNotFound)
+	}
+}
+
+func TestSoftNotFound_StaticMissingFile(t *testing.T) {
+	var seen string
+	a := New()
+	a.SetNotFoundHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.URL.Path
+		http.Error(w, "custom 404", http.StatusNotFound)
+	}))
+	a.StaticFS("/assets", fstest.MapFS{"app.js": {Data: []byte("js")}})
+
+	rec := getStatic(t, a, "/assets/app.js", "")
+	if rec.Code != http.StatusOK || rec.Body.String() != "js" {
+		t.Fatalf("code=%d body=%q", rec.Code, rec.Body.String())
+	}
+	rec = getStatic(t, a, "/assets/nope.js", "")
+	if rec.Code != http.StatusNotFound || rec.Body.String() != "custom 404\n" || seen != "/assets/nope.js" {
+		t.Fatalf("code=%d body=%q seen=%q", rec.Code, rec.Body.String(), seen)
+	}
+}
@@ -0,0 +1,156 @@
+package app
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+// Option configures a DefaultApp at construction time. Options are applied by
+// New after the defaults are installed, so they override them. Every app-level
+// knob has an option, so an app can be fully configured in one expression
+// instead of by calling setters after construction; the setters keep working.
+//
+// Example:
+//
+//	a := app.New(
+//		app.WithRouter(app.NewTreeRouter()),
+//		app.WithLogger(logger),
+//		app.WithErrorHandler(renderError),
+//		app.WithNotFoundHandler(notFound),
+//		app.WithJSONEscapeHTML(false),
+//	)
+type Option func(*DefaultApp)
+
+// WithRouter replaces the route matching engine. Nil routers are ignored.
+//
+// Example (constraints and host routing):
+//
+//	a := app.New(app.WithRouter(app.NewTreeRouter()))
+//	a.GET("/users/:id<int>", ShowUser)
+func WithRouter(r Router) Option {
+	return func(a *DefaultApp) {
+		if r != nil {
+			a.router = r
+		}
+	}
+}
+
+// WithTempLimits bounds the temp files and directories each request may create
+// via Ctx.TempFile and Ctx.TempDir, and sets their parent directory.
+//
+// Example:
+//
+//	a := app.New(app.WithTempLimits(ctx.TempLimits{MaxFiles: 4, MaxBytes: 64 << 20}))
+func WithTempLimits(l ctx.TempLimits) Option {
+	return func(a *DefaultApp) { a.tempLimits = &l }
+}
+
+// WithConnectionCloseOnDrain makes responses carry "Connection: close" once the
+// app is draining (see BeginDrain), so keep-alive clients reconnect elsewhere.
+//
+// Example:
+//
+//	a := app.New(app.WithConnectionCloseOnDrain(true))
+func WithConnectionCloseOnDrain(enabled bool) Option {
+	return func(a *DefaultApp) { a.drainClose = enabled }
+}
+
+// WithErrorHandler sets the ErrorHandler (see SetErrorHandler). Nil is ignored.
+func WithErrorHandler(h ErrorHandler) Option {
+	return func(a *DefaultApp) {
+		if h != nil {
+			a.SetErrorHandler(h)
+		}
+	}
+}
+
+// WithErrorHandlerV2 sets the ErrorHandlerV2 (see SetErrorHandlerV2).
+func WithErrorHandlerV2(h ErrorHandlerV2) Option {
+	return func(a *DefaultApp) { a.SetErrorHandlerV2(h) }
+}
+
+// WithNotFoundHandler sets the 404 handler (see SetNotFoundHandler). Nil is ignored.
+func WithNotFoundHandler(h http.Handler) Option {
+	return func(a *DefaultApp) {
+		if h != nil {
+			a.SetNotFoundHandler(h)
+		}
+	}
+}
+
+// WithMethodNotAllowedHandler sets the 405 handler (see SetMethodNotAllowedHandler).
+// Nil is ignored.
+func WithMethodNotAllowedHandler(h http.Handler) Option {
+	return func(a *DefaultApp) {
+		if h != nil {
+			a.SetMethodNotAllowedHandler(h)
+		}
+	}
+}
+
+// WithLogger sets the application logger (see SetLogger). Nil is ignored.
+//
+// Example:
+//
+//	a := app.New(app.WithLogger(slog.New(slog.NewTextHandler(os.Stderr, nil))))
+func WithLogger(l *slog.Logger) Option {
+	return func(a *DefaultApp) {
+		if l != nil {
+			a.SetLogger(l)
+		}
+	}
+}
+
+// WithBindDefaults sets the app-wide binding options (see SetBindDefaults).
+//
+// Example:
+//
+//	a := app.New(app.WithBindDefaults(ctx.BindJSONOptions{ErrorUnused: true, MaxBodyBytes: 1 << 20}))
+func WithBindDefaults(o ctx.BindJSONOptions) Option {
+	return func(a *DefaultApp) { a.SetBindDefaults(o) }
+}
+
+// WithJSONEscapeHTML sets whether c.JSON escapes HTML characters (<, >, &) by
+// default. The default is true; handlers can still override it per request with
+// SetJSONEscapeHTML.
+//
+// Example:
+//
+//	a := app.New(app.WithJSONEscapeHTML(false))
+func WithJSONEscapeHTML(escape bool) Option {
+	return func(a *DefaultApp) { a.noJSONEscape = !escape }
+}
+
+// WithFlashStore sets the default store for Ctx.Flash and Ctx.Flashes. The
+// Sessions middleware overrides it with a session-backed store for the requests
+// it handles. Nil is ignored.
+//
+// Example:
+//
+//	a := app.New(app.WithFlashStore(ctx.NewCookieFlashStore(secret)))
+func WithFlashStore(s ctx.FlashStore) Option {
+	return func(a *DefaultApp) {
+		if s != nil {
+			a.flashStore = s
+		}
+	}
+}
+
+// WithMountMiddleware runs the global middleware around net/http handlers
+// registered with Mount and HandleHTTP, as for regular routes. By default they
+// receive requests directly, so CORS, logging, auth and other global
+// middleware never see them; with this option, CORS middleware answers
+// preflight OPTIONS requests for mounted sub-muxes and Logger records their
+// status. As for routes, only middleware registered with Use before the Mount
+// or HandleHTTP call applies.
+//
+// Example:
+//
+//	a := flash.New(flash.WithMountMiddleware())
+//	a.Use(middleware.CORS(middleware.CORSConfig{Origins: []string{"https://app.example.com"}}))
+//	a.Mount("/admin/*path", http.StripPrefix("/admin", adminMux))
+func WithMountMiddleware() Option {
+	return func(a *DefaultApp) { a.mountMiddleware = true }
+}
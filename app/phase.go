@@ -0,0 +1,119 @@
+package app
+
+import (
+	"slices"
+	"strconv"
+)
+
+// Phase orders middleware into well-defined stages. Middleware registered with
+// UseOrdered always runs in phase order, regardless of the order of the
+// UseOrdered calls, so a late registration cannot end up in the wrong place
+// (e.g., authentication after body parsing, or logging outside recovery).
+//
+// Phases run outermost to innermost:
+//
+//	PhaseRecover   -> panic recovery; must wrap everything else
+//	PhaseTelemetry -> request IDs, logging, metrics, tracing
+//	PhaseSecurity  -> CORS, CSRF, authentication, rate limiting
+//	PhaseParsing   -> body limits, decompression, binding helpers
+//	PhaseBusiness  -> application middleware (the phase used by Use)
+//
+// Within a phase, middleware keeps registration order.
+type Phase int
+
+const (
+	PhaseRecover Phase = iota + 1
+	PhaseTelemetry
+	PhaseSecurity
+	PhaseParsing
+	PhaseBusiness
+)
+
+// String returns the phase name, e.g. "security".
+func (p Phase) String() string {
+	switch p {
+	case PhaseRecover:
+		return "recover"
+	case PhaseTelemetry:
+		return "telemetry"
+	case PhaseSecurity:
+		return "security"
+	case PhaseParsing:
+		return "parsing"
+	case PhaseBusiness:
+		return "business"
+	}
+	return "Phase(" + strconv.Itoa(int(p)) + ")"
+}
+
+// valid reports whether p is one of the defined phases.
+func (p Phase) valid() bool { return p >= PhaseRecover && p <= PhaseBusiness }
+
+// insertPhased inserts add into the phase-sorted lists mws/phases after every
+// middleware of the same or an earlier phase, keeping both slices aligned.
+func insertPhased(mws []Middleware, phases []Phase, p Phase, add ...Middleware) ([]Middleware, []Phase) {
+	if !p.valid() {
+		panic("flash: invalid middleware phase " + p.String())
+	}
+	i := len(phases)
+	for i > 0 && phases[i-1] > p {
+		i--
+	}
+	outM := make([]Middleware, 0, len(mws)+len(add))
+	outM = append(append(append(outM, mws[:i]...), add...), mws[i:]...)
+	outP := make([]Phase, 0, len(phases)+len(add))
+	outP = append(outP, phases[:i]...)
+	for range add {
+		outP = append(outP, p)
+	}
+	return outM, append(outP, phases[i:]...)
+}
+
+// UseOrdered registers global middleware in the given phase. Middleware in an
+// earlier phase always wraps middleware in a later one; Use is equivalent to
+// UseOrdered(PhaseBusiness, ...). As with Use, only routes registered
+// afterwards are affected. It panics on an undefined phase, and with a
+// *RegistrationError on a nil middleware.
+//
+// Example:
+//
+//	// Registration order does not matter; execution is
+//	// Recover -> Logger -> CSRF -> Auth -> handler.
+//	a.UseOrdered(flash.PhaseSecurity, middleware.CSRF(), Auth)
+//	a.UseOrdered(flash.PhaseTelemetry, middleware.Logger())
+//	a.UseOrdered(flash.PhaseRecover, middleware.Recover())
+func (a *DefaultApp) UseOrdered(p Phase, mw ...Middleware) {
+	if len(mw) == 0 {
+		return
+	}
+	checkMiddleware("", "", mw)
+	var before []string
+	if a.devMode {
+		before = orderWarnings(middlewareNames(a.middleware, nil))
+	}
+	a.middleware, a.mwPhases = insertPhased(a.middleware, a.mwPhases, p, mw...)
+	if a.devMode {
+		for _, w := range orderWarnings(middlewareNames(a.middleware, nil)) {
+			if !slices.Contains(before, w) {
+				a.Logger().Warn("flash: middleware order", "warning", w)
+			}
+		}
+	}
+}
+
+// UseOrdered registers group middleware in the given phase; see
+// DefaultApp.UseOrdered. Group middleware, phased or not, always runs after
+// all global middleware.
+//
+// Example:
+//
+//	api := a.Group("/api")
+//	api.Use(LoadAccount)
+//	api.UseOrdered(flash.PhaseSecurity, RequireToken) // runs before LoadAccount
+func (g *Group) UseOrdered(p Phase, mw ...Middleware) {
+	if len(mw) == 0 {
+		return
+	}
+	checkMiddleware("", g.prefix, mw)
+	g.middleware, g.phases = insertPhased(g.middleware, g.phases, p, mw...)
+}
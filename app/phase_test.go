@@ -0,0 +1,68 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func traceMW(trace *[]string, name string) Middleware {
+	return func(next Handler) Handler {
+		return func(c Ctx) error {
+			*trace = append(*trace, name)
+			return next(c)
+		}
+	}
+}
+
+func TestUseOrdered_SortsByPhase(t *testing.T) {
+	var trace []string
+	a := New()
+	a.Use(traceMW(&trace, "biz1"))
+	a.UseOrdered(PhaseSecurity, traceMW(&trace, "auth"), traceMW(&trace, "csrf"))
+	a.UseOrdered(PhaseTelemetry, traceMW(&trace, "log"))
+	a.Use(traceMW(&trace, "biz2"))
+	a.UseOrdered(PhaseParsing, traceMW(&trace, "limit"))
+	a.UseOrdered(PhaseRecover, traceMW(&trace, "recover"))
+	a.UseOrdered(PhaseSecurity, traceMW(&trace, "ratelimit"))
+	a.UseOrdered(PhaseSecurity)
+
+	g := a.Group("/g", traceMW(&trace, "gbiz"))
+	g.UseOrdered(PhaseSecurity, traceMW(&trace, "gauth"))
+	child := g.Group("/c", traceMW(&trace, "cbiz"))
+	child.UseOrdered(PhaseTelemetry, traceMW(&trace, "ctrace"))
+	child.GET("/x", func(c Ctx) error { return nil }, traceMW(&trace, "route"))
+	a.GET("/", func(c Ctx) error { return nil })
+
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	want := "recover,log,auth,csrf,ratelimit,limit,biz1,biz2"
+	if got := strings.Join(trace, ","); got != want {
+		t.Fatalf("global order:\n got %s\nwant %s", got, want)
+	}
+
+	trace = nil
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/g/c/x", nil))
+	want = "recover,log,auth,csrf,ratelimit,limit,biz1,biz2,ctrace,gauth,gbiz,cbiz,route"
+	if got := strings.Join(trace, ","); got != want {
+		t.Fatalf("group order:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestUseOrdered_InvalidPhasePanics(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), "Phase(9)") {
+			t.Fatalf("expected invalid phase panic, got %v", r)
+		}
+	}()
+	New().UseOrdered(Phase(9), func(next Handler) Handler { return next })
+}
+
+func TestPhase_String(t *testing.T) {
+	names := []string{"recover", "telemetry", "security", "parsing", "business"}
+	for i, n := range names {
+		if Phase(i+1).String() != n {
+			t.Fatalf("Phase(%d) = %s", i+1, Phase(i+1))
+		}
+	}
+}
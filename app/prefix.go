@@ -0,0 +1,139 @@
+package app
+
+import (
+	"path"
+	"strings"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+// prefixMiddleware is middleware registered with UsePrefix.
+type prefixMiddleware struct {
+	prefix string // cleaned, without trailing slash; "/" matches everything
+	mw     []Middleware
+}
+
+// UsePrefix registers middleware for every route whose path lies under
+// prefix, however the route is registered: directly on the app, in any group
+// (nested or built elsewhere), or with Mount and HandleHTTP. Cross-cutting
+// concerns for a URL space, e.g. authentication for "/admin", then do not
+// depend on every package receiving the right group.
+//
+// The prefix matches whole path segments: "/api" matches "/api",
+// "/api/users" and "/api/*filepath" but not "/apiv2". A ":param" segment of
+// the prefix matches any segment ("/t/:tenant" covers "/t/acme/users").
+// Routes whose param or catch-all segments may or may not fall under the
+// prefix, such as "/:section/users" or "/*filepath" for "/admin", run the
+// middleware only for request paths under it, compared after cleaning dot
+// segments and repeated slashes. The host part of TreeRouter
+// host patterns ("api.example.com/admin/users") is ignored.
+//
+// Prefix middleware runs after global middleware and before group and route
+// middleware; several matching UsePrefix registrations run in the order
+// added. Mounted handlers get prefix middleware even without
+// WithMountMiddleware, but not the global middleware in that case. Handlers
+// registered with Static, Assets or the upload helpers are not covered.
+//
+// UsePrefix must be called before the routes it covers are registered, so a
+// route can never be left without the middleware by registration order: it
+// panics with a *RegistrationError if a route under prefix already exists,
+// and on a nil middleware.
+//
+// Example:
+//
+//	a.UsePrefix("/admin", RequireAdmin)
+//	admin.Register(a.Group("/admin/reports"))  // RequireAdmin applies
+//	a.Mount("/admin/pprof", pprofMux)          // RequireAdmin applies
+//	a.GET("/administrators", ListAdmins)       // not under /admin
+func (a *DefaultApp) UsePrefix(prefix string, mw ...Middleware) {
+	if len(mw) == 0 {
+		return
+	}
+	checkMiddleware("", prefix, mw)
+	prefix = cleanPath(prefix)
+	if prefix != "/" {
+		prefix = strings.TrimSuffix(prefix, "/")
+	}
+	for _, r := range a.registered {
+		if ok, _ := matchPrefix(r.Path, prefix); ok {
+			panic(registrationError("", prefix, "UsePrefix after the route "+r.Method+" "+r.Path+" under it was registered"))
+		}
+	}
+	a.prefixes = append(a.prefixes, prefixMiddleware{prefix: prefix, mw: append([]Middleware(nil), mw...)})
+}
+
+// noteRegistered records a route pattern for the UsePrefix ordering check.
+func (a *DefaultApp) noteRegistered(method, path string) {
+	a.registered = append(a.registered, Route{Method: method, Path: path})
+}
+
+// prefixMiddlewareFor returns the UsePrefix middleware for the route pattern
+// path, in registration order, or nil if none matches. Middleware of
+// prefixes the pattern only may fall under is guarded by a request path check.
+func (a *DefaultApp) prefixMiddlewareFor(path string) []Middleware {
+	var out []Middleware
+	for _, p := range a.prefixes {
+		ok, always := matchPrefix(path, p.prefix)
+		if !ok {
+			continue
+		}
+		if always {
+			out = append(out, p.mw...)
+			continue
+		}
+		for _, m := range p.mw {
+			out = append(out, guardPrefix(p.prefix, m))
+		}
+	}
+	return out
+}
+
+// guardPrefix returns m restricted to requests whose path lies under prefix.
+// It keeps m's name and config for Route.Middleware and the timing breakdown.
+func guardPrefix(prefix string, m Middleware) Middleware {
+	var config any
+	if d, ok := m.description(); ok {
+		config = d.config
+	}
+	return Describe(m.Name(), config, func(next Handler) Handler {
+		inner := m(next)
+		return func(c ctx.Ctx) error {
+			// Match the canonical path, so "//admin" or "/x/../admin"
+			// cannot slip past a guard for "/admin".
+			if ok, _ := matchPrefix(path.Clean("/"+c.Request().URL.Path), prefix); ok {
+				return inner(c)
+			}
+			return next(c)
+		}
+	})
+}
+
+// matchPrefix reports whether the route pattern lies under prefix on segment
+// boundaries. ok reports a possible match; always is false when the match
+// depends on the values of the pattern's param or catch-all segments. A host
+// part of the pattern is ignored. Request paths can be checked too: their
+// segments are literal, except that ":" and "*" segments err towards a match.
+func matchPrefix(pattern, prefix string) (ok, always bool) {
+	if prefix == "/" {
+		return true, true
+	}
+	_, path := splitHostPattern(pattern)
+	segs := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	always = true
+	for i, p := range strings.Split(prefix[1:], "/") {
+		if i >= len(segs) {
+			return false, false
+		}
+		s := segs[i]
+		switch {
+		case strings.HasPrefix(s, "*"):
+			return true, false
+		case strings.HasPrefix(p, ":"):
+		case strings.HasPrefix(s, ":"):
+			always = false
+		case s != p:
+			return false, false
+		}
+	}
+	return true, always
+}
@@ -0,0 +1,250 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUsePrefix_RoutesAndGroups(t *testing.T) {
+	var trace []string
+	a := New()
+	a.Use(traceMW(&trace, "global"))
+	a.UsePrefix("/api", traceMW(&trace, "api"))
+	a.UsePrefix("/api/v1/", traceMW(&trace, "v1"))
+	a.UsePrefix("/api", traceMW(&trace, "api2"))
+	a.UsePrefix("/api")
+
+	h := func(c Ctx) error { return nil }
+	a.GET("/api", h)
+	g := a.Group("/api", traceMW(&trace, "group"))
+	g.Group("/v1").GET("/users/:id", h, traceMW(&trace, "route"))
+	a.GET("/apiv2", h)
+	a.GET("/other", h)
+
+	cases := map[string]string{
+		"/api":            "global,api,api2",
+		"/api/v1/users/1": "global,api,v1,api2,group,route",
+		"/apiv2":          "global",
+		"/other":          "global",
+	}
+	for path, want := range cases {
+		trace = nil
+		a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		if got := strings.Join(trace, ","); got != want {
+			t.Errorf("%s: trace = %q, want %q", path, got, want)
+		}
+	}
+
+	for _, r := range a.Routes() {
+		if r.Path == "/api/v1/users/:id" && len(r.Middleware()) != 6 {
+			t.Errorf("Middleware() = %v, want 6 entries", r.Middleware())
+		}
+	}
+}
+
+func TestUsePrefix_PanicsAfterRoutes(t *testing.T) {
+	cases := map[string]func(a App){
+		"route":     func(a App) { a.GET("/admin/users", func(c Ctx) error { return nil }) },
+		"group":     func(a App) { a.Group("/admin").GET("/", func(c Ctx) error { return nil }) },
+		"mount":     func(a App) { a.HandleHTTP(http.MethodGet, "/admin/raw", http.NotFoundHandler()) },
+		"param":     func(a App) { a.GET("/:section/users", func(c Ctx) error { return nil }) },
+		"catch-all": func(a App) { a.GET("/*filepath", func(c Ctx) error { return nil }) },
+	}
+	for name, register := range cases {
+		t.Run(name, func(t *testing.T) {
+			a := New()
+			register(a)
+			defer func() {
+				e, ok := recover().(*RegistrationError)
+				if !ok || e.Pattern != "/admin" || !strings.Contains(e.Reason, "UsePrefix after the route") {
+					t.Fatalf("recover = %v", e)
+				}
+			}()
+			a.UsePrefix("/admin", func(next Handler) Handler { return next })
+		})
+	}
+
+	a := New()
+	a.GET("/administrators", func(c Ctx) error { return nil })
+	a.GET("/public/:id", func(c Ctx) error { return nil })
+	a.UsePrefix("/admin", func(next Handler) Handler { return next }) // no route under /admin
+}
+
+func TestUsePrefix_ParamPatterns(t *testing.T) {
+	var trace []string
+	a := New(WithRouter(NewTreeRouter()))
+	a.UsePrefix("/admin", traceMW(&trace, "admin"))
+	a.UsePrefix("/t/:tenant/billing", traceMW(&trace, "billing"))
+	h := func(c Ctx) error { return nil }
+	a.GET("/:section/users", h)
+	a.GET("/files/*filepath", h)
+	a.GET("/t/:tenant/billing/invoices", h)
+
+	cases := map[string]string{
+		"/admin/users":             "admin",
+		"/shop/users":              "",
+		"/files/admin/x":           "",
+		"/t/acme/billing/invoices": "billing",
+	}
+	for path, want := range cases {
+		trace = nil
+		a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		if got := strings.Join(trace, ","); got != want {
+			t.Errorf("%s: trace = %q, want %q", path, got, want)
+		}
+	}
+	for _, r := range a.Routes() {
+		if r.Path == "/:section/users" && strings.Join(r.Middleware(), ",") != "app.traceMW" {
+			t.Errorf("Middleware() = %v", r.Middleware())
+		}
+	}
+}
+
+func TestUsePrefix_NonCanonicalPaths(t *testing.T) {
+	a := New()
+	deny := func(next Handler) Handler {
+		return func(c Ctx) error { return c.String(http.StatusForbidden, "denied") }
+	}
+	a.UsePrefix("/admin", deny)
+	a.GET("/*path", func(c Ctx) error { return c.String(http.StatusOK, "ok") })
+
+	for _, p := range []string{"/admin/secret", "//admin/secret", "/./admin/secret", "/x/../admin/secret", "/admin//secret", "/admin"} {
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, p, nil))
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s: code = %d, want %d", p, rec.Code, http.StatusForbidden)
+		}
+	}
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/administrators", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("/administrators: code = %d", rec.Code)
+	}
+}
+
+func TestUsePrefix_HostPatterns(t *testing.T) {
+	var trace []string
+	a := New(WithRouter(NewTreeRouter()))
+	a.UsePrefix("/admin", traceMW(&trace, "admin"))
+	h := func(c Ctx) error { return nil }
+	a.GET("api.example.com/admin/users", h)
+	a.GET(":tenant.example.com/admin/users", h)
+	a.GET("api.example.com/users", h)
+
+	cases := []struct{ host, path, want string }{
+		{"api.example.com", "/admin/users", "admin"},
+		{"acme.example.com", "/admin/users", "admin"},
+		{"api.example.com", "/users", ""},
+	}
+	for _, tc := range cases {
+		trace = nil
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Host = tc.host
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		if got := strings.Join(trace, ","); rec.Code != http.StatusOK || got != tc.want {
+			t.Errorf("%s%s: code %d, trace = %q, want %q", tc.host, tc.path, rec.Code, got, tc.want)
+		}
+	}
+}
+
+func TestUsePrefix_Root(t *testing.T) {
+	var trace []string
+	a := New()
+	a.UsePrefix("", traceMW(&trace, "all"))
+	a.GET("/x", func(c Ctx) error { return nil })
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/x", nil))
+	if strings.Join(trace, ",") != "all" {
+		t.Fatalf("trace = %v", trace)
+	}
+}
+
+func TestUsePrefix_Mounted(t *testing.T) {
+	var trace []string
+	deny := func(next Handler) Handler {
+		return func(c Ctx) error {
+			trace = append(trace, "deny")
+			return c.String(http.StatusForbidden, "no")
+		}
+	}
+	mux := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("mux")) })
+
+	a := New()
+	a.Use(traceMW(&trace, "global"))
+	a.UsePrefix("/admin", deny)
+	a.Mount("/admin/pprof/*rest", mux)
+	a.HandleHTTP(http.MethodGet, "/admin/raw", mux)
+	a.HandleHTTP(http.MethodGet, "/public", mux)
+
+	for _, path := range []string{"/admin/pprof/heap", "/admin/raw"} {
+		trace = nil
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("%s: status = %d, want 403", path, rec.Code)
+		}
+		if strings.Join(trace, ",") != "deny" {
+			t.Fatalf("%s: trace = %v, want global middleware skipped", path, trace)
+		}
+	}
+
+	trace = nil
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/public", nil))
+	if rec.Body.String() != "mux" || len(trace) != 0 {
+		t.Fatalf("/public: body %q, trace %v", rec.Body.String(), trace)
+	}
+}
+
+func TestUsePrefix_MountMiddleware(t *testing.T) {
+	var trace []string
+	a := New(WithMountMiddleware())
+	a.Use(traceMW(&trace, "global"))
+	a.UsePrefix("/admin", traceMW(&trace, "admin"))
+	a.HandleHTTP(http.MethodGet, "/admin/raw", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/admin/raw", nil))
+	if got := strings.Join(trace, ","); got != "global,admin" {
+		t.Fatalf("trace = %q", got)
+	}
+}
+
+func TestUsePrefix_NilMiddleware(t *testing.T) {
+	defer func() {
+		e, ok := recover().(*RegistrationError)
+		if !ok || e.Pattern != "/admin" {
+			t.Fatalf("recover = %v", e)
+		}
+	}()
+	New().UsePrefix("/admin", nil)
+}
+
+func TestMatchPrefix(t *testing.T) {
+	cases := []struct {
+		path, prefix string
+		ok, always   bool
+	}{
+		{"/api", "/api", true, true},
+		{"/api/x", "/api", true, true},
+		{"/api/", "/api", true, true},
+		{"/api/*filepath", "/api", true, true},
+		{"/apiv2", "/api", false, false},
+		{"/", "/api", false, false},
+		{"/anything", "/", true, true},
+		{"/t/:tenant/x", "/t/:tenant", true, true},
+		{"/t/acme/x", "/t/:tenant", true, true},
+		{"/:section/users", "/admin", true, false},
+		{"/:section", "/admin/users", false, false},
+		{"/*filepath", "/admin", true, false},
+		{"/api/:version/x", "/api/v1", true, false},
+		{"api.example.com/api/x", "/api", true, true},
+		{"api.example.com/x", "/api", false, false},
+	}
+	for _, c := range cases {
+		if ok, always := matchPrefix(c.path, c.prefix); ok != c.ok || always != c.always {
+			t.Errorf("matchPrefix(%q, %q) = %v, %v, want %v, %v", c.path, c.prefix, ok, always, c.ok, c.always)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+// ErrNotAcceptable is wrapped by the 406 errors of routes declared with
+// Route.Produces, so error handlers can recognize them with errors.Is.
+var ErrNotAcceptable = errors.New("flash: not acceptable")
+
+// Produces declares the media types the route responds with and returns the
+// route. Requests whose Accept header allows none of them fail with a 406
+// *ctx.HTTPError wrapping ErrNotAcceptable before the handler runs (route and
+// global middleware still run, so the request is logged and recovered as
+// usual). Requests without an Accept header are always served. The types are
+// also listed by ProducedTypes for documentation and API description
+// generators, and shown by DocsHandler.
+//
+// Matching follows RFC 9110: the most specific media range for a type decides
+// its quality, so "application/json;q=0, */*" rejects a JSON-only route.
+//
+// Example:
+//
+//	a.GET("/users/:id", ShowUser).Produces("application/json")
+//	a.GET("/report", Report).Produces("text/csv", "application/pdf")
+func (r *Route) Produces(types ...string) *Route {
+	for _, t := range types {
+		mt, _, _ := strings.Cut(t, ";")
+		r.produces = append(r.produces, strings.ToLower(strings.TrimSpace(mt)))
+	}
+	return r
+}
+
+// ProducedTypes returns the media types declared with Produces.
+func (r *Route) ProducedTypes() []string {
+	return append([]string(nil), r.produces...)
+}
+
+// checkProduces wraps h to answer 406 when rt declares produced types that the
+// request's Accept header does not allow. Produces is usually called after
+// registration, so rt is consulted per request.
+func checkProduces(rt *Route, h Handler) Handler {
+	return func(c ctx.Ctx) error {
+		if rt.produces != nil {
+			if accept := c.Request().Header.Get("Accept"); accept != "" && !acceptsAny(accept, rt.produces) {
+				return &ctx.HTTPError{
+					Code:    http.StatusNotAcceptable,
+					Message: "not acceptable; available: " + strings.Join(rt.produces, ", "),
+					Err:     ErrNotAcceptable,
+				}
+			}
+		}
+		return h(c)
+	}
+}
+
+// acceptsAny reports whether the Accept header value allows at least one of
+// the lower-cased media types.
+func acceptsAny(accept string, types []string) bool {
+	for _, t := range types {
+		if acceptQuality(accept, t) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptQuality returns the quality the Accept header assigns to media type
+// t, using the most specific matching media range, or 0 if none matches.
+func acceptQuality(accept, t string) float64 {
+	major, _, _ := strings.Cut(t, "/")
+	best, q := 0, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		rng, params, _ := strings.Cut(part, ";")
+		rng = strings.ToLower(strings.TrimSpace(rng))
+		spec := 0
+		switch {
+		case rng == t:
+			spec = 3
+		case rng == major+"/*":
+			spec = 2
+		case rng == "*/*" || rng == "*":
+			spec = 1
+		}
+		if spec <= best {
+			continue
+		}
+		best, q = spec, 1
+		for _, p := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(p, "=")
+			if strings.EqualFold(strings.TrimSpace(k), "q") {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					q = f
+				}
+			}
+		}
+	}
+	return q
+}
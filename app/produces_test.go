@@ -0,0 +1,112 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAcceptQuality(t *testing.T) {
+	cases := []struct {
+		accept, typ string
+		want        float64
+	}{
+		{"application/json", "application/json", 1},
+		{"Application/JSON", "application/json", 1},
+		{"text/html, application/*;q=0.5", "application/json", 0.5},
+		{"*/*;q=0.1", "text/csv", 0.1},
+		{"*", "text/csv", 1},
+		{"application/json;q=0, */*", "application/json", 0},
+		{"application/json;q=0, */*", "text/plain", 1},
+		{"text/*;q=0, text/csv", "text/csv", 1},
+		{"text/html", "application/json", 0},
+		{"application/json; charset=utf-8; q=0.8", "application/json", 0.8},
+		{"application/json;q=bogus", "application/json", 1},
+	}
+	for _, tc := range cases {
+		if got := acceptQuality(tc.accept, tc.typ); got != tc.want {
+			t.Fatalf("acceptQuality(%q, %q) = %v, want %v", tc.accept, tc.typ, got, tc.want)
+		}
+	}
+}
+
+func TestRouteProduces(t *testing.T) {
+	a := New()
+	var handled bool
+	rt := a.GET("/users/:id", func(c Ctx) error {
+		handled = true
+		return c.JSON(map[string]string{"id": c.Param("id")})
+	}).Produces("application/json; charset=utf-8", "Application/XML")
+	if got := rt.ProducedTypes(); len(got) != 2 || got[0] != "application/json" || got[1] != "application/xml" {
+		t.Fatalf("ProducedTypes = %v", got)
+	}
+
+	serve := func(accept string) *httptest.ResponseRecorder {
+		handled = false
+		req := httptest.NewRequest(http.MethodGet, "/users/7", nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		return rec
+	}
+	for _, accept := range []string{"", "application/json", "application/*", "text/html, */*;q=0.1", "application/xml"} {
+		if rec := serve(accept); rec.Code != http.StatusOK || !handled {
+			t.Fatalf("Accept %q: %d handled=%v", accept, rec.Code, handled)
+		}
+	}
+	rec := serve("text/html")
+	if rec.Code != http.StatusNotAcceptable || handled {
+		t.Fatalf("text/html: %d handled=%v", rec.Code, handled)
+	}
+	if !strings.Contains(rec.Body.String(), "available: application/json, application/xml") {
+		t.Fatalf("body = %q", rec.Body.String())
+	}
+}
+
+func TestRouteProducesRunsMiddleware(t *testing.T) {
+	a := New()
+	var mwSaw error
+	var handlerErr error
+	a.SetErrorHandler(func(c Ctx, err error) {
+		handlerErr = err
+		_ = c.String(http.StatusNotAcceptable, "nope")
+	})
+	a.Use(func(next Handler) Handler {
+		return func(c Ctx) error {
+			mwSaw = next(c)
+			return mwSaw
+		}
+	})
+	a.GET("/r", func(c Ctx) error { return nil }).Produces("text/csv")
+	a.GET("/free", func(c Ctx) error { return c.String(http.StatusOK, "ok") })
+
+	req := httptest.NewRequest(http.MethodGet, "/r", nil)
+	req.Header.Set("Accept", "application/json")
+	a.ServeHTTP(httptest.NewRecorder(), req)
+	if !errors.Is(mwSaw, ErrNotAcceptable) || !errors.Is(handlerErr, ErrNotAcceptable) {
+		t.Fatalf("middleware saw %v, error handler got %v", mwSaw, handlerErr)
+	}
+
+	// Routes without Produces ignore Accept.
+	req = httptest.NewRequest(http.MethodGet, "/free", nil)
+	req.Header.Set("Accept", "image/png")
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("route without Produces: %d", rec.Code)
+	}
+}
+
+func TestDocsShowsProduces(t *testing.T) {
+	a := New()
+	a.GET("/report", func(c Ctx) error { return nil }).Produces("text/csv")
+	rec := httptest.NewRecorder()
+	a.DocsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_docs", nil))
+	if !strings.Contains(rec.Body.String(), "Produces: <code>text/csv</code>") {
+		t.Fatalf("docs page missing produced types:\n%s", rec.Body.String())
+	}
+}
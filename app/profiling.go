@@ -0,0 +1,18 @@
+package app
+
+// WithProfilingLabels runs every route handler under pprof labels "method"
+// and "route" (the route pattern, e.g. "/users/:id") using pprof.Do, so CPU
+// and goroutine profiles can be sliced by endpoint:
+//
+//	go tool pprof -tagfocus=route=/search http://localhost:6060/debug/pprof/profile
+//
+// Middleware can add labels for the rest of the request with
+// ctx.WithProfilingLabels; middleware.Tenant adds "tenant". Labels cost a
+// context copy per request, so they are off by default.
+//
+// Example:
+//
+//	a := app.New(app.WithProfilingLabels())
+func WithProfilingLabels() Option {
+	return func(a *DefaultApp) { a.profilingLabels = true }
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+func TestProfilingLabels(t *testing.T) {
+	a := New(WithProfilingLabels())
+	got := map[string]string{}
+	a.GET("/users/:id", func(c Ctx) error {
+		rc := ctx.WithProfilingLabels(c.Context(), "tenant", "acme")
+		c.SetRequest(c.Request().WithContext(rc))
+		pprof.ForLabels(c.Context(), func(k, v string) bool {
+			got[k] = v
+			return true
+		})
+		return nil
+	})
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/7", nil))
+	want := map[string]string{"method": "GET", "route": "/users/:id", "tenant": "acme"}
+	if len(got) != len(want) {
+		t.Fatalf("labels = %v", got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("labels = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestProfilingLabelsDisabled(t *testing.T) {
+	a := New()
+	var labeled bool
+	a.GET("/", func(c Ctx) error {
+		rc := ctx.WithProfilingLabels(c.Context(), "tenant", "acme")
+		pprof.ForLabels(rc, func(string, string) bool { labeled = true; return false })
+		return nil
+	})
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if labeled {
+		t.Fatalf("labels must not be added unless enabled")
+	}
+	if rc := ctx.WithProfilingLabels(context.Background(), "k", "v"); rc != context.Background() {
+		t.Fatalf("expected parent to be returned unchanged")
+	}
+}
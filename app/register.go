@@ -0,0 +1,119 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// RegistrationError is the panic value raised when a nil handler or
+// middleware is registered. Registering nil would otherwise only fail with a
+// nil-dereference when the first request reaches the route, far from the
+// mistake; the error names the route and the application code that
+// registered it instead:
+//
+//	flash: nil handler for GET /users/:id (registered at /src/api/routes.go:42)
+type RegistrationError struct {
+	Method  string // HTTP method of the route; empty for middleware added with Use
+	Pattern string // route pattern, or group prefix for middleware added with Use
+	Reason  string // what was nil, e.g. "nil handler" or "nil middleware #2"
+	File    string // source file of the registering call, if known
+	Line    int    // line of the registering call
+}
+
+// Error implements error.
+func (e *RegistrationError) Error() string {
+	var b strings.Builder
+	b.WriteString("flash: ")
+	b.WriteString(e.Reason)
+	switch {
+	case e.Method != "":
+		b.WriteString(" for " + e.Method + " " + e.Pattern)
+	case e.Pattern != "":
+		b.WriteString(" in group " + e.Pattern)
+	}
+	if e.File != "" {
+		b.WriteString(" (registered at " + e.File + ":" + strconv.Itoa(e.Line) + ")")
+	}
+	return b.String()
+}
+
+// registrationError returns a RegistrationError located at the first caller
+// outside the framework.
+func registrationError(method, pattern, reason string) *RegistrationError {
+	e := &RegistrationError{Method: method, Pattern: pattern, Reason: reason}
+	e.File, e.Line = callerLocation()
+	return e
+}
+
+// frameworkPackages are the function name prefixes of the packages whose
+// frames callerLocation skips.
+var frameworkPackages = []string{"github.com/goflash/flash/v2/app.", "github.com/goflash/flash/v2."}
+
+// callerLocation returns the file and line of the innermost stack frame
+// outside the framework's registration code. Test files count as callers, so
+// tests of the framework itself report their own lines.
+func callerLocation() (string, int) {
+	var pcs [32]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs[:])])
+	for {
+		f, more := frames.Next()
+		internal := false
+		for _, p := range frameworkPackages {
+			if strings.HasPrefix(f.Function, p) && !strings.Contains(f.Function[len(p):], "/") {
+				internal = !strings.HasSuffix(f.File, "_test.go")
+				break
+			}
+		}
+		if !internal && f.File != "" {
+			return f.File, f.Line
+		}
+		if !more {
+			return "", 0
+		}
+	}
+}
+
+// checkHandler panics with a *RegistrationError if h or any of mws is nil.
+func checkHandler(method, pattern string, h Handler, mws []Middleware) {
+	if h == nil {
+		panic(registrationError(method, pattern, "nil handler"))
+	}
+	checkMiddleware(method, pattern, mws)
+}
+
+// checkMiddleware panics with a *RegistrationError if any of mws is nil.
+func checkMiddleware(method, pattern string, mws []Middleware) {
+	for i, mw := range mws {
+		if mw == nil {
+			panic(registrationError(method, pattern, "nil middleware #"+strconv.Itoa(i+1)))
+		}
+	}
+}
+
+// checkHTTPHandler panics with a *RegistrationError if h is nil.
+func checkHTTPHandler(method, pattern string, h http.Handler) {
+	if f, ok := h.(http.HandlerFunc); h == nil || ok && f == nil {
+		panic(registrationError(method, pattern, "nil http.Handler"))
+	}
+}
+
+// composeChain wraps h in route middleware mws and then the global
+// middleware global, panicking with a *RegistrationError if a middleware returns a
+// nil handler.
+func (a *DefaultApp) composeChain(method, pattern string, h Handler, global, mws []Middleware) Handler {
+	final := h
+	for i := len(mws) - 1; i >= 0; i-- {
+		if final = mws[i](final); final == nil {
+			panic(registrationError(method, pattern, fmt.Sprintf("middleware #%d returned a nil handler", i+1)))
+		}
+	}
+	for i := len(global) - 1; i >= 0; i-- {
+		if final = global[i](final); final == nil {
+			panic(registrationError(method, pattern, fmt.Sprintf("global middleware #%d returned a nil handler", i+1)))
+		}
+	}
+	return final
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// registrationPanic runs fn and returns the *RegistrationError it panics with.
+func registrationPanic(t *testing.T, fn func()) (re *RegistrationError) {
+	t.Helper()
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.As(err, &re) {
+			t.Fatalf("expected *RegistrationError panic, got %v", err)
+		}
+	}()
+	fn()
+	return nil
+}
+
+func TestRegistrationNilHandler(t *testing.T) {
+	a := New()
+	re := registrationPanic(t, func() { a.GET("/users/:id", nil) })
+	if re.Method != http.MethodGet || re.Pattern != "/users/:id" || re.Reason != "nil handler" {
+		t.Fatalf("error = %+v", re)
+	}
+	if !strings.HasSuffix(re.File, "register_test.go") || re.Line == 0 {
+		t.Fatalf("location = %s:%d", re.File, re.Line)
+	}
+	if msg := re.Error(); !strings.HasPrefix(msg, "flash: nil handler for GET /users/:id (registered at ") {
+		t.Fatalf("message = %q", msg)
+	}
+
+	registrationPanic(t, func() { a.ANY("/any", nil) })
+	registrationPanic(t, func() { a.Handle("REPORT", "/dav", nil) })
+	re = registrationPanic(t, func() { a.Group("/api").POST("/items", nil) })
+	if re.Pattern != "/api/items" {
+		t.Fatalf("group pattern = %q", re.Pattern)
+	}
+}
+
+func TestRegistrationNilMiddleware(t *testing.T) {
+	a := New()
+	h := func(c Ctx) error { return nil }
+	mw := func(next Handler) Handler { return next }
+
+	re := registrationPanic(t, func() { a.GET("/x", h, mw, nil) })
+	if re.Reason != "nil middleware #2" || re.Pattern != "/x" {
+		t.Fatalf("error = %+v", re)
+	}
+	re = registrationPanic(t, func() { a.Use(mw, nil) })
+	if re.Error() != "flash: nil middleware #2 (registered at "+re.File+":"+strconv.Itoa(re.Line)+")" {
+		t.Fatalf("message = %q", re.Error())
+	}
+	re = registrationPanic(t, func() { a.Group("/api", nil) })
+	if !strings.HasPrefix(re.Error(), "flash: nil middleware #1 in group /api (registered at ") {
+		t.Fatalf("message = %q", re.Error())
+	}
+	g := a.Group("/v1")
+	registrationPanic(t, func() { g.Use(nil) })
+	registrationPanic(t, func() { g.GET("/y", h, nil) })
+	registrationPanic(t, func() { Bypassable("gzip", nil) })
+
+	// Nothing nil was registered along the way.
+	if len(a.Routes()) != 0 {
+		t.Fatalf("routes registered: %v", a.Routes())
+	}
+}
+
+func TestRegistrationMiddlewareReturnsNil(t *testing.T) {
+	a := New()
+	a.Use(func(next Handler) Handler { return nil })
+	re := registrationPanic(t, func() { a.GET("/x", func(c Ctx) error { return nil }) })
+	if re.Reason != "global middleware #1 returned a nil handler" {
+		t.Fatalf("error = %+v", re)
+	}
+
+	b := New()
+	re = registrationPanic(t, func() {
+		b.GET("/x", func(c Ctx) error { return nil }, func(next Handler) Handler { return nil })
+	})
+	if re.Reason != "middleware #1 returned a nil handler" {
+		t.Fatalf("error = %+v", re)
+	}
+}
+
+func TestRegistrationNilHTTPHandler(t *testing.T) {
+	a := New()
+	re := registrationPanic(t, func() { a.HandleHTTP(http.MethodGet, "/metrics", nil) })
+	if re.Reason != "nil http.Handler" || re.Pattern != "/metrics" {
+		t.Fatalf("error = %+v", re)
+	}
+	registrationPanic(t, func() { a.HandleHTTP(http.MethodGet, "/f", http.HandlerFunc(nil)) })
+	registrationPanic(t, func() { a.Mount("/admin", nil) })
+}
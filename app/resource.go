@@ -0,0 +1,171 @@
+package app
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Resource controller interfaces. A controller passed to Resource implements
+// any subset of them; only the implemented actions are routed.
+type (
+	// Indexer handles GET /things.
+	Indexer interface{ Index(c Ctx) error }
+	// Creator handles POST /things.
+	Creator interface{ Create(c Ctx) error }
+	// Shower handles GET /things/:id.
+	Shower interface{ Show(c Ctx) error }
+	// Updater handles PUT and PATCH /things/:id.
+	Updater interface{ Update(c Ctx) error }
+	// Deleter handles DELETE /things/:id.
+	Deleter interface{ Delete(c Ctx) error }
+
+	// ResourceActioner adds custom member (/things/:id/archive) and collection
+	// (/things/search) actions.
+	ResourceActioner interface{ Actions() []ResourceAction }
+	// ResourceMiddlewarer supplies middleware applied to every route of the
+	// controller, after any middleware passed to Resource.
+	ResourceMiddlewarer interface{ Middleware() []Middleware }
+	// ResourceParamer overrides the member parameter name (default "id"), e.g.
+	// to avoid clashes in nested resources.
+	ResourceParamer interface{ ResourceParam() string }
+)
+
+// ResourceAction is a custom controller action.
+type ResourceAction struct {
+	// Name identifies the action; the route is named "<resource>.<Name>".
+	Name string
+	// Method is the HTTP method. Defaults to GET.
+	Method string
+	// Path is the sub-path under the collection or member. Defaults to Name.
+	Path string
+	// Member routes the action under /things/:id instead of /things.
+	Member bool
+	// Handler serves the action. Required.
+	Handler Handler
+	// Middleware applies to this action only.
+	Middleware []Middleware
+}
+
+// Resource registers RESTful routes for controller under p and returns them in
+// registration order. Routes are named "<resource>.<action>" where resource is
+// the last segment of p:
+//
+//	GET    /users             Index   users.index
+//	POST   /users             Create  users.create
+//	GET    /users/:id         Show    users.show
+//	PUT    /users/:id         Update  users.update
+//	PATCH  /users/:id         Update  users.update
+//	DELETE /users/:id         Delete  users.delete
+//
+// Middleware passed here and from ResourceMiddlewarer wraps every route of the
+// controller. It panics if the controller implements no action.
+//
+// Collection actions (/users/search) overlap the Show route (/users/:id); the
+// default httprouter-based router rejects that combination, so use
+// NewTreeRouter when a controller has both.
+//
+// Example:
+//
+//	type Users struct{ db *sql.DB }
+//
+//	func (u *Users) Index(c flash.Ctx) error { ... }
+//	func (u *Users) Show(c flash.Ctx) error  { id := c.Param("id"); ... }
+//	func (u *Users) Actions() []flash.ResourceAction {
+//		return []flash.ResourceAction{
+//			{Name: "search", Handler: u.Search},
+//			{Name: "archive", Method: http.MethodPost, Member: true, Handler: u.Archive},
+//		}
+//	}
+//
+//	a := flash.New(flash.WithRouter(flash.NewTreeRouter()))
+//	a.Resource("/users", &Users{db}, Auth)
+//	// GET /users, GET /users/search, GET /users/:id, POST /users/:id/archive
+func (a *DefaultApp) Resource(p string, controller any, mw ...Middleware) []*Route {
+	return a.Group(p, mw...).resource(path.Base(cleanPath(p)), controller)
+}
+
+// Resource registers RESTful routes for controller under the group's prefix +
+// p; see DefaultApp.Resource.
+//
+// Example:
+//
+//	api := a.Group("/api/v1", Auth)
+//	api.Resource("/posts", &Posts{})
+//	// GET /api/v1/posts (posts.index), GET /api/v1/posts/:id (posts.show), ...
+func (g *Group) Resource(p string, controller any, mw ...Middleware) []*Route {
+	return g.Group(p, mw...).resource(path.Base(cleanPath(p)), controller)
+}
+
+// resource registers controller's routes on g, whose prefix is the collection path.
+func (g *Group) resource(name string, controller any) []*Route {
+	if m, ok := controller.(ResourceMiddlewarer); ok {
+		g.Use(m.Middleware()...)
+	}
+	param := "id"
+	if p, ok := controller.(ResourceParamer); ok && p.ResourceParam() != "" {
+		param = p.ResourceParam()
+	}
+	member := "/:" + param
+
+	var routes []*Route
+	add := func(method, p, action string, h Handler, mws ...Middleware) {
+		routes = append(routes, g.handle(method, p, h, mws...).Named(name+"."+action))
+	}
+
+	if c, ok := controller.(Indexer); ok {
+		add(http.MethodGet, "/", "index", c.Index)
+	}
+	if c, ok := controller.(Creator); ok {
+		add(http.MethodPost, "/", "create", c.Create)
+	}
+	var actions []ResourceAction
+	if c, ok := controller.(ResourceActioner); ok {
+		actions = c.Actions()
+	}
+	for _, act := range actions {
+		if !act.Member {
+			add(act.method(), "/"+act.path(), act.Name, act.handler(), act.Middleware...)
+		}
+	}
+	if c, ok := controller.(Shower); ok {
+		add(http.MethodGet, member, "show", c.Show)
+	}
+	if c, ok := controller.(Updater); ok {
+		add(http.MethodPut, member, "update", c.Update)
+		add(http.MethodPatch, member, "update", c.Update)
+	}
+	if c, ok := controller.(Deleter); ok {
+		add(http.MethodDelete, member, "delete", c.Delete)
+	}
+	for _, act := range actions {
+		if act.Member {
+			add(act.method(), member+"/"+act.path(), act.Name, act.handler(), act.Middleware...)
+		}
+	}
+	if len(routes) == 0 {
+		panic("flash: resource " + name + " controller implements no actions")
+	}
+	return routes
+}
+
+func (act ResourceAction) method() string {
+	if act.Method == "" {
+		return http.MethodGet
+	}
+	return strings.ToUpper(act.Method)
+}
+
+func (act ResourceAction) path() string {
+	if act.Path != "" {
+		return strings.TrimPrefix(act.Path, "/")
+	}
+	return act.Name
+}
+
+func (act ResourceAction) handler() Handler {
+	if act.Handler == nil {
+		panic("flash: resource action " + act.Name + " has no handler")
+	}
+	return act.Handler
+}
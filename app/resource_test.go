@@ -0,0 +1,119 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type usersCtl struct{ trace *[]string }
+
+func (u usersCtl) reply(name string) Handler {
+	return func(c Ctx) error { return c.String(http.StatusOK, name+":"+c.Param("id")) }
+}
+func (u usersCtl) Index(c Ctx) error  { return u.reply("index")(c) }
+func (u usersCtl) Create(c Ctx) error { return u.reply("create")(c) }
+func (u usersCtl) Show(c Ctx) error   { return u.reply("show")(c) }
+func (u usersCtl) Update(c Ctx) error { return u.reply("update")(c) }
+func (u usersCtl) Delete(c Ctx) error { return u.reply("delete")(c) }
+func (u usersCtl) Actions() []ResourceAction {
+	return []ResourceAction{
+		{Name: "search", Handler: u.reply("search")},
+		{Name: "archive", Method: "post", Member: true, Handler: u.reply("archive"),
+			Middleware: []Middleware{u.mark("action")}},
+	}
+}
+func (u usersCtl) Middleware() []Middleware { return []Middleware{u.mark("controller")} }
+func (u usersCtl) mark(s string) Middleware {
+	return func(next Handler) Handler {
+		return func(c Ctx) error { *u.trace = append(*u.trace, s); return next(c) }
+	}
+}
+
+func TestResource_RoutesNamesAndMiddleware(t *testing.T) {
+	var trace []string
+	ctl := usersCtl{trace: &trace}
+	a := New(WithRouter(NewTreeRouter()))
+	routes := a.Resource("/users", ctl, ctl.mark("resource"))
+
+	var names []string
+	for _, r := range routes {
+		names = append(names, r.Method+" "+r.Path+" "+r.Name)
+	}
+	want := []string{
+		"GET /users users.index",
+		"POST /users users.create",
+		"GET /users/search users.search",
+		"GET /users/:id users.show",
+		"PUT /users/:id users.update",
+		"PATCH /users/:id users.update",
+		"DELETE /users/:id users.delete",
+		"POST /users/:id/archive users.archive",
+	}
+	if strings.Join(names, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("routes:\n%s", strings.Join(names, "\n"))
+	}
+
+	cases := []struct{ method, path, body string }{
+		{http.MethodGet, "/users", "index:"},
+		{http.MethodPost, "/users", "create:"},
+		{http.MethodGet, "/users/search", "search:"},
+		{http.MethodGet, "/users/7", "show:7"},
+		{http.MethodPatch, "/users/7", "update:7"},
+		{http.MethodDelete, "/users/7", "delete:7"},
+		{http.MethodPost, "/users/7/archive", "archive:7"},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if rec.Body.String() != tc.body {
+			t.Fatalf("%s %s: body=%q want %q", tc.method, tc.path, rec.Body.String(), tc.body)
+		}
+	}
+	if got := strings.Join(trace[len(trace)-3:], ","); got != "resource,controller,action" {
+		t.Fatalf("middleware order = %s", got)
+	}
+}
+
+type postsCtl struct{}
+
+func (postsCtl) Show(c Ctx) error      { return c.String(http.StatusOK, c.Param("post")) }
+func (postsCtl) ResourceParam() string { return "post" }
+
+func TestGroupResource_ParamAndPrefix(t *testing.T) {
+	a := New()
+	routes := a.Group("/api").Resource("posts", postsCtl{})
+	if len(routes) != 1 || routes[0].Path != "/api/posts/:post" || routes[0].Name != "posts.show" {
+		t.Fatalf("unexpected routes: %+v", routes[0])
+	}
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/posts/p1", nil))
+	if rec.Body.String() != "p1" {
+		t.Fatalf("body=%q", rec.Body.String())
+	}
+}
+
+type onlyActions struct{}
+
+func (onlyActions) Actions() []ResourceAction { return []ResourceAction{{Name: "broken"}} }
+
+func TestResource_Panics(t *testing.T) {
+	for name, ctl := range map[string]any{"no actions": struct{}{}, "nil handler": onlyActions{}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s: expected panic", name)
+				}
+			}()
+			New().Resource("/x", ctl)
+		}()
+	}
+}
+
+func TestRoute_Named(t *testing.T) {
+	a := New()
+	if r := a.GET("/x", func(Ctx) error { return nil }).Named("x"); r.Name != "x" || a.Routes()[0].Name != "x" {
+		t.Fatalf("name not set")
+	}
+}
@@ -1,14 +1,17 @@
 package app
 
 import (
+	"context"
 	"net/http"
+	"runtime/pprof"
+	"time"
 
 	"github.com/goflash/flash/v2/ctx"
-	"github.com/julienschmidt/httprouter"
 )
 
 // GET registers a handler for HTTP GET requests on the given path.
-// Optionally accepts route-specific middleware.
+// Optionally accepts route-specific middleware. Like every route method, it
+// returns the *Route so documentation can be attached with Route.Doc.
 //
 // Example:
 //
@@ -19,8 +22,8 @@ import (
 //
 //	a.GET("/users/:id", ShowUser, Auth)
 //	// order: global -> Auth -> ShowUser; handler sees c.Param("id")
-func (a *DefaultApp) GET(path string, h Handler, mws ...Middleware) {
-	a.handle(http.MethodGet, path, h, mws...)
+func (a *DefaultApp) GET(path string, h Handler, mws ...Middleware) *Route {
+	return a.handle(http.MethodGet, path, h, mws...)
 }
 
 // POST registers a handler for HTTP POST requests on the given path.
@@ -30,8 +33,8 @@ func (a *DefaultApp) GET(path string, h Handler, mws ...Middleware) {
 // Example:
 //
 //	a.POST("/users", CreateUser, CSRF)
-func (a *DefaultApp) POST(path string, h Handler, mws ...Middleware) {
-	a.handle(http.MethodPost, path, h, mws...)
+func (a *DefaultApp) POST(path string, h Handler, mws ...Middleware) *Route {
+	return a.handle(http.MethodPost, path, h, mws...)
 }
 
 // PUT registers a handler for HTTP PUT requests on the given path.
@@ -41,8 +44,8 @@ func (a *DefaultApp) POST(path string, h Handler, mws ...Middleware) {
 // Example:
 //
 //	a.PUT("/users/:id", ReplaceUser)
-func (a *DefaultApp) PUT(path string, h Handler, mws ...Middleware) {
-	a.handle(http.MethodPut, path, h, mws...)
+func (a *DefaultApp) PUT(path string, h Handler, mws ...Middleware) *Route {
+	return a.handle(http.MethodPut, path, h, mws...)
 }
 
 // PATCH registers a handler for HTTP PATCH requests on the given path.
@@ -52,8 +55,8 @@ func (a *DefaultApp) PUT(path string, h Handler, mws ...Middleware) {
 // Example:
 //
 //	a.PATCH("/users/:id", UpdateUserEmail)
-func (a *DefaultApp) PATCH(path string, h Handler, mws ...Middleware) {
-	a.handle(http.MethodPatch, path, h, mws...)
+func (a *DefaultApp) PATCH(path string, h Handler, mws ...Middleware) *Route {
+	return a.handle(http.MethodPatch, path, h, mws...)
 }
 
 // DELETE registers a handler for HTTP DELETE requests on the given path.
@@ -62,8 +65,8 @@ func (a *DefaultApp) PATCH(path string, h Handler, mws ...Middleware) {
 // Example:
 //
 //	a.DELETE("/users/:id", DeleteUser, Audit)
-func (a *DefaultApp) DELETE(path string, h Handler, mws ...Middleware) {
-	a.handle(http.MethodDelete, path, h, mws...)
+func (a *DefaultApp) DELETE(path string, h Handler, mws ...Middleware) *Route {
+	return a.handle(http.MethodDelete, path, h, mws...)
 }
 
 // OPTIONS registers a handler for HTTP OPTIONS requests on the given path.
@@ -73,8 +76,8 @@ func (a *DefaultApp) DELETE(path string, h Handler, mws ...Middleware) {
 // Example:
 //
 //	a.OPTIONS("/users", Preflight)
-func (a *DefaultApp) OPTIONS(path string, h Handler, mws ...Middleware) {
-	a.handle(http.MethodOptions, path, h, mws...)
+func (a *DefaultApp) OPTIONS(path string, h Handler, mws ...Middleware) *Route {
+	return a.handle(http.MethodOptions, path, h, mws...)
 }
 
 // HEAD registers a handler for HTTP HEAD requests on the given path.
@@ -84,8 +87,8 @@ func (a *DefaultApp) OPTIONS(path string, h Handler, mws ...Middleware) {
 // Example:
 //
 //	a.HEAD("/health", HeadHealth)
-func (a *DefaultApp) HEAD(path string, h Handler, mws ...Middleware) {
-	a.handle(http.MethodHead, path, h, mws...)
+func (a *DefaultApp) HEAD(path string, h Handler, mws ...Middleware) *Route {
+	return a.handle(http.MethodHead, path, h, mws...)
 }
 
 // ANY registers a handler for all common HTTP methods (GET, POST, PUT, PATCH,
@@ -95,10 +98,13 @@ func (a *DefaultApp) HEAD(path string, h Handler, mws ...Middleware) {
 // Example:
 //
 //	a.ANY("/webhook", Webhook)
-func (a *DefaultApp) ANY(path string, h Handler, mws ...Middleware) {
+func (a *DefaultApp) ANY(path string, h Handler, mws ...Middleware) *Route {
+	checkHandler("ANY", path, h, mws)
+	rt := &Route{Method: "ANY", Path: path}
 	for _, m := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions, http.MethodHead} {
-		a.handle(m, path, h, mws...)
+		a.route(m, path, h, nil, rt, mws...)
 	}
+	return a.addRoute(rt)
 }
 
 // Handle registers a handler for a custom HTTP method on the given path.
@@ -109,14 +115,14 @@ func (a *DefaultApp) ANY(path string, h Handler, mws ...Middleware) {
 // Example:
 //
 //	a.Handle("REPORT", "/dav/resource", HandleReport)
-func (a *DefaultApp) Handle(method, path string, h Handler, mws ...Middleware) {
-	a.handle(method, path, h, mws...)
+func (a *DefaultApp) Handle(method, path string, h Handler, mws ...Middleware) *Route {
+	return a.handle(method, path, h, mws...)
 }
 
 // handle is the internal route registration and handler composition method.
 // It composes the middleware chain (route-specific then global), adapts the
-// handler to the httprouter signature, injects the logger, and manages context
-// pooling for allocation-free request handling.
+// handler to the httprouter signature, injects the logger, and manages
+// context pooling for allocation-free request handling.
 //
 // Middleware composition order:
 //   - Route-specific middleware wraps the handler (right-to-left)
@@ -124,11 +130,18 @@ func (a *DefaultApp) Handle(method, path string, h Handler, mws ...Middleware) {
 //
 // The resulting call order at runtime is: global (left-to-right) -> route (left-to-right) -> handler.
 //
+// The chain is composed exactly once per route, at registration time, and the
+// fully wrapped handler is stored in the router. Requests call it directly, so
+// no per-request closures are allocated for middleware. As a consequence,
+// middleware added with Use only applies to routes registered afterwards.
+// See BenchmarkChain_Precomputed and BenchmarkChain_ComposedPerRequest.
+//
 // Context lifecycle:
 //   - Acquire a *ctx.DefaultContext from the pool
 //   - Reset it with the incoming request/params and computed route pattern
-//   - Call the composed handler
-//   - On error, invoke the configured ErrorHandler
+//   - Call the composed handler, emitting request events to OnEvent subscribers
+//   - On error, invoke the configured ErrorHandlerV2 or ErrorHandler
+//   - Schedule the request's OnCommit/AfterResponse hooks
 //   - Finish() and return the context to the pool
 //
 // Example (internal flow overview):
@@ -139,29 +152,133 @@ func (a *DefaultApp) Handle(method, path string, h Handler, mws ...Middleware) {
 //	// Internally becomes something like:
 //	// final := Global2(Global1(Auth(Show)))
 //	// router.Handle("GET", "/users/:id", adapted(final))
-func (a *DefaultApp) handle(method, path string, h Handler, mws ...Middleware) {
+func (a *DefaultApp) handle(method, path string, h Handler, mws ...Middleware) *Route {
+	checkHandler(method, path, h, mws)
+	rt := &Route{Method: method, Path: path}
+	a.route(method, path, h, nil, rt, mws...)
+	return a.addRoute(rt)
+}
+
+// route registers the composed handler. bind holds route-level binding defaults
+// (e.g., from a Group); when nil the app-wide defaults are used. rt is the
+// route's descriptor, consulted per request for its middleware bypass list.
+func (a *DefaultApp) route(method, path string, h Handler, bind *ctx.BindJSONOptions, rt *Route, mws ...Middleware) {
+	a.noteRegistered(method, path)
+	a.router.Handle(method, path, a.compose(method, path, h, bind, rt, mws...))
+}
+
+// compose builds the RouteHandler for a route: the middleware chain around h
+// plus the per-request context lifecycle. See route for the parameters.
+func (a *DefaultApp) compose(method, path string, h Handler, bind *ctx.BindJSONOptions, rt *Route, mws ...Middleware) RouteHandler {
 	// Compose middleware chain right-to-left for minimal allocations and call depth.
 	// Route-specific middleware wraps the handler, then global middleware wraps that.
 	// This is allocation-free: each layer is a direct function call, not a slice or struct.
-	final := h
-	for i := len(mws) - 1; i >= 0; i-- {
-		final = mws[i](final)
+	if h == nil {
+		panic(registrationError(method, path, "nil handler"))
+	}
+	global := a.middleware
+	if rt.mounted && !a.mountMiddleware {
+		global = nil
+	}
+	if pre := a.prefixMiddlewareFor(path); pre != nil {
+		mws = append(pre, mws...)
 	}
-	for i := len(a.middleware) - 1; i >= 0; i-- {
-		final = a.middleware[i](final)
+	var final Handler
+	if a.timing != nil {
+		final = a.composeTimedChain(method, path, checkProduces(rt, h), global, mws)
+	} else {
+		final = a.composeChain(method, path, checkProduces(rt, h), global, mws)
 	}
+	rt.middleware = middlewareNames(global, mws)
 
-	// Adapt to httprouter signature and manage context lifecycle.
+	if a.metrics != nil {
+		a.metrics.observeChain(len(mws) + len(global))
+	}
+
+	// Adapt to the router signature and manage context lifecycle.
 	pattern := path
-	a.router.Handle(method, path, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		// Inject app logger into request context for structured logging.
-		r = r.WithContext(ctx.ContextWithLogger(r.Context(), a.Logger()))
+	serve := func(w http.ResponseWriter, r *http.Request, ps Params) {
+		if a.drainClose && a.draining.Load() {
+			w.Header().Set("Connection", "close")
+		}
+		if a.metrics != nil {
+			a.metrics.poolGets.Add(1)
+		}
+		var timing *breakdown
+		if a.timing != nil {
+			w, timing = a.beginTiming(w, r, rt, pattern)
+		}
+		if a.trace != nil && a.trace.ServerTiming {
+			w = &traceWriter{ResponseWriter: w, r: r}
+		}
 		concrete := a.pool.Get().(*ctx.DefaultContext)
 		concrete.Reset(w, r, ps, pattern)
-		if err := final(concrete); err != nil {
-			a.ErrorHandler()(concrete, err)
+		// Inject app logger into request context for structured logging.
+		concrete.SetLogger(a.Logger())
+		if bind != nil {
+			concrete.SetBindDefaults(bind)
+		} else if a.bindOpts != nil {
+			concrete.SetBindDefaults(a.bindOpts)
+		}
+		if a.noJSONEscape {
+			concrete.SetJSONEscapeHTML(false)
+		}
+		if a.tempLimits != nil {
+			concrete.SetTempLimits(a.tempLimits)
+		}
+		if a.flashStore != nil {
+			concrete.SetFlashStore(a.flashStore)
+		}
+		if a.assets != nil {
+			concrete.SetAssets(a.assets)
+		}
+		concrete.SetRouteURLs(a)
+		if a.sanitizers != nil {
+			concrete.SetSanitizers(a.sanitizers)
+		}
+		if rt.bypass != nil {
+			concrete.SetRouteBypass(rt.bypass)
+		}
+		if rt.meta != nil {
+			concrete.SetRouteMeta(rt.meta)
+		}
+		subs := a.eventSubs()
+		var start time.Time
+		if subs != nil {
+			start = time.Now()
+			a.emit(subs, RequestStartEvent{Ctx: concrete, Method: r.Method, Route: pattern, Time: start})
+		}
+		err := final(concrete)
+		if err != nil {
+			if isSoftNotFound(concrete, err) {
+				a.softNotFound(concrete.ResponseWriter(), concrete.Request())
+			} else {
+				if subs != nil {
+					a.emitError(subs, concrete, err)
+				}
+				a.handleError(concrete, err)
+			}
+		}
+		if subs != nil {
+			a.emit(subs, RequestEndEvent{Ctx: concrete, Method: r.Method, Route: pattern, Status: concrete.StatusCode(), Duration: time.Since(start), Err: err})
+		}
+		if timing != nil {
+			a.reportTiming(concrete, timing)
+		}
+		if a.trace != nil {
+			a.reportTrace(concrete)
+		}
+		if hooks := concrete.TakeResponseHooks(err); hooks != nil {
+			a.runResponseHooks(concrete.Context(), hooks)
 		}
 		concrete.Finish()
 		a.pool.Put(concrete)
-	})
+	}
+	if a.profilingLabels {
+		labels := pprof.Labels("method", method, "route", pattern)
+		return func(w http.ResponseWriter, r *http.Request, ps Params) {
+			pprof.Do(r.Context(), labels, func(lc context.Context) { serve(w, r.WithContext(lc), ps) })
+		}
+	}
+	return serve
 }
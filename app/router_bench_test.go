@@ -0,0 +1,113 @@
+//go:build !race
+
+// Allocation assertions are skipped under the race detector, which instruments
+// memory accesses and allocates on its own.
+
+package app
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// nopWriter is a minimal ResponseWriter that does not allocate per write.
+type nopWriter struct{ h http.Header }
+
+func (w *nopWriter) Header() http.Header         { return w.h }
+func (w *nopWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *nopWriter) WriteHeader(int)             {}
+
+func newBenchApp() App {
+	a := New()
+	a.SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	a.GET("/static/path/to/resource", func(c Ctx) error {
+		c.Status(http.StatusNoContent)
+		return nil
+	})
+	a.GET("/orgs/:org/repos/:repo/issues/:issue/comments/:comment", func(c Ctx) error {
+		if c.Param("org") == "" || c.Param("repo") == "" || c.Param("issue") == "" || c.Param("comment") == "" {
+			return io.ErrUnexpectedEOF
+		}
+		c.Status(http.StatusNoContent)
+		return nil
+	})
+	a.GET("/files/*filepath", func(c Ctx) error {
+		if c.Param("filepath") == "" {
+			return io.ErrUnexpectedEOF
+		}
+		c.Status(http.StatusNoContent)
+		return nil
+	})
+	return a
+}
+
+var benchRoutes = []struct{ name, path string }{
+	{"Static", "/static/path/to/resource"},
+	{"Params", "/orgs/goflash/repos/flash/issues/42/comments/7"},
+	{"Wildcard", "/files/assets/css/site.css"},
+}
+
+func BenchmarkRouter(b *testing.B) {
+	a := newBenchApp()
+	for _, rt := range benchRoutes {
+		b.Run(rt.name, func(b *testing.B) {
+			req := httptest.NewRequest(http.MethodGet, rt.path, nil)
+			w := &nopWriter{h: http.Header{}}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				a.ServeHTTP(w, req)
+			}
+		})
+	}
+}
+
+func BenchmarkParamAccess(b *testing.B) {
+	a := New()
+	var got string
+	a.GET("/users/:id/posts/:post", func(c Ctx) error {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			got = c.Param("post")
+		}
+		return nil
+	})
+	a.ServeHTTP(&nopWriter{h: http.Header{}}, httptest.NewRequest(http.MethodGet, "/users/1/posts/2", nil))
+	if got != "2" {
+		b.Fatalf("param = %q", got)
+	}
+}
+
+// TestRouterAllocs guards the hot path: a static route only pays for
+// attaching the logger to the request context (a request copy and a context
+// value), and param routes add the router's params slice.
+func TestRouterAllocs(t *testing.T) {
+	a := newBenchApp()
+	limits := map[string]float64{"Static": 2, "Params": 3, "Wildcard": 3}
+	for _, rt := range benchRoutes {
+		req := httptest.NewRequest(http.MethodGet, rt.path, nil)
+		w := &nopWriter{h: http.Header{}}
+		a.ServeHTTP(w, req) // warm the context pool
+		allocs := testing.AllocsPerRun(200, func() { a.ServeHTTP(w, req) })
+		if allocs > limits[rt.name] {
+			t.Fatalf("%s: %.1f allocs/request, want <= %.0f", rt.name, allocs, limits[rt.name])
+		}
+	}
+}
+
+func TestParamAccessDoesNotAllocate(t *testing.T) {
+	a := New()
+	var allocs float64
+	a.GET("/users/:id", func(c Ctx) error {
+		allocs = testing.AllocsPerRun(100, func() { _ = c.Param("id") })
+		return nil
+	})
+	a.ServeHTTP(&nopWriter{h: http.Header{}}, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	if allocs != 0 {
+		t.Fatalf("Param allocated %.1f times", allocs)
+	}
+}
@@ -1,6 +1,8 @@
 package app
 
 import (
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -64,3 +66,72 @@ func TestRouterAllMethodsHelpersV2(t *testing.T) {
 		}
 	}
 }
+
+// passthrough returns a middleware that counts how many times it is composed.
+func passthrough(composed *int) Middleware {
+	return func(next Handler) Handler {
+		*composed++
+		return func(c Ctx) error { return next(c) }
+	}
+}
+
+func TestMiddlewareChainComposedOnceAtRegistration(t *testing.T) {
+	var global, group, route int
+	a := New()
+	a.Use(passthrough(&global))
+	g := a.Group("/g", passthrough(&group))
+	g.GET("/x", func(c Ctx) error { return c.String(http.StatusOK, "ok") }, passthrough(&route))
+
+	for i := 0; i < 5; i++ {
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/g/x", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("code=%d", rec.Code)
+		}
+	}
+	if global != 1 || group != 1 || route != 1 {
+		t.Fatalf("middleware composed per request: global=%d group=%d route=%d", global, group, route)
+	}
+}
+
+// chainMiddleware returns n no-op middleware layers used by the chain benchmarks.
+func chainMiddleware(n int) []Middleware {
+	mws := make([]Middleware, n)
+	for i := range mws {
+		mws[i] = func(next Handler) Handler { return func(c Ctx) error { return next(c) } }
+	}
+	return mws
+}
+
+func benchmarkChain(b *testing.B, perRequest bool) {
+	a := New()
+	a.SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	mws := chainMiddleware(8)
+	ok := func(c Ctx) error { c.Status(http.StatusNoContent); return nil }
+	if perRequest {
+		// Naive design: compose the full chain inside every request.
+		a.GET("/bench", func(c Ctx) error {
+			h := ok
+			for i := len(mws) - 1; i >= 0; i-- {
+				h = mws[i](h)
+			}
+			return h(c)
+		})
+	} else {
+		a.Use(mws[:4]...)
+		a.Group("/", mws[4:6]...).GET("/bench", ok, mws[6:]...)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/bench", nil)
+	w := httptest.NewRecorder()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a.ServeHTTP(w, req)
+	}
+}
+
+// BenchmarkChain_Precomputed measures the registered (precomputed) chain.
+func BenchmarkChain_Precomputed(b *testing.B) { benchmarkChain(b, false) }
+
+// BenchmarkChain_ComposedPerRequest is the baseline that rebuilds the chain per request.
+func BenchmarkChain_ComposedPerRequest(b *testing.B) { benchmarkChain(b, true) }
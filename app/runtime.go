@@ -0,0 +1,232 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// RuntimeConfig packages the Go runtime tuning commonly applied to production
+// services. Zero fields leave the corresponding runtime setting unchanged.
+//
+// Settings made explicitly by the operator through the GOGC and GOMEMLIMIT
+// environment variables take precedence: TuneRuntime does not override them.
+//
+// Example (config.yaml):
+//
+//	runtime: {gogc: 200, memory_limit_percent: 90}
+type RuntimeConfig struct {
+	// GOGC sets the garbage collection target percentage (runtime/debug.
+	// SetGCPercent). Negative disables the proportional collector, which is
+	// only safe together with a memory limit.
+	GOGC int `json:"gogc" yaml:"gogc" env:"GOGC"`
+
+	// MemoryLimit sets the soft memory limit (runtime/debug.SetMemoryLimit),
+	// e.g. "900MiB". It takes precedence over MemoryLimitPercent.
+	MemoryLimit ByteSize `json:"memory_limit" yaml:"memory_limit" env:"MEMORY_LIMIT"`
+
+	// MemoryLimitPercent sets the soft memory limit to a percentage (1-100) of
+	// the container's cgroup memory limit, leaving headroom for non-heap
+	// memory. TuneRuntime fails if no container limit is found.
+	MemoryLimitPercent int `json:"memory_limit_percent" yaml:"memory_limit_percent" env:"MEMORY_LIMIT_PERCENT"`
+
+	// Ballast allocates a heap ballast of this size: a never-touched
+	// allocation that raises the heap size the collector paces against, so
+	// small heaps are collected less often. Prefer MemoryLimit with a higher
+	// GOGC on Go 1.19+; Ballast is kept for services that already rely on it.
+	Ballast ByteSize `json:"ballast" yaml:"ballast" env:"BALLAST"`
+}
+
+// runtimeState holds the ballast allocated by TuneRuntime.
+var runtimeState struct {
+	mu      sync.Mutex
+	ballast []byte
+}
+
+// cgroupMemoryFiles are the cgroup v2 and v1 files holding the container
+// memory limit, in lookup order.
+var cgroupMemoryFiles = []string{
+	"/sys/fs/cgroup/memory.max",
+	"/sys/fs/cgroup/memory/memory.limit_in_bytes",
+}
+
+// TuneRuntime applies cfg to the Go runtime. It can be called again to change
+// the settings; a new Ballast replaces the previous one and a zero Ballast
+// keeps it. NewFromConfig calls it with Config.Runtime.
+//
+// Example:
+//
+//	if err := flash.TuneRuntime(flash.RuntimeConfig{GOGC: 200, MemoryLimitPercent: 90}); err != nil {
+//		log.Fatal(err)
+//	}
+func TuneRuntime(cfg RuntimeConfig) error {
+	if cfg.MemoryLimitPercent < 0 || cfg.MemoryLimitPercent > 100 {
+		return fmt.Errorf("flash: runtime memory_limit_percent %d: want 1-100", cfg.MemoryLimitPercent)
+	}
+	limit := int64(cfg.MemoryLimit)
+	if limit == 0 && cfg.MemoryLimitPercent > 0 {
+		container, ok := containerMemoryLimit()
+		if !ok {
+			return errors.New("flash: runtime memory_limit_percent: no container memory limit found")
+		}
+		limit = container / 100 * int64(cfg.MemoryLimitPercent)
+	}
+
+	if _, set := os.LookupEnv("GOGC"); cfg.GOGC != 0 && !set {
+		debug.SetGCPercent(cfg.GOGC)
+	}
+	if _, set := os.LookupEnv("GOMEMLIMIT"); limit > 0 && !set {
+		debug.SetMemoryLimit(limit)
+	}
+	if cfg.Ballast > 0 {
+		runtimeState.mu.Lock()
+		runtimeState.ballast = make([]byte, cfg.Ballast)
+		runtimeState.mu.Unlock()
+	}
+	return nil
+}
+
+// containerMemoryLimit returns the cgroup memory limit, if any.
+func containerMemoryLimit() (int64, bool) {
+	for _, path := range cgroupMemoryFiles {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+		// "max" (v2) or a page-rounded MaxInt64 (v1) mean unlimited.
+		if err != nil || n <= 0 || n >= math.MaxInt64/2 {
+			return 0, false
+		}
+		return n, true
+	}
+	return 0, false
+}
+
+// RuntimeStats is a snapshot of the garbage collector settings and state and
+// of the request context pool.
+type RuntimeStats struct {
+	GOGC          int     `json:"gogc"`         // -1 when the proportional collector is off
+	MemoryLimit   int64   `json:"memory_limit"` // math.MaxInt64 when unlimited
+	Ballast       int64   `json:"ballast"`
+	Goroutines    int     `json:"goroutines"`
+	HeapAlloc     uint64  `json:"heap_alloc"`
+	HeapInuse     uint64  `json:"heap_inuse"`
+	HeapSys       uint64  `json:"heap_sys"`
+	NextGC        uint64  `json:"next_gc"`
+	NumGC         uint32  `json:"num_gc"`
+	PauseTotalNs  uint64  `json:"gc_pause_total_ns"`
+	LastPauseNs   uint64  `json:"gc_last_pause_ns"`
+	GCCPUFraction float64 `json:"gc_cpu_fraction"`
+
+	// Context pool counters from DefaultMetrics; zero unless an app was
+	// created with WithMetrics.
+	ContextPoolGets   uint64 `json:"context_pool_gets"`
+	ContextPoolHits   uint64 `json:"context_pool_hits"`
+	ContextPoolMisses uint64 `json:"context_pool_misses"`
+}
+
+// ReadRuntimeStats returns the current RuntimeStats. It calls
+// runtime.ReadMemStats, which briefly stops the world, so it is meant for
+// debug endpoints rather than per-request use.
+func ReadRuntimeStats() RuntimeStats {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	gogc := []metrics.Sample{{Name: "/gc/gogc:percent"}}
+	metrics.Read(gogc)
+
+	s := RuntimeStats{
+		GOGC:          -1,
+		MemoryLimit:   debug.SetMemoryLimit(-1), // negative input only reads the limit
+		Goroutines:    runtime.NumGoroutine(),
+		HeapAlloc:     ms.HeapAlloc,
+		HeapInuse:     ms.HeapInuse,
+		HeapSys:       ms.HeapSys,
+		NextGC:        ms.NextGC,
+		NumGC:         ms.NumGC,
+		PauseTotalNs:  ms.PauseTotalNs,
+		GCCPUFraction: ms.GCCPUFraction,
+	}
+	if gogc[0].Value.Kind() == metrics.KindUint64 {
+		if v := gogc[0].Value.Uint64(); v <= math.MaxInt32 {
+			s.GOGC = int(v)
+		}
+	}
+	if ms.NumGC > 0 {
+		s.LastPauseNs = ms.PauseNs[(ms.NumGC+255)%256]
+	}
+	runtimeState.mu.Lock()
+	s.Ballast = int64(len(runtimeState.ballast))
+	runtimeState.mu.Unlock()
+
+	m := DefaultMetrics.Snapshot()
+	s.ContextPoolGets, s.ContextPoolHits, s.ContextPoolMisses = m.ContextPoolGets, m.ContextPoolHits, m.ContextPoolMisses
+	return s
+}
+
+// RuntimeStatsHandler returns an http.Handler serving ReadRuntimeStats as
+// JSON. Mount it on an internal or protected route.
+//
+// Example:
+//
+//	a.HandleHTTP(http.MethodGet, "/debug/runtime", flash.RuntimeStatsHandler())
+func RuntimeStatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = json.NewEncoder(w).Encode(ReadRuntimeStats())
+	})
+}
+
+// ByteSize is a byte count that reads and writes with the units accepted by
+// GOMEMLIMIT, such as "512MiB" or "1GiB", in YAML, JSON and environment
+// variables. A plain number is a count of bytes.
+type ByteSize int64
+
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10}, {"B", 1},
+}
+
+// UnmarshalText parses a size such as "512MiB"; an empty string is zero.
+func (s *ByteSize) UnmarshalText(b []byte) error {
+	text := strings.TrimSpace(string(b))
+	if text == "" {
+		*s = 0
+		return nil
+	}
+	num, mult := text, int64(1)
+	for _, u := range byteUnits {
+		if strings.HasSuffix(text, u.suffix) {
+			num, mult = strings.TrimSpace(strings.TrimSuffix(text, u.suffix)), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64/mult {
+		return fmt.Errorf("invalid byte size %q (want e.g. 512MiB)", text)
+	}
+	*s = ByteSize(n * mult)
+	return nil
+}
+
+// MarshalText formats the size with the largest unit that divides it exactly.
+func (s ByteSize) MarshalText() ([]byte, error) {
+	for _, u := range byteUnits {
+		if s != 0 && int64(s)%u.size == 0 {
+			return []byte(strconv.FormatInt(int64(s)/u.size, 10) + u.suffix), nil
+		}
+	}
+	return []byte("0"), nil
+}
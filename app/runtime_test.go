@@ -0,0 +1,164 @@
+package app
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"testing"
+)
+
+// restoreRuntime undoes TuneRuntime's global effects after a test.
+func restoreRuntime(t *testing.T) {
+	gc := debug.SetGCPercent(100)
+	debug.SetGCPercent(gc)
+	limit := debug.SetMemoryLimit(-1)
+	t.Cleanup(func() {
+		debug.SetGCPercent(gc)
+		debug.SetMemoryLimit(limit)
+		runtimeState.mu.Lock()
+		runtimeState.ballast = nil
+		runtimeState.mu.Unlock()
+	})
+}
+
+// unsetenv unsets key for the duration of the test.
+func unsetenv(t *testing.T, key string) {
+	t.Setenv(key, "")
+	os.Unsetenv(key)
+}
+
+func fakeCgroup(t *testing.T, content string) {
+	path := filepath.Join(t.TempDir(), "memory.max")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := cgroupMemoryFiles
+	cgroupMemoryFiles = []string{filepath.Join(t.TempDir(), "missing"), path}
+	t.Cleanup(func() { cgroupMemoryFiles = old })
+}
+
+func TestTuneRuntime(t *testing.T) {
+	restoreRuntime(t)
+	unsetenv(t, "GOGC")
+	unsetenv(t, "GOMEMLIMIT")
+	if err := TuneRuntime(RuntimeConfig{GOGC: 250, MemoryLimit: 512 << 20, Ballast: 1 << 20}); err != nil {
+		t.Fatal(err)
+	}
+	s := ReadRuntimeStats()
+	if s.GOGC != 250 || s.MemoryLimit != 512<<20 || s.Ballast != 1<<20 {
+		t.Fatalf("stats = %+v", s)
+	}
+	// Zero fields leave earlier settings in place.
+	if err := TuneRuntime(RuntimeConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if s := ReadRuntimeStats(); s.GOGC != 250 || s.Ballast != 1<<20 {
+		t.Fatalf("stats after zero config = %+v", s)
+	}
+	if err := TuneRuntime(RuntimeConfig{GOGC: -1}); err != nil {
+		t.Fatal(err)
+	}
+	if s := ReadRuntimeStats(); s.GOGC != -1 {
+		t.Fatalf("GOGC off = %d", s.GOGC)
+	}
+}
+
+func TestTuneRuntimeRespectsEnv(t *testing.T) {
+	restoreRuntime(t)
+	t.Setenv("GOGC", "150")
+	t.Setenv("GOMEMLIMIT", "1GiB")
+	before := ReadRuntimeStats()
+	if err := TuneRuntime(RuntimeConfig{GOGC: 300, MemoryLimit: 64 << 20}); err != nil {
+		t.Fatal(err)
+	}
+	if s := ReadRuntimeStats(); s.GOGC != before.GOGC || s.MemoryLimit != before.MemoryLimit {
+		t.Fatalf("env settings overridden: %+v", s)
+	}
+}
+
+func TestTuneRuntimeMemoryLimitPercent(t *testing.T) {
+	restoreRuntime(t)
+	unsetenv(t, "GOMEMLIMIT")
+	fakeCgroup(t, "1000000000\n")
+	if err := TuneRuntime(RuntimeConfig{MemoryLimitPercent: 90}); err != nil {
+		t.Fatal(err)
+	}
+	if s := ReadRuntimeStats(); s.MemoryLimit != 900000000 {
+		t.Fatalf("memory limit = %d", s.MemoryLimit)
+	}
+
+	fakeCgroup(t, "max\n")
+	if err := TuneRuntime(RuntimeConfig{MemoryLimitPercent: 90}); err == nil {
+		t.Fatalf("expected error without a container limit")
+	}
+	if err := TuneRuntime(RuntimeConfig{MemoryLimitPercent: 120}); err == nil {
+		t.Fatalf("expected error for percent > 100")
+	}
+}
+
+func TestRuntimeStatsHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	RuntimeStatsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/runtime", nil))
+	var s RuntimeStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &s); err != nil {
+		t.Fatalf("body %q: %v", rec.Body.String(), err)
+	}
+	if rec.Header().Get("Content-Type") != "application/json" || s.Goroutines == 0 || s.HeapSys == 0 || s.MemoryLimit == 0 {
+		t.Fatalf("stats = %+v", s)
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	cases := map[string]ByteSize{"": 0, "4096": 4096, "512MiB": 512 << 20, "1 GiB": 1 << 30, "10B": 10, "3KiB": 3 << 10}
+	for in, want := range cases {
+		var s ByteSize
+		if err := s.UnmarshalText([]byte(in)); err != nil || s != want {
+			t.Fatalf("%q = %d, %v; want %d", in, s, err, want)
+		}
+	}
+	for _, in := range []string{"1.5GiB", "-1", "12XB", "99999999TiB"} {
+		var s ByteSize
+		if err := s.UnmarshalText([]byte(in)); err == nil {
+			t.Fatalf("%q: expected error", in)
+		}
+	}
+	for s, want := range map[ByteSize]string{0: "0", 512 << 20: "512MiB", 1500: "1500B", math.MaxInt64: "9223372036854775807B"} {
+		if b, _ := s.MarshalText(); string(b) != want {
+			t.Fatalf("%d = %q, want %q", s, b, want)
+		}
+	}
+}
+
+func TestConfigRuntimeFromEnv(t *testing.T) {
+	t.Setenv("FLASH_RUNTIME_GOGC", "200")
+	t.Setenv("FLASH_RUNTIME_MEMORY_LIMIT", "900MiB")
+	cfg, err := LoadConfigEnv("FLASH", Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Runtime.GOGC != 200 || cfg.Runtime.MemoryLimit != 900<<20 {
+		t.Fatalf("runtime = %+v", cfg.Runtime)
+	}
+	t.Setenv("FLASH_RUNTIME_BALLAST", "lots")
+	if _, err := LoadConfigEnv("FLASH", Config{}); err == nil {
+		t.Fatalf("expected error for invalid ballast")
+	}
+}
+
+func TestNewFromConfigAppliesRuntime(t *testing.T) {
+	restoreRuntime(t)
+	unsetenv(t, "GOGC")
+	if _, err := NewFromConfig(Config{Runtime: RuntimeConfig{GOGC: 175}}); err != nil {
+		t.Fatal(err)
+	}
+	if s := ReadRuntimeStats(); s.GOGC != 175 {
+		t.Fatalf("GOGC = %d", s.GOGC)
+	}
+	if _, err := NewFromConfig(Config{Runtime: RuntimeConfig{MemoryLimitPercent: -5}}); err == nil {
+		t.Fatalf("expected error for invalid runtime config")
+	}
+}
@@ -0,0 +1,64 @@
+package app
+
+import "reflect"
+
+// Types declares the Go types of the route's JSON request and response bodies
+// and returns the route. Pass a value (or typed nil pointer) of each type, or
+// nil when the route has no such body. The types are not used when serving the
+// route; they describe it to generators such as the clientgen package, which
+// emits a typed client with matching structs.
+//
+// Routes without Types fall back to the types of DocInfo's RequestExample and
+// ResponseExample when those are Go values other than maps, strings and
+// []byte.
+//
+// Example:
+//
+//	a.POST("/users", CreateUser).Named("users.create").Types(NewUser{}, User{})
+//	a.GET("/users", ListUsers).Named("users.list").Types(nil, []User(nil))
+func (r *Route) Types(req, resp any) *Route {
+	r.reqType, r.respType = reflect.TypeOf(req), reflect.TypeOf(resp)
+	return r
+}
+
+// RequestType returns the request body type declared with Types or derived
+// from DocInfo.RequestExample, or nil if the route has none.
+func (r *Route) RequestType() reflect.Type {
+	if r.reqType != nil {
+		return r.reqType
+	}
+	if r.doc != nil {
+		return exampleType(r.doc.RequestExample)
+	}
+	return nil
+}
+
+// ResponseType returns the response body type declared with Types or derived
+// from DocInfo.ResponseExample, or nil if the route has none.
+func (r *Route) ResponseType() reflect.Type {
+	if r.respType != nil {
+		return r.respType
+	}
+	if r.doc != nil {
+		return exampleType(r.doc.ResponseExample)
+	}
+	return nil
+}
+
+// exampleType returns the type of a documentation example if it describes a
+// schema. Untyped examples (maps, strings, raw bytes) do not.
+func exampleType(v any) reflect.Type {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Map, reflect.String:
+		return nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 || t.Elem().Kind() == reflect.Interface {
+			return nil
+		}
+	}
+	return t
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+type schemaUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestRoute_Types(t *testing.T) {
+	a := New()
+	h := func(c ctx.Ctx) error { return nil }
+
+	rt := a.POST("/users", h).Types(schemaUser{}, (*schemaUser)(nil))
+	if rt.RequestType() != reflect.TypeOf(schemaUser{}) || rt.ResponseType() != reflect.TypeOf(&schemaUser{}) {
+		t.Fatalf("types = %v, %v", rt.RequestType(), rt.ResponseType())
+	}
+
+	rt = a.GET("/users", h).Types(nil, []schemaUser(nil))
+	if rt.RequestType() != nil || rt.ResponseType() != reflect.TypeOf([]schemaUser{}) {
+		t.Fatalf("types = %v, %v", rt.RequestType(), rt.ResponseType())
+	}
+
+	if rt := a.GET("/none", h); rt.RequestType() != nil || rt.ResponseType() != nil {
+		t.Fatalf("expected no types")
+	}
+}
+
+func TestRoute_TypesFromDocExamples(t *testing.T) {
+	a := New()
+	h := func(c ctx.Ctx) error { return nil }
+
+	rt := a.PUT("/users/:id", h).Doc(DocInfo{RequestExample: schemaUser{Name: "Ada"}, ResponseExample: []schemaUser{{ID: 1}}})
+	if rt.RequestType() != reflect.TypeOf(schemaUser{}) || rt.ResponseType() != reflect.TypeOf([]schemaUser{}) {
+		t.Fatalf("types = %v, %v", rt.RequestType(), rt.ResponseType())
+	}
+
+	for i, ex := range []any{map[string]any{"a": 1}, `{"a":1}`, []byte("x"), []any{1}} {
+		rt := a.POST("/x"+strconv.Itoa(i), h).Doc(DocInfo{RequestExample: ex, ResponseExample: ex})
+		if rt.RequestType() != nil || rt.ResponseType() != nil {
+			t.Fatalf("untyped example %T should not yield a type", ex)
+		}
+	}
+
+	rt = a.POST("/y", h).Doc(DocInfo{RequestExample: map[string]any{}}).Types(schemaUser{}, nil)
+	if rt.RequestType() != reflect.TypeOf(schemaUser{}) {
+		t.Fatalf("Types should win over examples")
+	}
+}
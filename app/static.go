@@ -0,0 +1,155 @@
+package app
+
+import (
+	"errors"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// precompressedEncodings lists the sibling suffixes checked by the static file
+// handler, in order of preference.
+var precompressedEncodings = []struct{ encoding, ext string }{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// StaticFS serves files from fsys (e.g., an embed.FS or a storage.FS such as
+// an S3 bucket) under a URL prefix for GET and HEAD requests. Like StaticDirs,
+// it serves precompressed ".br" and ".gz" siblings when the client accepts
+// them. Range requests are supported when fsys returns seekable files, which
+// storage backends do without downloading whole objects.
+//
+// Example:
+//
+//	//go:embed public
+//	var public embed.FS
+//
+//	sub, _ := fs.Sub(public, "public")
+//	a.StaticFS("/assets", sub)
+//	a.StaticFS("/media", storage.NewS3(s3cfg))
+func (a *DefaultApp) StaticFS(prefix string, fsys fs.FS) {
+	if fsys == nil {
+		return
+	}
+	a.serveStatic(prefix, http.FS(fsys))
+}
+
+// serveStatic registers GET and HEAD catch-all routes under prefix serving fsys.
+func (a *DefaultApp) serveStatic(prefix string, fsys http.FileSystem) {
+	prefix = cleanPath(prefix)
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	notFound := func(w http.ResponseWriter, r *http.Request) {
+		// Restore the path StripPrefix removed for the NotFound handler.
+		u := *r.URL
+		u.Path, u.RawPath = prefix+strings.TrimPrefix(u.Path, "/"), ""
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = &u
+		a.softNotFound(w, r2)
+	}
+	h := httpHandle(http.StripPrefix(prefix, staticHandler{fs: fsys, next: http.FileServer(fsys), notFound: notFound}))
+	a.router.Handle(http.MethodGet, prefix+"*filepath", h)
+	a.router.Handle(http.MethodHead, prefix+"*filepath", h)
+}
+
+// staticHandler serves precompressed siblings (e.g., "app.js.br" for "app.js")
+// when they exist and the client accepts the encoding, avoiding on-the-fly
+// compression. Missing files are answered by notFound, like unmatched routes,
+// so the app's NotFound handler, 404 error page and metrics apply. Everything
+// else is delegated to next (an http.FileServer).
+//
+// When any sibling exists, "Vary: Accept-Encoding" is set on the response,
+// whichever representation is served, so shared caches key on it.
+type staticHandler struct {
+	fs       http.FileSystem
+	next     http.Handler
+	notFound http.HandlerFunc // answers missing files like unmatched routes
+}
+
+func (h staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Path
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	if strings.HasSuffix(name, "/") {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	name = path.Clean(name)
+	accept := r.Header.Get("Accept-Encoding")
+	varied := false
+	for _, pc := range precompressedEncodings {
+		f, err := h.fs.Open(name + pc.ext)
+		if err != nil {
+			continue
+		}
+		fi, err := f.Stat()
+		if err != nil || fi.IsDir() {
+			_ = f.Close()
+			continue
+		}
+		if !varied {
+			w.Header().Add("Vary", "Accept-Encoding")
+			varied = true
+		}
+		if !acceptsEncoding(accept, pc.encoding) {
+			_ = f.Close()
+			continue
+		}
+		hdr := w.Header()
+		hdr.Set("Content-Encoding", pc.encoding)
+		if hdr.Get("Content-Type") == "" {
+			ct := mime.TypeByExtension(path.Ext(name))
+			if ct == "" {
+				ct = "application/octet-stream"
+			}
+			hdr.Set("Content-Type", ct)
+		}
+		http.ServeContent(w, r, name, fi.ModTime(), f)
+		_ = f.Close()
+		return
+	}
+	if h.notFound != nil {
+		if f, err := h.fs.Open(name); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				h.notFound(w, r)
+				return
+			}
+		} else {
+			_ = f.Close()
+		}
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header value allows enc,
+// honoring explicit "q=0" exclusions and the "*" wildcard.
+func acceptsEncoding(header, enc string) bool {
+	wildcard := false
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.TrimSpace(coding)
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+			if ok && strings.EqualFold(strings.TrimSpace(k), "q") {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					q = f
+				}
+			}
+		}
+		switch {
+		case strings.EqualFold(coding, enc):
+			return q > 0
+		case coding == "*":
+			wildcard = q > 0
+		}
+	}
+	return wildcard
+}
@@ -0,0 +1,156 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goflash/flash/v2/storage"
+)
+
+func getStatic(t *testing.T, a App, target, acceptEncoding string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestStaticDirs_ServesPrecompressedSiblings(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"app.js":      "plain-js",
+		"app.js.br":   "br-js",
+		"app.js.gz":   "gz-js",
+		"site.css":    "plain-css",
+		"site.css.gz": "gz-css",
+		"raw.txt":     "raw",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a := New()
+	a.Static("/assets", dir)
+
+	cases := []struct {
+		path, accept, body, encoding string
+		vary                         bool
+	}{
+		{"/assets/app.js", "gzip, deflate, br", "br-js", "br", true},
+		{"/assets/app.js", "gzip", "gz-js", "gzip", true},
+		{"/assets/app.js", "br;q=0, gzip;q=0.5", "gz-js", "gzip", true},
+		{"/assets/app.js", "", "plain-js", "", true},
+		{"/assets/app.js", "*", "br-js", "br", true},
+		{"/assets/app.js", "*, br;q=0", "gz-js", "gzip", true},
+		{"/assets/site.css", "br, gzip", "gz-css", "gzip", true},
+		{"/assets/raw.txt", "br, gzip", "raw", "", false},
+	}
+	for _, tc := range cases {
+		rec := getStatic(t, a, tc.path, tc.accept)
+		if rec.Code != http.StatusOK || rec.Body.String() != tc.body {
+			t.Fatalf("%s [%s]: code=%d body=%q want %q", tc.path, tc.accept, rec.Code, rec.Body.String(), tc.body)
+		}
+		if got := rec.Header().Get("Content-Encoding"); got != tc.encoding {
+			t.Fatalf("%s [%s]: Content-Encoding=%q want %q", tc.path, tc.accept, got, tc.encoding)
+		}
+		if got := rec.Header().Get("Vary") == "Accept-Encoding"; got != tc.vary {
+			t.Fatalf("%s [%s]: Vary=%q", tc.path, tc.accept, rec.Header().Get("Vary"))
+		}
+	}
+	rec := getStatic(t, a, "/assets/app.js", "br")
+	if ct := rec.Header().Get("Content-Type"); ct != "text/javascript; charset=utf-8" {
+		t.Fatalf("Content-Type should come from the original name, got %q", ct)
+	}
+}
+
+func TestStaticFS_EmbedLikeFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":   {Data: []byte("<h1>hi</h1>")},
+		"data.bin.gz":  {Data: []byte("gz-only")},
+		"sub/app.js":   {Data: []byte("js")},
+		"sub/dir.gz/x": {Data: []byte("x")},
+		"sub/dir":      {Data: []byte("file")},
+	}
+	a := New()
+	a.StaticFS("/static", fsys)
+	a.StaticFS("/none", nil)
+
+	if rec := getStatic(t, a, "/static/sub/app.js", "gzip"); rec.Body.String() != "js" || rec.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("unexpected: %q %v", rec.Body.String(), rec.Header())
+	}
+	rec := getStatic(t, a, "/static/data.bin", "gzip")
+	if rec.Body.String() != "gz-only" || rec.Header().Get("Content-Type") != "application/octet-stream" {
+		t.Fatalf("unexpected: %q %v", rec.Body.String(), rec.Header())
+	}
+	if rec := getStatic(t, a, "/static/sub/dir", "gzip"); rec.Body.String() != "file" || rec.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("directory sibling must be ignored: %q %v", rec.Body.String(), rec.Header())
+	}
+	if rec := getStatic(t, a, "/static/", ""); rec.Code != http.StatusOK || rec.Body.String() != "<h1>hi</h1>" {
+		t.Fatalf("index not served: %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := getStatic(t, a, "/none/x", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("nil fs should register nothing: %d", rec.Code)
+	}
+}
+
+func TestStaticHandler_RangeOnPrecompressed(t *testing.T) {
+	a := New()
+	a.StaticFS("/s", fstest.MapFS{"a.txt.gz": {Data: []byte("0123456789")}})
+	req := httptest.NewRequest(http.MethodGet, "/s/a.txt", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=2-4")
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "234" {
+		t.Fatalf("unexpected: %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStaticFS_StorageDir(t *testing.T) {
+	store := storage.Dir(t.TempDir())
+	if err := store.Write(context.Background(), "v/clip.mp4", strings.NewReader("0123456789")); err != nil {
+		t.Fatal(err)
+	}
+	a := New()
+	a.StaticFS("/media", store)
+	req := httptest.NewRequest(http.MethodGet, "/media/v/clip.mp4", nil)
+	req.Header.Set("Range", "bytes=7-")
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "789" || rec.Header().Get("Content-Type") != "video/mp4" {
+		t.Fatalf("unexpected: %d %q %q", rec.Code, rec.Body.String(), rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	cases := []struct {
+		header, enc string
+		want        bool
+	}{
+		{"", "gzip", false},
+		{"gzip", "gzip", true},
+		{"GZIP", "gzip", true},
+		{"deflate, gzip;q=0.8", "gzip", true},
+		{"gzip;q=0", "gzip", false},
+		{"gzip; q=0.0", "gzip", false},
+		{"gzip;q=bad", "gzip", true},
+		{"*", "br", true},
+		{"*;q=0", "br", false},
+		{"*, br;q=0", "br", false},
+		{"identity", "br", false},
+	}
+	for _, tc := range cases {
+		if got := acceptsEncoding(tc.header, tc.enc); got != tc.want {
+			t.Fatalf("acceptsEncoding(%q, %q) = %v, want %v", tc.header, tc.enc, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd socket
+// activation (SD_LISTEN_FDS_START).
+const listenFDsStart = 3
+
+// inherited holds the sockets passed by systemd. They are taken from the
+// environment once, on first use, and the LISTEN_* variables are then unset
+// so child processes do not mistake them for their own.
+var inherited struct {
+	once    sync.Once
+	mu      sync.Mutex
+	lns     []net.Listener
+	names   []string
+	claimed []bool
+	err     error
+}
+
+func loadSystemdListeners() {
+	fds := os.Getenv("LISTEN_FDS")
+	if fds == "" {
+		return
+	}
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return // meant for another process
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	n, err := strconv.Atoi(fds)
+	if err != nil || n < 0 {
+		inherited.err = fmt.Errorf("flash: invalid LISTEN_FDS %q", fds)
+		return
+	}
+	for i := 0; i < n; i++ {
+		name := "unknown"
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		// FileListener duplicates the descriptor with close-on-exec set, so
+		// closing the original keeps it from leaking into child processes.
+		f := os.NewFile(uintptr(listenFDsStart+i), name)
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, l := range inherited.lns {
+				l.Close()
+			}
+			inherited.lns, inherited.names = nil, nil
+			inherited.err = fmt.Errorf("flash: inherited socket %d (%s): %w", listenFDsStart+i, name, err)
+			return
+		}
+		inherited.lns = append(inherited.lns, ln)
+		inherited.names = append(inherited.names, name)
+	}
+	inherited.claimed = make([]bool, len(inherited.lns))
+}
+
+// SystemdListeners returns the stream sockets passed by systemd socket
+// activation (LISTEN_FDS), in the order of the ListenStream= lines of the
+// .socket unit, or nil when the process was not socket-activated. Sockets
+// that are not stream listeners (e.g. ListenDatagram=) make it fail.
+//
+// Listen hands these sockets out in order, so most apps never call
+// SystemdListeners directly.
+func SystemdListeners() ([]net.Listener, error) {
+	inherited.once.Do(loadSystemdListeners)
+	return append([]net.Listener(nil), inherited.lns...), inherited.err
+}
+
+// SystemdListener returns the socket-activated listener named name with
+// FileDescriptorName= in the .socket unit. Listen skips sockets claimed this
+// way.
+//
+// Example:
+//
+//	admin, err := flash.SystemdListener("admin")
+func SystemdListener(name string) (net.Listener, error) {
+	inherited.once.Do(loadSystemdListeners)
+	if inherited.err != nil {
+		return nil, inherited.err
+	}
+	inherited.mu.Lock()
+	defer inherited.mu.Unlock()
+	for i, n := range inherited.names {
+		if n == name {
+			inherited.claimed[i] = true
+			return inherited.lns[i], nil
+		}
+	}
+	return nil, fmt.Errorf("flash: no socket-activated listener named %q", name)
+}
+
+// nextSystemdListener claims the first inherited listener not yet handed out.
+func nextSystemdListener() (net.Listener, error) {
+	inherited.once.Do(loadSystemdListeners)
+	if inherited.err != nil {
+		return nil, inherited.err
+	}
+	inherited.mu.Lock()
+	defer inherited.mu.Unlock()
+	for i, ln := range inherited.lns {
+		if !inherited.claimed[i] {
+			inherited.claimed[i] = true
+			return ln, nil
+		}
+	}
+	return nil, nil
+}
+
+// SystemdNotify sends state to the systemd service manager (sd_notify), e.g.
+// "READY=1", "STOPPING=1" or "STATUS=warming caches". It does nothing and
+// returns nil when NOTIFY_SOCKET is unset, i.e. outside a Type=notify service.
+//
+// NotifyReady, Shutdown and Watchdog send the standard states, so calling it
+// directly is only needed for custom STATUS= lines.
+func SystemdNotify(state string) error {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return nil
+	}
+	if addr[0] == '@' {
+		addr = "\x00" + addr[1:] // abstract socket namespace
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("flash: sd_notify: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("flash: sd_notify: %w", err)
+	}
+	return nil
+}
+
+// watchdogInterval returns how often to ping the systemd watchdog: half of
+// WatchdogSec=, as recommended by sd_watchdog_enabled(3). It is zero when the
+// watchdog is off or meant for another process.
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// Watchdog keeps the systemd watchdog (WatchdogSec=) fed until ctx ends. Each
+// ping first runs the health checks added with AddHealthCheck: while one
+// fails, the ping is withheld and STATUS= names the failing check, so systemd
+// restarts a service whose dependencies stay broken for longer than
+// WatchdogSec. Pings continue while the app drains, so a graceful shutdown is
+// not cut short. Without WATCHDOG_USEC it returns nil immediately.
+//
+// Serve runs Watchdog for servers whose Handler is the app.
+//
+// Example:
+//
+//	go a.Watchdog(ctx)
+func (a *DefaultApp) Watchdog(ctx context.Context) error {
+	interval := watchdogInterval()
+	if interval <= 0 {
+		return nil
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	failing := ""
+	for {
+		state := "WATCHDOG=1"
+		if !a.Draining() {
+			cctx, cancel := context.WithTimeout(ctx, interval)
+			name, err := a.checkHealth(cctx)
+			cancel()
+			switch {
+			case err != nil && ctx.Err() == nil:
+				state = "STATUS=unhealthy: " + name
+			case failing != "":
+				state = "WATCHDOG=1\nSTATUS=healthy"
+			}
+			failing = name
+		}
+		if err := SystemdNotify(state); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSystemdHelper is run as a child process by TestSystemdListeners with
+// sockets at fd 3 and 4, like systemd passes them.
+func TestSystemdHelper(t *testing.T) {
+	if os.Getenv("FLASH_TEST_SYSTEMD") == "" {
+		t.Skip("helper process")
+	}
+	os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	admin, err := SystemdListener("admin")
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+	ln, err := Listen(":1") // addr is ignored under socket activation
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+	all, _ := SystemdListeners()
+	fmt.Println("count:", len(all), "env:", os.Getenv("LISTEN_FDS") == "")
+	for _, l := range []net.Listener{ln, admin} {
+		conn, err := l.Accept()
+		if err != nil {
+			fmt.Println("error:", err)
+			return
+		}
+		io.WriteString(conn, l.Addr().String())
+		conn.Close()
+	}
+}
+
+func TestSystemdListeners(t *testing.T) {
+	if testing.Short() || runtime.GOOS == "windows" {
+		t.Skip("starts a child process with inherited sockets")
+	}
+	web, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	webFile, _ := web.(*net.TCPListener).File()
+	adminFile, _ := admin.(*net.TCPListener).File()
+	web.Close()
+	admin.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSystemdHelper$", "-test.v")
+	cmd.ExtraFiles = []*os.File{webFile, adminFile}
+	cmd.Env = append(os.Environ(), "FLASH_TEST_SYSTEMD=1", "LISTEN_FDS=2", "LISTEN_FDNAMES=web:admin")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	webFile.Close()
+	adminFile.Close()
+
+	for _, addr := range []string{web.Addr().String(), admin.Addr().String()} {
+		var conn net.Conn
+		for i := 0; i < 100; i++ {
+			if conn, err = net.Dial("tcp", addr); err == nil {
+				break
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(conn)
+		conn.Close()
+		if string(b) != addr {
+			t.Fatalf("connection to %s served by %q", addr, b)
+		}
+	}
+	b, _ := io.ReadAll(out)
+	_ = cmd.Wait()
+	if !strings.Contains(string(b), "count: 2 env: true") || strings.Contains(string(b), "error:") {
+		t.Fatalf("helper output:\n%s", b)
+	}
+}
+
+func TestSystemdListeners_NotActivated(t *testing.T) {
+	lns, err := SystemdListeners()
+	if err != nil || len(lns) != 0 {
+		t.Fatalf("got %v, %v", lns, err)
+	}
+	if _, err := SystemdListener("web"); err == nil {
+		t.Fatalf("expected error without socket activation")
+	}
+}
+
+// notifySocket listens on a NOTIFY_SOCKET for sd_notify messages.
+func notifySocket(t *testing.T) *net.UnixConn {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets unavailable: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+	return conn
+}
+
+func readNotify(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 256)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("no sd_notify message: %v", err)
+	}
+	return string(buf[:n])
+}
+
+func TestSystemdNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if err := SystemdNotify("READY=1"); err != nil {
+		t.Fatalf("without NOTIFY_SOCKET: %v", err)
+	}
+
+	conn := notifySocket(t)
+	if err := SystemdNotify("STATUS=warming"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readNotify(t, conn); got != "STATUS=warming" {
+		t.Fatalf("got %q", got)
+	}
+
+	t.Setenv("NOTIFY_SOCKET", filepath.Join(t.TempDir(), "missing.sock"))
+	if err := SystemdNotify("READY=1"); err == nil {
+		t.Fatalf("expected error for missing socket")
+	}
+}
+
+func TestServe_SystemdReadyAndStopping(t *testing.T) {
+	conn := notifySocket(t)
+	t.Setenv(DevNotifyEnv, "")
+	a := New(WithLogger(quietLogger())).(*DefaultApp)
+	srv := &http.Server{Addr: "127.0.0.1:0", Handler: a}
+	errc := make(chan error, 1)
+	go func() { errc <- Serve(srv) }()
+	if got := readNotify(t, conn); got != "READY=1" {
+		t.Fatalf("got %q", got)
+	}
+	if err := a.Shutdown(context.Background(), srv, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := readNotify(t, conn); got != "STOPPING=1" {
+		t.Fatalf("got %q", got)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		t.Fatalf("Serve returned %v", err)
+	}
+}
+
+func TestWatchdog(t *testing.T) {
+	conn := notifySocket(t)
+	t.Setenv("WATCHDOG_USEC", "20000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+
+	a := New().(*DefaultApp)
+	healthy := make(chan bool, 1)
+	healthy <- true
+	a.AddHealthCheck("db", func(ctx context.Context) error {
+		ok := <-healthy
+		healthy <- ok
+		if !ok {
+			return errors.New("down")
+		}
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Watchdog(ctx) }()
+
+	if got := readNotify(t, conn); got != "WATCHDOG=1" {
+		t.Fatalf("got %q", got)
+	}
+	<-healthy
+	healthy <- false
+	for got := readNotify(t, conn); got != "STATUS=unhealthy: db"; got = readNotify(t, conn) {
+		if got != "WATCHDOG=1" {
+			t.Fatalf("got %q", got)
+		}
+	}
+	<-healthy
+	healthy <- true
+	for got := readNotify(t, conn); got != "WATCHDOG=1\nSTATUS=healthy"; got = readNotify(t, conn) {
+		if got != "STATUS=unhealthy: db" {
+			t.Fatalf("got %q", got)
+		}
+	}
+
+	// Draining keeps the watchdog fed even when checks fail.
+	<-healthy
+	healthy <- false
+	a.BeginDrain()
+	deadline := time.Now().Add(100 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		if got := readNotify(t, conn); got != "WATCHDOG=1" && got != "STATUS=unhealthy: db" {
+			t.Fatalf("got %q", got)
+		}
+	}
+	if got := readNotify(t, conn); got != "WATCHDOG=1" {
+		t.Fatalf("while draining got %q", got)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchdog_Disabled(t *testing.T) {
+	a := New().(*DefaultApp)
+	for _, env := range [][2]string{{"", ""}, {"1000", "1"}} {
+		t.Setenv("WATCHDOG_USEC", env[0])
+		t.Setenv("WATCHDOG_PID", env[1])
+		if err := a.Watchdog(context.Background()); err != nil {
+			t.Fatalf("disabled watchdog returned %v", err)
+		}
+	}
+}
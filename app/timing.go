@@ -0,0 +1,292 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+// TimingConfig selects where WithTimingBreakdown reports each request's
+// latency breakdown.
+type TimingConfig struct {
+	// ServerTiming adds a Server-Timing response header, which browser
+	// developer tools show in the network panel. Headers cannot change once
+	// written, so it covers the time until the response headers are sent;
+	// layers still running then report their time so far.
+	ServerTiming bool
+	// Log writes one debug-level "flash: timing" line per request through the
+	// request logger, covering the whole request.
+	Log bool
+	// Report, when set, receives every breakdown after the handler chain
+	// returns, e.g. to record it on a tracing span.
+	Report func(c Ctx, t Timing)
+}
+
+// Timing is the latency breakdown of one request.
+type Timing struct {
+	// Route is the matched route pattern.
+	Route string
+	// Router is the time from ServeHTTP to the first middleware: route lookup
+	// and request context setup.
+	Router time.Duration
+	// Middleware holds the time spent in each middleware itself, outermost
+	// first, excluding the layers it wraps.
+	Middleware []MiddlewareTiming
+	// Handler is the time spent in the route handler.
+	Handler time.Duration
+	// Total is the time from ServeHTTP until the chain returned and its error,
+	// if any, was handled.
+	Total time.Duration
+}
+
+// MiddlewareTiming is the time spent in one middleware (see Timing).
+type MiddlewareTiming struct {
+	Name     string
+	Duration time.Duration
+}
+
+// WithTimingBreakdown instruments every route: each middleware and the
+// handler are wrapped with timers, and the breakdown (router, each
+// middleware by its Name, handler) is reported per request. Without a config
+// it only logs at debug level; the Server-Timing header reveals the
+// middleware stack and its latencies to clients, so it must be enabled
+// explicitly. The timers cost a few clock reads per layer and a few
+// allocations per request, so enable them while diagnosing slow requests,
+// not by default in production.
+//
+// Example:
+//
+//	a := app.New(app.WithTimingBreakdown(app.TimingConfig{ServerTiming: true, Log: true}))
+//	// Server-Timing: router;dur=0.004, recover;dur=0.001, logger;dur=0.019, auth;dur=12.400, handler;dur=3.117, total;dur=15.571
+//
+// Example (tracing span):
+//
+//	a := app.New(app.WithTimingBreakdown(app.TimingConfig{
+//		Report: func(c app.Ctx, t app.Timing) {
+//			span := trace.SpanFromContext(c.Context())
+//			for _, m := range t.Middleware {
+//				span.SetAttributes(attribute.Int64("flash.middleware."+m.Name+".us", m.Duration.Microseconds()))
+//			}
+//		},
+//	}))
+func WithTimingBreakdown(cfg ...TimingConfig) Option {
+	c := TimingConfig{Log: true}
+	if len(cfg) > 0 {
+		c = cfg[0]
+	}
+	return func(a *DefaultApp) { a.timing = &c }
+}
+
+// timingKey carries a request's *breakdown in its context.
+type timingKey struct{}
+
+// breakdown collects the timers of one request. Layers are the middleware in
+// execution order followed by the handler. It is locked because middleware
+// such as Timeout may return while the layers they wrap keep running.
+type breakdown struct {
+	mu     sync.Mutex
+	start  time.Time
+	route  string
+	names  []string
+	layers []layerTimer
+}
+
+type layerTimer struct {
+	start   time.Time
+	total   time.Duration
+	running bool
+}
+
+// startTiming attaches a fresh breakdown to r. ServeHTTP calls it, so the
+// router's share is measured too.
+func startTiming(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), timingKey{}, &breakdown{start: time.Now()}))
+}
+
+// beginTiming prepares the request's breakdown for the route and wraps w to
+// add the Server-Timing header when enabled.
+func (a *DefaultApp) beginTiming(w http.ResponseWriter, r *http.Request, rt *Route, pattern string) (http.ResponseWriter, *breakdown) {
+	b, _ := r.Context().Value(timingKey{}).(*breakdown)
+	if b == nil {
+		b = &breakdown{start: time.Now()} // served through the router directly
+	}
+	b.route, b.names = pattern, rt.middleware
+	b.layers = make([]layerTimer, len(rt.middleware)+1)
+	if a.timing.ServerTiming {
+		w = &timingWriter{ResponseWriter: w, b: b}
+	}
+	return w, b
+}
+
+// composeTimedChain is composeChain with every layer wrapped by a timer.
+func (a *DefaultApp) composeTimedChain(method, pattern string, h Handler, global, mws []Middleware) Handler {
+	all := append(append(make([]Middleware, 0, len(global)+len(mws)), global...), mws...)
+	final := timedLayer(len(all), h)
+	for i := len(all) - 1; i >= 0; i-- {
+		next := all[i](final)
+		if next == nil {
+			if i < len(global) {
+				panic(registrationError(method, pattern, fmt.Sprintf("global middleware #%d returned a nil handler", i+1)))
+			}
+			panic(registrationError(method, pattern, fmt.Sprintf("middleware #%d returned a nil handler", i-len(global)+1)))
+		}
+		final = timedLayer(i, next)
+	}
+	return final
+}
+
+// timedLayer times h as layer i of the request's breakdown.
+func timedLayer(i int, h Handler) Handler {
+	return func(c Ctx) error {
+		b, _ := c.Context().Value(timingKey{}).(*breakdown)
+		if b == nil || i >= len(b.layers) {
+			return h(c)
+		}
+		b.mu.Lock()
+		b.layers[i].start, b.layers[i].running = time.Now(), true
+		b.mu.Unlock()
+		defer func() {
+			b.mu.Lock()
+			b.layers[i].total += time.Since(b.layers[i].start)
+			b.layers[i].running = false
+			b.mu.Unlock()
+		}()
+		return h(c)
+	}
+}
+
+// snapshot computes the breakdown as of now; layers still running count
+// their time so far.
+func (b *breakdown) snapshot() Timing {
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	totals := make([]time.Duration, len(b.layers))
+	for i, l := range b.layers {
+		totals[i] = l.total
+		if l.running {
+			totals[i] += now.Sub(l.start)
+		}
+	}
+	t := Timing{Route: b.route, Total: now.Sub(b.start), Middleware: make([]MiddlewareTiming, len(b.names))}
+	if len(b.layers) > 0 && !b.layers[0].start.IsZero() {
+		t.Router = b.layers[0].start.Sub(b.start)
+	}
+	for i, name := range b.names {
+		t.Middleware[i] = MiddlewareTiming{Name: name, Duration: max(totals[i]-totals[i+1], 0)}
+	}
+	if n := len(totals); n > 0 {
+		t.Handler = totals[n-1]
+	}
+	return t
+}
+
+// serverTiming formats t as a Server-Timing header value in milliseconds.
+func (t Timing) serverTiming() string {
+	var sb strings.Builder
+	add := func(name string, d time.Duration) {
+		if sb.Len() > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(timingToken(name))
+		sb.WriteString(";dur=")
+		sb.WriteString(strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64))
+	}
+	add("router", t.Router)
+	for _, m := range t.Middleware {
+		add(m.Name, m.Duration)
+	}
+	add("handler", t.Handler)
+	add("total", t.Total)
+	return sb.String()
+}
+
+// timingToken replaces the characters of name that are not allowed in a
+// Server-Timing metric name (an HTTP token) with '_'.
+func timingToken(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x80 && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("!#$%&'*+-.^_`|~", r)) {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// reportTiming logs and reports the finished breakdown of c's request.
+func (a *DefaultApp) reportTiming(c *ctx.DefaultContext, b *breakdown) {
+	cfg := a.timing
+	if !cfg.Log && cfg.Report == nil {
+		return
+	}
+	t := b.snapshot()
+	if cfg.Log {
+		l := ctx.LoggerFromContext(c.Context())
+		if l.Enabled(c.Context(), slog.LevelDebug) {
+			mws := make([]any, len(t.Middleware))
+			for i, m := range t.Middleware {
+				mws[i] = slog.Duration(m.Name, m.Duration)
+			}
+			l.Debug("flash: timing", "method", c.Method(), "route", t.Route, "router", t.Router,
+				slog.Group("middleware", mws...), "handler", t.Handler, "total", t.Total)
+		}
+	}
+	if cfg.Report != nil {
+		cfg.Report(c, t)
+	}
+}
+
+// timingWriter adds the Server-Timing header when the response headers are
+// written.
+type timingWriter struct {
+	http.ResponseWriter
+	b     *breakdown
+	wrote bool
+}
+
+func (w *timingWriter) commit() {
+	if !w.wrote {
+		w.wrote = true
+		w.Header().Add("Server-Timing", w.b.snapshot().serverTiming())
+	}
+}
+
+func (w *timingWriter) WriteHeader(code int) {
+	if code >= 200 {
+		w.commit()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timingWriter) Write(p []byte) (int, error) {
+	w.commit()
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher when the underlying writer does.
+func (w *timingWriter) Flush() {
+	w.commit()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker when the underlying writer does.
+func (w *timingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("app: hijacking not supported")
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *timingWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sleepMW(name string, d time.Duration) Middleware {
+	return Describe(name, nil, func(next Handler) Handler {
+		return func(c Ctx) error {
+			time.Sleep(d)
+			return next(c)
+		}
+	})
+}
+
+func TestTimingBreakdown_ServerTimingAndReport(t *testing.T) {
+	var got Timing
+	a := New(WithTimingBreakdown(TimingConfig{
+		ServerTiming: true,
+		Report:       func(c Ctx, tm Timing) { got = tm },
+	}))
+	a.Use(sleepMW("slow", 20*time.Millisecond), sleepMW("fast", 0))
+	a.GET("/users/:id", func(c Ctx) error {
+		time.Sleep(10 * time.Millisecond)
+		return c.String(http.StatusOK, "ok")
+	}, Describe("auth", nil, identityMW))
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status=%d", rec.Code)
+	}
+
+	h := rec.Header().Get("Server-Timing")
+	var names []string
+	for _, part := range strings.Split(h, ", ") {
+		name, dur, ok := strings.Cut(part, ";dur=")
+		if !ok || dur == "" {
+			t.Fatalf("bad Server-Timing entry %q in %q", part, h)
+		}
+		names = append(names, name)
+	}
+	if strings.Join(names, ",") != "router,slow,fast,auth,handler,total" {
+		t.Fatalf("Server-Timing=%q", h)
+	}
+
+	if got.Route != "/users/:id" || len(got.Middleware) != 3 {
+		t.Fatalf("timing=%+v", got)
+	}
+	if m := got.Middleware[0]; m.Name != "slow" || m.Duration < 20*time.Millisecond {
+		t.Fatalf("slow middleware=%+v", m)
+	}
+	if m := got.Middleware[1]; m.Name != "fast" || m.Duration >= 10*time.Millisecond {
+		t.Fatalf("fast middleware=%+v", m)
+	}
+	if got.Handler < 10*time.Millisecond {
+		t.Fatalf("handler=%v", got.Handler)
+	}
+	if got.Total < got.Router+got.Handler+got.Middleware[0].Duration {
+		t.Fatalf("total %v shorter than its parts: %+v", got.Total, got)
+	}
+}
+
+func TestTimingBreakdown_LogsAtDebug(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	a := New(WithTimingBreakdown(TimingConfig{ServerTiming: true, Log: true}), WithLogger(logger))
+	a.Use(namedMW("requestid"))
+	a.GET("/fail", func(c Ctx) error { return errors.New("boom") })
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status=%d", rec.Code)
+	}
+	if !strings.Contains(rec.Header().Get("Server-Timing"), "requestid;dur=") {
+		t.Fatalf("error response lacks Server-Timing: %v", rec.Header())
+	}
+	out := logs.String()
+	for _, want := range []string{"flash: timing", "route=/fail", "middleware.requestid=", "handler=", "total="} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("log lacks %q: %s", want, out)
+		}
+	}
+}
+
+func TestTimingBreakdown_Off(t *testing.T) {
+	a := New()
+	a.GET("/", func(c Ctx) error { return c.String(http.StatusOK, "ok") })
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if h := rec.Header().Get("Server-Timing"); h != "" {
+		t.Fatalf("Server-Timing without WithTimingBreakdown: %q", h)
+	}
+}
+
+func TestTimingBreakdown_DefaultOmitsServerTiming(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	a := New(WithTimingBreakdown(), WithLogger(logger))
+	a.Use(namedMW("requestid"))
+	a.GET("/", func(c Ctx) error { return c.String(http.StatusOK, "ok") })
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if h := rec.Header().Get("Server-Timing"); h != "" {
+		t.Fatalf("default config sent Server-Timing: %q", h)
+	}
+	if !strings.Contains(logs.String(), "middleware.requestid=") {
+		t.Fatalf("default config did not log: %s", logs.String())
+	}
+}
+
+func TestTimingBreakdown_ShortCircuit(t *testing.T) {
+	var got Timing
+	a := New(WithTimingBreakdown(TimingConfig{Report: func(c Ctx, tm Timing) { got = tm }}))
+	deny := Describe("deny", nil, func(next Handler) Handler {
+		return func(c Ctx) error { return c.String(http.StatusForbidden, "no") }
+	})
+	a.GET("/", func(c Ctx) error { return c.String(http.StatusOK, "ok") }, deny)
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusForbidden || rec.Header().Get("Server-Timing") != "" {
+		t.Fatalf("status=%d header=%q", rec.Code, rec.Header().Get("Server-Timing"))
+	}
+	if got.Handler != 0 || len(got.Middleware) != 1 || got.Middleware[0].Name != "deny" {
+		t.Fatalf("timing=%+v", got)
+	}
+}
+
+func TestTimingToken(t *testing.T) {
+	if got := timingToken("main.(*T).Wrap-fm"); got != "main._*T_.Wrap-fm" {
+		t.Fatalf("token=%q", got)
+	}
+	if got := timingToken("oidc.require_login"); got != "oidc.require_login" {
+		t.Fatalf("token=%q", got)
+	}
+}
+
+func TestTimingWriter_Hijack(t *testing.T) {
+	checkHijack(t, func(w http.ResponseWriter) http.ResponseWriter { return &timingWriter{ResponseWriter: w} })
+}
@@ -0,0 +1,149 @@
+package app
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+// TraceConfig selects where WithRequestTrace reports each request's trace
+// timeline (see Ctx.Trace).
+type TraceConfig struct {
+	// ServerTiming adds the events recorded before the response headers are
+	// written to the Server-Timing header, e.g. `db.query;dur=12.5`.
+	ServerTiming bool
+	// Log writes one debug-level "flash: trace" line per request listing the
+	// events, through the request logger.
+	Log bool
+	// MaxEvents caps the events kept per request. Default
+	// ctx.DefaultTraceEvents.
+	MaxEvents int
+}
+
+// WithRequestTrace attaches a trace timeline to every request, so events
+// recorded with Ctx.Trace or ctx.TraceContext, including from code that only
+// has the request context, are collected without OpenTelemetry. Without a
+// config it only logs at debug level; the Server-Timing header exposes event
+// names and latencies to clients, so it must be enabled explicitly.
+// Development mode (WithDevMode) attaches timelines too, and its error pages
+// show them.
+//
+// Example:
+//
+//	a := app.New(app.WithRequestTrace(app.TraceConfig{ServerTiming: true, Log: true}))
+//	a.GET("/orders", func(c app.Ctx) error {
+//		start := time.Now()
+//		orders, err := db.Orders(c.Context())
+//		c.Trace("db.orders", time.Since(start))
+//		// ...
+//	})
+//	// Server-Timing: db.orders;dur=12.480
+func WithRequestTrace(cfg ...TraceConfig) Option {
+	c := TraceConfig{Log: true}
+	if len(cfg) > 0 {
+		c = cfg[0]
+	}
+	return func(a *DefaultApp) { a.trace = &c }
+}
+
+// startTrace attaches a timeline to r when tracing or development mode is
+// enabled.
+func (a *DefaultApp) startTrace(r *http.Request) *http.Request {
+	max := 0
+	if a.trace != nil {
+		max = a.trace.MaxEvents
+	}
+	return r.WithContext(ctx.WithTraceTimeline(r.Context(), max))
+}
+
+// reportTrace logs the timeline of c's request.
+func (a *DefaultApp) reportTrace(c *ctx.DefaultContext) {
+	if !a.trace.Log {
+		return
+	}
+	rc := c.Context()
+	l := ctx.LoggerFromContext(rc)
+	if !l.Enabled(rc, slog.LevelDebug) {
+		return
+	}
+	events, dropped := ctx.TraceEventsFromContext(rc)
+	if len(events) == 0 {
+		return
+	}
+	attrs := make([]any, len(events))
+	for i, ev := range events {
+		attrs[i] = slog.Group(strconv.Itoa(i), append([]any{"name", ev.Name, "duration", ev.Duration}, ev.Attrs...)...)
+	}
+	l.Debug("flash: trace", "method", c.Method(), "route", c.Route(), "dropped", dropped, slog.Group("events", attrs...))
+}
+
+// traceServerTiming formats the events of r's timeline as a Server-Timing
+// header value, or returns "".
+func traceServerTiming(r *http.Request) string {
+	events, _ := ctx.TraceEventsFromContext(r.Context())
+	var sb strings.Builder
+	for _, ev := range events {
+		if sb.Len() > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(timingToken(ev.Name))
+		sb.WriteString(";dur=")
+		sb.WriteString(strconv.FormatFloat(float64(ev.Duration)/float64(time.Millisecond), 'f', 3, 64))
+	}
+	return sb.String()
+}
+
+// traceWriter adds the timeline to the Server-Timing header when the
+// response headers are written.
+type traceWriter struct {
+	http.ResponseWriter
+	r     *http.Request
+	wrote bool
+}
+
+func (w *traceWriter) commit() {
+	if !w.wrote {
+		w.wrote = true
+		if v := traceServerTiming(w.r); v != "" {
+			w.Header().Add("Server-Timing", v)
+		}
+	}
+}
+
+func (w *traceWriter) WriteHeader(code int) {
+	if code >= 200 {
+		w.commit()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *traceWriter) Write(p []byte) (int, error) {
+	w.commit()
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher when the underlying writer does.
+func (w *traceWriter) Flush() {
+	w.commit()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker when the underlying writer does.
+func (w *traceWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("app: hijacking not supported")
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *traceWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+func TestRequestTrace_ServerTimingAndLog(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	a := New(WithRequestTrace(TraceConfig{ServerTiming: true, Log: true}), WithLogger(logger))
+	a.GET("/orders", func(c Ctx) error {
+		c.Trace("db.orders", 12*time.Millisecond, "rows", 3)
+		// Deeper code only sees the request context.
+		ctx.TraceContext(c.Context(), "cache get", 500*time.Microsecond)
+		return c.String(http.StatusOK, "ok")
+	})
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders", nil))
+	if h := rec.Header().Get("Server-Timing"); h != "db.orders;dur=12.000, cache_get;dur=0.500" {
+		t.Fatalf("Server-Timing=%q", h)
+	}
+	out := logs.String()
+	for _, want := range []string{"flash: trace", "route=/orders", "events.0.name=db.orders", "events.0.rows=3", "events.1.duration=500µs"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("log lacks %q: %s", want, out)
+		}
+	}
+}
+
+func TestRequestTrace_DefaultOmitsServerTiming(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	a := New(WithRequestTrace(), WithLogger(logger))
+	a.GET("/", func(c Ctx) error {
+		c.Trace("db.orders", time.Millisecond)
+		return c.String(http.StatusOK, "ok")
+	})
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if h := rec.Header().Get("Server-Timing"); h != "" {
+		t.Fatalf("default config sent Server-Timing: %q", h)
+	}
+	if !strings.Contains(logs.String(), "events.0.name=db.orders") {
+		t.Fatalf("default config did not log: %s", logs.String())
+	}
+}
+
+func TestRequestTrace_Off(t *testing.T) {
+	a := New()
+	var seen int
+	a.GET("/", func(c Ctx) error {
+		ctx.TraceContext(c.Context(), "dropped", time.Millisecond) // no timeline attached
+		c.Trace("dropped", time.Millisecond)
+		seen = len(c.TraceEvents())
+		return c.String(http.StatusOK, "ok")
+	})
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Header().Get("Server-Timing") != "" || seen != 0 {
+		t.Fatalf("Server-Timing=%q events=%d", rec.Header().Get("Server-Timing"), seen)
+	}
+}
+
+func TestRequestTrace_DevErrorPage(t *testing.T) {
+	a := New(WithDevMode(true))
+	a.GET("/fail", func(c Ctx) error {
+		ctx.TraceContext(c.Context(), "upstream.call", 30*time.Millisecond, "host", "billing")
+		return errors.New("upstream failed")
+	})
+	req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set("Accept", "text/html")
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	body := rec.Body.String()
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(body, "<h2>Trace</h2>") ||
+		!strings.Contains(body, "<td>upstream.call</td><td>30ms</td><td>host billing</td>") {
+		t.Fatalf("code=%d body=%s", rec.Code, body)
+	}
+	if rec.Header().Get("Server-Timing") != "" {
+		t.Fatalf("dev mode alone should not set Server-Timing")
+	}
+}
+
+func TestTraceWriter_Hijack(t *testing.T) {
+	checkHijack(t, func(w http.ResponseWriter) http.ResponseWriter { return &traceWriter{ResponseWriter: w} })
+}
@@ -0,0 +1,513 @@
+package app
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// TreeRouter is an in-repo Router implementation built on a segment radix tree.
+// It supports everything the default httprouter-based router does, plus:
+//
+//   - Param constraints: ":id<int>", ":id<uint>", ":slug<alpha>", ":code<alnum>",
+//     ":id<uuid>" or any regular expression, e.g. ":year<[0-9]{4}>". Constraints
+//     are matched against a single path segment and may not contain "/".
+//   - Overlapping routes: static segments win over constrained params, which win
+//     over plain params, which win over catch-all wildcards. Lookup backtracks, so
+//     "/users/me" and "/users/:id<int>" and "/users/:name" can coexist.
+//   - Host-prefixed patterns: "api.example.com/users/:id" or
+//     ":tenant.example.com/dashboard". Host labels starting with ":" capture a
+//     param. Host routes are tried before host-less routes.
+//
+// Catch-all wildcards ("*filepath") must be the last segment and capture the rest
+// of the path including its leading slash, matching httprouter semantics.
+//
+// Example:
+//
+//	a := flash.New(flash.WithRouter(flash.NewTreeRouter()))
+//	a.GET("/users/:id<int>", ShowUser)
+//	a.GET("/users/:name", ShowUserByName)
+//	a.GET(":tenant.example.com/", TenantHome) // c.Param("tenant")
+type TreeRouter struct {
+	// RedirectTrailingSlash redirects "/foo/" to "/foo" (and vice versa) when only
+	// the other form is registered. GET requests use 301, others 307. Default true.
+	RedirectTrailingSlash bool
+	// HandleMethodNotAllowed answers with 405 and an Allow header when the path
+	// matches for other methods. Default true.
+	HandleMethodNotAllowed bool
+	// HandleOPTIONS answers OPTIONS requests without a registered handler with an
+	// Allow header. Default true.
+	HandleOPTIONS bool
+
+	notFound  http.Handler
+	methodNA  http.Handler
+	paths     map[string]*treeNode // method -> root for host-less routes
+	hosts     []*hostRoutes        // host-prefixed routes, in registration order
+	maxParams int
+}
+
+// hostRoutes holds the method trees registered for one host pattern.
+type hostRoutes struct {
+	host   string
+	labels []string
+	trees  map[string]*treeNode
+}
+
+// treeNode is a node of the segment tree. Static children are keyed by segment;
+// param children are ordered constrained-first; wildcard is the catch-all child.
+type treeNode struct {
+	static     map[string]*treeNode
+	params     []*treeNode
+	wildcard   *treeNode
+	name       string            // param or wildcard name
+	constraint string            // raw constraint text ("" = any segment)
+	match      func(string) bool // compiled constraint (nil = any segment)
+	handle     RouteHandler
+}
+
+// NewTreeRouter returns a TreeRouter with trailing-slash redirects, 405 handling
+// and automatic OPTIONS responses enabled.
+func NewTreeRouter() *TreeRouter {
+	return &TreeRouter{
+		RedirectTrailingSlash:  true,
+		HandleMethodNotAllowed: true,
+		HandleOPTIONS:          true,
+		paths:                  map[string]*treeNode{},
+	}
+}
+
+// SetNotFound sets the handler used when no route matches.
+func (t *TreeRouter) SetNotFound(h http.Handler) { t.notFound = h }
+
+// SetMethodNotAllowed sets the handler used when the path matches other methods only.
+func (t *TreeRouter) SetMethodNotAllowed(h http.Handler) { t.methodNA = h }
+
+// Handle registers h for method and pattern. It panics on malformed patterns and
+// on routes that conflict with existing registrations.
+func (t *TreeRouter) Handle(method, pattern string, h RouteHandler) {
+	if method == "" {
+		panic("flash: method must not be empty")
+	}
+	if h == nil {
+		panic("flash: handle must not be nil")
+	}
+	host, path := splitHostPattern(pattern)
+	if path == "" || path[0] != '/' {
+		panic("flash: path must begin with '/' in pattern '" + pattern + "'")
+	}
+	trees, hostParams := t.paths, 0
+	if host != "" {
+		hr := t.hostRoutes(host, pattern)
+		trees = hr.trees
+		for _, l := range hr.labels {
+			if strings.HasPrefix(l, ":") {
+				hostParams++
+			}
+		}
+	}
+	root := trees[method]
+	if root == nil {
+		root = &treeNode{}
+		trees[method] = root
+	}
+	n, params := root.insert(path, pattern)
+	if n.handle != nil {
+		panic("flash: a handle is already registered for " + method + " '" + pattern + "'")
+	}
+	n.handle = h
+	if total := hostParams + params; total > t.maxParams {
+		t.maxParams = total
+	}
+}
+
+// ServeHTTP dispatches the request to the matching route.
+func (t *TreeRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	if len(t.hosts) > 0 {
+		host := stripHostPort(r.Host)
+		for _, hr := range t.hosts {
+			ps, ok := hr.matchHost(host, t.maxParams)
+			if !ok {
+				continue
+			}
+			if root := hr.trees[r.Method]; root != nil {
+				if n := root.find(path, &ps, t.maxParams); n != nil {
+					n.handle(w, r, ps)
+					return
+				}
+			}
+		}
+	}
+	if root := t.paths[r.Method]; root != nil {
+		var ps Params
+		if n := root.find(path, &ps, t.maxParams); n != nil {
+			n.handle(w, r, ps)
+			return
+		}
+	}
+
+	if r.Method == http.MethodOptions && t.HandleOPTIONS {
+		if allow := t.allowed(r, path); allow != "" {
+			w.Header().Set("Allow", allow)
+			return
+		}
+	}
+	if t.RedirectTrailingSlash && r.Method != http.MethodConnect && path != "/" {
+		alt := path + "/"
+		if strings.HasSuffix(path, "/") {
+			alt = path[:len(path)-1]
+		}
+		if t.matches(r, r.Method, alt) {
+			code := http.StatusTemporaryRedirect
+			if r.Method == http.MethodGet {
+				code = http.StatusMovedPermanently
+			}
+			u := *r.URL
+			u.Path = alt
+			u.RawPath = ""
+			http.Redirect(w, r, u.String(), code)
+			return
+		}
+	}
+	if t.HandleMethodNotAllowed {
+		if allow := t.allowed(r, path); allow != "" {
+			w.Header().Set("Allow", allow)
+			if t.methodNA != nil {
+				t.methodNA.ServeHTTP(w, r)
+			} else {
+				http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			}
+			return
+		}
+	}
+	if t.notFound != nil {
+		t.notFound.ServeHTTP(w, r)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// matches reports whether a route is registered for method and path on r's host.
+func (t *TreeRouter) matches(r *http.Request, method, path string) bool {
+	host := stripHostPort(r.Host)
+	for _, hr := range t.hosts {
+		ps, ok := hr.matchHost(host, t.maxParams)
+		if !ok {
+			continue
+		}
+		if root := hr.trees[method]; root != nil && root.find(path, &ps, t.maxParams) != nil {
+			return true
+		}
+	}
+	var ps Params
+	root := t.paths[method]
+	return root != nil && root.find(path, &ps, t.maxParams) != nil
+}
+
+// allowed returns the sorted Allow header value for path, or "" if no other
+// method matches.
+func (t *TreeRouter) allowed(r *http.Request, path string) string {
+	seen := map[string]bool{}
+	collect := func(trees map[string]*treeNode) {
+		for m := range trees {
+			if m == r.Method || m == http.MethodOptions || seen[m] {
+				continue
+			}
+			if t.matches(r, m, path) {
+				seen[m] = true
+			}
+		}
+	}
+	host := stripHostPort(r.Host)
+	for _, hr := range t.hosts {
+		if _, ok := hr.matchHost(host, 0); ok {
+			collect(hr.trees)
+		}
+	}
+	collect(t.paths)
+	if len(seen) == 0 {
+		return ""
+	}
+	allow := make([]string, 0, len(seen)+1)
+	for m := range seen {
+		allow = append(allow, m)
+	}
+	allow = append(allow, http.MethodOptions)
+	sort.Strings(allow)
+	return strings.Join(allow, ", ")
+}
+
+// hostRoutes returns (creating if needed) the route set for a host pattern.
+func (t *TreeRouter) hostRoutes(host, pattern string) *hostRoutes {
+	host = strings.ToLower(host)
+	for _, hr := range t.hosts {
+		if hr.host == host {
+			return hr
+		}
+	}
+	labels := strings.Split(host, ".")
+	for _, l := range labels {
+		if l == "" || l == ":" {
+			panic("flash: invalid host in pattern '" + pattern + "'")
+		}
+	}
+	hr := &hostRoutes{host: host, labels: labels, trees: map[string]*treeNode{}}
+	t.hosts = append(t.hosts, hr)
+	return hr
+}
+
+// matchHost matches host against the pattern labels, capturing ":name" labels.
+func (hr *hostRoutes) matchHost(host string, capHint int) (Params, bool) {
+	var ps Params
+	for _, l := range hr.labels {
+		if host == "" {
+			return nil, false
+		}
+		var label string
+		label, host, _ = strings.Cut(host, ".")
+		if label == "" {
+			return nil, false
+		}
+		if l[0] == ':' {
+			if ps == nil {
+				ps = make(Params, 0, capHint)
+			}
+			ps = append(ps, Param{Key: l[1:], Value: label})
+			continue
+		}
+		if !strings.EqualFold(l, label) {
+			return nil, false
+		}
+	}
+	if host != "" {
+		return nil, false
+	}
+	return ps, true
+}
+
+// insert walks/creates nodes for path and returns the leaf with its param count.
+func (n *treeNode) insert(path, pattern string) (*treeNode, int) {
+	segs := strings.Split(path[1:], "/")
+	cur, count := n, 0
+	for i, seg := range segs {
+		switch {
+		case strings.HasPrefix(seg, "*"):
+			if i != len(segs)-1 {
+				panic("flash: catch-all routes are only allowed at the end of the path in pattern '" + pattern + "'")
+			}
+			name := seg[1:]
+			if name == "" {
+				panic("flash: catch-all must be named in pattern '" + pattern + "'")
+			}
+			if cur.wildcard == nil {
+				cur.wildcard = &treeNode{name: name}
+			} else if cur.wildcard.name != name {
+				panic("flash: catch-all '" + seg + "' conflicts with '*" + cur.wildcard.name + "' in pattern '" + pattern + "'")
+			}
+			cur = cur.wildcard
+			count++
+		case strings.HasPrefix(seg, ":"):
+			name, constraint := parseParamSegment(seg, pattern)
+			var child *treeNode
+			for _, p := range cur.params {
+				if p.constraint == constraint {
+					child = p
+					break
+				}
+			}
+			if child != nil && child.name != name {
+				panic("flash: param '" + seg + "' conflicts with ':" + child.name + "' in pattern '" + pattern + "'")
+			}
+			if child == nil {
+				child = &treeNode{name: name, constraint: constraint, match: compileConstraint(constraint, pattern)}
+				cur.addParam(child)
+			}
+			cur = child
+			count++
+		default:
+			if cur.static == nil {
+				cur.static = map[string]*treeNode{}
+			}
+			child := cur.static[seg]
+			if child == nil {
+				child = &treeNode{}
+				cur.static[seg] = child
+			}
+			cur = child
+		}
+	}
+	return cur, count
+}
+
+// addParam inserts a param child keeping constrained params before plain ones.
+func (n *treeNode) addParam(child *treeNode) {
+	if child.match == nil {
+		n.params = append(n.params, child)
+		return
+	}
+	i := 0
+	for i < len(n.params) && n.params[i].match != nil {
+		i++
+	}
+	n.params = append(n.params, nil)
+	copy(n.params[i+1:], n.params[i:])
+	n.params[i] = child
+}
+
+// find resolves path (beginning with "/" or empty at the end) below n, appending
+// captured params to ps. It backtracks across static, param and wildcard children.
+func (n *treeNode) find(path string, ps *Params, capHint int) *treeNode {
+	if path == "" {
+		if n.handle != nil {
+			return n
+		}
+		return nil
+	}
+	seg, next := path[1:], ""
+	if i := strings.IndexByte(seg, '/'); i >= 0 {
+		seg, next = seg[:i], seg[i:]
+	}
+	if c := n.static[seg]; c != nil {
+		if found := c.find(next, ps, capHint); found != nil {
+			return found
+		}
+	}
+	if seg != "" {
+		for _, p := range n.params {
+			if p.match != nil && !p.match(seg) {
+				continue
+			}
+			l := len(*ps)
+			pushParam(ps, p.name, seg, capHint)
+			if found := p.find(next, ps, capHint); found != nil {
+				return found
+			}
+			*ps = (*ps)[:l]
+		}
+	}
+	if w := n.wildcard; w != nil && w.handle != nil {
+		pushParam(ps, w.name, path, capHint)
+		return w
+	}
+	return nil
+}
+
+// pushParam appends a param, sizing the slice once for the deepest route.
+func pushParam(ps *Params, key, value string, capHint int) {
+	if *ps == nil {
+		*ps = make(Params, 0, capHint)
+	}
+	*ps = append(*ps, Param{Key: key, Value: value})
+}
+
+// parseParamSegment splits ":name<constraint>" into name and constraint.
+func parseParamSegment(seg, pattern string) (string, string) {
+	name, constraint := seg[1:], ""
+	if i := strings.IndexByte(name, '<'); i >= 0 {
+		if !strings.HasSuffix(name, ">") {
+			panic("flash: missing '>' in param '" + seg + "' in pattern '" + pattern + "'")
+		}
+		name, constraint = name[:i], name[i+1:len(name)-1]
+		if constraint == "" {
+			panic("flash: empty constraint in param '" + seg + "' in pattern '" + pattern + "'")
+		}
+	}
+	if name == "" {
+		panic("flash: param must be named in pattern '" + pattern + "'")
+	}
+	return name, constraint
+}
+
+// builtinConstraints are the named constraints usable as ":id<int>".
+var builtinConstraints = map[string]func(string) bool{
+	"int": func(s string) bool {
+		if s != "" && (s[0] == '-' || s[0] == '+') {
+			s = s[1:]
+		}
+		return isDigits(s)
+	},
+	"uint": isDigits,
+	"alpha": func(s string) bool {
+		for i := 0; i < len(s); i++ {
+			if c := s[i] | 0x20; c < 'a' || c > 'z' {
+				return false
+			}
+		}
+		return s != ""
+	},
+	"alnum": func(s string) bool {
+		for i := 0; i < len(s); i++ {
+			c := s[i]
+			if lc := c | 0x20; (lc < 'a' || lc > 'z') && (c < '0' || c > '9') {
+				return false
+			}
+		}
+		return s != ""
+	},
+	"uuid": func(s string) bool {
+		if len(s) != 36 {
+			return false
+		}
+		for i := 0; i < len(s); i++ {
+			c := s[i]
+			switch i {
+			case 8, 13, 18, 23:
+				if c != '-' {
+					return false
+				}
+			default:
+				if lc := c | 0x20; (c < '0' || c > '9') && (lc < 'a' || lc > 'f') {
+					return false
+				}
+			}
+		}
+		return true
+	},
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// compileConstraint returns the matcher for a constraint: a builtin name or an
+// anchored regular expression. An empty constraint matches any segment.
+func compileConstraint(constraint, pattern string) func(string) bool {
+	if constraint == "" {
+		return nil
+	}
+	if fn, ok := builtinConstraints[constraint]; ok {
+		return fn
+	}
+	re, err := regexp.Compile("^(?:" + constraint + ")$")
+	if err != nil {
+		panic("flash: invalid constraint '" + constraint + "' in pattern '" + pattern + "': " + err.Error())
+	}
+	return re.MatchString
+}
+
+// splitHostPattern splits "host/path" into host and path; patterns starting with
+// "/" have no host.
+func splitHostPattern(pattern string) (string, string) {
+	if strings.HasPrefix(pattern, "/") {
+		return "", pattern
+	}
+	i := strings.IndexByte(pattern, '/')
+	if i == -1 {
+		return pattern, ""
+	}
+	return pattern[:i], pattern[i:]
+}
+
+// stripHostPort removes an optional port from a Host header value.
+func stripHostPort(host string) string {
+	if i := strings.LastIndexByte(host, ':'); i != -1 && i > strings.LastIndexByte(host, ']') {
+		host = host[:i]
+	}
+	return strings.TrimSuffix(host, ".")
+}
@@ -0,0 +1,254 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// echoRoute returns a RouteHandler writing the route name and params.
+func echoRoute(name string) RouteHandler {
+	return func(w http.ResponseWriter, r *http.Request, ps Params) {
+		var b strings.Builder
+		b.WriteString(name)
+		for _, p := range ps {
+			b.WriteString(" " + p.Key + "=" + p.Value)
+		}
+		_, _ = w.Write([]byte(b.String()))
+	}
+}
+
+func serveTree(t *testing.T, tr *TreeRouter, method, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	tr.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	return rec
+}
+
+func TestTreeRouter_MatchingAndPriority(t *testing.T) {
+	tr := NewTreeRouter()
+	tr.Handle(http.MethodGet, "/", echoRoute("root"))
+	tr.Handle(http.MethodGet, "/users/me", echoRoute("me"))
+	tr.Handle(http.MethodGet, "/users/:id<int>", echoRoute("byid"))
+	tr.Handle(http.MethodGet, "/users/:name", echoRoute("byname"))
+	tr.Handle(http.MethodGet, "/users/:name/posts/:post<uuid>", echoRoute("post"))
+	tr.Handle(http.MethodGet, "/users/:name/files/*path", echoRoute("files"))
+	tr.Handle(http.MethodGet, "/archive/:year<[0-9]{4}>", echoRoute("year"))
+	tr.Handle(http.MethodGet, "/static/*filepath", echoRoute("static"))
+	tr.Handle(http.MethodGet, "/dir/", echoRoute("dir"))
+
+	cases := map[string]string{
+		"/":                        "root",
+		"/users/me":                "me",
+		"/users/42":                "byid id=42",
+		"/users/-7":                "byid id=-7",
+		"/users/ada":               "byname name=ada",
+		"/users/ada/files/a/b.txt": "files name=ada path=/a/b.txt",
+		"/users/ada/posts/123e4567-e89b-12d3-a456-426614174000": "post name=ada post=123e4567-e89b-12d3-a456-426614174000",
+		"/archive/2024": "year year=2024",
+		"/static/":      "static filepath=/",
+		"/static/css/x": "static filepath=/css/x",
+		"/dir/":         "dir",
+	}
+	for path, want := range cases {
+		rec := serveTree(t, tr, http.MethodGet, path)
+		if rec.Code != http.StatusOK || rec.Body.String() != want {
+			t.Fatalf("%s: code=%d body=%q want %q", path, rec.Code, rec.Body.String(), want)
+		}
+	}
+	for _, path := range []string{"/archive/24", "/users/ada/posts/not-a-uuid", "/nope", "/users//x"} {
+		if rec := serveTree(t, tr, http.MethodGet, path); rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404, got %d %q", path, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestTreeRouter_BacktracksAcrossBranches(t *testing.T) {
+	tr := NewTreeRouter()
+	tr.Handle(http.MethodGet, "/a/b/c", echoRoute("static"))
+	tr.Handle(http.MethodGet, "/a/:x/d", echoRoute("param"))
+	tr.Handle(http.MethodGet, "/a/*rest", echoRoute("wild"))
+	for path, want := range map[string]string{
+		"/a/b/c": "static",
+		"/a/b/d": "param x=b",
+		"/a/b/e": "wild rest=/b/e",
+	} {
+		if got := serveTree(t, tr, http.MethodGet, path).Body.String(); got != want {
+			t.Fatalf("%s: got %q want %q", path, got, want)
+		}
+	}
+}
+
+func TestTreeRouter_BuiltinConstraints(t *testing.T) {
+	cases := []struct {
+		constraint string
+		ok, bad    []string
+	}{
+		{"int", []string{"1", "-2", "+3"}, []string{"", "-", "1a"}},
+		{"uint", []string{"0", "12"}, []string{"-1", "x"}},
+		{"alpha", []string{"abc", "XyZ"}, []string{"", "a1"}},
+		{"alnum", []string{"a1", "Z9"}, []string{"", "a-1"}},
+		{"uuid", []string{"123E4567-e89b-12d3-a456-426614174000"}, []string{"123e4567e89b12d3a456426614174000", "123e4567-e89b-12d3-a456-42661417400g", "123e4567+e89b-12d3-a456-426614174000"}},
+	}
+	for _, tc := range cases {
+		fn := builtinConstraints[tc.constraint]
+		for _, s := range tc.ok {
+			if !fn(s) {
+				t.Fatalf("%s should accept %q", tc.constraint, s)
+			}
+		}
+		for _, s := range tc.bad {
+			if fn(s) {
+				t.Fatalf("%s should reject %q", tc.constraint, s)
+			}
+		}
+	}
+}
+
+func TestTreeRouter_HostRoutes(t *testing.T) {
+	tr := NewTreeRouter()
+	tr.Handle(http.MethodGet, "api.example.com/users/:id", echoRoute("api"))
+	tr.Handle(http.MethodGet, ":tenant.example.com/users/:id", echoRoute("tenant"))
+	tr.Handle(http.MethodGet, "/users/:id", echoRoute("any"))
+
+	for host, want := range map[string]string{
+		"api.example.com":      "api id=1",
+		"API.example.com:8080": "api id=1",
+		"acme.example.com":     "tenant tenant=acme id=1",
+		"example.com":          "any id=1",
+		"a.b.example.com":      "any id=1",
+		"localhost":            "any id=1",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		tr.ServeHTTP(rec, req)
+		if rec.Body.String() != want {
+			t.Fatalf("%s: got %q want %q", host, rec.Body.String(), want)
+		}
+	}
+}
+
+func TestTreeRouter_MethodNotAllowedOptionsAndRedirects(t *testing.T) {
+	tr := NewTreeRouter()
+	tr.Handle(http.MethodGet, "/items", echoRoute("list"))
+	tr.Handle(http.MethodPost, "/items", echoRoute("create"))
+	tr.Handle(http.MethodPut, "/items/:id/", echoRoute("update"))
+
+	rec := serveTree(t, tr, http.MethodDelete, "/items")
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, OPTIONS, POST" {
+		t.Fatalf("405: code=%d allow=%q", rec.Code, rec.Header().Get("Allow"))
+	}
+	rec = serveTree(t, tr, http.MethodOptions, "/items")
+	if rec.Code != http.StatusOK || rec.Header().Get("Allow") != "GET, OPTIONS, POST" {
+		t.Fatalf("OPTIONS: code=%d allow=%q", rec.Code, rec.Header().Get("Allow"))
+	}
+	rec = serveTree(t, tr, http.MethodGet, "/items/?q=1")
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/items?q=1" {
+		t.Fatalf("GET redirect: code=%d loc=%q", rec.Code, rec.Header().Get("Location"))
+	}
+	rec = serveTree(t, tr, http.MethodPut, "/items/5")
+	if rec.Code != http.StatusTemporaryRedirect || rec.Header().Get("Location") != "/items/5/" {
+		t.Fatalf("PUT redirect: code=%d loc=%q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	tr.RedirectTrailingSlash = false
+	tr.HandleOPTIONS = false
+	tr.HandleMethodNotAllowed = false
+	tr.SetNotFound(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTeapot) }))
+	for _, m := range []string{http.MethodGet + " /items/", http.MethodOptions + " /items", http.MethodDelete + " /items"} {
+		method, path, _ := strings.Cut(m, " ")
+		if rec := serveTree(t, tr, method, path); rec.Code != http.StatusTeapot {
+			t.Fatalf("%s: expected custom 404, got %d", m, rec.Code)
+		}
+	}
+
+	tr.HandleMethodNotAllowed = true
+	tr.SetMethodNotAllowed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusConflict) }))
+	if rec := serveTree(t, tr, http.MethodDelete, "/items"); rec.Code != http.StatusConflict {
+		t.Fatalf("custom 405 handler not used: %d", rec.Code)
+	}
+}
+
+func TestTreeRouter_RegistrationPanics(t *testing.T) {
+	h := echoRoute("x")
+	cases := map[string]func(tr *TreeRouter){
+		"empty method":       func(tr *TreeRouter) { tr.Handle("", "/", h) },
+		"nil handle":         func(tr *TreeRouter) { tr.Handle(http.MethodGet, "/", nil) },
+		"no slash":           func(tr *TreeRouter) { tr.Handle(http.MethodGet, "example.com", h) },
+		"duplicate":          func(tr *TreeRouter) { tr.Handle(http.MethodGet, "/a", h); tr.Handle(http.MethodGet, "/a", h) },
+		"wildcard not last":  func(tr *TreeRouter) { tr.Handle(http.MethodGet, "/a/*x/b", h) },
+		"unnamed wildcard":   func(tr *TreeRouter) { tr.Handle(http.MethodGet, "/a/*", h) },
+		"wildcard conflict":  func(tr *TreeRouter) { tr.Handle(http.MethodGet, "/a/*x", h); tr.Handle(http.MethodGet, "/a/*y", h) },
+		"param conflict":     func(tr *TreeRouter) { tr.Handle(http.MethodGet, "/a/:x", h); tr.Handle(http.MethodGet, "/a/:y/b", h) },
+		"unnamed param":      func(tr *TreeRouter) { tr.Handle(http.MethodGet, "/a/:", h) },
+		"unclosed":           func(tr *TreeRouter) { tr.Handle(http.MethodGet, "/a/:x<int", h) },
+		"empty constraint":   func(tr *TreeRouter) { tr.Handle(http.MethodGet, "/a/:x<>", h) },
+		"invalid regexp":     func(tr *TreeRouter) { tr.Handle(http.MethodGet, "/a/:x<[>", h) },
+		"invalid host label": func(tr *TreeRouter) { tr.Handle(http.MethodGet, "a..b/x", h) },
+	}
+	for name, fn := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s: expected panic", name)
+				}
+			}()
+			fn(NewTreeRouter())
+		}()
+	}
+}
+
+func TestTreeRouter_WithApp(t *testing.T) {
+	a := New(WithRouter(NewTreeRouter()), nil, WithRouter(nil))
+	a.GET("/users/:id<int>", func(c Ctx) error { return c.String(http.StatusOK, "id "+c.Param("id")) })
+	a.GET("/users/:name", func(c Ctx) error { return c.String(http.StatusOK, "name "+c.Param("name")) })
+	a.HandleHTTP(http.MethodGet, "/raw/:v", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(httprouter.ParamsFromContext(r.Context()).ByName("v")))
+	}))
+	a.SetNotFoundHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusGone) }))
+
+	for path, want := range map[string]string{"/users/7": "id 7", "/users/bob": "name bob", "/raw/x": "x"} {
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Body.String() != want {
+			t.Fatalf("%s: got %q want %q", path, rec.Body.String(), want)
+		}
+	}
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if rec.Code != http.StatusGone {
+		t.Fatalf("not found handler not wired: %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users/1", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("method not allowed handler not wired: %d", rec.Code)
+	}
+}
+
+func Test_stripHostPortAndSplitHostPattern(t *testing.T) {
+	for in, want := range map[string]string{
+		"example.com":    "example.com",
+		"example.com:80": "example.com",
+		"example.com.":   "example.com",
+		"[::1]:8080":     "[::1]",
+		"[::1]":          "[::1]",
+	} {
+		if got := stripHostPort(in); got != want {
+			t.Fatalf("stripHostPort(%q)=%q want %q", in, got, want)
+		}
+	}
+	if h, p := splitHostPattern("/x"); h != "" || p != "/x" {
+		t.Fatalf("got %q %q", h, p)
+	}
+	if h, p := splitHostPattern("a.com/x"); h != "a.com" || p != "/x" {
+		t.Fatalf("got %q %q", h, p)
+	}
+	if h, p := splitHostPattern("a.com"); h != "a.com" || p != "" {
+		t.Fatalf("got %q %q", h, p)
+	}
+}
@@ -1,8 +1,14 @@
 package app
 
 import (
+	"context"
+	"io/fs"
 	"log/slog"
+	"net"
 	"net/http"
+	"time"
+
+	"github.com/goflash/flash/v2/ctx"
 )
 
 // App defines the public surface of the router/app, suitable for mocking.
@@ -10,17 +16,25 @@ import (
 type App interface {
 	// Middleware management
 	Use(mw ...Middleware)
+	UseOrdered(p Phase, mw ...Middleware)
+	UsePrefix(prefix string, mw ...Middleware)
+	MiddlewareStack() []MiddlewareInfo
 
 	// Route registration
-	GET(path string, h Handler, mws ...Middleware)
-	POST(path string, h Handler, mws ...Middleware)
-	PUT(path string, h Handler, mws ...Middleware)
-	PATCH(path string, h Handler, mws ...Middleware)
-	DELETE(path string, h Handler, mws ...Middleware)
-	OPTIONS(path string, h Handler, mws ...Middleware)
-	HEAD(path string, h Handler, mws ...Middleware)
-	ANY(path string, h Handler, mws ...Middleware)
-	Handle(method, path string, h Handler, mws ...Middleware)
+	GET(path string, h Handler, mws ...Middleware) *Route
+	POST(path string, h Handler, mws ...Middleware) *Route
+	PUT(path string, h Handler, mws ...Middleware) *Route
+	PATCH(path string, h Handler, mws ...Middleware) *Route
+	DELETE(path string, h Handler, mws ...Middleware) *Route
+	OPTIONS(path string, h Handler, mws ...Middleware) *Route
+	HEAD(path string, h Handler, mws ...Middleware) *Route
+	ANY(path string, h Handler, mws ...Middleware) *Route
+	Handle(method, path string, h Handler, mws ...Middleware) *Route
+
+	// Route introspection and development docs
+	Routes() []*Route
+	DocsHandler() http.Handler
+	URL(name string, params ...any) (string, error)
 
 	// HTTP integration and mounting
 	ServeHTTP(w http.ResponseWriter, r *http.Request)
@@ -28,21 +42,86 @@ type App interface {
 	Mount(path string, h http.Handler)
 	Static(prefix, dir string)
 	StaticDirs(prefix string, dirs ...string)
+	StaticFS(prefix string, fsys fs.FS)
+	Assets(prefix string, cfg AssetConfig) (*Assets, error)
+	ServeFCGI(ln net.Listener) error
+	ServeCGI() error
 
-	// Grouping
+	// Grouping and resource controllers
 	Group(prefix string, mw ...Middleware) *Group
+	Resource(path string, controller any, mw ...Middleware) []*Route
 
 	// Logging
 	SetLogger(l *slog.Logger)
 	Logger() *slog.Logger
 
+	// Binding defaults used by Bind* helpers when no options are passed
+	SetBindDefaults(o ctx.BindJSONOptions)
+	BindDefaults() (ctx.BindJSONOptions, bool)
+
+	// Sanitizer policies for Ctx.ParamSanitized and Ctx.QuerySanitized
+	RegisterSanitizer(name string, fn ctx.Sanitizer)
+
+	// Config returns the configuration passed to NewFromConfig, if any
+	Config() (Config, bool)
+
+	// Modules
+	RegisterModules(mods ...Module) error
+
+	// Draining, health probes and shutdown hooks
+	BeginDrain()
+	Draining() bool
+	LivenessHandler() http.Handler
+	ReadinessHandler() http.Handler
+	AddHealthCheck(name string, check HealthCheck)
+	Watchdog(ctx context.Context) error
+	OnShutdown(hook ShutdownHook)
+	WaitResponseHooks(ctx context.Context) error
+	Shutdown(ctx context.Context, srv *http.Server, delay time.Duration) error
+
+	// Framework lifecycle events
+	OnEvent(h EventHandler, names ...string) (unsubscribe func())
+
 	// Error/NotFound/MethodNotAllowed handlers
 	SetErrorHandler(h ErrorHandler)
+	SetErrorHandlerV2(h ErrorHandlerV2)
 	SetNotFoundHandler(h http.Handler)
+	SetErrorTemplates(fsys fs.FS, pages map[int]string) error
 	SetMethodNotAllowedHandler(h http.Handler)
 
 	// Getters for handlers (mirrors Set*). Useful when holding App as an interface.
 	ErrorHandler() ErrorHandler
+	ErrorHandlerV2() ErrorHandlerV2
 	NotFoundHandler() http.Handler
 	MethodNotAllowedHandler() http.Handler
 }
+
+// Param is a single URL path parameter. Re-exported from ctx.Param.
+type Param = ctx.Param
+
+// Params are the path parameters captured by a route, in pattern order.
+// Re-exported from ctx.Params.
+type Params = ctx.Params
+
+// RouteHandler is the low-level handler signature routers dispatch to. It receives
+// the raw writer and request plus the params captured by the matched route.
+type RouteHandler func(w http.ResponseWriter, r *http.Request, ps Params)
+
+// Router abstracts the route matching engine used by the app. The default is
+// backed by github.com/julienschmidt/httprouter (see NewHTTPRouter); TreeRouter
+// is an in-repo alternative with param constraints and host routing. Select one
+// with WithRouter.
+//
+// Implementations must be safe for concurrent ServeHTTP calls once registration
+// is complete. Registration itself happens before serving and need not be
+// concurrency-safe.
+type Router interface {
+	http.Handler
+	// Handle registers h for method and pattern. Implementations panic on invalid
+	// or conflicting patterns.
+	Handle(method, pattern string, h RouteHandler)
+	// SetNotFound sets the handler used when no route matches.
+	SetNotFound(h http.Handler)
+	// SetMethodNotAllowed sets the handler used when the path only matches other methods.
+	SetMethodNotAllowed(h http.Handler)
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// URL builds the URL path of the route, substituting params in order for its
+// ":param" and "*catchall" segments. Param values are formatted with fmt.Sprint
+// and path-escaped; a catch-all value keeps its slashes. Param constraints
+// such as ":id<int>" are not checked. Routes with a host pattern (see
+// TreeRouter) yield a scheme-relative URL, e.g. "//acme.example.com/dashboard".
+//
+// Example:
+//
+//	rt := a.GET("/users/:id/files/*path", ShowFile)
+//	u, _ := rt.URL(42, "docs/a b.pdf") // "/users/42/files/docs/a%20b.pdf"
+func (r *Route) URL(params ...any) (string, error) {
+	host, path := splitHostPattern(r.Path)
+	used := 0
+	next := func(name string) (string, error) {
+		if used == len(params) {
+			return "", fmt.Errorf("flash: route %q: missing value for param %q", r.Path, name)
+		}
+		used++
+		return fmt.Sprint(params[used-1]), nil
+	}
+
+	var b strings.Builder
+	if host != "" {
+		b.WriteString("//")
+		for i, label := range strings.Split(host, ".") {
+			if i > 0 {
+				b.WriteByte('.')
+			}
+			if strings.HasPrefix(label, ":") {
+				v, err := next(label[1:])
+				if err != nil {
+					return "", err
+				}
+				label = v
+			}
+			b.WriteString(label)
+		}
+	}
+	for i, seg := range strings.Split(path, "/") {
+		if i > 0 {
+			b.WriteByte('/')
+		}
+		switch {
+		case strings.HasPrefix(seg, ":"):
+			name, _, _ := strings.Cut(seg[1:], "<")
+			v, err := next(name)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(url.PathEscape(v))
+		case strings.HasPrefix(seg, "*"):
+			v, err := next(seg[1:])
+			if err != nil {
+				return "", err
+			}
+			parts := strings.Split(strings.TrimPrefix(v, "/"), "/")
+			for j, p := range parts {
+				parts[j] = url.PathEscape(p)
+			}
+			b.WriteString(strings.Join(parts, "/"))
+		default:
+			b.WriteString(seg)
+		}
+	}
+	if used != len(params) {
+		return "", fmt.Errorf("flash: route %q: %d param values given, %d used", r.Path, len(params), used)
+	}
+	return b.String(), nil
+}
+
+// URL builds the URL path of the route named name (see Route.Named and
+// Route.URL). Ctx.RedirectToRoute uses it to redirect to named routes.
+//
+// Example:
+//
+//	a.GET("/users/:id", ShowUser).Named("users.show")
+//	u, err := a.URL("users.show", 42) // "/users/42"
+func (a *DefaultApp) URL(name string, params ...any) (string, error) {
+	for _, rt := range a.routes {
+		if rt.Name == name {
+			return rt.URL(params...)
+		}
+	}
+	return "", fmt.Errorf("flash: no route named %q", name)
+}
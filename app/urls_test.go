@@ -0,0 +1,56 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+func TestRouteURL(t *testing.T) {
+	noop := func(c ctx.Ctx) error { return nil }
+	a := New()
+	cases := []struct {
+		rt     *Route
+		params []any
+		want   string
+	}{
+		{a.GET("/users", noop), nil, "/users"},
+		{a.GET("/users/:id", noop), []any{42}, "/users/42"},
+		{a.GET("/users/:id/posts/:slug", noop), []any{"7", "a b/c"}, "/users/7/posts/a%20b%2Fc"},
+		{a.GET("/files/*path", noop), []any{"/docs/a b.pdf"}, "/files/docs/a%20b.pdf"},
+		{&Route{Path: "/n/:id<int>"}, []any{5}, "/n/5"},
+		{&Route{Path: ":tenant.example.com/dashboard/:tab"}, []any{"acme", "home"}, "//acme.example.com/dashboard/home"},
+	}
+	for _, tc := range cases {
+		got, err := tc.rt.URL(tc.params...)
+		if err != nil || got != tc.want {
+			t.Fatalf("%s %v: got %q, %v; want %q", tc.rt.Path, tc.params, got, err, tc.want)
+		}
+	}
+	if _, err := cases[1].rt.URL(); err == nil {
+		t.Fatalf("expected error for missing param")
+	}
+	if _, err := cases[1].rt.URL(1, 2); err == nil {
+		t.Fatalf("expected error for extra params")
+	}
+}
+
+func TestAppURLAndRedirectToRoute(t *testing.T) {
+	a := New()
+	a.GET("/users/:id", func(c ctx.Ctx) error { return nil }).Named("users.show")
+	if u, err := a.URL("users.show", 3); err != nil || u != "/users/3" {
+		t.Fatalf("URL = %q, %v", u, err)
+	}
+	if _, err := a.URL("missing"); err == nil {
+		t.Fatalf("expected error for unknown name")
+	}
+
+	a.POST("/users", func(c ctx.Ctx) error { return c.RedirectToRoute("users.show", 9) })
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", nil))
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/users/9" {
+		t.Fatalf("got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
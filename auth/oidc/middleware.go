@@ -0,0 +1,68 @@
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/goflash/flash/v2"
+	"github.com/goflash/flash/v2/ctx"
+	"github.com/goflash/flash/v2/middleware"
+)
+
+type claimsContextKey struct{}
+
+// Middleware returns middleware that loads the session's login into the
+// request context for ClaimsFromCtx, refreshing an expired access token
+// first. Logins whose token can no longer be refreshed are removed from the
+// session, so the request continues anonymously.
+//
+// It must run after middleware.Sessions.
+func (p *Provider) Middleware() flash.Middleware {
+	return flash.Describe("oidc", p.cfg.redacted(), func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			s := middleware.SessionFromCtx(c)
+			if _, ok := s.Get(sessionClaims); !ok {
+				return next(c)
+			}
+			if _, err := p.Token(c); err != nil {
+				if err != ErrNotLoggedIn {
+					ctx.LoggerFromContext(c.Context()).Warn("oidc: token refresh failed", "error", err)
+				}
+				s.Delete(sessionClaims)
+				s.Delete(sessionToken)
+				return next(c)
+			}
+			// Token may have replaced the claims with refreshed ones.
+			v, _ := s.Get(sessionClaims)
+			if claims, ok := v.(map[string]any); ok {
+				r := c.Request()
+				c.SetRequest(r.WithContext(context.WithValue(r.Context(), claimsContextKey{}, Claims(claims))))
+			}
+			return next(c)
+		}
+	})
+}
+
+// RequireLogin returns middleware that rejects requests without a login.
+// GET and HEAD requests are redirected to Config.LoginURL with the current
+// path in "next"; other methods get 401. It must run after Middleware.
+func (p *Provider) RequireLogin() flash.Middleware {
+	return flash.Describe("oidc.require_login", nil, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			if _, ok := ClaimsFromCtx(c); ok {
+				return next(c)
+			}
+			if m := c.Method(); m == http.MethodGet || m == http.MethodHead {
+				return redirect(c, withQuery(p.cfg.LoginURL, url.Values{"next": {c.Request().URL.RequestURI()}}))
+			}
+			return c.String(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		}
+	})
+}
+
+// ClaimsFromCtx returns the ID token claims loaded by Middleware.
+func ClaimsFromCtx(c flash.Ctx) (Claims, bool) {
+	claims, ok := c.Context().Value(claimsContextKey{}).(Claims)
+	return claims, ok
+}
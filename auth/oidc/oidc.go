@@ -0,0 +1,535 @@
+// Package oidc implements OpenID Connect login (authorization code flow with
+// PKCE) on top of the session middleware.
+//
+// A Provider discovers the issuer's endpoints, sends users to the issuer from
+// its Login handler, verifies the returned ID token in Callback, and keeps the
+// verified claims and tokens in the session. Middleware makes the claims
+// available to handlers through ClaimsFromCtx and refreshes expired access
+// tokens. The session holds only maps and strings, so stores that serialize
+// their values work too.
+//
+// Example:
+//
+//	p := oidc.New(oidc.Config{
+//		Issuer:       "https://accounts.example.com",
+//		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+//		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+//		RedirectURL:  "https://app.example.com/auth/callback",
+//	})
+//
+//	app.Use(middleware.Sessions(sessCfg), p.Middleware())
+//	app.GET("/auth/login", p.Login)
+//	app.GET("/auth/callback", p.Callback)
+//	app.POST("/auth/logout", p.Logout)
+//
+//	app.GET("/me", func(c flash.Ctx) error {
+//		claims, _ := oidc.ClaimsFromCtx(c)
+//		return c.JSON(claims)
+//	}, p.RequireLogin())
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goflash/flash/v2"
+	"github.com/goflash/flash/v2/middleware"
+)
+
+// Session keys used by the Provider.
+const (
+	sessionState    = "oidc.state"
+	sessionNonce    = "oidc.nonce"
+	sessionVerifier = "oidc.verifier"
+	sessionNext     = "oidc.next"
+	sessionClaims   = "oidc.claims"
+	sessionToken    = "oidc.token"
+)
+
+// Errors returned by the Provider.
+var (
+	// ErrState means the callback's state does not match the login request,
+	// or no login is in progress.
+	ErrState = errors.New("oidc: invalid state")
+	// ErrNotLoggedIn means the session holds no OIDC login.
+	ErrNotLoggedIn = errors.New("oidc: not logged in")
+	// ErrInvalidToken means an ID token failed verification.
+	ErrInvalidToken = errors.New("oidc: invalid id token")
+)
+
+// Config configures a Provider.
+type Config struct {
+	// Issuer is the issuer URL; discovery reads
+	// Issuer + "/.well-known/openid-configuration". Required.
+	Issuer string
+	// ClientID and ClientSecret are the client credentials. ClientID is
+	// required; ClientSecret may be empty for public clients.
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the absolute URL of the Callback route. Required.
+	RedirectURL string
+
+	// Scopes requested at login. Defaults to openid, profile and email;
+	// "openid" is always included.
+	Scopes []string
+
+	// AfterLoginURL is where Callback redirects when the login carried no
+	// "next" parameter. Defaults to "/".
+	AfterLoginURL string
+	// PostLogoutRedirectURL, when set, is sent to the issuer's end-session
+	// endpoint, or redirected to directly if the issuer has none.
+	PostLogoutRedirectURL string
+	// LoginURL is where RequireLogin sends anonymous GET requests, with the
+	// original path in the "next" parameter. Defaults to "/auth/login".
+	LoginURL string
+
+	// HTTPClient is used for discovery, key and token requests. Defaults to
+	// a client with a 10 second timeout.
+	HTTPClient *http.Client
+
+	// ErrorHandler renders Callback failures. Defaults to a 401 response.
+	ErrorHandler func(c flash.Ctx, err error) error
+
+	// Leeway tolerates clock skew when checking token times. Defaults to
+	// one minute.
+	Leeway time.Duration
+}
+
+// redacted returns cfg without its client secret, for Middleware.Config.
+func (cfg Config) redacted() Config {
+	if cfg.ClientSecret != "" {
+		cfg.ClientSecret = "[REDACTED]"
+	}
+	return cfg
+}
+
+// Token holds the tokens of a login.
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	IDToken      string    `json:"id_token,omitempty"`
+	TokenType    string    `json:"token_type,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+}
+
+// Expired reports whether the access token expires within leeway of now.
+// Tokens without an expiry never expire.
+func (t Token) Expired(now time.Time, leeway time.Duration) bool {
+	return !t.Expiry.IsZero() && !now.Add(leeway).Before(t.Expiry)
+}
+
+// sessionValue returns t as a map of strings, which session stores that
+// serialize their values (JSON, gob, cookies) can persist and restore.
+func (t Token) sessionValue() map[string]any {
+	m := map[string]any{
+		"access_token":  t.AccessToken,
+		"refresh_token": t.RefreshToken,
+		"id_token":      t.IDToken,
+		"token_type":    t.TokenType,
+	}
+	if !t.Expiry.IsZero() {
+		m["expiry"] = t.Expiry.UTC().Format(time.RFC3339Nano)
+	}
+	return m
+}
+
+// tokenFromSession restores a Token stored with sessionValue.
+func tokenFromSession(v any) (Token, bool) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return Token{}, false
+	}
+	str := func(k string) string { s, _ := m[k].(string); return s }
+	t := Token{
+		AccessToken:  str("access_token"),
+		RefreshToken: str("refresh_token"),
+		IDToken:      str("id_token"),
+		TokenType:    str("token_type"),
+	}
+	if e := str("expiry"); e != "" {
+		exp, err := time.Parse(time.RFC3339Nano, e)
+		if err != nil {
+			return Token{}, false
+		}
+		t.Expiry = exp
+	}
+	return t, t.AccessToken != ""
+}
+
+// Claims are the verified claims of an ID token.
+type Claims map[string]any
+
+// String returns the string claim name, or "".
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Subject returns the "sub" claim.
+func (c Claims) Subject() string { return c.String("sub") }
+
+// Email returns the "email" claim.
+func (c Claims) Email() string { return c.String("email") }
+
+// Provider performs OIDC logins against one issuer.
+type Provider struct {
+	cfg Config
+	now func() time.Time
+
+	mu   sync.Mutex
+	meta *metadata
+	keys *keySet
+}
+
+// metadata is the subset of the discovery document the Provider uses.
+type metadata struct {
+	Issuer             string `json:"issuer"`
+	AuthorizationURL   string `json:"authorization_endpoint"`
+	TokenURL           string `json:"token_endpoint"`
+	JWKSURL            string `json:"jwks_uri"`
+	EndSessionEndpoint string `json:"end_session_endpoint"`
+}
+
+// New returns a Provider. Discovery happens on first use; call Discover at
+// start-up to fail fast. New panics if Issuer, ClientID or RedirectURL is
+// empty.
+func New(cfg Config) *Provider {
+	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		panic("oidc: Config.Issuer, ClientID and RedirectURL are required")
+	}
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email"}
+	} else if !contains(cfg.Scopes, "openid") {
+		cfg.Scopes = append([]string{"openid"}, cfg.Scopes...)
+	}
+	if cfg.AfterLoginURL == "" {
+		cfg.AfterLoginURL = "/"
+	}
+	if cfg.LoginURL == "" {
+		cfg.LoginURL = "/auth/login"
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.ErrorHandler == nil {
+		cfg.ErrorHandler = func(c flash.Ctx, err error) error {
+			return c.String(http.StatusUnauthorized, "authentication failed")
+		}
+	}
+	if cfg.Leeway <= 0 {
+		cfg.Leeway = time.Minute
+	}
+	return &Provider{cfg: cfg, now: time.Now}
+}
+
+// Discover fetches the issuer's discovery document. It is called lazily when
+// needed; a failed discovery is retried on the next use.
+func (p *Provider) Discover(ctx context.Context) error {
+	_, err := p.metadata(ctx)
+	return err
+}
+
+func (p *Provider) metadata(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+	var m metadata
+	if err := p.getJSON(ctx, p.cfg.Issuer+"/.well-known/openid-configuration", &m); err != nil {
+		return nil, fmt.Errorf("oidc: discovery: %w", err)
+	}
+	if strings.TrimSuffix(m.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("oidc: discovery: issuer %q does not match %q", m.Issuer, p.cfg.Issuer)
+	}
+	if m.AuthorizationURL == "" || m.TokenURL == "" || m.JWKSURL == "" {
+		return nil, errors.New("oidc: discovery: missing endpoints")
+	}
+	p.meta = &m
+	p.keys = &keySet{url: m.JWKSURL, fetch: p.getJSON}
+	return p.meta, nil
+}
+
+// Login starts a login: it records state, nonce and a PKCE verifier in the
+// session and redirects to the issuer. A local path in the "next" query
+// parameter is where Callback sends the user afterwards.
+func (p *Provider) Login(c flash.Ctx) error {
+	m, err := p.metadata(c.Context())
+	if err != nil {
+		return err
+	}
+	state, nonce, verifier := randomString(), randomString(), randomString()
+	s := middleware.SessionFromCtx(c)
+	s.Set(sessionState, state)
+	s.Set(sessionNonce, nonce)
+	s.Set(sessionVerifier, verifier)
+	if next := c.Query("next"); isLocalPath(next) {
+		s.Set(sessionNext, next)
+	} else {
+		s.Delete(sessionNext)
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	return redirect(c, withQuery(m.AuthorizationURL, q))
+}
+
+// Callback completes a login: it checks the state, exchanges the code,
+// verifies the ID token and stores claims and tokens in a regenerated
+// session before redirecting. Failures are rendered by Config.ErrorHandler.
+func (p *Provider) Callback(c flash.Ctx) error {
+	if err := p.callback(c); err != nil {
+		return p.cfg.ErrorHandler(c, err)
+	}
+	s := middleware.SessionFromCtx(c)
+	next := p.cfg.AfterLoginURL
+	if v, ok := s.Get(sessionNext); ok {
+		next, _ = v.(string)
+		s.Delete(sessionNext)
+	}
+	return redirect(c, next)
+}
+
+func (p *Provider) callback(c flash.Ctx) error {
+	s := middleware.SessionFromCtx(c)
+	state, _ := s.Get(sessionState)
+	nonce, _ := s.Get(sessionNonce)
+	verifier, _ := s.Get(sessionVerifier)
+	s.Delete(sessionState)
+	s.Delete(sessionNonce)
+	s.Delete(sessionVerifier)
+
+	if e := c.Query("error"); e != "" {
+		return fmt.Errorf("oidc: authorization failed: %s %s", e, c.Query("error_description"))
+	}
+	want, _ := state.(string)
+	got := c.Query("state")
+	if want == "" || subtle.ConstantTimeCompare([]byte(want), []byte(got)) != 1 {
+		return ErrState
+	}
+	code := c.Query("code")
+	if code == "" {
+		return errors.New("oidc: missing authorization code")
+	}
+	v, _ := verifier.(string)
+	tok, err := p.exchange(c.Context(), url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {v},
+	})
+	if err != nil {
+		return err
+	}
+	if tok.IDToken == "" {
+		return fmt.Errorf("%w: token response has no id_token", ErrInvalidToken)
+	}
+	n, _ := nonce.(string)
+	claims, err := p.Verify(c.Context(), tok.IDToken, n)
+	if err != nil {
+		return err
+	}
+	s.Regenerate()
+	s.Set(sessionClaims, map[string]any(claims))
+	s.Set(sessionToken, tok.sessionValue())
+	return nil
+}
+
+// Logout clears the session and, when the issuer supports it, redirects to
+// its end-session endpoint so the user is logged out there too.
+func (p *Provider) Logout(c flash.Ctx) error {
+	s := middleware.SessionFromCtx(c)
+	var idToken string
+	if v, ok := s.Get(sessionToken); ok {
+		if tok, ok := tokenFromSession(v); ok {
+			idToken = tok.IDToken
+		}
+	}
+	s.Clear()
+	s.Regenerate()
+
+	target := p.cfg.PostLogoutRedirectURL
+	if m, err := p.metadata(c.Context()); err == nil && m.EndSessionEndpoint != "" {
+		q := url.Values{"client_id": {p.cfg.ClientID}}
+		if idToken != "" {
+			q.Set("id_token_hint", idToken)
+		}
+		if target != "" {
+			q.Set("post_logout_redirect_uri", target)
+		}
+		target = withQuery(m.EndSessionEndpoint, q)
+	}
+	if target == "" {
+		target = "/"
+	}
+	return redirect(c, target)
+}
+
+// Token returns the session's tokens, refreshing the access token first if
+// it has expired and a refresh token is available.
+func (p *Provider) Token(c flash.Ctx) (Token, error) {
+	s := middleware.SessionFromCtx(c)
+	v, ok := s.Get(sessionToken)
+	if !ok {
+		return Token{}, ErrNotLoggedIn
+	}
+	tok, ok := tokenFromSession(v)
+	if !ok {
+		return Token{}, ErrNotLoggedIn
+	}
+	if !tok.Expired(p.now(), p.cfg.Leeway) {
+		return tok, nil
+	}
+	if tok.RefreshToken == "" {
+		return Token{}, ErrNotLoggedIn
+	}
+	next, err := p.exchange(c.Context(), url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {tok.RefreshToken},
+	})
+	if err != nil {
+		return Token{}, err
+	}
+	if next.RefreshToken == "" {
+		next.RefreshToken = tok.RefreshToken
+	}
+	if next.IDToken == "" {
+		next.IDToken = tok.IDToken
+	} else {
+		claims, err := p.Verify(c.Context(), next.IDToken, "")
+		if err != nil {
+			return Token{}, err
+		}
+		s.Set(sessionClaims, map[string]any(claims))
+	}
+	s.Set(sessionToken, next.sessionValue())
+	return next, nil
+}
+
+// tokenResponse is the token endpoint's JSON response.
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	IDToken          string `json:"id_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// exchange posts form to the token endpoint with client_secret_basic
+// authentication.
+func (p *Provider) exchange(ctx context.Context, form url.Values) (Token, error) {
+	m, err := p.metadata(ctx)
+	if err != nil {
+		return Token{}, err
+	}
+	if p.cfg.ClientSecret == "" {
+		form.Set("client_id", p.cfg.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Token{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+	resp, err := p.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return Token{}, fmt.Errorf("oidc: token request: %w", err)
+	}
+	defer resp.Body.Close()
+	var tr tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tr); err != nil {
+		return Token{}, fmt.Errorf("oidc: token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || tr.Error != "" {
+		return Token{}, fmt.Errorf("oidc: token request failed: %d %s %s", resp.StatusCode, tr.Error, tr.ErrorDescription)
+	}
+	if tr.AccessToken == "" {
+		return Token{}, errors.New("oidc: token response has no access_token")
+	}
+	tok := Token{AccessToken: tr.AccessToken, RefreshToken: tr.RefreshToken, IDToken: tr.IDToken, TokenType: tr.TokenType}
+	if tr.ExpiresIn > 0 {
+		tok.Expiry = p.now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	return tok, nil
+}
+
+// getJSON fetches url and decodes its JSON body into v.
+func (p *Provider) getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+func redirect(c flash.Ctx, target string) error {
+	http.Redirect(c.ResponseWriter(), c.Request(), target, http.StatusFound)
+	return nil
+}
+
+func withQuery(base string, q url.Values) string {
+	if strings.Contains(base, "?") {
+		return base + "&" + q.Encode()
+	}
+	return base + "?" + q.Encode()
+}
+
+// isLocalPath reports whether p is a same-origin path, rejecting the
+// protocol-relative forms browsers treat as other hosts.
+func isLocalPath(p string) bool {
+	return strings.HasPrefix(p, "/") && !strings.HasPrefix(p, "//") && !strings.HasPrefix(p, "/\\")
+}
+
+// randomString returns 32 random bytes, base64url encoded.
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic("oidc: failed to generate random bytes: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,506 @@
+package oidc
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2"
+	"github.com/goflash/flash/v2/middleware"
+)
+
+// fakeIssuer is a minimal OpenID provider.
+type fakeIssuer struct {
+	srv *httptest.Server
+	rsa *rsa.PrivateKey
+
+	mu        sync.Mutex
+	nonce     string
+	challenge string
+	expiresIn int
+	refreshes int
+	failToken bool
+	claims    map[string]any // extra or overriding ID token claims
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeIssuer{rsa: key, expiresIn: 3600}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 f.srv.URL,
+			"authorization_endpoint": f.srv.URL + "/authorize",
+			"token_endpoint":         f.srv.URL + "/token",
+			"jwks_uri":               f.srv.URL + "/jwks",
+			"end_session_endpoint":   f.srv.URL + "/logout",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1", "use": "sig",
+			"n": b64(key.N.Bytes()),
+			"e": b64(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if id, secret, _ := r.BasicAuth(); id != "client" || secret != "secret" || f.failToken {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		r.ParseForm()
+		resp := map[string]any{"access_token": "at", "token_type": "Bearer", "expires_in": f.expiresIn, "refresh_token": "rt"}
+		switch r.Form.Get("grant_type") {
+		case "authorization_code":
+			sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+			if r.Form.Get("code") != "code-1" || b64(sum[:]) != f.challenge {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+			resp["id_token"] = f.sign(map[string]any{"nonce": f.nonce})
+		case "refresh_token":
+			f.refreshes++
+			resp["access_token"] = "at-refreshed"
+			resp["id_token"] = f.sign(map[string]any{"name": "Alice Refreshed"})
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+	f.srv = httptest.NewServer(mux)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+// sign returns an RS256 ID token. f.mu must be held.
+func (f *fakeIssuer) sign(extra map[string]any) string {
+	now := time.Now()
+	claims := map[string]any{
+		"iss": f.srv.URL, "aud": "client", "sub": "user-1", "email": "alice@example.com",
+		"iat": now.Unix(), "exp": now.Add(time.Hour).Unix(),
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	for k, v := range f.claims {
+		claims[k] = v
+	}
+	return signJWT(map[string]any{"alg": "RS256", "kid": "k1"}, claims, func(digest []byte) []byte {
+		sig, _ := rsa.SignPKCS1v15(rand.Reader, f.rsa, crypto.SHA256, digest)
+		return sig
+	})
+}
+
+func signJWT(header, claims map[string]any, sign func(digest []byte) []byte) string {
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	input := b64(h) + "." + b64(c)
+	sum := sha256.Sum256([]byte(input))
+	return input + "." + b64(sign(sum[:]))
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+// testApp wires a Provider into an app served over HTTP with a cookie jar.
+type testApp struct {
+	p      *Provider
+	srv    *httptest.Server
+	client *http.Client
+	logs   *syncBuffer
+}
+
+// syncBuffer collects log output written by the test server's goroutines.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+func newTestApp(t *testing.T, f *fakeIssuer) *testApp {
+	t.Helper()
+	return newTestAppWithStore(t, f, middleware.NewMemoryStore())
+}
+
+func newTestAppWithStore(t *testing.T, f *fakeIssuer, store middleware.Store) *testApp {
+	t.Helper()
+	ta := &testApp{logs: &syncBuffer{}}
+	app := flash.New()
+	app.SetLogger(slog.New(slog.NewTextHandler(ta.logs, nil)))
+	ta.srv = httptest.NewServer(app)
+	t.Cleanup(ta.srv.Close)
+	ta.p = New(Config{
+		Issuer:                f.srv.URL,
+		ClientID:              "client",
+		ClientSecret:          "secret",
+		RedirectURL:           ta.srv.URL + "/auth/callback",
+		PostLogoutRedirectURL: ta.srv.URL + "/bye",
+	})
+	app.Use(middleware.Sessions(middleware.SessionConfig{Store: store}), ta.p.Middleware())
+	app.GET("/auth/login", ta.p.Login)
+	app.GET("/auth/callback", ta.p.Callback)
+	app.POST("/auth/logout", ta.p.Logout)
+	app.GET("/me", func(c flash.Ctx) error {
+		claims, _ := ClaimsFromCtx(c)
+		tok, _ := ta.p.Token(c)
+		return c.String(http.StatusOK, claims.Subject()+" "+claims.String("name")+" "+tok.AccessToken)
+	}, ta.p.RequireLogin())
+	app.POST("/me", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") }, ta.p.RequireLogin())
+
+	jar, _ := cookiejar.New(nil)
+	ta.client = &http.Client{Jar: jar, CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	return ta
+}
+
+func (ta *testApp) do(t *testing.T, method, path string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(method, ta.srv.URL+path, nil)
+	resp, err := ta.client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func body(resp *http.Response) string {
+	b, _ := io.ReadAll(resp.Body)
+	return string(b)
+}
+
+// login runs Login, records the nonce and challenge at the fake issuer and
+// returns the state sent to the issuer.
+func (ta *testApp) login(t *testing.T, f *fakeIssuer, next string) string {
+	t.Helper()
+	resp := ta.do(t, http.MethodGet, "/auth/login?next="+url.QueryEscape(next))
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected redirect to issuer, got %d", resp.StatusCode)
+	}
+	loc, _ := url.Parse(resp.Header.Get("Location"))
+	q := loc.Query()
+	if !strings.HasPrefix(loc.String(), f.srv.URL+"/authorize?") || q.Get("client_id") != "client" ||
+		q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" ||
+		q.Get("scope") != "openid profile email" || q.Get("redirect_uri") != ta.srv.URL+"/auth/callback" {
+		t.Fatalf("unexpected authorization URL %s", loc)
+	}
+	f.mu.Lock()
+	f.nonce, f.challenge = q.Get("nonce"), q.Get("code_challenge")
+	f.mu.Unlock()
+	return q.Get("state")
+}
+
+func TestLoginFlow(t *testing.T) {
+	f := newFakeIssuer(t)
+	ta := newTestApp(t, f)
+
+	resp := ta.do(t, http.MethodGet, "/me?x=1")
+	if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "/auth/login?next=%2Fme%3Fx%3D1" {
+		t.Fatalf("expected redirect to login, got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if resp := ta.do(t, http.MethodPost, "/me"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for POST, got %d", resp.StatusCode)
+	}
+
+	state := ta.login(t, f, "/me")
+	resp = ta.do(t, http.MethodGet, "/auth/callback?code=code-1&state="+state)
+	if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "/me" {
+		t.Fatalf("expected redirect to next, got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if got := body(ta.do(t, http.MethodGet, "/me")); got != "user-1  at" {
+		t.Fatalf("unexpected /me %q", got)
+	}
+
+	// The state is single-use.
+	resp = ta.do(t, http.MethodGet, "/auth/callback?code=code-1&state="+state)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("replayed callback should fail, got %d", resp.StatusCode)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	f := newFakeIssuer(t)
+	ta := newTestApp(t, f)
+	state := ta.login(t, f, "")
+	resp := ta.do(t, http.MethodGet, "/auth/callback?code=code-1&state="+state)
+	if resp.Header.Get("Location") != "/" {
+		t.Fatalf("expected default redirect, got %q", resp.Header.Get("Location"))
+	}
+
+	resp = ta.do(t, http.MethodPost, "/auth/logout")
+	loc, _ := url.Parse(resp.Header.Get("Location"))
+	q := loc.Query()
+	if !strings.HasPrefix(loc.String(), f.srv.URL+"/logout?") || q.Get("id_token_hint") == "" ||
+		q.Get("post_logout_redirect_uri") != ta.srv.URL+"/bye" {
+		t.Fatalf("unexpected end-session redirect %s", loc)
+	}
+	if resp := ta.do(t, http.MethodGet, "/me"); resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected logged out, got %d", resp.StatusCode)
+	}
+}
+
+func TestCallbackFailures(t *testing.T) {
+	f := newFakeIssuer(t)
+	ta := newTestApp(t, f)
+
+	if resp := ta.do(t, http.MethodGet, "/auth/callback?code=code-1&state=x"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("callback without login should fail, got %d", resp.StatusCode)
+	}
+	state := ta.login(t, f, "")
+	if resp := ta.do(t, http.MethodGet, "/auth/callback?code=code-1&state=wrong"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("wrong state should fail, got %d", resp.StatusCode)
+	}
+
+	state = ta.login(t, f, "")
+	if resp := ta.do(t, http.MethodGet, "/auth/callback?code=other&state="+state); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("rejected code should fail, got %d", resp.StatusCode)
+	}
+
+	state = ta.login(t, f, "")
+	f.mu.Lock()
+	f.nonce = "forged"
+	f.mu.Unlock()
+	if resp := ta.do(t, http.MethodGet, "/auth/callback?code=code-1&state="+state); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("nonce mismatch should fail, got %d", resp.StatusCode)
+	}
+
+	state = ta.login(t, f, "")
+	if resp := ta.do(t, http.MethodGet, "/auth/callback?error=access_denied&state="+state); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("issuer error should fail, got %d", resp.StatusCode)
+	}
+	if resp := ta.do(t, http.MethodGet, "/me"); resp.StatusCode != http.StatusFound {
+		t.Fatalf("failed callbacks must not log in")
+	}
+}
+
+func TestLoginIgnoresExternalNext(t *testing.T) {
+	f := newFakeIssuer(t)
+	ta := newTestApp(t, f)
+	for _, next := range []string{"https://evil.example", "//evil.example", "/\\evil.example"} {
+		state := ta.login(t, f, next)
+		resp := ta.do(t, http.MethodGet, "/auth/callback?code=code-1&state="+state)
+		if resp.Header.Get("Location") != "/" {
+			t.Fatalf("%q: expected default redirect, got %q", next, resp.Header.Get("Location"))
+		}
+	}
+}
+
+func TestMiddlewareRefreshesToken(t *testing.T) {
+	f := newFakeIssuer(t)
+	f.expiresIn = 30 // inside the one minute leeway, so already expired
+	ta := newTestApp(t, f)
+	state := ta.login(t, f, "")
+	ta.do(t, http.MethodGet, "/auth/callback?code=code-1&state="+state)
+
+	if got := body(ta.do(t, http.MethodGet, "/me")); got != "user-1 Alice Refreshed at-refreshed" {
+		t.Fatalf("expected refreshed token and claims, got %q", got)
+	}
+	if f.refreshes == 0 {
+		t.Fatalf("expected a refresh request")
+	}
+
+	f.mu.Lock()
+	f.failToken = true
+	f.mu.Unlock()
+	if resp := ta.do(t, http.MethodGet, "/me"); resp.StatusCode != http.StatusFound {
+		t.Fatalf("failed refresh should log out, got %d", resp.StatusCode)
+	}
+	if !strings.Contains(ta.logs.String(), "oidc: token refresh failed") {
+		t.Fatalf("refresh failure not logged to the request logger: %q", ta.logs.String())
+	}
+}
+
+// jsonStore is a session store that persists values as JSON, like stores
+// backed by Redis or a database do.
+type jsonStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (s *jsonStore) Get(id string) (map[string]any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.data[id]
+	if !ok {
+		return nil, false
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, false
+	}
+	return m, true
+}
+
+func (s *jsonStore) Save(id string, data map[string]any, _ time.Duration) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[id] = b
+	return nil
+}
+
+func (s *jsonStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, id)
+	return nil
+}
+
+func TestSerializingSessionStore(t *testing.T) {
+	f := newFakeIssuer(t)
+	f.expiresIn = 30 // already expired, so the stored expiry must round-trip
+	ta := newTestAppWithStore(t, f, &jsonStore{data: map[string][]byte{}})
+	state := ta.login(t, f, "")
+	if resp := ta.do(t, http.MethodGet, "/auth/callback?code=code-1&state="+state); resp.StatusCode != http.StatusFound {
+		t.Fatalf("callback failed with %d", resp.StatusCode)
+	}
+
+	if got := body(ta.do(t, http.MethodGet, "/me")); got != "user-1 Alice Refreshed at-refreshed" {
+		t.Fatalf("expected refreshed login from the stored token, got %q", got)
+	}
+	if f.refreshes == 0 {
+		t.Fatalf("expected a refresh request")
+	}
+
+	resp := ta.do(t, http.MethodPost, "/auth/logout")
+	loc, _ := url.Parse(resp.Header.Get("Location"))
+	if loc.Query().Get("id_token_hint") == "" {
+		t.Fatalf("expected id_token_hint from the stored token, got %s", loc)
+	}
+	if resp := ta.do(t, http.MethodGet, "/me"); resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected logged out, got %d", resp.StatusCode)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	f := newFakeIssuer(t)
+	p := New(Config{Issuer: f.srv.URL, ClientID: "client", RedirectURL: "http://app/cb"})
+	ctx := context.Background()
+
+	f.mu.Lock()
+	good := f.sign(map[string]any{"nonce": "n", "aud": []any{"other", "client"}})
+	f.claims = map[string]any{"aud": "other"}
+	wrongAud := f.sign(nil)
+	f.claims = map[string]any{"iss": "https://evil.example"}
+	wrongIss := f.sign(nil)
+	f.claims = map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}
+	expired := f.sign(nil)
+	f.claims = nil
+	f.mu.Unlock()
+
+	claims, err := p.Verify(ctx, good, "n")
+	if err != nil || claims.Email() != "alice@example.com" {
+		t.Fatalf("expected valid token, got %v %v", claims, err)
+	}
+	parts := strings.Split(good, ".")
+	tampered := parts[0] + "." + b64([]byte(`{"sub":"admin"}`)) + "." + parts[2]
+	none := b64([]byte(`{"alg":"none"}`)) + "." + parts[1] + "."
+	for name, tok := range map[string]string{
+		"nonce": good, "aud": wrongAud, "iss": wrongIss, "exp": expired,
+		"tampered": tampered, "none": none, "malformed": "a.b",
+	} {
+		nonce := ""
+		if name == "nonce" {
+			nonce = "other"
+		}
+		if _, err := p.Verify(ctx, tok, nonce); !errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("%s: expected ErrInvalidToken, got %v", name, err)
+		}
+	}
+}
+
+func TestVerifyES256(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{
+				"issuer": srv.URL, "authorization_endpoint": srv.URL + "/a",
+				"token_endpoint": srv.URL + "/t", "jwks_uri": srv.URL + "/jwks",
+			})
+		case "/jwks":
+			json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+				"kty": "EC", "crv": "P-256", "x": b64(key.X.FillBytes(make([]byte, 32))), "y": b64(key.Y.FillBytes(make([]byte, 32))),
+			}}})
+		}
+	}))
+	defer srv.Close()
+
+	tok := signJWT(map[string]any{"alg": "ES256"}, map[string]any{
+		"iss": srv.URL, "aud": "client", "sub": "u", "exp": time.Now().Add(time.Hour).Unix(),
+	}, func(digest []byte) []byte {
+		r, s, _ := ecdsa.Sign(rand.Reader, key, digest)
+		return append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	})
+	p := New(Config{Issuer: srv.URL, ClientID: "client", RedirectURL: "http://app/cb"})
+	if claims, err := p.Verify(context.Background(), tok, ""); err != nil || claims.Subject() != "u" {
+		t.Fatalf("expected valid ES256 token, got %v %v", claims, err)
+	}
+}
+
+func TestDiscoverIssuerMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": "https://other.example"})
+	}))
+	defer srv.Close()
+	p := New(Config{Issuer: srv.URL, ClientID: "client", RedirectURL: "http://app/cb"})
+	if err := p.Discover(context.Background()); err == nil {
+		t.Fatalf("expected issuer mismatch error")
+	}
+}
+
+func TestMiddlewareConfigRedactsSecret(t *testing.T) {
+	f := newFakeIssuer(t)
+	p := New(Config{Issuer: f.srv.URL, ClientID: "client", ClientSecret: "secret", RedirectURL: "http://app/cb"})
+	cfg, ok := p.Middleware().Config().(Config)
+	if !ok || cfg.ClientID != "client" || cfg.ClientSecret != "[REDACTED]" || p.cfg.ClientSecret != "secret" {
+		t.Fatalf("config=%#v", cfg)
+	}
+}
+
+func TestNewRequiresConfig(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic")
+		}
+	}()
+	New(Config{Issuer: "https://issuer.example"})
+}
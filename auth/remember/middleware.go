@@ -0,0 +1,66 @@
+package remember
+
+import (
+	"github.com/goflash/flash/v2"
+	"github.com/goflash/flash/v2/ctx"
+	"github.com/goflash/flash/v2/middleware"
+)
+
+// MiddlewareConfig configures Manager.Middleware.
+type MiddlewareConfig struct {
+	// IsAuthenticated reports whether the request already has a logged-in
+	// user. If nil, the session (see middleware.Sessions) is checked for
+	// SessionKey.
+	IsAuthenticated func(c flash.Ctx) bool
+
+	// Login establishes a session for userID. If nil, the session ID is
+	// regenerated and userID stored under SessionKey.
+	Login func(c flash.Ctx, userID string) error
+
+	// SessionKey is the session key holding the user ID. Defaults to "user_id".
+	SessionKey string
+}
+
+// Middleware returns middleware that logs users in from their remember-me
+// cookie when they have no session. Requests without a valid cookie continue
+// unauthenticated; store errors are logged and the request continues.
+//
+// With the defaults it must run after middleware.Sessions.
+func (m *Manager) Middleware(cfg MiddlewareConfig) flash.Middleware {
+	if cfg.SessionKey == "" {
+		cfg.SessionKey = "user_id"
+	}
+	if cfg.IsAuthenticated == nil {
+		cfg.IsAuthenticated = func(c flash.Ctx) bool {
+			_, ok := middleware.SessionFromCtx(c).Get(cfg.SessionKey)
+			return ok
+		}
+	}
+	if cfg.Login == nil {
+		cfg.Login = func(c flash.Ctx, userID string) error {
+			s := middleware.SessionFromCtx(c)
+			s.Regenerate()
+			s.Set(cfg.SessionKey, userID)
+			return nil
+		}
+	}
+
+	return flash.Describe("remember", cfg, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			if cfg.IsAuthenticated(c) {
+				return next(c)
+			}
+			userID, err := m.Authenticate(c)
+			switch err {
+			case nil:
+				if err := cfg.Login(c, userID); err != nil {
+					return err
+				}
+			case ErrNoCookie, ErrInvalid, ErrTheft:
+			default:
+				ctx.LoggerFromContext(c.Context()).Warn("remember: authenticate failed", "error", err)
+			}
+			return next(c)
+		}
+	})
+}
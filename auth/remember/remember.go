@@ -0,0 +1,302 @@
+// Package remember implements persistent ("remember me") logins with
+// rotating series/token cookies.
+//
+// On login, Issue stores a new series for the user and sets a cookie holding
+// the series identifier and a random token; only a hash of the token is
+// stored. When a request arrives without a session, Authenticate looks the
+// series up, checks the token and rotates it, so every cookie value is used
+// once. If a stale token is presented for a live series, the cookie has been
+// copied: every series of the user is revoked and ErrTheft is returned.
+//
+// Example:
+//
+//	rm := remember.New(remember.Config{Store: remember.NewMemoryStore(), Secure: true})
+//	app.Use(middleware.Sessions(sessCfg), rm.Middleware(remember.MiddlewareConfig{}))
+//
+//	app.POST("/login", func(c flash.Ctx) error {
+//		user := authenticate(c)
+//		middleware.SessionFromCtx(c).Set("user_id", user.ID)
+//		if c.FormValue("remember") == "on" {
+//			if err := rm.Issue(c, user.ID); err != nil {
+//				return err
+//			}
+//		}
+//		return c.String(http.StatusOK, "welcome")
+//	})
+//
+//	app.POST("/logout", func(c flash.Ctx) error {
+//		middleware.SessionFromCtx(c).Clear()
+//		return rm.Forget(c)
+//	})
+package remember
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+// Errors returned by Authenticate.
+var (
+	// ErrNoCookie means the request carries no remember-me cookie.
+	ErrNoCookie = errors.New("remember: no cookie")
+	// ErrInvalid means the cookie is malformed, unknown or expired.
+	ErrInvalid = errors.New("remember: invalid or expired token")
+	// ErrTheft means a previously used token was presented; all of the user's
+	// series have been revoked.
+	ErrTheft = errors.New("remember: token reuse detected")
+	// ErrNotFound is returned by stores for unknown series.
+	ErrNotFound = errors.New("remember: series not found")
+	// ErrConflict is returned by Store.Rotate when the series was rotated
+	// since it was read.
+	ErrConflict = errors.New("remember: series rotated concurrently")
+)
+
+// Token is a stored remember-me series.
+type Token struct {
+	Series    string    // public series identifier (cookie part one)
+	UserID    string    // user the series logs in
+	Hash      string    // hex SHA-256 of the current token
+	PrevHash  string    // hash of the token replaced at RotatedAt, accepted during Config.Grace
+	RotatedAt time.Time // last rotation
+	Expires   time.Time // absolute expiry, set at Issue
+}
+
+// Store persists series. Implementations must be safe for concurrent use.
+type Store interface {
+	// Save creates or replaces the token with t.Series.
+	Save(ctx context.Context, t Token) error
+	// Rotate replaces the token with t.Series only if its stored Hash is
+	// still oldHash, and returns ErrConflict otherwise, e.g. with
+	// "UPDATE ... WHERE series = ? AND hash = ?". Authenticate relies on it
+	// so concurrent requests with the same cookie rotate the token once.
+	Rotate(ctx context.Context, t Token, oldHash string) error
+	// Get returns the token for series, or ErrNotFound.
+	Get(ctx context.Context, series string) (Token, error)
+	// Delete removes a series. Missing series are not an error.
+	Delete(ctx context.Context, series string) error
+	// DeleteUser removes every series of userID.
+	DeleteUser(ctx context.Context, userID string) error
+}
+
+// Config configures a Manager.
+type Config struct {
+	// Store persists series. Required.
+	Store Store
+
+	// CookieName defaults to "remember".
+	CookieName string
+	// TTL is how long a series stays valid after Issue. Defaults to 30 days.
+	TTL time.Duration
+	// Path defaults to "/".
+	Path string
+	// Domain, Secure and SameSite set the cookie attributes. SameSite
+	// defaults to Lax. The cookie is always HttpOnly.
+	Domain   string
+	Secure   bool
+	SameSite http.SameSite
+
+	// Grace is how long the token replaced by a rotation is still accepted,
+	// so concurrent requests sent with the same cookie are not mistaken for
+	// theft. Defaults to 30 seconds; negative disables it.
+	Grace time.Duration
+
+	// OnTheft, when set, is called after a token reuse revoked userID's series,
+	// e.g. to alert the user.
+	OnTheft func(c flash.Ctx, userID string)
+}
+
+// Manager issues, validates and revokes remember-me cookies.
+type Manager struct {
+	cfg Config
+	now func() time.Time
+}
+
+// New returns a Manager. It panics if cfg.Store is nil.
+func New(cfg Config) *Manager {
+	if cfg.Store == nil {
+		panic("remember: Config.Store is required")
+	}
+	if cfg.CookieName == "" {
+		cfg.CookieName = "remember"
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 30 * 24 * time.Hour
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/"
+	}
+	if cfg.SameSite == 0 {
+		cfg.SameSite = http.SameSiteLaxMode
+	}
+	if cfg.Grace == 0 {
+		cfg.Grace = 30 * time.Second
+	}
+	return &Manager{cfg: cfg, now: time.Now}
+}
+
+// Issue starts a new series for userID and sets the cookie.
+func (m *Manager) Issue(c flash.Ctx, userID string) error {
+	now := m.now()
+	series, token := randomString(), randomString()
+	t := Token{Series: series, UserID: userID, Hash: hashToken(token), RotatedAt: now, Expires: now.Add(m.cfg.TTL)}
+	if err := m.cfg.Store.Save(c.Context(), t); err != nil {
+		return err
+	}
+	m.setCookie(c, series+":"+token, t.Expires)
+	return nil
+}
+
+// Authenticate validates the request's cookie and returns the user it logs
+// in. On success the token is rotated and a new cookie is set. Invalid
+// cookies are cleared. Store failures are returned as is.
+func (m *Manager) Authenticate(c flash.Ctx) (string, error) {
+	ck, err := c.Request().Cookie(m.cfg.CookieName)
+	if err != nil || ck.Value == "" {
+		return "", ErrNoCookie
+	}
+	series, token, ok := strings.Cut(ck.Value, ":")
+	if !ok || series == "" || token == "" {
+		m.clearCookie(c)
+		return "", ErrInvalid
+	}
+
+	ctx := c.Context()
+	t, err := m.cfg.Store.Get(ctx, series)
+	if errors.Is(err, ErrNotFound) {
+		m.clearCookie(c)
+		return "", ErrInvalid
+	}
+	if err != nil {
+		return "", err
+	}
+	now := m.now()
+	if !now.Before(t.Expires) {
+		_ = m.cfg.Store.Delete(ctx, series)
+		m.clearCookie(c)
+		return "", ErrInvalid
+	}
+
+	h := hashToken(token)
+	switch {
+	case equal(h, t.Hash):
+		next := randomString()
+		rotated := t
+		rotated.PrevHash, rotated.Hash, rotated.RotatedAt = t.Hash, hashToken(next), now
+		err := m.cfg.Store.Rotate(ctx, rotated, t.Hash)
+		if errors.Is(err, ErrConflict) {
+			return m.lostRotation(c, series, h)
+		}
+		if errors.Is(err, ErrNotFound) {
+			m.clearCookie(c)
+			return "", ErrInvalid
+		}
+		if err != nil {
+			return "", err
+		}
+		m.setCookie(c, series+":"+next, t.Expires)
+		return t.UserID, nil
+	case m.cfg.Grace > 0 && t.PrevHash != "" && equal(h, t.PrevHash) && now.Sub(t.RotatedAt) <= m.cfg.Grace:
+		// A concurrent request rotated the token a moment ago; its response
+		// carries the new cookie.
+		return t.UserID, nil
+	default:
+		if err := m.cfg.Store.DeleteUser(ctx, t.UserID); err != nil {
+			return "", err
+		}
+		m.clearCookie(c)
+		if m.cfg.OnTheft != nil {
+			m.cfg.OnTheft(c, t.UserID)
+		}
+		return "", ErrTheft
+	}
+}
+
+// lostRotation handles a request whose rotation lost against a concurrent
+// request with the same cookie. The series now holds the winner's token, and
+// the winner's response carries its cookie, so the request is authenticated
+// without setting one. Anything else means the series changed otherwise and
+// the cookie is rejected.
+func (m *Manager) lostRotation(c flash.Ctx, series, hash string) (string, error) {
+	t, err := m.cfg.Store.Get(c.Context(), series)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return "", err
+	}
+	if err != nil || !equal(hash, t.PrevHash) {
+		m.clearCookie(c)
+		return "", ErrInvalid
+	}
+	return t.UserID, nil
+}
+
+// Forget revokes the request's series (if any) and clears the cookie. Call
+// it on logout.
+func (m *Manager) Forget(c flash.Ctx) error {
+	m.clearCookie(c)
+	ck, err := c.Request().Cookie(m.cfg.CookieName)
+	if err != nil {
+		return nil
+	}
+	if series, _, ok := strings.Cut(ck.Value, ":"); ok && series != "" {
+		return m.cfg.Store.Delete(c.Context(), series)
+	}
+	return nil
+}
+
+// ForgetUser revokes every series of userID, e.g. after a password change.
+func (m *Manager) ForgetUser(ctx context.Context, userID string) error {
+	return m.cfg.Store.DeleteUser(ctx, userID)
+}
+
+func (m *Manager) setCookie(c flash.Ctx, value string, expires time.Time) {
+	http.SetCookie(c.ResponseWriter(), &http.Cookie{
+		Name:     m.cfg.CookieName,
+		Value:    value,
+		Path:     m.cfg.Path,
+		Domain:   m.cfg.Domain,
+		Expires:  expires,
+		Secure:   m.cfg.Secure,
+		HttpOnly: true,
+		SameSite: m.cfg.SameSite,
+	})
+}
+
+func (m *Manager) clearCookie(c flash.Ctx) {
+	http.SetCookie(c.ResponseWriter(), &http.Cookie{
+		Name:     m.cfg.CookieName,
+		Value:    "",
+		Path:     m.cfg.Path,
+		Domain:   m.cfg.Domain,
+		MaxAge:   -1,
+		Secure:   m.cfg.Secure,
+		HttpOnly: true,
+		SameSite: m.cfg.SameSite,
+	})
+}
+
+// randomString returns 32 random bytes, base64url encoded.
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic("remember: failed to generate random bytes: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
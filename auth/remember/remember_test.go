@@ -0,0 +1,353 @@
+package remember
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2"
+	"github.com/goflash/flash/v2/middleware"
+)
+
+// harness exposes Issue, Authenticate and Forget as routes.
+type harness struct {
+	app   flash.App
+	m     *Manager
+	store *MemoryStore
+}
+
+func newHarness(t *testing.T, cfg Config) *harness {
+	t.Helper()
+	h := &harness{store: NewMemoryStore()}
+	if cfg.Store == nil {
+		cfg.Store = h.store
+	}
+	h.m = New(cfg)
+	h.app = flash.New()
+	h.app.GET("/issue", func(c flash.Ctx) error {
+		if err := h.m.Issue(c, "alice"); err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, "ok")
+	})
+	h.app.GET("/auth", func(c flash.Ctx) error {
+		id, err := h.m.Authenticate(c)
+		if err != nil {
+			return c.String(http.StatusUnauthorized, err.Error())
+		}
+		return c.String(http.StatusOK, id)
+	})
+	h.app.GET("/forget", func(c flash.Ctx) error {
+		if err := h.m.Forget(c); err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, "ok")
+	})
+	return h
+}
+
+func (h *harness) do(path string, ck *http.Cookie) (*httptest.ResponseRecorder, *http.Cookie) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if ck != nil {
+		req.AddCookie(ck)
+	}
+	rec := httptest.NewRecorder()
+	h.app.ServeHTTP(rec, req)
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == h.m.cfg.CookieName {
+			return rec, c
+		}
+	}
+	return rec, nil
+}
+
+func TestIssueAndRotate(t *testing.T) {
+	h := newHarness(t, Config{Secure: true})
+	_, ck := h.do("/issue", nil)
+	if ck == nil || !ck.HttpOnly || !ck.Secure || ck.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("unexpected cookie %+v", ck)
+	}
+	series, token, _ := strings.Cut(ck.Value, ":")
+	stored, err := h.store.Get(context.Background(), series)
+	if err != nil || stored.UserID != "alice" || stored.Hash == token {
+		t.Fatalf("unexpected stored token %+v %v", stored, err)
+	}
+
+	rec, next := h.do("/auth", ck)
+	if rec.Code != http.StatusOK || rec.Body.String() != "alice" {
+		t.Fatalf("expected alice, got %d %q", rec.Code, rec.Body.String())
+	}
+	if next == nil || next.Value == ck.Value || !strings.HasPrefix(next.Value, series+":") {
+		t.Fatalf("expected rotated token in the same series, got %+v", next)
+	}
+	if rec, _ := h.do("/auth", next); rec.Code != http.StatusOK {
+		t.Fatalf("rotated token should be accepted, got %d", rec.Code)
+	}
+}
+
+func TestTheftRevokesUser(t *testing.T) {
+	var victim string
+	h := newHarness(t, Config{Grace: -1, OnTheft: func(_ flash.Ctx, id string) { victim = id }})
+	_, a := h.do("/issue", nil)
+	_, other := h.do("/issue", nil) // a second device
+	_, rotated := h.do("/auth", a)
+
+	rec, cleared := h.do("/auth", a) // the old value is replayed
+	if rec.Code != http.StatusUnauthorized || rec.Body.String() != ErrTheft.Error() {
+		t.Fatalf("expected theft, got %d %q", rec.Code, rec.Body.String())
+	}
+	if victim != "alice" || cleared == nil || cleared.MaxAge >= 0 {
+		t.Fatalf("expected OnTheft and cleared cookie, got %q %+v", victim, cleared)
+	}
+	if h.store.Len() != 0 {
+		t.Fatalf("expected every series revoked")
+	}
+	for _, ck := range []*http.Cookie{rotated, other} {
+		if rec, _ := h.do("/auth", ck); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("revoked series must not authenticate")
+		}
+	}
+}
+
+func TestGraceAcceptsPreviousToken(t *testing.T) {
+	h := newHarness(t, Config{Grace: time.Minute})
+	now := time.Now()
+	h.m.now = func() time.Time { return now }
+	_, ck := h.do("/issue", nil)
+	h.do("/auth", ck)
+
+	rec, _ := h.do("/auth", ck)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("previous token should be accepted within grace, got %d", rec.Code)
+	}
+	now = now.Add(2 * time.Minute)
+	if rec, _ := h.do("/auth", ck); rec.Body.String() != ErrTheft.Error() {
+		t.Fatalf("expected theft after grace, got %q", rec.Body.String())
+	}
+}
+
+func TestAuthenticateInvalid(t *testing.T) {
+	h := newHarness(t, Config{TTL: time.Hour})
+	now := time.Now()
+	h.m.now = func() time.Time { return now }
+
+	if rec, _ := h.do("/auth", nil); rec.Body.String() != ErrNoCookie.Error() {
+		t.Fatalf("expected ErrNoCookie, got %q", rec.Body.String())
+	}
+	for _, v := range []string{"garbage", ":x", "unknown:token"} {
+		rec, cleared := h.do("/auth", &http.Cookie{Name: "remember", Value: v})
+		if rec.Body.String() != ErrInvalid.Error() || cleared == nil || cleared.MaxAge >= 0 {
+			t.Fatalf("%q: expected ErrInvalid and cleared cookie, got %q", v, rec.Body.String())
+		}
+	}
+
+	_, ck := h.do("/issue", nil)
+	now = now.Add(time.Hour)
+	if rec, _ := h.do("/auth", ck); rec.Body.String() != ErrInvalid.Error() {
+		t.Fatalf("expected expired series to be invalid, got %q", rec.Body.String())
+	}
+	if h.store.Len() != 0 {
+		t.Fatalf("expired series should be deleted")
+	}
+}
+
+type failingStore struct{ *MemoryStore }
+
+func (failingStore) Get(context.Context, string) (Token, error) {
+	return Token{}, errors.New("db down")
+}
+
+func TestAuthenticateStoreError(t *testing.T) {
+	store := failingStore{NewMemoryStore()}
+	h := newHarness(t, Config{Store: store})
+	_, ck := h.do("/issue", nil)
+	rec, cleared := h.do("/auth", ck)
+	if rec.Body.String() != "db down" || cleared != nil {
+		t.Fatalf("store errors must be returned and keep the cookie, got %q %+v", rec.Body.String(), cleared)
+	}
+}
+
+func TestForget(t *testing.T) {
+	h := newHarness(t, Config{})
+	_, ck := h.do("/issue", nil)
+	_, cleared := h.do("/forget", ck)
+	if cleared == nil || cleared.MaxAge >= 0 || h.store.Len() != 0 {
+		t.Fatalf("expected series deleted and cookie cleared")
+	}
+	if rec, _ := h.do("/forget", nil); rec.Code != http.StatusOK {
+		t.Fatalf("Forget without a cookie should succeed")
+	}
+
+	h.do("/issue", nil)
+	h.do("/issue", nil)
+	if err := h.m.ForgetUser(context.Background(), "alice"); err != nil || h.store.Len() != 0 {
+		t.Fatalf("ForgetUser should revoke every series")
+	}
+}
+
+func TestMiddlewareRestoresSession(t *testing.T) {
+	h := newHarness(t, Config{})
+	_, ck := h.do("/issue", nil)
+
+	app := flash.New()
+	app.Use(middleware.Sessions(middleware.SessionConfig{Store: middleware.NewMemoryStore()}), h.m.Middleware(MiddlewareConfig{}))
+	app.GET("/me", func(c flash.Ctx) error {
+		id, _ := middleware.SessionFromCtx(c).Get("user_id")
+		s, _ := id.(string)
+		return c.String(http.StatusOK, s)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.AddCookie(ck)
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	if rec.Body.String() != "alice" {
+		t.Fatalf("expected session restored for alice, got %q", rec.Body.String())
+	}
+	var sid, rotated *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		switch c.Name {
+		case "flash.sid":
+			sid = c
+		case "remember":
+			rotated = c
+		}
+	}
+	if sid == nil || rotated == nil || rotated.Value == ck.Value {
+		t.Fatalf("expected session and rotated remember cookies, got %v", rec.Result().Cookies())
+	}
+
+	// With a live session the remember-me cookie is not consulted.
+	req = httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.AddCookie(sid)
+	req.AddCookie(rotated)
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	if rec.Body.String() != "alice" {
+		t.Fatalf("expected existing session, got %q", rec.Body.String())
+	}
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "remember" {
+			t.Fatalf("token should not rotate when a session exists")
+		}
+	}
+}
+
+func TestMiddlewareAnonymous(t *testing.T) {
+	var logins int
+	m := New(Config{Store: NewMemoryStore()})
+	app := flash.New()
+	app.Use(m.Middleware(MiddlewareConfig{
+		IsAuthenticated: func(flash.Ctx) bool { return false },
+		Login:           func(flash.Ctx, string) error { logins++; return nil },
+	}))
+	app.GET("/", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "remember", Value: "bad:cookie"})
+	app.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || logins != 0 {
+		t.Fatalf("invalid cookies should continue anonymously, got %d logins=%d", rec.Code, logins)
+	}
+}
+
+func TestMiddlewareLogsStoreErrorsToRequestLogger(t *testing.T) {
+	m := New(Config{Store: failingStore{NewMemoryStore()}})
+	var logs bytes.Buffer
+	app := flash.New()
+	app.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	app.Use(m.Middleware(MiddlewareConfig{IsAuthenticated: func(flash.Ctx) bool { return false }}))
+	app.GET("/", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "remember", Value: "series:token"})
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(logs.String(), "remember: authenticate failed") {
+		t.Fatalf("code=%d logs=%q", rec.Code, logs.String())
+	}
+}
+
+func TestNewRequiresStore(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic")
+		}
+	}()
+	New(Config{})
+}
+
+// barrierStore holds the next n Get calls until all of them arrived, so the
+// callers race on Rotate.
+type barrierStore struct {
+	*MemoryStore
+	mu      sync.Mutex
+	n       int
+	release chan struct{}
+}
+
+func (s *barrierStore) Get(ctx context.Context, series string) (Token, error) {
+	t, err := s.MemoryStore.Get(ctx, series)
+	s.mu.Lock()
+	if s.n == 0 {
+		s.mu.Unlock()
+		return t, err
+	}
+	s.n--
+	if s.n == 0 {
+		close(s.release)
+	}
+	release := s.release
+	s.mu.Unlock()
+	<-release
+	return t, err
+}
+
+func TestConcurrentAuthenticateRotatesOnce(t *testing.T) {
+	store := &barrierStore{MemoryStore: NewMemoryStore(), release: make(chan struct{})}
+	var victim string
+	h := newHarness(t, Config{Store: store, Grace: -1, OnTheft: func(_ flash.Ctx, id string) { victim = id }})
+	_, ck := h.do("/issue", nil)
+
+	store.mu.Lock()
+	store.n = 2
+	store.mu.Unlock()
+	type result struct {
+		code int
+		ck   *http.Cookie
+	}
+	results := make(chan result, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			rec, next := h.do("/auth", ck)
+			results <- result{rec.Code, next}
+		}()
+	}
+	var winner *http.Cookie
+	for i := 0; i < 2; i++ {
+		r := <-results
+		if r.code != http.StatusOK {
+			t.Fatalf("concurrent request failed with %d", r.code)
+		}
+		if r.ck != nil {
+			if winner != nil {
+				t.Fatalf("token rotated twice")
+			}
+			winner = r.ck
+		}
+	}
+	if winner == nil {
+		t.Fatalf("no rotated cookie")
+	}
+	if rec, _ := h.do("/auth", winner); rec.Code != http.StatusOK || victim != "" {
+		t.Fatalf("next request with the rotated cookie: %d %q, theft=%q", rec.Code, rec.Body.String(), victim)
+	}
+}
@@ -0,0 +1,87 @@
+package remember
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-memory Store for development and tests. Series are
+// lost on restart and not shared between instances.
+type MemoryStore struct {
+	mu     sync.Mutex
+	series map[string]Token
+	now    func() time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{series: make(map[string]Token), now: time.Now}
+}
+
+// Save implements Store. Expired series are dropped on each save.
+func (s *MemoryStore) Save(_ context.Context, t Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for id, x := range s.series {
+		if !now.Before(x.Expires) {
+			delete(s.series, id)
+		}
+	}
+	s.series[t.Series] = t
+	return nil
+}
+
+// Rotate implements Store.
+func (s *MemoryStore) Rotate(_ context.Context, t Token, oldHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur, ok := s.series[t.Series]
+	if !ok {
+		return ErrNotFound
+	}
+	if cur.Hash != oldHash {
+		return ErrConflict
+	}
+	s.series[t.Series] = t
+	return nil
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(_ context.Context, series string) (Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.series[series]
+	if !ok {
+		return Token{}, ErrNotFound
+	}
+	return t, nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(_ context.Context, series string) error {
+	s.mu.Lock()
+	delete(s.series, series)
+	s.mu.Unlock()
+	return nil
+}
+
+// DeleteUser implements Store.
+func (s *MemoryStore) DeleteUser(_ context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, t := range s.series {
+		if t.UserID == userID {
+			delete(s.series, id)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of stored series.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.series)
+}
@@ -0,0 +1,197 @@
+package signature
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+// Errors returned by Verify.
+var (
+	// ErrMissing means the request has no Signature header.
+	ErrMissing = errors.New("signature: missing")
+	// ErrMalformed means the Signature header cannot be parsed, or does not
+	// sign a required header.
+	ErrMalformed = errors.New("signature: malformed")
+	// ErrUnknownKey means no verification key exists for the key ID, or the
+	// key does not match the algorithm.
+	ErrUnknownKey = errors.New("signature: unknown key")
+	// ErrExpired means the timestamp is outside the allowed clock skew.
+	ErrExpired = errors.New("signature: timestamp outside allowed skew")
+	// ErrInvalid means the signature does not match the request.
+	ErrInvalid = errors.New("signature: invalid")
+	// ErrBodyTooLarge means the body exceeds Config.MaxBodySize.
+	ErrBodyTooLarge = errBodyTooLarge
+)
+
+// Config configures a Verifier.
+type Config struct {
+	// Keys returns the verification key for a key ID. Required.
+	Keys func(keyID string) (Key, bool)
+
+	// MaxSkew is how far the signed timestamp may be from the server clock,
+	// in either direction. Defaults to 5 minutes.
+	MaxSkew time.Duration
+
+	// RequiredHeaders must be covered by every signature, in addition to
+	// Host (e.g. "content-type", "x-tenant-id").
+	RequiredHeaders []string
+
+	// MaxBodySize caps the body read to verify its hash. Defaults to 10 MiB.
+	MaxBodySize int64
+
+	// OnError handles rejected requests in Middleware. By default it answers
+	// 413 for ErrBodyTooLarge and 401 otherwise.
+	OnError func(c flash.Ctx, err error) error
+}
+
+// StaticKeys returns a Config.Keys function over a fixed set of keys.
+func StaticKeys(keys ...Key) func(string) (Key, bool) {
+	m := make(map[string]Key, len(keys))
+	for _, k := range keys {
+		m[k.ID] = k
+	}
+	return func(id string) (Key, bool) {
+		k, ok := m[id]
+		return k, ok
+	}
+}
+
+// Verifier checks signed requests.
+type Verifier struct {
+	cfg      Config
+	required []string
+	now      func() time.Time
+}
+
+// New returns a Verifier. It panics if cfg.Keys is nil.
+func New(cfg Config) *Verifier {
+	if cfg.Keys == nil {
+		panic("signature: Config.Keys is required")
+	}
+	if cfg.MaxSkew <= 0 {
+		cfg.MaxSkew = 5 * time.Minute
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = 10 << 20
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = func(c flash.Ctx, err error) error {
+			if errors.Is(err, ErrBodyTooLarge) {
+				return c.String(http.StatusRequestEntityTooLarge, http.StatusText(http.StatusRequestEntityTooLarge))
+			}
+			return c.String(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		}
+	}
+	return &Verifier{cfg: cfg, required: normalizeHeaders(cfg.RequiredHeaders), now: time.Now}
+}
+
+// Verify checks the request's signature and returns the signing key ID. The
+// body is read (up to MaxBodySize) and replaced, so handlers can still read
+// it. Verify must see the request path as sent, before any prefix
+// stripping.
+func (v *Verifier) Verify(r *http.Request) (string, error) {
+	raw := r.Header.Get(Header)
+	if raw == "" {
+		return "", ErrMissing
+	}
+	p, ok := parseHeader(raw)
+	if !ok {
+		return "", ErrMalformed
+	}
+	headers := strings.Split(p["headers"], ";")
+	signed := make(map[string]bool, len(headers))
+	for _, h := range headers {
+		signed[h] = true
+	}
+	for _, h := range v.required {
+		if !signed[h] {
+			return "", ErrMalformed
+		}
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(p["sig"])
+	if err != nil {
+		return "", ErrMalformed
+	}
+	ts, err := strconv.ParseInt(p["ts"], 10, 64)
+	if err != nil {
+		return "", ErrMalformed
+	}
+	if d := v.now().Sub(time.Unix(ts, 0)); d > v.cfg.MaxSkew || d < -v.cfg.MaxSkew {
+		return "", ErrExpired
+	}
+	key, ok := v.cfg.Keys(p["keyId"])
+	if !ok || key.alg() != p["alg"] {
+		return "", ErrUnknownKey
+	}
+	body, err := readBody(r, v.cfg.MaxBodySize)
+	if err != nil {
+		return "", err
+	}
+
+	canon := canonicalRequest(r, r.Host, headers, p["keyId"], p["alg"], p["ts"], body)
+	switch p["alg"] {
+	case AlgHMACSHA256:
+		ok = hmac.Equal(sig, hmacSum(key.Secret, canon))
+	case AlgEd25519:
+		ok = len(key.PublicKey) == ed25519.PublicKeySize && ed25519.Verify(key.PublicKey, []byte(canon), sig)
+	}
+	if !ok {
+		return "", ErrInvalid
+	}
+	return p["keyId"], nil
+}
+
+// parseHeader splits the Signature header into its parameters and checks
+// that every one is present.
+func parseHeader(raw string) (map[string]string, bool) {
+	p := make(map[string]string, 5)
+	for _, part := range strings.Split(raw, ",") {
+		k, val, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, false
+		}
+		p[k] = val
+	}
+	for _, k := range []string{"keyId", "alg", "ts", "headers", "sig"} {
+		if p[k] == "" {
+			return nil, false
+		}
+	}
+	return p, true
+}
+
+type keyIDContextKey struct{}
+
+// Middleware returns middleware that rejects requests without a valid
+// signature through Config.OnError, and otherwise stores the caller's key ID
+// for KeyIDFromCtx.
+func (v *Verifier) Middleware() flash.Middleware {
+	described := v.cfg
+	described.Keys = nil // hands out secrets
+	return flash.Describe("signature", described, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			r := c.Request()
+			id, err := v.Verify(r)
+			if err != nil {
+				return v.cfg.OnError(c, err)
+			}
+			c.SetRequest(r.WithContext(context.WithValue(r.Context(), keyIDContextKey{}, id)))
+			return next(c)
+		}
+	})
+}
+
+// KeyIDFromCtx returns the key ID of a request verified by Middleware.
+func KeyIDFromCtx(c flash.Ctx) (string, bool) {
+	id, ok := c.Context().Value(keyIDContextKey{}).(string)
+	return id, ok
+}
@@ -0,0 +1,176 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+// server runs an app whose /echo route returns the caller and request body.
+func server(t *testing.T, v *Verifier) *httptest.Server {
+	t.Helper()
+	a := flash.New()
+	a.Use(v.Middleware())
+	a.POST("/echo", func(c flash.Ctx) error {
+		id, _ := KeyIDFromCtx(c)
+		b, _ := io.ReadAll(c.Request().Body)
+		return c.String(http.StatusOK, id+":"+string(b))
+	})
+	srv := httptest.NewServer(a)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func post(t *testing.T, client *http.Client, url, body string) (int, string) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(b)
+}
+
+func TestHMACRoundTrip(t *testing.T) {
+	srv := server(t, New(Config{Keys: StaticKeys(HMACKey("billing", secret)), RequiredHeaders: []string{"Content-Type"}}))
+	signer := NewSigner(HMACKey("billing", secret), "content-type")
+	client := &http.Client{Transport: signer.Transport(nil)}
+
+	if code, body := post(t, client, srv.URL+"/echo?b=2&a=1", "hello"); code != http.StatusOK || body != "billing:hello" {
+		t.Fatalf("signed request: %d %q", code, body)
+	}
+	if code, _ := post(t, http.DefaultClient, srv.URL+"/echo", "hello"); code != http.StatusUnauthorized {
+		t.Fatalf("unsigned request: %d", code)
+	}
+	other := &http.Client{Transport: NewSigner(HMACKey("billing", []byte("wrong"))).Transport(nil)}
+	if code, _ := post(t, other, srv.URL+"/echo", "hello"); code != http.StatusUnauthorized {
+		t.Fatalf("wrong secret: %d", code)
+	}
+}
+
+func TestEd25519RoundTrip(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	srv := server(t, New(Config{Keys: StaticKeys(Ed25519PublicKey("jobs", pub))}))
+	client := &http.Client{Transport: NewSigner(Ed25519Key("jobs", priv)).Transport(nil)}
+	if code, body := post(t, client, srv.URL+"/echo", "run"); code != http.StatusOK || body != "jobs:run" {
+		t.Fatalf("signed request: %d %q", code, body)
+	}
+
+	// An HMAC signature using the public key bytes as the secret must not be
+	// accepted for an Ed25519 key.
+	forged := &http.Client{Transport: NewSigner(HMACKey("jobs", pub)).Transport(nil)}
+	if code, _ := post(t, forged, srv.URL+"/echo", "run"); code != http.StatusUnauthorized {
+		t.Fatalf("algorithm confusion: %d", code)
+	}
+}
+
+// signed returns a request to /echo signed at time at.
+func signed(t *testing.T, s *Signer, at time.Time, body string) *http.Request {
+	t.Helper()
+	s.now = func() time.Time { return at }
+	r := httptest.NewRequest(http.MethodPost, "http://svc.internal/echo?x=1", strings.NewReader(body))
+	if err := s.Sign(r); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestVerifyErrors(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v := New(Config{Keys: StaticKeys(HMACKey("a", secret)), RequiredHeaders: []string{"x-tenant"}, MaxBodySize: 8})
+	v.now = func() time.Time { return now }
+	s := NewSigner(HMACKey("a", secret), "X-Tenant")
+
+	r := signed(t, s, now.Add(-time.Minute), "body")
+	if id, err := v.Verify(r); err != nil || id != "a" {
+		t.Fatalf("valid: %q %v", id, err)
+	}
+	if b, _ := io.ReadAll(r.Body); string(b) != "body" {
+		t.Fatalf("body should be restored, got %q", b)
+	}
+
+	cases := map[string]struct {
+		req  func() *http.Request
+		want error
+	}{
+		"missing": {func() *http.Request { return httptest.NewRequest(http.MethodGet, "/", nil) }, ErrMissing},
+		"malformed": {func() *http.Request {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set(Header, "keyId=a,alg=hmac-sha256")
+			return r
+		}, ErrMalformed},
+		"expired": {func() *http.Request { return signed(t, s, now.Add(-10*time.Minute), "body") }, ErrExpired},
+		"future":  {func() *http.Request { return signed(t, s, now.Add(10*time.Minute), "body") }, ErrExpired},
+		"unknown key": {func() *http.Request {
+			return signed(t, NewSigner(HMACKey("b", secret), "x-tenant"), now, "body")
+		}, ErrUnknownKey},
+		"required header unsigned": {func() *http.Request { return signed(t, NewSigner(HMACKey("a", secret)), now, "body") }, ErrMalformed},
+		"tampered body": {func() *http.Request {
+			r := signed(t, s, now, "body")
+			r.Body = io.NopCloser(strings.NewReader("BODY"))
+			return r
+		}, ErrInvalid},
+		"tampered header": {func() *http.Request {
+			r := signed(t, s, now, "body")
+			r.Header.Set("X-Tenant", "other")
+			return r
+		}, ErrInvalid},
+		"tampered query": {func() *http.Request {
+			r := signed(t, s, now, "body")
+			r.URL.RawQuery = "x=2"
+			return r
+		}, ErrInvalid},
+		"body too large": {func() *http.Request { return signed(t, s, now, "0123456789") }, ErrBodyTooLarge},
+	}
+	for name, tc := range cases {
+		if _, err := v.Verify(tc.req()); !errors.Is(err, tc.want) {
+			t.Fatalf("%s: expected %v, got %v", name, tc.want, err)
+		}
+	}
+}
+
+func TestMiddlewareBodyTooLarge(t *testing.T) {
+	srv := server(t, New(Config{Keys: StaticKeys(HMACKey("a", secret)), MaxBodySize: 4}))
+	client := &http.Client{Transport: NewSigner(HMACKey("a", secret)).Transport(nil)}
+	if code, _ := post(t, client, srv.URL+"/echo", "too long"); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", code)
+	}
+}
+
+func TestMiddlewareConfigOmitsKeys(t *testing.T) {
+	v := New(Config{Keys: StaticKeys(HMACKey("a", secret)), MaxSkew: time.Minute})
+	cfg, ok := v.Middleware().Config().(Config)
+	if !ok || cfg.Keys != nil || cfg.MaxSkew != time.Minute || v.cfg.Keys == nil {
+		t.Fatalf("config=%#v", cfg)
+	}
+}
+
+func TestConstructorsPanic(t *testing.T) {
+	for name, fn := range map[string]func(){
+		"verifier without keys": func() { New(Config{}) },
+		"signer without secret": func() { NewSigner(Key{ID: "a"}) },
+		"signer public only":    func() { NewSigner(Ed25519PublicKey("a", make(ed25519.PublicKey, ed25519.PublicKeySize))) },
+		"bad key ID":            func() { NewSigner(HMACKey("a,b", secret)) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s: expected panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
@@ -0,0 +1,72 @@
+package benchmarks
+
+import (
+	"net/http"
+	"testing"
+)
+
+// Fixtures shared by the benchmarks and TestParity.
+var (
+	staticRequest    = request{method: http.MethodGet, target: pingRoute}
+	paramRequest     = request{method: http.MethodGet, target: "/repos/goflash/flash/issues/42"}
+	jsonRequest      = request{method: http.MethodPost, target: usersRoute, body: userJSON}
+	chainRequest     = request{method: http.MethodGet, target: chainPrefix + pingRoute}
+	rateLimitRequest = request{method: http.MethodGet, target: limitedRoute}
+)
+
+// benchFrameworks runs req against every framework as a sub-benchmark.
+func benchFrameworks(b *testing.B, req request) {
+	for _, fw := range frameworks {
+		b.Run(fw.name, func(b *testing.B) {
+			serve, _ := fw.new().prepare(req)
+			if status := serve(); status != http.StatusOK {
+				b.Fatalf("%s %s: status %d", req.method, req.target, status)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				serve()
+			}
+		})
+	}
+}
+
+// BenchmarkStatic measures dispatch of a static route.
+func BenchmarkStatic(b *testing.B) { benchFrameworks(b, staticRequest) }
+
+// BenchmarkParam measures lookup of a route with three parameters in the
+// shared route table, plus reading two of them.
+func BenchmarkParam(b *testing.B) { benchFrameworks(b, paramRequest) }
+
+// BenchmarkRouteTable serves every route of the shared table in turn.
+func BenchmarkRouteTable(b *testing.B) {
+	for _, fw := range frameworks {
+		b.Run(fw.name, func(b *testing.B) {
+			t := fw.new()
+			serves := make([]func() int, len(routes))
+			for i, route := range routes {
+				serves[i], _ = t.prepare(request{method: http.MethodGet, target: routeURL(route)})
+				if status := serves[i](); status != http.StatusOK {
+					b.Fatalf("%s: status %d", route, status)
+				}
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := range b.N {
+				serves[i%len(serves)]()
+			}
+		})
+	}
+}
+
+// BenchmarkJSON measures binding a JSON body and rendering it back.
+func BenchmarkJSON(b *testing.B) { benchFrameworks(b, jsonRequest) }
+
+// BenchmarkMiddlewareChain measures a static route behind chainDepth
+// pass-through middleware; compare with BenchmarkStatic for the per-layer
+// overhead.
+func BenchmarkMiddlewareChain(b *testing.B) { benchFrameworks(b, chainRequest) }
+
+// BenchmarkRateLimit measures a static route behind each framework's
+// per-client rate limiter (keyedLimiter where there is none built in).
+func BenchmarkRateLimit(b *testing.B) { benchFrameworks(b, rateLimitRequest) }
@@ -0,0 +1,21 @@
+// Package benchmarks compares flash against net/http, Echo, Gin and Fiber on
+// the same workloads: static and parameterized routing, JSON binding and
+// rendering, middleware chain overhead and rate limiting.
+//
+// It is a separate module so the framework itself does not depend on its
+// competitors. Every framework registers the same route table (see routes)
+// and must produce the same responses, which TestParity checks before any
+// numbers are trusted.
+//
+// Run the suite from this directory:
+//
+//	go test -run '^$' -bench . -benchmem -count 10 | tee new.txt
+//
+// and compare runs with benchstat (golang.org/x/perf/cmd/benchstat):
+//
+//	benchstat old.txt new.txt
+//
+// Requests are served in-process against a reusable response writer, so the
+// numbers measure framework overhead (context pooling, routing, binding,
+// middleware dispatch) rather than the network stack.
+package benchmarks
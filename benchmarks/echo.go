@@ -0,0 +1,46 @@
+package benchmarks
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
+)
+
+// newEcho builds the Echo app.
+func newEcho() target {
+	e := echo.New()
+	e.Logger.SetOutput(io.Discard)
+	ok := func(c echo.Context) error { return c.String(http.StatusOK, "ok") }
+	pong := func(c echo.Context) error { return c.String(http.StatusOK, "pong") }
+	for _, route := range routes {
+		e.GET(route, ok)
+	}
+	e.GET(pingRoute, pong)
+	e.GET(issueRoute, func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Param("owner")+"#"+c.Param("number"))
+	})
+	e.POST(usersRoute, func(c echo.Context) error {
+		var u user
+		if err := c.Bind(&u); err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.JSON(http.StatusOK, u)
+	})
+
+	mws := make([]echo.MiddlewareFunc, chainDepth)
+	for i := range mws {
+		mws[i] = func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error { return next(c) }
+		}
+	}
+	e.Group(chainPrefix, mws...).GET(pingRoute, pong)
+
+	e.GET(limitedRoute, pong, middleware.RateLimiter(middleware.NewRateLimiterMemoryStoreWithConfig(
+		middleware.RateLimiterMemoryStoreConfig{Rate: rate.Limit(limiterRate), Burst: limiterRate, ExpiresIn: time.Minute},
+	)))
+	return httpTarget{e}
+}
@@ -0,0 +1,67 @@
+package benchmarks
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+	"github.com/valyala/fasthttp"
+)
+
+// newFiber builds the Fiber app. Fiber runs on fasthttp rather than net/http,
+// so it is served natively through fiberTarget instead of an adaptor, which
+// would add conversion overhead Fiber users do not pay.
+func newFiber() target {
+	app := fiber.New()
+	ok := func(c *fiber.Ctx) error { return c.SendString("ok") }
+	pong := func(c *fiber.Ctx) error { return c.SendString("pong") }
+	for _, route := range routes {
+		app.Get(route, ok)
+	}
+	app.Get(pingRoute, pong)
+	app.Get(issueRoute, func(c *fiber.Ctx) error {
+		return c.SendString(c.Params("owner") + "#" + c.Params("number"))
+	})
+	app.Post(usersRoute, func(c *fiber.Ctx) error {
+		var u user
+		if err := c.BodyParser(&u); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		return c.JSON(u)
+	})
+
+	mws := make([]fiber.Handler, chainDepth)
+	for i := range mws {
+		mws[i] = func(c *fiber.Ctx) error { return c.Next() }
+	}
+	app.Group(chainPrefix, mws...).Get(pingRoute, pong)
+
+	app.Get(limitedRoute, limiter.New(limiter.Config{Max: limiterRate, Expiration: time.Second}), pong)
+	return fiberTarget{app.Handler()}
+}
+
+// fiberTarget serves a fasthttp handler with a reused RequestCtx.
+type fiberTarget struct{ h fasthttp.RequestHandler }
+
+func (t fiberTarget) prepare(req request) (func() int, func() response) {
+	var fctx fasthttp.RequestCtx
+	fctx.Request.Header.SetMethod(req.method)
+	fctx.Request.SetRequestURI(req.target)
+	if req.body != "" {
+		fctx.Request.Header.SetContentType("application/json")
+		fctx.Request.SetBodyString(req.body)
+	}
+	serve := func() int {
+		fctx.Response.Reset()
+		t.h(&fctx)
+		return fctx.Response.StatusCode()
+	}
+	last := func() response {
+		return response{
+			status:      fctx.Response.StatusCode(),
+			contentType: string(fctx.Response.Header.ContentType()),
+			body:        fctx.Response.Body(),
+		}
+	}
+	return serve, last
+}
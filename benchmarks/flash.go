@@ -0,0 +1,51 @@
+package benchmarks
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/goflash/flash/v2"
+	"github.com/goflash/flash/v2/middleware"
+)
+
+// newFlash builds the flash app with the default httprouter engine.
+func newFlash() target { return httpTarget{flashApp()} }
+
+// newFlashTree builds the flash app with the in-repo radix tree router.
+func newFlashTree() target { return httpTarget{flashApp(flash.WithRouter(flash.NewTreeRouter()))} }
+
+func flashApp(opts ...flash.Option) flash.App {
+	a := flash.New(opts...)
+	a.SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ok := func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") }
+	pong := func(c flash.Ctx) error { return c.String(http.StatusOK, "pong") }
+	for _, route := range routes {
+		a.GET(route, ok)
+	}
+	a.GET(pingRoute, pong)
+	a.GET(issueRoute, func(c flash.Ctx) error {
+		return c.String(http.StatusOK, c.Param("owner")+"#"+c.Param("number"))
+	})
+	a.POST(usersRoute, func(c flash.Ctx) error {
+		var u user
+		if err := c.BindJSON(&u); err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.JSON(u)
+	})
+
+	mws := make([]flash.Middleware, chainDepth)
+	for i := range mws {
+		mws[i] = func(next flash.Handler) flash.Handler {
+			return func(c flash.Ctx) error { return next(c) }
+		}
+	}
+	a.Group(chainPrefix, mws...).GET(pingRoute, pong)
+
+	a.GET(limitedRoute, pong, middleware.RateLimit(
+		middleware.WithStrategy(middleware.NewTokenBucketStrategy(limiterRate, time.Second)),
+	))
+	return a
+}
@@ -0,0 +1,46 @@
+package benchmarks
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newGin builds the Gin app. Gin has no built-in rate limiter, so
+// limitedRoute uses keyedLimiter like the net/http baseline.
+func newGin() target {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+	pong := func(c *gin.Context) { c.String(http.StatusOK, "pong") }
+	for _, route := range routes {
+		r.GET(route, ok)
+	}
+	r.GET(pingRoute, pong)
+	r.GET(issueRoute, func(c *gin.Context) {
+		c.String(http.StatusOK, c.Param("owner")+"#"+c.Param("number"))
+	})
+	r.POST(usersRoute, func(c *gin.Context) {
+		var u user
+		if err := c.ShouldBindJSON(&u); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, u)
+	})
+
+	mws := make([]gin.HandlerFunc, chainDepth)
+	for i := range mws {
+		mws[i] = func(c *gin.Context) { c.Next() }
+	}
+	r.Group(chainPrefix, mws...).GET(pingRoute, pong)
+
+	lim := newKeyedLimiter()
+	r.GET(limitedRoute, func(c *gin.Context) {
+		if !lim.allow(c.Request.RemoteAddr) {
+			limitedResponse(c.Writer)
+			c.Abort()
+		}
+	}, pong)
+	return httpTarget{r}
+}
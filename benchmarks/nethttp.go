@@ -0,0 +1,53 @@
+package benchmarks
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// newNetHTTP is the baseline: a Go 1.22+ ServeMux with hand-written
+// middleware, as a service without a framework would be built.
+func newNetHTTP() target {
+	mux := http.NewServeMux()
+	ok := func(w http.ResponseWriter, _ *http.Request) { w.Write([]byte("ok")) }
+	pong := func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte("pong"))
+	}
+	for _, route := range routes {
+		mux.HandleFunc(muxPattern(http.MethodGet, route), ok)
+	}
+	mux.HandleFunc(muxPattern(http.MethodGet, pingRoute), pong)
+	mux.HandleFunc(muxPattern(http.MethodGet, issueRoute), func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(r.PathValue("owner") + "#" + r.PathValue("number")))
+	})
+	mux.HandleFunc(muxPattern(http.MethodPost, usersRoute), func(w http.ResponseWriter, r *http.Request) {
+		var u user
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&u); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(u)
+	})
+
+	var chained http.Handler = http.HandlerFunc(pong)
+	for range chainDepth {
+		next := chained
+		chained = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { next.ServeHTTP(w, r) })
+	}
+	mux.Handle(muxPattern(http.MethodGet, chainPrefix+pingRoute), chained)
+
+	lim := newKeyedLimiter()
+	mux.HandleFunc(muxPattern(http.MethodGet, limitedRoute), func(w http.ResponseWriter, r *http.Request) {
+		if !lim.allow(r.RemoteAddr) {
+			limitedResponse(w)
+			return
+		}
+		pong(w, r)
+	})
+	return httpTarget{mux}
+}
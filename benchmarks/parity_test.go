@@ -0,0 +1,75 @@
+package benchmarks
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// routeURL fills the parameters of an httprouter pattern with "x".
+func routeURL(route string) string {
+	segs := strings.Split(route, "/")
+	for i, s := range segs {
+		if strings.HasPrefix(s, ":") {
+			segs[i] = "x"
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// TestParity checks that every framework answers the benchmark fixtures the
+// same way, so the benchmarks compare equivalent work.
+func TestParity(t *testing.T) {
+	text := []struct {
+		name string
+		req  request
+		want string
+	}{
+		{"static", staticRequest, "pong"},
+		{"param", paramRequest, "goflash#42"},
+		{"chain", chainRequest, "pong"},
+		{"ratelimit", rateLimitRequest, "pong"},
+	}
+	var want user
+	if err := json.Unmarshal([]byte(userJSON), &want); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fw := range frameworks {
+		t.Run(fw.name, func(t *testing.T) {
+			tg := fw.new()
+			for _, tc := range text {
+				serve, last := tg.prepare(tc.req)
+				serve()
+				res := last()
+				if res.status != http.StatusOK || string(res.body) != tc.want {
+					t.Fatalf("%s: got %d %q, want 200 %q", tc.name, res.status, res.body, tc.want)
+				}
+				if mt, _, _ := mime.ParseMediaType(res.contentType); mt != "text/plain" {
+					t.Fatalf("%s: content type %q", tc.name, res.contentType)
+				}
+			}
+
+			serve, last := tg.prepare(jsonRequest)
+			serve()
+			res := last()
+			var got user
+			if res.status != http.StatusOK || json.Unmarshal(res.body, &got) != nil || !reflect.DeepEqual(got, want) {
+				t.Fatalf("json: got %d %q", res.status, res.body)
+			}
+			if mt, _, _ := mime.ParseMediaType(res.contentType); mt != "application/json" {
+				t.Fatalf("json: content type %q", res.contentType)
+			}
+
+			for _, route := range routes {
+				serve, last := tg.prepare(request{method: http.MethodGet, target: routeURL(route)})
+				if serve(); last().status != http.StatusOK || string(last().body) != "ok" {
+					t.Fatalf("%s: got %d %q", route, last().status, last().body)
+				}
+			}
+		})
+	}
+}
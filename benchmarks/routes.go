@@ -0,0 +1,108 @@
+package benchmarks
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// routes is the shared route table in httprouter syntax, modeled on a subset
+// of the GitHub REST API. Every framework registers all of them with okHandler
+// semantics, except issueRoute which echoes its parameters.
+var routes = []string{
+	"/users/:user",
+	"/users/:user/repos",
+	"/repos/:owner/:repo",
+	"/repos/:owner/:repo/issues",
+	"/repos/:owner/:repo/issues/:number/comments",
+	"/repos/:owner/:repo/pulls",
+	"/repos/:owner/:repo/pulls/:number",
+	"/repos/:owner/:repo/contributors",
+	"/orgs/:org",
+	"/orgs/:org/members",
+	"/orgs/:org/repos",
+	"/search/repositories",
+	"/search/users",
+	"/notifications",
+}
+
+const (
+	// pingRoute is a static route answering "pong".
+	pingRoute = "/ping"
+	// issueRoute answers "<owner>#<number>".
+	issueRoute = "/repos/:owner/:repo/issues/:number"
+	// usersRoute binds a user from a JSON body and renders it back.
+	usersRoute = "/users"
+	// chainPrefix groups pingRoute behind chainDepth pass-through middleware.
+	chainPrefix = "/chain"
+	// limitedRoute is pingRoute behind a per-client rate limiter whose limit
+	// is never reached, measuring the limiter's bookkeeping.
+	limitedRoute = "/limited/ping"
+)
+
+// chainDepth is the number of pass-through middleware in front of
+// chainPrefix + pingRoute.
+const chainDepth = 5
+
+// limiterRate is the per-client limit of limitedRoute in requests per second,
+// high enough that benchmarks are never limited.
+const limiterRate = 1 << 30
+
+// user is the JSON binding and rendering payload.
+type user struct {
+	ID    int      `json:"id"`
+	Name  string   `json:"name"`
+	Email string   `json:"email"`
+	Admin bool     `json:"admin"`
+	Tags  []string `json:"tags"`
+}
+
+// userJSON is the request body for usersRoute.
+const userJSON = `{"id":42,"name":"Ada Lovelace","email":"ada@example.com","admin":true,"tags":["math","engines"]}`
+
+// muxPattern converts an httprouter pattern to a net/http ServeMux pattern.
+func muxPattern(method, route string) string {
+	segs := strings.Split(route, "/")
+	for i, s := range segs {
+		if strings.HasPrefix(s, ":") {
+			segs[i] = "{" + s[1:] + "}"
+		}
+	}
+	return method + " " + strings.Join(segs, "/")
+}
+
+// keyedLimiter is the idiomatic per-client limiter for frameworks without a
+// built-in one: a mutex-guarded map of golang.org/x/time/rate limiters keyed
+// by client IP.
+type keyedLimiter struct {
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+func newKeyedLimiter() *keyedLimiter {
+	return &keyedLimiter{limiters: make(map[string]*rate.Limiter)}
+}
+
+// allow reports whether the client at remoteAddr may proceed.
+func (l *keyedLimiter) allow(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	l.mu.Lock()
+	lim := l.limiters[host]
+	if lim == nil {
+		lim = rate.NewLimiter(limiterRate, limiterRate)
+		l.limiters[host] = lim
+	}
+	l.mu.Unlock()
+	return lim.Allow()
+}
+
+// limitedResponse answers a rate limited request.
+func limitedResponse(w http.ResponseWriter) {
+	http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+}
@@ -0,0 +1,107 @@
+package benchmarks
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+)
+
+// request is a benchmark request fixture.
+type request struct {
+	method string
+	target string
+	body   string
+}
+
+// response is what a target answered.
+type response struct {
+	status      int
+	contentType string
+	body        []byte
+}
+
+// target serves fixtures against one framework's app.
+type target interface {
+	// prepare returns a function that serves req once and returns the
+	// status, and a function describing the last response. serve reuses its
+	// request and response buffers, so benchmarks measure the framework
+	// rather than fixture allocation.
+	prepare(req request) (serve func() int, last func() response)
+}
+
+// framework is a benchmarked framework.
+type framework struct {
+	name string
+	new  func() target
+}
+
+// frameworks lists every framework in benchmark order.
+var frameworks = []framework{
+	{"nethttp", newNetHTTP},
+	{"flash", newFlash},
+	{"flash-tree", newFlashTree},
+	{"echo", newEcho},
+	{"gin", newGin},
+	{"fiber", newFiber},
+}
+
+// httpTarget serves an http.Handler.
+type httpTarget struct{ h http.Handler }
+
+func (t httpTarget) prepare(req request) (func() int, func() response) {
+	payload := []byte(req.body)
+	body := &rewindBody{Reader: bytes.NewReader(payload)}
+	r := httptest.NewRequest(req.method, req.target, nil)
+	if req.body != "" {
+		r.Header.Set("Content-Type", "application/json")
+		r.ContentLength = int64(len(req.body))
+	}
+	w := &discardWriter{header: make(http.Header)}
+	serve := func() int {
+		body.Reset(payload)
+		r.Body = body
+		clear(w.header)
+		w.status, w.body = 0, w.body[:0]
+		t.h.ServeHTTP(w, r)
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		return w.status
+	}
+	last := func() response {
+		return response{status: w.status, contentType: w.header.Get("Content-Type"), body: w.body}
+	}
+	return serve, last
+}
+
+// rewindBody is a request body that can be replayed without allocating.
+type rewindBody struct{ *bytes.Reader }
+
+func (*rewindBody) Close() error { return nil }
+
+var _ io.ReadCloser = (*rewindBody)(nil)
+
+// discardWriter is a reusable http.ResponseWriter that keeps the status and
+// body of the last response.
+type discardWriter struct {
+	header http.Header
+	status int
+	body   []byte
+}
+
+func (w *discardWriter) Header() http.Header { return w.header }
+
+func (w *discardWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *discardWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body = append(w.body, b...)
+	return len(b), nil
+}
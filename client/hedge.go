@@ -0,0 +1,334 @@
+// Package client provides helpers for outbound HTTP calls made while serving
+// requests.
+//
+// HedgeTransport sends hedged requests: when an idempotent call has not
+// answered after a high latency percentile, a duplicate is sent and the first
+// successful response wins, trimming tail latency caused by a slow upstream
+// instance. A budget caps how many calls are hedged so a slow upstream is not
+// overloaded further.
+//
+// Example:
+//
+//	upstream := &http.Client{Transport: client.NewHedgeTransport(nil, client.HedgeConfig{})}
+//
+//	a.GET("/profile/:id", func(c flash.Ctx) error {
+//		// The inbound request's deadline (e.g. from middleware.Timeout) bounds
+//		// both attempts; no hedge is sent when it leaves too little time.
+//		req, _ := http.NewRequestWithContext(c.Context(), http.MethodGet, profileURL+c.Param("id"), nil)
+//		resp, err := upstream.Do(req)
+//		...
+//	})
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// hedgeSamples is how many recent latencies the hedge delay is computed
+	// from.
+	hedgeSamples = 256
+	// hedgeMinSamples is how many latencies must be observed before the
+	// percentile-based delay is trusted; until then calls are not hedged.
+	hedgeMinSamples = 20
+	// hedgeBurst caps the hedge budget's saved tokens, i.e. how many calls
+	// may be hedged back to back.
+	hedgeBurst = 10
+)
+
+// HedgeConfig tunes a HedgeTransport. The zero value hedges idempotent
+// requests once, after the 95th percentile of recent latencies, for at most
+// 10% of calls.
+type HedgeConfig struct {
+	// Delay is a fixed time to wait before hedging. Zero derives it from
+	// Percentile of the latencies of recent successful calls.
+	Delay time.Duration
+	// Percentile of recent latencies after which a call is hedged, in (0, 1).
+	// Default 0.95.
+	Percentile float64
+	// MinDelay is the lower bound of the derived delay, so very fast upstreams
+	// are not hedged on noise. Default 5ms.
+	MinDelay time.Duration
+	// MaxHedges is how many duplicates a call may send, one per Delay.
+	// Default 1.
+	MaxHedges int
+	// Budget is the fraction of calls that may be hedged, enforced with a
+	// token bucket: each call earns Budget tokens and each hedge spends one.
+	// Default 0.1.
+	Budget float64
+	// Idempotent reports whether req may be sent more than once. Default:
+	// GET, HEAD, OPTIONS and TRACE requests, and requests with an
+	// Idempotency-Key header. Requests with a body must also have GetBody
+	// set (http.NewRequest does so for in-memory bodies).
+	Idempotent func(req *http.Request) bool
+	// Success reports whether an attempt's outcome ends the call. Default: no
+	// error and a status below 500. A failed attempt leaves the others
+	// running; when all fail, the last outcome is returned.
+	Success func(resp *http.Response, err error) bool
+}
+
+// HedgeStats counts the calls of a HedgeTransport.
+type HedgeStats struct {
+	Requests uint64 // calls eligible for hedging
+	Hedged   uint64 // duplicates sent
+	HedgeWon uint64 // calls answered by a duplicate
+}
+
+// HedgeTransport is an http.RoundTripper that hedges idempotent requests. It
+// is safe for concurrent use.
+type HedgeTransport struct {
+	base http.RoundTripper
+	cfg  HedgeConfig
+
+	mu      sync.Mutex
+	samples []time.Duration // ring buffer of recent latencies
+	next    int
+	delay   time.Duration // cached percentile of samples
+	stale   int           // samples added since delay was computed
+	tokens  float64
+
+	requests, hedged, won atomic.Uint64
+}
+
+// NewHedgeTransport returns a HedgeTransport sending requests through base,
+// or http.DefaultTransport when base is nil.
+//
+// Example:
+//
+//	t := client.NewHedgeTransport(nil, client.HedgeConfig{Percentile: 0.9, Budget: 0.05})
+//	c := &http.Client{Transport: t, Timeout: 2 * time.Second}
+func NewHedgeTransport(base http.RoundTripper, cfg HedgeConfig) *HedgeTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if cfg.Percentile <= 0 || cfg.Percentile >= 1 {
+		cfg.Percentile = 0.95
+	}
+	if cfg.MinDelay <= 0 {
+		cfg.MinDelay = 5 * time.Millisecond
+	}
+	if cfg.MaxHedges <= 0 {
+		cfg.MaxHedges = 1
+	}
+	if cfg.Budget <= 0 {
+		cfg.Budget = 0.1
+	}
+	if cfg.Idempotent == nil {
+		cfg.Idempotent = idempotent
+	}
+	if cfg.Success == nil {
+		cfg.Success = func(resp *http.Response, err error) bool {
+			return err == nil && resp.StatusCode < http.StatusInternalServerError
+		}
+	}
+	return &HedgeTransport{base: base, cfg: cfg, samples: make([]time.Duration, 0, hedgeSamples), tokens: hedgeBurst}
+}
+
+// idempotent is the default HedgeConfig.Idempotent.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	_, key := req.Header["Idempotency-Key"]
+	_, xkey := req.Header["X-Idempotency-Key"]
+	return key || xkey
+}
+
+// Stats returns the transport's counters.
+func (t *HedgeTransport) Stats() HedgeStats {
+	return HedgeStats{Requests: t.requests.Load(), Hedged: t.hedged.Load(), HedgeWon: t.won.Load()}
+}
+
+// attempt is the outcome of one of a call's requests.
+type attempt struct {
+	idx   int
+	resp  *http.Response
+	err   error
+	took  time.Duration
+	hedge bool
+}
+
+// RoundTrip sends req and, if it is idempotent and has not answered after
+// the hedge delay, up to MaxHedges duplicates. It returns the first
+// successful response; the other attempts are canceled. No hedge is sent
+// when req's context deadline is closer than the hedge delay, or when the
+// budget is spent.
+func (t *HedgeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	if !replayable || !t.cfg.Idempotent(req) {
+		return t.base.RoundTrip(req)
+	}
+	t.requests.Add(1)
+	t.earn()
+	delay, ok := t.hedgeDelay()
+	if dl, has := req.Context().Deadline(); has && time.Until(dl) <= delay {
+		ok = false // a hedge could not finish in time
+	}
+	if !ok {
+		start := time.Now()
+		resp, err := t.base.RoundTrip(req)
+		if t.cfg.Success(resp, err) {
+			t.observe(time.Since(start))
+		}
+		return resp, err
+	}
+
+	results := make(chan attempt, 1+t.cfg.MaxHedges)
+	var cancels []context.CancelFunc
+	launch := func(r *http.Request, hedge bool) {
+		actx, cancel := context.WithCancel(req.Context())
+		idx := len(cancels)
+		cancels = append(cancels, cancel)
+		r = r.WithContext(actx)
+		start := time.Now()
+		go func() {
+			resp, err := t.base.RoundTrip(r)
+			results <- attempt{idx: idx, resp: resp, err: err, took: time.Since(start), hedge: hedge}
+		}()
+	}
+	launch(req, false)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	pending, hedges := 1, 0
+	var last attempt
+	for {
+		select {
+		case <-timer.C:
+			if hedges >= t.cfg.MaxHedges || !t.spend() {
+				continue
+			}
+			dup := req.Clone(req.Context())
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					continue
+				}
+				dup.Body = body
+			}
+			launch(dup, true)
+			pending++
+			hedges++
+			t.hedged.Add(1)
+			if hedges < t.cfg.MaxHedges {
+				timer.Reset(delay)
+			}
+		case a := <-results:
+			pending--
+			if t.cfg.Success(a.resp, a.err) {
+				t.observe(a.took)
+				if a.hedge {
+					t.won.Add(1)
+				}
+				for i, cancel := range cancels {
+					if i != a.idx {
+						cancel()
+					}
+				}
+				go discard(results, pending)
+				a.resp.Body = &cancelBody{ReadCloser: a.resp.Body, cancel: cancels[a.idx]}
+				return a.resp, nil
+			}
+			if last.resp != nil || last.err != nil {
+				closeAttempt(last, cancels)
+			}
+			last = a
+			if pending == 0 {
+				if last.err != nil {
+					cancels[last.idx]()
+					return nil, last.err
+				}
+				last.resp.Body = &cancelBody{ReadCloser: last.resp.Body, cancel: cancels[last.idx]}
+				return last.resp, nil
+			}
+		}
+	}
+}
+
+// closeAttempt releases an attempt that will not be returned.
+func closeAttempt(a attempt, cancels []context.CancelFunc) {
+	if a.resp != nil {
+		a.resp.Body.Close()
+	}
+	cancels[a.idx]()
+}
+
+// discard closes the responses of the n attempts still running after a
+// call returned; their contexts are already canceled.
+func discard(results <-chan attempt, n int) {
+	for ; n > 0; n-- {
+		if a := <-results; a.resp != nil {
+			a.resp.Body.Close()
+		}
+	}
+}
+
+// cancelBody cancels the winning attempt's context once its body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// hedgeDelay returns how long to wait before hedging, and false while too
+// few latencies have been observed to derive it.
+func (t *HedgeTransport) hedgeDelay() (time.Duration, bool) {
+	if t.cfg.Delay > 0 {
+		return t.cfg.Delay, true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.samples) < hedgeMinSamples {
+		return 0, false
+	}
+	if t.delay == 0 || t.stale >= hedgeSamples/8 {
+		sorted := slices.Clone(t.samples)
+		slices.Sort(sorted)
+		t.delay = max(sorted[int(t.cfg.Percentile*float64(len(sorted)-1))], t.cfg.MinDelay)
+		t.stale = 0
+	}
+	return t.delay, true
+}
+
+// observe records the latency of a successful attempt.
+func (t *HedgeTransport) observe(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.samples) < hedgeSamples {
+		t.samples = append(t.samples, d)
+	} else {
+		t.samples[t.next] = d
+		t.next = (t.next + 1) % hedgeSamples
+	}
+	t.stale++
+}
+
+// earn adds a call's share to the hedge budget.
+func (t *HedgeTransport) earn() {
+	t.mu.Lock()
+	t.tokens = min(t.tokens+t.cfg.Budget, hedgeBurst)
+	t.mu.Unlock()
+}
+
+// spend takes one hedge from the budget.
+func (t *HedgeTransport) spend() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tokens < 1 {
+		return false
+	}
+	t.tokens--
+	return true
+}
@@ -0,0 +1,210 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeUpstream answers the nth call (from 0) after delays[n], echoing the
+// request body, and honors cancellation.
+type fakeUpstream struct {
+	delays []time.Duration
+	status []int
+	calls  atomic.Int32
+	mu     sync.Mutex
+	bodies []string
+}
+
+func (f *fakeUpstream) RoundTrip(req *http.Request) (*http.Response, error) {
+	n := int(f.calls.Add(1)) - 1
+	var body string
+	if req.Body != nil {
+		b, _ := io.ReadAll(req.Body)
+		body = string(b)
+	}
+	f.mu.Lock()
+	f.bodies = append(f.bodies, body)
+	f.mu.Unlock()
+	var d time.Duration
+	if n < len(f.delays) {
+		d = f.delays[n]
+	}
+	select {
+	case <-time.After(d):
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	status := http.StatusOK
+	if n < len(f.status) {
+		status = f.status[n]
+	}
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader("attempt" + string(rune('0'+n))))}, nil
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return string(b)
+}
+
+func TestHedge_DuplicateWins(t *testing.T) {
+	up := &fakeUpstream{delays: []time.Duration{time.Second, 0}}
+	tr := NewHedgeTransport(up, HedgeConfig{Delay: 20 * time.Millisecond})
+	req, _ := http.NewRequest(http.MethodGet, "http://upstream/x", nil)
+
+	start := time.Now()
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("round trip: %v", err)
+	}
+	if got := readBody(t, resp); got != "attempt1" {
+		t.Fatalf("body=%q, want the hedge's", got)
+	}
+	if took := time.Since(start); took > 500*time.Millisecond {
+		t.Fatalf("hedged call took %v", took)
+	}
+	if s := tr.Stats(); s != (HedgeStats{Requests: 1, Hedged: 1, HedgeWon: 1}) {
+		t.Fatalf("stats=%+v", s)
+	}
+}
+
+func TestHedge_FastCallNotHedged(t *testing.T) {
+	up := &fakeUpstream{}
+	tr := NewHedgeTransport(up, HedgeConfig{Delay: 50 * time.Millisecond})
+	req, _ := http.NewRequest(http.MethodGet, "http://upstream/x", nil)
+	resp, err := tr.RoundTrip(req)
+	if err != nil || readBody(t, resp) != "attempt0" {
+		t.Fatalf("resp=%v err=%v", resp, err)
+	}
+	time.Sleep(70 * time.Millisecond)
+	if up.calls.Load() != 1 || tr.Stats().Hedged != 0 {
+		t.Fatalf("calls=%d stats=%+v", up.calls.Load(), tr.Stats())
+	}
+}
+
+func TestHedge_NonIdempotentNotHedged(t *testing.T) {
+	up := &fakeUpstream{delays: []time.Duration{60 * time.Millisecond}}
+	tr := NewHedgeTransport(up, HedgeConfig{Delay: 10 * time.Millisecond})
+	req, _ := http.NewRequest(http.MethodPost, "http://upstream/x", strings.NewReader("order"))
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("round trip: %v", err)
+	}
+	resp.Body.Close()
+	if up.calls.Load() != 1 || tr.Stats().Requests != 0 {
+		t.Fatalf("POST hedged: calls=%d stats=%+v", up.calls.Load(), tr.Stats())
+	}
+}
+
+func TestHedge_IdempotencyKeyReplaysBody(t *testing.T) {
+	up := &fakeUpstream{delays: []time.Duration{time.Second, 0}}
+	tr := NewHedgeTransport(up, HedgeConfig{Delay: 10 * time.Millisecond})
+	req, _ := http.NewRequest(http.MethodPost, "http://upstream/x", strings.NewReader("order"))
+	req.Header.Set("Idempotency-Key", "k1")
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("round trip: %v", err)
+	}
+	resp.Body.Close()
+	up.mu.Lock()
+	defer up.mu.Unlock()
+	if len(up.bodies) != 2 || up.bodies[0] != "order" || up.bodies[1] != "order" {
+		t.Fatalf("bodies=%q", up.bodies)
+	}
+}
+
+func TestHedge_DeadlineTooClose(t *testing.T) {
+	up := &fakeUpstream{delays: []time.Duration{40 * time.Millisecond}}
+	tr := NewHedgeTransport(up, HedgeConfig{Delay: 100 * time.Millisecond})
+	ctx, cancel := context.WithTimeout(context.Background(), 80*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://upstream/x", nil)
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("round trip: %v", err)
+	}
+	resp.Body.Close()
+	if tr.Stats().Hedged != 0 {
+		t.Fatalf("hedged despite deadline: %+v", tr.Stats())
+	}
+}
+
+func TestHedge_BudgetSpent(t *testing.T) {
+	up := &fakeUpstream{delays: []time.Duration{40 * time.Millisecond}}
+	tr := NewHedgeTransport(up, HedgeConfig{Delay: 10 * time.Millisecond})
+	tr.tokens = 0
+	req, _ := http.NewRequest(http.MethodGet, "http://upstream/x", nil)
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("round trip: %v", err)
+	}
+	resp.Body.Close()
+	if up.calls.Load() != 1 || tr.Stats().Hedged != 0 {
+		t.Fatalf("hedged without budget: calls=%d", up.calls.Load())
+	}
+	for i := 0; i < 10; i++ {
+		tr.earn()
+	}
+	if !tr.spend() || tr.spend() {
+		t.Fatalf("10 calls at 10%% should earn exactly one hedge")
+	}
+}
+
+func TestHedge_AllFailReturnsLast(t *testing.T) {
+	up := &fakeUpstream{delays: []time.Duration{30 * time.Millisecond, 0}, status: []int{http.StatusBadGateway, http.StatusServiceUnavailable}}
+	tr := NewHedgeTransport(up, HedgeConfig{Delay: 10 * time.Millisecond})
+	req, _ := http.NewRequest(http.MethodGet, "http://upstream/x", nil)
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("round trip: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Fatalf("status=%d, want the last failure", resp.StatusCode)
+	}
+}
+
+func TestHedge_ErrorPropagates(t *testing.T) {
+	boom := errors.New("dial failed")
+	tr := NewHedgeTransport(roundTripFunc(func(*http.Request) (*http.Response, error) { return nil, boom }), HedgeConfig{Delay: time.Second})
+	req, _ := http.NewRequest(http.MethodGet, "http://upstream/x", nil)
+	if _, err := tr.RoundTrip(req); !errors.Is(err, boom) {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestHedge_PercentileDelay(t *testing.T) {
+	tr := NewHedgeTransport(&fakeUpstream{}, HedgeConfig{Percentile: 0.9})
+	if _, ok := tr.hedgeDelay(); ok {
+		t.Fatalf("delay derived without samples")
+	}
+	for i := 1; i <= 100; i++ {
+		tr.observe(time.Duration(i) * time.Millisecond)
+	}
+	d, ok := tr.hedgeDelay()
+	if !ok || d < 89*time.Millisecond || d > 91*time.Millisecond {
+		t.Fatalf("p90 delay=%v ok=%v", d, ok)
+	}
+	tr2 := NewHedgeTransport(&fakeUpstream{}, HedgeConfig{})
+	for i := 0; i < hedgeMinSamples; i++ {
+		tr2.observe(time.Microsecond)
+	}
+	if d, _ := tr2.hedgeDelay(); d != 5*time.Millisecond {
+		t.Fatalf("delay=%v, want MinDelay", d)
+	}
+}
@@ -0,0 +1,300 @@
+package clientgen
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2/app"
+	"github.com/goflash/flash/v2/ctx"
+)
+
+type Address struct {
+	City string `json:"city"`
+}
+
+type base struct {
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type User struct {
+	base
+	ID       int               `json:"id"`
+	Name     string            `json:"name,omitempty"`
+	Address  *Address          `json:"address,omitempty"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels"`
+	Manager  *User             `json:"manager,omitempty"`
+	Password string            `json:"-"`
+	secret   string
+	Token    apiToken `json:"token"`
+	Extra    rawThing `json:"extra"`
+}
+
+type NewUser struct {
+	Name string `json:"name" validate:"required"`
+}
+
+// apiToken has a text encoding, so clients see a string.
+type apiToken [4]byte
+
+func (t apiToken) MarshalText() ([]byte, error) { return []byte("tok"), nil }
+
+// rawThing has a custom JSON encoding, so clients see raw JSON.
+type rawThing struct{ v int }
+
+func (r rawThing) MarshalJSON() ([]byte, error) { return []byte(`{"v":1}`), nil }
+
+type Page[T any] struct {
+	Items []T `json:"items"`
+	Next  string
+}
+
+func testRoutes() *app.DefaultApp {
+	a := app.New().(*app.DefaultApp)
+	h := func(c ctx.Ctx) error { return nil }
+	a.GET("/users", h).Named("users.list").Types(nil, Page[User]{})
+	a.POST("/users", h).Named("users.create").Types(NewUser{}, &User{}).
+		Doc(app.DocInfo{Summary: "Creates a user.\nReturns **409** when taken."})
+	a.GET("/users/:user_id<int>", h).Types(nil, User{})
+	a.DELETE("/users/:id", h).Named("users.delete")
+	a.GET("/files/*path", h).Types(nil, []byte(nil))
+	a.GET("/count", h).Doc(app.DocInfo{ResponseExample: 3})
+	a.ANY("/any", h)
+	return a
+}
+
+func TestGenerate(t *testing.T) {
+	src, err := Generate(testRoutes().Routes(), Config{Package: "apiclient"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(src)
+	for _, want := range []string{
+		"// Code generated by github.com/goflash/flash/v2/clientgen. DO NOT EDIT.",
+		"package apiclient",
+		`"time"`,
+		"func (c *Client) UsersList(ctx context.Context, opts ...RequestOption) (*PageUser, error)",
+		"func (c *Client) UsersCreate(ctx context.Context, body NewUser, opts ...RequestOption) (*User, error)",
+		"// Returns **409** when taken.",
+		"func (c *Client) GetUsersByUserID(ctx context.Context, userID string, opts ...RequestOption) (*User, error)",
+		`c.Do(ctx, "GET", "/users/"+url.PathEscape(userID), nil, out, opts...)`,
+		"func (c *Client) UsersDelete(ctx context.Context, id string, opts ...RequestOption) error",
+		`return c.Do(ctx, "DELETE", "/users/"+url.PathEscape(id), nil, nil, opts...)`,
+		"func (c *Client) GetFilesByPath(ctx context.Context, path string, opts ...RequestOption) ([]byte, error)",
+		`"/files/"+escapeCatchAll(path)`,
+		"func (c *Client) GetCount(ctx context.Context, opts ...RequestOption) (int, error)",
+		"type PageUser struct",
+		"Items []User `json:\"items\"`",
+		"Next  string\n",
+		"\tbase\n",
+		"CreatedAt time.Time `json:\"created_at\"`",
+		"Manager *User",
+		"Token   string",
+		"Extra   json.RawMessage",
+		"Name string `json:\"name\"`\n",
+	} {
+		if !strings.Contains(s, want) {
+			t.Fatalf("generated code lacks %q:\n%s", want, s)
+		}
+	}
+	for _, unwanted := range []string{"Password", "secret", "validate", "/any", "Any("} {
+		if strings.Contains(s, unwanted) {
+			t.Fatalf("generated code contains %q", unwanted)
+		}
+	}
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	h := func(c ctx.Ctx) error { return nil }
+	cases := map[string]func(a *app.DefaultApp){
+		"both map to method": func(a *app.DefaultApp) {
+			a.GET("/a", h).Named("x")
+			a.GET("/b", h).Named("x")
+		},
+		"clashes with the client": func(a *app.DefaultApp) { a.GET("/a", h).Named("client") },
+		"cannot be encoded":       func(a *app.DefaultApp) { a.GET("/a", h).Types(nil, struct{ F func() }{}) },
+		"does not yield":          func(a *app.DefaultApp) { a.GET("/a", h).Named("...") },
+		"both map to Address": func(a *app.DefaultApp) {
+			type Address struct{ Street string }
+			a.GET("/a", h).Types(nil, struct {
+				A Address
+				B addressAlias
+			}{})
+		},
+	}
+	for want, setup := range cases {
+		a := app.New().(*app.DefaultApp)
+		setup(a)
+		if _, err := Generate(a.Routes(), Config{}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: err = %v", want, err)
+		}
+	}
+	if _, err := Generate(nil, Config{Package: "a-b"}); err == nil {
+		t.Fatalf("expected invalid package error")
+	}
+}
+
+type addressAlias = Address
+
+func TestGenerate_Include(t *testing.T) {
+	src, err := Generate(testRoutes().Routes(), Config{Include: func(rt *app.Route) bool { return rt.Name == "users.delete" }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(src)
+	if !strings.Contains(s, "package client") || !strings.Contains(s, "UsersDelete") || strings.Contains(s, "UsersList") {
+		t.Fatalf("unexpected output:\n%s", s)
+	}
+}
+
+func TestWriteFile_Unchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client.go")
+	routes := testRoutes().Routes()
+	if err := WriteFile(path, routes, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(path, routes, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if fi, _ := os.Stat(path); !fi.ModTime().Equal(old) {
+		t.Fatalf("unchanged file was rewritten")
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	for in, want := range map[string]string{
+		"users.show": "UsersShow", "api-keys": "APIKeys", "user_id": "UserID", "2fa": "N2fa",
+	} {
+		if got := identifier(in, true); got != want {
+			t.Fatalf("identifier(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := argName("user_id"); got != "userID" {
+		t.Fatalf("argName = %q", got)
+	}
+	if got := pkgName("math/rand/v2"); got != "rand" {
+		t.Fatalf("pkgName = %q", got)
+	}
+}
+
+// TestGeneratedClient compiles the generated client and runs it against a
+// flash app serving the same routes.
+func TestGeneratedClient(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a Go program")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+
+	a := app.New().(*app.DefaultApp)
+	a.POST("/users", func(c ctx.Ctx) error {
+		var in NewUser
+		if err := c.BindJSON(&in); err != nil {
+			return err
+		}
+		return c.Status(http.StatusCreated).JSON(User{ID: 7, Name: in.Name, Tags: []string{c.Request().URL.Query().Get("tag")}})
+	}).Named("users.create").Types(NewUser{}, User{})
+	a.GET("/files/*path", func(c ctx.Ctx) error {
+		return c.JSON([]string{c.Param("path"), c.Request().Header.Get("X-Test")})
+	}).Named("files.show").Types(nil, []string(nil))
+	a.DELETE("/users/:id", func(c ctx.Ctx) error {
+		return c.Status(http.StatusNotFound).JSON(map[string]string{"error": "user " + c.Param("id") + " not found"})
+	}).Named("users.delete")
+	srv := httptest.NewServer(a)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	mustWrite := func(name, body string) {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustWrite("go.mod", "module gentest\n\ngo 1.23\n")
+	if err := WriteFile(filepath.Join(dir, "apiclient", "client.go"), a.Routes(), Config{Package: "apiclient"}); err != nil {
+		t.Fatal(err)
+	}
+	mustWrite("main.go", `package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+
+	"gentest/apiclient"
+)
+
+func main() {
+	c := apiclient.New(os.Args[1], apiclient.WithHeader("X-Test", "yes"))
+	ctx := context.Background()
+	out := map[string]any{}
+	u, err := c.UsersCreate(ctx, apiclient.NewUser{Name: "Ada"}, apiclient.WithQuery(url.Values{"tag": {"admin"}}))
+	out["user"], out["err1"] = u, errString(err)
+	files, err := c.FilesShow(ctx, "docs/a b.txt")
+	out["files"], out["err2"] = files, errString(err)
+	err = c.UsersDelete(ctx, "9")
+	var apiErr *apiclient.Error
+	out["status"] = 0
+	if errors.As(err, &apiErr) {
+		out["status"] = apiErr.StatusCode
+	}
+	out["err3"] = errString(err)
+	_ = json.NewEncoder(os.Stdout).Encode(out)
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+`)
+	cmd := exec.Command(gobin, "run", ".", srv.URL)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off")
+	b, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go run: %v\n%s", err, b)
+	}
+	var got struct {
+		User struct {
+			ID   int      `json:"id"`
+			Name string   `json:"name"`
+			Tags []string `json:"tags"`
+		} `json:"user"`
+		Files  []string `json:"files"`
+		Status int      `json:"status"`
+		Err1   string   `json:"err1"`
+		Err2   string   `json:"err2"`
+		Err3   string   `json:"err3"`
+	}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("decode %q: %v", b, err)
+	}
+	if got.Err1 != "" || got.User.ID != 7 || got.User.Name != "Ada" || len(got.User.Tags) != 1 || got.User.Tags[0] != "admin" {
+		t.Fatalf("create: %+v", got)
+	}
+	if got.Err2 != "" || len(got.Files) != 2 || got.Files[0] != "/docs/a b.txt" || got.Files[1] != "yes" {
+		t.Fatalf("files: %+v", got)
+	}
+	if got.Status != http.StatusNotFound || !strings.Contains(got.Err3, "user 9 not found") {
+		t.Fatalf("delete: %+v", got)
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/goflash/flash/v2/app"
+)
+
+// devConfig holds the flags of "flash dev".
+type devConfig struct {
+	addr         string        // public address of the proxy
+	pkg          string        // main package to build
+	root         string        // directory watched and built in
+	exts         []string      // watched file extensions
+	interval     time.Duration // polling interval
+	readyTimeout time.Duration // time a new process has to call NotifyReady
+	grace        time.Duration // time an old process has to shut down
+	args         []string      // arguments passed to the app
+}
+
+// parseDevFlags parses "flash dev [flags] [package] [-- app arguments]".
+func parseDevFlags(args []string, out io.Writer) (devConfig, error) {
+	var cfg devConfig
+	for i, a := range args {
+		if a == "--" {
+			args, cfg.args = args[:i], args[i+1:]
+			break
+		}
+	}
+	fs := flag.NewFlagSet("flash dev", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.StringVar(&cfg.addr, "addr", "localhost:3000", "address the dev server listens on")
+	fs.StringVar(&cfg.root, "root", ".", "directory to watch and build in")
+	ext := fs.String("ext", strings.Join(defaultWatchExts, ","), "comma-separated file extensions that trigger a rebuild")
+	fs.DurationVar(&cfg.interval, "interval", 500*time.Millisecond, "how often to poll for file changes")
+	fs.DurationVar(&cfg.readyTimeout, "ready-timeout", 30*time.Second, "how long a new process has to become ready")
+	fs.DurationVar(&cfg.grace, "grace", 5*time.Second, "how long an old process has to shut down before it is killed")
+	fs.Usage = func() {
+		fmt.Fprintln(out, "Usage: flash dev [flags] [package] [-- app arguments]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	cfg.pkg = "."
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		cfg.pkg = fs.Arg(0)
+	default:
+		fs.Usage()
+		return cfg, errors.New("flash dev: at most one package")
+	}
+	cfg.exts = strings.Split(*ext, ",")
+	return cfg, nil
+}
+
+// supervisor builds, runs and replaces the app process.
+type supervisor struct {
+	cfg    devConfig
+	stdout io.Writer
+	stderr io.Writer
+	proxy  *devProxy
+	binDir string
+	builds int
+	cur    *process
+}
+
+// process is a running app binary.
+type process struct {
+	cmd  *exec.Cmd
+	bin  string
+	done chan struct{} // closed when the process has exited
+	err  error         // exit error, valid after done
+}
+
+func newSupervisor(cfg devConfig, stdout, stderr io.Writer) *supervisor {
+	return &supervisor{cfg: cfg, stdout: stdout, stderr: stderr, proxy: &devProxy{}}
+}
+
+func (s *supervisor) logf(format string, args ...any) {
+	fmt.Fprintf(s.stderr, "flash dev: "+format+"\n", args...)
+}
+
+// run serves the proxy and reloads the app on changes until ctx ends.
+func (s *supervisor) run(ctx context.Context) error {
+	var err error
+	if s.binDir, err = os.MkdirTemp("", "flash-dev-"); err != nil {
+		return err
+	}
+	defer os.RemoveAll(s.binDir)
+
+	ln, err := net.Listen("tcp", s.cfg.addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: s.proxy, ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = srv.Serve(ln) }()
+	s.logf("serving on http://%s", ln.Addr())
+
+	w := newWatcher(s.cfg.root, s.cfg.exts)
+	snap, err := w.scan()
+	if err != nil {
+		_ = srv.Close()
+		return err
+	}
+	s.reload(ctx)
+
+	ticker := time.NewTicker(s.cfg.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if s.cur != nil {
+				s.cur.stop(s.cfg.grace)
+			}
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.grace)
+			defer cancel()
+			_ = srv.Shutdown(shutdownCtx)
+			return nil
+		case <-ticker.C:
+			next, err := w.scan()
+			if err != nil {
+				s.logf("watch: %v", err)
+				continue
+			}
+			if files := changed(snap, next); len(files) > 0 {
+				snap = next
+				if len(files) == 1 {
+					s.logf("%s changed, rebuilding", files[0])
+				} else {
+					s.logf("%s and %d more files changed, rebuilding", files[0], len(files)-1)
+				}
+				s.reload(ctx)
+			}
+		}
+	}
+}
+
+// reload builds the app and, on success, starts the new binary and switches
+// traffic to it before stopping the previous process. On failure the
+// previous process keeps serving.
+func (s *supervisor) reload(ctx context.Context) {
+	start := time.Now()
+	s.builds++
+	bin := filepath.Join(s.binDir, fmt.Sprintf("app-%d", s.builds))
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+	build := exec.CommandContext(ctx, "go", "build", "-o", bin, s.cfg.pkg)
+	build.Dir = s.cfg.root
+	if out, err := build.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		s.proxy.setBuildError(string(out))
+		s.logf("build failed: %v\n%s", err, out)
+		return
+	}
+
+	p, addr, err := s.start(ctx, bin)
+	if err != nil {
+		_ = os.Remove(bin)
+		s.logf("%v", err)
+		return
+	}
+	old := s.cur
+	s.cur = p
+	s.proxy.setTarget(&url.URL{Scheme: "http", Host: addr})
+	s.logf("app ready on %s (%s)", addr, time.Since(start).Round(time.Millisecond))
+	if old != nil {
+		go old.stop(s.cfg.grace)
+	}
+}
+
+// start runs bin and waits for it to report its address with NotifyReady.
+func (s *supervisor) start(ctx context.Context, bin string) (*process, string, error) {
+	notify, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, "", err
+	}
+	defer notify.Close()
+
+	cmd := exec.Command(bin, s.cfg.args...)
+	cmd.Dir = s.cfg.root
+	cmd.Stdout, cmd.Stderr = s.stdout, s.stderr
+	cmd.Env = append(os.Environ(),
+		app.DevAddrEnv+"=127.0.0.1:0",
+		app.DevNotifyEnv+"="+notify.Addr().String(),
+	)
+	if err := cmd.Start(); err != nil {
+		return nil, "", err
+	}
+	p := &process{cmd: cmd, bin: bin, done: make(chan struct{})}
+	go func() {
+		p.err = cmd.Wait()
+		close(p.done)
+	}()
+
+	ready := make(chan string, 1)
+	go func() {
+		conn, err := notify.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if line, err := bufio.NewReader(conn).ReadString('\n'); err == nil {
+			ready <- strings.TrimSpace(line)
+		}
+	}()
+
+	timer := time.NewTimer(s.cfg.readyTimeout)
+	defer timer.Stop()
+	select {
+	case addr := <-ready:
+		return p, addr, nil
+	case <-p.done:
+		return nil, "", fmt.Errorf("app exited before becoming ready: %v", p.err)
+	case <-timer.C:
+		p.stop(0)
+		return nil, "", fmt.Errorf("app did not signal readiness within %s; start it with flash.Serve or call flash.NotifyReady", s.cfg.readyTimeout)
+	case <-ctx.Done():
+		p.stop(0)
+		return nil, "", ctx.Err()
+	}
+}
+
+// stop asks the process to shut down gracefully and kills it after grace,
+// then removes its binary.
+func (p *process) stop(grace time.Duration) {
+	if runtime.GOOS == "windows" || grace <= 0 {
+		_ = p.cmd.Process.Kill()
+	} else {
+		_ = p.cmd.Process.Signal(syscall.SIGTERM)
+	}
+	select {
+	case <-p.done:
+	case <-time.After(grace):
+		_ = p.cmd.Process.Kill()
+		<-p.done
+	}
+	_ = os.Remove(p.bin)
+}
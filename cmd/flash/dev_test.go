@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2/app"
+)
+
+// TestMain lets the test binary act as the supervised app: with
+// FLASH_TEST_APP set it serves a single handler through app.Serve.
+func TestMain(m *testing.M) {
+	switch os.Getenv("FLASH_TEST_APP") {
+	case "":
+		os.Exit(m.Run())
+	case "serve":
+		srv := &http.Server{Addr: "127.0.0.1:0", Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "from child")
+		})}
+		_ = app.Serve(srv)
+	case "exit":
+		os.Exit(3)
+	}
+}
+
+func TestSupervisorStart(t *testing.T) {
+	t.Setenv("FLASH_TEST_APP", "serve")
+	s := newSupervisor(devConfig{root: ".", readyTimeout: 10 * time.Second}, io.Discard, io.Discard)
+	p, addr, err := s.start(context.Background(), os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.bin = "" // the test binary must not be removed
+	defer p.stop(time.Second)
+
+	resp, err := http.Get("http://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "from child" {
+		t.Fatalf("body = %q", body)
+	}
+
+	p.stop(time.Second)
+	select {
+	case <-p.done:
+	default:
+		t.Fatalf("process still running after stop")
+	}
+}
+
+func TestSupervisorStartExited(t *testing.T) {
+	t.Setenv("FLASH_TEST_APP", "exit")
+	s := newSupervisor(devConfig{root: ".", readyTimeout: 10 * time.Second}, io.Discard, io.Discard)
+	_, _, err := s.start(context.Background(), os.Args[0])
+	if err == nil || !strings.Contains(err.Error(), "exited before becoming ready") {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+// Command flash is the development tool of the flash web framework.
+//
+// Usage:
+//
+//	flash dev [flags] [package] [-- app arguments]
+//
+// "flash dev" builds the app's main package (default "."), runs it behind a
+// reverse proxy on -addr and rebuilds it whenever a watched file changes. The
+// new process is started on a fresh port next to the running one; traffic is
+// switched over once it signals readiness and the old process is then asked to
+// shut down gracefully. A failed build keeps the previous process serving and
+// prints the compiler output. WebSocket and other upgraded connections are
+// proxied as well.
+//
+// The app must listen through flash.Serve (or flash.Listen and
+// flash.NotifyReady), which pick up the address and readiness channel the
+// supervisor passes in the FLASH_DEV_ADDR and FLASH_DEV_NOTIFY environment
+// variables and behave as usual otherwise.
+//
+// Install it with:
+//
+//	go install github.com/goflash/flash/v2/cmd/flash@latest
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	switch args[0] {
+	case "dev":
+		cfg, err := parseDevFlags(args[1:], stderr)
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		if err != nil {
+			return 2
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := newSupervisor(cfg, stdout, stderr).run(ctx); err != nil {
+			fmt.Fprintln(stderr, "flash dev:", err)
+			return 1
+		}
+		return 0
+	case "help", "-h", "-help", "--help":
+		usage(stdout)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "flash: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprint(w, `Usage: flash <command> [arguments]
+
+Commands:
+  dev    build, run and live-reload the app on file changes
+
+Run "flash dev -h" for the dev server flags.
+`)
+}
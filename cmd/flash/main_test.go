@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunUsage(t *testing.T) {
+	var out, errOut bytes.Buffer
+	if code := run(nil, &out, &errOut); code != 2 || !strings.Contains(errOut.String(), "Usage: flash") {
+		t.Fatalf("code=%d stderr=%q", code, errOut.String())
+	}
+	errOut.Reset()
+	if code := run([]string{"nope"}, &out, &errOut); code != 2 || !strings.Contains(errOut.String(), `unknown command "nope"`) {
+		t.Fatalf("code=%d stderr=%q", code, errOut.String())
+	}
+	if code := run([]string{"help"}, &out, &errOut); code != 0 || !strings.Contains(out.String(), "dev") {
+		t.Fatalf("code=%d stdout=%q", code, out.String())
+	}
+}
+
+func TestRunDevHelp(t *testing.T) {
+	var out, errOut bytes.Buffer
+	if code := run([]string{"dev", "-h"}, &out, &errOut); code != 0 {
+		t.Fatalf("code = %d", code)
+	}
+	if !strings.Contains(errOut.String(), "-ready-timeout") {
+		t.Fatalf("help = %q", errOut.String())
+	}
+	if code := run([]string{"dev", "-bogus"}, &out, &errOut); code != 2 {
+		t.Fatalf("code = %d", code)
+	}
+}
+
+func TestParseDevFlags(t *testing.T) {
+	var out bytes.Buffer
+	cfg, err := parseDevFlags(nil, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.addr != "localhost:3000" || cfg.pkg != "." || cfg.root != "." || len(cfg.exts) != len(defaultWatchExts) || cfg.grace != 5*time.Second {
+		t.Fatalf("defaults = %+v", cfg)
+	}
+
+	cfg, err = parseDevFlags([]string{"-addr", ":4000", "-ext", "go,tmpl", "./cmd/server", "--", "-port", "1"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.addr != ":4000" || cfg.pkg != "./cmd/server" || strings.Join(cfg.exts, " ") != "go tmpl" || strings.Join(cfg.args, " ") != "-port 1" {
+		t.Fatalf("cfg = %+v", cfg)
+	}
+
+	if _, err := parseDevFlags([]string{"a", "b"}, &out); err == nil {
+		t.Fatalf("expected error for two packages")
+	}
+}
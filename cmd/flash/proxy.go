@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+)
+
+// devProxy forwards requests to the current app process. The target is
+// switched atomically when a rebuilt process becomes ready; requests already
+// in flight, including upgraded WebSocket connections, stay with the process
+// that accepted them until it shuts down.
+type devProxy struct {
+	mu       sync.RWMutex
+	target   *url.URL
+	buildErr string // output of the last failed build, shown while no app runs
+}
+
+func (p *devProxy) setTarget(u *url.URL) {
+	p.mu.Lock()
+	p.target, p.buildErr = u, ""
+	p.mu.Unlock()
+}
+
+func (p *devProxy) setBuildError(out string) {
+	p.mu.Lock()
+	p.buildErr = out
+	p.mu.Unlock()
+}
+
+func (p *devProxy) state() (*url.URL, string) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.target, p.buildErr
+}
+
+func (p *devProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target, buildErr := p.state()
+	if target == nil {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		if buildErr != "" {
+			fmt.Fprintf(w, "<h1>Build failed</h1><pre>%s</pre>", html.EscapeString(buildErr))
+			return
+		}
+		fmt.Fprint(w, "<h1>Starting…</h1><p>flash dev is building the app.</p>")
+		return
+	}
+	rp := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			pr.Out.Host = pr.In.Host
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, "flash dev: app unavailable: "+err.Error(), http.StatusBadGateway)
+		},
+	}
+	rp.ServeHTTP(w, r)
+}
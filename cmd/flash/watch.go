@@ -0,0 +1,98 @@
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fileState is the part of a file's metadata that changes on edits.
+type fileState struct {
+	mod  time.Time
+	size int64
+}
+
+// snapshot maps watched file paths to their state.
+type snapshot map[string]fileState
+
+// watcher finds changed files by polling, which needs no platform-specific
+// notification APIs and copes with editors that replace files on save.
+type watcher struct {
+	root string
+	exts map[string]bool // watched extensions, e.g. ".go"
+	skip []string        // directory names never descended into
+}
+
+// defaultWatchExts are the extensions watched unless -ext is given.
+var defaultWatchExts = []string{".go", ".mod", ".sum", ".html", ".tmpl", ".gohtml", ".css", ".js", ".json", ".yaml", ".yml", ".toml", ".sql"}
+
+func newWatcher(root string, exts []string) *watcher {
+	w := &watcher{root: root, exts: map[string]bool{}, skip: []string{"node_modules", "vendor", "testdata"}}
+	for _, e := range exts {
+		if e = strings.TrimSpace(e); e != "" {
+			if !strings.HasPrefix(e, ".") {
+				e = "." + e
+			}
+			w.exts[e] = true
+		}
+	}
+	return w
+}
+
+// scan returns the state of every watched file under the root. Hidden
+// directories (such as .git) and the skip list are ignored, as are test files,
+// which do not affect the running app.
+func (w *watcher) scan() (snapshot, error) {
+	s := snapshot{}
+	err := filepath.WalkDir(w.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == w.root {
+				return err
+			}
+			return nil // files may disappear while walking
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != w.root && (strings.HasPrefix(name, ".") || w.skipped(name)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !w.exts[filepath.Ext(name)] || strings.HasSuffix(name, "_test.go") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		s[path] = fileState{mod: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	return s, err
+}
+
+func (w *watcher) skipped(dir string) bool {
+	for _, s := range w.skip {
+		if dir == s {
+			return true
+		}
+	}
+	return false
+}
+
+// changed returns the paths added, removed or modified between a and b.
+func changed(a, b snapshot) []string {
+	var out []string
+	for p, st := range b {
+		if old, ok := a[p]; !ok || old != st {
+			out = append(out, p)
+		}
+	}
+	for p := range a {
+		if _, ok := b[p]; !ok {
+			out = append(out, p)
+		}
+	}
+	return out
+}
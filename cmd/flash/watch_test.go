@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, body string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcherScanSkipsIgnoredFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.go"), "package main")
+	writeFile(t, filepath.Join(dir, "views", "index.html"), "<p>")
+	writeFile(t, filepath.Join(dir, "main_test.go"), "package main")
+	writeFile(t, filepath.Join(dir, "README.md"), "#")
+	writeFile(t, filepath.Join(dir, ".git", "x.go"), "")
+	writeFile(t, filepath.Join(dir, "node_modules", "a.js"), "")
+	writeFile(t, filepath.Join(dir, "testdata", "a.go"), "")
+
+	w := newWatcher(dir, []string{".go", "html", " "})
+	s, err := w.scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for p := range s {
+		rel, _ := filepath.Rel(dir, p)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	sort.Strings(got)
+	if len(got) != 2 || got[0] != "main.go" || got[1] != "views/index.html" {
+		t.Fatalf("scan = %v", got)
+	}
+}
+
+func TestWatcherScanMissingRoot(t *testing.T) {
+	if _, err := newWatcher(filepath.Join(t.TempDir(), "nope"), defaultWatchExts).scan(); err == nil {
+		t.Fatalf("expected error for missing root")
+	}
+}
+
+func TestChanged(t *testing.T) {
+	now := time.Now()
+	a := snapshot{"a.go": {mod: now, size: 1}, "b.go": {mod: now, size: 1}, "c.go": {mod: now, size: 1}}
+	b := snapshot{"a.go": {mod: now, size: 1}, "b.go": {mod: now.Add(time.Second), size: 1}, "d.go": {mod: now, size: 1}}
+	got := changed(a, b)
+	sort.Strings(got)
+	if len(got) != 3 || got[0] != "b.go" || got[1] != "c.go" || got[2] != "d.go" {
+		t.Fatalf("changed = %v", got)
+	}
+	if got := changed(a, a); len(got) != 0 {
+		t.Fatalf("no changes expected, got %v", got)
+	}
+}
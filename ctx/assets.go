@@ -0,0 +1,26 @@
+package ctx
+
+// AssetResolver maps logical asset names such as "app.js" to the URL paths of
+// their fingerprinted files. app.Assets implements it.
+type AssetResolver interface {
+	AssetPath(name string) string
+}
+
+// SetAssets sets the resolver used by AssetPath. Used internally by the app
+// to apply DefaultApp.Assets.
+func (c *DefaultContext) SetAssets(r AssetResolver) { c.assets = r }
+
+// AssetPath returns the URL path of the fingerprinted asset name, e.g.
+// "/assets/app.3f9a1c2b7d.js" for "app.js", so pages can reference assets that
+// are cached forever and still pick up new versions. Without assets
+// configured on the app it returns name unchanged.
+//
+// Example:
+//
+//	c.Header("Link", "<"+c.AssetPath("app.css")+">; rel=preload; as=style")
+func (c *DefaultContext) AssetPath(name string) string {
+	if c.assets == nil {
+		return name
+	}
+	return c.assets.AssetPath(name)
+}
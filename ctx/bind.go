@@ -1,11 +1,15 @@
 package ctx
 
 import (
+	"bytes"
 	"encoding/json"
+	"io"
 	"mime"
+	"net/http"
 	"net/url"
 	"reflect"
 	"strings"
+	"sync"
 
 	ms "github.com/mitchellh/mapstructure"
 )
@@ -19,6 +23,9 @@ var newMSDecoder = ms.NewDecoder
 //   - ErrorUnused = true  (unknown fields cause an error)
 //   - WeaklyTypedInput = false (no implicit type coercion)
 //
+// These defaults can be replaced for the whole app or for a group of routes via
+// App.SetBindDefaults and Group.SetBindDefaults.
+//
 // If an options value is provided explicitly, its zero-values are honored as-is.
 //
 // Example (strict decoding, reject unknown fields):
@@ -38,11 +45,94 @@ var newMSDecoder = ms.NewDecoder
 //
 //	var u User
 //	_ = c.BindJSON(&u, BindJSONOptions{WeaklyTypedInput: true, ErrorUnused: false})
+//
+// Example (dates, decimals and custom types):
+//
+//	type Order struct {
+//		Placed time.Time       `json:"placed"` // "2024-01-02" accepted with weak typing or TimeLayouts
+//		Total  decimal.Decimal `json:"total"`  // any encoding.TextUnmarshaler binds from strings/numbers
+//	}
+//	var o Order
+//	_ = c.BindJSON(&o, BindJSONOptions{
+//		ErrorUnused: true,
+//		TimeLayouts: []string{time.DateOnly},
+//		UseNumber:   true, // keep full decimal precision
+//	})
+//
+// Example (guard against resource exhaustion):
+//
+//	err := c.BindJSON(&o, BindJSONOptions{ErrorUnused: true, MaxBodyBytes: 1 << 20, MaxDepth: 32, MaxKeys: 1000})
+//	// oversized => *HTTPError{Code: 413}; too deep / too many keys => *HTTPError{Code: 422}
 type BindJSONOptions struct {
 	// WeaklyTypedInput allows common type coercions, e.g., "10" -> 10 for int fields.
+	// It also enables lenient date parsing ("2024-01-02", "2024-01-02 15:04:05") for
+	// time.Time fields.
 	WeaklyTypedInput bool
 	// ErrorUnused when true returns an error for unexpected fields.
 	ErrorUnused bool
+	// ErrorUnset when true reports struct fields missing from the input as
+	// "required" field errors. Every field of the target must then be present.
+	ErrorUnset bool
+	// TimeLayouts lists additional layouts tried when binding strings into time.Time
+	// fields. RFC 3339 is always accepted.
+	TimeLayouts []string
+	// DecodeHooks run before the built-in conversions and can bind custom types.
+	// Types implementing encoding.TextUnmarshaler are handled without a hook.
+	DecodeHooks []DecodeHook
+	// UseNumber decodes JSON numbers as json.Number instead of float64, preserving
+	// precision for large integers and decimal types.
+	UseNumber bool
+	// Stream decodes struct targets directly from the body with encoding/json
+	// also when ErrorUnused is false, skipping the intermediate map. It only
+	// takes effect when no flexible options (WeaklyTypedInput, ErrorUnset,
+	// DecodeHooks, TimeLayouts) are set. Strict binding (ErrorUnused without
+	// flexible options) streams automatically for bodies over 64 KiB or of
+	// unknown length; when such a body fails after its first 64 KiB, only the
+	// first error is reported. Permissive streaming follows encoding/json
+	// rules, which differ from the map path for duplicate keys differing in
+	// case.
+	Stream bool
+	// MaxBodyBytes caps the JSON body size. Larger bodies fail with a 413 *HTTPError
+	// wrapping ErrBodyTooLarge. Zero means no limit.
+	MaxBodyBytes int64
+	// MaxDepth caps object/array nesting. Deeper documents fail with a 422 *HTTPError
+	// wrapping ErrJSONTooDeep. Zero means no limit.
+	MaxDepth int
+	// MaxKeys caps the total number of object keys in the document. Exceeding it fails
+	// with a 422 *HTTPError wrapping ErrJSONTooManyKeys. Zero means no limit.
+	MaxKeys int
+	// Uploads, when set, makes BindForm and BindAny validate multipart files
+	// against the policy; violations are reported with the binding's own
+	// FieldErrors.
+	Uploads *UploadPolicy
+	// KeyProviders opens struct fields tagged `seal:"<name>"`, keyed by name.
+	// Entries take precedence over providers registered with
+	// RegisterKeyProvider.
+	KeyProviders map[string]KeyProvider
+}
+
+// SetBindDefaults sets the binding options used when Bind* helpers are called
+// without explicit options. Passing nil restores the built-in defaults
+// (ErrorUnused = true, WeaklyTypedInput = false). The app calls this for every
+// request based on App.SetBindDefaults and Group.SetBindDefaults; handlers rarely
+// need to call it directly.
+//
+// Example:
+//
+//	c.SetBindDefaults(&ctx.BindJSONOptions{WeaklyTypedInput: true, ErrorUnused: true})
+//	_ = c.BindQuery(&q) // "2" binds into int fields
+func (c *DefaultContext) SetBindDefaults(o *BindJSONOptions) { c.bindOpts = o }
+
+// bindOptions resolves the effective options: explicit options win, then the
+// route defaults, then the built-in strict defaults.
+func (c *DefaultContext) bindOptions(opts []BindJSONOptions) BindJSONOptions {
+	if len(opts) > 0 {
+		return opts[0]
+	}
+	if c.bindOpts != nil {
+		return *c.bindOpts
+	}
+	return BindJSONOptions{ErrorUnused: true}
 }
 
 // BindJSON decodes the request body JSON into v.
@@ -58,6 +148,15 @@ type BindJSONOptions struct {
 // Field error mapping: common json.Decoder errors are converted into user-friendly
 // FieldErrors keyed by the offending json field.
 //
+// Decoding paths: struct targets are normally collected into a map and bound with
+// mapstructure, which enables coercion, hooks and reporting every unknown field.
+// When no flexible options are set, the body is decoded straight into v with
+// encoding/json instead, avoiding the intermediate map and the second pass over
+// the data: always with Stream, and with ErrorUnused (the default) for bodies
+// larger than 64 KiB or of unknown length. Unknown fields are then rejected with
+// DisallowUnknownFields. Targets with sealed fields (see RegisterKeyProvider) or
+// embedded structs always take the map path, so both paths bind the same fields.
+//
 // Examples:
 //
 //	// 1) Strict struct binding
@@ -77,25 +176,199 @@ type BindJSONOptions struct {
 //	_ = c.BindJSON(&m) // uses DisallowUnknownFields and returns raw json errors
 func (c *DefaultContext) BindJSON(v any, opts ...BindJSONOptions) error {
 	// Non-struct targets: keep strict json decoder behavior regardless of options.
+	o := c.bindOptions(opts)
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
-		defer c.r.Body.Close()
-		dec := json.NewDecoder(c.r.Body)
+		return c.decodeJSONStream(v, o, true, nil)
+	}
+	// Struct targets without flexible options decode straight from the body.
+	if o.streamable() && streamableType(rv.Elem().Type()) {
+		switch {
+		case !o.ErrorUnused:
+			return c.decodeJSONStream(v, o, false, rv.Elem().Type())
+		case o.Stream || c.r.ContentLength < 0 || c.r.ContentLength > strictStreamMin:
+			return c.bindJSONStrict(v, rv.Elem(), o)
+		}
+	}
+	// For struct targets, collect to map and delegate to BindMap for consistent behavior.
+	m, err := c.collectJSONMap(o)
+	if err != nil {
+		return err
+	}
+	return c.BindMap(v, m, o)
+}
+
+// streamable reports whether o can be served by decoding straight into the
+// target with encoding/json instead of going through a map and mapstructure.
+func (o BindJSONOptions) streamable() bool {
+	if o.WeaklyTypedInput || o.ErrorUnset || len(o.DecodeHooks) > 0 || len(o.TimeLayouts) > 0 {
+		return false
+	}
+	return o.Stream || o.ErrorUnused
+}
+
+// streamableTypes caches streamableType by struct type.
+var streamableTypes sync.Map // reflect.Type -> bool
+
+// streamableType reports whether struct type t binds the same with
+// encoding/json as with mapstructure: it has no sealed fields and no embedded
+// structs, which encoding/json flattens but mapstructure binds by type name.
+func streamableType(t reflect.Type) bool {
+	if ok, found := streamableTypes.Load(t); found {
+		return ok.(bool)
+	}
+	ok := sealPlanFor(t) == nil && !hasEmbeddedStruct(t, map[reflect.Type]bool{})
+	streamableTypes.Store(t, ok)
+	return ok
+}
+
+// hasEmbeddedStruct reports whether t, or a type reachable from its fields,
+// embeds a struct.
+func hasEmbeddedStruct(t reflect.Type, seen map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return false
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && ft.Kind() == reflect.Struct {
+			return true
+		}
+		if f.IsExported() && hasEmbeddedStruct(f.Type, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeJSONStream decodes the body directly into v with a json.Decoder, honoring
+// body guards and UseNumber. targetType (optional) improves type error messages.
+func (c *DefaultContext) decodeJSONStream(v any, o BindJSONOptions, disallowUnknown bool, targetType reflect.Type) error {
+	defer c.r.Body.Close()
+	body, err := c.jsonBody(o)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(body)
+	if disallowUnknown {
 		dec.DisallowUnknownFields()
-		if err := dec.Decode(v); err != nil {
-			if fErr := mapJSONStrictError(err, reflect.TypeOf(nil)); fErr != nil { // no struct type context
-				return fErr
-			}
-			return err
+	}
+	if o.UseNumber {
+		dec.UseNumber()
+	}
+	if err := dec.Decode(v); err != nil {
+		if mErr := mapBodyReadError(err); mErr != err {
+			return mErr
+		}
+		if fErr := mapJSONStrictError(err, targetType); fErr != nil {
+			return fErr
 		}
+		return err
+	}
+	return nil
+}
+
+// strictStreamMin is the body size above which strict binding streams. It
+// also bounds the prefix bindJSONStrict keeps to report errors in full.
+const strictStreamMin = 64 << 10
+
+// bindJSONStrict decodes the body straight into the struct target with
+// DisallowUnknownFields. Valid input takes a single pass with no intermediate
+// map, keeping at most the first strictStreamMin bytes. On a decode error
+// target is restored and, if the bytes read so far were kept, the body is
+// bound through the map path, so errors are reported in full and exactly as
+// there; otherwise the decoder's error is reported.
+func (c *DefaultContext) bindJSONStrict(v any, target reflect.Value, o BindJSONOptions) error {
+	defer c.r.Body.Close()
+	body, err := c.jsonBody(o)
+	if err != nil {
+		return err
+	}
+	orig := reflect.New(target.Type()).Elem()
+	orig.Set(target)
+	read := prefixBuffer{max: strictStreamMin}
+	dec := json.NewDecoder(io.TeeReader(body, &read))
+	dec.DisallowUnknownFields()
+	if o.UseNumber {
+		dec.UseNumber()
+	}
+	err = dec.Decode(v)
+	if err == nil {
 		return nil
 	}
-	// For struct targets, collect to map and delegate to BindMap for consistent behavior.
-	m, err := c.collectJSONMap()
+	if mErr := mapBodyReadError(err); mErr != err {
+		return mErr
+	}
+	target.Set(orig)
+	if read.overflow {
+		if fErr := mapJSONStrictError(err, target.Type()); fErr != nil {
+			return fErr
+		}
+		return err
+	}
+	m, err := decodeJSONMap(io.MultiReader(&read.buf, body), o)
 	if err != nil {
 		return err
 	}
-	return c.BindMap(v, m, opts...)
+	return c.BindMap(v, m, o)
+}
+
+// prefixBuffer records what is written to it until more than max bytes were
+// written; then it drops the data and sets overflow.
+type prefixBuffer struct {
+	buf      bytes.Buffer
+	max      int
+	overflow bool
+}
+
+func (b *prefixBuffer) Write(p []byte) (int, error) {
+	if b.overflow {
+		return len(p), nil
+	}
+	if b.buf.Len()+len(p) > b.max {
+		b.overflow = true
+		b.buf = bytes.Buffer{}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// JSONDecoder returns a json.Decoder reading the request body, for handlers that
+// need token-level control (e.g., streaming a large top-level array). The route's
+// binding defaults are honored for MaxBodyBytes and UseNumber; other guards do not
+// apply because nothing is buffered.
+//
+// Example:
+//
+//	dec := c.JSONDecoder()
+//	if _, err := dec.Token(); err != nil { // opening '['
+//		return err
+//	}
+//	for dec.More() {
+//		var item Item
+//		if err := dec.Decode(&item); err != nil {
+//			return err
+//		}
+//		process(item)
+//	}
+func (c *DefaultContext) JSONDecoder() *json.Decoder {
+	o := c.bindOptions(nil)
+	var body io.Reader = c.r.Body
+	if o.MaxBodyBytes > 0 {
+		body = http.MaxBytesReader(c.w, c.r.Body, o.MaxBodyBytes)
+	}
+	dec := json.NewDecoder(body)
+	if o.UseNumber {
+		dec.UseNumber()
+	}
+	return dec
 }
 
 // BindMap binds fields from the provided map into v using mapstructure, honoring options.
@@ -122,12 +395,7 @@ func (c *DefaultContext) BindJSON(v any, opts ...BindJSONOptions) error {
 //		// err can be converted to FieldErrors indicating "extra" is unexpected
 //	}
 func (c *DefaultContext) BindMap(v any, m map[string]any, opts ...BindJSONOptions) error {
-	var o BindJSONOptions
-	if len(opts) > 0 {
-		o = opts[0]
-	} else {
-		o.ErrorUnused = true
-	}
+	o := c.bindOptions(opts)
 
 	// Target struct type for better error messages
 	var targetType reflect.Type
@@ -136,29 +404,50 @@ func (c *DefaultContext) BindMap(v any, m map[string]any, opts ...BindJSONOption
 		targetType = rv.Elem().Type()
 	}
 
+	// Open sealed fields first; failures are reported with the binding errors.
+	// Error values come from the original input so plaintext never leaks.
+	var sealErr error
+	orig := m
+	if targetType != nil {
+		if p := sealPlanFor(targetType); p != nil {
+			errs := fieldErrorsMap{m: map[string]string{}, codes: map[string]string{}}
+			var err error
+			if m, err = c.unseal(p, m, o, "", errs); err != nil {
+				return err
+			}
+			if len(errs.m) > 0 {
+				sealErr = errs
+			}
+		}
+	}
+
 	cfg := &ms.DecoderConfig{
 		TagName:          "json",
 		Result:           v,
 		WeaklyTypedInput: o.WeaklyTypedInput,
 		ErrorUnused:      o.ErrorUnused,
+		ErrorUnset:       o.ErrorUnset,
+		DecodeHook:       buildDecodeHook(o),
 	}
 	dec, err := newMSDecoder(cfg)
 	if err != nil {
 		return err
 	}
 	if err := dec.Decode(m); err != nil {
-		if fe := mapMapStructureError(err, o, targetType); fe != nil {
-			return fe
+		fe := mapMapStructureError(err, o, targetType)
+		if fm, ok := fe.(fieldErrorsMap); ok {
+			return mergeFieldErrors(fm.withValues(orig), sealErr)
 		}
-		return err
+		return fe
 	}
-	return nil
+	return sealErr
 }
 
 // BindForm collects form body fields and binds them into v.
 // Supports application/x-www-form-urlencoded and multipart/form-data (textual fields only).
 //
-// For multipart/form-data, file uploads are ignored here; only textual values are bound.
+// For multipart/form-data, only textual values are bound; files are checked
+// against BindJSONOptions.Uploads when set.
 //
 // Examples:
 //
@@ -175,7 +464,17 @@ func (c *DefaultContext) BindForm(v any, opts ...BindJSONOptions) error {
 	if err != nil {
 		return err
 	}
-	return c.BindMap(v, m, opts...)
+	return c.checkUploads(c.BindMap(v, m, opts...), opts)
+}
+
+// checkUploads validates files against the effective Uploads policy and merges
+// the result with the binding error err.
+func (c *DefaultContext) checkUploads(err error, opts []BindJSONOptions) error {
+	o := c.bindOptions(opts)
+	if o.Uploads == nil {
+		return err
+	}
+	return mergeFieldErrors(err, c.ValidateUploads(*o.Uploads))
 }
 
 // BindQuery collects query string parameters and binds them into v.
@@ -227,7 +526,7 @@ func (c *DefaultContext) BindPath(v any, opts ...BindJSONOptions) error {
 //	// Body: name="A" (form) and {"name":"B"} (json) => name becomes "B"
 func (c *DefaultContext) BindAny(v any, opts ...BindJSONOptions) error {
 	// Pre-size map to reduce growth rehashing
-	est := len(c.r.URL.Query()) + len(c.params)
+	est := len(c.queryValues()) + len(c.params)
 	if c.r.PostForm != nil {
 		est += len(c.r.PostForm)
 	}
@@ -248,7 +547,7 @@ func (c *DefaultContext) BindAny(v any, opts ...BindJSONOptions) error {
 		}
 	}
 	if strings.Contains(mediaType, "+json") || mediaType == "application/json" {
-		jm, err := c.collectJSONMap()
+		jm, err := c.collectJSONMap(c.bindOptions(opts))
 		if err != nil {
 			return err
 		}
@@ -258,33 +557,38 @@ func (c *DefaultContext) BindAny(v any, opts ...BindJSONOptions) error {
 	// Highest: Path
 	c.collectPathInto(out)
 
-	return c.BindMap(v, out, opts...)
+	return c.checkUploads(c.BindMap(v, out, opts...), opts)
 }
 
 // collectJSONMap reads body and parses into map[string]any. Honors default strictness at BindMap stage.
-func (c *DefaultContext) collectJSONMap() (map[string]any, error) {
+// Body guards (MaxBodyBytes, MaxDepth, MaxKeys) and UseNumber are taken from o.
+func (c *DefaultContext) collectJSONMap(o BindJSONOptions) (map[string]any, error) {
 	defer c.r.Body.Close()
+	body, err := c.jsonBody(o)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSONMap(body, o)
+}
+
+// decodeJSONMap decodes the JSON object read from body into a map.
+func decodeJSONMap(body io.Reader, o BindJSONOptions) (map[string]any, error) {
 	var m map[string]any
-	dec := json.NewDecoder(c.r.Body)
+	dec := json.NewDecoder(body)
+	if o.UseNumber {
+		dec.UseNumber()
+	}
 	if err := dec.Decode(&m); err != nil {
-		return nil, err
+		return nil, mapBodyReadError(err)
 	}
 	return m, nil
 }
 
 // collectFormMap parses the request form and returns a map[string]any using first value per key.
 func (c *DefaultContext) collectFormMap() (map[string]any, error) {
-	// ParseForm handles both x-www-form-urlencoded and multipart/form-data
-	if err := c.r.ParseForm(); err != nil {
+	if err := c.parseForm(); err != nil {
 		return nil, err
 	}
-	// For multipart/form-data, ensure MultipartForm is populated
-	if ct := c.r.Header.Get("Content-Type"); strings.HasPrefix(ct, "multipart/") && c.r.MultipartForm == nil {
-		// Use a reasonable default memory limit similar to net/http server
-		if err := c.r.ParseMultipartForm(32 << 20); err != nil { // 32 MB
-			return nil, err
-		}
-	}
 	// Prefer PostForm values; also include multipart textual values
 	out := valuesToMap(c.r.PostForm)
 	if c.r.MultipartForm != nil && c.r.MultipartForm.Value != nil {
@@ -300,14 +604,39 @@ func (c *DefaultContext) collectFormMap() (map[string]any, error) {
 	return out, nil
 }
 
+// parseForm parses the request form, and the multipart form of multipart
+// bodies, once per request. The first result is remembered: net/http leaves an
+// empty PostForm behind after a failed parse, so parsing again would report
+// success with no values.
+func (c *DefaultContext) parseForm() error {
+	if c.formParsed {
+		return c.formErr
+	}
+	c.formParsed = true
+	// ParseForm handles both x-www-form-urlencoded and multipart/form-data
+	if err := c.r.ParseForm(); err != nil {
+		c.formErr = err
+		return err
+	}
+	// For multipart/form-data, ensure MultipartForm is populated
+	if ct := c.r.Header.Get("Content-Type"); strings.HasPrefix(ct, "multipart/") && c.r.MultipartForm == nil {
+		// Use a reasonable default memory limit similar to net/http server
+		if err := c.r.ParseMultipartForm(32 << 20); err != nil { // 32 MB
+			c.formErr = err
+			return err
+		}
+	}
+	return nil
+}
+
 // collectQueryMap returns a map from URL query parameters (first value per key).
 func (c *DefaultContext) collectQueryMap() map[string]any {
-	return valuesToMap(c.r.URL.Query())
+	return valuesToMap(c.queryValues())
 }
 
 // collectQueryInto writes first query values into dst (no intermediate map).
 func (c *DefaultContext) collectQueryInto(dst map[string]any) {
-	for k, vals := range c.r.URL.Query() {
+	for k, vals := range c.queryValues() {
 		if len(vals) > 0 {
 			dst[k] = vals[0]
 		}
@@ -332,14 +661,9 @@ func (c *DefaultContext) collectPathInto(dst map[string]any) {
 
 // collectFormInto parses the form and writes first values into dst (no intermediate map).
 func (c *DefaultContext) collectFormInto(dst map[string]any) error {
-	if err := c.r.ParseForm(); err != nil {
+	if err := c.parseForm(); err != nil {
 		return err
 	}
-	if ct := c.r.Header.Get("Content-Type"); strings.HasPrefix(ct, "multipart/") && c.r.MultipartForm == nil {
-		if err := c.r.ParseMultipartForm(32 << 20); err != nil { // 32 MB
-			return err
-		}
-	}
 	for k, vals := range c.r.PostForm {
 		if len(vals) > 0 {
 			dst[k] = vals[0]
@@ -434,57 +758,106 @@ func tryJSONTypeErrorToField(err error, targetType reflect.Type) error {
 }
 
 // mapMapStructureError converts map structure errors into FieldErrors with friendly messages.
+// Every bullet of a multi-error is inspected so that all failing fields are reported,
+// using dotted paths for nested input (e.g., "items[2].price").
 func mapMapStructureError(err error, o BindJSONOptions, targetType reflect.Type) error {
-	// Map structure may return a multi-error string; handle key cases.
 	s := err.Error()
-	// Unknown field when ErrorUnused is true: "has invalid keys: asdf, ..."
-	if o.ErrorUnused {
-		if strings.Contains(s, "has invalid keys:") {
-			// Extract only the comma-separated keys on the same line as the marker.
-			marker := "has invalid keys:"
-			idx := strings.Index(s, marker)
-			if idx != -1 {
-				list := s[idx+len(marker):]
-				// Cut at first newline to avoid pulling in subsequent error bullets.
-				if nl := strings.IndexByte(list, '\n'); nl != -1 {
-					list = list[:nl]
-				}
-				// Normalize whitespace and bullets
-				list = strings.TrimSpace(list)
-				// Split by comma and trim punctuation/whitespace around keys
-				parts := strings.Split(list, ",")
-				fe := map[string]string{}
-				for _, p := range parts {
-					k := strings.TrimSpace(p)
-					// remove any leading bullet or quotes
-					k = strings.TrimLeft(k, "* '`\"")
-					// strip trailing punctuation if present
-					k = strings.Trim(k, "'`\" .;:")
-					if k != "" {
-						fe[k] = ErrFieldUnexpected.Error()
-					}
-				}
-				if len(fe) > 0 {
-					return fieldErrorsFromMap(fe)
+	lines := []string{s}
+	if strings.Contains(s, "error(s) decoding:") {
+		lines = strings.Split(s, "\n")
+	}
+	fe := map[string]string{}
+	for _, line := range lines {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "* "))
+		if line == "" || strings.HasSuffix(line, "error(s) decoding:") {
+			continue
+		}
+		// Unknown field when ErrorUnused is true: "'<parent>' has invalid keys: asdf, ..."
+		if o.ErrorUnused {
+			if keys, ok := parseMapStructureKeyList(line, "has invalid keys:", targetType); ok {
+				for _, k := range keys {
+					fe[k] = ErrFieldUnexpected.Error()
 				}
+				continue
 			}
 		}
-	}
-	// Type mismatch when WeaklyTypedInput is false. map structure reports e.g.:
-	// "cannot decode 'age' from string into int"
-	if !o.WeaklyTypedInput {
-		if field, ok := extractFieldFromMapStructureTypeError(s); ok {
-			if targetType != nil {
-				if ft, ok2 := findExpectedFieldType(targetType, field); ok2 {
-					return fieldErrorsFromMap(map[string]string{field: expectedTypeLabel(ft) + " " + ErrFieldTypeExpected.Error()})
+		// Missing field when ErrorUnset is true: "'<parent>' has unset fields: name, ..."
+		if o.ErrorUnset {
+			if keys, ok := parseMapStructureKeyList(line, "has unset fields:", targetType); ok {
+				for _, k := range keys {
+					fe[k] = ErrFieldRequired.Error()
 				}
+				continue
 			}
-			return fieldErrorsFromMap(map[string]string{field: ErrFieldInvalidType.Error()})
 		}
+		// Decode hook failures (time layouts, TextUnmarshaler, custom hooks):
+		// "error decoding 'when': cannot parse ..."
+		if field, ok := extractFieldFromHookError(line); ok {
+			fe[field] = typeMismatchMessage(targetType, field)
+			continue
+		}
+		// Type mismatch when WeaklyTypedInput is false. map structure reports e.g.:
+		// "cannot decode 'age' from string into int"
+		if !o.WeaklyTypedInput {
+			if field, ok := extractFieldFromMapStructureTypeError(line); ok {
+				fe[field] = typeMismatchMessage(targetType, field)
+			}
+		}
+	}
+	if len(fe) > 0 {
+		return fieldErrorsFromMap(fe)
 	}
 	return err
 }
 
+// parseMapStructureKeyList parses "'<parent>' <marker> a, b" into field paths.
+// Keys are qualified with their parent path when the parent is a nested field.
+func parseMapStructureKeyList(line, marker string, targetType reflect.Type) ([]string, bool) {
+	idx := strings.Index(line, marker)
+	if idx == -1 {
+		return nil, false
+	}
+	parent := strings.Trim(strings.TrimSpace(line[:idx]), "'`\"")
+	// Normalize whitespace and bullets, then split by comma and trim punctuation/whitespace around keys
+	list := strings.TrimSpace(line[idx+len(marker):])
+	var keys []string
+	for _, p := range strings.Split(list, ",") {
+		k := strings.TrimSpace(p)
+		// remove any leading bullet or quotes
+		k = strings.TrimLeft(k, "* '`\"")
+		// strip trailing punctuation if present
+		k = strings.Trim(k, "'`\" .;:")
+		if k == "" {
+			continue
+		}
+		keys = append(keys, qualifyFieldPath(parent, k, targetType))
+	}
+	return keys, len(keys) > 0
+}
+
+// qualifyFieldPath joins parent and key unless parent is the decoding root.
+func qualifyFieldPath(parent, key string, targetType reflect.Type) string {
+	if parent == "" {
+		return key
+	}
+	if strings.ContainsAny(parent, ".[") {
+		return parent + "." + key
+	}
+	if _, ok := findExpectedFieldPath(targetType, parent); ok {
+		return parent + "." + key
+	}
+	return key
+}
+
+// typeMismatchMessage returns "<type> type expected" when the field type is known,
+// otherwise the generic invalid type message.
+func typeMismatchMessage(targetType reflect.Type, field string) string {
+	if ft, ok := findExpectedFieldPath(targetType, field); ok {
+		return expectedTypeLabel(ft) + " " + ErrFieldTypeExpected.Error()
+	}
+	return ErrFieldInvalidType.Error()
+}
+
 // extractFieldFromMapStructureTypeError extracts the field name from a map structure type error string.
 func extractFieldFromMapStructureTypeError(s string) (string, bool) {
 	if strings.HasPrefix(s, " error(s) decoding:") {
@@ -533,6 +906,56 @@ func extractFieldFromMapStructureTypeError(s string) (string, bool) {
 	return field, true
 }
 
+// extractFieldFromHookError extracts the field name from a map structure decode hook error.
+func extractFieldFromHookError(s string) (string, bool) {
+	const marker = "error decoding '"
+	i := strings.Index(s, marker)
+	if i == -1 {
+		return "", false
+	}
+	s = s[i+len(marker):]
+	end := strings.IndexByte(s, '\'')
+	if end <= 0 {
+		return "", false
+	}
+	return s[:end], true
+}
+
+// findExpectedFieldPath resolves a dotted/indexed field path ("items[2].price")
+// against t, descending through pointers, slices, arrays and maps.
+func findExpectedFieldPath(t reflect.Type, path string) (reflect.Type, bool) {
+	if t == nil {
+		return nil, false
+	}
+	cur := t
+	for _, seg := range strings.Split(path, ".") {
+		name, depth := seg, 0
+		if i := strings.IndexByte(seg, '['); i >= 0 {
+			name, depth = seg[:i], strings.Count(seg[i:], "[")
+		}
+		for cur.Kind() == reflect.Pointer {
+			cur = cur.Elem()
+		}
+		ft, ok := findExpectedFieldType(cur, name)
+		if !ok {
+			return nil, false
+		}
+		cur = ft
+		for ; depth > 0; depth-- {
+			for cur.Kind() == reflect.Pointer {
+				cur = cur.Elem()
+			}
+			switch cur.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map:
+				cur = cur.Elem()
+			default:
+				return nil, false
+			}
+		}
+	}
+	return cur, true
+}
+
 // findExpectedFieldType finds the struct field type by matching json tag name (or field name if no tag).
 func findExpectedFieldType(t reflect.Type, jsonField string) (reflect.Type, bool) {
 	if t == nil || t.Kind() != reflect.Struct {
@@ -567,6 +990,9 @@ func expectedTypeLabel(t reflect.Type) string {
 	for t.Kind() == reflect.Pointer {
 		t = t.Elem()
 	}
+	if t == timeType {
+		return "time"
+	}
 	switch t.Kind() {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		return "int"
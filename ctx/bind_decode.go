@@ -0,0 +1,124 @@
+package ctx
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	ms "github.com/mitchellh/mapstructure"
+)
+
+// DecodeHook converts an input value before it is assigned to a field of type to.
+//
+// Hooks receive the source type, the destination type and the raw value and must
+// return either the (possibly converted) value or an error. Returning data unchanged
+// lets the next hook (or the default decoder) handle it. Hook errors are reported as
+// FieldErrors keyed by the offending field.
+//
+// Example (bind a custom Money type from "12.50 EUR"):
+//
+//	moneyHook := func(from, to reflect.Type, data any) (any, error) {
+//		if to != reflect.TypeOf(Money{}) {
+//			return data, nil
+//		}
+//		s, ok := data.(string)
+//		if !ok {
+//			return data, nil
+//		}
+//		return ParseMoney(s)
+//	}
+//	_ = c.BindJSON(&in, ctx.BindJSONOptions{ErrorUnused: true, DecodeHooks: []ctx.DecodeHook{moneyHook}})
+type DecodeHook = func(from, to reflect.Type, data any) (any, error)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	jsonNumberType      = reflect.TypeOf(json.Number(""))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// weakTimeLayouts are accepted in addition to RFC 3339 when WeaklyTypedInput is enabled.
+var weakTimeLayouts = []string{time.DateOnly, time.DateTime}
+
+// buildDecodeHook composes user hooks with the built-in time and text hooks.
+// User hooks run first so they can take precedence over the built-in conversions.
+func buildDecodeHook(o BindJSONOptions) ms.DecodeHookFunc {
+	layouts := make([]string, 0, 1+len(o.TimeLayouts)+len(weakTimeLayouts))
+	layouts = append(layouts, time.RFC3339Nano)
+	layouts = append(layouts, o.TimeLayouts...)
+	if o.WeaklyTypedInput {
+		layouts = append(layouts, weakTimeLayouts...)
+	}
+
+	hooks := make([]ms.DecodeHookFunc, 0, len(o.DecodeHooks)+3)
+	for _, h := range o.DecodeHooks {
+		if h != nil {
+			hooks = append(hooks, ms.DecodeHookFuncType(h))
+		}
+	}
+	hooks = append(hooks,
+		ms.DecodeHookFuncType(timeDecodeHook(layouts, o.WeaklyTypedInput)),
+		ms.DecodeHookFuncType(textUnmarshalerHook),
+		ms.DecodeHookFuncType(jsonNumberHook(o.WeaklyTypedInput)),
+	)
+	return ms.ComposeDecodeHookFunc(hooks...)
+}
+
+// timeDecodeHook parses strings into time.Time using the given layouts in order.
+func timeDecodeHook(layouts []string, weak bool) DecodeHook {
+	return func(_, to reflect.Type, data any) (any, error) {
+		if to != timeType {
+			return data, nil
+		}
+		s, ok := data.(string)
+		if !ok {
+			return data, nil
+		}
+		if s == "" && weak {
+			return time.Time{}, nil
+		}
+		for _, layout := range layouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("cannot parse %q as time", s)
+	}
+}
+
+// textUnmarshalerHook binds string and numeric input into types implementing
+// encoding.TextUnmarshaler (e.g., uuid.UUID, decimal.Decimal, net.IP).
+func textUnmarshalerHook(_, to reflect.Type, data any) (any, error) {
+	if to == timeType || !reflect.PointerTo(to).Implements(textUnmarshalerType) {
+		return data, nil
+	}
+	var text string
+	switch v := data.(type) {
+	case string:
+		text = v
+	case json.Number:
+		text = v.String()
+	case float64:
+		text = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return data, nil
+	}
+	out := reflect.New(to)
+	if err := out.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text)); err != nil {
+		return nil, err
+	}
+	return out.Elem().Interface(), nil
+}
+
+// jsonNumberHook rejects json.Number input for string fields unless weak typing is on,
+// keeping UseNumber from silently relaxing strict binding.
+func jsonNumberHook(weak bool) DecodeHook {
+	return func(from, to reflect.Type, data any) (any, error) {
+		if weak || from != jsonNumberType || to.Kind() != reflect.String {
+			return data, nil
+		}
+		return nil, fmt.Errorf("number cannot be decoded into %s", to)
+	}
+}
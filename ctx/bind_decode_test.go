@@ -0,0 +1,170 @@
+package ctx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cents is a minimal decimal-like type bound via encoding.TextUnmarshaler.
+type cents int64
+
+func (c *cents) UnmarshalText(b []byte) error {
+	var whole, frac int64
+	if _, err := fmt.Sscanf(string(b), "%d.%d", &whole, &frac); err != nil {
+		return err
+	}
+	*c = cents(whole*100 + frac)
+	return nil
+}
+
+func bindJSONBody(t *testing.T, body string, v any, opts ...BindJSONOptions) error {
+	t.Helper()
+	req, rec := newRequest(http.MethodPost, "/", strings.NewReader(body))
+	var c DefaultContext
+	c.Reset(rec, req, nil, "/")
+	return c.BindJSON(v, opts...)
+}
+
+func TestBindJSON_TimeRFC3339_Default(t *testing.T) {
+	var in struct {
+		At time.Time `json:"at"`
+	}
+	if err := bindJSONBody(t, `{"at":"2024-01-02T03:04:05Z"}`, &in); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if !in.At.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Fatalf("got %v", in.At)
+	}
+}
+
+func TestBindJSON_DateOnly_RequiresWeakOrLayout(t *testing.T) {
+	type In struct {
+		At time.Time `json:"at"`
+	}
+	var in In
+	err := bindJSONBody(t, `{"at":"2024-01-02"}`, &in)
+	var fe FieldErrors
+	if !errors.As(err, &fe) {
+		t.Fatalf("expected field errors, got %v", err)
+	}
+	if got := fe.All()[0].Message(); got != "time type expected" {
+		t.Fatalf("message = %q", got)
+	}
+
+	in = In{}
+	if err := bindJSONBody(t, `{"at":"2024-01-02"}`, &in, BindJSONOptions{WeaklyTypedInput: true}); err != nil {
+		t.Fatalf("weak: unexpected err: %v", err)
+	}
+	if in.At.Year() != 2024 || in.At.Day() != 2 {
+		t.Fatalf("weak: got %v", in.At)
+	}
+
+	in = In{}
+	if err := bindJSONBody(t, `{"at":"02/01/2024"}`, &in, BindJSONOptions{TimeLayouts: []string{"02/01/2006"}}); err != nil {
+		t.Fatalf("layout: unexpected err: %v", err)
+	}
+	if in.At.Month() != time.January || in.At.Day() != 2 {
+		t.Fatalf("layout: got %v", in.At)
+	}
+}
+
+func TestBindJSON_TimePointerField(t *testing.T) {
+	var in struct {
+		At *time.Time `json:"at"`
+	}
+	if err := bindJSONBody(t, `{"at":"2024-01-02 10:00:00"}`, &in, BindJSONOptions{WeaklyTypedInput: true}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if in.At == nil || in.At.Hour() != 10 {
+		t.Fatalf("got %v", in.At)
+	}
+}
+
+func TestBindJSON_TextUnmarshaler_FromStringAndNumber(t *testing.T) {
+	var in struct {
+		A cents `json:"a"`
+		B cents `json:"b"`
+	}
+	if err := bindJSONBody(t, `{"a":"12.34","b":5.67}`, &in, BindJSONOptions{ErrorUnused: true, UseNumber: true}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if in.A != 1234 || in.B != 567 {
+		t.Fatalf("got a=%d b=%d", in.A, in.B)
+	}
+}
+
+func TestBindJSON_TextUnmarshaler_ErrorMapped(t *testing.T) {
+	var in struct {
+		A cents `json:"a"`
+	}
+	err := bindJSONBody(t, `{"a":"abc"}`, &in)
+	var fe FieldErrors
+	if !errors.As(err, &fe) || fe.All()[0].Field() != "a" {
+		t.Fatalf("expected field error for a, got %v", err)
+	}
+}
+
+func TestBindJSON_CustomDecodeHook(t *testing.T) {
+	type upper string
+	hook := func(_, to reflect.Type, data any) (any, error) {
+		if to != reflect.TypeOf(upper("")) {
+			return data, nil
+		}
+		if s, ok := data.(string); ok {
+			return upper(strings.ToUpper(s)), nil
+		}
+		return data, nil
+	}
+	var in struct {
+		Name upper `json:"name"`
+	}
+	if err := bindJSONBody(t, `{"name":"ada"}`, &in, BindJSONOptions{DecodeHooks: []DecodeHook{nil, hook}}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if in.Name != "ADA" {
+		t.Fatalf("got %q", in.Name)
+	}
+}
+
+func TestBindJSON_UseNumber_PreservesPrecision(t *testing.T) {
+	var in struct {
+		ID int64 `json:"id"`
+	}
+	if err := bindJSONBody(t, `{"id":9007199254740993}`, &in, BindJSONOptions{UseNumber: true}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if in.ID != 9007199254740993 {
+		t.Fatalf("got %d", in.ID)
+	}
+}
+
+func TestBindJSON_UseNumber_StrictStringRejectsNumber(t *testing.T) {
+	var in struct {
+		Name string `json:"name"`
+	}
+	err := bindJSONBody(t, `{"name":42}`, &in, BindJSONOptions{UseNumber: true})
+	if !errors.Is(err, ErrFieldTypeExpected) {
+		t.Fatalf("expected type expected error, got %v", err)
+	}
+	in.Name = ""
+	if err := bindJSONBody(t, `{"name":42}`, &in, BindJSONOptions{UseNumber: true, WeaklyTypedInput: true}); err != nil || in.Name != "42" {
+		t.Fatalf("weak: got %q err=%v", in.Name, err)
+	}
+}
+
+func Test_extractFieldFromHookError(t *testing.T) {
+	if f, ok := extractFieldFromHookError("1 error(s) decoding:\n\n* error decoding 'at': boom"); !ok || f != "at" {
+		t.Fatalf("got %q %v", f, ok)
+	}
+	if _, ok := extractFieldFromHookError("nothing here"); ok {
+		t.Fatalf("expected no match")
+	}
+	if _, ok := extractFieldFromHookError("error decoding '': x"); ok {
+		t.Fatalf("expected no match for empty field")
+	}
+}
@@ -0,0 +1,58 @@
+package ctx
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type fuzzDTO struct {
+	Name    string            `json:"name"`
+	Age     int               `json:"age"`
+	Score   float64           `json:"score"`
+	Active  bool              `json:"active"`
+	Tags    []string          `json:"tags"`
+	Labels  map[string]string `json:"labels"`
+	Born    time.Time         `json:"born"`
+	Address *struct {
+		City string `json:"city"`
+		Zip  uint16 `json:"zip"`
+	} `json:"address"`
+}
+
+func FuzzBindJSON(f *testing.F) {
+	for _, seed := range []string{
+		`{"name":"ada","age":36,"tags":["a","b"],"address":{"city":"x","zip":1}}`,
+		`{"age":"36","score":"1.5","active":"true","born":"2024-01-02"}`,
+		`{"labels":{"k":"v"},"unknown":1}`,
+		`{"address":{"zip":70000}}`,
+		`[[[[[[[[[[1]]]]]]]]]]`,
+		`{"name":`,
+		`null`,
+	} {
+		f.Add([]byte(seed))
+	}
+	optsList := []BindJSONOptions{
+		{},
+		{ErrorUnused: true},
+		{WeaklyTypedInput: true, TimeLayouts: []string{time.DateOnly}},
+		{ErrorUnset: true, UseNumber: true},
+		{Stream: true},
+		{MaxBodyBytes: 256, MaxDepth: 4, MaxKeys: 16},
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, opts := range optsList {
+			req, rec := newRequest(http.MethodPost, "/", bytes.NewReader(data))
+			req.Header.Set("Content-Type", "application/json")
+			var c DefaultContext
+			c.Reset(rec, req, nil, "/")
+			var v fuzzDTO
+			err := c.BindJSON(&v, opts)
+			if err == nil && opts.MaxBodyBytes > 0 && int64(len(data)) > opts.MaxBodyBytes {
+				t.Fatalf("%d-byte body bound despite MaxBodyBytes %d", len(data), opts.MaxBodyBytes)
+			}
+			c.Finish()
+		}
+	})
+}
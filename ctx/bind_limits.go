@@ -0,0 +1,92 @@
+package ctx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// Sentinel causes wrapped by the *HTTPError returned when a JSON body exceeds
+// the limits configured in BindJSONOptions. Use errors.Is to detect them.
+var (
+	// ErrBodyTooLarge is returned (as a 413 *HTTPError) when the body exceeds MaxBodyBytes.
+	ErrBodyTooLarge = errors.New("request body too large")
+	// ErrJSONTooDeep is returned (as a 422 *HTTPError) when nesting exceeds MaxDepth.
+	ErrJSONTooDeep = errors.New("json nesting too deep")
+	// ErrJSONTooManyKeys is returned (as a 422 *HTTPError) when the object key count exceeds MaxKeys.
+	ErrJSONTooManyKeys = errors.New("too many json keys")
+)
+
+// hasJSONLimits reports whether any body guard is configured.
+func (o BindJSONOptions) hasJSONLimits() bool {
+	return o.MaxBodyBytes > 0 || o.MaxDepth > 0 || o.MaxKeys > 0
+}
+
+// jsonBody returns a reader over the request body honoring the configured guards.
+// Size is enforced while reading; depth and key limits are checked with a single
+// pass over the raw bytes before any decoding allocates Go values.
+func (c *DefaultContext) jsonBody(o BindJSONOptions) (io.Reader, error) {
+	var body io.Reader = c.r.Body
+	if o.MaxBodyBytes > 0 {
+		body = http.MaxBytesReader(c.w, c.r.Body, o.MaxBodyBytes)
+	}
+	if o.MaxDepth <= 0 && o.MaxKeys <= 0 {
+		return body, nil
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return nil, mapBodyReadError(err)
+	}
+	if err := checkJSONLimits(b, o.MaxDepth, o.MaxKeys); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
+}
+
+// mapBodyReadError converts http.MaxBytesReader failures into a 413 *HTTPError.
+func mapBodyReadError(err error) error {
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		return NewError(http.StatusRequestEntityTooLarge, ErrBodyTooLarge.Error()).WithErr(ErrBodyTooLarge)
+	}
+	return err
+}
+
+// checkJSONLimits scans raw JSON and enforces maximum nesting depth and total
+// object key count. A limit <= 0 disables that check. Malformed input is left
+// for the decoder to report.
+func checkJSONLimits(b []byte, maxDepth, maxKeys int) error {
+	depth, keys := 0, 0
+	inString, escaped := false, false
+	for _, ch := range b {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == '"':
+				inString = false
+			}
+			continue
+		}
+		switch ch {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+			if maxDepth > 0 && depth > maxDepth {
+				return NewError(http.StatusUnprocessableEntity, ErrJSONTooDeep.Error()).WithErr(ErrJSONTooDeep)
+			}
+		case '}', ']':
+			depth--
+		case ':':
+			keys++
+			if maxKeys > 0 && keys > maxKeys {
+				return NewError(http.StatusUnprocessableEntity, ErrJSONTooManyKeys.Error()).WithErr(ErrJSONTooManyKeys)
+			}
+		}
+	}
+	return nil
+}
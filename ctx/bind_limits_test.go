@@ -0,0 +1,87 @@
+package ctx
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func bindWithLimits(t *testing.T, body string, v any, o BindJSONOptions) error {
+	t.Helper()
+	req, rec := newRequest(http.MethodPost, "/", strings.NewReader(body))
+	var c DefaultContext
+	c.Reset(rec, req, nil, "/")
+	return c.BindJSON(v, o)
+}
+
+func assertHTTPError(t *testing.T, err error, code int, cause error) {
+	t.Helper()
+	var he *HTTPError
+	if !errors.As(err, &he) {
+		t.Fatalf("expected *HTTPError, got %T: %v", err, err)
+	}
+	if he.Code != code {
+		t.Fatalf("code = %d, want %d", he.Code, code)
+	}
+	if !errors.Is(err, cause) {
+		t.Fatalf("expected cause %v, got %v", cause, err)
+	}
+}
+
+func TestBindJSON_MaxBodyBytes(t *testing.T) {
+	var in struct {
+		Name string `json:"name"`
+	}
+	err := bindWithLimits(t, `{"name":"`+strings.Repeat("a", 100)+`"}`, &in, BindJSONOptions{MaxBodyBytes: 16})
+	assertHTTPError(t, err, http.StatusRequestEntityTooLarge, ErrBodyTooLarge)
+
+	if err := bindWithLimits(t, `{"name":"ok"}`, &in, BindJSONOptions{MaxBodyBytes: 64}); err != nil || in.Name != "ok" {
+		t.Fatalf("within limit: name=%q err=%v", in.Name, err)
+	}
+}
+
+func TestBindJSON_MaxBodyBytes_WithScanAndNonStruct(t *testing.T) {
+	var m map[string]any
+	err := bindWithLimits(t, `{"a":"`+strings.Repeat("x", 64)+`"}`, &m, BindJSONOptions{MaxBodyBytes: 8})
+	assertHTTPError(t, err, http.StatusRequestEntityTooLarge, ErrBodyTooLarge)
+
+	var in struct{}
+	err = bindWithLimits(t, `{"a":"`+strings.Repeat("x", 64)+`"}`, &in, BindJSONOptions{MaxBodyBytes: 8, MaxDepth: 4})
+	assertHTTPError(t, err, http.StatusRequestEntityTooLarge, ErrBodyTooLarge)
+}
+
+func TestBindJSON_MaxDepth(t *testing.T) {
+	var in struct {
+		A any `json:"a"`
+	}
+	deep := `{"a":` + strings.Repeat("[", 10) + strings.Repeat("]", 10) + `}`
+	err := bindWithLimits(t, deep, &in, BindJSONOptions{MaxDepth: 5})
+	assertHTTPError(t, err, http.StatusUnprocessableEntity, ErrJSONTooDeep)
+
+	// Brackets inside strings do not count.
+	if err := bindWithLimits(t, `{"a":"[[[[[[[[\"{{{{"}`, &in, BindJSONOptions{MaxDepth: 1}); err != nil {
+		t.Fatalf("string content must be ignored: %v", err)
+	}
+
+	var arr []any
+	err = bindWithLimits(t, `[[[1]]]`, &arr, BindJSONOptions{MaxDepth: 2})
+	assertHTTPError(t, err, http.StatusUnprocessableEntity, ErrJSONTooDeep)
+}
+
+func TestBindJSON_MaxKeys(t *testing.T) {
+	var m map[string]any
+	err := bindWithLimits(t, `{"a":1,"b":2,"c":{"d":3}}`, &m, BindJSONOptions{MaxKeys: 3})
+	assertHTTPError(t, err, http.StatusUnprocessableEntity, ErrJSONTooManyKeys)
+
+	if err := bindWithLimits(t, `{"a":"x:y:z","b":2}`, &m, BindJSONOptions{MaxKeys: 2}); err != nil {
+		t.Fatalf("colons in strings must be ignored: %v", err)
+	}
+}
+
+func Test_mapBodyReadError_Passthrough(t *testing.T) {
+	e := errors.New("other")
+	if mapBodyReadError(e) != e {
+		t.Fatalf("expected passthrough")
+	}
+}
@@ -11,8 +11,8 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
-	"github.com/julienschmidt/httprouter"
 	ms "github.com/mitchellh/mapstructure"
 )
 
@@ -679,7 +679,7 @@ func TestBindPath(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/u/xyz", nil)
 	rec := httptest.NewRecorder()
 	var c DefaultContext
-	ps := httprouter.Params{{Key: "id", Value: "xyz"}, {Key: "name", Value: "P"}, {Key: "age", Value: "33"}}
+	ps := Params{{Key: "id", Value: "xyz"}, {Key: "name", Value: "P"}, {Key: "age", Value: "33"}}
 	c.Reset(rec, req, ps, "/u/:id")
 	var out userDTO
 	if err := c.BindPath(&out, BindJSONOptions{WeaklyTypedInput: true}); err != nil {
@@ -698,7 +698,7 @@ func TestBindAny_Precedence_PathOverBodyOverQuery(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
 	var c DefaultContext
-	ps := httprouter.Params{{Key: "id", Value: "abc"}, {Key: "name", Value: "P"}}
+	ps := Params{{Key: "id", Value: "abc"}, {Key: "name", Value: "P"}}
 	c.Reset(rec, req, ps, "/users/:id")
 
 	var out userDTO
@@ -719,7 +719,7 @@ func TestBindAny_FormPrecedenceOverQuery(t *testing.T) {
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rec := httptest.NewRecorder()
 	var c DefaultContext
-	ps := httprouter.Params{{Key: "id", Value: "9"}}
+	ps := Params{{Key: "id", Value: "9"}}
 	c.Reset(rec, req, ps, "/users/:id")
 
 	var out userDTO
@@ -740,7 +740,7 @@ func TestBindAny_PathOverridesFormAndQuery(t *testing.T) {
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rec := httptest.NewRecorder()
 	var c DefaultContext
-	ps := httprouter.Params{{Key: "id", Value: "abc"}, {Key: "name", Value: "P"}}
+	ps := Params{{Key: "id", Value: "abc"}, {Key: "name", Value: "P"}}
 	c.Reset(rec, req, ps, "/users/:id")
 
 	var out userDTO
@@ -760,7 +760,7 @@ func TestBindAny_IgnoresJSONWithoutContentType(t *testing.T) {
 	// Intentionally no Content-Type header
 	rec := httptest.NewRecorder()
 	var c DefaultContext
-	ps := httprouter.Params{{Key: "id", Value: "xyz"}}
+	ps := Params{{Key: "id", Value: "xyz"}}
 	c.Reset(rec, req, ps, "/u/:id")
 
 	var out userDTO
@@ -921,7 +921,7 @@ func TestBindAny_PathOnly(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/u/xyz", nil)
 	rec := httptest.NewRecorder()
 	var c DefaultContext
-	ps := httprouter.Params{{Key: "id", Value: "xyz"}, {Key: "name", Value: "P"}, {Key: "age", Value: "33"}}
+	ps := Params{{Key: "id", Value: "xyz"}, {Key: "name", Value: "P"}, {Key: "age", Value: "33"}}
 	c.Reset(rec, req, ps, "/u/:id")
 	var out userDTO
 	if err := c.BindAny(&out, BindJSONOptions{WeaklyTypedInput: true}); err != nil {
@@ -965,7 +965,7 @@ func TestBindAny_FormPrecedenceOverQuery2(t *testing.T) {
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rec := httptest.NewRecorder()
 	var c DefaultContext
-	ps := httprouter.Params{{Key: "id", Value: "9"}}
+	ps := Params{{Key: "id", Value: "9"}}
 	c.Reset(rec, req, ps, "/users/:id")
 
 	var out userDTO
@@ -986,7 +986,7 @@ func TestBindAny_PathOverridesFormAndQuery2(t *testing.T) {
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rec := httptest.NewRecorder()
 	var c DefaultContext
-	ps := httprouter.Params{{Key: "id", Value: "abc"}, {Key: "name", Value: "P"}}
+	ps := Params{{Key: "id", Value: "abc"}, {Key: "name", Value: "P"}}
 	c.Reset(rec, req, ps, "/users/:id")
 
 	var out userDTO
@@ -1006,7 +1006,7 @@ func TestBindAny_IgnoresJSONWithoutContentType2(t *testing.T) {
 	// Intentionally no Content-Type header
 	rec := httptest.NewRecorder()
 	var c DefaultContext
-	ps := httprouter.Params{{Key: "id", Value: "xyz"}}
+	ps := Params{{Key: "id", Value: "xyz"}}
 	c.Reset(rec, req, ps, "/u/:id")
 
 	var out userDTO
@@ -1167,7 +1167,7 @@ func TestBindAny_PathOnly2(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/u/xyz", nil)
 	rec := httptest.NewRecorder()
 	var c DefaultContext
-	ps := httprouter.Params{{Key: "id", Value: "xyz"}, {Key: "name", Value: "P"}, {Key: "age", Value: "33"}}
+	ps := Params{{Key: "id", Value: "xyz"}, {Key: "name", Value: "P"}, {Key: "age", Value: "33"}}
 	c.Reset(rec, req, ps, "/u/:id")
 	var out userDTO
 	if err := c.BindAny(&out, BindJSONOptions{WeaklyTypedInput: true}); err != nil {
@@ -1283,7 +1283,7 @@ func TestBindAnyWithMultipartForm(t *testing.T) {
 	rec := httptest.NewRecorder()
 
 	var c DefaultContext
-	c.Reset(rec, req, httprouter.Params{{Key: "path_field", Value: "path_value"}}, "/test")
+	c.Reset(rec, req, Params{{Key: "path_field", Value: "path_value"}}, "/test")
 
 	type TestStruct struct {
 		Name       string `json:"name"`
@@ -1321,7 +1321,7 @@ func TestBindAnyWithApplicationJSON(t *testing.T) {
 	rec := httptest.NewRecorder()
 
 	var c DefaultContext
-	c.Reset(rec, req, httprouter.Params{{Key: "path_field", Value: "path_value"}}, "/test")
+	c.Reset(rec, req, Params{{Key: "path_field", Value: "path_value"}}, "/test")
 
 	type TestStruct struct {
 		Name       string `json:"name"`
@@ -1708,3 +1708,375 @@ func TestMapJSONStrictErrorWithComplexTypes(t *testing.T) {
 		t.Error("expected at least one field error")
 	}
 }
+
+func TestSetBindDefaults_UsedWhenNoOptionsAndClearedOnReset(t *testing.T) {
+	type In struct {
+		Age int `json:"age"`
+	}
+	req, rec := newRequest(http.MethodPost, "/", strings.NewReader(`{"age":"3"}`))
+	var c DefaultContext
+	c.Reset(rec, req, nil, "/")
+	c.SetBindDefaults(&BindJSONOptions{WeaklyTypedInput: true})
+	var in In
+	if err := c.BindJSON(&in); err != nil || in.Age != 3 {
+		t.Fatalf("defaults not applied: age=%d err=%v", in.Age, err)
+	}
+	// Explicit options win over defaults.
+	if err := c.BindMap(&in, map[string]any{"age": "4"}, BindJSONOptions{}); err == nil {
+		t.Fatalf("expected strict explicit options to reject string")
+	}
+	c.Reset(rec, req, nil, "/")
+	if err := c.BindMap(&in, map[string]any{"age": "5"}); err == nil {
+		t.Fatalf("expected built-in strict defaults after Reset")
+	}
+}
+
+func TestBindJSON_NestedPathsCodesAndValues(t *testing.T) {
+	type Item struct {
+		Price float64 `json:"price"`
+	}
+	type Order struct {
+		Items []Item `json:"items"`
+		Meta  struct {
+			Tag string `json:"tag"`
+		} `json:"meta"`
+	}
+	body := `{"items":[{"price":1},{"price":2},{"price":"abc"}],"meta":{"tag":"x","bogus":1}}`
+	req, rec := newRequest(http.MethodPost, "/", strings.NewReader(body))
+	var c DefaultContext
+	c.Reset(rec, req, nil, "/")
+	var o Order
+	err := c.BindJSON(&o)
+	var fe FieldErrors
+	if !errors.As(err, &fe) {
+		t.Fatalf("expected FieldErrors, got %v", err)
+	}
+	all := fieldErrorDetails(t, fe)
+	if len(all) != 2 {
+		t.Fatalf("expected 2 errors, got %#v", fieldErrorsToMap(fe))
+	}
+	if all[0].Field() != "items[2].price" || all[0].Code() != FieldCodeTypeMismatch ||
+		all[0].Message() != "float type expected" || all[0].Value() != "abc" || all[0].Pointer() != "/items/2/price" {
+		t.Fatalf("unexpected first error: %+v", all[0])
+	}
+	if all[1].Field() != "meta.bogus" || all[1].Code() != FieldCodeUnexpected || all[1].Value() != float64(1) {
+		t.Fatalf("unexpected second error: %+v", all[1])
+	}
+}
+
+func TestBindMap_ErrorUnset_ReportsRequired(t *testing.T) {
+	type In struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	req, rec := newRequest(http.MethodPost, "/", nil)
+	var c DefaultContext
+	c.Reset(rec, req, nil, "/")
+	var in In
+	err := c.BindMap(&in, map[string]any{"age": 3}, BindJSONOptions{ErrorUnused: true, ErrorUnset: true})
+	if !errors.Is(err, ErrFieldRequired) {
+		t.Fatalf("expected required error, got %v", err)
+	}
+	all := fieldErrorDetails(t, err.(FieldErrors))
+	if len(all) != 1 || all[0].Field() != "name" || all[0].Code() != FieldCodeRequired || all[0].Value() != nil {
+		t.Fatalf("unexpected: %+v", all)
+	}
+}
+
+func Test_qualifyFieldPath(t *testing.T) {
+	type Inner struct {
+		X int `json:"x"`
+	}
+	type T struct {
+		Inner Inner `json:"inner"`
+	}
+	rt := reflect.TypeOf(T{})
+	cases := []struct{ parent, key, want string }{
+		{"", "k", "k"},
+		{"inner", "k", "inner.k"},
+		{"items[0]", "k", "items[0].k"},
+		{"T", "k", "k"},
+	}
+	for _, tc := range cases {
+		if got := qualifyFieldPath(tc.parent, tc.key, rt); got != tc.want {
+			t.Fatalf("qualifyFieldPath(%q,%q)=%q want %q", tc.parent, tc.key, got, tc.want)
+		}
+	}
+}
+
+func Test_findExpectedFieldPath(t *testing.T) {
+	type Item struct {
+		Price *float64 `json:"price"`
+	}
+	type T struct {
+		Items []*Item          `json:"items"`
+		Tags  map[string]Item  `json:"tags"`
+		Grid  [][]int          `json:"grid"`
+		Name  string           `json:"name"`
+		Ptr   *struct{ A int } `json:"ptr"`
+	}
+	rt := reflect.TypeOf(T{})
+	for path, want := range map[string]string{
+		"items[1].price": "*float64",
+		"tags[k].price":  "*float64",
+		"grid[0][1]":     "int",
+		"ptr.A":          "int",
+	} {
+		ft, ok := findExpectedFieldPath(rt, path)
+		if !ok || ft.String() != want {
+			t.Fatalf("%s: got %v ok=%v want %s", path, ft, ok, want)
+		}
+	}
+	for _, path := range []string{"name[0]", "missing", "items[0].nope"} {
+		if _, ok := findExpectedFieldPath(rt, path); ok {
+			t.Fatalf("%s: expected miss", path)
+		}
+	}
+	if _, ok := findExpectedFieldPath(nil, "x"); ok {
+		t.Fatalf("nil type should miss")
+	}
+}
+
+func TestBindJSON_StreamPath_SkipsMapstructure(t *testing.T) {
+	orig := newMSDecoder
+	newMSDecoder = func(*ms.DecoderConfig) (*ms.Decoder, error) { return nil, errors.New("mapstructure used") }
+	defer func() { newMSDecoder = orig }()
+
+	type In struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	body := `{"name":"Ada","age":36}`
+	large := `{"name":"Ada","age":36` + strings.Repeat(` `, strictStreamMin) + `}`
+	cases := []struct {
+		o      BindJSONOptions
+		body   string
+		length int64 // -1 for a body of unknown length
+	}{
+		{BindJSONOptions{ErrorUnused: true, Stream: true}, body, int64(len(body))},
+		{BindJSONOptions{Stream: true}, body, int64(len(body))},
+		{BindJSONOptions{ErrorUnused: true}, large, int64(len(large))},
+		{BindJSONOptions{ErrorUnused: true}, body, -1},
+	}
+	for _, tc := range cases {
+		req, rec := newRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+		req.ContentLength = tc.length
+		var c DefaultContext
+		c.Reset(rec, req, nil, "/")
+		var in In
+		if err := c.BindJSON(&in, tc.o); err != nil || in.Name != "Ada" || in.Age != 36 {
+			t.Fatalf("%+v (length %d): in=%+v err=%v", tc.o, tc.length, in, err)
+		}
+	}
+}
+
+func TestBindJSON_StreamPath_ErrorsMapped(t *testing.T) {
+	type In struct {
+		Age int `json:"age"`
+	}
+	req, rec := newRequest(http.MethodPost, "/", strings.NewReader(`{"age":1,"extra":true}`))
+	var c DefaultContext
+	c.Reset(rec, req, nil, "/")
+	var in In
+	err := c.BindJSON(&in, BindJSONOptions{Stream: true, ErrorUnused: true})
+	if !errors.Is(err, ErrFieldUnexpected) {
+		t.Fatalf("expected unexpected field error, got %v", err)
+	}
+
+	req, rec = newRequest(http.MethodPost, "/", strings.NewReader(`{"age":"x"}`))
+	c.Reset(rec, req, nil, "/")
+	err = c.BindJSON(&in, BindJSONOptions{})
+	if m := fieldErrorsToMap(err.(FieldErrors)); m["age"] != "int type expected" {
+		t.Fatalf("unexpected: %#v", m)
+	}
+
+	req, rec = newRequest(http.MethodPost, "/", strings.NewReader(`{"age":1}`))
+	c.Reset(rec, req, nil, "/")
+	err = c.BindJSON(&in, BindJSONOptions{MaxDepth: 1, MaxBodyBytes: 4})
+	var he *HTTPError
+	if !errors.As(err, &he) || he.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %v", err)
+	}
+}
+
+func TestBindJSONOptions_streamable(t *testing.T) {
+	cases := []struct {
+		o    BindJSONOptions
+		want bool
+	}{
+		{BindJSONOptions{}, false},
+		{BindJSONOptions{ErrorUnused: true}, true},
+		{BindJSONOptions{Stream: true}, true},
+		{BindJSONOptions{ErrorUnused: true, WeaklyTypedInput: true}, false},
+		{BindJSONOptions{Stream: true, WeaklyTypedInput: true}, false},
+		{BindJSONOptions{Stream: true, ErrorUnset: true}, false},
+		{BindJSONOptions{Stream: true, TimeLayouts: []string{"2006"}}, false},
+		{BindJSONOptions{Stream: true, DecodeHooks: []DecodeHook{nil}}, false},
+	}
+	for i, tc := range cases {
+		if got := tc.o.streamable(); got != tc.want {
+			t.Fatalf("case %d: got %v want %v", i, got, tc.want)
+		}
+	}
+}
+
+func TestBindJSON_StrictAndPermissiveAgree(t *testing.T) {
+	type Base struct {
+		ID int `json:"id"`
+	}
+	type Item struct {
+		SKU string `json:"sku"`
+	}
+	type Flat struct {
+		Name  string    `json:"name"`
+		Items []Item    `json:"items"`
+		At    time.Time `json:"at"`
+		Ptr   *Item     `json:"ptr"`
+	}
+	type Embedded struct {
+		Base
+		Name string `json:"name"`
+	}
+	bind := func(v any, body string, o BindJSONOptions) error {
+		req, rec := newRequest(http.MethodPost, "/", strings.NewReader(body))
+		var c DefaultContext
+		c.Reset(rec, req, nil, "/")
+		return c.BindJSON(v, o)
+	}
+	cases := []struct {
+		body string
+		new  func() any
+	}{
+		{`{"NAME":"Ada","items":[{"sku":"A1"}],"at":"2024-01-02T03:04:05Z","ptr":{"sku":"B"}}`, func() any { return &Flat{} }},
+		{`{"Base":{"id":7},"name":"Ada"}`, func() any { return &Embedded{} }},
+	}
+	for _, tc := range cases {
+		strict, lax := tc.new(), tc.new()
+		if err := bind(strict, tc.body, BindJSONOptions{ErrorUnused: true, Stream: true}); err != nil {
+			t.Fatalf("%s: strict: %v", tc.body, err)
+		}
+		if err := bind(lax, tc.body, BindJSONOptions{}); err != nil {
+			t.Fatalf("%s: permissive: %v", tc.body, err)
+		}
+		if !reflect.DeepEqual(strict, lax) {
+			t.Fatalf("%s: strict %+v != permissive %+v", tc.body, strict, lax)
+		}
+	}
+}
+
+func TestBindJSON_StrictStreamFallsBackForErrors(t *testing.T) {
+	type In struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	req, rec := newRequest(http.MethodPost, "/", strings.NewReader(`{"name":"Ada","extra":1,"more":2,"age":"x"}`))
+	var c DefaultContext
+	c.Reset(rec, req, nil, "/")
+	in := In{Name: "orig"}
+	err := c.BindJSON(&in, BindJSONOptions{ErrorUnused: true, Stream: true})
+	fe, ok := err.(FieldErrors)
+	if !ok {
+		t.Fatalf("expected field errors, got %v", err)
+	}
+	if m := fieldErrorsToMap(fe); len(m) != 3 || m["extra"] == "" || m["more"] == "" || m["age"] == "" {
+		t.Fatalf("errors = %#v", m)
+	}
+
+	// Past the kept prefix only the decoder's first error is reported.
+	body := `{"name":"Ada",` + strings.Repeat(` `, strictStreamMin) + `"extra":1,"more":2}`
+	req, rec = newRequest(http.MethodPost, "/", strings.NewReader(body))
+	c.Reset(rec, req, nil, "/")
+	in = In{Name: "orig"}
+	err = c.BindJSON(&in)
+	fe, ok = err.(FieldErrors)
+	if !ok {
+		t.Fatalf("expected field errors, got %v", err)
+	}
+	if m := fieldErrorsToMap(fe); len(m) != 1 || m["extra"] != ErrFieldUnexpected.Error() || in.Name != "orig" {
+		t.Fatalf("errors = %#v, in = %+v", m, in)
+	}
+}
+
+func TestPrefixBuffer(t *testing.T) {
+	b := prefixBuffer{max: 4}
+	_, _ = b.Write([]byte("ab"))
+	_, _ = b.Write([]byte("cd"))
+	if b.overflow || b.buf.String() != "abcd" {
+		t.Fatalf("buf = %q overflow = %v", b.buf.String(), b.overflow)
+	}
+	if n, err := b.Write([]byte("e")); n != 1 || err != nil || !b.overflow || b.buf.Len() != 0 {
+		t.Fatalf("n = %d err = %v overflow = %v len = %d", n, err, b.overflow, b.buf.Len())
+	}
+}
+
+func TestJSONDecoder_TokenStreaming(t *testing.T) {
+	req, rec := newRequest(http.MethodPost, "/", strings.NewReader(`[{"n":1},{"n":2.5}]`))
+	var c DefaultContext
+	c.Reset(rec, req, nil, "/")
+	c.SetBindDefaults(&BindJSONOptions{UseNumber: true})
+	dec := c.JSONDecoder()
+	if _, err := dec.Token(); err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	var got []any
+	for dec.More() {
+		var item map[string]any
+		if err := dec.Decode(&item); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		got = append(got, item["n"])
+	}
+	if len(got) != 2 || got[1].(interface{ String() string }).String() != "2.5" {
+		t.Fatalf("unexpected items: %#v", got)
+	}
+}
+
+func TestJSONDecoder_HonorsMaxBodyBytes(t *testing.T) {
+	req, rec := newRequest(http.MethodPost, "/", strings.NewReader(`{"a":"`+strings.Repeat("x", 64)+`"}`))
+	var c DefaultContext
+	c.Reset(rec, req, nil, "/")
+	c.SetBindDefaults(&BindJSONOptions{MaxBodyBytes: 8})
+	var m map[string]any
+	err := c.JSONDecoder().Decode(&m)
+	var mbe *http.MaxBytesError
+	if !errors.As(err, &mbe) {
+		t.Fatalf("expected MaxBytesError, got %v", err)
+	}
+}
+
+func benchmarkBindJSON(b *testing.B, o BindJSONOptions) {
+	type Item struct {
+		ID    int     `json:"id"`
+		Name  string  `json:"name"`
+		Price float64 `json:"price"`
+	}
+	type In struct {
+		Items []Item `json:"items"`
+	}
+	var sb strings.Builder
+	sb.WriteString(`{"items":[`)
+	for i := 0; i < 200; i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(`{"id":1,"name":"widget","price":9.99}`)
+	}
+	sb.WriteString(`]}`)
+	body := sb.String()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req, rec := newRequest(http.MethodPost, "/", strings.NewReader(body))
+		var c DefaultContext
+		c.Reset(rec, req, nil, "/")
+		var in In
+		if err := c.BindJSON(&in, o); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBindJSON_Map(b *testing.B) { benchmarkBindJSON(b, BindJSONOptions{ErrorUnused: true}) }
+func BenchmarkBindJSON_Stream(b *testing.B) {
+	benchmarkBindJSON(b, BindJSONOptions{ErrorUnused: true, Stream: true})
+}
@@ -0,0 +1,48 @@
+package ctx
+
+// SetRouteBypass sets the middleware names the matched route opts out of (see
+// app.Route.Bypass). The slice is shared between requests and must not be
+// modified. Used internally by the app.
+func (c *DefaultContext) SetRouteBypass(names []string) { c.routeBypass = names }
+
+// BypassMiddleware marks the named middleware to be skipped for the rest of
+// this request. It only affects middleware that run after the call and check
+// Bypassed, such as those wrapped with app.Bypassable; to opt a route out of
+// middleware that run before its handler, use app.Route.Bypass instead.
+//
+// Example:
+//
+//	// A route middleware deciding per request
+//	func NoBufferForStreams(next flash.Handler) flash.Handler {
+//		return func(c flash.Ctx) error {
+//			if c.Query("stream") == "1" {
+//				c.BypassMiddleware("buffer")
+//			}
+//			return next(c)
+//		}
+//	}
+func (c *DefaultContext) BypassMiddleware(names ...string) {
+	c.bypass = append(c.bypass, names...)
+}
+
+// Bypassed reports whether the middleware called name should skip this
+// request, because the route opted out of it or BypassMiddleware was called.
+//
+// Example:
+//
+//	if c.Bypassed("gzip") {
+//		return next(c)
+//	}
+func (c *DefaultContext) Bypassed(name string) bool {
+	for _, n := range c.routeBypass {
+		if n == name {
+			return true
+		}
+	}
+	for _, n := range c.bypass {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package ctx
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBypassed(t *testing.T) {
+	c := &DefaultContext{}
+	c.Reset(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), nil, "/")
+	if c.Bypassed("gzip") {
+		t.Fatalf("nothing bypassed yet")
+	}
+	c.SetRouteBypass([]string{"gzip"})
+	c.BypassMiddleware("cache", "buffer")
+	for _, n := range []string{"gzip", "cache", "buffer"} {
+		if !c.Bypassed(n) {
+			t.Fatalf("expected %s to be bypassed", n)
+		}
+	}
+	if c.Bypassed("timeout") {
+		t.Fatalf("timeout not bypassed")
+	}
+	c.Reset(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), nil, "/")
+	if c.Bypassed("gzip") || c.Bypassed("cache") {
+		t.Fatalf("Reset must clear bypasses")
+	}
+}
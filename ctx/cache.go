@@ -0,0 +1,115 @@
+package ctx
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheDirectives describes a Cache-Control response header. Zero fields are
+// omitted; durations are rounded down to whole seconds.
+//
+// To express "max-age=0", prefer NoCache: true (or c.NoCache() to forbid
+// caching entirely).
+//
+// Example (fingerprinted static asset):
+//
+//	c.CacheControl(ctx.CacheDirectives{MaxAge: 365 * 24 * time.Hour, Public: true, Immutable: true})
+//	// Cache-Control: public, max-age=31536000, immutable
+//
+// Example (per-user API response):
+//
+//	c.CacheControl(ctx.CacheDirectives{Private: true, MaxAge: time.Minute, MustRevalidate: true})
+type CacheDirectives struct {
+	Public          bool // response may be stored by shared caches
+	Private         bool // response is for a single user; shared caches must not store it
+	NoCache         bool // caches must revalidate before reuse
+	NoStore         bool // caches must not store the response at all
+	NoTransform     bool // intermediaries must not transform the payload
+	MustRevalidate  bool // stale responses must be revalidated
+	ProxyRevalidate bool // like MustRevalidate, for shared caches only
+	Immutable       bool // response will not change while fresh
+
+	MaxAge               time.Duration // max-age
+	SMaxAge              time.Duration // s-maxage (shared caches)
+	StaleWhileRevalidate time.Duration // stale-while-revalidate
+	StaleIfError         time.Duration // stale-if-error
+}
+
+// String formats the directives as a Cache-Control header value.
+func (d CacheDirectives) String() string {
+	var b strings.Builder
+	add := func(s string) {
+		if b.Len() > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(s)
+	}
+	addSeconds := func(name string, v time.Duration) {
+		if v > 0 {
+			add(name + "=" + strconv.FormatInt(int64(v/time.Second), 10))
+		}
+	}
+	if d.Public {
+		add("public")
+	}
+	if d.Private {
+		add("private")
+	}
+	if d.NoCache {
+		add("no-cache")
+	}
+	if d.NoStore {
+		add("no-store")
+	}
+	addSeconds("max-age", d.MaxAge)
+	addSeconds("s-maxage", d.SMaxAge)
+	if d.MustRevalidate {
+		add("must-revalidate")
+	}
+	if d.ProxyRevalidate {
+		add("proxy-revalidate")
+	}
+	if d.NoTransform {
+		add("no-transform")
+	}
+	if d.Immutable {
+		add("immutable")
+	}
+	addSeconds("stale-while-revalidate", d.StaleWhileRevalidate)
+	addSeconds("stale-if-error", d.StaleIfError)
+	return b.String()
+}
+
+// CacheControl sets the Cache-Control header from d. When MaxAge is set, an
+// Expires header is added for HTTP/1.0 caches; when NoCache or NoStore is set,
+// "Pragma: no-cache" is added as well. Returns the Ctx to allow chaining.
+// Has no effect after the header is written.
+//
+// Example:
+//
+//	return c.CacheControl(ctx.CacheDirectives{Public: true, MaxAge: time.Hour}).JSON(list)
+func (c *DefaultContext) CacheControl(d CacheDirectives) Ctx {
+	h := c.w.Header()
+	h.Set("Cache-Control", d.String())
+	switch {
+	case d.NoCache || d.NoStore:
+		h.Set("Pragma", "no-cache")
+		h.Set("Expires", "0")
+	case d.MaxAge > 0:
+		h.Set("Expires", time.Now().Add(d.MaxAge).UTC().Format(http.TimeFormat))
+	}
+	return c
+}
+
+// NoCache marks the response as not cacheable by browsers, proxies and
+// HTTP/1.0 caches: "Cache-Control: no-cache, no-store, must-revalidate",
+// "Pragma: no-cache" and "Expires: 0". Returns the Ctx to allow chaining.
+//
+// Example:
+//
+//	return c.NoCache().JSON(session)
+func (c *DefaultContext) NoCache() Ctx {
+	return c.CacheControl(CacheDirectives{NoCache: true, NoStore: true, MustRevalidate: true})
+}
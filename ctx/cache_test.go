@@ -0,0 +1,59 @@
+package ctx
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCacheDirectives_String(t *testing.T) {
+	cases := []struct {
+		d    CacheDirectives
+		want string
+	}{
+		{CacheDirectives{}, ""},
+		{CacheDirectives{MaxAge: time.Hour, Public: true, Immutable: true}, "public, max-age=3600, immutable"},
+		{CacheDirectives{Private: true, MaxAge: 1500 * time.Millisecond, MustRevalidate: true}, "private, max-age=1, must-revalidate"},
+		{CacheDirectives{NoCache: true, NoStore: true}, "no-cache, no-store"},
+		{CacheDirectives{
+			Public: true, SMaxAge: time.Minute, ProxyRevalidate: true, NoTransform: true,
+			StaleWhileRevalidate: 30 * time.Second, StaleIfError: time.Hour,
+		}, "public, s-maxage=60, proxy-revalidate, no-transform, stale-while-revalidate=30, stale-if-error=3600"},
+	}
+	for _, tc := range cases {
+		if got := tc.d.String(); got != tc.want {
+			t.Fatalf("got %q want %q", got, tc.want)
+		}
+	}
+}
+
+func TestCacheControl_SetsExpires(t *testing.T) {
+	req, rec := newRequest(http.MethodGet, "/", nil)
+	var c DefaultContext
+	c.Reset(rec, req, nil, "/")
+	before := time.Now().Add(time.Hour).Add(-time.Second)
+	if err := c.CacheControl(CacheDirectives{Public: true, MaxAge: time.Hour}).String(http.StatusOK, "x"); err != nil {
+		t.Fatal(err)
+	}
+	h := rec.Header()
+	if h.Get("Cache-Control") != "public, max-age=3600" || h.Get("Pragma") != "" {
+		t.Fatalf("unexpected headers: %v", h)
+	}
+	exp, err := http.ParseTime(h.Get("Expires"))
+	if err != nil || exp.Before(before.Truncate(time.Second)) || exp.After(time.Now().Add(time.Hour+time.Second)) {
+		t.Fatalf("unexpected Expires %q: %v", h.Get("Expires"), err)
+	}
+}
+
+func TestNoCache(t *testing.T) {
+	req, rec := newRequest(http.MethodGet, "/", nil)
+	var c DefaultContext
+	c.Reset(rec, req, nil, "/")
+	if c.NoCache() != &c {
+		t.Fatalf("NoCache should return the context for chaining")
+	}
+	h := rec.Header()
+	if h.Get("Cache-Control") != "no-cache, no-store, must-revalidate" || h.Get("Pragma") != "no-cache" || h.Get("Expires") != "0" {
+		t.Fatalf("unexpected headers: %v", h)
+	}
+}
@@ -0,0 +1,138 @@
+package ctx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrInvalidCookie is wrapped by the errors of ValidateCookie and
+// Ctx.SetCookie.
+var ErrInvalidCookie = errors.New("ctx: invalid cookie")
+
+// Cookie name prefixes that browsers enforce (RFC 6265bis): a "__Secure-"
+// cookie must be Secure; a "__Host-" cookie must also have Path "/" and no
+// Domain, which pins it to the exact host that set it.
+const (
+	CookiePrefixSecure = "__Secure-"
+	CookiePrefixHost   = "__Host-"
+)
+
+// ValidateCookie checks that ck would be accepted by browsers: a valid name,
+// value and attributes, the requirements of the "__Secure-" and "__Host-"
+// name prefixes, and Secure for SameSite=None and Partitioned (CHIPS)
+// cookies. Browsers drop violating cookies silently, so the error lists every
+// problem. The error wraps ErrInvalidCookie.
+//
+// Example:
+//
+//	err := ctx.ValidateCookie(&http.Cookie{Name: "__Host-sid", Value: id, Path: "/app"})
+//	// ctx: invalid cookie "__Host-sid": __Host- cookies require Secure
+//	// __Host- cookies require Path "/"
+func ValidateCookie(ck *http.Cookie) error {
+	var errs []error
+	if err := ck.Valid(); err != nil {
+		errs = append(errs, err)
+	}
+	if ck.SameSite == http.SameSiteNoneMode && !ck.Secure {
+		errs = append(errs, errors.New("SameSite=None requires Secure"))
+	}
+	if ck.Partitioned && !ck.Secure {
+		errs = append(errs, errors.New("Partitioned requires Secure"))
+	}
+	if strings.HasPrefix(ck.Name, CookiePrefixSecure) && !ck.Secure {
+		errs = append(errs, errors.New("__Secure- cookies require Secure"))
+	}
+	if strings.HasPrefix(ck.Name, CookiePrefixHost) {
+		if !ck.Secure {
+			errs = append(errs, errors.New("__Host- cookies require Secure"))
+		}
+		if ck.Domain != "" {
+			errs = append(errs, errors.New("__Host- cookies must not set Domain"))
+		}
+		if ck.Path != "/" {
+			errs = append(errs, errors.New(`__Host- cookies require Path "/"`))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w %q: %w", ErrInvalidCookie, ck.Name, errors.Join(errs...))
+}
+
+// HostCookie returns a "__Host-" prefixed cookie with the attributes the
+// prefix requires (Secure, Path "/", no Domain) plus HttpOnly and
+// SameSite=Lax. It is the safest default for session-like cookies: it cannot
+// be set or overwritten by subdomains or plain-HTTP responses. Set
+// Partitioned on the result for cookies used in third-party embeds.
+//
+// Example:
+//
+//	ck := ctx.HostCookie("sid", id) // __Host-sid
+//	ck.MaxAge = 3600
+//	_ = c.SetCookie(ck)
+//	// later: id := c.Cookies()["__Host-sid"]
+func HostCookie(name, value string) *http.Cookie {
+	return &http.Cookie{
+		Name:     CookiePrefixHost + name,
+		Value:    value,
+		Path:     "/",
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// Cookies returns the request cookies by name. When a name is sent more than
+// once (e.g. for different paths), the first, most specific one wins. The
+// map is built on each call.
+//
+// Example:
+//
+//	theme := c.Cookies()["theme"]
+func (c *DefaultContext) Cookies() map[string]string {
+	cookies := c.r.Cookies()
+	out := make(map[string]string, len(cookies))
+	for _, ck := range cookies {
+		if _, ok := out[ck.Name]; !ok {
+			out[ck.Name] = ck.Value
+		}
+	}
+	return out
+}
+
+// SetCookie adds a Set-Cookie header for ck after checking it with
+// ValidateCookie, so prefixed and partitioned cookies that browsers would
+// drop fail loudly instead. Nothing is written when ck is invalid.
+//
+// Example:
+//
+//	err := c.SetCookie(&http.Cookie{
+//		Name: "__Secure-prefs", Value: "dark", Path: "/", Secure: true,
+//		SameSite: http.SameSiteNoneMode, Partitioned: true,
+//	})
+func (c *DefaultContext) SetCookie(ck *http.Cookie) error {
+	if err := ValidateCookie(ck); err != nil {
+		return err
+	}
+	http.SetCookie(c.w, ck)
+	return nil
+}
+
+// ClearCookie tells the browser to delete the cookie name set with Path "/"
+// and no Domain, adding the Secure attribute for prefixed names. Cookies set
+// with another Path or a Domain are deleted by passing SetCookie a copy with
+// MaxAge -1 and the same Path and Domain.
+//
+// Example:
+//
+//	c.ClearCookie("__Host-sid")
+func (c *DefaultContext) ClearCookie(name string) {
+	http.SetCookie(c.w, &http.Cookie{
+		Name:   name,
+		Path:   "/",
+		MaxAge: -1,
+		Secure: strings.HasPrefix(name, CookiePrefixSecure) || strings.HasPrefix(name, CookiePrefixHost),
+	})
+}
@@ -0,0 +1,94 @@
+package ctx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateCookie(t *testing.T) {
+	cases := []struct {
+		ck   http.Cookie
+		want []string // substrings of the error; nil means valid
+	}{
+		{http.Cookie{Name: "sid", Value: "x"}, nil},
+		{http.Cookie{Name: "__Host-sid", Value: "x", Path: "/", Secure: true}, nil},
+		{http.Cookie{Name: "__Secure-sid", Value: "x", Secure: true, Domain: "example.com", Path: "/a"}, nil},
+		{http.Cookie{Name: "p", Value: "x", Secure: true, Partitioned: true, SameSite: http.SameSiteNoneMode}, nil},
+		{http.Cookie{Name: "__Secure-sid", Value: "x"}, []string{"__Secure- cookies require Secure"}},
+		{http.Cookie{Name: "__Host-sid", Value: "x", Domain: "example.com", Path: "/app"}, []string{
+			"__Host- cookies require Secure", "must not set Domain", `require Path "/"`,
+		}},
+		{http.Cookie{Name: "p", Value: "x", Partitioned: true}, []string{"Partitioned requires Secure"}},
+		{http.Cookie{Name: "n", Value: "x", SameSite: http.SameSiteNoneMode}, []string{"SameSite=None requires Secure"}},
+		{http.Cookie{Name: "bad name", Value: "x"}, []string{"invalid Cookie.Name"}},
+	}
+	for _, tc := range cases {
+		err := ValidateCookie(&tc.ck)
+		if tc.want == nil {
+			if err != nil {
+				t.Fatalf("%s: unexpected %v", tc.ck.Name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrInvalidCookie) {
+			t.Fatalf("%s: err=%v", tc.ck.Name, err)
+		}
+		for _, w := range tc.want {
+			if !strings.Contains(err.Error(), w) {
+				t.Fatalf("%s: %q lacks %q", tc.ck.Name, err, w)
+			}
+		}
+	}
+}
+
+func TestHostCookieIsValid(t *testing.T) {
+	ck := HostCookie("sid", "abc")
+	if ck.Name != "__Host-sid" || !ck.Secure || !ck.HttpOnly || ck.Path != "/" || ck.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("cookie=%+v", ck)
+	}
+	ck.Partitioned = true
+	if err := ValidateCookie(ck); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCtxCookies(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Add("Cookie", "theme=dark; __Host-sid=abc; theme=light")
+	rec := httptest.NewRecorder()
+	var c DefaultContext
+	c.Reset(rec, r, nil, "/")
+
+	got := c.Cookies()
+	if len(got) != 2 || got["theme"] != "dark" || got["__Host-sid"] != "abc" {
+		t.Fatalf("cookies=%v", got)
+	}
+
+	if err := c.SetCookie(&http.Cookie{Name: "__Host-sid", Value: "x", Path: "/"}); !errors.Is(err, ErrInvalidCookie) {
+		t.Fatalf("err=%v", err)
+	}
+	if rec.Header().Get("Set-Cookie") != "" {
+		t.Fatalf("invalid cookie written")
+	}
+	if err := c.SetCookie(HostCookie("sid", "new")); err != nil {
+		t.Fatal(err)
+	}
+	c.ClearCookie("__Secure-old")
+	c.ClearCookie("plain")
+	sc := rec.Header().Values("Set-Cookie")
+	if len(sc) != 3 {
+		t.Fatalf("Set-Cookie=%v", sc)
+	}
+	if !strings.HasPrefix(sc[0], "__Host-sid=new; Path=/; HttpOnly; Secure") {
+		t.Fatalf("set=%q", sc[0])
+	}
+	if !strings.Contains(sc[1], "Max-Age=0") || !strings.Contains(sc[1], "Secure") {
+		t.Fatalf("clear prefixed=%q", sc[1])
+	}
+	if strings.Contains(sc[2], "Secure") || !strings.Contains(sc[2], "Path=/") {
+		t.Fatalf("clear plain=%q", sc[2])
+	}
+}
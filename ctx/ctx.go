@@ -5,15 +5,19 @@ import (
 	"context"
 	"encoding/json"
 	"html"
+	"image"
 	"io"
+	"io/fs"
+	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strconv"
-	"strings"
 	"sync"
+	"time"
 
-	router "github.com/julienschmidt/httprouter"
+	"github.com/goflash/flash/v2/storage"
 )
 
 // Ctx is the request/response context interface exposed to handlers and middleware.
@@ -41,7 +45,8 @@ import (
 //
 // Concurrency: Ctx is not safe for concurrent writes to the underlying
 // http.ResponseWriter. Use Clone() and swap the writer if responding from
-// another goroutine.
+// another goroutine, and Detach() for background work that outlives the
+// handler.
 type Ctx interface {
 	// Request/Response accessors and mutators
 	// Request returns the underlying *http.Request associated with this context.
@@ -72,6 +77,21 @@ type Ctx interface {
 	// Query returns a query string parameter by key ("" if not present).
 	// Example: for "/items?sort=asc", Query("sort") => "asc".
 	Query(key string) string
+	// RequestHeader returns a request header by (case-insensitive) key ("" if not present).
+	RequestHeader(key string) string
+
+	// Typed request header helpers
+	HeaderInt(key string, def ...int) int
+	HeaderTime(key string, def ...time.Time) time.Time // HTTP dates (If-Modified-Since, Date...)
+	HeaderList(key string) []HeaderValue               // comma-separated values ordered by quality
+
+	// Cookies
+	// Cookies returns the request cookies by name (first value wins).
+	Cookies() map[string]string
+	// SetCookie validates ck (see ValidateCookie) and adds it to the response.
+	SetCookie(ck *http.Cookie) error
+	// ClearCookie deletes the cookie name set with Path "/".
+	ClearCookie(name string)
 
 	// Typed path parameter helpers with optional defaults
 	ParamInt(name string, def ...int) int
@@ -94,15 +114,31 @@ type Ctx interface {
 	QueryAlphaNum(key string) string  // Alphanumeric-only query parameter
 	ParamFilename(name string) string // Safe filename parameter (no path traversal)
 	QueryFilename(key string) string  // Safe filename query parameter
+	// ParamSanitized and QuerySanitized clean a value with a named sanitizer
+	// policy (built-in, see SanitizeUUID, or registered with
+	// App.RegisterSanitizer); "" means the policy rejected it.
+	ParamSanitized(name, policy string) string
+	QuerySanitized(key, policy string) string
 
 	// Response helpers
 	// Header sets a response header key/value.
 	Header(key, value string)
+	// AppendHeader adds a response header value, keeping existing ones.
+	AppendHeader(key, value string)
+	// SetHeaders sets several response headers at once.
+	SetHeaders(h map[string]string)
+	// TeeBody copies the response body written from now on to w as it is sent.
+	TeeBody(w io.Writer)
 	// Status stages the HTTP status code to be written; returns the Ctx to allow chaining.
 	// Example: c.Status(http.StatusCreated).JSON(obj)
 	Status(code int) Ctx
 	// StatusCode returns the status that will be written (or 200 after header write, or 0 if unset).
 	StatusCode() int
+	// CacheControl sets Cache-Control (plus Expires/Pragma) from d; returns the Ctx to allow chaining.
+	// Example: c.CacheControl(ctx.CacheDirectives{Public: true, MaxAge: time.Hour}).JSON(obj)
+	CacheControl(d CacheDirectives) Ctx
+	// NoCache forbids caching of the response; returns the Ctx to allow chaining.
+	NoCache() Ctx
 	// JSON serializes v to JSON and writes it with an appropriate Content-Type.
 	// If Status() was not set, it defaults to 200.
 	JSON(v any) error
@@ -110,11 +146,37 @@ type Ctx interface {
 	String(status int, body string) error
 	// Send writes raw bytes with a specific status and content type.
 	Send(status int, contentType string, b []byte) (int, error)
+	// Image encodes img as png, jpeg or gif and writes it.
+	Image(img image.Image, format string, quality int) error
+	// FileFS writes a file from fsys, honouring Range and conditional requests when it is seekable.
+	FileFS(fsys fs.FS, name string) error
+	// Redirect replies with a redirect to location using a 3xx status.
+	Redirect(status int, location string) error
+	// RedirectBack redirects (303) to the same-origin Referer, or to fallback.
+	RedirectBack(fallback string) error
+	// RedirectWithQuery redirects to path, keeping the current query parameters.
+	RedirectWithQuery(status int, path string) error
+	// RedirectToRoute redirects (303) to a named route, filling its params in order.
+	RedirectToRoute(name string, params ...any) error
+	// Feed writes an RSS 2.0 or Atom feed, negotiated from the path extension or Accept header.
+	Feed(f Feed) error
+	// LongPoll holds the request open until poll returns data or wait elapses (204), with resume tokens.
+	LongPoll(ctx context.Context, wait time.Duration, poll PollFunc, opts ...LongPollOptions) error
+	// Trace records a named operation and its duration in the request's trace timeline; see TraceContext.
+	Trace(name string, d time.Duration, attrs ...any)
+	// TraceEvents returns the events recorded with Trace so far.
+	TraceEvents() []TraceEvent
 	// WroteHeader reports whether the header has already been written to the client.
 	WroteHeader() bool
 
 	// BindJSON decodes request body JSON into v with strict defaults; see BindJSONOptions.
 	BindJSON(v any, opts ...BindJSONOptions) error
+	// BindJSONPatch applies an RFC 6902 JSON Patch from the body to dst; see PatchOptions.
+	BindJSONPatch(dst any, opts ...PatchOptions) error
+	// BindMergePatch applies an RFC 7386 JSON Merge Patch from the body to dst; see PatchOptions.
+	BindMergePatch(dst any, opts ...PatchOptions) error
+	// RequireIfMatch checks If-Match against the resource's current ETag, returning a 412 or 428 *HTTPError.
+	RequireIfMatch(currentETag string) error
 
 	// BindMap binds from a generic map (e.g. collected from body/query/path) into v using mapstructure.
 	// Options mirror BindJSONOptions.
@@ -132,6 +194,16 @@ type Ctx interface {
 	// BindAny collects from path, body (json/form), and query according to priority and binds them into v.
 	BindAny(v any, opts ...BindJSONOptions) error
 
+	// ValidateUploads checks multipart files against p, returning FieldErrors on violations.
+	ValidateUploads(p UploadPolicy) error
+	// SaveUploadedFile writes an uploaded file to dst, scanning it if the route's UploadPolicy has a Scanner.
+	SaveUploadedFile(fh *multipart.FileHeader, dst string) error
+	// SaveUploadedFileFS writes an uploaded file to fsys under name, scanning it like SaveUploadedFile.
+	SaveUploadedFileFS(fh *multipart.FileHeader, fsys storage.FS, name string) error
+
+	// JSONDecoder returns a json.Decoder over the request body for token-level or streaming decoding.
+	JSONDecoder() *json.Decoder
+
 	// Utilities
 	// Get retrieves a value from the request context by key, with optional default.
 	Get(key any, def ...any) any
@@ -140,6 +212,40 @@ type Ctx interface {
 
 	// Clone returns a shallow copy of the context suitable for use in a separate goroutine.
 	Clone() Ctx
+	// Detach returns a read-only snapshot of the request, usable as a context.Context after the handler returns.
+	Detach() *Detached
+
+	// Request-scoped temp files
+	// TempFile creates a temporary file removed automatically when the request finishes.
+	TempFile(pattern string) (*TempFile, error)
+	// TempDir creates a temporary directory removed automatically when the request finishes.
+	TempDir() (string, error)
+
+	// Flash messages
+	// Flash queues a one-time message for the next request (session or signed cookie backed).
+	Flash(kind, message string) error
+	// Flashes returns and clears the pending flash messages.
+	Flashes() []FlashMessage
+
+	// Middleware bypass
+	// BypassMiddleware asks named middleware that run later in the chain to skip this request.
+	BypassMiddleware(names ...string)
+	// Bypassed reports whether the named middleware should skip this request.
+	Bypassed(name string) bool
+
+	// Static assets
+	// AssetPath returns the URL path of a fingerprinted static asset (see app.Assets).
+	AssetPath(name string) string
+
+	// Route metadata
+	// RouteMeta returns metadata the matched route attached with Route.Meta.
+	RouteMeta(key string) (any, bool)
+
+	// Response hooks
+	// OnCommit registers fn to run after a successful response (no error, status < 400).
+	OnCommit(fn func(ctx context.Context))
+	// AfterResponse registers fn to run after the response with its final status.
+	AfterResponse(fn func(ctx context.Context, status int))
 }
 
 // DefaultContext is the concrete implementation of Ctx used by goflash.
@@ -149,25 +255,44 @@ type Ctx interface {
 // Handlers generally accept the interface type (ctx.Ctx), not *DefaultContext, to
 // allow substituting alternative implementations if desired.
 type DefaultContext struct {
-	w           http.ResponseWriter // underlying response writer
-	r           *http.Request       // underlying request
-	params      router.Params       // route parameters
-	status      int                 // status code to write
-	wroteHeader bool                // whether header was written
-	wroteBytes  int                 // number of bytes written
-	route       string              // route pattern (e.g., /users/:id)
-	jsonEscape  bool                // whether JSON encoder escapes HTML (default true)
+	w           http.ResponseWriter                // underlying response writer
+	r           *http.Request                      // underlying request
+	params      Params                             // route parameters
+	status      int                                // status code to write
+	wroteHeader bool                               // whether header was written
+	wroteBytes  int                                // number of bytes written
+	route       string                             // route pattern (e.g., /users/:id)
+	jsonEscape  bool                               // whether JSON encoder escapes HTML (default true)
+	bindOpts    *BindJSONOptions                   // default binding options for this route (nil = built-in defaults)
+	tempLimits  *TempLimits                        // limits for TempFile/TempDir (nil = unlimited)
+	tmp         *tempStore                         // temp artifacts created by this request (lazily allocated)
+	flashStore  FlashStore                         // default flash message store (nil = none)
+	routeBypass []string                           // middleware names the route opts out of (shared, read-only)
+	bypass      []string                           // middleware names bypassed by this request
+	routeMeta   map[string]any                     // metadata of the matched route (shared, read-only)
+	hooks       *hookList                          // OnCommit/AfterResponse hooks (lazily allocated, shared with clones)
+	scanned     map[*multipart.FileHeader]struct{} // uploads scanned clean by an UploadScanner
+	assets      AssetResolver                      // fingerprinted asset paths (nil = none)
+	urls        RouteURLBuilder                    // named route URLs for RedirectToRoute (nil = none)
+	query       url.Values                         // parsed query string (lazily parsed, see queryValues)
+	queryRaw    string                             // RawQuery that query was parsed from
+	formParsed  bool                               // whether parseForm ran for this request
+	formErr     error                              // result of the first parseForm
+	sanitizers  map[string]Sanitizer               // app-registered sanitizer policies (shared, read-only)
+	tee         *teeWriter                         // response body tee (see TeeBody)
 }
 
 // Reset prepares the context for a new request. Used internally by the framework.
 // It swaps in the writer, request, params and route pattern, and clears any
-// response state. Libraries and middleware should not need to call Reset.
+// response state. ps is kept as given, not copied, so the router's params
+// slice is the only one per request. Libraries and middleware should not need
+// to call Reset.
 //
 // Example:
 //
 //	// internal server code
 //	dctx.Reset(w, r, params, "/users/:id")
-func (c *DefaultContext) Reset(w http.ResponseWriter, r *http.Request, ps router.Params, route string) {
+func (c *DefaultContext) Reset(w http.ResponseWriter, r *http.Request, ps Params, route string) {
 	c.w = w
 	c.r = r
 	c.params = ps
@@ -176,13 +301,48 @@ func (c *DefaultContext) Reset(w http.ResponseWriter, r *http.Request, ps router
 	c.wroteBytes = 0
 	c.route = route
 	c.jsonEscape = true
+	c.bindOpts = nil
+	c.tempLimits = nil
+	c.tmp = nil
+	c.flashStore = nil
+	c.routeBypass = nil
+	c.bypass = c.bypass[:0]
+	c.routeMeta = nil
+	c.hooks = nil
+	c.scanned = nil
+	c.assets = nil
+	c.urls = nil
+	c.query = nil
+	c.queryRaw = ""
+	c.formParsed = false
+	c.formErr = nil
+	c.sanitizers = nil
+	c.tee = nil
+}
+
+// SetLogger attaches l to the request context (see ContextWithLogger). It is
+// called once per request, before any handler runs, so Request and Context
+// never modify the context and are safe to call from several goroutines. Used
+// internally by the app to inject its logger.
+//
+// Example:
+//
+//	c.SetLogger(appLogger)
+//	l := ctx.LoggerFromContext(c.Context()) // == appLogger
+func (c *DefaultContext) SetLogger(l *slog.Logger) {
+	if l != nil {
+		c.r = c.r.WithContext(ContextWithLogger(c.r.Context(), l))
+	}
 }
 
-// Finish is a hook for context cleanup after request handling. No-op by default.
+// Finish is a hook for context cleanup after request handling. It closes and
+// removes temp files and directories created via TempFile and TempDir.
 // Frameworks may override or extend this method to release per-request resources.
 func (c *DefaultContext) Finish() {
-	// Reserved for future cleanup; reference receiver to create a coverable statement.
-	_ = c
+	if c.tmp != nil {
+		c.tmp.cleanup()
+		c.tmp = nil
+	}
 }
 
 // Request returns the underlying *http.Request.
@@ -258,7 +418,7 @@ func (c *DefaultContext) Path() string { return c.r.URL.Path }
 func (c *DefaultContext) Route() string { return c.route }
 
 // Param returns a path parameter by name. Returns "" if not found.
-// Note: router.Params.ByName returns "" if not found, so this avoids extra allocation.
+// Note: Params.ByName returns "" if not found, so this avoids extra allocation.
 //
 // Example:
 //
@@ -267,13 +427,27 @@ func (c *DefaultContext) Route() string { return c.route }
 func (c *DefaultContext) Param(name string) string { return c.params.ByName(name) }
 
 // Query returns a query string parameter by key. Returns "" if not found.
-// Note: url.Values.Get returns "" if not found, so this avoids extra allocation.
+// The query string is parsed once per request and cached, so handlers reading
+// many parameters (and the typed QueryInt, QueryBool... helpers) do not
+// re-parse the URL on every call.
 //
 // Example:
 //
 //	// URL: /search?q=flash
 //	q := c.Query("q")
-func (c *DefaultContext) Query(key string) string { return c.r.URL.Query().Get(key) }
+func (c *DefaultContext) Query(key string) string { return c.queryValues().Get(key) }
+
+// queryValues returns the parsed query string, parsing it on first use. The
+// cache remembers the RawQuery it was parsed from, so a request swapped in
+// with SetRequest or a URL rewritten by middleware is parsed again. Callers
+// must not modify the returned values.
+func (c *DefaultContext) queryValues() url.Values {
+	if c.query == nil || c.queryRaw != c.r.URL.RawQuery {
+		c.query, _ = url.ParseQuery(c.r.URL.RawQuery)
+		c.queryRaw = c.r.URL.RawQuery
+	}
+	return c.query
+}
 
 // ParamInt returns the named path parameter parsed as int.
 // Returns def (or 0) on missing or parse error.
@@ -513,6 +687,7 @@ func (c *DefaultContext) JSON(v any) error {
 		// if header not written, send 500
 		if !c.wroteHeader {
 			c.w.WriteHeader(http.StatusInternalServerError)
+			c.status = http.StatusInternalServerError
 			c.wroteHeader = true
 		}
 		return err
@@ -550,6 +725,7 @@ func (c *DefaultContext) String(status int, body string) error {
 		c.Header("Content-Type", "text/plain; charset=utf-8")
 		c.Header("Content-Length", strconv.Itoa(len(body)))
 		c.w.WriteHeader(status)
+		c.status = status
 		c.wroteHeader = true
 	}
 	n, err := io.WriteString(c.w, body)
@@ -572,6 +748,7 @@ func (c *DefaultContext) Send(status int, contentType string, b []byte) (int, er
 		}
 		c.Header("Content-Length", strconv.Itoa(len(b)))
 		c.w.WriteHeader(status)
+		c.status = status
 		c.wroteHeader = true
 	}
 	n, err := c.w.Write(b)
@@ -581,8 +758,14 @@ func (c *DefaultContext) Send(status int, contentType string, b []byte) (int, er
 
 // Clone returns a shallow copy of the context.
 // Safe for use across goroutines as long as the ResponseWriter is swapped to a
-// concurrency-safe writer if needed.
-func (c *DefaultContext) Clone() Ctx { cp := *c; return &cp }
+// concurrency-safe writer if needed. Clone itself prepares state shared with
+// the copy, so call it from the goroutine handling the request.
+func (c *DefaultContext) Clone() Ctx {
+	c.temps()         // share one temp store so Finish also removes the clone's artifacts
+	c.responseHooks() // and one hook list so hooks registered on the clone run
+	cp := *c
+	return &cp
+}
 
 // Security-focused parameter and query helpers for input validation and sanitization.
 // These methods help prevent common security vulnerabilities like XSS, path traversal,
@@ -637,14 +820,7 @@ func (c *DefaultContext) ParamAlphaNum(name string) string {
 		return ""
 	}
 
-	// Extract only alphanumeric characters
-	var result strings.Builder
-	for _, r := range param {
-		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
-			result.WriteRune(r)
-		}
-	}
-	return result.String()
+	return sanitizeAlphaNum(param)
 }
 
 // QueryAlphaNum returns a query parameter containing only alphanumeric characters.
@@ -662,14 +838,7 @@ func (c *DefaultContext) QueryAlphaNum(key string) string {
 		return ""
 	}
 
-	// Extract only alphanumeric characters
-	var result strings.Builder
-	for _, r := range query {
-		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
-			result.WriteRune(r)
-		}
-	}
-	return result.String()
+	return sanitizeAlphaNum(query)
 }
 
 // ParamFilename returns a path parameter as a safe filename.
@@ -692,27 +861,7 @@ func (c *DefaultContext) ParamFilename(name string) string {
 		return ""
 	}
 
-	// URL decode first to handle encoded path traversal attempts
-	decoded, err := url.QueryUnescape(param)
-	if err != nil {
-		decoded = param
-	}
-
-	// Extract only safe filename characters
-	var result strings.Builder
-	for _, r := range decoded {
-		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') ||
-			r == '.' || r == '-' || r == '_' {
-			result.WriteRune(r)
-		}
-	}
-
-	filename := result.String()
-
-	// Prevent hidden files and relative paths
-	filename = strings.TrimPrefix(filename, ".")
-
-	return filename
+	return sanitizeFilename(param)
 }
 
 // QueryFilename returns a query parameter as a safe filename.
@@ -734,25 +883,5 @@ func (c *DefaultContext) QueryFilename(key string) string {
 		return ""
 	}
 
-	// URL decode first to handle encoded path traversal attempts
-	decoded, err := url.QueryUnescape(query)
-	if err != nil {
-		decoded = query
-	}
-
-	// Extract only safe filename characters
-	var result strings.Builder
-	for _, r := range decoded {
-		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') ||
-			r == '.' || r == '-' || r == '_' {
-			result.WriteRune(r)
-		}
-	}
-
-	filename := result.String()
-
-	// Prevent hidden files and relative paths
-	filename = strings.TrimPrefix(filename, ".")
-
-	return filename
+	return sanitizeFilename(query)
 }
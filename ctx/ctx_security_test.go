@@ -3,8 +3,6 @@ package ctx
 import (
 	"net/http/httptest"
 	"testing"
-
-	router "github.com/julienschmidt/httprouter"
 )
 
 // TestSecurityHelpers tests the new security-focused parameter and query helpers
@@ -119,9 +117,9 @@ func TestSecurityHelpers(t *testing.T) {
 			w := httptest.NewRecorder()
 			r := httptest.NewRequest("GET", "/", nil)
 
-			var params router.Params
+			var params Params
 			if tt.method == "param" {
-				params = router.Params{{Key: "test", Value: tt.input}}
+				params = Params{{Key: "test", Value: tt.input}}
 			} else {
 				// For query tests, add the parameter to the URL
 				q := r.URL.Query()
@@ -195,7 +193,7 @@ func TestSecurityHelpersEmptyValues(t *testing.T) {
 func TestSecurityHelpersUnicodeHandling(t *testing.T) {
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest("GET", "/?test=café123", nil)
-	params := router.Params{{Key: "test", Value: "café123"}}
+	params := Params{{Key: "test", Value: "café123"}}
 
 	ctx := &DefaultContext{}
 	ctx.Reset(w, r, params, "/test")
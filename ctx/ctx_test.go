@@ -3,12 +3,14 @@ package ctx
 import (
 	"bytes"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
+	"sync"
 	"testing"
 
-	"github.com/julienschmidt/httprouter"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -27,6 +29,7 @@ func TestStringWritesStatusHeadersAndBody(t *testing.T) {
 	require.NoError(t, c.String(http.StatusCreated, "hello"))
 	assert.True(t, c.WroteHeader())
 	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, http.StatusCreated, c.StatusCode())
 	assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
 	assert.Equal(t, "5", rec.Header().Get("Content-Length"))
 	assert.Equal(t, "hello", rec.Body.String())
@@ -98,7 +101,7 @@ func TestParamQueryMethodPathRoute(t *testing.T) {
 	u := &url.URL{Scheme: "http", Host: "example.com", Path: "/users/123", RawQuery: "q=go"}
 	req := &http.Request{Method: http.MethodGet, URL: u}
 	rec := httptest.NewRecorder()
-	ps := httprouter.Params{httprouter.Param{Key: "id", Value: "123"}}
+	ps := Params{Param{Key: "id", Value: "123"}}
 	var c DefaultContext
 	c.Reset(rec, req, ps, "/users/:id")
 	assert.Equal(t, "GET", c.Method())
@@ -112,7 +115,7 @@ func TestTypedParamHelpers(t *testing.T) {
 	u := &url.URL{Scheme: "http", Host: "example.com", Path: "/u/42/3.14/true"}
 	req := &http.Request{Method: http.MethodGet, URL: u}
 	rec := httptest.NewRecorder()
-	ps := httprouter.Params{
+	ps := Params{
 		{Key: "id", Value: "42"},
 		{Key: "pi", Value: "3.14"},
 		{Key: "ok", Value: "true"},
@@ -130,7 +133,7 @@ func TestTypedParamHelpers(t *testing.T) {
 	assert.Equal(t, -1, c.ParamInt("missing", -1))
 	// No default provided -> zero value
 	assert.Equal(t, 0, c.ParamInt("missing"))
-	ps = append(ps, httprouter.Param{Key: "bad", Value: "xx"})
+	ps = append(ps, Param{Key: "bad", Value: "xx"})
 	c.params = ps
 	assert.Equal(t, 7, c.ParamInt("bad", 7))
 }
@@ -265,7 +268,7 @@ func TestCtx_SetGet(t *testing.T) {
 func TestCtx_Clone_ShallowCopy(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/a/123?x=1", nil)
 	rec := httptest.NewRecorder()
-	ps := httprouter.Params{{Key: "id", Value: "123"}}
+	ps := Params{{Key: "id", Value: "123"}}
 	var c DefaultContext
 	c.Reset(rec, req, ps, "/a/:id")
 	c.Header("X-Test", "1")
@@ -308,7 +311,7 @@ func TestTypedHelpers_DefaultAndNoDefaultFallbacks(t *testing.T) {
 	u := &url.URL{Scheme: "http", Host: "example.com", Path: "/p/xx/yy/-1/x/maybe", RawQuery: q.Encode()}
 	req := &http.Request{Method: http.MethodGet, URL: u}
 	rec := httptest.NewRecorder()
-	ps := httprouter.Params{
+	ps := Params{
 		{Key: "pi", Value: "xx"},    // invalid int
 		{Key: "pi64", Value: "yy"},  // invalid int64
 		{Key: "pu", Value: "-1"},    // invalid uint
@@ -364,7 +367,7 @@ func TestTypedHelpers_DefaultAndNoDefaultFallbacks(t *testing.T) {
 func TestParamInt64WithInvalidValue(t *testing.T) {
 	req, rec := newRequest(http.MethodGet, "/user/invalid", nil)
 	var c DefaultContext
-	c.Reset(rec, req, httprouter.Params{{Key: "id", Value: "invalid"}}, "/user/:id")
+	c.Reset(rec, req, Params{{Key: "id", Value: "invalid"}}, "/user/:id")
 
 	// Should return default value when parsing fails
 	assert.Equal(t, int64(42), c.ParamInt64("id", 42))
@@ -404,7 +407,7 @@ func TestQueryBoolWithInvalidValue(t *testing.T) {
 func TestParamFilenameWithInvalidCharacters(t *testing.T) {
 	req, rec := newRequest(http.MethodGet, "/file/invalid..file", nil)
 	var c DefaultContext
-	c.Reset(rec, req, httprouter.Params{{Key: "filename", Value: "invalid..file"}}, "/file/:filename")
+	c.Reset(rec, req, Params{{Key: "filename", Value: "invalid..file"}}, "/file/:filename")
 
 	// Should return sanitized filename (dots are allowed)
 	result := c.ParamFilename("filename")
@@ -427,7 +430,7 @@ func TestParamInt64EdgeCases(t *testing.T) {
 	// Test ParamInt64 with various edge cases
 	req, rec := newRequest(http.MethodGet, "/user/9223372036854775807", nil)
 	var c DefaultContext
-	c.Reset(rec, req, httprouter.Params{{Key: "id", Value: "9223372036854775807"}}, "/user/:id")
+	c.Reset(rec, req, Params{{Key: "id", Value: "9223372036854775807"}}, "/user/:id")
 
 	// Test max int64
 	assert.Equal(t, int64(9223372036854775807), c.ParamInt64("id"))
@@ -489,7 +492,7 @@ func TestParamFilenameEdgeCases(t *testing.T) {
 	// Test with path traversal attempts
 	req, rec := newRequest(http.MethodGet, "/file/..%2F..%2Fetc%2Fpasswd", nil)
 	var c DefaultContext
-	c.Reset(rec, req, httprouter.Params{{Key: "filename", Value: "../../../etc/passwd"}}, "/file/:filename")
+	c.Reset(rec, req, Params{{Key: "filename", Value: "../../../etc/passwd"}}, "/file/:filename")
 
 	// Should sanitize path traversal
 	result := c.ParamFilename("filename")
@@ -498,7 +501,7 @@ func TestParamFilenameEdgeCases(t *testing.T) {
 
 	// Test empty filename
 	req2, rec2 := newRequest(http.MethodGet, "/file/", nil)
-	c.Reset(rec2, req2, httprouter.Params{{Key: "filename", Value: ""}}, "/file/:filename")
+	c.Reset(rec2, req2, Params{{Key: "filename", Value: ""}}, "/file/:filename")
 	assert.Equal(t, "", c.ParamFilename("filename"))
 }
 
@@ -540,7 +543,7 @@ func TestParamFilenameWithSpecialCharacters(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/test", nil)
 
 		var c DefaultContext
-		c.Reset(rec, req, httprouter.Params{{Key: "filename", Value: tc.input}}, "/test")
+		c.Reset(rec, req, Params{{Key: "filename", Value: tc.input}}, "/test")
 
 		result := c.ParamFilename("filename")
 		if result != tc.expected {
@@ -578,3 +581,179 @@ func TestQueryFilenameWithSpecialCharacters(t *testing.T) {
 		}
 	}
 }
+
+func TestSetLogger_AttachedOnce(t *testing.T) {
+	req, rec := newRequest(http.MethodGet, "/", nil)
+	var c DefaultContext
+	c.Reset(rec, req, nil, "/")
+	l := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c.SetLogger(l)
+	r1 := c.Request()
+	if r1 == req || LoggerFromContext(r1.Context()) != l {
+		t.Fatalf("logger not attached by SetLogger")
+	}
+	// Getters only read the request.
+	if LoggerFromContext(c.Context()) != l || c.Request() != r1 {
+		t.Fatalf("Context or Request modified the request")
+	}
+}
+
+func TestSetLogger_ConcurrentGetters(t *testing.T) {
+	req, rec := newRequest(http.MethodGet, "/", nil)
+	var c DefaultContext
+	c.Reset(rec, req, nil, "/")
+	l := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c.SetLogger(l)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if LoggerFromContext(c.Context()) != l || LoggerFromContext(c.Request().Context()) != l {
+				t.Errorf("logger missing")
+			}
+			if d := c.Detach(); LoggerFromContext(d) != l {
+				t.Errorf("detached logger missing")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSetLogger_CloneAndSetRequestAndReset(t *testing.T) {
+	req, rec := newRequest(http.MethodGet, "/", nil)
+	var c DefaultContext
+	c.Reset(rec, req, nil, "/")
+	l := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	c.SetLogger(l)
+	cl := c.Clone()
+	if LoggerFromContext(cl.Context()) != l || LoggerFromContext(c.Context()) != l {
+		t.Fatalf("clone should share attached logger")
+	}
+
+	c.SetRequest(req)
+	if LoggerFromContext(c.Context()) == l {
+		t.Fatalf("SetRequest should replace the request and its logger")
+	}
+
+	c.SetLogger(l)
+	c.Reset(rec, req, nil, "/")
+	if LoggerFromContext(c.Context()) == l {
+		t.Fatalf("Reset should drop the previous request's logger")
+	}
+}
+
+func TestSendRecordsStatusCode(t *testing.T) {
+	req, rec := newRequest(http.MethodGet, "/", nil)
+	var c DefaultContext
+	c.Reset(rec, req, nil, "/")
+	_, err := c.Send(http.StatusAccepted, "text/plain", []byte("x"))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, c.StatusCode())
+}
+
+type prefixAssets string
+
+func (p prefixAssets) AssetPath(name string) string { return string(p) + name }
+
+func TestCtx_AssetPath(t *testing.T) {
+	var c DefaultContext
+	c.Reset(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil, "/")
+	if got := c.AssetPath("app.js"); got != "app.js" {
+		t.Fatalf("without assets got %q", got)
+	}
+	c.SetAssets(prefixAssets("/static/"))
+	if got := c.AssetPath("app.js"); got != "/static/app.js" {
+		t.Fatalf("with assets got %q", got)
+	}
+	c.Reset(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil, "/")
+	if got := c.AssetPath("app.js"); got != "app.js" {
+		t.Fatalf("Reset must clear assets, got %q", got)
+	}
+}
+
+func TestQueryParsedOnceAndRefreshedOnChange(t *testing.T) {
+	req, rec := newRequest(http.MethodGet, "/?a=1&b=2", nil)
+	var c DefaultContext
+	c.Reset(rec, req, nil, "/")
+	assert.Equal(t, "1", c.Query("a"))
+	assert.Equal(t, "2", c.Query("b"))
+	assert.Equal(t, 2, c.QueryInt("b"))
+	// same map reused while the raw query is unchanged
+	c.query["a"] = []string{"cached"}
+	assert.Equal(t, "cached", c.Query("a"))
+
+	// middleware rewriting the URL invalidates the cache
+	req.URL.RawQuery = "a=3"
+	assert.Equal(t, "3", c.Query("a"))
+	assert.Equal(t, "", c.Query("b"))
+
+	// so does swapping the request
+	c.SetRequest(httptest.NewRequest(http.MethodGet, "/?a=4", nil))
+	assert.Equal(t, "4", c.Query("a"))
+
+	// and Reset for the next request
+	req2, rec2 := newRequest(http.MethodGet, "/?a=5", nil)
+	c.Reset(rec2, req2, nil, "/")
+	assert.Nil(t, c.query)
+	assert.Equal(t, "5", c.Query("a"))
+}
+
+func TestQueryCacheNotSharedAcrossReset(t *testing.T) {
+	req, rec := newRequest(http.MethodGet, "/?q=x", nil)
+	var c DefaultContext
+	c.Reset(rec, req, nil, "/")
+	assert.Equal(t, "x", c.Query("q"))
+	req2, rec2 := newRequest(http.MethodGet, "/?q=x", nil)
+	c.Reset(rec2, req2, nil, "/")
+	assert.Equal(t, "x", c.Query("q"))
+	req3, rec3 := newRequest(http.MethodGet, "/", nil)
+	c.Reset(rec3, req3, nil, "/")
+	assert.Equal(t, "", c.Query("q"))
+}
+
+func TestParseFormErrorRemembered(t *testing.T) {
+	req, rec := newRequest(http.MethodPost, "/", strings.NewReader("a=%zz"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var c DefaultContext
+	c.Reset(rec, req, nil, "/")
+	var v struct {
+		A string `json:"a"`
+	}
+	require.Error(t, c.BindForm(&v))
+	// net/http would report success on a second ParseForm
+	require.Error(t, c.BindForm(&v))
+
+	req2, rec2 := newRequest(http.MethodPost, "/", strings.NewReader("a=ok"))
+	req2.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	c.Reset(rec2, req2, nil, "/")
+	require.NoError(t, c.BindForm(&v))
+	assert.Equal(t, "ok", v.A)
+}
+
+func BenchmarkQuery_ManyParams(b *testing.B) {
+	req, rec := newRequest(http.MethodGet, "/search?q=flash&page=2&per_page=50&sort=name&order=asc&active=true&min=1.5&tag=go", nil)
+	keys := []string{"q", "page", "per_page", "sort", "order", "active", "min", "tag"}
+	var c DefaultContext
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Reset(rec, req, nil, "/search")
+		for _, k := range keys {
+			_ = c.Query(k)
+		}
+	}
+}
+
+func BenchmarkQuery_URLValuesPerCall(b *testing.B) {
+	req, _ := newRequest(http.MethodGet, "/search?q=flash&page=2&per_page=50&sort=name&order=asc&active=true&min=1.5&tag=go", nil)
+	keys := []string{"q", "page", "per_page", "sort", "order", "active", "min", "tag"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, k := range keys {
+			_ = req.URL.Query().Get(k)
+		}
+	}
+}
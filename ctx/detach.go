@@ -0,0 +1,95 @@
+package ctx
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+)
+
+// Detached is a read-only snapshot of a request, taken with Ctx.Detach, that
+// stays valid after the handler returns. It is a context.Context carrying the
+// request context's values (logger, request ID, trace spans, values stored
+// with Set) without its cancellation and deadline, so work started for the
+// request is not aborted when the response is sent.
+//
+// The Ctx itself must not be used after the handler returns: the framework
+// resets and reuses it for the next request, so a goroutine reading it may see
+// another request's params, headers or values. Detach copies what background
+// work typically needs instead.
+type Detached struct {
+	context.Context
+
+	method string
+	path   string
+	route  string
+	params Params
+	query  url.Values
+	header http.Header
+	meta   map[string]any
+}
+
+// Detach returns a snapshot of the request for use by goroutines that may
+// outlive the handler. See Detached.
+//
+// Example:
+//
+//	d := c.Detach()
+//	go func() {
+//		d.Logger().Info("sending welcome email", "user", d.Param("id"))
+//		_ = mailer.Send(d, d.Param("id")) // d is a context.Context
+//	}()
+//	return c.String(http.StatusAccepted, "queued")
+func (c *DefaultContext) Detach() *Detached {
+	d := &Detached{
+		Context: context.WithoutCancel(c.r.Context()),
+		method:  c.r.Method,
+		path:    c.r.URL.Path,
+		route:   c.route,
+		query:   c.r.URL.Query(),
+		header:  c.r.Header.Clone(),
+		meta:    c.routeMeta, // shared and read-only, see SetRouteMeta
+	}
+	if len(c.params) > 0 {
+		d.params = append(Params(nil), c.params...)
+	}
+	return d
+}
+
+// Method returns the HTTP method of the request.
+func (d *Detached) Method() string { return d.method }
+
+// Path returns the request URL path.
+func (d *Detached) Path() string { return d.path }
+
+// Route returns the route pattern of the request, e.g. "/users/:id".
+func (d *Detached) Route() string { return d.route }
+
+// Param returns a path parameter by name ("" if not present).
+func (d *Detached) Param(name string) string { return d.params.ByName(name) }
+
+// Query returns a query string parameter by key ("" if not present).
+func (d *Detached) Query(key string) string { return d.query.Get(key) }
+
+// Header returns the first value of the request header key ("" if not present).
+func (d *Detached) Header(key string) string { return d.header.Get(key) }
+
+// Get returns a value of the request context by key, like Ctx.Get.
+func (d *Detached) Get(key any, def ...any) any {
+	if v := d.Value(key); v != nil {
+		return v
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	return nil
+}
+
+// Logger returns the request's logger (see LoggerFromContext).
+func (d *Detached) Logger() *slog.Logger { return LoggerFromContext(d) }
+
+// RouteMeta returns metadata the matched route attached with app.Route.Meta.
+func (d *Detached) RouteMeta(key string) (any, bool) {
+	v, ok := d.meta[key]
+	return v, ok
+}
@@ -0,0 +1,66 @@
+package ctx
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type detachKey struct{}
+
+func TestDetach_SurvivesReset(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/users/7?notify=yes", nil)
+	req.Header.Set("X-Request-ID", "abc")
+	reqCtx, cancel := context.WithCancel(req.Context())
+	req = req.WithContext(reqCtx)
+	logger := slog.New(slog.NewTextHandler(nil, nil))
+
+	var c DefaultContext
+	c.Reset(httptest.NewRecorder(), req, Params{{Key: "id", Value: "7"}}, "/users/:id")
+	c.SetLogger(logger)
+	c.SetRouteMeta(map[string]any{"team": "growth"})
+	c.Set(detachKey{}, "v")
+	d := c.Detach()
+
+	// The request ends and the pooled context is reused for another request.
+	cancel()
+	c.Finish()
+	c.Reset(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/other", nil), Params{{Key: "id", Value: "9"}}, "/other")
+
+	if d.Err() != nil {
+		t.Fatalf("detached context canceled with the request: %v", d.Err())
+	}
+	if d.Method() != http.MethodPost || d.Path() != "/users/7" || d.Route() != "/users/:id" {
+		t.Fatalf("request data: %s %s %s", d.Method(), d.Path(), d.Route())
+	}
+	if d.Param("id") != "7" || d.Query("notify") != "yes" || d.Header("X-Request-ID") != "abc" {
+		t.Fatalf("param/query/header: %q %q %q", d.Param("id"), d.Query("notify"), d.Header("X-Request-ID"))
+	}
+	if d.Get(detachKey{}) != "v" || d.Get("missing", 1) != 1 || d.Get("missing") != nil {
+		t.Fatalf("values not preserved")
+	}
+	if d.Logger() != logger {
+		t.Fatalf("logger not preserved")
+	}
+	if v, ok := d.RouteMeta("team"); !ok || v != "growth" {
+		t.Fatalf("route meta: %v %v", v, ok)
+	}
+}
+
+func TestDetach_CopiesParams(t *testing.T) {
+	ps := Params{{Key: "id", Value: "1"}}
+	var c DefaultContext
+	c.Reset(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/x/1", nil), ps, "/x/:id")
+	d := c.Detach()
+	ps[0].Value = "2" // the router reuses its params slices
+	if d.Param("id") != "1" {
+		t.Fatalf("params aliased: %q", d.Param("id"))
+	}
+
+	c.Reset(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil, "/")
+	if d := c.Detach(); d.Param("id") != "" || d.Route() != "/" {
+		t.Fatalf("empty params: %q", d.Param("id"))
+	}
+}
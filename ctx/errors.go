@@ -0,0 +1,127 @@
+package ctx
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// HTTPError is an error that carries the HTTP status code to respond with.
+//
+// Handlers and helpers return *HTTPError when a failure maps to a specific
+// client-facing status (e.g., 413 for oversized bodies). The default error
+// handler writes Code and Message; custom error handlers can detect it with
+// errors.As.
+//
+// Example:
+//
+//	if !allowed {
+//		return ctx.NewError(http.StatusForbidden, "not allowed")
+//	}
+//
+// Example (custom error handler):
+//
+//	var he *ctx.HTTPError
+//	if errors.As(err, &he) {
+//		_ = c.Status(he.Code).JSON(map[string]any{"error": he.Message})
+//		return
+//	}
+type HTTPError struct {
+	// Code is the HTTP status code, e.g. http.StatusUnprocessableEntity.
+	Code int
+	// Message is a client-safe description. Defaults to http.StatusText(Code).
+	Message string
+	// Err is the underlying cause, if any. It is not exposed to clients.
+	Err error
+}
+
+// NewError returns an *HTTPError with the given status code and message.
+// An empty message falls back to http.StatusText(code).
+func NewError(code int, message string) *HTTPError {
+	if message == "" {
+		message = http.StatusText(code)
+	}
+	return &HTTPError{Code: code, Message: message}
+}
+
+// Error returns the message, followed by the underlying cause when present.
+func (e *HTTPError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.Code)
+	}
+	if e.Err != nil && e.Err.Error() != msg {
+		return msg + ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying cause so errors.Is/As can inspect it.
+func (e *HTTPError) Unwrap() error { return e.Err }
+
+// WithErr returns a copy of e with Err set to cause.
+//
+// Example:
+//
+//	return ctx.NewError(http.StatusBadGateway, "upstream failed").WithErr(err)
+func (e *HTTPError) WithErr(cause error) *HTTPError {
+	cp := *e
+	cp.Err = cause
+	return &cp
+}
+
+// ErrNotFound signals a soft 404: the route matched, but the resource it
+// names does not exist (e.g. a wildcard route with an unknown page). The app
+// answers it exactly like an unmatched route, through its NotFound handler or
+// 404 error page and the not-found metric, instead of the error handler.
+// Wrapped errors are recognized too. It is a 404 *HTTPError, so error
+// handlers that still see it (e.g. after the response was started) map it
+// correctly.
+//
+// Example:
+//
+//	a.GET("/docs/*page", func(c flash.Ctx) error {
+//		doc, ok := docs[c.Param("page")]
+//		if !ok {
+//			return flash.ErrNotFound
+//		}
+//		return c.HTML(doc)
+//	})
+var ErrNotFound error = NewError(http.StatusNotFound, "")
+
+// PanicError is the error produced when a handler panics and the panic is
+// recovered (see middleware.Recover). It is passed to the app's error handler
+// like any other error, so panics can be rendered, reported and classified in
+// one place.
+//
+// Value is the value passed to panic. Stack holds the goroutine stack captured
+// at recovery time when stack capture is enabled; it must never be sent to
+// clients.
+//
+// Example (custom error handler):
+//
+//	var pe *ctx.PanicError
+//	if errors.As(err, &pe) {
+//		logger.Error("panic", "value", pe.Value, "stack", string(pe.Stack))
+//		_ = c.String(http.StatusInternalServerError, "internal error")
+//		return
+//	}
+type PanicError struct {
+	// Value is the recovered panic value.
+	Value any
+	// Stack is the stack trace at the point of recovery, or nil if not captured.
+	Stack []byte
+}
+
+// Error describes the panic value.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns Value when it is an error (e.g., runtime errors or
+// panic(err)), so errors.Is/As can inspect it.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package ctx
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestNewError_DefaultsMessage(t *testing.T) {
+	e := NewError(http.StatusNotFound, "")
+	if e.Code != http.StatusNotFound || e.Message != "Not Found" {
+		t.Fatalf("unexpected: %+v", e)
+	}
+	if e.Error() != "Not Found" {
+		t.Fatalf("Error() = %q", e.Error())
+	}
+}
+
+func TestHTTPError_ErrorAndUnwrap(t *testing.T) {
+	base := NewError(http.StatusBadGateway, "upstream failed")
+	e := base.WithErr(io.EOF)
+	if base.Err != nil {
+		t.Fatalf("WithErr must not mutate receiver")
+	}
+	if e.Error() != "upstream failed: EOF" {
+		t.Fatalf("Error() = %q", e.Error())
+	}
+	if !errors.Is(e, io.EOF) {
+		t.Fatalf("expected errors.Is to see cause")
+	}
+	same := NewError(http.StatusTeapot, "boom").WithErr(errors.New("boom"))
+	if same.Error() != "boom" {
+		t.Fatalf("duplicate cause should be collapsed, got %q", same.Error())
+	}
+	if (&HTTPError{Code: http.StatusConflict}).Error() != "Conflict" {
+		t.Fatalf("empty message should fall back to status text")
+	}
+}
+
+func TestPanicError_ErrorAndUnwrap(t *testing.T) {
+	pe := &PanicError{Value: "boom"}
+	if pe.Error() != "panic: boom" || pe.Unwrap() != nil {
+		t.Fatalf("unexpected: %q %v", pe.Error(), pe.Unwrap())
+	}
+	pe = &PanicError{Value: io.EOF}
+	if !errors.Is(pe, io.EOF) || pe.Error() != "panic: EOF" {
+		t.Fatalf("expected to unwrap io.EOF: %q", pe.Error())
+	}
+}
@@ -0,0 +1,335 @@
+package ctx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FeedFormat selects the syndication format written by EncodeFeed.
+type FeedFormat string
+
+const (
+	FeedRSS  FeedFormat = "rss"  // RSS 2.0, application/rss+xml
+	FeedAtom FeedFormat = "atom" // Atom 1.0 (RFC 4287), application/atom+xml
+)
+
+// Feed is a syndication feed written by Ctx.Feed as RSS 2.0 or Atom.
+type Feed struct {
+	Title       string
+	Link        string // URL of the site or page the feed belongs to
+	Description string // RSS description, Atom subtitle
+	ID          string // permanent Atom feed ID; defaults to Link
+	FeedURL     string // URL of the feed itself, written as the rel="self" link
+	Author      string
+	Language    string    // e.g. "en-us"
+	Updated     time.Time // defaults to the newest item's Updated or Published time
+	TTL         time.Duration
+	Items       []FeedItem
+}
+
+// FeedItem is an entry of a Feed.
+type FeedItem struct {
+	Title       string
+	Link        string
+	ID          string // permanent ID (RSS guid); defaults to Link
+	Description string // summary; RSS falls back to Content when empty
+	Content     string // full HTML content (Atom only)
+	Author      string
+	Published   time.Time
+	Updated     time.Time // defaults to Published
+}
+
+// Feed writes f as RSS 2.0 or Atom. The format follows the request path's
+// extension (".atom", or ".rss"/".xml"), then the Accept header
+// (application/atom+xml or application/rss+xml), defaulting to RSS.
+//
+// The response carries an ETag and Last-Modified (from f.Updated), so feed
+// readers polling with If-None-Match or If-Modified-Since get 304 Not
+// Modified, and "Cache-Control: public, max-age=..." when f.TTL is set (also
+// written as the RSS <ttl>). Vary: Accept is added when the format was
+// negotiated from the Accept header.
+//
+// Example:
+//
+//	app.GET("/blog/feed", func(c flash.Ctx) error {
+//		posts := store.Latest(20)
+//		feed := flash.Feed{Title: "Blog", Link: "https://example.com/blog", FeedURL: "https://example.com/blog/feed", TTL: time.Hour}
+//		for _, p := range posts {
+//			feed.Items = append(feed.Items, flash.FeedItem{Title: p.Title, Link: p.URL, Description: p.Summary, Published: p.Date})
+//		}
+//		return c.Feed(feed)
+//	})
+func (c *DefaultContext) Feed(f Feed) error {
+	format, negotiated := feedFormat(c.r)
+	var buf bytes.Buffer
+	ct, err := EncodeFeed(&buf, f, format)
+	if err != nil {
+		return err
+	}
+	h := c.w.Header()
+	h.Set("Content-Type", ct)
+	sum := sha256.Sum256(buf.Bytes())
+	h.Set("ETag", `"`+base64.RawURLEncoding.EncodeToString(sum[:12])+`"`)
+	if negotiated {
+		h.Add("Vary", "Accept")
+	}
+	if f.TTL > 0 {
+		c.CacheControl(CacheDirectives{Public: true, MaxAge: f.TTL})
+	}
+	http.ServeContent(&fileResponseWriter{ResponseWriter: c.w, c: c}, c.Request(), "", feedUpdated(f), bytes.NewReader(buf.Bytes()))
+	return nil
+}
+
+// feedFormat picks the feed format for r and reports whether it came from
+// the Accept header.
+func feedFormat(r *http.Request) (FeedFormat, bool) {
+	switch path.Ext(r.URL.Path) {
+	case ".atom":
+		return FeedAtom, false
+	case ".rss", ".xml":
+		return FeedRSS, false
+	}
+	atom, rss := -1.0, -1.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mt, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			if k, v, ok := strings.Cut(strings.TrimSpace(p), "="); ok && strings.EqualFold(k, "q") {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(mt)) {
+		case "application/atom+xml":
+			atom = q
+		case "application/rss+xml":
+			rss = q
+		}
+	}
+	if atom > 0 && atom > rss {
+		return FeedAtom, true
+	}
+	return FeedRSS, true
+}
+
+// feedUpdated returns f.Updated or the newest item time.
+func feedUpdated(f Feed) time.Time {
+	if !f.Updated.IsZero() {
+		return f.Updated
+	}
+	var t time.Time
+	for _, it := range f.Items {
+		if u := itemUpdated(it); u.After(t) {
+			t = u
+		}
+	}
+	return t
+}
+
+func itemUpdated(it FeedItem) time.Time {
+	if !it.Updated.IsZero() {
+		return it.Updated
+	}
+	return it.Published
+}
+
+// EncodeFeed writes f to w in format and returns its Content-Type. See
+// Ctx.Feed.
+func EncodeFeed(w io.Writer, f Feed, format FeedFormat) (string, error) {
+	var v any
+	ct := "application/rss+xml; charset=utf-8"
+	if format == FeedAtom {
+		v, ct = atomFeed(f), "application/atom+xml; charset=utf-8"
+	} else {
+		v = rssFeed(f)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return "", err
+	}
+	enc := xml.NewEncoder(w)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return ct, enc.Close()
+}
+
+type rssDoc struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr,omitempty"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	TTL           int       `xml:"ttl,omitempty"`
+	Self          *atomLink `xml:"atom:link,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title,omitempty"`
+	Link        string   `xml:"link,omitempty"`
+	Description string   `xml:"description,omitempty"`
+	Author      string   `xml:"author,omitempty"`
+	GUID        *rssGUID `xml:"guid,omitempty"`
+	PubDate     string   `xml:"pubDate,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func rssDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC1123Z)
+}
+
+func rssFeed(f Feed) rssDoc {
+	ch := rssChannel{
+		Title:         f.Title,
+		Link:          f.Link,
+		Description:   f.Description,
+		Language:      f.Language,
+		LastBuildDate: rssDate(feedUpdated(f)),
+		TTL:           int(f.TTL / time.Minute),
+	}
+	doc := rssDoc{Version: "2.0"}
+	if f.FeedURL != "" {
+		doc.AtomNS = "http://www.w3.org/2005/Atom"
+		ch.Self = &atomLink{Href: f.FeedURL, Rel: "self", Type: "application/rss+xml"}
+	}
+	for _, it := range f.Items {
+		item := rssItem{
+			Title:       it.Title,
+			Link:        it.Link,
+			Description: it.Description,
+			Author:      it.Author,
+			PubDate:     rssDate(it.Published),
+		}
+		if item.Description == "" {
+			item.Description = it.Content
+		}
+		if it.ID != "" {
+			item.GUID = &rssGUID{Value: it.ID, IsPermaLink: it.ID == it.Link}
+		} else if it.Link != "" {
+			item.GUID = &rssGUID{Value: it.Link, IsPermaLink: true}
+		}
+		ch.Items = append(ch.Items, item)
+	}
+	doc.Channel = ch
+	return doc
+}
+
+type atomDoc struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	ID       string      `xml:"id"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Author   *atomPerson `xml:"author,omitempty"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Links     []atomLink  `xml:"link"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published,omitempty"`
+	Author    *atomPerson `xml:"author,omitempty"`
+	Summary   *atomText   `xml:"summary,omitempty"`
+	Content   *atomText   `xml:"content,omitempty"`
+}
+
+func atomDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func atomFeed(f Feed) atomDoc {
+	updated := feedUpdated(f)
+	doc := atomDoc{
+		Title:    f.Title,
+		Subtitle: f.Description,
+		ID:       f.ID,
+		Updated:  atomDate(updated),
+	}
+	if doc.ID == "" {
+		doc.ID = f.Link
+	}
+	if doc.Updated == "" {
+		doc.Updated = atomDate(time.Unix(0, 0))
+	}
+	if f.Link != "" {
+		doc.Links = append(doc.Links, atomLink{Href: f.Link, Rel: "alternate"})
+	}
+	if f.FeedURL != "" {
+		doc.Links = append(doc.Links, atomLink{Href: f.FeedURL, Rel: "self", Type: "application/atom+xml"})
+	}
+	if f.Author != "" {
+		doc.Author = &atomPerson{Name: f.Author}
+	}
+	for _, it := range f.Items {
+		e := atomEntry{
+			Title:     it.Title,
+			ID:        it.ID,
+			Updated:   atomDate(itemUpdated(it)),
+			Published: atomDate(it.Published),
+		}
+		if e.ID == "" {
+			e.ID = it.Link
+		}
+		if e.Updated == "" {
+			e.Updated = doc.Updated
+		}
+		if it.Link != "" {
+			e.Links = []atomLink{{Href: it.Link, Rel: "alternate"}}
+		}
+		if it.Author != "" {
+			e.Author = &atomPerson{Name: it.Author}
+		}
+		if it.Description != "" {
+			e.Summary = &atomText{Value: it.Description}
+		}
+		if it.Content != "" {
+			e.Content = &atomText{Type: "html", Value: it.Content}
+		}
+		doc.Entries = append(doc.Entries, e)
+	}
+	return doc
+}
@@ -0,0 +1,147 @@
+package ctx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testFeed = Feed{
+	Title:       "Blog",
+	Link:        "https://example.com/blog",
+	Description: "News & notes",
+	FeedURL:     "https://example.com/blog/feed",
+	Author:      "Ada",
+	TTL:         time.Hour,
+	Items: []FeedItem{
+		{Title: "First", Link: "https://example.com/blog/1", Description: "one", Published: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{Title: "Second", Link: "https://example.com/blog/2", ID: "urn:post:2", Content: "<p>two</p>", Published: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+	},
+}
+
+func serveFeed(t *testing.T, target string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	var c DefaultContext
+	c.Reset(rec, req, nil, "/feed")
+	if err := c.Feed(testFeed); err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+func TestFeed_RSSDefault(t *testing.T) {
+	rec := serveFeed(t, "/feed", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/rss+xml; charset=utf-8" {
+		t.Fatalf("got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var doc struct {
+		Version string `xml:"version,attr"`
+		Channel struct {
+			Title         string `xml:"title"`
+			LastBuildDate string `xml:"lastBuildDate"`
+			TTL           int    `xml:"ttl"`
+			Items         []struct {
+				Description string `xml:"description"`
+				GUID        struct {
+					IsPermaLink string `xml:"isPermaLink,attr"`
+					Value       string `xml:",chardata"`
+				} `xml:"guid"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid RSS: %v\n%s", err, rec.Body.String())
+	}
+	ch := doc.Channel
+	if doc.Version != "2.0" || ch.Title != "Blog" || ch.TTL != 60 || len(ch.Items) != 2 {
+		t.Fatalf("unexpected channel: %+v", doc)
+	}
+	if ch.LastBuildDate != "Thu, 01 Feb 2024 00:00:00 +0000" {
+		t.Fatalf("lastBuildDate %q", ch.LastBuildDate)
+	}
+	if g := ch.Items[1].GUID; g.Value != "urn:post:2" || g.IsPermaLink != "false" || ch.Items[1].Description != "<p>two</p>" {
+		t.Fatalf("second item: %+v", ch.Items[1])
+	}
+	if !strings.Contains(rec.Body.String(), `<atom:link href="https://example.com/blog/feed" rel="self" type="application/rss+xml">`) {
+		t.Fatalf("missing self link:\n%s", rec.Body.String())
+	}
+	if rec.Header().Get("Cache-Control") != "public, max-age=3600" || rec.Header().Get("ETag") == "" {
+		t.Fatalf("caching headers: %v", rec.Header())
+	}
+	if rec.Header().Get("Last-Modified") != "Thu, 01 Feb 2024 00:00:00 GMT" || rec.Header().Get("Vary") != "Accept" {
+		t.Fatalf("Last-Modified/Vary: %v", rec.Header())
+	}
+}
+
+func TestFeed_AtomNegotiation(t *testing.T) {
+	rec := serveFeed(t, "/feed", http.Header{"Accept": {"application/rss+xml;q=0.5, application/atom+xml"}})
+	if rec.Header().Get("Content-Type") != "application/atom+xml; charset=utf-8" {
+		t.Fatalf("Accept negotiation: %q", rec.Header().Get("Content-Type"))
+	}
+	var doc struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string   `xml:"id"`
+		Updated string   `xml:"updated"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Updated string `xml:"updated"`
+			Content struct {
+				Type  string `xml:"type,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"content"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid Atom: %v\n%s", err, rec.Body.String())
+	}
+	if doc.ID != "https://example.com/blog" || doc.Updated != "2024-02-01T00:00:00Z" || len(doc.Entries) != 2 {
+		t.Fatalf("unexpected feed: %+v", doc)
+	}
+	if e := doc.Entries[1]; e.ID != "urn:post:2" || e.Content.Type != "html" || e.Content.Value != "<p>two</p>" {
+		t.Fatalf("second entry: %+v", e)
+	}
+
+	// The path extension wins over Accept and does not vary.
+	rec = serveFeed(t, "/feed.rss", http.Header{"Accept": {"application/atom+xml"}})
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/rss+xml") || rec.Header().Get("Vary") != "" {
+		t.Fatalf(".rss: %v", rec.Header())
+	}
+	rec = serveFeed(t, "/feed.atom", nil)
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/atom+xml") {
+		t.Fatalf(".atom: %v", rec.Header())
+	}
+}
+
+func TestFeed_ConditionalRequests(t *testing.T) {
+	etag := serveFeed(t, "/feed", nil).Header().Get("ETag")
+	if rec := serveFeed(t, "/feed", http.Header{"If-None-Match": {etag}}); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("If-None-Match: %d", rec.Code)
+	}
+	since := http.Header{"If-Modified-Since": {"Sat, 01 Jun 2024 00:00:00 GMT"}}
+	if rec := serveFeed(t, "/feed", since); rec.Code != http.StatusNotModified {
+		t.Fatalf("If-Modified-Since: %d", rec.Code)
+	}
+}
+
+func TestEncodeFeed_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := EncodeFeed(&buf, Feed{Title: "t"}, FeedAtom); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "<updated>1970-01-01T00:00:00Z</updated>") {
+		t.Fatalf("atom feeds require updated:\n%s", buf.String())
+	}
+	buf.Reset()
+	if _, err := EncodeFeed(&buf, Feed{Title: "t"}, FeedRSS); err != nil || strings.Contains(buf.String(), "lastBuildDate") {
+		t.Fatalf("rss: %v\n%s", err, buf.String())
+	}
+}
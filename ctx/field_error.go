@@ -1,7 +1,10 @@
 package ctx
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -52,13 +55,31 @@ var (
 	ErrFieldInvalidType error = fieldSentinel("invalid type")
 	// ErrFieldTypeExpected matches any message that ends with " type expected" (e.g., "int type expected").
 	ErrFieldTypeExpected error = fieldSentinel("type expected")
+	// ErrFieldRequired matches fields missing from the input (see BindJSONOptions.ErrorUnset).
+	ErrFieldRequired error = fieldSentinel("required")
+)
+
+// Machine-readable field error codes returned by FieldErrorDetail.Code.
+//
+// Unlike messages, codes are stable and intended for clients that need to react
+// programmatically (e.g., highlight a form input, pick a translated message).
+const (
+	// FieldCodeRequired marks a field that was expected but missing from the input.
+	FieldCodeRequired = "required"
+	// FieldCodeTypeMismatch marks a value that could not be converted to the field type.
+	FieldCodeTypeMismatch = "type_mismatch"
+	// FieldCodeUnexpected marks an input key that does not correspond to any field.
+	FieldCodeUnexpected = "unexpected_field"
+	// FieldCodeInvalid is used for any other field-level failure.
+	FieldCodeInvalid = "invalid"
 )
 
 // FieldError represents a validation or binding error for a specific field.
 // Implementations provide a field path/name and a human-friendly message.
 // The same information can be obtained via Error(), but Field()/Message() are
 // convenient for structured handling in application code and for serializing
-// field-specific errors in APIs.
+// field-specific errors in APIs. Errors created by this package also
+// implement FieldErrorDetail.
 //
 // Example (printing structured errors):
 //
@@ -73,6 +94,31 @@ type FieldError interface {
 	Message() string
 }
 
+// FieldErrorDetail is an optional interface of FieldError adding a
+// machine-readable code, the offending input value and a JSON Pointer. Every
+// FieldError returned by this package's binders and validators implements it.
+//
+// Field paths use dotted notation with indexes for nested input, e.g.
+// "items[2].price"; Pointer returns the same location as an RFC 6901 JSON
+// Pointer ("/items/2/price").
+//
+// Example:
+//
+//	for _, e := range fe.All() {
+//	    if d, ok := e.(ctx.FieldErrorDetail); ok && d.Code() == ctx.FieldCodeRequired {
+//	        missing = append(missing, d.Pointer())
+//	    }
+//	}
+type FieldErrorDetail interface {
+	FieldError
+	// Code returns one of the FieldCode* constants.
+	Code() string
+	// Value returns the offending input value, or nil when unknown or missing.
+	Value() any
+	// Pointer returns the field location as a JSON Pointer.
+	Pointer() string
+}
+
 // FieldErrors represents multiple field validation/binding errors for a single
 // decoding/binding operation.
 //
@@ -99,14 +145,39 @@ type FieldErrors interface {
 type fieldError struct {
 	field   string
 	message string
+	code    string
+	value   any
 }
 
 func (e fieldError) Field() string   { return e.field }
 func (e fieldError) Message() string { return e.message }
+func (e fieldError) Value() any      { return e.value }
+func (e fieldError) Pointer() string { return fieldPointer(e.field) }
 func (e fieldError) Error() string   { return fmt.Sprintf("field %s: %s", e.field, e.message) }
 
+// Code returns the explicit code or one inferred from the message.
+func (e fieldError) Code() string {
+	if e.code != "" {
+		return e.code
+	}
+	return codeForMessage(e.message)
+}
+
+// MarshalJSON renders a single field error with its code, path, message and value.
+func (e fieldError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Field   string `json:"field"`
+		Pointer string `json:"pointer"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Value   any    `json:"value,omitempty"`
+	}{e.field, e.Pointer(), e.Code(), e.message, e.value})
+}
+
 type fieldErrorsMap struct {
-	m map[string]string
+	m      map[string]string
+	values map[string]any    // offending input values keyed by field (optional)
+	codes  map[string]string // explicit codes keyed by field (optional; inferred from the message otherwise)
 }
 
 func (f fieldErrorsMap) Error() string {
@@ -144,6 +215,10 @@ func (f fieldErrorsMap) Is(target error) bool {
 			if msg == ErrFieldInvalidType.Error() {
 				return true
 			}
+		case ErrFieldRequired.(fieldSentinel):
+			if msg == ErrFieldRequired.Error() {
+				return true
+			}
 		default:
 			if msg == s.Error() {
 				return true
@@ -154,8 +229,9 @@ func (f fieldErrorsMap) Is(target error) bool {
 }
 
 // All returns the list of individual field errors contained in the aggregate.
-// Each entry exposes the field path/name and a human-friendly message.
-// The order is unspecified; callers should not rely on ordering semantics.
+// Each entry exposes the field path/name and a human-friendly message, and
+// implements FieldErrorDetail for the code and offending value. Entries are
+// sorted by field path.
 //
 // Example:
 //
@@ -168,13 +244,123 @@ func (f fieldErrorsMap) Is(target error) bool {
 //	    _ = errs
 //	}
 func (f fieldErrorsMap) All() []FieldError {
-	out := make([]FieldError, 0, len(f.m))
-	for k, v := range f.m {
-		out = append(out, fieldError{field: k, message: v})
+	keys := make([]string, 0, len(f.m))
+	for k := range f.m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]FieldError, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, fieldError{field: k, message: f.m[k], code: f.codes[k], value: f.values[k]})
 	}
 	return out
 }
 
+// MarshalJSON serializes the aggregate as a JSON array sorted by field, so the
+// output is stable across runs:
+//
+//	[{"field":"items[2].price","pointer":"/items/2/price","code":"type_mismatch","message":"float type expected","value":"abc"}]
+func (f fieldErrorsMap) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.All())
+}
+
+// withValues returns a copy of f carrying the offending values found in input.
+func (f fieldErrorsMap) withValues(input map[string]any) fieldErrorsMap {
+	if len(input) == 0 {
+		return f
+	}
+	vals := make(map[string]any, len(f.m))
+	for k, msg := range f.m {
+		if msg == ErrFieldRequired.Error() {
+			continue
+		}
+		if v, ok := lookupInputPath(input, k); ok {
+			vals[k] = v
+		}
+	}
+	f.values = vals
+	return f
+}
+
+// codeForMessage maps the binder's human-friendly messages to stable codes.
+func codeForMessage(msg string) string {
+	switch {
+	case msg == ErrFieldUnexpected.Error():
+		return FieldCodeUnexpected
+	case msg == ErrFieldRequired.Error():
+		return FieldCodeRequired
+	case msg == ErrFieldInvalidType.Error(), strings.HasSuffix(msg, " "+ErrFieldTypeExpected.Error()):
+		return FieldCodeTypeMismatch
+	default:
+		return FieldCodeInvalid
+	}
+}
+
+// fieldPointer converts a dotted/indexed path ("items[2].price") to a JSON
+// Pointer ("/items/2/price"), escaping "~" and "/" per RFC 6901.
+func fieldPointer(path string) string {
+	if path == "" {
+		return ""
+	}
+	var b strings.Builder
+	for _, seg := range splitFieldPath(path) {
+		b.WriteByte('/')
+		seg = strings.ReplaceAll(seg, "~", "~0")
+		b.WriteString(strings.ReplaceAll(seg, "/", "~1"))
+	}
+	return b.String()
+}
+
+// splitFieldPath splits "items[2].price" into ["items", "2", "price"].
+func splitFieldPath(path string) []string {
+	var out []string
+	for _, part := range strings.Split(path, ".") {
+		for {
+			i := strings.IndexByte(part, '[')
+			if i == -1 {
+				break
+			}
+			j := strings.IndexByte(part[i:], ']')
+			if j == -1 {
+				break
+			}
+			if i > 0 {
+				out = append(out, part[:i])
+			}
+			out = append(out, part[i+1:i+j])
+			part = part[i+j+1:]
+		}
+		if part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// lookupInputPath resolves a field path against decoded input (maps and slices).
+func lookupInputPath(input map[string]any, path string) (any, bool) {
+	var cur any = input
+	for _, seg := range splitFieldPath(path) {
+		switch v := cur.(type) {
+		case map[string]any:
+			next, ok := v[seg]
+			if !ok {
+				return nil, false
+			}
+			cur = next
+		case []any:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			cur = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
 // fieldErrorsFromMap constructs a FieldErrors aggregate from field->message
 // pairs. If the provided map is empty, it returns nil.
 //
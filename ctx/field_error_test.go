@@ -1,6 +1,7 @@
 package ctx
 
 import (
+	"encoding/json"
 	"errors"
 	"testing"
 )
@@ -81,3 +82,104 @@ func Test_fieldErrorsFromMap_EmptyIsNil(t *testing.T) {
 		t.Fatalf("expected nil for empty map")
 	}
 }
+
+// fieldErrorDetails returns the entries of fe as FieldErrorDetail.
+func fieldErrorDetails(t *testing.T, fe FieldErrors) []FieldErrorDetail {
+	t.Helper()
+	var out []FieldErrorDetail
+	for _, e := range fe.All() {
+		d, ok := e.(FieldErrorDetail)
+		if !ok {
+			t.Fatalf("%T does not implement FieldErrorDetail", e)
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// legacyFieldError implements only the original FieldError methods.
+type legacyFieldError struct{}
+
+func (legacyFieldError) Field() string   { return "f" }
+func (legacyFieldError) Message() string { return "m" }
+
+func Test_FieldError_OptionalDetail(t *testing.T) {
+	var _ FieldError = legacyFieldError{}
+	var _ FieldErrorDetail = fieldError{}
+}
+
+func Test_fieldError_CodesInferredFromMessages(t *testing.T) {
+	cases := map[string]string{
+		ErrFieldUnexpected.Error():            FieldCodeUnexpected,
+		ErrFieldRequired.Error():              FieldCodeRequired,
+		ErrFieldInvalidType.Error():           FieldCodeTypeMismatch,
+		"int " + ErrFieldTypeExpected.Error(): FieldCodeTypeMismatch,
+		"something else":                      FieldCodeInvalid,
+	}
+	for msg, want := range cases {
+		if got := (fieldError{field: "f", message: msg}).Code(); got != want {
+			t.Fatalf("%q: code=%q want %q", msg, got, want)
+		}
+	}
+	if got := (fieldError{message: "x", code: "custom"}).Code(); got != "custom" {
+		t.Fatalf("explicit code not honored: %q", got)
+	}
+}
+
+func Test_fieldPointer(t *testing.T) {
+	cases := map[string]string{
+		"":                 "",
+		"age":              "/age",
+		"items[2].price":   "/items/2/price",
+		"m[a/b].x~y":       "/m/a~1b/x~0y",
+		"grid[1][3]":       "/grid/1/3",
+		"broken[1.after":   "/broken[1/after",
+		"[0].name":         "/0/name",
+		"outer.inner.leaf": "/outer/inner/leaf",
+	}
+	for in, want := range cases {
+		if got := fieldPointer(in); got != want {
+			t.Fatalf("fieldPointer(%q)=%q want %q", in, got, want)
+		}
+	}
+}
+
+func Test_lookupInputPath(t *testing.T) {
+	in := map[string]any{"items": []any{map[string]any{"price": "x"}}, "n": 1}
+	if v, ok := lookupInputPath(in, "items[0].price"); !ok || v != "x" {
+		t.Fatalf("got %v %v", v, ok)
+	}
+	for _, p := range []string{"items[5].price", "items[a]", "n.x", "missing"} {
+		if _, ok := lookupInputPath(in, p); ok {
+			t.Fatalf("%s: expected miss", p)
+		}
+	}
+}
+
+func Test_fieldErrorsMap_MarshalJSON_SortedWithValues(t *testing.T) {
+	fe := fieldErrorsFromMap(map[string]string{
+		"b":              ErrFieldUnexpected.Error(),
+		"a":              ErrFieldRequired.Error(),
+		"items[1].price": "float " + ErrFieldTypeExpected.Error(),
+	}).(fieldErrorsMap).withValues(map[string]any{
+		"b":     true,
+		"a":     "ignored for required",
+		"items": []any{nil, map[string]any{"price": "abc"}},
+	})
+	b, err := json.Marshal(fe)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want := `[{"field":"a","pointer":"/a","code":"required","message":"required"},` +
+		`{"field":"b","pointer":"/b","code":"unexpected_field","message":"unexpected","value":true},` +
+		`{"field":"items[1].price","pointer":"/items/1/price","code":"type_mismatch","message":"float type expected","value":"abc"}]`
+	if string(b) != want {
+		t.Fatalf("got  %s\nwant %s", b, want)
+	}
+	if !errors.Is(fe, ErrFieldRequired) {
+		t.Fatalf("expected ErrFieldRequired match")
+	}
+	if got := fieldErrorsMap.withValues(fe, nil); len(got.values) != len(fe.values) {
+		t.Fatalf("nil input should keep existing values")
+	}
+}
@@ -0,0 +1,94 @@
+package ctx
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+)
+
+// FileFS writes the file name from fsys as the response, for example from an
+// embed.FS or a storage.S3 bucket. Content-Type is derived from the extension
+// and Last-Modified from the file's modification time.
+//
+// When the file is seekable (os files, storage backends, embed.FS), the
+// response is served by http.ServeContent, so Range and conditional requests
+// (If-Modified-Since, If-Range) are honoured and only the requested bytes are
+// read. Other files are streamed as a whole.
+//
+// A missing file or a directory yields a 404 *HTTPError; nothing is written in
+// that case.
+//
+// Example:
+//
+//	app.GET("/videos/:name", func(c flash.Ctx) error {
+//		return c.FileFS(media, "videos/"+c.ParamFilename("name"))
+//	})
+func (c *DefaultContext) FileFS(fsys fs.FS, name string) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
+			return NewError(http.StatusNotFound, "file not found").WithErr(err)
+		}
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return NewError(http.StatusNotFound, "file not found")
+	}
+
+	w := &fileResponseWriter{ResponseWriter: c.w, c: c}
+	if rs, ok := f.(io.ReadSeeker); ok {
+		http.ServeContent(w, c.Request(), path.Base(name), fi.ModTime(), rs)
+		return nil
+	}
+	ct := mime.TypeByExtension(path.Ext(name))
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+	c.Header("Content-Type", ct)
+	c.Header("Content-Length", strconv.FormatInt(fi.Size(), 10))
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	w.WriteHeader(c.status)
+	if c.Request().Method == http.MethodHead {
+		return nil
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// fileResponseWriter records the status and body size written by
+// http.ServeContent on the context.
+type fileResponseWriter struct {
+	http.ResponseWriter
+	c *DefaultContext
+}
+
+func (w *fileResponseWriter) WriteHeader(code int) {
+	if w.c.wroteHeader {
+		return
+	}
+	w.c.status = code
+	w.c.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *fileResponseWriter) Write(b []byte) (int, error) {
+	if !w.c.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.c.wroteBytes += n
+	return n, err
+}
+
+func (w *fileResponseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
@@ -0,0 +1,78 @@
+package ctx
+
+import (
+	"errors"
+	"io/fs"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var fileTestFS = fstest.MapFS{
+	"docs/a.txt": {Data: []byte("0123456789"), ModTime: time.Unix(1e9, 0)},
+	"docs/b.bin": {Data: []byte("stream")},
+}
+
+func TestFileFSServesRanges(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Range", "bytes=2-4")
+	c := &DefaultContext{}
+	c.Reset(rec, req, nil, "/")
+	if err := c.FileFS(fileTestFS, "docs/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != 206 || rec.Body.String() != "234" || rec.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Fatalf("code %d body %q type %q", rec.Code, rec.Body.String(), rec.Header().Get("Content-Type"))
+	}
+	if c.StatusCode() != 206 || !c.WroteHeader() {
+		t.Fatalf("status not recorded: %d", c.StatusCode())
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("If-Modified-Since", time.Unix(1e9, 0).UTC().Format("Mon, 02 Jan 2006 15:04:05 GMT"))
+	c.Reset(rec, req, nil, "/")
+	if err := c.FileFS(fileTestFS, "docs/a.txt"); err != nil || rec.Code != 304 {
+		t.Fatalf("conditional request: %d %v", rec.Code, err)
+	}
+}
+
+// noSeekFS hides Seek from the files it opens.
+type noSeekFS struct{ fs.FS }
+
+func (n noSeekFS) Open(name string) (fs.File, error) {
+	f, err := n.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return struct{ fs.File }{f}, nil
+}
+
+func TestFileFSStreamsUnseekableFiles(t *testing.T) {
+	rec := httptest.NewRecorder()
+	c := &DefaultContext{}
+	c.Reset(rec, httptest.NewRequest("GET", "/", nil), nil, "/")
+	c.Status(202)
+	if err := c.FileFS(noSeekFS{fileTestFS}, "docs/b.bin"); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != 202 || rec.Body.String() != "stream" || rec.Header().Get("Content-Length") != "6" ||
+		rec.Header().Get("Content-Type") != "application/octet-stream" {
+		t.Fatalf("code %d body %q headers %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+}
+
+func TestFileFSNotFound(t *testing.T) {
+	for _, name := range []string{"missing.txt", "docs", "../etc/passwd"} {
+		rec := httptest.NewRecorder()
+		c := &DefaultContext{}
+		c.Reset(rec, httptest.NewRequest("GET", "/", nil), nil, "/")
+		err := c.FileFS(fileTestFS, name)
+		var he *HTTPError
+		if !errors.As(err, &he) || he.Code != 404 || c.WroteHeader() {
+			t.Fatalf("%s: expected unwritten 404, got %v", name, err)
+		}
+	}
+}
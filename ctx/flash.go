@@ -0,0 +1,270 @@
+package ctx
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// ErrNoFlashStore is returned by Flash when neither the app (WithFlashStore) nor
+// a middleware (e.g. Sessions) provides a FlashStore for the request.
+var ErrNoFlashStore = errors.New("flash: no flash message store configured")
+
+// FlashMessage is a one-time message shown on the next rendered page, typically
+// after a redirect (Post/Redirect/Get).
+type FlashMessage struct {
+	Kind    string `json:"k"` // e.g. "success", "error", "info"
+	Message string `json:"m"`
+}
+
+// FlashStore persists flash messages between requests. AddFlash queues a
+// message for a later request; PopFlashes returns the pending messages and
+// clears them. Implementations must write any cookies before the response
+// header is sent, so call Flash before writing the response (e.g. before
+// redirecting).
+type FlashStore interface {
+	AddFlash(c Ctx, m FlashMessage) error
+	PopFlashes(c Ctx) ([]FlashMessage, error)
+}
+
+type flashStoreKey struct{}
+
+// ContextWithFlashStore returns a copy of parent in which s is the flash store.
+// Middleware uses it to take over flash storage for a request; a store found
+// in the request context takes precedence over the app default. The Sessions
+// middleware does this so flashes live in the session when sessions are used.
+//
+// Example:
+//
+//	c.SetRequest(r.WithContext(ctx.ContextWithFlashStore(r.Context(), myStore)))
+func ContextWithFlashStore(parent context.Context, s FlashStore) context.Context {
+	return context.WithValue(parent, flashStoreKey{}, s)
+}
+
+// FlashStoreFromContext returns the flash store installed by
+// ContextWithFlashStore, or nil.
+func FlashStoreFromContext(c context.Context) FlashStore {
+	s, _ := c.Value(flashStoreKey{}).(FlashStore)
+	return s
+}
+
+// SetFlashStore sets the default flash store for this request. Used internally
+// by the app to apply WithFlashStore.
+func (c *DefaultContext) SetFlashStore(s FlashStore) { c.flashStore = s }
+
+// flashes resolves the store for this request: request context first, then
+// the app default.
+func (c *DefaultContext) flashes() FlashStore {
+	if s := FlashStoreFromContext(c.Context()); s != nil {
+		return s
+	}
+	return c.flashStore
+}
+
+// Flash queues a one-time message of the given kind for the next request that
+// calls Flashes. It must be called before the response header is written.
+// It returns ErrNoFlashStore when no store is configured.
+//
+// Example:
+//
+//	if err := c.Flash("success", "Saved!"); err != nil {
+//		return err
+//	}
+//	http.Redirect(c.ResponseWriter(), c.Request(), "/items", http.StatusSeeOther)
+//	return nil
+func (c *DefaultContext) Flash(kind, message string) error {
+	s := c.flashes()
+	if s == nil {
+		return ErrNoFlashStore
+	}
+	return s.AddFlash(c, FlashMessage{Kind: kind, Message: message})
+}
+
+// Flashes returns and clears the pending flash messages, oldest first. It
+// returns nil when there are none, when no store is configured, or when the
+// stored messages cannot be read (e.g. a tampered cookie). Like Flash, call it
+// before the response header is written so the store can clear its cookie.
+//
+// Example:
+//
+//	for _, f := range c.Flashes() {
+//		fmt.Fprintf(&page, "<div class=%q>%s</div>", f.Kind, html.EscapeString(f.Message))
+//	}
+func (c *DefaultContext) Flashes() []FlashMessage {
+	s := c.flashes()
+	if s == nil {
+		return nil
+	}
+	msgs, err := s.PopFlashes(c)
+	if err != nil {
+		return nil
+	}
+	return msgs
+}
+
+// FlashFuncs returns html/template functions exposing the request's flash
+// messages to templates. Messages are read (and cleared) once, on the first
+// call of either function, so a layout may call both:
+//
+//	flashes          -> []FlashMessage
+//	flashesOf "kind" -> []string of that kind
+//
+// Example:
+//
+//	t := template.Must(layout.Clone()).Funcs(ctx.FlashFuncs(c))
+//	// {{range flashes}}<div class="alert-{{.Kind}}">{{.Message}}</div>{{end}}
+//	return t.Execute(c.ResponseWriter(), data)
+func FlashFuncs(c Ctx) template.FuncMap {
+	var (
+		msgs []FlashMessage
+		read bool
+	)
+	load := func() []FlashMessage {
+		if !read {
+			msgs, read = c.Flashes(), true
+		}
+		return msgs
+	}
+	return template.FuncMap{
+		"flashes": load,
+		"flashesOf": func(kind string) []string {
+			var out []string
+			for _, m := range load() {
+				if m.Kind == kind {
+					out = append(out, m.Message)
+				}
+			}
+			return out
+		},
+	}
+}
+
+// CookieFlashStore keeps flash messages in an HMAC-SHA256 signed cookie, for
+// apps that do not use server-side sessions. Messages are signed, not
+// encrypted: clients can read but not forge them, so do not flash secrets.
+// Browsers limit cookies to about 4KB, which bounds the pending messages.
+//
+// Example:
+//
+//	app := flash.New(flash.WithFlashStore(flash.NewCookieFlashStore(secret)))
+type CookieFlashStore struct {
+	// Secret signs the cookie. Use at least 32 random bytes. Required.
+	Secret []byte
+	// Name is the cookie name. Defaults to "flash".
+	Name string
+	// Path is the cookie path. Defaults to "/".
+	Path string
+	// Secure sets the Secure flag on the cookie.
+	Secure bool
+}
+
+// NewCookieFlashStore returns a CookieFlashStore signing with secret. It panics
+// if secret is empty.
+func NewCookieFlashStore(secret []byte) *CookieFlashStore {
+	if len(secret) == 0 {
+		panic("flash: CookieFlashStore requires a secret")
+	}
+	return &CookieFlashStore{Secret: secret}
+}
+
+// cookieFlashState is the per-request view of the flash cookie, so several
+// Flash calls in one request accumulate instead of overwriting each other.
+type cookieFlashState struct {
+	msgs []FlashMessage
+}
+
+type cookieFlashStateKey struct{}
+
+// AddFlash appends m to the pending messages and rewrites the cookie.
+func (s *CookieFlashStore) AddFlash(c Ctx, m FlashMessage) error {
+	st := s.state(c)
+	st.msgs = append(st.msgs, m)
+	raw, err := json.Marshal(st.msgs)
+	if err != nil {
+		return err
+	}
+	s.setCookie(c, s.sign(raw), 0)
+	return nil
+}
+
+// PopFlashes returns the pending messages and expires the cookie.
+func (s *CookieFlashStore) PopFlashes(c Ctx) ([]FlashMessage, error) {
+	st := s.state(c)
+	msgs := st.msgs
+	st.msgs = nil
+	if _, err := c.Request().Cookie(s.name()); err == nil || len(msgs) > 0 {
+		s.setCookie(c, "", -1)
+	}
+	return msgs, nil
+}
+
+// state returns the request's flash state, decoding the cookie on first use.
+// Invalid or tampered cookies are treated as empty.
+func (s *CookieFlashStore) state(c Ctx) *cookieFlashState {
+	if st, ok := c.Get(cookieFlashStateKey{}).(*cookieFlashState); ok {
+		return st
+	}
+	st := &cookieFlashState{}
+	if ck, err := c.Request().Cookie(s.name()); err == nil {
+		if raw, ok := s.verify(ck.Value); ok {
+			_ = json.Unmarshal(raw, &st.msgs)
+		}
+	}
+	c.Set(cookieFlashStateKey{}, st)
+	return st
+}
+
+func (s *CookieFlashStore) name() string {
+	if s.Name == "" {
+		return "flash"
+	}
+	return s.Name
+}
+
+func (s *CookieFlashStore) setCookie(c Ctx, value string, maxAge int) {
+	path := s.Path
+	if path == "" {
+		path = "/"
+	}
+	http.SetCookie(c.ResponseWriter(), &http.Cookie{
+		Name:     s.name(),
+		Value:    value,
+		Path:     path,
+		MaxAge:   maxAge,
+		Secure:   s.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// sign encodes raw as "<payload>.<mac>" using unpadded base64url.
+func (s *CookieFlashStore) sign(raw []byte) string {
+	payload := base64.RawURLEncoding.EncodeToString(raw)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload))
+}
+
+// verify checks the signature of a value produced by sign and returns the payload.
+func (s *CookieFlashStore) verify(v string) ([]byte, bool) {
+	payload, sig, ok := strings.Cut(v, ".")
+	if !ok {
+		return nil, false
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, s.mac(payload)) {
+		return nil, false
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	return raw, err == nil
+}
+
+func (s *CookieFlashStore) mac(payload string) []byte {
+	h := hmac.New(sha256.New, s.Secret)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
@@ -0,0 +1,164 @@
+package ctx
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newFlashCtx(s FlashStore, cookies ...*http.Cookie) (*DefaultContext, *httptest.ResponseRecorder) {
+	req, rec := newRequest(http.MethodGet, "/", nil)
+	for _, ck := range cookies {
+		req.AddCookie(ck)
+	}
+	c := &DefaultContext{}
+	c.Reset(rec, req, nil, "/")
+	c.SetFlashStore(s)
+	return c, rec
+}
+
+// lastCookie returns the last Set-Cookie for name, which is what browsers keep.
+func lastCookie(rec *httptest.ResponseRecorder, name string) *http.Cookie {
+	var out *http.Cookie
+	for _, ck := range rec.Result().Cookies() {
+		if ck.Name == name {
+			out = ck
+		}
+	}
+	return out
+}
+
+func TestFlash_NoStore(t *testing.T) {
+	c, _ := newFlashCtx(nil)
+	if err := c.Flash("info", "x"); !errors.Is(err, ErrNoFlashStore) {
+		t.Fatalf("expected ErrNoFlashStore, got %v", err)
+	}
+	if got := c.Flashes(); got != nil {
+		t.Fatalf("expected nil flashes, got %v", got)
+	}
+}
+
+func TestCookieFlashStore_RoundTripAndClearOnRead(t *testing.T) {
+	store := NewCookieFlashStore([]byte("0123456789abcdef0123456789abcdef"))
+
+	c, rec := newFlashCtx(store)
+	if err := c.Flash("success", "Saved!"); err != nil {
+		t.Fatalf("Flash: %v", err)
+	}
+	if err := c.Flash("error", "but slowly"); err != nil {
+		t.Fatalf("Flash: %v", err)
+	}
+	ck := lastCookie(rec, "flash")
+	if ck == nil || ck.Value == "" || !ck.HttpOnly {
+		t.Fatalf("expected flash cookie, got %+v", ck)
+	}
+
+	c, rec = newFlashCtx(store, ck)
+	got := c.Flashes()
+	if len(got) != 2 || got[0] != (FlashMessage{"success", "Saved!"}) || got[1].Kind != "error" {
+		t.Fatalf("unexpected flashes: %+v", got)
+	}
+	if again := c.Flashes(); len(again) != 0 {
+		t.Fatalf("flashes not cleared within request: %+v", again)
+	}
+	if cl := lastCookie(rec, "flash"); cl == nil || cl.MaxAge >= 0 {
+		t.Fatalf("expected expiring cookie, got %+v", cl)
+	}
+}
+
+func TestCookieFlashStore_RejectsTamperedCookie(t *testing.T) {
+	store := NewCookieFlashStore([]byte("secret"))
+	other := &CookieFlashStore{Secret: []byte("other")}
+	forged := &http.Cookie{Name: "flash", Value: other.sign([]byte(`[{"k":"info","m":"forged"}]`))}
+
+	c, _ := newFlashCtx(store, forged)
+	if got := c.Flashes(); len(got) != 0 {
+		t.Fatalf("forged cookie accepted: %+v", got)
+	}
+	for _, v := range []string{"garbage", "a.b", "!!.!!"} {
+		c, _ = newFlashCtx(store, &http.Cookie{Name: "flash", Value: v})
+		if got := c.Flashes(); len(got) != 0 {
+			t.Fatalf("invalid cookie %q accepted: %+v", v, got)
+		}
+	}
+}
+
+func TestCookieFlashStore_NameAndPath(t *testing.T) {
+	store := &CookieFlashStore{Secret: []byte("k"), Name: "msgs", Path: "/app", Secure: true}
+	c, rec := newFlashCtx(store)
+	_ = c.Flash("info", "hi")
+	ck := lastCookie(rec, "msgs")
+	if ck == nil || ck.Path != "/app" || !ck.Secure {
+		t.Fatalf("unexpected cookie: %+v", ck)
+	}
+	// Nothing pending and no cookie: reading writes nothing.
+	c, rec = newFlashCtx(store)
+	if c.Flashes() != nil || len(rec.Result().Cookies()) != 0 {
+		t.Fatalf("expected no flashes and no cookie")
+	}
+}
+
+func TestNewCookieFlashStore_PanicsWithoutSecret(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic")
+		}
+	}()
+	NewCookieFlashStore(nil)
+}
+
+type memFlashStore struct{ msgs []FlashMessage }
+
+func (m *memFlashStore) AddFlash(_ Ctx, f FlashMessage) error { m.msgs = append(m.msgs, f); return nil }
+func (m *memFlashStore) PopFlashes(Ctx) ([]FlashMessage, error) {
+	out := m.msgs
+	m.msgs = nil
+	return out, nil
+}
+
+type errFlashStore struct{}
+
+func (errFlashStore) AddFlash(Ctx, FlashMessage) error       { return errors.New("boom") }
+func (errFlashStore) PopFlashes(Ctx) ([]FlashMessage, error) { return nil, errors.New("boom") }
+
+func TestFlash_ContextStoreTakesPrecedence(t *testing.T) {
+	def, mw := &memFlashStore{}, &memFlashStore{}
+	c, _ := newFlashCtx(def)
+	c.SetRequest(c.Request().WithContext(ContextWithFlashStore(c.Context(), mw)))
+	_ = c.Flash("info", "a")
+	if len(mw.msgs) != 1 || len(def.msgs) != 0 {
+		t.Fatalf("context store not preferred: mw=%v def=%v", mw.msgs, def.msgs)
+	}
+	if FlashStoreFromContext(c.Context()) != FlashStore(mw) {
+		t.Fatalf("FlashStoreFromContext mismatch")
+	}
+
+	c, _ = newFlashCtx(errFlashStore{})
+	if err := c.Flash("info", "a"); err == nil {
+		t.Fatalf("expected store error")
+	}
+	if c.Flashes() != nil {
+		t.Fatalf("expected nil on store error")
+	}
+}
+
+func TestFlashFuncs_Template(t *testing.T) {
+	store := &memFlashStore{msgs: []FlashMessage{{"success", "Saved!"}, {"error", "<b>bad</b>"}, {"success", "Again"}}}
+	c, _ := newFlashCtx(store)
+	tpl := template.Must(template.New("t").Funcs(FlashFuncs(c)).Parse(
+		`{{range flashes}}[{{.Kind}}:{{.Message}}]{{end}}|{{range flashesOf "success"}}{{.}};{{end}}`))
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, nil); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	want := "[success:Saved!][error:&lt;b&gt;bad&lt;/b&gt;][success:Again]|Saved!;Again;"
+	if buf.String() != want {
+		t.Fatalf("got %q want %q", buf.String(), want)
+	}
+	if len(store.msgs) != 0 {
+		t.Fatalf("messages not consumed")
+	}
+}
@@ -0,0 +1,189 @@
+package ctx
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HeaderValue is one element of a comma-separated request header, as
+// returned by Ctx.HeaderList.
+type HeaderValue struct {
+	Value   string            // the element without parameters, e.g. "text/html" or "gzip"
+	Quality float64           // the "q" parameter; 1 when absent
+	Params  map[string]string // other parameters with lower-cased names (nil if none)
+}
+
+// RequestHeader returns the first value of the request header key, or "".
+// The key is canonicalized, so "x-request-id" and "X-Request-Id" are the same
+// header.
+//
+// Example:
+//
+//	id := c.RequestHeader("x-request-id")
+func (c *DefaultContext) RequestHeader(key string) string { return c.r.Header.Get(key) }
+
+// HeaderInt returns the request header key parsed as int.
+// Returns def (or 0) on missing or parse error.
+//
+// Example:
+//
+//	// X-Page-Size: 50
+//	size := c.HeaderInt("X-Page-Size", 20) // 50
+func (c *DefaultContext) HeaderInt(key string, def ...int) int {
+	s := strings.TrimSpace(c.RequestHeader(key))
+	fallback := 0
+	if len(def) > 0 {
+		fallback = def[0]
+	}
+	if s == "" {
+		return fallback
+	}
+	v, err := strconv.ParseInt(s, 10, 0)
+	if err != nil {
+		return fallback
+	}
+	return int(v)
+}
+
+// HeaderTime returns the request header key parsed as an HTTP date (the
+// IMF-fixdate, RFC 850 and asctime formats of RFC 9110), as sent in
+// If-Modified-Since, If-Unmodified-Since or Date.
+// Returns def (or the zero time) on missing or parse error.
+//
+// Example:
+//
+//	if since := c.HeaderTime("If-Modified-Since"); !since.IsZero() && !post.Updated.After(since) {
+//		return c.Send(http.StatusNotModified, "", nil)
+//	}
+func (c *DefaultContext) HeaderTime(key string, def ...time.Time) time.Time {
+	var fallback time.Time
+	if len(def) > 0 {
+		fallback = def[0]
+	}
+	s := c.RequestHeader(key)
+	if s == "" {
+		return fallback
+	}
+	t, err := http.ParseTime(s)
+	if err != nil {
+		return fallback
+	}
+	return t
+}
+
+// HeaderList returns the comma-separated elements of every request header
+// line named key, ordered by descending quality ("q" parameter); elements of
+// equal quality keep their order. Commas inside quoted strings do not split
+// elements. Elements refused with q=0 are kept, last, so callers can tell
+// them apart from elements never mentioned.
+//
+// Example:
+//
+//	// Accept-Language: fr-CH, fr;q=0.9, en;q=0.8, *;q=0.5
+//	for _, l := range c.HeaderList("Accept-Language") {
+//		if lang, ok := supported[l.Value]; ok {
+//			return lang
+//		}
+//	}
+func (c *DefaultContext) HeaderList(key string) []HeaderValue {
+	var out []HeaderValue
+	for _, line := range c.r.Header.Values(key) {
+		for _, part := range splitHeaderList(line) {
+			if v, ok := parseHeaderValue(part); ok {
+				out = append(out, v)
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Quality > out[j].Quality })
+	return out
+}
+
+// AppendHeader adds value to the response header key, keeping the values
+// already set (e.g. for Vary, Link or Set-Cookie).
+// Has no effect after the header is written.
+//
+// Example:
+//
+//	c.AppendHeader("Vary", "Accept-Language")
+func (c *DefaultContext) AppendHeader(key, value string) { c.w.Header().Add(key, value) }
+
+// SetHeaders sets every response header in h, replacing existing values.
+// Has no effect after the header is written.
+//
+// Example:
+//
+//	c.SetHeaders(map[string]string{
+//		"Cache-Control":          "no-store",
+//		"X-Content-Type-Options": "nosniff",
+//	})
+func (c *DefaultContext) SetHeaders(h map[string]string) {
+	dst := c.w.Header()
+	for k, v := range h {
+		dst.Set(k, v)
+	}
+}
+
+// splitHeaderList splits a header line at commas outside quoted strings and
+// drops empty elements.
+func splitHeaderList(s string) []string {
+	var parts []string
+	start, quoted := 0, false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				if p := strings.TrimSpace(s[start:i]); p != "" {
+					parts = append(parts, p)
+				}
+				start = i + 1
+			}
+		}
+	}
+	if p := strings.TrimSpace(s[start:]); p != "" {
+		parts = append(parts, p)
+	}
+	return parts
+}
+
+// parseHeaderValue parses `value;q=0.8;name="x"`. Invalid quality values
+// count as 1, like a missing one; values outside 0..1 are clamped.
+func parseHeaderValue(s string) (HeaderValue, bool) {
+	value, rest, _ := strings.Cut(s, ";")
+	v := HeaderValue{Value: strings.TrimSpace(value), Quality: 1}
+	if v.Value == "" {
+		return v, false
+	}
+	for rest != "" {
+		var p string
+		p, rest, _ = strings.Cut(rest, ";")
+		k, val, _ := strings.Cut(p, "=")
+		k = strings.ToLower(strings.TrimSpace(k))
+		val = strings.TrimSpace(val)
+		if k == "" {
+			continue
+		}
+		if k == "q" {
+			if q, err := strconv.ParseFloat(val, 64); err == nil {
+				v.Quality = min(max(q, 0), 1)
+			}
+			continue
+		}
+		if len(val) >= 2 && val[0] == '"' && val[len(val)-1] == '"' {
+			val = val[1 : len(val)-1]
+		}
+		if v.Params == nil {
+			v.Params = make(map[string]string)
+		}
+		v.Params[k] = val
+	}
+	return v, true
+}
@@ -0,0 +1,107 @@
+package ctx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func headerCtx(h http.Header) (*DefaultContext, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header = h
+	rec := httptest.NewRecorder()
+	var c DefaultContext
+	c.Reset(rec, r, nil, "/")
+	return &c, rec
+}
+
+func TestRequestHeaderAndHeaderInt(t *testing.T) {
+	c, _ := headerCtx(http.Header{"X-Page-Size": {" 50 "}, "X-Bad": {"x"}, "X-Request-Id": {"abc"}})
+	if got := c.RequestHeader("x-request-id"); got != "abc" {
+		t.Fatalf("RequestHeader=%q", got)
+	}
+	if got := c.HeaderInt("x-page-size"); got != 50 {
+		t.Fatalf("HeaderInt=%d", got)
+	}
+	if got := c.HeaderInt("X-Bad", 7); got != 7 {
+		t.Fatalf("invalid HeaderInt=%d", got)
+	}
+	if got := c.HeaderInt("X-Missing"); got != 0 {
+		t.Fatalf("missing HeaderInt=%d", got)
+	}
+}
+
+func TestHeaderTime(t *testing.T) {
+	want := time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+	c, _ := headerCtx(http.Header{
+		"If-Modified-Since": {"Wed, 21 Oct 2015 07:28:00 GMT"},
+		"Date":              {"Wednesday, 21-Oct-15 07:28:00 GMT"}, // RFC 850
+		"X-Bad":             {"yesterday"},
+	})
+	if got := c.HeaderTime("If-Modified-Since"); !got.Equal(want) {
+		t.Fatalf("HeaderTime=%v", got)
+	}
+	if got := c.HeaderTime("Date"); !got.Equal(want) {
+		t.Fatalf("RFC 850 HeaderTime=%v", got)
+	}
+	if got := c.HeaderTime("X-Bad"); !got.IsZero() {
+		t.Fatalf("invalid HeaderTime=%v", got)
+	}
+	if got := c.HeaderTime("X-Missing", want); !got.Equal(want) {
+		t.Fatalf("default HeaderTime=%v", got)
+	}
+}
+
+func TestHeaderList(t *testing.T) {
+	c, _ := headerCtx(http.Header{
+		"Accept-Language": {"en;q=0.8, fr-CH", "fr;q=0.9, de;q=0, *;q=0.5"},
+		"If-None-Match":   {`"a,b", W/"c"`},
+		"Accept":          {`text/html;level=1;charset="utf-8";q=bogus, , image/*;q=2`},
+	})
+	var langs []string
+	for _, v := range c.HeaderList("accept-language") {
+		langs = append(langs, v.Value)
+	}
+	if !reflect.DeepEqual(langs, []string{"fr-CH", "fr", "en", "*", "de"}) {
+		t.Fatalf("order=%v", langs)
+	}
+
+	tags := c.HeaderList("If-None-Match")
+	if len(tags) != 2 || tags[0].Value != `"a,b"` || tags[1].Value != `W/"c"` {
+		t.Fatalf("quoted split=%+v", tags)
+	}
+
+	acc := c.HeaderList("Accept")
+	if len(acc) != 2 {
+		t.Fatalf("accept=%+v", acc)
+	}
+	if acc[0].Value != "text/html" || acc[0].Quality != 1 || acc[0].Params["level"] != "1" || acc[0].Params["charset"] != "utf-8" {
+		t.Fatalf("params=%+v", acc[0])
+	}
+	if acc[1].Quality != 1 || acc[1].Params != nil {
+		t.Fatalf("clamped=%+v", acc[1])
+	}
+
+	if got := c.HeaderList("X-Missing"); got != nil {
+		t.Fatalf("missing=%v", got)
+	}
+}
+
+func TestAppendAndSetHeaders(t *testing.T) {
+	c, rec := headerCtx(http.Header{})
+	c.Header("Vary", "Accept")
+	c.AppendHeader("Vary", "Accept-Language")
+	c.SetHeaders(map[string]string{"cache-control": "no-store", "X-Frame-Options": "DENY"})
+	c.SetHeaders(map[string]string{"X-Frame-Options": "SAMEORIGIN"})
+	if err := c.String(http.StatusOK, "ok"); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.Header().Values("Vary"); !reflect.DeepEqual(got, []string{"Accept", "Accept-Language"}) {
+		t.Fatalf("Vary=%v", got)
+	}
+	if rec.Header().Get("Cache-Control") != "no-store" || rec.Header().Get("X-Frame-Options") != "SAMEORIGIN" {
+		t.Fatalf("headers=%v", rec.Header())
+	}
+}
@@ -0,0 +1,111 @@
+package ctx
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// hookList holds the response hooks of a request. It is shared with clones so
+// hooks registered from another goroutine (e.g. under the Timeout
+// middleware) are not lost.
+type hookList struct {
+	mu    sync.Mutex
+	hooks []responseHook
+}
+
+type responseHook struct {
+	fn         func(ctx context.Context, status int)
+	commitOnly bool
+}
+
+func (c *DefaultContext) responseHooks() *hookList {
+	if c.hooks == nil {
+		c.hooks = &hookList{}
+	}
+	return c.hooks
+}
+
+// OnCommit registers fn to run after the response has been sent, only if the
+// request succeeded: the handler chain returned no error and the status is
+// below 400. Because the Tx middleware turns commit failures into errors, fn
+// runs only once the request's transaction is committed, which makes it the
+// place to publish transactional-outbox messages, send emails or enqueue jobs.
+//
+// Hooks run in registration order on a background goroutine tracked by the
+// app, which waits for them during Shutdown. fn receives a context carrying
+// the request's values but not its cancellation. Panics are recovered and
+// logged.
+//
+// Example:
+//
+//	api.POST("/orders", func(c flash.Ctx) error {
+//		tx, _ := middleware.TxFromCtx(c)
+//		id, err := insertOrder(c.Context(), tx, order)
+//		if err != nil {
+//			return err
+//		}
+//		c.OnCommit(func(ctx context.Context) { outbox.Flush(ctx) })
+//		return c.Status(http.StatusCreated).JSON(map[string]any{"id": id})
+//	})
+func (c *DefaultContext) OnCommit(fn func(ctx context.Context)) {
+	if fn == nil {
+		return
+	}
+	c.addHook(responseHook{fn: func(ctx context.Context, _ int) { fn(ctx) }, commitOnly: true})
+}
+
+// AfterResponse registers fn to run after the response has been sent,
+// whatever its outcome, with the final status (500 if the handler chain
+// returned an error and nothing was written). Scheduling and panic handling
+// are as for OnCommit.
+//
+// Example:
+//
+//	c.AfterResponse(func(ctx context.Context, status int) {
+//		audit.Record(ctx, c.Route(), status)
+//	})
+func (c *DefaultContext) AfterResponse(fn func(ctx context.Context, status int)) {
+	if fn == nil {
+		return
+	}
+	c.addHook(responseHook{fn: fn})
+}
+
+func (c *DefaultContext) addHook(h responseHook) {
+	l := c.responseHooks()
+	l.mu.Lock()
+	l.hooks = append(l.hooks, h)
+	l.mu.Unlock()
+}
+
+// TakeResponseHooks removes the registered hooks and returns those that apply
+// to a request whose handler chain returned err, bound to the final status.
+// It returns nil when there are none. Used internally by the app.
+func (c *DefaultContext) TakeResponseHooks(err error) []func(ctx context.Context) {
+	if c.hooks == nil {
+		return nil
+	}
+	c.hooks.mu.Lock()
+	hooks := c.hooks.hooks
+	c.hooks.hooks = nil
+	c.hooks.mu.Unlock()
+
+	status := c.status
+	if status == 0 {
+		status = http.StatusOK
+		if err != nil && !c.wroteHeader {
+			status = http.StatusInternalServerError
+		}
+	}
+	ok := err == nil && status < http.StatusBadRequest
+	var out []func(ctx context.Context)
+	for _, h := range hooks {
+		if h.commitOnly && !ok {
+			continue
+		}
+		fn := h.fn
+		out = append(out, func(ctx context.Context) { fn(ctx, status) })
+	}
+	return out
+}
@@ -0,0 +1,72 @@
+package ctx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func runHooks(hooks []func(context.Context)) {
+	for _, h := range hooks {
+		h(context.Background())
+	}
+}
+
+func TestResponseHooks(t *testing.T) {
+	cases := []struct {
+		name       string
+		respond    func(c *DefaultContext)
+		err        error
+		wantCommit bool
+		wantStatus int
+	}{
+		{"ok", func(c *DefaultContext) { _ = c.String(http.StatusCreated, "") }, nil, true, http.StatusCreated},
+		{"no write", func(c *DefaultContext) {}, nil, true, http.StatusOK},
+		{"4xx", func(c *DefaultContext) { _ = c.String(http.StatusConflict, "") }, nil, false, http.StatusConflict},
+		{"error", func(c *DefaultContext) {}, errors.New("boom"), false, http.StatusInternalServerError},
+		{"error after write", func(c *DefaultContext) { _ = c.String(http.StatusOK, "") }, errors.New("commit failed"), false, http.StatusOK},
+	}
+	for _, tc := range cases {
+		c := &DefaultContext{}
+		c.Reset(httptest.NewRecorder(), httptest.NewRequest("POST", "/", nil), nil, "/")
+		var order []string
+		var status int
+		c.OnCommit(func(context.Context) { order = append(order, "commit") })
+		c.AfterResponse(func(_ context.Context, s int) { order = append(order, "after"); status = s })
+		c.OnCommit(nil)
+		c.AfterResponse(nil)
+		tc.respond(c)
+
+		runHooks(c.TakeResponseHooks(tc.err))
+		want := "after"
+		if tc.wantCommit {
+			want = "commit,after"
+		}
+		if got := strings.Join(order, ","); got != want || status != tc.wantStatus {
+			t.Fatalf("%s: ran %q with status %d, want %q %d", tc.name, got, status, want, tc.wantStatus)
+		}
+		if c.TakeResponseHooks(nil) != nil {
+			t.Fatalf("%s: hooks must be taken once", tc.name)
+		}
+	}
+}
+
+func TestResponseHooksSharedWithClone(t *testing.T) {
+	c := &DefaultContext{}
+	c.Reset(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), nil, "/")
+	ran := false
+	c.Clone().OnCommit(func(context.Context) { ran = true })
+	runHooks(c.TakeResponseHooks(nil))
+	if !ran {
+		t.Fatalf("hook registered on a clone should run")
+	}
+
+	c.OnCommit(func(context.Context) {})
+	c.Reset(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), nil, "/")
+	if c.TakeResponseHooks(nil) != nil {
+		t.Fatalf("Reset must clear hooks")
+	}
+}
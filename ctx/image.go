@@ -0,0 +1,179 @@
+package ctx
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrImageFormat is returned when encoding to a format other than "png",
+// "jpeg" (or "jpg") and "gif".
+var ErrImageFormat = errors.New("unsupported image format")
+
+// DefaultImageQuality is the JPEG quality used when none is given.
+const DefaultImageQuality = 85
+
+// ImageFit selects how ResizeImage maps an image onto the requested box.
+type ImageFit string
+
+const (
+	// FitContain scales the image to fit inside the box, keeping its aspect
+	// ratio. The result may be smaller than the box on one side.
+	FitContain ImageFit = "contain"
+	// FitCover scales the image to cover the box, keeping its aspect ratio,
+	// and crops the overflow around the center.
+	FitCover ImageFit = "cover"
+	// FitFill stretches the image to exactly the box.
+	FitFill ImageFit = "fill"
+)
+
+// Image encodes img in format ("png", "jpeg"/"jpg" or "gif") and writes it
+// with the matching Content-Type and the status set by Status (default 200).
+// quality applies to JPEG only, from 1 to 100; zero means
+// DefaultImageQuality. Unknown formats return ErrImageFormat before anything
+// is written.
+//
+// Example:
+//
+//	img := renderChart(data)
+//	return c.Image(img, "png", 0)
+//
+// Example (thumbnail from an upload):
+//
+//	src, _, err := image.Decode(f)
+//	if err != nil {
+//		return err
+//	}
+//	return c.Image(ctx.ResizeImage(src, 200, 200, ctx.FitCover), "jpeg", 80)
+func (c *DefaultContext) Image(img image.Image, format string, quality int) error {
+	var buf bytes.Buffer
+	ct, err := EncodeImage(&buf, img, format, quality)
+	if err != nil {
+		return err
+	}
+	status := c.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	_, err = c.Send(status, ct, buf.Bytes())
+	return err
+}
+
+// EncodeImage writes img to w in format and returns its Content-Type. See
+// Ctx.Image for the supported formats and quality.
+func EncodeImage(w io.Writer, img image.Image, format string, quality int) (string, error) {
+	switch strings.ToLower(format) {
+	case "png":
+		return "image/png", png.Encode(w, img)
+	case "jpeg", "jpg":
+		if quality <= 0 || quality > 100 {
+			quality = DefaultImageQuality
+		}
+		return "image/jpeg", jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case "gif":
+		return "image/gif", gif.Encode(w, img, nil)
+	}
+	return "", ErrImageFormat
+}
+
+// ResizeImage scales img into a width x height box according to fit. A zero
+// width or height is derived from the other using the image's aspect ratio
+// (the box is then exact for every fit); if both are zero img is returned
+// unchanged. Downscaling averages the covered source pixels, which is
+// adequate for thumbnails without a dedicated imaging library.
+//
+// Example:
+//
+//	thumb := ctx.ResizeImage(photo, 320, 0, ctx.FitContain) // 320px wide
+func ResizeImage(img image.Image, width, height int, fit ImageFit) image.Image {
+	sb := img.Bounds()
+	sw, sh := sb.Dx(), sb.Dy()
+	if sw == 0 || sh == 0 || (width <= 0 && height <= 0) {
+		return img
+	}
+	switch {
+	case width <= 0:
+		width = max(1, sw*height/sh)
+	case height <= 0:
+		height = max(1, sh*width/sw)
+	}
+
+	src := sb // source rectangle to sample
+	dw, dh := width, height
+	switch fit {
+	case FitCover:
+		// Crop the source to the box's aspect ratio around the center.
+		if sw*height > sh*width {
+			cw := sh * width / height
+			src.Min.X += (sw - cw) / 2
+			src.Max.X = src.Min.X + cw
+		} else {
+			ch := sw * height / width
+			src.Min.Y += (sh - ch) / 2
+			src.Max.Y = src.Min.Y + ch
+		}
+	case FitFill:
+	default: // FitContain
+		if sw*height > sh*width {
+			dh = max(1, sh*width/sw)
+		} else {
+			dw = max(1, sw*height/sh)
+		}
+	}
+	return scaleBox(toRGBA(img), src, dw, dh)
+}
+
+// toRGBA returns img as *image.RGBA, converting if needed.
+func toRGBA(img image.Image) *image.RGBA {
+	if m, ok := img.(*image.RGBA); ok {
+		return m
+	}
+	b := img.Bounds()
+	m := image.NewRGBA(b)
+	draw.Draw(m, b, img, b.Min, draw.Src)
+	return m
+}
+
+// scaleBox resamples the src rectangle of m to dw x dh pixels, averaging the
+// source pixels each destination pixel covers (nearest neighbour when
+// enlarging).
+func scaleBox(m *image.RGBA, src image.Rectangle, dw, dh int) *image.RGBA {
+	out := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	sw, sh := src.Dx(), src.Dy()
+	if sw <= 0 || sh <= 0 {
+		return out
+	}
+	for y := 0; y < dh; y++ {
+		y0 := src.Min.Y + y*sh/dh
+		y1 := max(y0+1, src.Min.Y+(y+1)*sh/dh)
+		for x := 0; x < dw; x++ {
+			x0 := src.Min.X + x*sw/dw
+			x1 := max(x0+1, src.Min.X+(x+1)*sw/dw)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				i := m.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint64(m.Pix[i])
+					g += uint64(m.Pix[i+1])
+					b += uint64(m.Pix[i+2])
+					a += uint64(m.Pix[i+3])
+					i += 4
+					n++
+				}
+			}
+			o := out.PixOffset(x, y)
+			out.Pix[o] = uint8(r / n)
+			out.Pix[o+1] = uint8(g / n)
+			out.Pix[o+2] = uint8(b / n)
+			out.Pix[o+3] = uint8(a / n)
+		}
+	}
+	return out
+}
@@ -0,0 +1,115 @@
+package ctx
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http/httptest"
+	"testing"
+)
+
+// halves returns a w x h image whose left half is red and right half blue.
+func halves(w, h int) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{R: 255, A: 255}
+			if x >= w/2 {
+				c = color.NRGBA{B: 255, A: 255}
+			}
+			m.SetNRGBA(x, y, c)
+		}
+	}
+	return m
+}
+
+func TestImageWritesEncodedBody(t *testing.T) {
+	rec := httptest.NewRecorder()
+	c := &DefaultContext{}
+	c.Reset(rec, httptest.NewRequest("GET", "/", nil), nil, "/")
+	if err := c.Image(halves(4, 4), "png", 0); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != 200 || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("code %d, type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	img, err := png.Decode(rec.Body)
+	if err != nil || img.Bounds().Dx() != 4 {
+		t.Fatalf("decode: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	c.Reset(rec, httptest.NewRequest("GET", "/", nil), nil, "/")
+	c.Status(201)
+	if err := c.Image(halves(4, 4), "JPG", 50); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != 201 || rec.Header().Get("Content-Type") != "image/jpeg" {
+		t.Fatalf("code %d, type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if _, err := jpeg.Decode(rec.Body); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImageUnknownFormat(t *testing.T) {
+	rec := httptest.NewRecorder()
+	c := &DefaultContext{}
+	c.Reset(rec, httptest.NewRequest("GET", "/", nil), nil, "/")
+	if err := c.Image(halves(2, 2), "webp", 0); !errors.Is(err, ErrImageFormat) {
+		t.Fatalf("err = %v", err)
+	}
+	if c.WroteHeader() {
+		t.Fatalf("nothing should be written")
+	}
+	if _, err := EncodeImage(&bytes.Buffer{}, halves(2, 2), "gif", 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResizeImageFits(t *testing.T) {
+	src := halves(200, 100)
+	cases := []struct {
+		w, h   int
+		fit    ImageFit
+		ww, wh int
+	}{
+		{50, 50, FitContain, 50, 25},
+		{50, 50, FitCover, 50, 50},
+		{50, 50, FitFill, 50, 50},
+		{100, 0, FitContain, 100, 50},
+		{0, 20, FitCover, 40, 20},
+		{400, 400, FitContain, 400, 200},
+	}
+	for _, tc := range cases {
+		b := ResizeImage(src, tc.w, tc.h, tc.fit).Bounds()
+		if b.Dx() != tc.ww || b.Dy() != tc.wh {
+			t.Fatalf("%dx%d %s: got %v, want %dx%d", tc.w, tc.h, tc.fit, b, tc.ww, tc.wh)
+		}
+	}
+	if got := ResizeImage(src, 0, 0, FitCover); got != image.Image(src) {
+		t.Fatalf("zero box should return the source")
+	}
+}
+
+func TestResizeImageSamplesColors(t *testing.T) {
+	out := ResizeImage(halves(100, 100), 10, 10, FitFill)
+	if r, _, b, _ := out.At(0, 5).RGBA(); r>>8 != 255 || b != 0 {
+		t.Fatalf("left pixel should be red")
+	}
+	if r, _, b, _ := out.At(9, 5).RGBA(); r != 0 || b>>8 != 255 {
+		t.Fatalf("right pixel should be blue")
+	}
+
+	// Cover keeps the center of a wide image, which is still half red, half blue.
+	cov := ResizeImage(halves(400, 100), 10, 10, FitCover)
+	if r, _, _, _ := cov.At(0, 0).RGBA(); r>>8 != 255 {
+		t.Fatalf("cover lost the left half")
+	}
+	if _, _, b, _ := cov.At(9, 0).RGBA(); b>>8 != 255 {
+		t.Fatalf("cover lost the right half")
+	}
+}
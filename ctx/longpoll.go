@@ -0,0 +1,117 @@
+package ctx
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// PollFunc checks for data newer than the resume token (empty on a client's
+// first request). It returns the data and the token the client should resume
+// from, or nil data when nothing is available yet.
+type PollFunc func(ctx context.Context, token string) (data any, next string, err error)
+
+// LongPollOptions tunes Ctx.LongPoll.
+type LongPollOptions struct {
+	// Interval is how often the PollFunc runs while waiting. Default 1s.
+	Interval time.Duration
+	// Notify, when set, makes the PollFunc run as soon as a value is received,
+	// e.g. from a pub/sub subscription, instead of waiting for the next tick.
+	Notify <-chan struct{}
+	// TokenParam is the query parameter carrying the resume token; the
+	// X-Poll-Token request header is used when it is absent. Default "since".
+	TokenParam string
+}
+
+// LongPollTokenHeader carries the resume token in long-poll requests and
+// responses.
+const LongPollTokenHeader = "X-Poll-Token"
+
+// LongPoll holds the request open for up to wait until poll returns data,
+// then writes it as JSON with the next resume token in the X-Poll-Token
+// header. When wait elapses or ctx ends first, it answers 204 No Content with
+// the client's token, so the client simply polls again. The client sends the
+// token back with ?since= or the X-Poll-Token header. poll runs at once, then
+// every Interval and whenever Notify fires.
+//
+// The wait is shortened to fit the request's deadline (e.g. one set by the
+// Timeout middleware). When the client disconnects, LongPoll returns nil
+// without writing. Pass a context that ends at shutdown to release waiting
+// clients early; nil means c.Context().
+//
+// Example:
+//
+//	app.GET("/events", func(c flash.Ctx) error {
+//		return c.LongPoll(c.Context(), 30*time.Second, func(ctx context.Context, since string) (any, string, error) {
+//			events, last := store.After(ctx, since)
+//			if len(events) == 0 {
+//				return nil, since, nil
+//			}
+//			return events, last, nil
+//		}, flash.LongPollOptions{Notify: store.Subscribe(c.Context())})
+//	})
+func (c *DefaultContext) LongPoll(ctx context.Context, wait time.Duration, poll PollFunc, opts ...LongPollOptions) error {
+	var o LongPollOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Interval <= 0 {
+		o.Interval = time.Second
+	}
+	if o.TokenParam == "" {
+		o.TokenParam = "since"
+	}
+	if ctx == nil {
+		ctx = c.Context()
+	}
+	reqCtx := c.Context()
+	if dl, ok := reqCtx.Deadline(); ok {
+		// Answer before the deadline so the client gets 204, not a timeout.
+		wait = min(wait, time.Until(dl)*9/10)
+	}
+	token := c.Query(o.TokenParam)
+	if token == "" {
+		token = c.r.Header.Get(LongPollTokenHeader)
+	}
+
+	timer := time.NewTimer(max(wait, 0))
+	defer timer.Stop()
+	ticker := time.NewTicker(o.Interval)
+	defer ticker.Stop()
+	for {
+		data, next, err := poll(ctx, token)
+		if err != nil {
+			return err
+		}
+		if data != nil {
+			h := c.w.Header()
+			h.Set(LongPollTokenHeader, next)
+			h.Set("Cache-Control", "no-store")
+			return c.Status(http.StatusOK).JSON(data)
+		}
+		select {
+		case <-ticker.C:
+		case <-o.Notify:
+		case <-timer.C:
+			return c.noPollData(token)
+		case <-reqCtx.Done():
+			return nil // client gone
+		case <-ctx.Done():
+			if reqCtx.Err() != nil {
+				return nil
+			}
+			return c.noPollData(token)
+		}
+	}
+}
+
+// noPollData answers a long poll that timed out.
+func (c *DefaultContext) noPollData(token string) error {
+	h := c.w.Header()
+	h.Set(LongPollTokenHeader, token)
+	h.Set("Cache-Control", "no-store")
+	c.Status(http.StatusNoContent)
+	c.w.WriteHeader(http.StatusNoContent)
+	c.wroteHeader = true
+	return nil
+}
@@ -0,0 +1,129 @@
+package ctx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func longPollCtx(target string, reqCtx context.Context) (*DefaultContext, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodGet, target, nil).WithContext(reqCtx)
+	rec := httptest.NewRecorder()
+	var c DefaultContext
+	c.Reset(rec, req, nil, "/events")
+	return &c, rec
+}
+
+func TestLongPoll_DataAvailable(t *testing.T) {
+	c, rec := longPollCtx("/events?since=5", context.Background())
+	var calls atomic.Int32
+	err := c.LongPoll(nil, time.Second, func(_ context.Context, token string) (any, string, error) {
+		if token != "5" {
+			t.Errorf("token=%q", token)
+		}
+		if calls.Add(1) < 3 {
+			return nil, token, nil
+		}
+		return []int{6, 7}, "7", nil
+	}, LongPollOptions{Interval: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || rec.Body.String() != "[6,7]\n" && rec.Body.String() != "[6,7]" {
+		t.Fatalf("code=%d body=%q", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get(LongPollTokenHeader) != "7" || rec.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("headers=%v", rec.Header())
+	}
+}
+
+func TestLongPoll_TimeoutNoContent(t *testing.T) {
+	c, rec := longPollCtx("/events", context.Background())
+	c.Request().Header.Set(LongPollTokenHeader, "abc")
+	start := time.Now()
+	err := c.LongPoll(nil, 30*time.Millisecond, func(context.Context, string) (any, string, error) {
+		return nil, "", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if took := time.Since(start); took < 30*time.Millisecond || took > time.Second {
+		t.Fatalf("waited %v", took)
+	}
+	if rec.Code != http.StatusNoContent || rec.Header().Get(LongPollTokenHeader) != "abc" {
+		t.Fatalf("code=%d headers=%v", rec.Code, rec.Header())
+	}
+	if !c.WroteHeader() || c.StatusCode() != http.StatusNoContent {
+		t.Fatalf("ctx state: wrote=%v status=%d", c.WroteHeader(), c.StatusCode())
+	}
+}
+
+func TestLongPoll_Notify(t *testing.T) {
+	c, rec := longPollCtx("/events", context.Background())
+	notify := make(chan struct{}, 1)
+	var ready atomic.Bool
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		ready.Store(true)
+		notify <- struct{}{}
+	}()
+	start := time.Now()
+	err := c.LongPoll(nil, 5*time.Second, func(context.Context, string) (any, string, error) {
+		if ready.Load() {
+			return "hello", "1", nil
+		}
+		return nil, "", nil
+	}, LongPollOptions{Interval: time.Hour, Notify: notify})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || time.Since(start) > time.Second {
+		t.Fatalf("code=%d after %v", rec.Code, time.Since(start))
+	}
+}
+
+func TestLongPoll_ContextEnds(t *testing.T) {
+	// Shutdown context: answer 204 so the client resumes elsewhere.
+	c, rec := longPollCtx("/events", context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.LongPoll(ctx, time.Minute, func(context.Context, string) (any, string, error) { return nil, "", nil }); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("code=%d", rec.Code)
+	}
+
+	// Client gone: nothing is written.
+	reqCtx, cancelReq := context.WithCancel(context.Background())
+	cancelReq()
+	c, _ = longPollCtx("/events", reqCtx)
+	if err := c.LongPoll(nil, time.Minute, func(context.Context, string) (any, string, error) { return nil, "", nil }); err != nil {
+		t.Fatal(err)
+	}
+	if c.WroteHeader() {
+		t.Fatalf("wrote a response to a disconnected client")
+	}
+}
+
+func TestLongPoll_DeadlineAndErrors(t *testing.T) {
+	reqCtx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	c, rec := longPollCtx("/events", reqCtx)
+	if err := c.LongPoll(nil, time.Minute, func(context.Context, string) (any, string, error) { return nil, "", nil }); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("deadline: code=%d", rec.Code)
+	}
+
+	boom := errors.New("store down")
+	c, _ = longPollCtx("/events", context.Background())
+	if err := c.LongPoll(nil, time.Minute, func(context.Context, string) (any, string, error) { return nil, "", boom }); !errors.Is(err, boom) {
+		t.Fatalf("err=%v", err)
+	}
+}
@@ -0,0 +1,20 @@
+package ctx
+
+// SetRouteMeta sets the metadata of the matched route (see app.Route.Meta).
+// The map is shared between requests and must not be modified. Used
+// internally by the app.
+func (c *DefaultContext) SetRouteMeta(m map[string]any) { c.routeMeta = m }
+
+// RouteMeta returns the value the matched route attached under key with
+// app.Route.Meta.
+//
+// Example:
+//
+//	// a.GET("/reports", Reports).Meta("team", "analytics")
+//	if team, ok := c.RouteMeta("team"); ok {
+//		c.Header("X-Owner", team.(string))
+//	}
+func (c *DefaultContext) RouteMeta(key string) (any, bool) {
+	v, ok := c.routeMeta[key]
+	return v, ok
+}
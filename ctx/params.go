@@ -0,0 +1,29 @@
+package ctx
+
+// Param is a single URL path parameter matched by a route, such as "id" in
+// "/users/:id".
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params are the path parameters of a request in the order they appear in the
+// route pattern. Routers fill them when matching a request (see
+// DefaultContext.Reset).
+type Params []Param
+
+// ByName returns the value of the first parameter named name, or "" if there
+// is none.
+//
+// Example:
+//
+//	ps := ctx.Params{{Key: "id", Value: "42"}}
+//	id := ps.ByName("id") // "42"
+func (ps Params) ByName(name string) string {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,19 @@
+package ctx
+
+import "testing"
+
+func TestParams_ByName(t *testing.T) {
+	ps := Params{{Key: "id", Value: "42"}, {Key: "slug", Value: "a"}, {Key: "id", Value: "shadowed"}}
+	if got := ps.ByName("id"); got != "42" {
+		t.Fatalf("id=%q", got)
+	}
+	if got := ps.ByName("slug"); got != "a" {
+		t.Fatalf("slug=%q", got)
+	}
+	if got := ps.ByName("missing"); got != "" {
+		t.Fatalf("missing=%q", got)
+	}
+	if got := Params(nil).ByName("id"); got != "" {
+		t.Fatalf("nil params=%q", got)
+	}
+}
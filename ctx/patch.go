@@ -0,0 +1,635 @@
+package ctx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// FieldCodeReadOnly marks a patch operation on a path the patch may not change
+// (see PatchOptions).
+const FieldCodeReadOnly = "read_only"
+
+// PatchOptions restricts what BindJSONPatch and BindMergePatch may change.
+// Paths are RFC 6901 JSON Pointers into the JSON form of the target, e.g.
+// "/id" or "/owner/email".
+type PatchOptions struct {
+	// ReadOnly lists paths the patch may not change. A path also protects
+	// everything below it, and replacing or removing a parent of a read-only
+	// path is rejected too.
+	ReadOnly []string
+	// Authorize, when set, is called for every path the patch changes with the
+	// operation ("add", "remove", "replace", "move", "copy" or "merge"). A
+	// non-nil error rejects the patch; its message is reported for the path.
+	Authorize func(op, path string) error
+}
+
+// patchOp is one RFC 6902 operation. Value is nil when the member is missing;
+// json.RawMessage receives an explicit null as the literal "null".
+type patchOp struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// BindJSONPatch applies the RFC 6902 JSON Patch in the request body
+// (application/json-patch+json) to dst, a pointer to a struct or map holding
+// the current state. The patch is applied to dst's JSON form and the result
+// decoded back, so field names follow the `json` tags; fields JSON does not
+// see (unexported or tagged "-") keep their values. Patches are atomic: on
+// any error dst is left unchanged.
+//
+// Malformed operations, paths rejected by PatchOptions, failed "test"
+// operations and results that do not fit dst are reported as FieldErrors:
+// malformed operations by their index ("[1].path"), the others by the patched
+// field ("items[2].price"). The route's body size and depth limits apply.
+//
+// Example:
+//
+//	app.PATCH("/users/:id", func(c flash.Ctx) error {
+//		u := store.Get(c.Param("id"))
+//		// [{"op":"replace","path":"/name","value":"Ada"},{"op":"add","path":"/tags/-","value":"admin"}]
+//		if err := c.BindJSONPatch(&u, flash.PatchOptions{ReadOnly: []string{"/id", "/created_at"}}); err != nil {
+//			return err
+//		}
+//		store.Save(u)
+//		return c.JSON(u)
+//	})
+func (c *DefaultContext) BindJSONPatch(dst any, opts ...PatchOptions) error {
+	var o PatchOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	var ops []patchOp
+	if err := c.decodePatchBody(&ops); err != nil {
+		return err
+	}
+	errs := fieldErrorsMap{m: map[string]string{}, values: map[string]any{}, codes: map[string]string{}}
+	for i, op := range ops {
+		validatePatchOp(i, op, errs)
+	}
+	if len(errs.m) > 0 {
+		return errs
+	}
+	for _, op := range ops {
+		switch op.Op {
+		case "move":
+			o.check(op.Op, *op.From, errs)
+			o.check(op.Op, *op.Path, errs)
+		case "test":
+		default:
+			o.check(op.Op, *op.Path, errs)
+		}
+	}
+	if len(errs.m) > 0 {
+		return errs
+	}
+
+	doc, err := patchDocument(dst)
+	if err != nil {
+		return err
+	}
+	for _, op := range ops {
+		if doc, err = applyPatchOp(doc, op); err != nil {
+			var pe *patchError
+			if errors.As(err, &pe) {
+				errs.m[pointerField(pe.path)] = pe.msg
+				errs.codes[pointerField(pe.path)] = FieldCodeInvalid
+				return errs
+			}
+			return err
+		}
+	}
+	return decodePatched(doc, dst)
+}
+
+// BindMergePatch applies the RFC 7386 JSON Merge Patch in the request body
+// (application/merge-patch+json) to dst, a pointer to a struct or map holding
+// the current state: members of the patch replace those of dst, nested
+// objects are merged, and null removes a member (resetting a struct field to
+// its zero value). Like BindJSONPatch it honors PatchOptions, reports
+// FieldErrors and leaves dst unchanged on error; unknown fields are rejected.
+//
+// Example:
+//
+//	// {"name":"Ada","address":{"city":"London"},"nickname":null}
+//	if err := c.BindMergePatch(&u, flash.PatchOptions{ReadOnly: []string{"/id"}}); err != nil {
+//		return err
+//	}
+func (c *DefaultContext) BindMergePatch(dst any, opts ...PatchOptions) error {
+	var o PatchOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	var patch any
+	if err := c.decodePatchBody(&patch); err != nil {
+		return err
+	}
+	doc, err := patchDocument(dst)
+	if err != nil {
+		return err
+	}
+	var changed []string
+	doc = mergePatch(doc, patch, "", &changed)
+	errs := fieldErrorsMap{m: map[string]string{}, values: map[string]any{}, codes: map[string]string{}}
+	for _, p := range changed {
+		o.check("merge", p, errs)
+	}
+	if len(errs.m) > 0 {
+		return errs
+	}
+	return decodePatched(doc, dst)
+}
+
+// decodePatchBody decodes the request body into v, honoring the route's body
+// guards.
+func (c *DefaultContext) decodePatchBody(v any) error {
+	defer c.r.Body.Close()
+	body, err := c.jsonBody(c.bindOptions(nil))
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(body)
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		if mapped := mapBodyReadError(err); mapped != err {
+			return mapped
+		}
+		return NewError(http.StatusBadRequest, "invalid patch: "+err.Error()).WithErr(err)
+	}
+	return nil
+}
+
+// validatePatchOp records the structural problems of ops[i] in errs.
+func validatePatchOp(i int, op patchOp, errs fieldErrorsMap) {
+	at := func(member, msg string) {
+		k := "[" + strconv.Itoa(i) + "]." + member
+		errs.m[k], errs.codes[k] = msg, FieldCodeInvalid
+		if msg == ErrFieldRequired.Error() {
+			errs.codes[k] = FieldCodeRequired
+		}
+	}
+	switch op.Op {
+	case "add", "remove", "replace", "move", "copy", "test":
+	case "":
+		at("op", ErrFieldRequired.Error())
+		return
+	default:
+		at("op", "unknown operation "+strconv.Quote(op.Op))
+		return
+	}
+	if op.Path == nil {
+		at("path", ErrFieldRequired.Error())
+	} else if _, err := parsePointer(*op.Path); err != nil {
+		at("path", err.Error())
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			at("value", ErrFieldRequired.Error())
+		}
+	case "move", "copy":
+		if op.From == nil {
+			at("from", ErrFieldRequired.Error())
+		} else if _, err := parsePointer(*op.From); err != nil {
+			at("from", err.Error())
+		} else if op.Op == "move" && op.Path != nil && strings.HasPrefix(*op.Path, *op.From+"/") {
+			at("path", "cannot move a value into itself")
+		}
+	}
+}
+
+// check records in errs whether o forbids changing path with op.
+func (o PatchOptions) check(op, path string, errs fieldErrorsMap) {
+	field := pointerField(path)
+	for _, ro := range o.ReadOnly {
+		if path == ro || path == "" || strings.HasPrefix(path, ro+"/") || strings.HasPrefix(ro, path+"/") {
+			errs.m[field], errs.codes[field] = "read-only", FieldCodeReadOnly
+			return
+		}
+	}
+	if o.Authorize != nil {
+		if err := o.Authorize(op, path); err != nil {
+			errs.m[field], errs.codes[field] = err.Error(), FieldCodeReadOnly
+		}
+	}
+}
+
+// patchDocument returns the JSON form of dst as a tree of maps, slices and
+// json.Number values.
+func patchDocument(dst any) (any, error) {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil, errors.New("patch: destination must be a non-nil pointer")
+	}
+	b, err := json.Marshal(dst)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// decodePatched decodes the patched document into a fresh value of dst's type
+// and, on success, copies its JSON-visible fields into dst. Fields JSON does
+// not see (unexported or tagged "-") keep their values.
+func decodePatched(doc any, dst any) error {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(dst).Elem()
+	fresh := reflect.New(rv.Type())
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(fresh.Interface()); err != nil {
+		var target reflect.Type
+		if rv.Kind() == reflect.Struct {
+			target = rv.Type()
+		}
+		if fErr := mapJSONStrictError(err, target); fErr != nil {
+			return fErr
+		}
+		return err
+	}
+	rv.Set(mergeVisible(rv, fresh.Elem()))
+	return nil
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// mergeVisible returns a copy of old whose JSON-visible fields are taken from
+// patched, recursing into nested structs and non-nil struct pointers present
+// in both. old itself is not modified. Values that decode themselves
+// (json.Unmarshaler, encoding.TextUnmarshaler) and non-struct values are
+// taken from patched as a whole.
+func mergeVisible(old, patched reflect.Value) reflect.Value {
+	t := old.Type()
+	switch {
+	case t.Kind() == reflect.Pointer:
+		if old.IsNil() || patched.IsNil() || t.Elem().Kind() != reflect.Struct || decodesItself(t.Elem()) {
+			return patched
+		}
+		p := reflect.New(t.Elem())
+		p.Elem().Set(mergeVisible(old.Elem(), patched.Elem()))
+		return p
+	case t.Kind() != reflect.Struct || decodesItself(t):
+		return patched
+	}
+	out := reflect.New(t).Elem()
+	out.Set(old)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		dst, o, p := out.Field(i), old.Field(i), patched.Field(i)
+		if !f.IsExported() {
+			// Exported fields of an unexported embedded struct are promoted
+			// into the JSON object, but reflect only reads the struct.
+			if f.Type.Kind() != reflect.Struct {
+				continue
+			}
+			dst, o, p = exposed(dst), exposed(o), exposed(p)
+		}
+		dst.Set(mergeVisible(o, p))
+	}
+	return out
+}
+
+// exposed returns the addressable field v with reflect's read-only flag for
+// unexported fields cleared.
+func exposed(v reflect.Value) reflect.Value {
+	return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+}
+
+// decodesItself reports whether t or *t implements a JSON or text
+// unmarshaler, so its internals are not merged field by field.
+func decodesItself(t reflect.Type) bool {
+	pt := reflect.PointerTo(t)
+	return t.Implements(jsonUnmarshalerType) || pt.Implements(jsonUnmarshalerType) ||
+		t.Implements(textUnmarshalerType) || pt.Implements(textUnmarshalerType)
+}
+
+// patchError is a failed operation, reported for path.
+type patchError struct {
+	path, msg string
+}
+
+func (e *patchError) Error() string { return "patch " + e.path + ": " + e.msg }
+
+// applyPatchOp applies one validated operation to doc and returns the result.
+func applyPatchOp(doc any, op patchOp) (any, error) {
+	path, _ := parsePointer(*op.Path)
+	var value any
+	if op.Value != nil {
+		dec := json.NewDecoder(bytes.NewReader(op.Value))
+		dec.UseNumber()
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+	}
+	fail := func(msg string) error { return &patchError{path: *op.Path, msg: msg} }
+	switch op.Op {
+	case "add":
+		return addAt(doc, path, value, fail)
+	case "remove":
+		doc, _, err := removeAt(doc, path, fail)
+		return doc, err
+	case "replace":
+		if _, err := getAt(doc, path, fail); err != nil {
+			return nil, err
+		}
+		doc, _, err := removeAt(doc, path, fail)
+		if err != nil {
+			return nil, err
+		}
+		return addAt(doc, path, value, fail)
+	case "move", "copy":
+		from, _ := parsePointer(*op.From)
+		failFrom := func(msg string) error { return &patchError{path: *op.From, msg: msg} }
+		v, err := getAt(doc, from, failFrom)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "move" {
+			if doc, _, err = removeAt(doc, from, failFrom); err != nil {
+				return nil, err
+			}
+		} else {
+			v = copyJSON(v)
+		}
+		return addAt(doc, path, v, fail)
+	default: // test
+		v, err := getAt(doc, path, fail)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(v, value) {
+			return nil, fail("test failed")
+		}
+		return doc, nil
+	}
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped tokens.
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+	if p[0] != '/' {
+		return nil, errors.New("invalid JSON pointer")
+	}
+	toks := strings.Split(p[1:], "/")
+	for i, t := range toks {
+		toks[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return toks, nil
+}
+
+// pointerField converts a JSON Pointer to the dotted field path used by
+// FieldErrors, e.g. "/items/2/price" to "items[2].price".
+func pointerField(p string) string {
+	toks, _ := parsePointer(p)
+	var b strings.Builder
+	for _, t := range toks {
+		if _, err := strconv.Atoi(t); err == nil || t == "-" {
+			b.WriteString("[" + t + "]")
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(t)
+	}
+	return b.String()
+}
+
+// arrayIndex parses tok as an index into a of length n; "-" (append) is
+// allowed when end is true.
+func arrayIndex(tok string, n int, end bool) (int, bool) {
+	if tok == "-" && end {
+		return n, true
+	}
+	if tok == "" || (len(tok) > 1 && tok[0] == '0') {
+		return 0, false
+	}
+	i, err := strconv.Atoi(tok)
+	if err != nil || i < 0 || i > n || (i == n && !end) {
+		return 0, false
+	}
+	return i, true
+}
+
+func getAt(node any, path []string, fail func(string) error) (any, error) {
+	for _, tok := range path {
+		switch n := node.(type) {
+		case map[string]any:
+			v, ok := n[tok]
+			if !ok {
+				return nil, fail("path not found")
+			}
+			node = v
+		case []any:
+			i, ok := arrayIndex(tok, len(n), false)
+			if !ok {
+				return nil, fail("index out of range")
+			}
+			node = n[i]
+		default:
+			return nil, fail("path not found")
+		}
+	}
+	return node, nil
+}
+
+// addAt returns node with value added at path (RFC 6902 "add").
+func addAt(node any, path []string, value any, fail func(string) error) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	tok := path[0]
+	switch n := node.(type) {
+	case map[string]any:
+		if len(path) == 1 {
+			n[tok] = value
+			return n, nil
+		}
+		child, ok := n[tok]
+		if !ok {
+			return nil, fail("path not found")
+		}
+		v, err := addAt(child, path[1:], value, fail)
+		if err != nil {
+			return nil, err
+		}
+		n[tok] = v
+		return n, nil
+	case []any:
+		if len(path) == 1 {
+			i, ok := arrayIndex(tok, len(n), true)
+			if !ok {
+				return nil, fail("index out of range")
+			}
+			n = append(n, nil)
+			copy(n[i+1:], n[i:])
+			n[i] = value
+			return n, nil
+		}
+		i, ok := arrayIndex(tok, len(n), false)
+		if !ok {
+			return nil, fail("index out of range")
+		}
+		v, err := addAt(n[i], path[1:], value, fail)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = v
+		return n, nil
+	default:
+		return nil, fail("path not found")
+	}
+}
+
+// removeAt returns node without the value at path, and that value.
+func removeAt(node any, path []string, fail func(string) error) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, fail("cannot remove the whole document")
+	}
+	tok := path[0]
+	switch n := node.(type) {
+	case map[string]any:
+		child, ok := n[tok]
+		if !ok {
+			return nil, nil, fail("path not found")
+		}
+		if len(path) == 1 {
+			delete(n, tok)
+			return n, child, nil
+		}
+		v, removed, err := removeAt(child, path[1:], fail)
+		if err != nil {
+			return nil, nil, err
+		}
+		n[tok] = v
+		return n, removed, nil
+	case []any:
+		i, ok := arrayIndex(tok, len(n), false)
+		if !ok {
+			return nil, nil, fail("index out of range")
+		}
+		if len(path) == 1 {
+			removed := n[i]
+			return append(n[:i], n[i+1:]...), removed, nil
+		}
+		v, removed, err := removeAt(n[i], path[1:], fail)
+		if err != nil {
+			return nil, nil, err
+		}
+		n[i] = v
+		return n, removed, nil
+	default:
+		return nil, nil, fail("path not found")
+	}
+}
+
+// mergePatch applies an RFC 7386 merge patch to target, recording the paths
+// it changes.
+func mergePatch(target, patch any, path string, changed *[]string) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		*changed = append(*changed, path)
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		if target != nil {
+			*changed = append(*changed, path)
+		}
+		t = map[string]any{}
+	}
+	for k, v := range p {
+		kp := path + "/" + strings.ReplaceAll(strings.ReplaceAll(k, "~", "~0"), "/", "~1")
+		if v == nil {
+			if _, ok := t[k]; ok {
+				delete(t, k)
+				*changed = append(*changed, kp)
+			}
+			continue
+		}
+		t[k] = mergePatch(t[k], v, kp, changed)
+	}
+	return t
+}
+
+// copyJSON deep-copies a decoded JSON value.
+func copyJSON(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(x))
+		for k, e := range x {
+			m[k] = copyJSON(e)
+		}
+		return m
+	case []any:
+		s := make([]any, len(x))
+		for i, e := range x {
+			s[i] = copyJSON(e)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+// jsonEqual compares decoded JSON values, numbers by value.
+func jsonEqual(a, b any) bool {
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fx, err1 := x.Float64()
+		fy, err2 := y.Float64()
+		return err1 == nil && err2 == nil && fx == fy
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !jsonEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !jsonEqual(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return fmt.Sprint(a) == fmt.Sprint(b) && reflect.TypeOf(a) == reflect.TypeOf(b)
+	}
+}
@@ -0,0 +1,312 @@
+package ctx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type patchAddress struct {
+	City string `json:"city"`
+	Zip  string `json:"zip,omitempty"`
+}
+
+type patchUser struct {
+	ID      int           `json:"id"`
+	Name    string        `json:"name"`
+	Tags    []string      `json:"tags"`
+	Address *patchAddress `json:"address,omitempty"`
+	Score   float64       `json:"score"`
+}
+
+func patchCtx(body string) *DefaultContext {
+	req := httptest.NewRequest(http.MethodPatch, "/users/1", strings.NewReader(body))
+	var c DefaultContext
+	c.Reset(httptest.NewRecorder(), req, nil, "/users/:id")
+	return &c
+}
+
+// patchErrs flattens fe to field => message.
+func patchErrs(fe FieldErrors) map[string]string {
+	m := map[string]string{}
+	for _, e := range fe.All() {
+		m[e.Field()] = e.Message()
+	}
+	return m
+}
+
+func sampleUser() patchUser {
+	return patchUser{ID: 1, Name: "ada", Tags: []string{"a", "b"}, Address: &patchAddress{City: "London"}, Score: 1}
+}
+
+func TestBindJSONPatch_Operations(t *testing.T) {
+	u := sampleUser()
+	err := patchCtx(`[
+		{"op":"test","path":"/score","value":1.0},
+		{"op":"replace","path":"/name","value":"Ada"},
+		{"op":"add","path":"/tags/-","value":"c"},
+		{"op":"add","path":"/tags/0","value":"z"},
+		{"op":"remove","path":"/tags/1"},
+		{"op":"copy","from":"/address/city","path":"/address/zip"},
+		{"op":"move","from":"/tags/0","path":"/name"}
+	]`).BindJSONPatch(&u)
+	if err != nil {
+		t.Fatalf("patch: %v", err)
+	}
+	want := patchUser{ID: 1, Name: "z", Tags: []string{"b", "c"}, Address: &patchAddress{City: "London", Zip: "London"}, Score: 1}
+	if !reflect.DeepEqual(u, want) {
+		t.Fatalf("got %+v %+v", u, u.Address)
+	}
+}
+
+func TestBindJSONPatch_NullValue(t *testing.T) {
+	u := sampleUser()
+	err := patchCtx(`[
+		{"op":"replace","path":"/address","value":null},
+		{"op":"test","path":"/address","value":null},
+		{"op":"add","path":"/tags","value":null}
+	]`).BindJSONPatch(&u)
+	if err != nil {
+		t.Fatalf("patch: %v %v", err, patchErrs(err.(FieldErrors)))
+	}
+	if u.Address != nil || u.Tags != nil || u.Name != "ada" {
+		t.Fatalf("got %+v", u)
+	}
+
+	m := map[string]any{"nick": "x"}
+	if err := patchCtx(`[{"op":"replace","path":"/nick","value":null},{"op":"test","path":"/nick","value":null}]`).BindJSONPatch(&m); err != nil {
+		t.Fatalf("patch map: %v", err)
+	}
+	if v, ok := m["nick"]; !ok || v != nil {
+		t.Fatalf("got %v", m)
+	}
+
+	u = sampleUser()
+	err = patchCtx(`[{"op":"test","path":"/name","value":null}]`).BindJSONPatch(&u)
+	if fe, ok := err.(FieldErrors); !ok || patchErrs(fe)["name"] == "" {
+		t.Fatalf("test null against a string should fail: %v", err)
+	}
+}
+
+func TestBindJSONPatch_AtomicOnFailure(t *testing.T) {
+	u := sampleUser()
+	err := patchCtx(`[
+		{"op":"replace","path":"/name","value":"Bob"},
+		{"op":"test","path":"/tags/0","value":"x"}
+	]`).BindJSONPatch(&u)
+	var fe FieldErrors
+	if !errors.As(err, &fe) || patchErrs(fe)["tags[0]"] != "test failed" {
+		t.Fatalf("err=%v", err)
+	}
+	if !reflect.DeepEqual(u, sampleUser()) {
+		t.Fatalf("dst changed on failure: %+v", u)
+	}
+
+	err = patchCtx(`[{"op":"remove","path":"/address/street"}]`).BindJSONPatch(&u)
+	if !errors.As(err, &fe) || patchErrs(fe)["address.street"] != "path not found" {
+		t.Fatalf("missing path err=%v", err)
+	}
+	err = patchCtx(`[{"op":"add","path":"/tags/5","value":"x"}]`).BindJSONPatch(&u)
+	if !errors.As(err, &fe) || patchErrs(fe)["tags[5]"] != "index out of range" {
+		t.Fatalf("index err=%v", err)
+	}
+}
+
+func TestBindJSONPatch_MalformedOps(t *testing.T) {
+	u := sampleUser()
+	err := patchCtx(`[
+		{"op":"frobnicate","path":"/name"},
+		{"op":"add","path":"/name"},
+		{"op":"move","path":"/tags"},
+		{"op":"replace","path":"name","value":1},
+		{"path":"/id"}
+	]`).BindJSONPatch(&u)
+	var fe FieldErrors
+	if !errors.As(err, &fe) {
+		t.Fatalf("err=%v", err)
+	}
+	all := patchErrs(fe)
+	for _, k := range []string{"[0].op", "[1].value", "[2].from", "[3].path", "[4].op"} {
+		if all[k] == "" {
+			t.Fatalf("missing %s in %v", k, all)
+		}
+	}
+	if err := patchCtx(`{"op":"add"}`).BindJSONPatch(&u); err == nil {
+		t.Fatalf("expected error for a non-array patch")
+	}
+}
+
+func TestBindJSONPatch_ReadOnlyAndAuthorize(t *testing.T) {
+	opts := PatchOptions{
+		ReadOnly: []string{"/id", "/address/city"},
+		Authorize: func(op, path string) error {
+			if path == "/score" {
+				return errors.New("admins only")
+			}
+			return nil
+		},
+	}
+	u := sampleUser()
+	err := patchCtx(`[
+		{"op":"replace","path":"/id","value":2},
+		{"op":"remove","path":"/address"},
+		{"op":"move","from":"/address/city","path":"/name"},
+		{"op":"replace","path":"/score","value":9},
+		{"op":"test","path":"/id","value":1}
+	]`).BindJSONPatch(&u, opts)
+	var fe FieldErrors
+	if !errors.As(err, &fe) {
+		t.Fatalf("err=%v", err)
+	}
+	all := patchErrs(fe)
+	want := map[string]string{"id": "read-only", "address": "read-only", "address.city": "read-only", "score": "admins only"}
+	for k, msg := range want {
+		if all[k] != msg {
+			t.Fatalf("%s=%q in %v", k, all[k], all)
+		}
+	}
+	for _, e := range fieldErrorDetails(t, fe) {
+		if e.Code() != FieldCodeReadOnly {
+			t.Fatalf("%s code=%q", e.Field(), e.Code())
+		}
+	}
+	if !reflect.DeepEqual(u, sampleUser()) {
+		t.Fatalf("dst changed: %+v", u)
+	}
+	if err := patchCtx(`[{"op":"add","path":"/address/zip","value":"N1"}]`).BindJSONPatch(&u, opts); err != nil {
+		t.Fatalf("sibling of read-only path: %v", err)
+	}
+}
+
+func TestBindJSONPatch_ResultMustFit(t *testing.T) {
+	u := sampleUser()
+	err := patchCtx(`[{"op":"add","path":"/role","value":"admin"}]`).BindJSONPatch(&u)
+	var fe FieldErrors
+	if !errors.As(err, &fe) || patchErrs(fe)["role"] == "" {
+		t.Fatalf("unknown field err=%v", err)
+	}
+	err = patchCtx(`[{"op":"replace","path":"/id","value":"one"}]`).BindJSONPatch(&u)
+	if !errors.As(err, &fe) || patchErrs(fe)["id"] == "" {
+		t.Fatalf("type err=%v", err)
+	}
+	if !reflect.DeepEqual(u, sampleUser()) {
+		t.Fatalf("dst changed: %+v", u)
+	}
+	if err := patchCtx(`[]`).BindJSONPatch(u); err == nil {
+		t.Fatalf("expected error for a non-pointer destination")
+	}
+}
+
+func TestBindJSONPatch_Map(t *testing.T) {
+	m := map[string]any{"a": map[string]any{"b/c": 1.0}}
+	if err := patchCtx(`[{"op":"replace","path":"/a/b~1c","value":2},{"op":"add","path":"/d","value":[1]}]`).BindJSONPatch(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m["a"].(map[string]any)["b/c"] != 2.0 || len(m["d"].([]any)) != 1 {
+		t.Fatalf("m=%v", m)
+	}
+}
+
+func TestBindMergePatch(t *testing.T) {
+	u := sampleUser()
+	if err := patchCtx(`{"name":"Ada","address":{"zip":"N1"},"tags":null}`).BindMergePatch(&u); err != nil {
+		t.Fatal(err)
+	}
+	want := patchUser{ID: 1, Name: "Ada", Address: &patchAddress{City: "London", Zip: "N1"}, Score: 1}
+	if !reflect.DeepEqual(u, want) {
+		t.Fatalf("got %+v %+v", u, u.Address)
+	}
+
+	opts := PatchOptions{ReadOnly: []string{"/id", "/address/city"}}
+	err := patchCtx(`{"id":5,"address":null,"name":"x"}`).BindMergePatch(&u, opts)
+	var fe FieldErrors
+	if !errors.As(err, &fe) || patchErrs(fe)["id"] != "read-only" || patchErrs(fe)["address"] != "read-only" || patchErrs(fe)["name"] != "" {
+		t.Fatalf("err=%v", err)
+	}
+	if u.Name != "Ada" {
+		t.Fatalf("dst changed: %+v", u)
+	}
+	if err := patchCtx(`{"address":{"zip":"E1"}}`).BindMergePatch(&u, opts); err != nil || u.Address.Zip != "E1" {
+		t.Fatalf("err=%v u=%+v", err, u.Address)
+	}
+	if err := patchCtx(`{"nickname":"a"}`).BindMergePatch(&u); !errors.As(err, &fe) || patchErrs(fe)["nickname"] == "" {
+		t.Fatalf("unknown field err=%v", err)
+	}
+}
+
+type patchAudit struct {
+	Note string `json:"note"`
+	by   string
+}
+
+type patchAccount struct {
+	patchAudit
+	Name         string `json:"name"`
+	PasswordHash string `json:"-"`
+	secret       string
+	Address      *patchAddress `json:"address,omitempty"`
+	Owner        *patchOwner   `json:"owner,omitempty"`
+}
+
+type patchOwner struct {
+	Email string `json:"email"`
+	token string
+}
+
+func TestPatch_KeepsHiddenFields(t *testing.T) {
+	sample := func() patchAccount {
+		return patchAccount{
+			patchAudit:   patchAudit{Note: "n", by: "admin"},
+			Name:         "ada",
+			PasswordHash: "h",
+			secret:       "s",
+			Address:      &patchAddress{City: "London"},
+			Owner:        &patchOwner{Email: "a@example.com", token: "t"},
+		}
+	}
+	check := func(name string, got patchAccount, owner *patchOwner) {
+		t.Helper()
+		if got.PasswordHash != "h" || got.secret != "s" || got.by != "admin" || got.Owner.token != "t" {
+			t.Fatalf("%s: hidden fields lost: %+v %+v", name, got, got.Owner)
+		}
+		if got.Name != "Ada" || got.Note != "m" || got.Owner.Email != "b@example.com" || got.Address != nil {
+			t.Fatalf("%s: patch not applied: %+v %+v", name, got, got.Owner)
+		}
+		if owner.Email != "a@example.com" {
+			t.Fatalf("%s: original owner modified: %+v", name, owner)
+		}
+	}
+
+	u := sample()
+	owner := u.Owner
+	err := patchCtx(`[
+		{"op":"replace","path":"/name","value":"Ada"},
+		{"op":"replace","path":"/note","value":"m"},
+		{"op":"replace","path":"/owner/email","value":"b@example.com"},
+		{"op":"remove","path":"/address"}
+	]`).BindJSONPatch(&u)
+	if err != nil {
+		t.Fatalf("patch: %v", err)
+	}
+	check("json patch", u, owner)
+
+	u = sample()
+	owner = u.Owner
+	if err := patchCtx(`{"name":"Ada","note":"m","owner":{"email":"b@example.com"},"address":null}`).BindMergePatch(&u); err != nil {
+		t.Fatalf("merge patch: %v", err)
+	}
+	check("merge patch", u, owner)
+}
+
+func TestBindPatch_BodyLimit(t *testing.T) {
+	c := patchCtx(`[{"op":"replace","path":"/name","value":"` + strings.Repeat("x", 100) + `"}]`)
+	c.SetBindDefaults(&BindJSONOptions{MaxBodyBytes: 32})
+	u := sampleUser()
+	var he *HTTPError
+	if err := c.BindJSONPatch(&u); !errors.As(err, &he) || he.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("err=%v", err)
+	}
+}
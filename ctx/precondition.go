@@ -0,0 +1,84 @@
+package ctx
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Sentinel causes of the errors returned by RequireIfMatch and the
+// RequirePreconditions middleware. Use errors.Is to detect them.
+var (
+	// ErrPreconditionFailed is wrapped by 412 errors: the client's If-Match
+	// does not match the current representation.
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrPreconditionRequired is wrapped by 428 errors: a write arrived
+	// without If-Match.
+	ErrPreconditionRequired = errors.New("precondition required")
+)
+
+// RequireIfMatch enforces optimistic concurrency on a write (RFC 9110
+// section 13.1.1). currentETag is the entity tag of the resource as it is
+// now, quoted or not; pass "" when the resource does not exist. It returns:
+//
+//   - nil when If-Match lists currentETag, or is "*" and the resource exists;
+//   - a 428 *HTTPError wrapping ErrPreconditionRequired when the request has
+//     no If-Match header;
+//   - a 412 *HTTPError wrapping ErrPreconditionFailed otherwise. The current
+//     ETag is set on the response so the client can refetch and retry.
+//
+// Matching uses the strong comparison: weak tags (W/"...") never match.
+//
+// Example:
+//
+//	app.PUT("/docs/:id", func(c flash.Ctx) error {
+//		doc, err := store.Get(c.Param("id"))
+//		if err != nil {
+//			return err
+//		}
+//		if err := c.RequireIfMatch(doc.Version); err != nil {
+//			return err // 412 or 428, rendered by the error handler
+//		}
+//		// ... update and respond with the new ETag
+//	})
+func (c *DefaultContext) RequireIfMatch(currentETag string) error {
+	header := c.r.Header.Values("If-Match")
+	if len(header) == 0 {
+		return NewError(http.StatusPreconditionRequired, "this request requires an If-Match header").WithErr(ErrPreconditionRequired)
+	}
+	etag := quoteETag(currentETag)
+	if ifMatch(strings.Join(header, ","), etag) {
+		return nil
+	}
+	if etag != "" {
+		c.w.Header().Set("ETag", etag)
+	}
+	return NewError(http.StatusPreconditionFailed, "").WithErr(ErrPreconditionFailed)
+}
+
+// quoteETag returns etag as an entity tag, quoting a bare value.
+func quoteETag(etag string) string {
+	if etag == "" || strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
+
+// ifMatch reports whether an If-Match field value matches etag.
+func ifMatch(header, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	for _, t := range splitHeaderList(header) {
+		if t == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package ctx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func ifMatchCtx(ifMatch ...string) (*DefaultContext, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodPut, "/docs/1", nil)
+	for _, v := range ifMatch {
+		req.Header.Add("If-Match", v)
+	}
+	rec := httptest.NewRecorder()
+	var c DefaultContext
+	c.Reset(rec, req, nil, "/docs/:id")
+	return &c, rec
+}
+
+func TestRequireIfMatch(t *testing.T) {
+	cases := []struct {
+		ifMatch []string
+		current string
+		want    int // 0 = pass
+	}{
+		{[]string{`"v2"`}, `"v2"`, 0},
+		{[]string{`"v2"`}, "v2", 0}, // bare current tag is quoted
+		{[]string{`"v1", "v2"`}, `"v2"`, 0},
+		{[]string{`"v1"`, `"v2"`}, `"v2"`, 0},
+		{[]string{`"a,b"`}, `"a,b"`, 0}, // commas inside a tag do not split it
+		{[]string{`"a,b"`}, `"a"`, http.StatusPreconditionFailed},
+		{[]string{"*"}, `"v2"`, 0},
+		{[]string{"*"}, "", http.StatusPreconditionFailed},
+		{[]string{`"v1"`}, `"v2"`, http.StatusPreconditionFailed},
+		{[]string{`W/"v2"`}, `"v2"`, http.StatusPreconditionFailed},
+		{[]string{`W/"v2"`}, `W/"v2"`, http.StatusPreconditionFailed},
+		{nil, `"v2"`, http.StatusPreconditionRequired},
+	}
+	for _, tc := range cases {
+		c, _ := ifMatchCtx(tc.ifMatch...)
+		err := c.RequireIfMatch(tc.current)
+		if tc.want == 0 {
+			if err != nil {
+				t.Fatalf("If-Match %v vs %q: %v", tc.ifMatch, tc.current, err)
+			}
+			continue
+		}
+		var he *HTTPError
+		if !errors.As(err, &he) || he.Code != tc.want {
+			t.Fatalf("If-Match %v vs %q: err=%v want %d", tc.ifMatch, tc.current, err, tc.want)
+		}
+	}
+}
+
+func TestRequireIfMatch_Errors(t *testing.T) {
+	c, rec := ifMatchCtx(`"v1"`)
+	if err := c.RequireIfMatch("v2"); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("err=%v", err)
+	}
+	if rec.Header().Get("ETag") != `"v2"` {
+		t.Fatalf("ETag=%q", rec.Header().Get("ETag"))
+	}
+	c, _ = ifMatchCtx()
+	if err := c.RequireIfMatch("v2"); !errors.Is(err, ErrPreconditionRequired) {
+		t.Fatalf("err=%v", err)
+	}
+}
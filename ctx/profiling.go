@@ -0,0 +1,26 @@
+package ctx
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// WithProfilingLabels adds pprof labels (key/value pairs) to parent and to the
+// calling goroutine, so the rest of the request is attributed to them in CPU
+// profiles. It only acts on requests served with profiling labels enabled
+// (app.WithProfilingLabels), recognized by their "route" label, and returns
+// parent unchanged otherwise. Set the returned context on the request so
+// goroutines started with it inherit the labels via pprof.Do.
+//
+// Example:
+//
+//	rc := ctx.WithProfilingLabels(c.Context(), "customer", customerID)
+//	c.SetRequest(c.Request().WithContext(rc))
+func WithProfilingLabels(parent context.Context, kv ...string) context.Context {
+	if _, ok := pprof.Label(parent, "route"); !ok || len(kv) == 0 {
+		return parent
+	}
+	lc := pprof.WithLabels(parent, pprof.Labels(kv...))
+	pprof.SetGoroutineLabels(lc)
+	return lc
+}
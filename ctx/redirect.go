@@ -0,0 +1,122 @@
+package ctx
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RouteURLBuilder builds URLs of named routes. app.DefaultApp implements it.
+type RouteURLBuilder interface {
+	URL(name string, params ...any) (string, error)
+}
+
+// SetRouteURLs sets the builder used by RedirectToRoute. Used internally by
+// the app.
+func (c *DefaultContext) SetRouteURLs(b RouteURLBuilder) { c.urls = b }
+
+// Redirect replies with a redirect to location, which may be relative to the
+// request path, using status (301, 302, 303, 307 or 308). Status codes outside
+// 300-399 return an error before anything is written.
+//
+// Example:
+//
+//	return c.Redirect(http.StatusSeeOther, "/login")
+func (c *DefaultContext) Redirect(status int, location string) error {
+	if status < 300 || status > 399 {
+		return fmt.Errorf("flash: invalid redirect status %d", status)
+	}
+	http.Redirect(&fileResponseWriter{ResponseWriter: c.w, c: c}, c.Request(), location, status)
+	return nil
+}
+
+// RedirectBack redirects with 303 See Other to the page the request came
+// from, as given by the Referer header, or to fallback when there is no
+// Referer or it points to another origin. Only same-host Referers are
+// followed, so the header cannot be used for open redirects.
+//
+// Example (Post/Redirect/Get):
+//
+//	if err := c.Flash("success", "Saved"); err != nil {
+//		return err
+//	}
+//	return c.RedirectBack("/settings")
+func (c *DefaultContext) RedirectBack(fallback string) error {
+	return c.Redirect(http.StatusSeeOther, c.sameOriginReferer(fallback))
+}
+
+// sameOriginReferer returns the path and query of the Referer if it has the
+// request's host, else fallback.
+func (c *DefaultContext) sameOriginReferer(fallback string) string {
+	ref := c.r.Referer()
+	if ref == "" {
+		return fallback
+	}
+	u, err := url.Parse(ref)
+	if err != nil || u.Host == "" || !strings.EqualFold(u.Host, c.r.Host) ||
+		u.Scheme != "http" && u.Scheme != "https" || u.User != nil {
+		return fallback
+	}
+	back := u.EscapedPath()
+	if !strings.HasPrefix(back, "/") || strings.HasPrefix(back, "//") {
+		return fallback
+	}
+	if u.RawQuery != "" {
+		back += "?" + u.RawQuery
+	}
+	return back
+}
+
+// RedirectWithQuery redirects to path keeping the current request's query
+// parameters, e.g. to carry filters or pagination across a form submission.
+// Parameters already present in path take precedence over the current ones.
+//
+// Example:
+//
+//	// GET /items/export?sort=name&page=3
+//	return c.RedirectWithQuery(http.StatusFound, "/items?page=1") // -> /items?page=1&sort=name
+func (c *DefaultContext) RedirectWithQuery(status int, path string) error {
+	cur := c.r.URL.Query()
+	if len(cur) == 0 {
+		return c.Redirect(status, path)
+	}
+	base, rawQuery, _ := strings.Cut(path, "?")
+	base, frag, hasFrag := strings.Cut(base, "#")
+	if q, f, ok := strings.Cut(rawQuery, "#"); ok {
+		rawQuery, frag, hasFrag = q, f, true
+	}
+	q, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return fmt.Errorf("flash: redirect %q: %w", path, err)
+	}
+	for k, vs := range cur {
+		if _, ok := q[k]; !ok {
+			q[k] = vs
+		}
+	}
+	location := base + "?" + q.Encode()
+	if hasFrag {
+		location += "#" + frag
+	}
+	return c.Redirect(status, location)
+}
+
+// RedirectToRoute redirects with 303 See Other to the route registered under
+// name (see app.Route.Named), filling its params in order. An unknown name or
+// a wrong number of params returns an error before anything is written.
+//
+// Example:
+//
+//	// a.GET("/users/:id", ShowUser).Named("users.show")
+//	return c.RedirectToRoute("users.show", user.ID)
+func (c *DefaultContext) RedirectToRoute(name string, params ...any) error {
+	if c.urls == nil {
+		return fmt.Errorf("flash: no route named %q", name)
+	}
+	u, err := c.urls.URL(name, params...)
+	if err != nil {
+		return err
+	}
+	return c.Redirect(http.StatusSeeOther, u)
+}
@@ -0,0 +1,106 @@
+package ctx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func redirectCtx(target string, header http.Header) (*DefaultContext, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodPost, target, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	c := &DefaultContext{}
+	c.Reset(rec, req, nil, "/")
+	return c, rec
+}
+
+func TestRedirect(t *testing.T) {
+	c, rec := redirectCtx("/a", nil)
+	if err := c.Redirect(http.StatusFound, "/b"); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/b" || c.StatusCode() != http.StatusFound || !c.WroteHeader() {
+		t.Fatalf("got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	c, rec = redirectCtx("/a", nil)
+	if err := c.Redirect(http.StatusOK, "/b"); err == nil || rec.Header().Get("Location") != "" {
+		t.Fatalf("non-3xx status must fail without writing")
+	}
+}
+
+func TestRedirectBack(t *testing.T) {
+	cases := []struct {
+		referer string
+		want    string
+	}{
+		{"", "/home"},
+		{"http://example.com/items?page=2", "/items?page=2"},
+		{"https://example.com/a%20b", "/a%20b"},
+		{"http://evil.com/items", "/home"},
+		{"//evil.com/items", "/home"},
+		{"javascript:alert(1)", "/home"},
+		{"http://user@example.com/x", "/home"},
+		{"http://example.com//evil.com/x", "/home"},
+		{"/relative", "/home"},
+	}
+	for _, tc := range cases {
+		c, rec := redirectCtx("/save", http.Header{"Referer": {tc.referer}})
+		if err := c.RedirectBack("/home"); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != tc.want {
+			t.Fatalf("Referer %q: got %d %q, want %q", tc.referer, rec.Code, rec.Header().Get("Location"), tc.want)
+		}
+	}
+}
+
+func TestRedirectWithQuery(t *testing.T) {
+	cases := []struct {
+		target, path, want string
+	}{
+		{"/export", "/items", "/items"},
+		{"/export?sort=name&page=3", "/items", "/items?page=3&sort=name"},
+		{"/export?sort=name&page=3", "/items?page=1", "/items?page=1&sort=name"},
+		{"/export?q=a+b", "/items#top", "/items?q=a+b#top"},
+	}
+	for _, tc := range cases {
+		c, rec := redirectCtx(tc.target, nil)
+		if err := c.RedirectWithQuery(http.StatusFound, tc.path); err != nil {
+			t.Fatal(err)
+		}
+		if got := rec.Header().Get("Location"); got != tc.want {
+			t.Fatalf("%s -> %s: got %q, want %q", tc.target, tc.path, got, tc.want)
+		}
+	}
+	c, _ := redirectCtx("/x?a=1", nil)
+	if err := c.RedirectWithQuery(http.StatusFound, "/y?%zz"); err == nil {
+		t.Fatalf("expected error for invalid query")
+	}
+}
+
+type stubURLs map[string]string
+
+func (s stubURLs) URL(name string, _ ...any) (string, error) {
+	if u, ok := s[name]; ok {
+		return u, nil
+	}
+	return "", errors.New("unknown route")
+}
+
+func TestRedirectToRoute(t *testing.T) {
+	c, rec := redirectCtx("/", nil)
+	if err := c.RedirectToRoute("home"); err == nil {
+		t.Fatalf("expected error without a route URL builder")
+	}
+	c.SetRouteURLs(stubURLs{"home": "/home"})
+	if err := c.RedirectToRoute("nope"); err == nil || rec.Header().Get("Location") != "" {
+		t.Fatalf("unknown route must fail without writing")
+	}
+	if err := c.RedirectToRoute("home"); err != nil || rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/home" {
+		t.Fatalf("got %v %d %q", err, rec.Code, rec.Header().Get("Location"))
+	}
+}
@@ -0,0 +1,206 @@
+package ctx
+
+import (
+	"fmt"
+	"html"
+	"net/mail"
+	"net/url"
+	"strings"
+)
+
+// Sanitizer cleans an untrusted input value for a named policy. It returns
+// the normalized value, or "" to reject the input.
+type Sanitizer func(s string) string
+
+// Built-in sanitizer policies, available to ParamSanitized and QuerySanitized
+// in every app. App.RegisterSanitizer adds policies or replaces these.
+const (
+	SanitizeHTML     = "html"     // HTML-escaped, like ParamSafe
+	SanitizeAlphaNum = "alphanum" // ASCII letters and digits only, like ParamAlphaNum
+	SanitizeFilename = "filename" // safe file name, like ParamFilename
+	SanitizeUUID     = "uuid"     // canonical UUID, lowercased; anything else is rejected
+	SanitizeEmail    = "email"    // bare address with a lowercased domain; anything else is rejected
+	SanitizeSlug     = "slug"     // lowercase ASCII words joined by "-", e.g. "hello-world"
+	SanitizeHostname = "hostname" // RFC 1123 host name, lowercased; anything else is rejected
+)
+
+var builtinSanitizers = map[string]Sanitizer{
+	SanitizeHTML:     html.EscapeString,
+	SanitizeAlphaNum: sanitizeAlphaNum,
+	SanitizeFilename: sanitizeFilename,
+	SanitizeUUID:     sanitizeUUID,
+	SanitizeEmail:    sanitizeEmail,
+	SanitizeSlug:     sanitizeSlug,
+	SanitizeHostname: sanitizeHostname,
+}
+
+// SetSanitizers sets the app-registered sanitizer policies, which take
+// precedence over the built-in ones. Used internally by the app on each
+// request; the map is shared and must not be modified.
+func (c *DefaultContext) SetSanitizers(m map[string]Sanitizer) { c.sanitizers = m }
+
+// ParamSanitized returns the path parameter name cleaned by the sanitizer
+// policy registered as policy, or "" when the policy rejects it. Built-in
+// policies are listed with SanitizeHTML; apps add their own with
+// App.RegisterSanitizer so input hygiene rules live in one place instead of
+// in every handler. It panics if no sanitizer is registered for policy,
+// which surfaces typos as a 500 on the first request.
+//
+// Example:
+//
+//	// Route: /posts/:slug, URL: /posts/Hello%20World!
+//	slug := c.ParamSanitized("slug", ctx.SanitizeSlug) // "hello-world"
+//
+//	// Route: /orders/:id
+//	id := c.ParamSanitized("id", ctx.SanitizeUUID)
+//	if id == "" {
+//		return c.Status(http.StatusBadRequest).JSON(map[string]string{"error": "invalid id"})
+//	}
+func (c *DefaultContext) ParamSanitized(name, policy string) string {
+	return c.sanitize(policy, c.Param(name))
+}
+
+// QuerySanitized returns the query parameter key cleaned by the sanitizer
+// policy registered as policy, or "" when the policy rejects it. See
+// ParamSanitized.
+//
+// Example:
+//
+//	// URL: /invite?email=Ada@Example.COM
+//	email := c.QuerySanitized("email", ctx.SanitizeEmail) // "Ada@example.com"
+func (c *DefaultContext) QuerySanitized(key, policy string) string {
+	return c.sanitize(policy, c.Query(key))
+}
+
+func (c *DefaultContext) sanitize(policy, s string) string {
+	fn, ok := c.sanitizers[policy]
+	if !ok {
+		fn, ok = builtinSanitizers[policy]
+	}
+	if !ok || fn == nil {
+		panic(fmt.Sprintf("ctx: no sanitizer registered for policy %q", policy))
+	}
+	if s == "" {
+		return ""
+	}
+	return fn(s)
+}
+
+func isAlphaNum(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+// sanitizeAlphaNum strips everything but ASCII letters and digits.
+func sanitizeAlphaNum(s string) string {
+	var result strings.Builder
+	for _, r := range s {
+		if isAlphaNum(r) {
+			result.WriteRune(r)
+		}
+	}
+	return result.String()
+}
+
+// sanitizeFilename keeps ASCII letters, digits, dots, dashes and
+// underscores of the URL-decoded s, without a leading dot.
+func sanitizeFilename(s string) string {
+	// URL decode first to handle encoded path traversal attempts
+	decoded, err := url.QueryUnescape(s)
+	if err != nil {
+		decoded = s
+	}
+
+	// Extract only safe filename characters
+	var result strings.Builder
+	for _, r := range decoded {
+		if isAlphaNum(r) || r == '.' || r == '-' || r == '_' {
+			result.WriteRune(r)
+		}
+	}
+
+	filename := result.String()
+
+	// Prevent hidden files and relative paths
+	filename = strings.TrimPrefix(filename, ".")
+
+	return filename
+}
+
+// sanitizeUUID accepts the canonical 8-4-4-4-12 hex form.
+func sanitizeUUID(s string) string {
+	if len(s) != 36 {
+		return ""
+	}
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if ch != '-' {
+				return ""
+			}
+		default:
+			if !(ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'f' || ch >= 'A' && ch <= 'F') {
+				return ""
+			}
+		}
+	}
+	return strings.ToLower(s)
+}
+
+// sanitizeEmail accepts a bare address (no display name or angle brackets)
+// of at most 254 bytes whose domain is a valid host name.
+func sanitizeEmail(s string) string {
+	if len(s) > 254 {
+		return ""
+	}
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Name != "" || addr.Address != s {
+		return ""
+	}
+	at := strings.LastIndexByte(s, '@')
+	domain := sanitizeHostname(s[at+1:])
+	if domain == "" || !strings.Contains(domain, ".") {
+		return ""
+	}
+	return s[:at+1] + domain
+}
+
+// sanitizeSlug lowercases s and joins its runs of ASCII letters and digits
+// with single dashes.
+func sanitizeSlug(s string) string {
+	var result strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if isAlphaNum(r) {
+			if dash && result.Len() > 0 {
+				result.WriteByte('-')
+			}
+			dash = false
+			result.WriteRune(r)
+			continue
+		}
+		dash = true
+	}
+	return result.String()
+}
+
+// sanitizeHostname accepts dot-separated labels of 1 to 63 letters, digits
+// and inner dashes, 253 bytes at most. A trailing dot is dropped.
+func sanitizeHostname(s string) string {
+	s = strings.TrimSuffix(strings.ToLower(s), ".")
+	if s == "" || len(s) > 253 {
+		return ""
+	}
+	for _, label := range strings.Split(s, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return ""
+		}
+		for i := 0; i < len(label); i++ {
+			ch := label[i]
+			if !(ch >= 'a' && ch <= 'z' || ch >= '0' && ch <= '9' || ch == '-') {
+				return ""
+			}
+		}
+	}
+	return s
+}
@@ -0,0 +1,91 @@
+package ctx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinSanitizers(t *testing.T) {
+	cases := []struct {
+		policy, in, want string
+	}{
+		{SanitizeHTML, "<b>", "&lt;b&gt;"},
+		{SanitizeAlphaNum, "ab-12_!", "ab12"},
+		{SanitizeFilename, "report%20v2.pdf", "reportv2.pdf"},
+		{SanitizeUUID, "550E8400-E29B-41D4-A716-446655440000", "550e8400-e29b-41d4-a716-446655440000"},
+		{SanitizeUUID, "550e8400e29b41d4a716446655440000", ""},
+		{SanitizeUUID, "550e8400-e29b-41d4-a716-44665544000g", ""},
+		{SanitizeEmail, "Ada@Example.COM", "Ada@example.com"},
+		{SanitizeEmail, "Ada <ada@example.com>", ""},
+		{SanitizeEmail, "ada@localhost", ""},
+		{SanitizeEmail, "ada@exa_mple.com", ""},
+		{SanitizeEmail, "not-an-email", ""},
+		{SanitizeSlug, "  Hello, World!  2024 ", "hello-world-2024"},
+		{SanitizeSlug, "---", ""},
+		{SanitizeHostname, "API.Example.com.", "api.example.com"},
+		{SanitizeHostname, "-bad.example.com", ""},
+		{SanitizeHostname, "a..b", ""},
+		{SanitizeHostname, strings.Repeat("a", 64) + ".com", ""},
+		{SanitizeHostname, "exa mple.com", ""},
+	}
+	for _, tc := range cases {
+		if got := builtinSanitizers[tc.policy](tc.in); got != tc.want {
+			t.Fatalf("%s(%q)=%q want %q", tc.policy, tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestParamAndQuerySanitized(t *testing.T) {
+	var c DefaultContext
+	r := httptest.NewRequest(http.MethodGet, "/posts/x?email=Ada@Example.COM&id=nope", nil)
+	c.Reset(httptest.NewRecorder(), r, Params{{Key: "slug", Value: "Hello World!"}}, "/posts/:slug")
+	if got := c.ParamSanitized("slug", SanitizeSlug); got != "hello-world" {
+		t.Fatalf("slug=%q", got)
+	}
+	if got := c.QuerySanitized("email", SanitizeEmail); got != "Ada@example.com" {
+		t.Fatalf("email=%q", got)
+	}
+	if got := c.QuerySanitized("id", SanitizeUUID); got != "" {
+		t.Fatalf("invalid uuid accepted: %q", got)
+	}
+	if got := c.QuerySanitized("missing", SanitizeSlug); got != "" {
+		t.Fatalf("missing=%q", got)
+	}
+}
+
+func TestSanitized_AppPoliciesOverrideBuiltins(t *testing.T) {
+	var c DefaultContext
+	r := httptest.NewRequest(http.MethodGet, "/?sku=ab-12&slug=A_B", nil)
+	c.Reset(httptest.NewRecorder(), r, nil, "/")
+	c.SetSanitizers(map[string]Sanitizer{
+		"sku":        strings.ToUpper,
+		SanitizeSlug: func(s string) string { return "custom" },
+	})
+	if got := c.QuerySanitized("sku", "sku"); got != "AB-12" {
+		t.Fatalf("sku=%q", got)
+	}
+	if got := c.QuerySanitized("slug", SanitizeSlug); got != "custom" {
+		t.Fatalf("override not used: %q", got)
+	}
+	// Built-ins stay available next to app policies.
+	if got := c.QuerySanitized("sku", SanitizeAlphaNum); got != "ab12" {
+		t.Fatalf("alphanum=%q", got)
+	}
+	c.Reset(httptest.NewRecorder(), r, nil, "/")
+	if c.sanitizers != nil {
+		t.Fatalf("Reset kept app sanitizers")
+	}
+}
+
+func TestSanitized_UnknownPolicyPanics(t *testing.T) {
+	var c DefaultContext
+	c.Reset(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/?q=x", nil), nil, "/")
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), `"slgu"`) {
+			t.Fatalf("recover=%v", r)
+		}
+	}()
+	c.QuerySanitized("q", "slgu")
+}
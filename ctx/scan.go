@@ -0,0 +1,261 @@
+package ctx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/goflash/flash/v2/storage"
+)
+
+// ErrUploadInfected is wrapped by ScanFunc results that report malware (as
+// opposed to a scanner failure), and by SaveUploadedFile when it refuses an
+// infected file.
+var ErrUploadInfected = errors.New("upload rejected by virus scanner")
+
+// ScanFunc streams a file through a malware scanner such as ClamAV (clamd
+// INSTREAM) or an ICAP server. It returns nil for clean files, an error
+// wrapping ErrUploadInfected on detection, and any other error when the file
+// could not be scanned.
+//
+// Example (signature name in the error):
+//
+//	scan := func(r io.Reader) error {
+//		res, err := clam.ScanStream(r)
+//		if err != nil {
+//			return err
+//		}
+//		if res.Infected {
+//			return fmt.Errorf("%w: %s", flash.ErrUploadInfected, res.Signature)
+//		}
+//		return nil
+//	}
+type ScanFunc func(r io.Reader) error
+
+// UploadScanner configures virus scanning for an UploadPolicy.
+//
+// In synchronous mode (the default) files are scanned while the policy is
+// validated, so BindForm, BindAny and ValidateUploads report infected files as
+// FieldErrors with code FieldCodeInfected, and SaveUploadedFile refuses to
+// write a file that was not scanned clean. Scanner failures are returned as
+// errors: uploads fail closed.
+//
+// In Async mode validation does not scan; SaveUploadedFile writes the file and
+// scans it after the response has been sent (see Ctx.AfterResponse, so the
+// app's Shutdown waits for pending scans). Infected files are moved to
+// QuarantineDir, or removed when it is empty, and reported to OnDetect.
+type UploadScanner struct {
+	// Scan scans one file. Required.
+	Scan ScanFunc
+	// Async scans saved files in the background instead of during validation.
+	Async bool
+	// QuarantineDir receives infected files found by async scans.
+	QuarantineDir string
+	// OnDetect is called for every infected file found by an async scan.
+	OnDetect func(ctx context.Context, d UploadDetection)
+	// OnError is called when an async scan fails. The file is kept in place.
+	OnError func(ctx context.Context, path string, err error)
+}
+
+// UploadDetection describes an infected file found by an async scan.
+type UploadDetection struct {
+	Filename   string // client-supplied name
+	Path       string // where the file was saved
+	Quarantine string // where it was moved, or "" if removed
+	Err        error  // the scanner's result, wrapping ErrUploadInfected
+}
+
+// scanUpload runs scan over the file and records clean files so
+// SaveUploadedFile does not scan them again.
+func (c *DefaultContext) scanUpload(fh *multipart.FileHeader, scan ScanFunc) error {
+	f, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := scan(f); err != nil {
+		return err
+	}
+	if c.scanned == nil {
+		c.scanned = make(map[*multipart.FileHeader]struct{})
+	}
+	c.scanned[fh] = struct{}{}
+	return nil
+}
+
+// SaveUploadedFile writes an uploaded file to dst, creating or truncating it
+// with mode 0o600.
+//
+// When the route's UploadPolicy (see BindJSONOptions.Uploads) has a Scanner,
+// synchronous mode scans the file first unless validation already did, and
+// returns a 422 *HTTPError wrapping ErrUploadInfected instead of writing an
+// infected file; async mode writes the file and scans it after the response.
+//
+// Example:
+//
+//	fh := c.Request().MultipartForm.File["avatar"][0]
+//	if err := c.SaveUploadedFile(fh, filepath.Join(dir, uuid)); err != nil {
+//		return err
+//	}
+func (c *DefaultContext) SaveUploadedFile(fh *multipart.FileHeader, dst string) error {
+	sc, err := c.scanBeforeSave(fh)
+	if err != nil {
+		return err
+	}
+	if err := writeUpload(fh, dst); err != nil {
+		return err
+	}
+	if sc != nil && sc.Async {
+		name := fh.Filename
+		c.AfterResponse(func(ctx context.Context, _ int) { sc.scanSaved(ctx, name, dst) })
+	}
+	return nil
+}
+
+// SaveUploadedFileFS writes an uploaded file to fsys under name, for example
+// to an S3 bucket through storage.S3. Scanning works as for SaveUploadedFile;
+// in async mode infected files are copied to QuarantineDir on local disk and
+// removed from fsys.
+//
+// Example:
+//
+//	fh := c.Request().MultipartForm.File["avatar"][0]
+//	if err := c.SaveUploadedFileFS(fh, media, "avatars/"+id+".png"); err != nil {
+//		return err
+//	}
+func (c *DefaultContext) SaveUploadedFileFS(fh *multipart.FileHeader, fsys storage.FS, name string) error {
+	sc, err := c.scanBeforeSave(fh)
+	if err != nil {
+		return err
+	}
+	src, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	err = fsys.Write(c.Context(), name, src)
+	src.Close()
+	if err != nil {
+		return err
+	}
+	if sc != nil && sc.Async {
+		filename := fh.Filename
+		c.AfterResponse(func(ctx context.Context, _ int) { sc.scanSavedFS(ctx, filename, fsys, name) })
+	}
+	return nil
+}
+
+// scanBeforeSave returns the route's scanner and, in synchronous mode, scans
+// fh unless validation already did.
+func (c *DefaultContext) scanBeforeSave(fh *multipart.FileHeader) (*UploadScanner, error) {
+	o := c.bindOptions(nil)
+	if o.Uploads == nil || o.Uploads.Scanner == nil {
+		return nil, nil
+	}
+	sc := o.Uploads.Scanner
+	if !sc.Async {
+		if _, ok := c.scanned[fh]; !ok {
+			if err := c.scanUpload(fh, sc.Scan); err != nil {
+				if errors.Is(err, ErrUploadInfected) {
+					return nil, NewError(http.StatusUnprocessableEntity, "upload rejected").WithErr(err)
+				}
+				return nil, err
+			}
+		}
+	}
+	return sc, nil
+}
+
+func writeUpload(fh *multipart.FileHeader, dst string) error {
+	src, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return writeFile(dst, src)
+}
+
+// writeFile copies src to dst, creating or truncating it with mode 0o600.
+func writeFile(dst string, src io.Reader) error {
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// scanSaved scans a file saved in async mode and quarantines it on detection.
+func (sc *UploadScanner) scanSaved(ctx context.Context, filename, path string) {
+	sc.scanStored(ctx, filename, path,
+		func() (io.ReadCloser, error) { return os.Open(path) },
+		func(dst string) error { return os.Rename(path, dst) },
+		func() error { return os.Remove(path) })
+}
+
+// scanSavedFS is scanSaved for files written to a storage.FS.
+func (sc *UploadScanner) scanSavedFS(ctx context.Context, filename string, fsys storage.FS, name string) {
+	sc.scanStored(ctx, filename, name,
+		func() (io.ReadCloser, error) { return fsys.Open(name) },
+		func(dst string) error {
+			f, err := fsys.Open(name)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if err := writeFile(dst, f); err != nil {
+				return err
+			}
+			return fsys.Remove(ctx, name)
+		},
+		func() error { return fsys.Remove(ctx, name) })
+}
+
+// scanStored scans a saved file through open and, on detection, moves it into
+// QuarantineDir with quarantine or deletes it with remove.
+func (sc *UploadScanner) scanStored(ctx context.Context, filename, path string, open func() (io.ReadCloser, error), quarantine func(dst string) error, remove func() error) {
+	f, err := open()
+	if err != nil {
+		sc.scanFailed(ctx, path, err)
+		return
+	}
+	err = sc.Scan(f)
+	f.Close()
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, ErrUploadInfected) {
+		sc.scanFailed(ctx, path, err)
+		return
+	}
+	d := UploadDetection{Filename: filename, Path: path, Err: err}
+	if sc.QuarantineDir != "" {
+		q := filepath.Join(sc.QuarantineDir, strconv.FormatInt(time.Now().UnixNano(), 10)+"-"+filepath.Base(filepath.FromSlash(path)))
+		if merr := quarantine(q); merr != nil {
+			sc.scanFailed(ctx, path, fmt.Errorf("quarantine: %w", merr))
+			_ = remove()
+		} else {
+			d.Quarantine = q
+		}
+	} else {
+		_ = remove()
+	}
+	if sc.OnDetect != nil {
+		sc.OnDetect(ctx, d)
+	}
+}
+
+func (sc *UploadScanner) scanFailed(ctx context.Context, path string, err error) {
+	if sc.OnError != nil {
+		sc.OnError(ctx, path, err)
+	}
+}
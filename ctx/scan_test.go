@@ -0,0 +1,247 @@
+package ctx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/goflash/flash/v2/storage"
+)
+
+var eicar = []byte("EICAR-TEST")
+
+// fakeScan flags content containing the EICAR marker and counts calls.
+func fakeScan(calls *int) ScanFunc {
+	return func(r io.Reader) error {
+		*calls++
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if bytes.Contains(b, eicar) {
+			return fmt.Errorf("%w: Eicar-Signature", ErrUploadInfected)
+		}
+		return nil
+	}
+}
+
+func TestValidateUploadsScansFiles(t *testing.T) {
+	var calls int
+	policy := UploadPolicy{
+		Files:   map[string]FileRule{"docs": {MaxFiles: 2}, "big": {MaxSize: 1}},
+		Scanner: &UploadScanner{Scan: fakeScan(&calls)},
+	}
+	c := uploadCtx(t, nil,
+		part{"docs", "ok.txt", []byte("hello")},
+		part{"docs", "bad.txt", eicar},
+		part{"big", "big.txt", []byte("too large")},
+	)
+	errs := fieldErrs(t, c.ValidateUploads(policy))
+	if len(errs) != 2 || errs["docs[1]"].Code() != FieldCodeInfected || errs["big"].Code() != FieldCodeFileTooLarge {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	if errs["docs[1]"].Value() != "bad.txt" {
+		t.Fatalf("value = %v", errs["docs[1]"].Value())
+	}
+	if calls != 2 {
+		t.Fatalf("files failing the rules should not be scanned, calls = %d", calls)
+	}
+}
+
+func TestValidateUploadsScannerFailure(t *testing.T) {
+	boom := errors.New("clamd unreachable")
+	policy := UploadPolicy{
+		Files:   map[string]FileRule{"doc": {}},
+		Scanner: &UploadScanner{Scan: func(io.Reader) error { return boom }},
+	}
+	c := uploadCtx(t, nil, part{"doc", "a.txt", []byte("a")})
+	if err := c.ValidateUploads(policy); !errors.Is(err, boom) {
+		t.Fatalf("scanner failures must fail closed, got %v", err)
+	}
+}
+
+func TestSaveUploadedFileSync(t *testing.T) {
+	var calls int
+	policy := &UploadPolicy{
+		Files:   map[string]FileRule{"doc": {MaxFiles: -1}},
+		Scanner: &UploadScanner{Scan: fakeScan(&calls)},
+	}
+	c := uploadCtx(t, nil, part{"doc", "ok.txt", []byte("hello")}, part{"doc", "bad.txt", eicar})
+	c.SetBindDefaults(&BindJSONOptions{Uploads: policy})
+	var in struct{}
+	if err := c.BindForm(&in); err == nil {
+		t.Fatalf("expected infected file to fail binding")
+	}
+	dir := t.TempDir()
+	fhs := c.Request().MultipartForm.File["doc"]
+
+	ok := filepath.Join(dir, "ok")
+	if err := c.SaveUploadedFile(fhs[0], ok); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(ok); string(b) != "hello" {
+		t.Fatalf("saved %q", b)
+	}
+	if calls != 2 {
+		t.Fatalf("file scanned during binding should not be rescanned, calls = %d", calls)
+	}
+
+	bad := filepath.Join(dir, "bad")
+	err := c.SaveUploadedFile(fhs[1], bad)
+	var he *HTTPError
+	if !errors.As(err, &he) || he.Code != 422 || !errors.Is(err, ErrUploadInfected) {
+		t.Fatalf("expected 422 wrapping ErrUploadInfected, got %v", err)
+	}
+	if _, err := os.Stat(bad); !os.IsNotExist(err) {
+		t.Fatalf("infected file must not be written")
+	}
+}
+
+func TestSaveUploadedFileWithoutScanner(t *testing.T) {
+	c := uploadCtx(t, nil, part{"doc", "a.txt", eicar})
+	if err := c.ValidateUploads(UploadPolicy{Files: map[string]FileRule{"doc": {}}}); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(t.TempDir(), "a")
+	if err := c.SaveUploadedFile(c.Request().MultipartForm.File["doc"][0], dst); err != nil {
+		t.Fatal(err)
+	}
+	if hooks := c.TakeResponseHooks(nil); hooks != nil {
+		t.Fatalf("no hooks expected")
+	}
+}
+
+func TestSaveUploadedFileAsyncQuarantine(t *testing.T) {
+	var calls int
+	var got []UploadDetection
+	quarantine := t.TempDir()
+	policy := &UploadPolicy{
+		Files: map[string]FileRule{"doc": {MaxFiles: -1}},
+		Scanner: &UploadScanner{
+			Scan:          fakeScan(&calls),
+			Async:         true,
+			QuarantineDir: quarantine,
+			OnDetect:      func(_ context.Context, d UploadDetection) { got = append(got, d) },
+		},
+	}
+	c := uploadCtx(t, nil, part{"doc", "ok.txt", []byte("hello")}, part{"doc", "bad.txt", eicar})
+	c.SetBindDefaults(&BindJSONOptions{Uploads: policy})
+	var in struct{}
+	if err := c.BindForm(&in); err != nil {
+		t.Fatalf("async mode should not scan during binding: %v", err)
+	}
+	dir := t.TempDir()
+	fhs := c.Request().MultipartForm.File["doc"]
+	ok, bad := filepath.Join(dir, "ok"), filepath.Join(dir, "bad")
+	for i, dst := range []string{ok, bad} {
+		if err := c.SaveUploadedFile(fhs[i], dst); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 0 {
+		t.Fatalf("scan ran before the response, calls = %d", calls)
+	}
+	for _, h := range c.TakeResponseHooks(nil) {
+		h(context.Background())
+	}
+	if calls != 2 {
+		t.Fatalf("calls = %d", calls)
+	}
+	if _, err := os.Stat(ok); err != nil {
+		t.Fatalf("clean file should stay: %v", err)
+	}
+	if _, err := os.Stat(bad); !os.IsNotExist(err) {
+		t.Fatalf("infected file should be moved")
+	}
+	if len(got) != 1 || got[0].Filename != "bad.txt" || got[0].Path != bad || !errors.Is(got[0].Err, ErrUploadInfected) {
+		t.Fatalf("detections = %+v", got)
+	}
+	if b, err := os.ReadFile(got[0].Quarantine); err != nil || !bytes.Equal(b, eicar) || filepath.Dir(got[0].Quarantine) != quarantine {
+		t.Fatalf("quarantined file %q: %v", got[0].Quarantine, err)
+	}
+}
+
+func TestSaveUploadedFileAsyncRemoveAndError(t *testing.T) {
+	var detected, failed int
+	sc := &UploadScanner{
+		Async:    true,
+		OnDetect: func(_ context.Context, d UploadDetection) { detected++ },
+		OnError:  func(context.Context, string, error) { failed++ },
+	}
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad")
+	_ = os.WriteFile(bad, eicar, 0o600)
+
+	var calls int
+	sc.Scan = fakeScan(&calls)
+	sc.scanSaved(context.Background(), "bad.txt", bad)
+	if _, err := os.Stat(bad); !os.IsNotExist(err) || detected != 1 {
+		t.Fatalf("infected file should be removed without a quarantine dir")
+	}
+
+	kept := filepath.Join(dir, "kept")
+	_ = os.WriteFile(kept, []byte("x"), 0o600)
+	sc.Scan = func(io.Reader) error { return errors.New("icap timeout") }
+	sc.scanSaved(context.Background(), "kept.txt", kept)
+	if _, err := os.Stat(kept); err != nil || failed != 1 {
+		t.Fatalf("scanner failure should keep the file and call OnError")
+	}
+}
+
+func TestSaveUploadedFileFS(t *testing.T) {
+	var calls int
+	var got []UploadDetection
+	quarantine := t.TempDir()
+	policy := &UploadPolicy{
+		Files: map[string]FileRule{"doc": {MaxFiles: -1}},
+		Scanner: &UploadScanner{
+			Scan:          fakeScan(&calls),
+			Async:         true,
+			QuarantineDir: quarantine,
+			OnDetect:      func(_ context.Context, d UploadDetection) { got = append(got, d) },
+		},
+	}
+	c := uploadCtx(t, nil, part{"doc", "ok.txt", []byte("hello")}, part{"doc", "bad.txt", eicar})
+	c.SetBindDefaults(&BindJSONOptions{Uploads: policy})
+	var in struct{}
+	if err := c.BindForm(&in); err != nil {
+		t.Fatal(err)
+	}
+	store := storage.Dir(t.TempDir())
+	fhs := c.Request().MultipartForm.File["doc"]
+	for i, name := range []string{"u/ok", "u/bad"} {
+		if err := c.SaveUploadedFileFS(fhs[i], store, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, h := range c.TakeResponseHooks(nil) {
+		h(context.Background())
+	}
+	if b, err := fs.ReadFile(store, "u/ok"); err != nil || string(b) != "hello" {
+		t.Fatalf("clean file = %q %v", b, err)
+	}
+	if _, err := store.Stat("u/bad"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("infected file should be removed from the store")
+	}
+	if len(got) != 1 || got[0].Path != "u/bad" || filepath.Dir(got[0].Quarantine) != quarantine {
+		t.Fatalf("detections = %+v", got)
+	}
+	if b, err := os.ReadFile(got[0].Quarantine); err != nil || !bytes.Equal(b, eicar) {
+		t.Fatalf("quarantined file: %q %v", b, err)
+	}
+
+	policy.Scanner.Async = false
+	err := c.SaveUploadedFileFS(fhs[1], store, "u/bad2")
+	if !errors.Is(err, ErrUploadInfected) {
+		t.Fatalf("sync mode should reject infected uploads, got %v", err)
+	}
+	if _, err := store.Stat("u/bad2"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("infected file must not be written")
+	}
+}
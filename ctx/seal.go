@@ -0,0 +1,320 @@
+package ctx
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FieldCodeSealed marks a sealed field whose value could not be opened.
+const FieldCodeSealed = "sealed_invalid"
+
+// ErrSealedInvalid is wrapped by KeyProvider errors for values that are
+// malformed, tampered with or sealed under an unknown key. Binding reports
+// them as FieldErrors with code FieldCodeSealed; any other provider error
+// (e.g. an unreachable KMS) is returned as is.
+var ErrSealedInvalid = errors.New("sealed value invalid")
+
+// KeyProvider opens values sealed by a client or an upstream service, e.g. by
+// calling a KMS Decrypt API or using a local key.
+type KeyProvider interface {
+	// Open authenticates and decrypts sealed, returning the plaintext.
+	Open(ctx context.Context, sealed string) ([]byte, error)
+}
+
+// KeyProviderFunc adapts a function to KeyProvider.
+type KeyProviderFunc func(ctx context.Context, sealed string) ([]byte, error)
+
+// Open calls f.
+func (f KeyProviderFunc) Open(ctx context.Context, sealed string) ([]byte, error) {
+	return f(ctx, sealed)
+}
+
+var keyProviders sync.Map // seal tag name -> KeyProvider
+
+// RegisterKeyProvider registers p for struct fields tagged `seal:"<name>"`.
+// Registering a name again replaces its provider; a nil p removes it.
+// BindJSONOptions.KeyProviders overrides the registry per app, group or call.
+//
+// During binding (BindJSON, BindMap, BindForm, BindQuery, BindPath, BindAny),
+// the input value of every sealed field must be a string; it is opened with
+// the field's provider before decoding. String fields receive the plaintext
+// and []byte fields the raw bytes; other fields decode the plaintext as JSON,
+// so a sealed value may hold a number, object or array. Sealed fields can sit
+// in nested structs and slices of structs.
+//
+// Example:
+//
+//	ctx.RegisterKeyProvider("kms", ctx.KeyProviderFunc(func(ctx context.Context, sealed string) ([]byte, error) {
+//		out, err := kmsClient.Decrypt(ctx, sealed)
+//		if errors.Is(err, kms.ErrInvalidCiphertext) {
+//			return nil, fmt.Errorf("%w: %v", ctx.ErrSealedInvalid, err)
+//		}
+//		return out, err
+//	}))
+//
+//	type Payment struct {
+//		Amount int    `json:"amount"`
+//		Card   string `json:"card" seal:"kms"` // plaintext PAN after binding
+//	}
+func RegisterKeyProvider(name string, p KeyProvider) {
+	if p == nil {
+		keyProviders.Delete(name)
+		return
+	}
+	keyProviders.Store(name, p)
+}
+
+// keyProvider resolves the provider for a seal tag name.
+func (o BindJSONOptions) keyProvider(name string) (KeyProvider, error) {
+	if p, ok := o.KeyProviders[name]; ok && p != nil {
+		return p, nil
+	}
+	if p, ok := keyProviders.Load(name); ok {
+		return p.(KeyProvider), nil
+	}
+	return nil, fmt.Errorf("ctx: no KeyProvider registered for seal %q", name)
+}
+
+// AESGCMKeyProvider opens values sealed with AES-GCM under one of Keys (16,
+// 24 or 32 bytes). Sealed values have the form "<key id>.<base64url(nonce ||
+// ciphertext)>", so keys can be rotated by adding a new ID and keeping the
+// old one until no value sealed under it remains.
+//
+// Example:
+//
+//	p := ctx.AESGCMKeyProvider{Keys: map[string][]byte{"k2": newKey, "k1": oldKey}}
+//	ctx.RegisterKeyProvider("local", p)
+//	token, _ := p.Seal("k2", []byte("4111111111111111")) // on the sending side
+type AESGCMKeyProvider struct {
+	Keys map[string][]byte
+}
+
+// Open implements KeyProvider.
+func (p AESGCMKeyProvider) Open(_ context.Context, sealed string) ([]byte, error) {
+	id, data, ok := strings.Cut(sealed, ".")
+	if !ok {
+		return nil, ErrSealedInvalid
+	}
+	aead, err := p.aead(id)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil || len(raw) < aead.NonceSize() {
+		return nil, ErrSealedInvalid
+	}
+	plain, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return nil, ErrSealedInvalid
+	}
+	return plain, nil
+}
+
+// Seal encrypts plaintext under the key keyID.
+func (p AESGCMKeyProvider) Seal(keyID string, plaintext []byte) (string, error) {
+	aead, err := p.aead(keyID)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return keyID + "." + base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, plaintext, []byte(keyID))), nil
+}
+
+func (p AESGCMKeyProvider) aead(id string) (cipher.AEAD, error) {
+	key, ok := p.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrSealedInvalid, id)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealField is a struct field that is sealed itself (provider set) or
+// contains sealed fields (nested set).
+type sealField struct {
+	name     string       // json key
+	provider string       // seal tag value
+	target   reflect.Type // field type without pointers
+	slice    bool         // nested applies to each element
+	nested   *sealPlan
+}
+
+type sealPlan struct{ fields []sealField }
+
+var sealPlans sync.Map // reflect.Type -> *sealPlan (nil: no sealed fields)
+
+// sealPlanFor returns the sealed fields of struct type t, or nil.
+func sealPlanFor(t reflect.Type) *sealPlan {
+	if p, ok := sealPlans.Load(t); ok {
+		return p.(*sealPlan)
+	}
+	p := buildSealPlan(t, map[reflect.Type]bool{})
+	sealPlans.Store(t, p)
+	return p
+}
+
+func buildSealPlan(t reflect.Type, seen map[reflect.Type]bool) *sealPlan {
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	var p sealPlan
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		ft := derefType(f.Type)
+		if tag := f.Tag.Get("seal"); tag != "" {
+			p.fields = append(p.fields, sealField{name: name, provider: tag, target: ft})
+			continue
+		}
+		slice := false
+		if ft.Kind() == reflect.Slice || ft.Kind() == reflect.Array {
+			ft, slice = derefType(ft.Elem()), true
+		}
+		if ft.Kind() == reflect.Struct && ft != timeType {
+			if np := buildSealPlan(ft, seen); np != nil {
+				p.fields = append(p.fields, sealField{name: name, target: ft, slice: slice, nested: np})
+			}
+		}
+	}
+	if len(p.fields) == 0 {
+		return nil
+	}
+	return &p
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// unseal returns a copy of m with the sealed fields of p opened. Values that
+// cannot be opened are removed and reported in errs.
+func (c *DefaultContext) unseal(p *sealPlan, m map[string]any, o BindJSONOptions, prefix string, errs fieldErrorsMap) (map[string]any, error) {
+	out := maps.Clone(m)
+	for _, f := range p.fields {
+		key, v, ok := lookupField(m, f.name)
+		if !ok || v == nil {
+			continue
+		}
+		path := f.name
+		if prefix != "" {
+			path = prefix + "." + f.name
+		}
+		switch {
+		case f.nested == nil:
+			val, err := c.openSealed(f, v, o)
+			if errors.Is(err, ErrSealedInvalid) {
+				errs.m[path] = "cannot be unsealed"
+				errs.codes[path] = FieldCodeSealed
+				delete(out, key)
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			out[key] = val
+		case !f.slice:
+			if nm, ok := v.(map[string]any); ok {
+				res, err := c.unseal(f.nested, nm, o, path, errs)
+				if err != nil {
+					return nil, err
+				}
+				out[key] = res
+			}
+		default:
+			if items, ok := v.([]any); ok {
+				res := make([]any, len(items))
+				for i, item := range items {
+					res[i] = item
+					if nm, ok := item.(map[string]any); ok {
+						r, err := c.unseal(f.nested, nm, o, path+"["+strconv.Itoa(i)+"]", errs)
+						if err != nil {
+							return nil, err
+						}
+						res[i] = r
+					}
+				}
+				out[key] = res
+			}
+		}
+	}
+	return out, nil
+}
+
+// openSealed opens one sealed value and converts the plaintext for the
+// field's type.
+func (c *DefaultContext) openSealed(f sealField, v any, o BindJSONOptions) (any, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, ErrSealedInvalid
+	}
+	kp, err := o.keyProvider(f.provider)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := kp.Open(c.Context(), s)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case f.target.Kind() == reflect.String:
+		return string(plain), nil
+	case f.target.Kind() == reflect.Slice && f.target.Elem().Kind() == reflect.Uint8:
+		return plain, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(plain))
+	if o.UseNumber {
+		dec.UseNumber()
+	}
+	var out any
+	if err := dec.Decode(&out); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSealedInvalid, err)
+	}
+	return out, nil
+}
+
+// lookupField finds name in m, falling back to a case-insensitive match like
+// mapstructure does.
+func lookupField(m map[string]any, name string) (string, any, bool) {
+	if v, ok := m[name]; ok {
+		return name, v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, name) {
+			return k, v, true
+		}
+	}
+	return "", nil, false
+}
@@ -0,0 +1,150 @@
+package ctx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+var testSealer = AESGCMKeyProvider{Keys: map[string][]byte{
+	"k1": bytes.Repeat([]byte{1}, 32),
+	"k2": bytes.Repeat([]byte{2}, 16),
+}}
+
+func seal(t *testing.T, keyID, plain string) string {
+	t.Helper()
+	s, err := testSealer.Seal(keyID, []byte(plain))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func sealCtx(body string, o *BindJSONOptions) *DefaultContext {
+	req, rec := newRequest(http.MethodPost, "/", strings.NewReader(body))
+	var c DefaultContext
+	c.Reset(rec, req, nil, "/")
+	c.SetBindDefaults(o)
+	return &c
+}
+
+type sealedCard struct {
+	Last4 string `json:"last4"`
+	PAN   string `json:"pan" seal:"local"`
+}
+
+type sealedOrder struct {
+	ID     string       `json:"id"`
+	Amount int          `json:"amount" seal:"local"`
+	Raw    []byte       `json:"raw" seal:"local"`
+	Card   *sealedCard  `json:"card"`
+	Extra  []sealedCard `json:"extra"`
+}
+
+func TestBindJSONOpensSealedFields(t *testing.T) {
+	body := `{"id":"o1","amount":"` + seal(t, "k1", "1250") + `","raw":"` + seal(t, "k2", "\x00bin") + `",` +
+		`"card":{"last4":"1111","pan":"` + seal(t, "k1", "4111111111111111") + `"},` +
+		`"extra":[{"last4":"2222","pan":"` + seal(t, "k2", "5555555555552222") + `"}]}`
+	c := sealCtx(body, &BindJSONOptions{ErrorUnused: true, KeyProviders: map[string]KeyProvider{"local": testSealer}})
+	var in sealedOrder
+	if err := c.BindJSON(&in); err != nil {
+		t.Fatal(err)
+	}
+	if in.Amount != 1250 || string(in.Raw) != "\x00bin" || in.Card.PAN != "4111111111111111" || in.Extra[0].PAN != "5555555555552222" {
+		t.Fatalf("unexpected %+v card %+v", in, in.Card)
+	}
+}
+
+func TestBindJSONSealedFieldErrors(t *testing.T) {
+	tampered := seal(t, "k1", "4111111111111111")
+	tampered = tampered[:len(tampered)-2] + "AA"
+	body := `{"id":"o1","amount":"` + seal(t, "k1", `"not a number"`) + `","card":{"pan":"` + tampered + `"},` +
+		`"extra":[{"pan":42},{"pan":"k9.AAAA"}]}`
+	c := sealCtx(body, &BindJSONOptions{ErrorUnused: true, KeyProviders: map[string]KeyProvider{"local": testSealer}})
+	var in sealedOrder
+	err := c.BindJSON(&in)
+	fe, ok := err.(FieldErrors)
+	if !ok {
+		t.Fatalf("expected FieldErrors, got %v", err)
+	}
+	got := map[string]string{}
+	for _, e := range fieldErrorDetails(t, fe) {
+		got[e.Field()] = e.Code()
+		if s, ok := e.Value().(string); ok && strings.Contains(s, "not a number") {
+			t.Fatalf("plaintext leaked into error value: %v", e.Value())
+		}
+	}
+	want := map[string]string{
+		"amount":       FieldCodeTypeMismatch,
+		"card.pan":     FieldCodeSealed,
+		"extra[0].pan": FieldCodeSealed,
+		"extra[1].pan": FieldCodeSealed,
+	}
+	for k, code := range want {
+		if got[k] != code {
+			t.Fatalf("%s: code %q, all %v", k, got[k], got)
+		}
+	}
+}
+
+func TestBindSealedUsesRegistryAndProviderErrors(t *testing.T) {
+	outage := errors.New("kms unavailable")
+	RegisterKeyProvider("test-kms", KeyProviderFunc(func(_ context.Context, s string) ([]byte, error) {
+		if s == "down" {
+			return nil, outage
+		}
+		return []byte(strings.ToUpper(s)), nil
+	}))
+	defer RegisterKeyProvider("test-kms", nil)
+
+	type In struct {
+		Secret string `json:"secret" seal:"test-kms"`
+	}
+	var in In
+	if err := sealCtx(`{"secret":"abc"}`, nil).BindJSON(&in); err != nil || in.Secret != "ABC" {
+		t.Fatalf("registry provider: %q %v", in.Secret, err)
+	}
+	if err := sealCtx(`{"secret":"down"}`, nil).BindJSON(&in); !errors.Is(err, outage) {
+		t.Fatalf("provider failures must be returned, got %v", err)
+	}
+
+	type Missing struct {
+		Secret string `json:"secret" seal:"nope"`
+	}
+	var m Missing
+	if err := sealCtx(`{"secret":"x"}`, nil).BindJSON(&m); err == nil || !strings.Contains(err.Error(), `"nope"`) {
+		t.Fatalf("expected missing provider error, got %v", err)
+	}
+}
+
+func TestBindMapSealedCaseInsensitiveAndAbsent(t *testing.T) {
+	c := sealCtx("", &BindJSONOptions{KeyProviders: map[string]KeyProvider{"local": testSealer}})
+	var in sealedCard
+	if err := c.BindMap(&in, map[string]any{"PAN": seal(t, "k1", "4000")}); err != nil || in.PAN != "4000" {
+		t.Fatalf("got %q %v", in.PAN, err)
+	}
+	var empty sealedCard
+	if err := c.BindMap(&empty, map[string]any{"last4": "1"}); err != nil || empty.PAN != "" {
+		t.Fatalf("absent sealed field: %v", err)
+	}
+}
+
+func TestAESGCMKeyProvider(t *testing.T) {
+	s := seal(t, "k1", "hello")
+	if b, err := testSealer.Open(context.Background(), s); err != nil || string(b) != "hello" {
+		t.Fatalf("open = %q %v", b, err)
+	}
+	// The key ID is authenticated: relabeling a value under another key fails.
+	relabeled := "k2" + strings.TrimPrefix(s, "k1")
+	for _, bad := range []string{"", "k1", "k1.!!", "k1.AA", "zz." + strings.TrimPrefix(s, "k1."), relabeled} {
+		if _, err := testSealer.Open(context.Background(), bad); !errors.Is(err, ErrSealedInvalid) {
+			t.Fatalf("%q: expected ErrSealedInvalid, got %v", bad, err)
+		}
+	}
+	if _, err := testSealer.Seal("missing", nil); !errors.Is(err, ErrSealedInvalid) {
+		t.Fatalf("unknown key: %v", err)
+	}
+}
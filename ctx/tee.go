@@ -0,0 +1,73 @@
+package ctx
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+)
+
+// teeWriter copies the response body to sinks as it is written. One tee is
+// shared by every TeeBody call on a request.
+type teeWriter struct {
+	http.ResponseWriter
+	sinks []io.Writer
+}
+
+// TeeBody copies the response body written from now on to w as it is sent,
+// without buffering a second copy, for post-response processing such as
+// hashing, audit logging or filling a cache. Consumers calling TeeBody on the
+// same request share one writer wrapper. Only bytes the client accepted are
+// copied. A sink that returns an error stops receiving data; the response
+// itself is unaffected. Call it before the body is written; headers are not
+// copied.
+//
+// Example:
+//
+//	h := sha256.New()
+//	c.TeeBody(h)
+//	c.AfterResponse(func(context.Context) {
+//		audit.Record(c.Route(), hex.EncodeToString(h.Sum(nil)))
+//	})
+//	return c.JSON(report)
+func (c *DefaultContext) TeeBody(w io.Writer) {
+	if c.tee == nil || c.w != http.ResponseWriter(c.tee) {
+		c.tee = &teeWriter{ResponseWriter: c.w}
+		c.w = c.tee
+	}
+	c.tee.sinks = append(c.tee.sinks, w)
+}
+
+func (t *teeWriter) Write(p []byte) (int, error) {
+	n, err := t.ResponseWriter.Write(p)
+	if n > 0 {
+		live := t.sinks[:0]
+		for _, s := range t.sinks {
+			if _, werr := s.Write(p[:n]); werr == nil {
+				live = append(live, s)
+			}
+		}
+		clear(t.sinks[len(live):])
+		t.sinks = live
+	}
+	return n, err
+}
+
+// Flush implements http.Flusher when the underlying writer does.
+func (t *teeWriter) Flush() {
+	if f, ok := t.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker when the underlying writer does.
+func (t *teeWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := t.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("ctx: hijacking not supported")
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (t *teeWriter) Unwrap() http.ResponseWriter { return t.ResponseWriter }
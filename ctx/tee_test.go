@@ -0,0 +1,87 @@
+package ctx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type failingWriter struct{ n int }
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.n++
+	return 0, errors.New("sink down")
+}
+
+func TestTeeBody_SharedSinks(t *testing.T) {
+	rec := httptest.NewRecorder()
+	var c DefaultContext
+	c.Reset(rec, httptest.NewRequest(http.MethodGet, "/", nil), nil, "/")
+
+	var audit bytes.Buffer
+	h := sha256.New()
+	bad := &failingWriter{}
+	c.TeeBody(&audit)
+	c.TeeBody(h)
+	c.TeeBody(bad)
+	tee := c.tee
+	if c.ResponseWriter() != http.ResponseWriter(tee) {
+		t.Fatalf("writer not wrapped")
+	}
+
+	if err := c.String(http.StatusOK, "hello "); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ResponseWriter().Write([]byte("world")); err != nil {
+		t.Fatal(err)
+	}
+	if c.tee != tee {
+		t.Fatalf("second tee created")
+	}
+	if rec.Body.String() != "hello world" || audit.String() != "hello world" {
+		t.Fatalf("body=%q audit=%q", rec.Body.String(), audit.String())
+	}
+	want := sha256.Sum256([]byte("hello world"))
+	if !bytes.Equal(h.Sum(nil), want[:]) {
+		t.Fatalf("hash mismatch")
+	}
+	if bad.n != 1 {
+		t.Fatalf("failing sink written %d times", bad.n)
+	}
+}
+
+func TestTeeBody_AfterWriterReplaced(t *testing.T) {
+	rec := httptest.NewRecorder()
+	var c DefaultContext
+	c.Reset(rec, httptest.NewRequest(http.MethodGet, "/", nil), nil, "/")
+	var first, second bytes.Buffer
+	c.TeeBody(&first)
+	// Middleware restoring the writer it wrapped drops the tee.
+	c.SetResponseWriter(rec)
+	c.TeeBody(&second)
+	_ = c.String(http.StatusOK, "x")
+	if first.Len() != 0 || second.String() != "x" {
+		t.Fatalf("first=%q second=%q", first.String(), second.String())
+	}
+
+	c.Reset(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil, "/")
+	if c.tee != nil {
+		t.Fatalf("Reset kept the tee")
+	}
+}
+
+func TestTeeBody_ResponseController(t *testing.T) {
+	rec := httptest.NewRecorder()
+	var c DefaultContext
+	c.Reset(rec, httptest.NewRequest(http.MethodGet, "/", nil), nil, "/")
+	c.TeeBody(&bytes.Buffer{})
+	if err := http.NewResponseController(c.ResponseWriter()).Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if !rec.Flushed {
+		t.Fatalf("not flushed")
+	}
+}
@@ -0,0 +1,193 @@
+package ctx
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Sentinel causes for request-scoped temp file limits. Use errors.Is to detect them.
+var (
+	// ErrTooManyTempFiles is returned when a request exceeds TempLimits.MaxFiles.
+	ErrTooManyTempFiles = errors.New("too many temporary files")
+	// ErrTempQuotaExceeded is returned (as a 413 *HTTPError) when writes exceed TempLimits.MaxBytes.
+	ErrTempQuotaExceeded = errors.New("temporary file quota exceeded")
+)
+
+// TempLimits bounds the temporary files and directories a single request may
+// create via TempFile and TempDir. Zero values mean "no limit".
+//
+// Example:
+//
+//	a := flash.New(flash.WithTempLimits(ctx.TempLimits{MaxFiles: 4, MaxBytes: 64 << 20}))
+type TempLimits struct {
+	// Dir is the parent directory for temp artifacts. Defaults to os.TempDir().
+	Dir string
+	// MaxFiles caps the number of files and directories created per request.
+	MaxFiles int
+	// MaxBytes caps the total bytes written through TempFile handles per request.
+	// Files written directly inside a TempDir are not counted.
+	MaxBytes int64
+}
+
+// tempStore tracks the artifacts created during one request. It is allocated on
+// first use and shared with clones so everything is removed by Finish.
+type tempStore struct {
+	mu      sync.Mutex
+	limits  TempLimits
+	files   []*TempFile
+	dirs    []string
+	written int64
+}
+
+// reserve accounts for n more bytes, returning how many may be written.
+func (s *tempStore) reserve(n int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.limits.MaxBytes <= 0 {
+		s.written += n
+		return n, nil
+	}
+	left := s.limits.MaxBytes - s.written
+	if left < n {
+		if left < 0 {
+			left = 0
+		}
+		s.written += left
+		return left, NewError(http.StatusRequestEntityTooLarge, ErrTempQuotaExceeded.Error()).WithErr(ErrTempQuotaExceeded)
+	}
+	s.written += n
+	return n, nil
+}
+
+// admit checks the artifact count limit.
+func (s *tempStore) admit() error {
+	if s.limits.MaxFiles > 0 && len(s.files)+len(s.dirs) >= s.limits.MaxFiles {
+		return ErrTooManyTempFiles
+	}
+	return nil
+}
+
+// cleanup closes and removes every tracked artifact.
+func (s *tempStore) cleanup() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f := range s.files {
+		_ = f.File.Close()
+		_ = os.Remove(f.Name())
+	}
+	for _, d := range s.dirs {
+		_ = os.RemoveAll(d)
+	}
+	s.files, s.dirs = nil, nil
+}
+
+// TempFile is a request-scoped temporary file created by Ctx.TempFile. It embeds
+// *os.File, so it can be read, seeked and passed wherever an *os.File's methods
+// are needed; writes are metered against TempLimits.MaxBytes. The file is closed
+// and removed when the request finishes; closing it earlier is allowed.
+type TempFile struct {
+	*os.File
+	store *tempStore
+}
+
+// Write writes p, failing with ErrTempQuotaExceeded once the byte quota is used up.
+func (f *TempFile) Write(p []byte) (int, error) {
+	n, qerr := f.store.reserve(int64(len(p)))
+	w, err := f.File.Write(p[:n])
+	if err == nil {
+		err = qerr
+	}
+	return w, err
+}
+
+// WriteString is like Write but writes a string.
+func (f *TempFile) WriteString(s string) (int, error) { return f.Write([]byte(s)) }
+
+// WriteAt writes p at offset off, metered like Write.
+func (f *TempFile) WriteAt(p []byte, off int64) (int, error) {
+	n, qerr := f.store.reserve(int64(len(p)))
+	w, err := f.File.WriteAt(p[:n], off)
+	if err == nil {
+		err = qerr
+	}
+	return w, err
+}
+
+// ReadFrom copies r into the file through Write so io.Copy honors the quota.
+func (f *TempFile) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{f}, r)
+}
+
+// SetTempLimits sets the temp file limits for this request. Used internally by
+// the app to apply WithTempLimits; handlers rarely need it.
+func (c *DefaultContext) SetTempLimits(l *TempLimits) { c.tempLimits = l }
+
+// temps returns the request's temp store, creating it on first use.
+func (c *DefaultContext) temps() *tempStore {
+	if c.tmp == nil {
+		c.tmp = &tempStore{}
+		if c.tempLimits != nil {
+			c.tmp.limits = *c.tempLimits
+		}
+	}
+	return c.tmp
+}
+
+// TempFile creates a new temporary file (see os.CreateTemp for pattern) that is
+// closed and removed automatically when the request finishes, including on
+// error and panic paths handled by the app.
+//
+// Example (spooling an upload):
+//
+//	f, err := c.TempFile("upload-*.bin")
+//	if err != nil {
+//		return err
+//	}
+//	if _, err := io.Copy(f, c.Request().Body); err != nil {
+//		return err // 413 *HTTPError when the quota is exceeded
+//	}
+//	_, _ = f.Seek(0, io.SeekStart)
+//	return process(f)
+func (c *DefaultContext) TempFile(pattern string) (*TempFile, error) {
+	s := c.temps()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.admit(); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(s.limits.Dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	tf := &TempFile{File: f, store: s}
+	s.files = append(s.files, tf)
+	return tf, nil
+}
+
+// TempDir creates a new temporary directory that is removed, with its contents,
+// when the request finishes. Each call returns a distinct directory.
+//
+// Example:
+//
+//	dir, err := c.TempDir()
+//	if err != nil {
+//		return err
+//	}
+//	return unzip(c.Request().Body, dir)
+func (c *DefaultContext) TempDir() (string, error) {
+	s := c.temps()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.admit(); err != nil {
+		return "", err
+	}
+	d, err := os.MkdirTemp(s.limits.Dir, "flash-")
+	if err != nil {
+		return "", err
+	}
+	s.dirs = append(s.dirs, d)
+	return d, nil
+}
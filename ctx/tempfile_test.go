@@ -0,0 +1,111 @@
+package ctx
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTempCtx(t *testing.T, l *TempLimits) *DefaultContext {
+	t.Helper()
+	req, rec := newRequest(http.MethodPost, "/", nil)
+	c := &DefaultContext{}
+	c.Reset(rec, req, nil, "/")
+	if l != nil {
+		l.Dir = t.TempDir()
+	} else {
+		l = &TempLimits{Dir: t.TempDir()}
+	}
+	c.SetTempLimits(l)
+	return c
+}
+
+func TestTempFileAndDirRemovedOnFinish(t *testing.T) {
+	c := newTempCtx(t, nil)
+	f, err := c.TempFile("up-*.txt")
+	if err != nil {
+		t.Fatalf("TempFile: %v", err)
+	}
+	if _, err := f.WriteString("hello"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if !strings.HasPrefix(filepath.Base(f.Name()), "up-") {
+		t.Fatalf("pattern not honored: %s", f.Name())
+	}
+	dir, err := c.TempDir()
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "x"), []byte("x"), 0o600); err != nil {
+		t.Fatalf("write in dir: %v", err)
+	}
+	_ = f.Close() // closing early is allowed
+
+	c.Finish()
+	for _, p := range []string{f.Name(), dir} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Fatalf("%s not removed: %v", p, err)
+		}
+	}
+	c.Finish() // idempotent
+}
+
+func TestTempFileLimits(t *testing.T) {
+	c := newTempCtx(t, &TempLimits{MaxFiles: 2, MaxBytes: 8})
+	f, err := c.TempFile("")
+	if err != nil {
+		t.Fatalf("TempFile: %v", err)
+	}
+	if _, err := c.TempDir(); err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	if _, err := c.TempFile(""); !errors.Is(err, ErrTooManyTempFiles) {
+		t.Fatalf("expected ErrTooManyTempFiles, got %v", err)
+	}
+	if _, err := c.TempDir(); !errors.Is(err, ErrTooManyTempFiles) {
+		t.Fatalf("expected ErrTooManyTempFiles, got %v", err)
+	}
+
+	n, err := io.Copy(f, strings.NewReader("0123456789"))
+	var he *HTTPError
+	if n != 8 || !errors.Is(err, ErrTempQuotaExceeded) || !errors.As(err, &he) || he.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("unexpected copy result: n=%d err=%v", n, err)
+	}
+	if w, err := f.WriteAt([]byte("z"), 0); w != 0 || !errors.Is(err, ErrTempQuotaExceeded) {
+		t.Fatalf("WriteAt should be metered: %d %v", w, err)
+	}
+	b, _ := os.ReadFile(f.Name())
+	if string(b) != "01234567" {
+		t.Fatalf("unexpected content %q", b)
+	}
+	c.Finish()
+}
+
+func TestTempFileCloneSharesStore(t *testing.T) {
+	c := newTempCtx(t, nil)
+	cl := c.Clone()
+	f, err := cl.TempFile("")
+	if err != nil {
+		t.Fatalf("TempFile: %v", err)
+	}
+	c.Finish()
+	if _, err := os.Stat(f.Name()); !os.IsNotExist(err) {
+		t.Fatalf("clone's temp file not removed: %v", err)
+	}
+}
+
+func TestTempFileCreateError(t *testing.T) {
+	c := newTempCtx(t, nil)
+	s := c.temps()
+	s.limits.Dir = filepath.Join(s.limits.Dir, "missing")
+	if _, err := c.TempFile(""); err == nil {
+		t.Fatalf("expected error for missing dir")
+	}
+	if _, err := c.TempDir(); err == nil {
+		t.Fatalf("expected error for missing dir")
+	}
+}
@@ -0,0 +1,134 @@
+package ctx
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TraceEvent is one entry of a request's trace timeline.
+type TraceEvent struct {
+	Name     string        // e.g. "db.query", "cache.get"
+	Start    time.Time     // when the traced operation started
+	Duration time.Duration // how long it took
+	Attrs    []any         // slog-style key/value pairs
+}
+
+// TraceHook receives every event traced in a context, e.g. to turn it into a
+// span. Tracing middleware install one with ContextWithTraceHook.
+type TraceHook func(ctx context.Context, ev TraceEvent)
+
+// DefaultTraceEvents is the number of events a timeline keeps by default;
+// later events are counted as dropped.
+const DefaultTraceEvents = 256
+
+type (
+	timelineKey  struct{}
+	traceHookKey struct{}
+)
+
+// timeline collects the events of one request. It is locked because
+// handlers may trace from several goroutines.
+type timeline struct {
+	mu      sync.Mutex
+	events  []TraceEvent
+	max     int
+	dropped int
+}
+
+// WithTraceTimeline returns a copy of parent that collects the events traced
+// with TraceContext and Ctx.Trace, keeping at most maxEvents (<= 0 means
+// DefaultTraceEvents). Apps attach one to every request with
+// app.WithRequestTrace and in development mode.
+func WithTraceTimeline(parent context.Context, maxEvents int) context.Context {
+	if maxEvents <= 0 {
+		maxEvents = DefaultTraceEvents
+	}
+	return context.WithValue(parent, timelineKey{}, &timeline{max: maxEvents})
+}
+
+// ContextWithTraceHook returns a copy of parent whose traced events are also
+// passed to hook. This is how tracing middleware bridge the built-in timeline
+// to OpenTelemetry or another tracer.
+//
+// Example (OpenTelemetry):
+//
+//	rc := ctx.ContextWithTraceHook(c.Context(), func(cx context.Context, ev ctx.TraceEvent) {
+//		_, span := tracer.Start(cx, ev.Name, trace.WithTimestamp(ev.Start))
+//		span.End(trace.WithTimestamp(ev.Start.Add(ev.Duration)))
+//	})
+//	c.SetRequest(c.Request().WithContext(rc))
+func ContextWithTraceHook(parent context.Context, hook TraceHook) context.Context {
+	return context.WithValue(parent, traceHookKey{}, hook)
+}
+
+// TraceContext records that the operation name finished after taking d, in
+// the timeline and trace hook carried by ctx. Without either it does nothing,
+// so libraries can trace unconditionally. attrs are slog-style key/value
+// pairs.
+//
+// Example:
+//
+//	start := time.Now()
+//	rows, err := db.QueryContext(ctx, q)
+//	ctx.TraceContext(ctx, "db.query", time.Since(start), "table", "orders")
+func TraceContext(ctx context.Context, name string, d time.Duration, attrs ...any) {
+	tl, _ := ctx.Value(timelineKey{}).(*timeline)
+	hook, _ := ctx.Value(traceHookKey{}).(TraceHook)
+	if tl == nil && hook == nil {
+		return
+	}
+	ev := TraceEvent{Name: name, Start: time.Now().Add(-d), Duration: d, Attrs: attrs}
+	if tl != nil {
+		tl.add(ev)
+	}
+	if hook != nil {
+		hook(ctx, ev)
+	}
+}
+
+// TraceEventsFromContext returns the events traced so far in ctx's timeline,
+// in the order they were recorded, and how many were dropped over the limit.
+func TraceEventsFromContext(ctx context.Context) (events []TraceEvent, dropped int) {
+	tl, _ := ctx.Value(timelineKey{}).(*timeline)
+	if tl == nil {
+		return nil, 0
+	}
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	return append([]TraceEvent(nil), tl.events...), tl.dropped
+}
+
+func (t *timeline) add(ev TraceEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.events) >= t.max {
+		t.dropped++
+		return
+	}
+	t.events = append(t.events, ev)
+}
+
+// Trace records that the operation name finished after taking d in the
+// request's trace timeline, without OpenTelemetry. The timeline is shown on
+// development error pages and, with app.WithRequestTrace, in the
+// Server-Timing header and the debug log; a trace hook installed by tracing
+// middleware also receives the event. Without a timeline or hook the event
+// is dropped and nothing is allocated, so handlers can trace unconditionally.
+// attrs are slog-style key/value pairs. Code that only has the request
+// context can use TraceContext.
+//
+// Example:
+//
+//	start := time.Now()
+//	user, err := users.Find(c.Context(), id)
+//	c.Trace("db.users.find", time.Since(start), "id", id)
+func (c *DefaultContext) Trace(name string, d time.Duration, attrs ...any) {
+	TraceContext(c.r.Context(), name, d, attrs...)
+}
+
+// TraceEvents returns the events recorded with Trace so far.
+func (c *DefaultContext) TraceEvents() []TraceEvent {
+	events, _ := TraceEventsFromContext(c.r.Context())
+	return events
+}
@@ -0,0 +1,86 @@
+package ctx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTrace_Timeline(t *testing.T) {
+	var c DefaultContext
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Reset(httptest.NewRecorder(), r.WithContext(WithTraceTimeline(r.Context(), 0)), nil, "/")
+	if c.TraceEvents() != nil {
+		t.Fatalf("events before any Trace")
+	}
+	c.Trace("db.query", 5*time.Millisecond, "table", "orders")
+	// Code holding only the request context records into the same timeline.
+	TraceContext(c.Context(), "cache.get", time.Millisecond)
+
+	events := c.TraceEvents()
+	if len(events) != 2 || events[0].Name != "db.query" || events[1].Name != "cache.get" {
+		t.Fatalf("events=%+v", events)
+	}
+	ev := events[0]
+	if ev.Duration != 5*time.Millisecond || len(ev.Attrs) != 2 || ev.Attrs[1] != "orders" {
+		t.Fatalf("event=%+v", ev)
+	}
+	if since := time.Since(ev.Start); since < 5*time.Millisecond || since > time.Second {
+		t.Fatalf("start %v ago", since)
+	}
+}
+
+func TestTrace_NoTimeline(t *testing.T) {
+	var c DefaultContext
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Reset(httptest.NewRecorder(), r, nil, "/")
+	c.Trace("db.query", time.Millisecond)
+	if c.Request() != r || c.TraceEvents() != nil {
+		t.Fatalf("Trace attached a timeline with tracing disabled")
+	}
+	if n := testing.AllocsPerRun(100, func() { c.Trace("db.query", time.Millisecond) }); n != 0 {
+		t.Fatalf("Trace allocated %v times with tracing disabled", n)
+	}
+}
+
+func TestTraceContext_LimitAndHook(t *testing.T) {
+	var mu sync.Mutex
+	var hooked []string
+	rc := WithTraceTimeline(context.Background(), 3)
+	rc = ContextWithTraceHook(rc, func(_ context.Context, ev TraceEvent) {
+		mu.Lock()
+		hooked = append(hooked, ev.Name)
+		mu.Unlock()
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			TraceContext(rc, "op", time.Millisecond)
+		}()
+	}
+	wg.Wait()
+	events, dropped := TraceEventsFromContext(rc)
+	if len(events) != 3 || dropped != 2 {
+		t.Fatalf("events=%d dropped=%d", len(events), dropped)
+	}
+	if len(hooked) != 5 {
+		t.Fatalf("hook saw %d events", len(hooked))
+	}
+
+	// A hook alone (no timeline) still receives events.
+	var n int
+	hookOnly := ContextWithTraceHook(context.Background(), func(context.Context, TraceEvent) { n++ })
+	TraceContext(hookOnly, "op", 0)
+	TraceContext(context.Background(), "ignored", 0)
+	if n != 1 {
+		t.Fatalf("hook-only calls=%d", n)
+	}
+	if events, _ := TraceEventsFromContext(hookOnly); events != nil {
+		t.Fatalf("events without timeline=%+v", events)
+	}
+}
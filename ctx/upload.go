@@ -0,0 +1,257 @@
+package ctx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Field error codes reported by upload validation (see UploadPolicy).
+const (
+	// FieldCodeTooManyFiles marks a file field with more parts than allowed.
+	FieldCodeTooManyFiles = "too_many_files"
+	// FieldCodeFileTooLarge marks a file larger than allowed.
+	FieldCodeFileTooLarge = "file_too_large"
+	// FieldCodeFileType marks a file whose sniffed content type is not allowed.
+	FieldCodeFileType = "file_type"
+	// FieldCodeInfected marks a file rejected by an UploadScanner.
+	FieldCodeInfected = "file_infected"
+)
+
+// FileRule constrains the files uploaded under one multipart field name.
+type FileRule struct {
+	// MaxFiles caps the number of files in the field. Zero means 1; negative
+	// means unlimited.
+	MaxFiles int
+	// MaxSize caps each file's size in bytes. Zero means no limit.
+	MaxSize int64
+	// AllowedTypes lists the accepted media types, such as "image/png" or
+	// "image/*". Types are detected from the file content with
+	// http.DetectContentType, never from the file name or the part's
+	// Content-Type header. Empty allows any type.
+	AllowedTypes []string
+	// Required reports a missing field as a "required" error.
+	Required bool
+}
+
+// UploadPolicy declares the files a multipart request may carry. Set it on
+// BindJSONOptions.Uploads (for example through Group.SetBindDefaults) to have
+// BindForm and BindAny enforce it per route, or pass it to
+// Ctx.ValidateUploads.
+//
+// Violations are returned as FieldErrors naming the offending part, e.g.
+// "photos[1]" for the second file of the "photos" field, with the file name
+// as the value and one of the FieldCode* upload codes.
+//
+// The policy checks files after the body was parsed; bound the body size
+// itself with the RequestSize middleware or http.MaxBytesReader.
+//
+// Example:
+//
+//	uploads := app.Group("/uploads")
+//	uploads.SetBindDefaults(flash.BindJSONOptions{ErrorUnused: true, Uploads: &flash.UploadPolicy{
+//		Files: map[string]flash.FileRule{
+//			"avatar": {MaxSize: 2 << 20, AllowedTypes: []string{"image/png", "image/jpeg"}, Required: true},
+//			"photos": {MaxFiles: 10, MaxSize: 10 << 20, AllowedTypes: []string{"image/*"}},
+//		},
+//	}})
+//	uploads.POST("/profile", func(c flash.Ctx) error {
+//		var in struct{ Name string `json:"name"` }
+//		if err := c.BindForm(&in); err != nil {
+//			return err // FieldErrors for text fields and files alike
+//		}
+//		avatar := c.Request().MultipartForm.File["avatar"][0]
+//		...
+//	})
+type UploadPolicy struct {
+	// Files maps multipart field names to their rules.
+	Files map[string]FileRule
+	// AllowUnknown accepts files in fields without a rule. By default they
+	// are reported as "unexpected".
+	AllowUnknown bool
+	// Scanner, if set, scans files for malware (see UploadScanner).
+	Scanner *UploadScanner
+}
+
+// ValidateUploads checks the request's multipart files against p and returns
+// FieldErrors describing every violation, or nil. Requests that are not
+// multipart carry no files, so only Required rules can fail for them.
+//
+// Example:
+//
+//	if err := c.ValidateUploads(policy); err != nil {
+//		return err
+//	}
+func (c *DefaultContext) ValidateUploads(p UploadPolicy) error {
+	files, err := c.multipartFiles()
+	if err != nil {
+		return err
+	}
+	var scan func(*multipart.FileHeader) error
+	if p.Scanner != nil && !p.Scanner.Async {
+		scan = func(fh *multipart.FileHeader) error { return c.scanUpload(fh, p.Scanner.Scan) }
+	}
+	return p.validate(files, scan)
+}
+
+// multipartFiles parses a multipart body (once) and returns its files.
+func (c *DefaultContext) multipartFiles() (map[string][]*multipart.FileHeader, error) {
+	mediaType, _, _ := mime.ParseMediaType(c.r.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, nil
+	}
+	if c.r.MultipartForm == nil {
+		if err := c.r.ParseMultipartForm(32 << 20); err != nil { // 32 MB
+			return nil, err
+		}
+	}
+	return c.r.MultipartForm.File, nil
+}
+
+// validate checks files against the rules. scan, if non-nil, is run on every
+// file that passed them.
+func (p UploadPolicy) validate(files map[string][]*multipart.FileHeader, scan func(*multipart.FileHeader) error) error {
+	errs := fieldErrorsMap{m: map[string]string{}, values: map[string]any{}, codes: map[string]string{}}
+	add := func(field, code, msg string, value any) {
+		errs.m[field] = msg
+		errs.codes[field] = code
+		if value != nil {
+			errs.values[field] = value
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fhs := files[name]
+		rule, ok := p.Files[name]
+		if !ok {
+			if !p.AllowUnknown {
+				add(name, FieldCodeUnexpected, ErrFieldUnexpected.Error(), nil)
+			}
+			continue
+		}
+		limit := rule.MaxFiles
+		if limit == 0 {
+			limit = 1
+		}
+		if limit > 0 && len(fhs) > limit {
+			add(name, FieldCodeTooManyFiles, fmt.Sprintf("too many files (max %d)", limit), len(fhs))
+			continue
+		}
+		for i, fh := range fhs {
+			field := name
+			if len(fhs) > 1 {
+				field = name + "[" + strconv.Itoa(i) + "]"
+			}
+			if rule.MaxSize > 0 && fh.Size > rule.MaxSize {
+				add(field, FieldCodeFileTooLarge, fmt.Sprintf("file too large (max %d bytes)", rule.MaxSize), fh.Filename)
+				continue
+			}
+			if len(rule.AllowedTypes) > 0 {
+				typ, err := DetectUploadType(fh)
+				if err != nil {
+					return err
+				}
+				if !typeAllowed(typ, rule.AllowedTypes) {
+					add(field, FieldCodeFileType, "file type "+typ+" not allowed", fh.Filename)
+					continue
+				}
+			}
+			if scan == nil {
+				continue
+			}
+			if err := scan(fh); err != nil {
+				if !errors.Is(err, ErrUploadInfected) {
+					return err
+				}
+				add(field, FieldCodeInfected, "file rejected by virus scanner", fh.Filename)
+			}
+		}
+	}
+	for name, rule := range p.Files {
+		if rule.Required && len(files[name]) == 0 {
+			add(name, FieldCodeRequired, ErrFieldRequired.Error(), nil)
+		}
+	}
+
+	if len(errs.m) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// DetectUploadType returns the media type of an uploaded file sniffed from its
+// first 512 bytes with http.DetectContentType, without parameters (for
+// example "image/png" or "text/plain"). The client-supplied name and
+// Content-Type are ignored.
+func DetectUploadType(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	typ, _, _ := mime.ParseMediaType(http.DetectContentType(buf[:n]))
+	return typ, nil
+}
+
+// typeAllowed matches typ against exact types and "type/*" wildcards.
+func typeAllowed(typ string, allowed []string) bool {
+	for _, a := range allowed {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a == typ || a == "*/*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(a, "/*"); ok && strings.HasPrefix(typ, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeFieldErrors combines the field errors of binding and upload
+// validation. Other errors take precedence.
+func mergeFieldErrors(a, b error) error {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	fa, okA := a.(fieldErrorsMap)
+	fb, okB := b.(fieldErrorsMap)
+	if !okA {
+		return a
+	}
+	if !okB {
+		return b
+	}
+	out := fieldErrorsMap{m: map[string]string{}, values: map[string]any{}, codes: map[string]string{}}
+	for _, f := range []fieldErrorsMap{fa, fb} {
+		for k, v := range f.m {
+			out.m[k] = v
+		}
+		for k, v := range f.values {
+			out.values[k] = v
+		}
+		for k, v := range f.codes {
+			out.codes[k] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,160 @@
+package ctx
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+type part struct {
+	field, filename string
+	content         []byte
+}
+
+func uploadCtx(t *testing.T, texts map[string]string, parts ...part) *DefaultContext {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for k, v := range texts {
+		_ = w.WriteField(k, v)
+	}
+	for _, p := range parts {
+		fw, err := w.CreateFormFile(p.field, p.filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = fw.Write(p.content)
+	}
+	_ = w.Close()
+	req := httptest.NewRequest("POST", "/", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	c := &DefaultContext{}
+	c.Reset(httptest.NewRecorder(), req, nil, "/")
+	return c
+}
+
+func fieldErrs(t *testing.T, err error) map[string]FieldErrorDetail {
+	t.Helper()
+	var fe FieldErrors
+	if !errors.As(err, &fe) {
+		t.Fatalf("expected FieldErrors, got %v", err)
+	}
+	out := map[string]FieldErrorDetail{}
+	for _, e := range fieldErrorDetails(t, fe) {
+		out[e.Field()] = e
+	}
+	return out
+}
+
+func TestValidateUploads(t *testing.T) {
+	policy := UploadPolicy{Files: map[string]FileRule{
+		"avatar": {MaxSize: 1 << 10, AllowedTypes: []string{"image/png"}, Required: true},
+		"photos": {MaxFiles: 3, AllowedTypes: []string{"image/*"}},
+		"docs":   {MaxFiles: 1},
+		"cv":     {Required: true},
+	}}
+
+	c := uploadCtx(t, nil,
+		part{"avatar", "me.png", pngHeader},
+		part{"photos", "a.png", pngHeader},
+		part{"photos", "b.png", []byte("<html><body>not an image</body></html>")},
+		part{"docs", "1.txt", []byte("one")},
+		part{"docs", "2.txt", []byte("two")},
+		part{"extra", "x.bin", []byte{0}},
+	)
+	errs := fieldErrs(t, c.ValidateUploads(policy))
+	want := map[string]string{
+		"photos[1]": FieldCodeFileType,
+		"docs":      FieldCodeTooManyFiles,
+		"extra":     FieldCodeUnexpected,
+		"cv":        FieldCodeRequired,
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	for field, code := range want {
+		if e, ok := errs[field]; !ok || e.Code() != code {
+			t.Fatalf("%s: expected code %s, got %+v", field, code, e)
+		}
+	}
+	if errs["photos[1]"].Value() != "b.png" || errs["photos[1]"].Message() != "file type text/html not allowed" {
+		t.Fatalf("unexpected file type error %+v", errs["photos[1]"])
+	}
+
+	// The extension and part Content-Type are ignored: a renamed HTML file is
+	// still rejected, and size limits apply.
+	c = uploadCtx(t, nil, part{"avatar", "evil.png", bytes.Repeat([]byte("<html>"), 10)}, part{"cv", "cv.pdf", []byte("%PDF-1.4")})
+	if e := fieldErrs(t, c.ValidateUploads(policy))["avatar"]; e == nil || e.Code() != FieldCodeFileType {
+		t.Fatalf("expected sniffed type rejection, got %+v", e)
+	}
+	c = uploadCtx(t, nil, part{"avatar", "big.png", append(pngHeader, make([]byte, 2<<10)...)}, part{"cv", "cv.pdf", nil})
+	if e := fieldErrs(t, c.ValidateUploads(policy))["avatar"]; e == nil || e.Code() != FieldCodeFileTooLarge {
+		t.Fatalf("expected size rejection, got %+v", e)
+	}
+
+	c = uploadCtx(t, nil, part{"avatar", "me.png", pngHeader}, part{"cv", "cv.pdf", []byte("%PDF-1.4")}, part{"extra", "x", nil})
+	if err := c.ValidateUploads(UploadPolicy{Files: policy.Files, AllowUnknown: true}); err != nil {
+		t.Fatalf("expected valid upload, got %v", err)
+	}
+}
+
+func TestValidateUploadsNonMultipart(t *testing.T) {
+	c := &DefaultContext{}
+	c.Reset(httptest.NewRecorder(), httptest.NewRequest("POST", "/", nil), nil, "/")
+	if err := c.ValidateUploads(UploadPolicy{Files: map[string]FileRule{"f": {}}}); err != nil {
+		t.Fatalf("optional files may be absent, got %v", err)
+	}
+	err := c.ValidateUploads(UploadPolicy{Files: map[string]FileRule{"f": {Required: true}}})
+	if !errors.Is(err, ErrFieldRequired) || fieldErrs(t, err)["f"].Code() != FieldCodeRequired {
+		t.Fatalf("expected required error, got %v", err)
+	}
+}
+
+func TestBindFormWithUploads(t *testing.T) {
+	opts := BindJSONOptions{ErrorUnused: true, Uploads: &UploadPolicy{Files: map[string]FileRule{
+		"avatar": {AllowedTypes: []string{"image/png"}},
+	}}}
+	var in struct {
+		Name string `json:"name"`
+	}
+
+	c := uploadCtx(t, map[string]string{"name": "Ada"}, part{"avatar", "a.png", pngHeader})
+	if err := c.BindForm(&in, opts); err != nil || in.Name != "Ada" {
+		t.Fatalf("expected bind to succeed, got %v %+v", err, in)
+	}
+
+	c = uploadCtx(t, map[string]string{"name": "Ada", "age": "3"}, part{"avatar", "a.gif", []byte("GIF89a")})
+	errs := fieldErrs(t, c.BindForm(&in, opts))
+	if errs["age"] == nil || errs["age"].Code() != FieldCodeUnexpected || errs["avatar"] == nil || errs["avatar"].Code() != FieldCodeFileType {
+		t.Fatalf("expected text and file errors together, got %v", errs)
+	}
+
+	// Route defaults apply the policy to BindAny as well.
+	c = uploadCtx(t, nil, part{"avatar", "a.gif", []byte("GIF89a")})
+	c.SetBindDefaults(&opts)
+	if e := fieldErrs(t, c.BindAny(&in))["avatar"]; e == nil {
+		t.Fatalf("expected BindAny to validate uploads")
+	}
+}
+
+func TestTypeAllowed(t *testing.T) {
+	for _, tc := range []struct {
+		typ     string
+		allowed []string
+		want    bool
+	}{
+		{"image/png", []string{"image/png"}, true},
+		{"image/png", []string{"Image/*"}, true},
+		{"image/png", []string{"*/*"}, true},
+		{"text/plain", []string{"image/*", "application/pdf"}, false},
+		{"imagex/png", []string{"image/*"}, false},
+	} {
+		if got := typeAllowed(tc.typ, tc.allowed); got != tc.want {
+			t.Fatalf("typeAllowed(%q, %v) = %v", tc.typ, tc.allowed, got)
+		}
+	}
+}
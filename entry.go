@@ -1,6 +1,12 @@
 package flash
 
 import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
 	"github.com/goflash/flash/v2/app"
 	"github.com/goflash/flash/v2/ctx"
 )
@@ -8,6 +14,44 @@ import (
 // Group is a route group for organizing routes. Re-exported from app.Group for convenience.
 type Group = app.Group
 
+// Route describes a registered route; returned by the route methods. Re-exported from app.Route.
+type Route = app.Route
+
+// DocInfo documents a route for the development docs page. Re-exported from app.DocInfo.
+type DocInfo = app.DocInfo
+
+// Resource controller interfaces and custom actions, re-exported from app for
+// use with App.Resource.
+type (
+	Indexer             = app.Indexer
+	Creator             = app.Creator
+	Shower              = app.Shower
+	Updater             = app.Updater
+	Deleter             = app.Deleter
+	ResourceActioner    = app.ResourceActioner
+	ResourceMiddlewarer = app.ResourceMiddlewarer
+	ResourceParamer     = app.ResourceParamer
+	ResourceAction      = app.ResourceAction
+)
+
+// Module bundles routes, middleware, health checks and shutdown hooks. Re-exported from app.Module.
+type Module = app.Module
+
+// ModuleDependent declares module dependencies by name. Re-exported from app.ModuleDependent.
+type ModuleDependent = app.ModuleDependent
+
+// HealthCheck is a readiness check for AddHealthCheck. Re-exported from app.HealthCheck.
+type HealthCheck = app.HealthCheck
+
+// ShutdownHook is run by Shutdown. Re-exported from app.ShutdownHook.
+type ShutdownHook = app.ShutdownHook
+
+// Module registration errors, re-exported from app.
+var (
+	ErrDuplicateModule  = app.ErrDuplicateModule
+	ErrModuleDependency = app.ErrModuleDependency
+)
+
 // App is the public interface of the application, re-exported for convenience.
 type App = app.App
 
@@ -25,11 +69,463 @@ type Middleware = app.Middleware
 // ErrorHandler handles errors returned from handlers. Re-exported from app.ErrorHandler.
 type ErrorHandler = app.ErrorHandler
 
+// ErrorHandlerV2 handles errors with access to response state. Re-exported from app.ErrorHandlerV2.
+type ErrorHandlerV2 = app.ErrorHandlerV2
+
+// ErrorInfo describes a failed request. Re-exported from app.ErrorInfo.
+type ErrorInfo = app.ErrorInfo
+
+// ErrorPageData is the data of error page templates. Re-exported from app.ErrorPageData.
+type ErrorPageData = app.ErrorPageData
+
 // Ctx is the request context interface, re-exported for convenience.
 type Ctx = ctx.Ctx
 
 // DefaultContext is the concrete context implementation used by the framework.
 type DefaultContext = ctx.DefaultContext
 
+// HTTPError is an error carrying an HTTP status code. Re-exported from ctx.HTTPError.
+type HTTPError = ctx.HTTPError
+
+// PanicError is the error produced by recovered panics. Re-exported from ctx.PanicError.
+type PanicError = ctx.PanicError
+
+// NewError returns an *HTTPError with the given status and message. Re-exported from ctx.NewError.
+func NewError(code int, message string) *HTTPError { return ctx.NewError(code, message) }
+
+// Phase orders middleware registered with UseOrdered. Re-exported from app.Phase.
+type Phase = app.Phase
+
+// Middleware phases, outermost first. Re-exported from app.
+const (
+	PhaseRecover   = app.PhaseRecover
+	PhaseTelemetry = app.PhaseTelemetry
+	PhaseSecurity  = app.PhaseSecurity
+	PhaseParsing   = app.PhaseParsing
+	PhaseBusiness  = app.PhaseBusiness
+)
+
+// Option configures the App at construction time. Re-exported from app.Option.
+type Option = app.Option
+
+// Router is the pluggable route matching engine. Re-exported from app.Router.
+type Router = app.Router
+
+// Param is a single URL path parameter. Re-exported from ctx.Param.
+type Param = ctx.Param
+
+// Params are the path parameters a Router captured for a route. Re-exported from ctx.Params.
+type Params = ctx.Params
+
+// TreeRouter is the in-repo radix tree router with param constraints and host
+// routing. Re-exported from app.TreeRouter.
+type TreeRouter = app.TreeRouter
+
+// WithRouter selects the route matching engine. Re-exported from app.WithRouter.
+//
+// Example:
+//
+//	a := flash.New(flash.WithRouter(flash.NewTreeRouter()))
+func WithRouter(r Router) Option { return app.WithRouter(r) }
+
+// CacheDirectives describes a Cache-Control header for c.CacheControl. Re-exported from ctx.CacheDirectives.
+type CacheDirectives = ctx.CacheDirectives
+
+// WithConnectionCloseOnDrain sends "Connection: close" while draining. Re-exported from app.WithConnectionCloseOnDrain.
+func WithConnectionCloseOnDrain(enabled bool) Option { return app.WithConnectionCloseOnDrain(enabled) }
+
+// WithErrorHandler sets the error handler. Re-exported from app.WithErrorHandler.
+func WithErrorHandler(h ErrorHandler) Option { return app.WithErrorHandler(h) }
+
+// WithErrorHandlerV2 sets the error handler with response state. Re-exported from app.WithErrorHandlerV2.
+func WithErrorHandlerV2(h ErrorHandlerV2) Option { return app.WithErrorHandlerV2(h) }
+
+// JSONError is the default body written by WithJSONErrors. Re-exported from app.JSONError.
+type JSONError = app.JSONError
+
+// JSONErrorConfig configures WithJSONErrors. Re-exported from app.JSONErrorConfig.
+type JSONErrorConfig = app.JSONErrorConfig
+
+// WithJSONErrors makes the default error, 404 and 405 handlers answer with JSON.
+// Re-exported from app.WithJSONErrors.
+func WithJSONErrors(cfg ...JSONErrorConfig) Option { return app.WithJSONErrors(cfg...) }
+
+// WithDevMode enables or disables development mode. Re-exported from app.WithDevMode.
+func WithDevMode(enabled bool) Option { return app.WithDevMode(enabled) }
+
+// DevMode enables development mode. Re-exported from app.DevMode.
+func DevMode() Option { return app.DevMode() }
+
+// DevRoutesPath is the route table path in development mode. Re-exported from app.DevRoutesPath.
+const DevRoutesPath = app.DevRoutesPath
+
+// KubernetesConfig tunes KubernetesPreset. Re-exported from app.KubernetesConfig.
+type KubernetesConfig = app.KubernetesConfig
+
+// KubernetesPreset wires probes, metrics, pod log attributes and SIGTERM handling. Re-exported from app.KubernetesPreset.
+func KubernetesPreset(cfg ...KubernetesConfig) Option { return app.KubernetesPreset(cfg...) }
+
+// TimingConfig selects where WithTimingBreakdown reports. Re-exported from app.TimingConfig.
+type TimingConfig = app.TimingConfig
+
+// Timing is the latency breakdown of one request. Re-exported from app.Timing.
+type Timing = app.Timing
+
+// MiddlewareTiming is the time spent in one middleware. Re-exported from app.MiddlewareTiming.
+type MiddlewareTiming = app.MiddlewareTiming
+
+// WithTimingBreakdown reports per-middleware latency for every request. Re-exported from app.WithTimingBreakdown.
+func WithTimingBreakdown(cfg ...TimingConfig) Option { return app.WithTimingBreakdown(cfg...) }
+
+// TraceConfig selects where WithRequestTrace reports. Re-exported from app.TraceConfig.
+type TraceConfig = app.TraceConfig
+
+// WithRequestTrace collects a trace timeline for every request. Re-exported from app.WithRequestTrace.
+func WithRequestTrace(cfg ...TraceConfig) Option { return app.WithRequestTrace(cfg...) }
+
+// TraceEvent is one entry of a request's trace timeline. Re-exported from ctx.TraceEvent.
+type TraceEvent = ctx.TraceEvent
+
+// TraceHook receives traced events, e.g. to create spans. Re-exported from ctx.TraceHook.
+type TraceHook = ctx.TraceHook
+
+// TraceContext records a traced operation in ctx's timeline. Re-exported from ctx.TraceContext.
+func TraceContext(c context.Context, name string, d time.Duration, attrs ...any) {
+	ctx.TraceContext(c, name, d, attrs...)
+}
+
+// ContextWithTraceHook passes the events traced in parent to hook. Re-exported from ctx.ContextWithTraceHook.
+func ContextWithTraceHook(parent context.Context, hook TraceHook) context.Context {
+	return ctx.ContextWithTraceHook(parent, hook)
+}
+
+// WithNotFoundHandler sets the 404 handler. Re-exported from app.WithNotFoundHandler.
+func WithNotFoundHandler(h http.Handler) Option { return app.WithNotFoundHandler(h) }
+
+// WithMethodNotAllowedHandler sets the 405 handler. Re-exported from app.WithMethodNotAllowedHandler.
+func WithMethodNotAllowedHandler(h http.Handler) Option { return app.WithMethodNotAllowedHandler(h) }
+
+// WrapHTTP adapts an http.Handler to a Handler for regular routes. Re-exported from app.WrapHTTP.
+func WrapHTTP(h http.Handler) Handler { return app.WrapHTTP(h) }
+
+// Metrics counts framework internals. Re-exported from app.Metrics.
+type Metrics = app.Metrics
+
+// MetricsSnapshot is a point-in-time copy of Metrics. Re-exported from app.MetricsSnapshot.
+type MetricsSnapshot = app.MetricsSnapshot
+
+// DefaultMetrics is the process-wide Metrics instance. Re-exported from app.DefaultMetrics.
+var DefaultMetrics = app.DefaultMetrics
+
+// WithMetrics records request-path metrics into DefaultMetrics. Re-exported from app.WithMetrics.
+func WithMetrics() Option { return app.WithMetrics() }
+
+// WithProfilingLabels runs handlers under pprof route and method labels. Re-exported from app.WithProfilingLabels.
+func WithProfilingLabels() Option { return app.WithProfilingLabels() }
+
+// WithLogger sets the application logger. Re-exported from app.WithLogger.
+func WithLogger(l *slog.Logger) Option { return app.WithLogger(l) }
+
+// BindJSONOptions configures request binding. Re-exported from ctx.BindJSONOptions.
+type BindJSONOptions = ctx.BindJSONOptions
+
+// UploadPolicy declares the files a multipart request may carry. Re-exported from ctx.UploadPolicy.
+type UploadPolicy = ctx.UploadPolicy
+
+// FileRule constrains the files of one multipart field. Re-exported from ctx.FileRule.
+type FileRule = ctx.FileRule
+
+// UploadScanner scans uploads for malware. Re-exported from ctx.UploadScanner.
+type UploadScanner = ctx.UploadScanner
+
+// ScanFunc streams a file through a scanner. Re-exported from ctx.ScanFunc.
+type ScanFunc = ctx.ScanFunc
+
+// UploadDetection describes an infected file found by an async scan. Re-exported from ctx.UploadDetection.
+type UploadDetection = ctx.UploadDetection
+
+// ErrUploadInfected is wrapped by ScanFunc results reporting malware. Re-exported from ctx.ErrUploadInfected.
+var ErrUploadInfected = ctx.ErrUploadInfected
+
+// KeyProvider opens sealed struct fields during binding. Re-exported from ctx.KeyProvider.
+type KeyProvider = ctx.KeyProvider
+
+// KeyProviderFunc adapts a function to KeyProvider. Re-exported from ctx.KeyProviderFunc.
+type KeyProviderFunc = ctx.KeyProviderFunc
+
+// AESGCMKeyProvider opens values sealed with AES-GCM. Re-exported from ctx.AESGCMKeyProvider.
+type AESGCMKeyProvider = ctx.AESGCMKeyProvider
+
+// ErrSealedInvalid is wrapped by KeyProvider errors for invalid sealed values. Re-exported from ctx.ErrSealedInvalid.
+var ErrSealedInvalid = ctx.ErrSealedInvalid
+
+// HeaderValue is one element of a comma-separated request header, as returned
+// by Ctx.HeaderList. Re-exported from ctx.HeaderValue.
+type HeaderValue = ctx.HeaderValue
+
+// ErrInvalidCookie is wrapped by the errors of ValidateCookie and
+// Ctx.SetCookie. Re-exported from ctx.ErrInvalidCookie.
+var ErrInvalidCookie = ctx.ErrInvalidCookie
+
+// ValidateCookie checks that a cookie would be accepted by browsers.
+// Re-exported from ctx.ValidateCookie.
+func ValidateCookie(ck *http.Cookie) error { return ctx.ValidateCookie(ck) }
+
+// HostCookie returns a "__Host-" prefixed cookie with compliant attributes.
+// Re-exported from ctx.HostCookie.
+func HostCookie(name, value string) *http.Cookie { return ctx.HostCookie(name, value) }
+
+// Sanitizer cleans an input value for a named policy, returning "" to reject
+// it. Re-exported from ctx.Sanitizer.
+type Sanitizer = ctx.Sanitizer
+
+// Built-in sanitizer policies for Ctx.ParamSanitized and Ctx.QuerySanitized.
+// Re-exported from ctx.
+const (
+	SanitizeHTML     = ctx.SanitizeHTML
+	SanitizeAlphaNum = ctx.SanitizeAlphaNum
+	SanitizeFilename = ctx.SanitizeFilename
+	SanitizeUUID     = ctx.SanitizeUUID
+	SanitizeEmail    = ctx.SanitizeEmail
+	SanitizeSlug     = ctx.SanitizeSlug
+	SanitizeHostname = ctx.SanitizeHostname
+)
+
+// RegisterKeyProvider registers p for struct fields tagged `seal:"<name>"`. Re-exported from ctx.RegisterKeyProvider.
+func RegisterKeyProvider(name string, p KeyProvider) { ctx.RegisterKeyProvider(name, p) }
+
+// ImageFit selects how images are resized. Re-exported from ctx.ImageFit.
+type ImageFit = ctx.ImageFit
+
+// Image fit modes, re-exported from ctx.
+const (
+	FitContain = ctx.FitContain
+	FitCover   = ctx.FitCover
+	FitFill    = ctx.FitFill
+)
+
+// WithBindDefaults sets app-wide binding options. Re-exported from app.WithBindDefaults.
+func WithBindDefaults(o BindJSONOptions) Option { return app.WithBindDefaults(o) }
+
+// WithJSONEscapeHTML sets the default HTML escaping of c.JSON. Re-exported from app.WithJSONEscapeHTML.
+func WithJSONEscapeHTML(escape bool) Option { return app.WithJSONEscapeHTML(escape) }
+
+// Config holds deployment-tunable settings. Re-exported from app.Config.
+type Config = app.Config
+
+// Duration is a config duration written as "5s". Re-exported from app.Duration.
+type Duration = app.Duration
+
+// NewFromConfig creates an App configured from cfg. Re-exported from app.NewFromConfig.
+//
+// Example:
+//
+//	cfg, _ := flash.LoadConfigFile("config.yaml")
+//	a, err := flash.NewFromConfig(cfg)
+func NewFromConfig(cfg Config, opts ...Option) (App, error) { return app.NewFromConfig(cfg, opts...) }
+
+// RuntimeConfig tunes the garbage collector. Re-exported from app.RuntimeConfig.
+type RuntimeConfig = app.RuntimeConfig
+
+// RuntimeStats is a snapshot of GC and context pool state. Re-exported from app.RuntimeStats.
+type RuntimeStats = app.RuntimeStats
+
+// ByteSize is a config byte count written as "512MiB". Re-exported from app.ByteSize.
+type ByteSize = app.ByteSize
+
+// TuneRuntime applies GOGC, memory limit and ballast settings. Re-exported from app.TuneRuntime.
+//
+// Example:
+//
+//	_ = flash.TuneRuntime(flash.RuntimeConfig{GOGC: 200, MemoryLimitPercent: 90})
+func TuneRuntime(cfg RuntimeConfig) error { return app.TuneRuntime(cfg) }
+
+// ReadRuntimeStats returns current GC and context pool stats. Re-exported from app.ReadRuntimeStats.
+func ReadRuntimeStats() RuntimeStats { return app.ReadRuntimeStats() }
+
+// RuntimeStatsHandler serves ReadRuntimeStats as JSON. Re-exported from app.RuntimeStatsHandler.
+func RuntimeStatsHandler() http.Handler { return app.RuntimeStatsHandler() }
+
+// LoadConfigFile reads a Config from a YAML or JSON file. Re-exported from app.LoadConfigFile.
+func LoadConfigFile(path string) (Config, error) { return app.LoadConfigFile(path) }
+
+// LoadConfigEnv overlays prefixed environment variables onto base. Re-exported from app.LoadConfigEnv.
+func LoadConfigEnv(prefix string, base Config) (Config, error) {
+	return app.LoadConfigEnv(prefix, base)
+}
+
+// HardenConfig tunes HardenedServer. Re-exported from app.HardenConfig.
+type HardenConfig = app.HardenConfig
+
+// HardenedServer returns an *http.Server with slowloris-resistant timeouts and
+// header limits. Re-exported from app.HardenedServer.
+//
+// Example:
+//
+//	srv := flash.HardenedServer(a, flash.HardenConfig{Addr: ":8080"})
+//	log.Fatal(srv.ListenAndServe())
+func HardenedServer(h http.Handler, cfg HardenConfig) *http.Server { return app.HardenedServer(h, cfg) }
+
+// Serve listens on srv.Addr, signals readiness to `flash dev` or systemd and serves srv. Re-exported from app.Serve.
+func Serve(srv *http.Server) error { return app.Serve(srv) }
+
+// Listen opens a listener on addr, or takes a socket-activated or `flash dev` one. Re-exported from app.Listen.
+func Listen(addr string) (net.Listener, error) { return app.Listen(addr) }
+
+// NotifyReady signals readiness to the `flash dev` supervisor or systemd. Re-exported from app.NotifyReady.
+func NotifyReady(ln net.Listener) error { return app.NotifyReady(ln) }
+
+// SystemdListeners returns the sockets passed by systemd socket activation. Re-exported from app.SystemdListeners.
+func SystemdListeners() ([]net.Listener, error) { return app.SystemdListeners() }
+
+// SystemdListener returns the socket-activated listener with the given FileDescriptorName. Re-exported from app.SystemdListener.
+func SystemdListener(name string) (net.Listener, error) { return app.SystemdListener(name) }
+
+// SystemdNotify sends a state such as "STATUS=..." to systemd (sd_notify). Re-exported from app.SystemdNotify.
+func SystemdNotify(state string) error { return app.SystemdNotify(state) }
+
+// TempLimits bounds per-request temp files. Re-exported from ctx.TempLimits.
+type TempLimits = ctx.TempLimits
+
+// TempFile is a request-scoped temporary file. Re-exported from ctx.TempFile.
+type TempFile = ctx.TempFile
+
+// Feed is an RSS/Atom feed written by Ctx.Feed. Re-exported from ctx.Feed.
+type Feed = ctx.Feed
+
+// FeedItem is an entry of a Feed. Re-exported from ctx.FeedItem.
+type FeedItem = ctx.FeedItem
+
+// PollFunc checks for long-poll data newer than a resume token. Re-exported from ctx.PollFunc.
+type PollFunc = ctx.PollFunc
+
+// LongPollOptions tunes Ctx.LongPoll. Re-exported from ctx.LongPollOptions.
+type LongPollOptions = ctx.LongPollOptions
+
+// PatchOptions restricts the paths a JSON Patch or merge patch may change. Re-exported from ctx.PatchOptions.
+type PatchOptions = ctx.PatchOptions
+
+// ErrNotFound makes the app answer a matched route like an unmatched one
+// (soft 404). Re-exported from ctx.ErrNotFound.
+var ErrNotFound = ctx.ErrNotFound
+
+// ErrPreconditionFailed is wrapped by 412 If-Match failures. Re-exported from ctx.ErrPreconditionFailed.
+var ErrPreconditionFailed = ctx.ErrPreconditionFailed
+
+// ErrPreconditionRequired is wrapped by 428 errors for unconditional writes. Re-exported from ctx.ErrPreconditionRequired.
+var ErrPreconditionRequired = ctx.ErrPreconditionRequired
+
+// Detached is a read-only request snapshot for background goroutines. Re-exported from ctx.Detached.
+type Detached = ctx.Detached
+
+// WithTempLimits bounds the temp files each request may create. Re-exported from app.WithTempLimits.
+func WithTempLimits(l TempLimits) Option { return app.WithTempLimits(l) }
+
+// FlashMessage is a one-time message for the next request. Re-exported from ctx.FlashMessage.
+type FlashMessage = ctx.FlashMessage
+
+// FlashStore persists flash messages between requests. Re-exported from ctx.FlashStore.
+type FlashStore = ctx.FlashStore
+
+// CookieFlashStore keeps flash messages in a signed cookie. Re-exported from ctx.CookieFlashStore.
+type CookieFlashStore = ctx.CookieFlashStore
+
+// NewCookieFlashStore returns a signed-cookie flash store. Re-exported from ctx.NewCookieFlashStore.
+func NewCookieFlashStore(secret []byte) *CookieFlashStore { return ctx.NewCookieFlashStore(secret) }
+
+// WithFlashStore sets the default flash message store. Re-exported from app.WithFlashStore.
+func WithFlashStore(s FlashStore) Option { return app.WithFlashStore(s) }
+
+// WithMountMiddleware runs global middleware around Mount and HandleHTTP handlers. Re-exported from app.WithMountMiddleware.
+func WithMountMiddleware() Option { return app.WithMountMiddleware() }
+
+// AssetConfig configures App.Assets. Re-exported from app.AssetConfig.
+type AssetConfig = app.AssetConfig
+
+// Assets serves fingerprinted static files and resolves their paths. Re-exported from app.Assets.
+type Assets = app.Assets
+
+// ContentTypeConfig configures AllowedContentTypes. Re-exported from app.ContentTypeConfig.
+type ContentTypeConfig = app.ContentTypeConfig
+
+// ErrUnsupportedMediaType is wrapped by content type rejections. Re-exported from app.ErrUnsupportedMediaType.
+var ErrUnsupportedMediaType = app.ErrUnsupportedMediaType
+
+// WithAllowedContentTypes rejects request bodies of other media types with 415.
+// Re-exported from app.WithAllowedContentTypes.
+//
+// Example:
+//
+//	a.POST("/users", CreateUser, flash.WithAllowedContentTypes("application/json"))
+func WithAllowedContentTypes(types ...string) Middleware {
+	return app.WithAllowedContentTypes(types...)
+}
+
+// AllowedContentTypes enforces cfg.Types on request bodies. Re-exported from app.AllowedContentTypes.
+func AllowedContentTypes(cfg ContentTypeConfig) Middleware { return app.AllowedContentTypes(cfg) }
+
+// ErrNotAcceptable is wrapped by 406 errors of routes declared with Route.Produces.
+// Re-exported from app.ErrNotAcceptable.
+var ErrNotAcceptable = app.ErrNotAcceptable
+
+// RegistrationError is the panic value for nil handlers and middleware. Re-exported from app.RegistrationError.
+type RegistrationError = app.RegistrationError
+
+// Bypassable wraps mw so routes can opt out of it with Route.Bypass(name).
+// Re-exported from app.Bypassable.
+func Bypassable(name string, mw Middleware) Middleware { return app.Bypassable(name, mw) }
+
+// BatchConfig tunes BatchHandler. Re-exported from app.BatchConfig.
+type BatchConfig = app.BatchConfig
+
+// BatchItem is one sub-request of a batch. Re-exported from app.BatchItem.
+type BatchItem = app.BatchItem
+
+// BatchResult is the response to one BatchItem. Re-exported from app.BatchResult.
+type BatchResult = app.BatchResult
+
+// BatchHandler serves a JSON array of sub-requests through a's router. Re-exported from app.BatchHandler.
+func BatchHandler(a App, cfg ...BatchConfig) Handler { return app.BatchHandler(a, cfg...) }
+
+// NamedMiddleware is implemented by middleware that report a name and config. Re-exported from app.NamedMiddleware.
+type NamedMiddleware = app.NamedMiddleware
+
+// MiddlewareInfo describes one middleware of the global stack. Re-exported from app.MiddlewareInfo.
+type MiddlewareInfo = app.MiddlewareInfo
+
+// Describe attaches a name and config to mw. Re-exported from app.Describe.
+func Describe(name string, config any, mw Middleware) Middleware {
+	return app.Describe(name, config, mw)
+}
+
+// Event is a framework lifecycle event delivered to OnEvent handlers. Re-exported from app.Event.
+type Event = app.Event
+
+// EventHandler receives framework events. Re-exported from app.EventHandler.
+type EventHandler = app.EventHandler
+
+// Typed framework event payloads, re-exported from app.
+type (
+	RequestStartEvent    = app.RequestStartEvent
+	RequestEndEvent      = app.RequestEndEvent
+	ErrorEvent           = app.ErrorEvent
+	PanicEvent           = app.PanicEvent
+	RouteRegisteredEvent = app.RouteRegisteredEvent
+	ShutdownEvent        = app.ShutdownEvent
+)
+
+// Framework event names for OnEvent filters, re-exported from app.
+const (
+	EventRequestStart    = app.EventRequestStart
+	EventRequestEnd      = app.EventRequestEnd
+	EventError           = app.EventError
+	EventPanic           = app.EventPanic
+	EventRouteRegistered = app.EventRouteRegistered
+	EventShutdown        = app.EventShutdown
+)
+
+// NewTreeRouter returns the in-repo radix tree router. Re-exported from app.NewTreeRouter.
+func NewTreeRouter() *TreeRouter { return app.NewTreeRouter() }
+
 // New creates a new App with sensible defaults. Re-exported from app.New.
-func New() App { return app.New() }
+func New(opts ...Option) App { return app.New(opts...) }
@@ -1,9 +1,147 @@
 package flash
 
-import "testing"
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
 
 func TestEntryNewReexport(t *testing.T) {
 	if New() == nil {
 		t.Fatalf("New returned nil")
 	}
 }
+
+func TestEntryNewErrorReexport(t *testing.T) {
+	var e *HTTPError = NewError(404, "missing")
+	if e.Code != 404 || e.Message != "missing" {
+		t.Fatalf("unexpected: %+v", e)
+	}
+}
+
+func TestEntryWithRouterReexport(t *testing.T) {
+	a := New(WithRouter(NewTreeRouter()))
+	a.GET("/n/:id<int>", func(c Ctx) error { return c.String(200, c.Param("id")) })
+	if a == nil {
+		t.Fatalf("New returned nil")
+	}
+}
+
+func TestEntryErrorHandlerV2Reexport(t *testing.T) {
+	var h ErrorHandlerV2 = func(c Ctx, info ErrorInfo) {}
+	a := New()
+	a.SetErrorHandlerV2(h)
+	if a.ErrorHandlerV2() == nil {
+		t.Fatalf("ErrorHandlerV2 not set")
+	}
+}
+
+func TestEntryWithTempLimitsReexport(t *testing.T) {
+	var l TempLimits = TempLimits{MaxFiles: 1}
+	if New(WithTempLimits(l)) == nil {
+		t.Fatalf("New returned nil")
+	}
+}
+
+func TestEntryOptionReexports(t *testing.T) {
+	a := New(
+		WithErrorHandler(func(Ctx, error) {}),
+		WithErrorHandlerV2(nil),
+		WithNotFoundHandler(nil),
+		WithMethodNotAllowedHandler(nil),
+		WithLogger(nil),
+		WithBindDefaults(BindJSONOptions{}),
+		WithJSONEscapeHTML(true),
+		WithConnectionCloseOnDrain(true),
+	)
+	if _, ok := a.BindDefaults(); !ok {
+		t.Fatalf("bind defaults not applied")
+	}
+}
+
+func TestEntryConfigReexports(t *testing.T) {
+	cfg, err := LoadConfigEnv("FLASH_ENTRY_TEST", Config{Addr: ":1", ReadTimeout: Duration(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewFromConfig(cfg)
+	if err != nil || a == nil {
+		t.Fatalf("NewFromConfig: %v", err)
+	}
+	if _, err := LoadConfigFile("missing.yaml"); err == nil {
+		t.Fatalf("expected error for missing file")
+	}
+}
+
+func TestEntryHardenedServerReexport(t *testing.T) {
+	a := New()
+	if srv := HardenedServer(a, HardenConfig{Addr: ":0"}); srv.Handler != a || srv.ReadHeaderTimeout == 0 {
+		t.Fatalf("unexpected server: %+v", srv)
+	}
+}
+
+func TestEntryPhaseReexport(t *testing.T) {
+	var p Phase = PhaseSecurity
+	a := New()
+	a.UseOrdered(p, func(next Handler) Handler { return next })
+	if PhaseRecover >= PhaseBusiness || p.String() != "security" {
+		t.Fatalf("unexpected phases")
+	}
+}
+
+func TestEntryFlashReexports(t *testing.T) {
+	var s FlashStore = NewCookieFlashStore([]byte("k"))
+	var _ *CookieFlashStore = s.(*CookieFlashStore)
+	if New(WithFlashStore(s)) == nil || (FlashMessage{Kind: "info"}).Kind != "info" {
+		t.Fatalf("unexpected")
+	}
+}
+
+func TestEntryRouteDocReexport(t *testing.T) {
+	a := New()
+	var r *Route = a.GET("/x", func(Ctx) error { return nil }).Doc(DocInfo{Summary: "x"})
+	if d, ok := r.DocInfo(); !ok || d.Summary != "x" || len(a.Routes()) != 1 {
+		t.Fatalf("unexpected route docs")
+	}
+}
+
+type entryShower struct{}
+
+func (entryShower) Show(c Ctx) error { return nil }
+
+func TestEntryResourceReexport(t *testing.T) {
+	var _ Shower = entryShower{}
+	var _ = ResourceAction{Name: "x"}
+	if rs := New().Resource("/things", entryShower{}); len(rs) != 1 || rs[0].Name != "things.show" {
+		t.Fatalf("unexpected resource routes")
+	}
+}
+
+type entryModule struct{}
+
+func (entryModule) Name() string       { return "m" }
+func (entryModule) Register(App) error { return nil }
+
+func TestEntryModuleReexports(t *testing.T) {
+	var m Module = entryModule{}
+	a := New()
+	a.AddHealthCheck("x", HealthCheck(nil))
+	a.OnShutdown(ShutdownHook(nil))
+	if err := a.RegisterModules(m, m); !errors.Is(err, ErrDuplicateModule) || errors.Is(err, ErrModuleDependency) {
+		t.Fatalf("unexpected err %v", err)
+	}
+	var _ ModuleDependent = nil
+}
+
+func TestEntryBypassableReexport(t *testing.T) {
+	a := New()
+	calls := 0
+	a.Use(Bypassable("count", func(next Handler) Handler {
+		return func(c Ctx) error { calls++; return next(c) }
+	}))
+	a.GET("/x", func(Ctx) error { return nil }).Bypass("count")
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/x", nil))
+	if calls != 0 {
+		t.Fatalf("expected middleware to be bypassed")
+	}
+}
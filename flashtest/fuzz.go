@@ -0,0 +1,147 @@
+// Package flashtest provides helpers for testing flash applications. It
+// depends on net/http/httptest and is meant to be imported from _test.go
+// files only, so production binaries do not link the test recorder.
+//
+// FuzzServe and FuzzBindJSON drive an app's routing and binding layers from
+// native Go fuzz targets:
+//
+//	func FuzzAPI(f *testing.F) {
+//		a := newApp()
+//		f.Fuzz(func(t *testing.T, data []byte) {
+//			if rec := flashtest.FuzzServe(a, data); rec.Code >= 500 {
+//				t.Fatalf("status %d for %q", rec.Code, data)
+//			}
+//		})
+//	}
+package flashtest
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+// fuzzMethods are the methods FuzzRequest picks from with the first byte.
+var fuzzMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodHead, http.MethodOptions,
+}
+
+// FuzzRequest decodes arbitrary fuzzer input into a valid *http.Request, so
+// fuzzing explores the app's routing, binding and handlers rather than the
+// HTTP wire parser. The input is laid out as:
+//
+//	byte 0          method (GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS)
+//	up to "\n"      request target, e.g. "/users/7?expand=1"
+//	"Key: Value\n"  headers, up to an empty line
+//	rest            body
+//
+// Missing parts default to GET, "/", no headers and no body; invalid targets
+// are escaped into a path.
+func FuzzRequest(data []byte) *http.Request {
+	method := http.MethodGet
+	if len(data) > 0 {
+		method = fuzzMethods[int(data[0])%len(fuzzMethods)]
+		data = data[1:]
+	}
+	line, rest, _ := bytes.Cut(data, []byte("\n"))
+	target := string(line)
+	if !strings.HasPrefix(target, "/") {
+		target = "/" + target
+	}
+	if u, err := url.ParseRequestURI(target); err != nil || u.Host != "" || strings.ContainsFunc(target, invalidTargetRune) {
+		target = "/" + url.PathEscape(strings.TrimPrefix(target, "/"))
+	}
+
+	header := http.Header{}
+	for len(rest) > 0 {
+		line, rest, _ = bytes.Cut(rest, []byte("\n"))
+		if len(bytes.TrimSpace(line)) == 0 {
+			break
+		}
+		k, v, ok := strings.Cut(string(line), ":")
+		if k = strings.TrimSpace(k); ok && validHeaderName(k) {
+			header.Add(k, strings.Map(headerValueRune, strings.TrimSpace(v)))
+		}
+	}
+
+	req := httptest.NewRequest(method, target, bytes.NewReader(rest))
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	return req
+}
+
+// invalidTargetRune reports runes that cannot appear in a request line.
+func invalidTargetRune(r rune) bool { return r <= ' ' || r == 0x7f }
+
+func validHeaderName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r > 0x7e || r <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// headerValueRune drops control characters that net/http never delivers in
+// header values.
+func headerValueRune(r rune) rune {
+	if r < ' ' && r != '\t' || r == 0x7f {
+		return -1
+	}
+	return r
+}
+
+// FuzzServe serves the request decoded from data (see FuzzRequest) with h and
+// returns the recorded response. Panics propagate to the fuzzer; place it
+// outside Recover, or check the response for 500s, to catch handler crashes.
+//
+// Example:
+//
+//	func FuzzAPI(f *testing.F) {
+//		a := newApp() // the application's route table
+//		f.Add([]byte("\x01/users\nContent-Type: application/json\n\n{\"name\":\"ada\"}"))
+//		f.Fuzz(func(t *testing.T, data []byte) {
+//			if rec := flashtest.FuzzServe(a, data); rec.Code >= 500 {
+//				t.Fatalf("status %d for %q", rec.Code, data)
+//			}
+//		})
+//	}
+func FuzzServe(h http.Handler, data []byte) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, FuzzRequest(data))
+	return rec
+}
+
+// FuzzBindJSON binds data as a JSON request body into dst with c.BindJSON,
+// so DTOs can be fuzzed against the framework's binding layer. Errors are
+// expected for most inputs; the fuzz target should assert invariants on dst
+// or fail on panics.
+//
+// Example:
+//
+//	func FuzzCreateUser(f *testing.F) {
+//		f.Add([]byte(`{"name":"ada","age":36}`))
+//		f.Fuzz(func(t *testing.T, data []byte) {
+//			var in CreateUserInput
+//			if flashtest.FuzzBindJSON(data, &in) == nil && in.Age < 0 {
+//				t.Fatalf("negative age accepted: %q", data)
+//			}
+//		})
+//	}
+func FuzzBindJSON(data []byte, dst any, opts ...ctx.BindJSONOptions) error {
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	var c ctx.DefaultContext
+	c.Reset(httptest.NewRecorder(), req, nil, "/")
+	defer c.Finish()
+	return c.BindJSON(dst, opts...)
+}
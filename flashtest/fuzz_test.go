@@ -0,0 +1,58 @@
+package flashtest
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/goflash/flash/v2/app"
+)
+
+func TestFuzzRequestDecodes(t *testing.T) {
+	req := FuzzRequest([]byte("\x01/users/7?x=1\nContent-Type: application/json\nX-Bad\x00: v\nX-Ok: a\x01b\n\n{\"a\":1}"))
+	if req.Method != http.MethodPost || req.URL.Path != "/users/7" || req.URL.Query().Get("x") != "1" {
+		t.Fatalf("request = %s %s", req.Method, req.URL)
+	}
+	if req.Header.Get("Content-Type") != "application/json" || req.Header.Get("X-Ok") != "ab" {
+		t.Fatalf("headers = %v", req.Header)
+	}
+	if len(req.Header) != 2 {
+		t.Fatalf("invalid header kept: %v", req.Header)
+	}
+	if body, _ := io.ReadAll(req.Body); string(body) != `{"a":1}` {
+		t.Fatalf("body = %q", body)
+	}
+}
+
+func TestFuzzRequestSanitizesTarget(t *testing.T) {
+	for _, in := range []string{"", "\x00", "\x00users", "\x00//evil.com/x", "\x00/%zz", "\x00/a b\x7f"} {
+		req := FuzzRequest([]byte(in))
+		if req.Method != http.MethodGet || !strings.HasPrefix(req.URL.Path, "/") || req.Host != "example.com" {
+			t.Fatalf("%q: request = %s %s host %s", in, req.Method, req.URL, req.Host)
+		}
+	}
+}
+
+func TestFuzzServe(t *testing.T) {
+	a := app.New()
+	a.GET("/hello/:name", func(c app.Ctx) error { return c.String(http.StatusOK, "hi "+c.Param("name")) })
+	if rec := FuzzServe(a, []byte("\x00/hello/ada")); rec.Code != http.StatusOK || rec.Body.String() != "hi ada" {
+		t.Fatalf("got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := FuzzServe(a, []byte("\x01/hello/ada")); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST status = %d", rec.Code)
+	}
+}
+
+func TestFuzzBindJSON(t *testing.T) {
+	var v struct {
+		Name string `json:"name"`
+	}
+	if err := FuzzBindJSON([]byte(`{"name":"ada"}`), &v); err != nil || v.Name != "ada" {
+		t.Fatalf("bind = %v, %+v", err, v)
+	}
+	if err := FuzzBindJSON([]byte(`{"name":`), &v); err == nil {
+		t.Fatalf("expected error for truncated JSON")
+	}
+}
@@ -0,0 +1,157 @@
+package hub
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Broker relays messages between hub instances, so a message published on
+// one instance reaches SSE and WebSocket clients connected to any of them.
+// Payloads are opaque envelopes produced by the hub; a Broker only transports
+// them. RedisBroker is the built-in implementation.
+type Broker interface {
+	// Publish sends payload to every instance, including this one.
+	Publish(ctx context.Context, payload []byte) error
+
+	// Run delivers payloads published by any instance to deliver until ctx
+	// ends or the connection fails. The hub calls Run again, with backoff,
+	// after a failure; messages published in between are lost.
+	Run(ctx context.Context, deliver func(payload []byte)) error
+}
+
+// EnvelopeVersion is the version of the envelopes the hub sends through a
+// Broker. Envelopes of a newer version, from instances running a later
+// release during a rolling deploy, are dropped and counted in
+// Stats.BrokerDropped.
+const EnvelopeVersion = 1
+
+// envelope is the wire format of messages relayed through a Broker.
+type envelope struct {
+	V      int    `json:"v"`           // EnvelopeVersion
+	Origin string `json:"o"`           // instance ID of the publisher
+	Topic  string `json:"t"`           // Message.Topic
+	Event  string `json:"e,omitempty"` // Message.Event
+	ID     string `json:"i,omitempty"` // Message.ID
+	Data   []byte `json:"d,omitempty"` // Message.Data
+}
+
+// errEnvelope reports an envelope that cannot be decoded.
+var errEnvelope = errors.New("hub: invalid broker envelope")
+
+// Broker reconnect backoff bounds.
+const (
+	brokerMinBackoff = 100 * time.Millisecond
+	brokerMaxBackoff = 10 * time.Second
+)
+
+// startBroker starts relaying through h.cfg.Broker until h is closed.
+func (h *Hub) startBroker() {
+	if h.cfg.InstanceID == "" {
+		var b [8]byte
+		_, _ = rand.Read(b[:])
+		h.cfg.InstanceID = hex.EncodeToString(b[:])
+	}
+	if h.cfg.BrokerQueue <= 0 {
+		h.cfg.BrokerQueue = 1024
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	h.stopBroker = cancel
+	h.outbox = make(chan []byte, h.cfg.BrokerQueue)
+	h.brokerDone.Add(2)
+	go h.receiveLoop(ctx)
+	go h.sendLoop(ctx)
+}
+
+// relay queues m for the broker without blocking.
+func (h *Hub) relay(m Message) {
+	b, err := json.Marshal(envelope{V: EnvelopeVersion, Origin: h.cfg.InstanceID, Topic: m.Topic, Event: m.Event, ID: m.ID, Data: m.Data})
+	if err != nil {
+		h.brokerError(err)
+		return
+	}
+	select {
+	case h.outbox <- b:
+	default:
+		h.brokerDropped.Add(1)
+	}
+}
+
+// sendLoop publishes queued envelopes until ctx ends.
+func (h *Hub) sendLoop(ctx context.Context) {
+	defer h.brokerDone.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case b := <-h.outbox:
+			pctx, cancel := context.WithTimeout(ctx, h.cfg.WriteTimeout)
+			err := h.cfg.Broker.Publish(pctx, b)
+			cancel()
+			if err != nil {
+				h.brokerError(err)
+			} else {
+				h.brokerSent.Add(1)
+			}
+		}
+	}
+}
+
+// receiveLoop runs the broker, reconnecting with exponential backoff, until
+// ctx ends.
+func (h *Hub) receiveLoop(ctx context.Context) {
+	defer h.brokerDone.Done()
+	delay := brokerMinBackoff
+	for {
+		start := time.Now()
+		err := h.cfg.Broker.Run(ctx, h.receive)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			h.brokerError(err)
+		}
+		if time.Since(start) > brokerMaxBackoff {
+			delay = brokerMinBackoff
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		delay = min(delay*2, brokerMaxBackoff)
+	}
+}
+
+// receive delivers an envelope from the broker to local subscribers. The
+// hub's own envelopes were delivered when published and are skipped.
+func (h *Hub) receive(payload []byte) {
+	var e envelope
+	if err := json.Unmarshal(payload, &e); err != nil || e.V < 1 {
+		h.brokerDropped.Add(1)
+		h.brokerError(errEnvelope)
+		return
+	}
+	if e.V > EnvelopeVersion {
+		h.brokerDropped.Add(1)
+		return
+	}
+	if e.Origin == h.cfg.InstanceID {
+		return
+	}
+	h.brokerReceived.Add(1)
+	h.deliver(Message{Topic: e.Topic, Event: e.Event, ID: e.ID, Data: e.Data})
+}
+
+// brokerError counts err and reports it to OnBrokerError.
+func (h *Hub) brokerError(err error) {
+	h.brokerErrors.Add(1)
+	if h.cfg.OnBrokerError != nil {
+		h.cfg.OnBrokerError(err)
+	}
+}
@@ -0,0 +1,170 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memBroker is an in-memory Broker connecting several hubs.
+type memBroker struct {
+	mu    sync.Mutex
+	subs  map[chan []byte]struct{}
+	fails int // Run calls left that fail at once
+	runs  int
+}
+
+func newMemBroker() *memBroker { return &memBroker{subs: make(map[chan []byte]struct{})} }
+
+func (b *memBroker) Publish(_ context.Context, payload []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		ch <- payload
+	}
+	return nil
+}
+
+func (b *memBroker) Run(ctx context.Context, deliver func([]byte)) error {
+	b.mu.Lock()
+	b.runs++
+	if b.fails > 0 {
+		b.fails--
+		b.mu.Unlock()
+		return errors.New("connection refused")
+	}
+	ch := make(chan []byte, 16)
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		delete(b.subs, ch)
+		b.mu.Unlock()
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case p := <-ch:
+			deliver(p)
+		}
+	}
+}
+
+func (b *memBroker) connected() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBroker_RelaysBetweenHubs(t *testing.T) {
+	b := newMemBroker()
+	h1 := New(Config{Broker: b, InstanceID: "one"})
+	defer h1.Close()
+	h2 := New(Config{Broker: b})
+	defer h2.Close()
+	waitFor(t, "subscriptions", func() bool { return b.connected() == 2 })
+
+	s1 := h1.Subscribe("chat")
+	s2 := h2.Subscribe("chat")
+	h1.PublishMessage(Message{Topic: "chat", Event: "msg", ID: "9", Data: []byte("hi")})
+
+	if m := <-s1.C(); string(m.Data) != "hi" {
+		t.Fatalf("local subscriber got %+v", m)
+	}
+	m := <-s2.C()
+	if m.Topic != "chat" || m.Event != "msg" || m.ID != "9" || string(m.Data) != "hi" {
+		t.Fatalf("remote subscriber got %+v", m)
+	}
+	waitFor(t, "stats", func() bool { return h1.Stats().BrokerSent == 1 && h2.Stats().BrokerReceived == 1 })
+	select {
+	case m := <-s1.C():
+		t.Fatalf("own message delivered twice: %+v", m)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestBroker_Envelopes(t *testing.T) {
+	b := newMemBroker()
+	var errs []error
+	var mu sync.Mutex
+	h := New(Config{Broker: b, OnBrokerError: func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}})
+	defer h.Close()
+	waitFor(t, "subscription", func() bool { return b.connected() == 1 })
+	s := h.Subscribe("t")
+
+	newer, _ := json.Marshal(envelope{V: EnvelopeVersion + 1, Origin: "x", Topic: "t", Data: []byte("v2")})
+	current, _ := json.Marshal(envelope{V: EnvelopeVersion, Origin: "x", Topic: "t", Data: []byte("v1")})
+	_ = b.Publish(context.Background(), []byte("garbage"))
+	_ = b.Publish(context.Background(), newer)
+	_ = b.Publish(context.Background(), current)
+
+	if m := <-s.C(); string(m.Data) != "v1" {
+		t.Fatalf("got %+v", m)
+	}
+	st := h.Stats()
+	if st.BrokerDropped != 2 || st.BrokerErrors != 1 || st.BrokerReceived != 1 {
+		t.Fatalf("Stats = %+v", st)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 1 || !errors.Is(errs[0], errEnvelope) {
+		t.Fatalf("OnBrokerError got %v", errs)
+	}
+}
+
+func TestBroker_Reconnects(t *testing.T) {
+	b := newMemBroker()
+	b.fails = 2
+	h := New(Config{Broker: b})
+	defer h.Close()
+	waitFor(t, "reconnect", func() bool { return b.connected() == 1 })
+	if st := h.Stats(); st.BrokerErrors != 2 {
+		t.Fatalf("Stats = %+v", st)
+	}
+}
+
+func TestBroker_QueueFull(t *testing.T) {
+	block := make(chan struct{})
+	h := New(Config{Broker: blockingBroker(block), BrokerQueue: 1})
+	defer h.Close()
+	defer close(block)
+	for i := 0; i < 5; i++ {
+		h.Publish("t", nil)
+	}
+	waitFor(t, "drops", func() bool { return h.Stats().BrokerDropped >= 3 })
+}
+
+// blockingBroker publishes only once block is closed.
+type blockingBroker chan struct{}
+
+func (b blockingBroker) Publish(ctx context.Context, _ []byte) error {
+	select {
+	case <-b:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+func (b blockingBroker) Run(ctx context.Context, _ func([]byte)) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
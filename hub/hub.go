@@ -0,0 +1,376 @@
+// Package hub provides topic-based publish/subscribe for realtime fan-out to
+// Server-Sent Events and WebSocket clients, so small deployments do not need
+// a separate message broker.
+//
+// Every subscriber has its own bounded send queue. Publishing never blocks:
+// when a subscriber's queue is full, the hub applies the configured
+// SlowConsumerPolicy (drop the new message, drop the oldest queued one, or
+// close the subscriber), so one slow client cannot hold up the others.
+//
+// A hub serves the clients of one process. With several instances behind a
+// load balancer, set Config.Broker (e.g. a RedisBroker) so messages reach
+// clients connected to any instance.
+//
+// Example:
+//
+//	h := hub.New(hub.Config{QueueSize: 32, Policy: hub.CloseSlow})
+//	app.GET("/events/:room", func(c flash.Ctx) error {
+//		return h.ServeSSE(c, "room:"+c.Param("room"))
+//	})
+//	app.GET("/ws/:room", func(c flash.Ctx) error {
+//		return h.ServeWS(c, "room:"+c.Param("room"))
+//	})
+//	// elsewhere
+//	h.PublishJSON("room:42", chatMessage)
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Message is a published message.
+type Message struct {
+	Topic string // topic the message was published to
+	Event string // SSE event name; empty for the default "message" event
+	ID    string // SSE event ID, sent back by reconnecting clients as Last-Event-ID
+	Data  []byte // payload; sent as a text frame over WebSocket when valid UTF-8
+}
+
+// SlowConsumerPolicy decides what happens when a subscriber's queue is full.
+type SlowConsumerPolicy int
+
+const (
+	// DropNewest drops the message being published for that subscriber.
+	DropNewest SlowConsumerPolicy = iota
+	// DropOldest drops the oldest queued message to make room.
+	DropOldest
+	// CloseSlow closes the subscriber; ServeSSE and ServeWS then end the
+	// connection and the client is expected to reconnect.
+	CloseSlow
+)
+
+// Config configures a Hub.
+type Config struct {
+	// QueueSize is the number of messages queued per subscriber.
+	// If <= 0, defaults to 64.
+	QueueSize int
+
+	// Policy applies when a subscriber's queue is full. Defaults to DropNewest.
+	Policy SlowConsumerPolicy
+
+	// Heartbeat is the interval of SSE comment lines and WebSocket pings that
+	// keep idle connections open through proxies and detect dead clients.
+	// If 0, defaults to 30 seconds; negative disables heartbeats.
+	Heartbeat time.Duration
+
+	// WriteTimeout bounds each WebSocket write, so a client that stops
+	// reading is dropped, and each Broker publish. If <= 0, defaults to 10
+	// seconds.
+	WriteTimeout time.Duration
+
+	// Broker, when set, relays published messages to the hubs of other
+	// instances (see RedisBroker). Local subscribers still get messages
+	// published on this instance directly.
+	Broker Broker
+
+	// InstanceID identifies this hub in broker envelopes. If empty, a random
+	// ID is used.
+	InstanceID string
+
+	// BrokerQueue is the number of messages waiting to be sent to the
+	// Broker; more are dropped and counted in Stats.BrokerDropped.
+	// If <= 0, defaults to 1024.
+	BrokerQueue int
+
+	// OnBrokerError, when set, is called with Broker publish, connection and
+	// decoding errors, e.g. to log them.
+	OnBrokerError func(err error)
+
+	// CheckOrigin reports whether ServeWS accepts a handshake. Browsers send
+	// cookies with cross-site WebSocket handshakes, so by default handshakes
+	// whose Origin header names another host than the request's are
+	// rejected with 403; handshakes without Origin (non-browser clients) are
+	// accepted.
+	CheckOrigin func(r *http.Request) bool
+}
+
+// Stats is a snapshot of a Hub's counters.
+type Stats struct {
+	Topics      int    `json:"topics"`      // topics with at least one subscriber
+	Subscribers int    `json:"subscribers"` // open subscriptions
+	Published   uint64 `json:"published"`   // Publish calls
+	Delivered   uint64 `json:"delivered"`   // messages queued to subscribers
+	Dropped     uint64 `json:"dropped"`     // messages dropped by DropNewest or DropOldest
+	SlowClosed  uint64 `json:"slow_closed"` // subscribers closed by CloseSlow
+
+	BrokerSent     uint64 `json:"broker_sent"`     // messages sent to the Broker
+	BrokerReceived uint64 `json:"broker_received"` // messages received from other instances
+	BrokerDropped  uint64 `json:"broker_dropped"`  // messages dropped: full BrokerQueue, invalid or newer envelopes
+	BrokerErrors   uint64 `json:"broker_errors"`   // Broker publish, connection and decoding errors
+}
+
+// Hub routes published messages to the subscribers of their topic. It is
+// safe for concurrent use. Create it with New.
+type Hub struct {
+	cfg    Config
+	mu     sync.RWMutex
+	topics map[string]map[*Subscription]struct{}
+	subs   int
+	closed bool
+
+	published  atomic.Uint64
+	delivered  atomic.Uint64
+	dropped    atomic.Uint64
+	slowClosed atomic.Uint64
+
+	stopBroker     context.CancelFunc // stops the broker loops (nil without Broker)
+	brokerDone     sync.WaitGroup     // running broker loops
+	outbox         chan []byte        // envelopes waiting for the broker
+	brokerSent     atomic.Uint64
+	brokerReceived atomic.Uint64
+	brokerDropped  atomic.Uint64
+	brokerErrors   atomic.Uint64
+}
+
+// Subscription receives the messages of one or more topics. Read them from
+// C until Done is closed; call Close when finished.
+type Subscription struct {
+	hub     *Hub
+	topics  []string
+	ch      chan Message
+	done    chan struct{}
+	once    sync.Once
+	slow    atomic.Bool
+	dropped atomic.Uint64
+}
+
+// Default is the hub used by the package-level functions.
+var Default = New()
+
+// New returns a hub with no subscribers. An optional Config overrides the
+// defaults. With a Broker, relaying starts at once and runs until Close.
+func New(cfg ...Config) *Hub {
+	var c Config
+	if len(cfg) > 0 {
+		c = cfg[0]
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = 64
+	}
+	if c.Heartbeat == 0 {
+		c.Heartbeat = 30 * time.Second
+	}
+	if c.WriteTimeout <= 0 {
+		c.WriteTimeout = 10 * time.Second
+	}
+	h := &Hub{cfg: c, topics: make(map[string]map[*Subscription]struct{})}
+	if c.Broker != nil {
+		h.startBroker()
+	}
+	return h
+}
+
+// Subscribe returns a subscription to topics. After Close, the returned
+// subscription is already closed.
+func (h *Hub) Subscribe(topics ...string) *Subscription {
+	s := &Subscription{
+		hub:    h,
+		topics: append([]string(nil), topics...),
+		ch:     make(chan Message, h.cfg.QueueSize),
+		done:   make(chan struct{}),
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		s.once.Do(func() { close(s.done) })
+		return s
+	}
+	for _, t := range s.topics {
+		set := h.topics[t]
+		if set == nil {
+			set = make(map[*Subscription]struct{})
+			h.topics[t] = set
+		}
+		set[s] = struct{}{}
+	}
+	h.subs++
+	return s
+}
+
+// Publish sends data to every subscriber of topic and returns how many
+// subscribers it was queued for. It never blocks.
+func (h *Hub) Publish(topic string, data []byte) int {
+	return h.PublishMessage(Message{Topic: topic, Data: data})
+}
+
+// PublishJSON publishes v encoded as JSON.
+func (h *Hub) PublishJSON(topic string, v any) (int, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return 0, err
+	}
+	return h.Publish(topic, b), nil
+}
+
+// PublishMessage publishes m to the subscribers of m.Topic and returns how
+// many local subscribers it was queued for. It never blocks; full queues are
+// handled by the hub's SlowConsumerPolicy. With a Broker, m is also relayed
+// to the other instances.
+func (h *Hub) PublishMessage(m Message) int {
+	h.published.Add(1)
+	if h.outbox != nil {
+		h.relay(m)
+	}
+	return h.deliver(m)
+}
+
+// deliver queues m for the local subscribers of m.Topic.
+func (h *Hub) deliver(m Message) int {
+	var slow []*Subscription
+	n := 0
+	h.mu.RLock()
+	for s := range h.topics[m.Topic] {
+		if s.offer(m, h.cfg.Policy) {
+			n++
+		} else if h.cfg.Policy == CloseSlow {
+			slow = append(slow, s)
+		}
+	}
+	h.mu.RUnlock()
+	for _, s := range slow {
+		if s.slow.CompareAndSwap(false, true) {
+			h.slowClosed.Add(1)
+		}
+		s.Close()
+	}
+	h.delivered.Add(uint64(n))
+	return n
+}
+
+// Subscribers returns the number of subscribers of topic.
+func (h *Hub) Subscribers(topic string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.topics[topic])
+}
+
+// Stats returns a snapshot of the hub's counters.
+func (h *Hub) Stats() Stats {
+	h.mu.RLock()
+	s := Stats{Topics: len(h.topics), Subscribers: h.subs}
+	h.mu.RUnlock()
+	s.Published = h.published.Load()
+	s.Delivered = h.delivered.Load()
+	s.Dropped = h.dropped.Load()
+	s.SlowClosed = h.slowClosed.Load()
+	s.BrokerSent = h.brokerSent.Load()
+	s.BrokerReceived = h.brokerReceived.Load()
+	s.BrokerDropped = h.brokerDropped.Load()
+	s.BrokerErrors = h.brokerErrors.Load()
+	return s
+}
+
+// Close closes every subscription, ending their SSE and WebSocket
+// connections, e.g. during shutdown, and stops relaying through the Broker.
+// Later subscriptions are closed at once. The Broker itself is not closed.
+func (h *Hub) Close() {
+	if h.stopBroker != nil {
+		h.stopBroker()
+		h.brokerDone.Wait()
+	}
+	h.mu.Lock()
+	h.closed = true
+	var all []*Subscription
+	for _, set := range h.topics {
+		for s := range set {
+			all = append(all, s)
+		}
+	}
+	h.mu.Unlock()
+	for _, s := range all {
+		s.Close()
+	}
+}
+
+// C returns the channel delivering the subscription's messages. It is never
+// closed; select on Done as well.
+func (s *Subscription) C() <-chan Message { return s.ch }
+
+// Done is closed when the subscription is closed, by Close, Hub.Close or
+// the CloseSlow policy.
+func (s *Subscription) Done() <-chan struct{} { return s.done }
+
+// Slow reports whether the subscription was closed by the CloseSlow policy.
+func (s *Subscription) Slow() bool { return s.slow.Load() }
+
+// Dropped returns the number of messages dropped for this subscriber.
+func (s *Subscription) Dropped() uint64 { return s.dropped.Load() }
+
+// Close unsubscribes from every topic. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		close(s.done)
+		h := s.hub
+		h.mu.Lock()
+		for _, t := range s.topics {
+			if set := h.topics[t]; set != nil {
+				delete(set, s)
+				if len(set) == 0 {
+					delete(h.topics, t)
+				}
+			}
+		}
+		h.subs--
+		h.mu.Unlock()
+	})
+}
+
+// offer queues m without blocking and reports whether it was queued. With
+// DropOldest the oldest queued message makes room; with DropNewest or
+// DropOldest a dropped message is counted.
+func (s *Subscription) offer(m Message, p SlowConsumerPolicy) bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+	}
+	select {
+	case s.ch <- m:
+		return true
+	default:
+	}
+	if p == CloseSlow {
+		return false
+	}
+	if p == DropOldest {
+		select {
+		case <-s.ch:
+		default:
+		}
+		select {
+		case s.ch <- m:
+			s.drop()
+			return true
+		default:
+		}
+	}
+	s.drop()
+	return false
+}
+
+// drop counts a dropped message.
+func (s *Subscription) drop() {
+	s.dropped.Add(1)
+	s.hub.dropped.Add(1)
+}
+
+// Publish publishes data to topic on the Default hub.
+func Publish(topic string, data []byte) int { return Default.Publish(topic, data) }
+
+// PublishJSON publishes v as JSON to topic on the Default hub.
+func PublishJSON(topic string, v any) (int, error) { return Default.PublishJSON(topic, v) }
@@ -0,0 +1,146 @@
+package hub
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestPublishSubscribe(t *testing.T) {
+	h := New()
+	a := h.Subscribe("news")
+	b := h.Subscribe("news", "sport")
+	if n := h.Publish("news", []byte("hi")); n != 2 {
+		t.Fatalf("Publish = %d, want 2", n)
+	}
+	if n := h.Publish("sport", []byte("goal")); n != 1 {
+		t.Fatalf("Publish = %d, want 1", n)
+	}
+	if n := h.Publish("empty", []byte("x")); n != 0 {
+		t.Fatalf("Publish = %d, want 0", n)
+	}
+	if m := <-a.C(); string(m.Data) != "hi" || m.Topic != "news" {
+		t.Fatalf("a got %+v", m)
+	}
+	if m := <-b.C(); string(m.Data) != "hi" {
+		t.Fatalf("b got %+v", m)
+	}
+	if m := <-b.C(); string(m.Data) != "goal" {
+		t.Fatalf("b got %+v", m)
+	}
+
+	s := h.Stats()
+	if s.Topics != 2 || s.Subscribers != 2 || s.Published != 3 || s.Delivered != 3 {
+		t.Fatalf("Stats = %+v", s)
+	}
+	b.Close()
+	b.Close()
+	if h.Subscribers("sport") != 0 || h.Subscribers("news") != 1 || h.Stats().Subscribers != 1 {
+		t.Fatalf("Close should unsubscribe: %+v", h.Stats())
+	}
+	if h.Publish("sport", nil) != 0 {
+		t.Fatalf("closed subscriber should not receive")
+	}
+}
+
+func TestPublishJSON(t *testing.T) {
+	h := New()
+	s := h.Subscribe("t")
+	if _, err := h.PublishJSON("t", map[string]int{"n": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if m := <-s.C(); string(m.Data) != `{"n":1}` {
+		t.Fatalf("data = %s", m.Data)
+	}
+	if _, err := h.PublishJSON("t", func() {}); err == nil {
+		t.Fatalf("unencodable value should fail")
+	}
+}
+
+func TestSlowConsumerPolicies(t *testing.T) {
+	drain := func(s *Subscription) (out []string) {
+		for {
+			select {
+			case m := <-s.C():
+				out = append(out, string(m.Data))
+			default:
+				return out
+			}
+		}
+	}
+
+	h := New(Config{QueueSize: 2, Policy: DropNewest})
+	s := h.Subscribe("t")
+	for _, d := range []string{"1", "2", "3"} {
+		h.Publish("t", []byte(d))
+	}
+	if got := drain(s); len(got) != 2 || got[0] != "1" || got[1] != "2" || s.Dropped() != 1 {
+		t.Fatalf("DropNewest: got %v, dropped %d", got, s.Dropped())
+	}
+
+	h = New(Config{QueueSize: 2, Policy: DropOldest})
+	s = h.Subscribe("t")
+	for _, d := range []string{"1", "2", "3"} {
+		h.Publish("t", []byte(d))
+	}
+	if got := drain(s); len(got) != 2 || got[0] != "2" || got[1] != "3" || h.Stats().Dropped != 1 {
+		t.Fatalf("DropOldest: got %v, stats %+v", got, h.Stats())
+	}
+
+	h = New(Config{QueueSize: 1, Policy: CloseSlow})
+	slow := h.Subscribe("t")
+	fast := h.Subscribe("t")
+	h.Publish("t", []byte("1"))
+	<-fast.C()
+	if n := h.Publish("t", []byte("2")); n != 1 {
+		t.Fatalf("Publish = %d, want 1", n)
+	}
+	select {
+	case <-slow.Done():
+	default:
+		t.Fatalf("slow subscriber should be closed")
+	}
+	if !slow.Slow() || fast.Slow() || h.Stats().SlowClosed != 1 || h.Subscribers("t") != 1 {
+		t.Fatalf("slow=%v fast=%v stats=%+v", slow.Slow(), fast.Slow(), h.Stats())
+	}
+}
+
+func TestHubClose(t *testing.T) {
+	h := New()
+	s := h.Subscribe("t")
+	h.Close()
+	<-s.Done()
+	late := h.Subscribe("t")
+	<-late.Done()
+	late.Close()
+	if st := h.Stats(); st.Subscribers != 0 || st.Topics != 0 {
+		t.Fatalf("Stats = %+v", st)
+	}
+}
+
+func TestConcurrentPublish(t *testing.T) {
+	h := New(Config{QueueSize: 4, Policy: CloseSlow})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			s := h.Subscribe("t")
+			for j := 0; j < 50; j++ {
+				select {
+				case <-s.C():
+				case <-s.Done():
+					return
+				}
+			}
+			s.Close()
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				h.Publish("t", []byte("x"))
+			}
+		}()
+	}
+	h.Close()
+	wg.Wait()
+}
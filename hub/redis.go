@@ -0,0 +1,308 @@
+package hub
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RedisConfig configures a RedisBroker.
+type RedisConfig struct {
+	// Addr is the Redis server address. Defaults to "localhost:6379".
+	Addr string
+
+	// Username and Password authenticate with AUTH when Password is set;
+	// Username is only sent for Redis 6 ACL users.
+	Username string
+	Password string
+
+	// Channel is the Redis Pub/Sub channel carrying the hub messages. Use a
+	// different channel per application sharing a Redis server.
+	// Defaults to "flash:hub".
+	Channel string
+
+	// Dial opens connections, e.g. with TLS. Defaults to a net.Dialer.
+	Dial func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// Timeout bounds connecting and authenticating. Defaults to 5 seconds.
+	Timeout time.Duration
+
+	// PingInterval is how often an idle subscription is checked with PING,
+	// so a silently dropped connection is detected and replaced.
+	// Defaults to 30 seconds.
+	PingInterval time.Duration
+}
+
+// RedisBroker is a Broker using Redis Pub/Sub, so every instance's hub
+// receives the messages published on any of them. It speaks the Redis
+// protocol directly and needs no client library. Redis Pub/Sub does not
+// store messages: those published while an instance is reconnecting are
+// lost for its clients.
+//
+// Example:
+//
+//	h := hub.New(hub.Config{
+//		Broker: hub.NewRedisBroker(hub.RedisConfig{Addr: "redis:6379", Channel: "chat:hub"}),
+//		OnBrokerError: func(err error) { slog.Warn("hub broker", "err", err) },
+//	})
+//	defer h.Close()
+type RedisBroker struct {
+	cfg RedisConfig
+
+	mu  sync.Mutex // guards pub
+	pub *redisConn // connection for PUBLISH, dialed on demand
+}
+
+// NewRedisBroker returns a broker for the Redis server in cfg. No connection
+// is made until the hub uses it.
+func NewRedisBroker(cfg RedisConfig) *RedisBroker {
+	if cfg.Addr == "" {
+		cfg.Addr = "localhost:6379"
+	}
+	if cfg.Channel == "" {
+		cfg.Channel = "flash:hub"
+	}
+	if cfg.Dial == nil {
+		var d net.Dialer
+		cfg.Dial = d.DialContext
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.PingInterval <= 0 {
+		cfg.PingInterval = 30 * time.Second
+	}
+	return &RedisBroker{cfg: cfg}
+}
+
+// Publish sends payload to the channel with PUBLISH. After a failure the
+// connection is dropped and the next call dials again.
+func (b *RedisBroker) Publish(ctx context.Context, payload []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.pub == nil {
+		rc, err := b.connect(ctx)
+		if err != nil {
+			return err
+		}
+		b.pub = rc
+	}
+	if d, ok := ctx.Deadline(); ok {
+		_ = b.pub.conn.SetDeadline(d)
+	} else {
+		_ = b.pub.conn.SetDeadline(time.Time{})
+	}
+	if _, err := b.pub.do([]byte("PUBLISH"), []byte(b.cfg.Channel), payload); err != nil {
+		_ = b.pub.conn.Close()
+		b.pub = nil
+		return err
+	}
+	return nil
+}
+
+// Run subscribes to the channel and delivers its messages until ctx ends or
+// the connection fails.
+func (b *RedisBroker) Run(ctx context.Context, deliver func(payload []byte)) error {
+	rc, err := b.connect(ctx)
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { _ = rc.conn.Close() })
+	defer stop()
+	defer rc.conn.Close()
+
+	var wmu sync.Mutex // serializes SUBSCRIBE and PING writes
+	send := func(args ...[]byte) error {
+		wmu.Lock()
+		defer wmu.Unlock()
+		_ = rc.conn.SetWriteDeadline(time.Now().Add(b.cfg.Timeout))
+		return rc.write(args...)
+	}
+	if err := send([]byte("SUBSCRIBE"), []byte(b.cfg.Channel)); err != nil {
+		return err
+	}
+
+	pingDone := make(chan struct{})
+	defer close(pingDone)
+	go func() {
+		t := time.NewTicker(b.cfg.PingInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-pingDone:
+				return
+			case <-t.C:
+				if send([]byte("PING")) != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		_ = rc.conn.SetReadDeadline(time.Now().Add(2*b.cfg.PingInterval + b.cfg.Timeout))
+		v, err := rc.read()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if e, ok := v.(redisError); ok {
+			return e
+		}
+		msg, ok := v.([]any)
+		if !ok || len(msg) < 2 {
+			continue
+		}
+		kind, _ := msg[0].([]byte)
+		switch string(kind) {
+		case "message":
+			if len(msg) == 3 {
+				if p, ok := msg[2].([]byte); ok {
+					deliver(p)
+				}
+			}
+		case "subscribe", "pong":
+		default:
+			return fmt.Errorf("hub: unexpected redis reply %q", kind)
+		}
+	}
+}
+
+// Close closes the publishing connection. Subscriptions end with the
+// context passed to Run (see Hub.Close).
+func (b *RedisBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.pub == nil {
+		return nil
+	}
+	err := b.pub.conn.Close()
+	b.pub = nil
+	return err
+}
+
+// connect dials the server and authenticates.
+func (b *RedisBroker) connect(ctx context.Context) (*redisConn, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.cfg.Timeout)
+	defer cancel()
+	conn, err := b.cfg.Dial(ctx, "tcp", b.cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	rc := &redisConn{conn: conn, br: bufio.NewReader(conn), bw: bufio.NewWriter(conn)}
+	if b.cfg.Password != "" {
+		if d, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(d)
+		}
+		args := [][]byte{[]byte("AUTH"), []byte(b.cfg.Password)}
+		if b.cfg.Username != "" {
+			args = [][]byte{[]byte("AUTH"), []byte(b.cfg.Username), []byte(b.cfg.Password)}
+		}
+		if _, err := rc.do(args...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		_ = conn.SetDeadline(time.Time{})
+	}
+	return rc, nil
+}
+
+// redisError is an error reply from the server.
+type redisError string
+
+func (e redisError) Error() string { return "hub: redis: " + string(e) }
+
+// redisConn is a connection speaking RESP2.
+type redisConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	bw   *bufio.Writer
+}
+
+// do sends a command and reads its reply; error replies are returned as
+// errors.
+func (rc *redisConn) do(args ...[]byte) (any, error) {
+	if err := rc.write(args...); err != nil {
+		return nil, err
+	}
+	v, err := rc.read()
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := v.(redisError); ok {
+		return nil, e
+	}
+	return v, nil
+}
+
+// write sends a command as an array of bulk strings.
+func (rc *redisConn) write(args ...[]byte) error {
+	rc.bw.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, a := range args {
+		rc.bw.WriteString("$" + strconv.Itoa(len(a)) + "\r\n")
+		rc.bw.Write(a)
+		rc.bw.WriteString("\r\n")
+	}
+	return rc.bw.Flush()
+}
+
+// maxRedisBulk bounds bulk replies, like Redis' own proto-max-bulk-len.
+const maxRedisBulk = 512 << 20
+
+// read parses one reply: simple strings and bulk strings as []byte (nil for
+// null), integers as int64, errors as redisError and arrays as []any.
+func (rc *redisConn) read() (any, error) {
+	line, err := rc.br.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.New("hub: malformed redis reply")
+	}
+	kind, body := line[0], string(line[1:len(line)-2])
+	switch kind {
+	case '+':
+		return []byte(body), nil
+	case '-':
+		return redisError(body), nil
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n > maxRedisBulk {
+			return nil, errors.New("hub: malformed redis bulk length")
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		p := make([]byte, n+2)
+		if _, err := io.ReadFull(rc.br, p); err != nil {
+			return nil, err
+		}
+		return p[:n], nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n > 1<<20 {
+			return nil, errors.New("hub: malformed redis array length")
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		arr := make([]any, n)
+		for i := range arr {
+			if arr[i], err = rc.read(); err != nil {
+				return nil, err
+			}
+		}
+		return arr, nil
+	}
+	return nil, fmt.Errorf("hub: unknown redis reply type %q", kind)
+}
@@ -0,0 +1,208 @@
+package hub
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is a minimal Redis server supporting AUTH, PUBLISH, SUBSCRIBE
+// and PING.
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+	mu       sync.Mutex
+	subs     map[string][]net.Conn
+	conns    []net.Conn
+	cmds     []string
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{ln: ln, password: password, subs: make(map[string][]net.Conn)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.conns = append(f.conns, conn)
+			f.mu.Unlock()
+			go f.serve(conn)
+		}
+	}()
+	t.Cleanup(f.close)
+	return f
+}
+
+func (f *fakeRedis) addr() string { return f.ln.Addr().String() }
+
+func (f *fakeRedis) close() {
+	f.ln.Close()
+	f.dropConns()
+}
+
+// dropConns closes every client connection, as a Redis restart would.
+func (f *fakeRedis) dropConns() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.conns {
+		c.Close()
+	}
+	f.conns = nil
+	f.subs = make(map[string][]net.Conn)
+}
+
+func (f *fakeRedis) subscribers(channel string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subs[channel])
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	rc := &redisConn{conn: conn, br: bufio.NewReader(conn), bw: bufio.NewWriter(conn)}
+	authed := f.password == ""
+	for {
+		v, err := rc.read()
+		if err != nil {
+			return
+		}
+		arr, _ := v.([]any)
+		var args []string
+		for _, a := range arr {
+			b, _ := a.([]byte)
+			args = append(args, string(b))
+		}
+		if len(args) == 0 {
+			return
+		}
+		f.mu.Lock()
+		f.cmds = append(f.cmds, strings.Join(args, " "))
+		f.mu.Unlock()
+		var reply string
+		switch cmd := strings.ToUpper(args[0]); {
+		case cmd == "AUTH":
+			if args[len(args)-1] == f.password {
+				authed = true
+				reply = "+OK\r\n"
+			} else {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case cmd == "SUBSCRIBE":
+			f.mu.Lock()
+			f.subs[args[1]] = append(f.subs[args[1]], conn)
+			f.mu.Unlock()
+			reply = "*3\r\n$9\r\nsubscribe\r\n" + bulk(args[1]) + ":1\r\n"
+		case cmd == "PING":
+			reply = "*2\r\n$4\r\npong\r\n$0\r\n\r\n"
+		case cmd == "PUBLISH":
+			f.mu.Lock()
+			subs := append([]net.Conn(nil), f.subs[args[1]]...)
+			f.mu.Unlock()
+			for _, s := range subs {
+				_, _ = s.Write([]byte("*3\r\n$7\r\nmessage\r\n" + bulk(args[1]) + bulk(args[2])))
+			}
+			reply = ":" + strconv.Itoa(len(subs)) + "\r\n"
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func bulk(s string) string { return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n" }
+
+func TestRedisBroker_PublishRun(t *testing.T) {
+	srv := newFakeRedis(t, "s3cret")
+	b := NewRedisBroker(RedisConfig{Addr: srv.addr(), Username: "app", Password: "s3cret", Channel: "test:hub", PingInterval: 10 * time.Millisecond})
+	defer b.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	got := make(chan string, 4)
+	done := make(chan error)
+	go func() { done <- b.Run(ctx, func(p []byte) { got <- string(p) }) }()
+	waitFor(t, "SUBSCRIBE", func() bool { return srv.subscribers("test:hub") == 1 })
+
+	if err := b.Publish(context.Background(), []byte("hello\r\nworld")); err != nil {
+		t.Fatal(err)
+	}
+	if p := <-got; p != "hello\r\nworld" {
+		t.Fatalf("delivered %q", p)
+	}
+	time.Sleep(30 * time.Millisecond) // let a few PINGs through
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Run = %v, want context.Canceled", err)
+	}
+	srv.mu.Lock()
+	cmds := strings.Join(srv.cmds, "\n")
+	srv.mu.Unlock()
+	if !strings.Contains(cmds, "AUTH app s3cret") || !strings.Contains(cmds, "PING") {
+		t.Fatalf("commands:\n%s", cmds)
+	}
+}
+
+func TestRedisBroker_Errors(t *testing.T) {
+	srv := newFakeRedis(t, "s3cret")
+	b := NewRedisBroker(RedisConfig{Addr: srv.addr(), Password: "wrong"})
+	if err := b.Publish(context.Background(), []byte("x")); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Fatalf("Publish = %v", err)
+	}
+	b = NewRedisBroker(RedisConfig{Addr: srv.addr()})
+	if err := b.Run(context.Background(), func([]byte) {}); err == nil || !strings.Contains(err.Error(), "NOAUTH") {
+		t.Fatalf("Run = %v", err)
+	}
+}
+
+func TestRedisBroker_HubsReconnect(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	cfg := RedisConfig{Addr: srv.addr(), Channel: "chat"}
+	h1 := New(Config{Broker: NewRedisBroker(cfg)})
+	defer h1.Close()
+	h2 := New(Config{Broker: NewRedisBroker(cfg)})
+	defer h2.Close()
+	waitFor(t, "subscriptions", func() bool { return srv.subscribers("chat") == 2 })
+
+	s := h2.Subscribe("room")
+	h1.Publish("room", []byte("before"))
+	if m := <-s.C(); string(m.Data) != "before" {
+		t.Fatalf("got %+v", m)
+	}
+
+	srv.dropConns()
+	waitFor(t, "resubscribe", func() bool { return srv.subscribers("chat") == 2 })
+	// The first publish may fail on the dropped connection; the next redials.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		h1.Publish("room", []byte("after"))
+		select {
+		case m := <-s.C():
+			if string(m.Data) != "after" {
+				t.Fatalf("got %+v", m)
+			}
+			if h1.Stats().BrokerErrors == 0 && h2.Stats().BrokerErrors == 0 {
+				t.Fatalf("dropped connections should be reported")
+			}
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no delivery after reconnect: %+v", h1.Stats())
+		}
+	}
+}
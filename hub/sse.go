@@ -0,0 +1,90 @@
+package hub
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+// ServeSSE streams the messages of topics to the client as Server-Sent
+// Events until the client disconnects or the subscription is closed. Each
+// message becomes one event with its ID, Event name and Data (multi-line
+// data is split into several data lines). Comment lines are sent every
+// Heartbeat. It returns nil when the stream ends.
+//
+// Example:
+//
+//	app.GET("/events", func(c flash.Ctx) error {
+//		return h.ServeSSE(c, "news", "user:"+userID(c))
+//	})
+func (h *Hub) ServeSSE(c flash.Ctx, topics ...string) error {
+	sub := h.Subscribe(topics...)
+	defer sub.Close()
+
+	w := c.ResponseWriter()
+	rc := http.NewResponseController(w)
+	hdr := w.Header()
+	hdr.Set("Content-Type", "text/event-stream")
+	hdr.Set("Cache-Control", "no-cache")
+	hdr.Set("X-Accel-Buffering", "no") // disable proxy buffering (nginx)
+	c.Status(http.StatusOK)
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return err
+	}
+
+	var tick <-chan time.Time
+	if h.cfg.Heartbeat > 0 {
+		t := time.NewTicker(h.cfg.Heartbeat)
+		defer t.Stop()
+		tick = t.C
+	}
+	var buf bytes.Buffer
+	for {
+		buf.Reset()
+		select {
+		case <-c.Context().Done():
+			return nil
+		case <-sub.Done():
+			return nil
+		case <-tick:
+			buf.WriteString(": ping\n\n")
+		case m := <-sub.C():
+			writeEvent(&buf, m)
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return nil
+		}
+		if err := rc.Flush(); err != nil {
+			return nil
+		}
+	}
+}
+
+// writeEvent formats m as an SSE event.
+func writeEvent(buf *bytes.Buffer, m Message) {
+	if m.ID != "" {
+		buf.WriteString("id: " + oneLine(m.ID) + "\n")
+	}
+	if m.Event != "" {
+		buf.WriteString("event: " + oneLine(m.Event) + "\n")
+	}
+	// SSE parsers end a line at "\r\n", "\n" or a lone "\r".
+	data := strings.ReplaceAll(string(m.Data), "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		buf.WriteString("data: " + line + "\n")
+	}
+	buf.WriteByte('\n')
+}
+
+// oneLine strips line breaks, which would end an SSE field early.
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// ServeSSE streams topics of the Default hub; see Hub.ServeSSE.
+func ServeSSE(c flash.Ctx, topics ...string) error { return Default.ServeSSE(c, topics...) }
@@ -0,0 +1,86 @@
+package hub
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+func TestWriteEvent(t *testing.T) {
+	var buf bytes.Buffer
+	writeEvent(&buf, Message{ID: "7", Event: "up\ndate", Data: []byte("a\r\nb")})
+	if want := "id: 7\nevent: update\ndata: a\ndata: b\n\n"; buf.String() != want {
+		t.Fatalf("event = %q, want %q", buf.String(), want)
+	}
+
+	// A lone CR ends a line too, so it must not let data inject fields.
+	buf.Reset()
+	writeEvent(&buf, Message{Data: []byte("x\revent: admin\r\n\ry")})
+	if want := "data: x\ndata: event: admin\ndata: \ndata: y\n\n"; buf.String() != want {
+		t.Fatalf("event = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestServeSSE(t *testing.T) {
+	h := New(Config{Heartbeat: -1})
+	a := flash.New()
+	a.GET("/events", func(c flash.Ctx) error { return h.ServeSSE(c, "news") })
+	srv := httptest.NewServer(a)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	deadline := time.Now().Add(time.Second)
+	for h.Subscribers("news") == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	h.PublishMessage(Message{Topic: "news", Event: "headline", Data: []byte("hello")})
+
+	r := bufio.NewReader(resp.Body)
+	var got []string
+	for len(got) < 3 {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read: %v (got %q)", err, got)
+		}
+		got = append(got, strings.TrimSuffix(line, "\n"))
+	}
+	if strings.Join(got, "|") != "event: headline|data: hello|" {
+		t.Fatalf("stream = %q", got)
+	}
+
+	h.Close()
+	if _, err := r.ReadString('\n'); err == nil {
+		t.Fatalf("closing the hub should end the stream")
+	}
+}
+
+func TestServeSSE_Heartbeat(t *testing.T) {
+	h := New(Config{Heartbeat: 5 * time.Millisecond})
+	a := flash.New()
+	a.GET("/events", func(c flash.Ctx) error { return h.ServeSSE(c, "x") })
+	srv := httptest.NewServer(a)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || line != ": ping\n" {
+		t.Fatalf("heartbeat = %q, %v", line, err)
+	}
+}
@@ -0,0 +1,301 @@
+package hub
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/goflash/flash/v2"
+)
+
+// WebSocket opcodes and close codes (RFC 6455).
+const (
+	opText   = 0x1
+	opBinary = 0x2
+	opClose  = 0x8
+	opPing   = 0x9
+	opPong   = 0xA
+
+	closeNormal        = 1000
+	closeGoingAway     = 1001
+	closeProtocolError = 1002
+	closeTryAgainLater = 1013
+)
+
+// wsGUID is appended to the client key to compute Sec-WebSocket-Accept.
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxClientFrame bounds the payload of frames read from clients. Clients of
+// a broadcast stream only send control frames and small messages.
+const maxClientFrame = 64 << 10
+
+// ServeWS upgrades the request to a WebSocket and sends the messages of
+// topics to the client until either side closes the connection or the
+// subscription is closed. Messages are sent as text frames, or binary frames
+// when the data is not valid UTF-8. Pings are sent every Heartbeat; client
+// pings are answered and other client messages are discarded. Subscribers
+// closed by the CloseSlow policy get close code 1013 (try again later).
+//
+// Requests that are not WebSocket handshakes get 400 Bad Request (426
+// Upgrade Required for unsupported protocol versions), and handshakes
+// rejected by Config.CheckOrigin (by default, cross-origin ones) get 403
+// Forbidden. Once the connection is upgraded, ServeWS returns nil.
+//
+// Example:
+//
+//	app.GET("/ws", func(c flash.Ctx) error {
+//		return h.ServeWS(c, "prices")
+//	})
+func (h *Hub) ServeWS(c flash.Ctx, topics ...string) error {
+	conn, brw, err := upgrade(c, h.cfg.CheckOrigin)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	sub := h.Subscribe(topics...)
+	defer sub.Close()
+
+	ws := &wsConn{conn: conn, bw: brw.Writer, timeout: h.cfg.WriteTimeout}
+	clientDone := make(chan struct{})
+	go func() {
+		defer close(clientDone)
+		ws.readLoop(brw.Reader)
+	}()
+
+	var tick <-chan time.Time
+	if h.cfg.Heartbeat > 0 {
+		t := time.NewTicker(h.cfg.Heartbeat)
+		defer t.Stop()
+		tick = t.C
+	}
+	for {
+		select {
+		case <-clientDone:
+			return nil
+		case <-c.Context().Done():
+			ws.close(closeGoingAway)
+			return nil
+		case <-sub.Done():
+			code := closeGoingAway
+			if sub.Slow() {
+				code = closeTryAgainLater
+			}
+			ws.close(code)
+			return nil
+		case <-tick:
+			if ws.write(opPing, nil) != nil {
+				return nil
+			}
+		case m := <-sub.C():
+			op := byte(opText)
+			if !utf8.Valid(m.Data) {
+				op = opBinary
+			}
+			if ws.write(op, m.Data) != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// upgrade validates the WebSocket handshake, checks its origin with
+// checkOrigin (sameOrigin if nil), hijacks the connection and writes the 101
+// response.
+func upgrade(c flash.Ctx, checkOrigin func(*http.Request) bool) (net.Conn, *bufio.ReadWriter, error) {
+	r := c.Request()
+	if r.Method != http.MethodGet ||
+		!strings.EqualFold(r.Header.Get("Upgrade"), "websocket") ||
+		!headerHasToken(r.Header, "Connection", "upgrade") {
+		return nil, nil, flash.NewError(http.StatusBadRequest, "hub: not a WebSocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		c.Header("Sec-WebSocket-Version", "13")
+		return nil, nil, flash.NewError(http.StatusUpgradeRequired, "hub: unsupported WebSocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, nil, flash.NewError(http.StatusBadRequest, "hub: missing Sec-WebSocket-Key")
+	}
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, nil, flash.NewError(http.StatusForbidden, "hub: cross-origin WebSocket handshake")
+	}
+	conn, brw, err := http.NewResponseController(c.ResponseWriter()).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	c.Status(http.StatusSwitchingProtocols)
+	sum := sha1.Sum([]byte(key + wsGUID))
+	_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " +
+		base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := brw.Flush(); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, brw, nil
+}
+
+// sameOrigin reports whether r has no Origin header or one whose host equals
+// the request's Host.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, r.Host)
+}
+
+// headerHasToken reports whether the comma-separated header key contains
+// token, case-insensitively.
+func headerHasToken(h http.Header, key, token string) bool {
+	for _, v := range h.Values(key) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// wsConn writes frames to a server-side WebSocket connection.
+type wsConn struct {
+	conn    net.Conn
+	mu      sync.Mutex // serializes frame writes
+	bw      *bufio.Writer
+	timeout time.Duration
+	closed  bool
+}
+
+// write sends one unmasked, unfragmented frame.
+func (ws *wsConn) write(op byte, payload []byte) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.closed {
+		return net.ErrClosed
+	}
+	if op == opClose {
+		ws.closed = true
+	}
+	var hdr [10]byte
+	hdr[0] = 0x80 | op
+	n := 2
+	switch l := len(payload); {
+	case l < 126:
+		hdr[1] = byte(l)
+	case l <= 0xFFFF:
+		hdr[1] = 126
+		binary.BigEndian.PutUint16(hdr[2:], uint16(l))
+		n = 4
+	default:
+		hdr[1] = 127
+		binary.BigEndian.PutUint64(hdr[2:], uint64(l))
+		n = 10
+	}
+	_ = ws.conn.SetWriteDeadline(time.Now().Add(ws.timeout))
+	_, _ = ws.bw.Write(hdr[:n])
+	_, _ = ws.bw.Write(payload)
+	return ws.bw.Flush()
+}
+
+// close sends a close frame with code.
+func (ws *wsConn) close(code int) {
+	var p [2]byte
+	binary.BigEndian.PutUint16(p[:], uint16(code))
+	_ = ws.write(opClose, p[:])
+}
+
+// readLoop reads client frames until the connection fails or the client
+// closes it. Pings are answered, close frames are echoed and data frames
+// are discarded.
+func (ws *wsConn) readLoop(br *bufio.Reader) {
+	for {
+		op, payload, err := readFrame(br)
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				ws.close(closeProtocolError)
+			}
+			return
+		}
+		switch op {
+		case opPing:
+			if ws.write(opPong, payload) != nil {
+				return
+			}
+		case opClose:
+			code := closeNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			ws.close(code)
+			return
+		}
+	}
+}
+
+// errProtocol reports a malformed or oversized client frame.
+var errProtocol = errors.New("hub: WebSocket protocol error")
+
+// readFrame reads one client frame. Only control frame payloads are kept;
+// data payloads are discarded as they are read.
+func readFrame(br *bufio.Reader) (byte, []byte, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	op := hdr[0] & 0x0F
+	if hdr[1]&0x80 == 0 {
+		return 0, nil, errProtocol // client frames must be masked
+	}
+	length := uint64(hdr[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(br, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(br, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	control := op&0x8 != 0
+	if control && (length > 125 || hdr[0]&0x80 == 0) || length > maxClientFrame {
+		return 0, nil, errProtocol
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(br, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	if !control {
+		_, err := io.CopyN(io.Discard, br, int64(length))
+		return op, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return op, payload, nil
+}
+
+// ServeWS serves topics of the Default hub over a WebSocket; see Hub.ServeWS.
+func ServeWS(c flash.Ctx, topics ...string) error { return Default.ServeWS(c, topics...) }
@@ -0,0 +1,257 @@
+package hub
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+// wsClient is a minimal WebSocket client for the tests.
+type wsClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialWS(t *testing.T, url string) *wsClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+	_, _ = io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: x\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	// Example key and accept value from RFC 6455, section 1.3.
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Sec-WebSocket-Accept = %q", got)
+	}
+	return &wsClient{conn: conn, br: br}
+}
+
+// send writes a masked client frame.
+func (c *wsClient) send(op byte, payload []byte) {
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x80 | op, 0x80 | byte(len(payload))}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, _ = c.conn.Write(frame)
+}
+
+// read returns the next server frame.
+func (c *wsClient) read(t *testing.T) (byte, []byte) {
+	t.Helper()
+	var hdr [2]byte
+	if _, err := io.ReadFull(c.br, hdr[:]); err != nil {
+		t.Fatal(err)
+	}
+	n := int(hdr[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		_, _ = io.ReadFull(c.br, ext[:])
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		_, _ = io.ReadFull(c.br, ext[:])
+		n = int(binary.BigEndian.Uint64(ext[:]))
+	}
+	p := make([]byte, n)
+	if _, err := io.ReadFull(c.br, p); err != nil {
+		t.Fatal(err)
+	}
+	return hdr[0] & 0x0F, p
+}
+
+func waitSubscribers(t *testing.T, h *Hub, topic string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for h.Subscribers(topic) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Subscribers(%q) = %d, want %d", topic, h.Subscribers(topic), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func newWSServer(h *Hub) *httptest.Server {
+	a := flash.New()
+	a.GET("/ws", func(c flash.Ctx) error { return h.ServeWS(c, "prices") })
+	return httptest.NewServer(a)
+}
+
+func TestServeWS(t *testing.T) {
+	h := New(Config{Heartbeat: -1})
+	srv := newWSServer(h)
+	defer srv.Close()
+	c := dialWS(t, srv.URL)
+	defer c.conn.Close()
+	waitSubscribers(t, h, "prices", 1)
+
+	h.Publish("prices", []byte("42"))
+	if op, p := c.read(t); op != opText || string(p) != "42" {
+		t.Fatalf("frame = %x %q", op, p)
+	}
+	long := strings.Repeat("x", 300)
+	h.Publish("prices", []byte(long))
+	if op, p := c.read(t); op != opText || string(p) != long {
+		t.Fatalf("long frame = %x len %d", op, len(p))
+	}
+	h.Publish("prices", []byte{0xff, 0xfe})
+	if op, _ := c.read(t); op != opBinary {
+		t.Fatalf("invalid UTF-8 should be sent as binary, got %x", op)
+	}
+
+	c.send(opText, []byte("ignored"))
+	c.send(opPing, []byte("p"))
+	if op, p := c.read(t); op != opPong || string(p) != "p" {
+		t.Fatalf("pong = %x %q", op, p)
+	}
+
+	c.send(opClose, []byte{0x03, 0xe8})
+	if op, p := c.read(t); op != opClose || binary.BigEndian.Uint16(p) != closeNormal {
+		t.Fatalf("close = %x %v", op, p)
+	}
+	waitSubscribers(t, h, "prices", 0)
+}
+
+func TestServeWS_SlowConsumerClosed(t *testing.T) {
+	h := New(Config{Heartbeat: -1, QueueSize: 1, Policy: CloseSlow})
+	srv := newWSServer(h)
+	defer srv.Close()
+	c := dialWS(t, srv.URL)
+	defer c.conn.Close()
+	waitSubscribers(t, h, "prices", 1)
+
+	// Close the subscription directly as CloseSlow would.
+	h.mu.RLock()
+	var sub *Subscription
+	for s := range h.topics["prices"] {
+		sub = s
+	}
+	h.mu.RUnlock()
+	sub.slow.Store(true)
+	sub.Close()
+
+	op, p := c.read(t)
+	for op != opClose {
+		op, p = c.read(t)
+	}
+	if binary.BigEndian.Uint16(p) != closeTryAgainLater {
+		t.Fatalf("close code = %d", binary.BigEndian.Uint16(p))
+	}
+}
+
+func TestServeWS_Ping(t *testing.T) {
+	h := New(Config{Heartbeat: 5 * time.Millisecond})
+	srv := newWSServer(h)
+	defer srv.Close()
+	c := dialWS(t, srv.URL)
+	defer c.conn.Close()
+	if op, _ := c.read(t); op != opPing {
+		t.Fatalf("want ping, got %x", op)
+	}
+}
+
+func TestServeWS_ProtocolError(t *testing.T) {
+	h := New(Config{Heartbeat: -1})
+	srv := newWSServer(h)
+	defer srv.Close()
+	c := dialWS(t, srv.URL)
+	defer c.conn.Close()
+	_, _ = c.conn.Write([]byte{0x81, 0x01, 'x'}) // unmasked client frame
+	if op, p := c.read(t); op != opClose || binary.BigEndian.Uint16(p) != closeProtocolError {
+		t.Fatalf("close = %x %v", op, p)
+	}
+}
+
+func TestServeWS_BadHandshake(t *testing.T) {
+	h := New()
+	srv := newWSServer(h)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("plain GET: status = %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/ws", nil)
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Version", "8")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUpgradeRequired || resp.Header.Get("Sec-WebSocket-Version") != "13" {
+		t.Fatalf("old version: status = %d", resp.StatusCode)
+	}
+}
+
+func TestServeWS_CheckOrigin(t *testing.T) {
+	handshake := func(srv *httptest.Server, origin string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/ws", nil)
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	srv := newWSServer(New())
+	defer srv.Close()
+	if code := handshake(srv, "https://evil.example"); code != http.StatusForbidden {
+		t.Fatalf("cross-origin: status = %d", code)
+	}
+	if code := handshake(srv, "null"); code != http.StatusForbidden {
+		t.Fatalf("opaque origin: status = %d", code)
+	}
+	if code := handshake(srv, "http://"+strings.TrimPrefix(srv.URL, "http://")); code != http.StatusSwitchingProtocols {
+		t.Fatalf("same origin: status = %d", code)
+	}
+	if code := handshake(srv, ""); code != http.StatusSwitchingProtocols {
+		t.Fatalf("no origin: status = %d", code)
+	}
+
+	allow := newWSServer(New(Config{CheckOrigin: func(r *http.Request) bool {
+		return r.Header.Get("Origin") == "https://app.example"
+	}}))
+	defer allow.Close()
+	if code := handshake(allow, "https://app.example"); code != http.StatusSwitchingProtocols {
+		t.Fatalf("allowed origin: status = %d", code)
+	}
+	if code := handshake(allow, "https://evil.example"); code != http.StatusForbidden {
+		t.Fatalf("custom check: status = %d", code)
+	}
+}
@@ -0,0 +1,275 @@
+// Package kvcache provides a bounded, concurrency-safe in-memory key/value
+// cache with LRU eviction and per-entry TTLs. Middleware uses it for state
+// that must not grow without bound (sessions, tokens, per-client counters).
+//
+// Expired entries are dropped lazily on access and in bulk by DeleteExpired;
+// the cache starts no goroutines of its own.
+//
+// Example:
+//
+//	c := kvcache.New(kvcache.Options[string, []byte]{
+//		MaxEntries: 10_000,
+//		MaxCost:    64 << 20, // 64MB of values
+//		Cost:       func(_ string, v []byte) int64 { return int64(len(v)) },
+//		TTL:        5 * time.Minute,
+//	})
+//	c.Set("user:42", payload)
+//	if v, ok := c.Get("user:42"); ok { ... }
+package kvcache
+
+import (
+	"sync"
+	"time"
+)
+
+// EvictReason tells OnEvict why an entry left the cache.
+type EvictReason int
+
+const (
+	// EvictCapacity means the entry was the least recently used one when the
+	// cache exceeded MaxEntries or MaxCost.
+	EvictCapacity EvictReason = iota + 1
+	// EvictExpired means the entry's TTL elapsed.
+	EvictExpired
+)
+
+// String returns "capacity" or "expired".
+func (r EvictReason) String() string {
+	switch r {
+	case EvictCapacity:
+		return "capacity"
+	case EvictExpired:
+		return "expired"
+	}
+	return "unknown"
+}
+
+// Options configures a Cache. The zero value is an unbounded cache whose
+// entries never expire.
+type Options[K comparable, V any] struct {
+	// MaxEntries bounds the number of entries. 0 means unlimited.
+	MaxEntries int
+
+	// MaxCost bounds the total Cost of all entries. 0 means unlimited. The
+	// most recently set entry is never evicted, even if it alone exceeds MaxCost.
+	MaxCost int64
+
+	// Cost returns the size of an entry (e.g. bytes). Defaults to 1 per entry.
+	Cost func(key K, value V) int64
+
+	// TTL is the default lifetime used by Set. 0 means entries do not expire.
+	TTL time.Duration
+
+	// OnEvict, when set, is called for entries removed by capacity or
+	// expiration (not by Delete or overwrite). It runs with the cache lock
+	// held and must not call back into the cache.
+	OnEvict func(key K, value V, reason EvictReason)
+}
+
+// Stats is a snapshot of cache counters.
+type Stats struct {
+	Hits        uint64 // Get calls that found a live entry
+	Misses      uint64 // Get calls that found nothing or an expired entry
+	Evictions   uint64 // entries removed to respect MaxEntries/MaxCost
+	Expirations uint64 // entries removed because their TTL elapsed
+	Entries     int    // current number of entries (including not yet collected expired ones)
+	Cost        int64  // current total cost
+}
+
+// Cache is an LRU cache with TTLs. Create it with New; it is safe for
+// concurrent use.
+type Cache[K comparable, V any] struct {
+	mu    sync.Mutex
+	opts  Options[K, V]
+	items map[K]*node[K, V]
+	head  *node[K, V] // most recently used
+	tail  *node[K, V] // least recently used
+	cost  int64
+	stats Stats
+	now   func() time.Time
+}
+
+type node[K comparable, V any] struct {
+	key        K
+	value      V
+	exp        time.Time // zero = never
+	cost       int64
+	prev, next *node[K, V]
+}
+
+// New returns an empty cache configured by opts.
+func New[K comparable, V any](opts Options[K, V]) *Cache[K, V] {
+	return &Cache[K, V]{opts: opts, items: make(map[K]*node[K, V]), now: time.Now}
+}
+
+// Get returns the value for key and marks it most recently used. Expired
+// entries are removed and reported as missing.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n, ok := c.items[key]
+	if ok && n.expired(c.now()) {
+		c.remove(n)
+		c.stats.Expirations++
+		c.evicted(n, EvictExpired)
+		ok = false
+	}
+	if !ok {
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+	c.stats.Hits++
+	c.moveToFront(n)
+	return n.value, true
+}
+
+// Peek returns the value for key without updating recency or counters.
+func (c *Cache[K, V]) Peek(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n, ok := c.items[key]; ok && !n.expired(c.now()) {
+		return n.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Set stores value under key with the default TTL.
+func (c *Cache[K, V]) Set(key K, value V) { c.SetWithTTL(key, value, c.opts.TTL) }
+
+// SetWithTTL stores value under key, expiring after ttl (0 or negative: never),
+// and evicts least recently used entries if the cache is over capacity.
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	cost := int64(1)
+	if c.opts.Cost != nil {
+		cost = c.opts.Cost(key, value)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.items[key]; ok {
+		c.remove(old)
+	}
+	n := &node[K, V]{key: key, value: value, cost: cost}
+	if ttl > 0 {
+		n.exp = c.now().Add(ttl)
+	}
+	c.items[key] = n
+	c.cost += cost
+	c.pushFront(n)
+	for c.tail != nil && c.tail != n && c.overCapacity() {
+		lru := c.tail
+		c.remove(lru)
+		c.stats.Evictions++
+		c.evicted(lru, EvictCapacity)
+	}
+}
+
+// Delete removes key, reporting whether it was present.
+func (c *Cache[K, V]) Delete(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n, ok := c.items[key]
+	if ok {
+		c.remove(n)
+	}
+	return ok
+}
+
+// DeleteExpired removes every expired entry and returns how many were removed.
+// Call it periodically for caches whose keys are rarely read again.
+func (c *Cache[K, V]) DeleteExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	removed := 0
+	for n := c.tail; n != nil; {
+		prev := n.prev
+		if n.expired(now) {
+			c.remove(n)
+			c.stats.Expirations++
+			c.evicted(n, EvictExpired)
+			removed++
+		}
+		n = prev
+	}
+	return removed
+}
+
+// Len returns the number of entries, including expired ones not yet removed.
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// Clear removes all entries without calling OnEvict. Counters are kept.
+func (c *Cache[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = make(map[K]*node[K, V])
+	c.head, c.tail, c.cost = nil, nil, 0
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *Cache[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Entries = len(c.items)
+	s.Cost = c.cost
+	return s
+}
+
+func (n *node[K, V]) expired(now time.Time) bool { return !n.exp.IsZero() && now.After(n.exp) }
+
+func (c *Cache[K, V]) overCapacity() bool {
+	return (c.opts.MaxEntries > 0 && len(c.items) > c.opts.MaxEntries) ||
+		(c.opts.MaxCost > 0 && c.cost > c.opts.MaxCost)
+}
+
+func (c *Cache[K, V]) evicted(n *node[K, V], reason EvictReason) {
+	if c.opts.OnEvict != nil {
+		c.opts.OnEvict(n.key, n.value, reason)
+	}
+}
+
+// remove unlinks n and forgets its key.
+func (c *Cache[K, V]) remove(n *node[K, V]) {
+	c.unlink(n)
+	delete(c.items, n.key)
+	c.cost -= n.cost
+}
+
+func (c *Cache[K, V]) unlink(n *node[K, V]) {
+	if n.prev != nil {
+		n.prev.next = n.next
+	} else {
+		c.head = n.next
+	}
+	if n.next != nil {
+		n.next.prev = n.prev
+	} else {
+		c.tail = n.prev
+	}
+	n.prev, n.next = nil, nil
+}
+
+func (c *Cache[K, V]) pushFront(n *node[K, V]) {
+	n.next = c.head
+	if c.head != nil {
+		c.head.prev = n
+	}
+	c.head = n
+	if c.tail == nil {
+		c.tail = n
+	}
+}
+
+func (c *Cache[K, V]) moveToFront(n *node[K, V]) {
+	if c.head == n {
+		return
+	}
+	c.unlink(n)
+	c.pushFront(n)
+}
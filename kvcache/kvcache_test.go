@@ -0,0 +1,211 @@
+package kvcache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock lets tests control expiration.
+type fakeClock struct{ t time.Time }
+
+func (f *fakeClock) now() time.Time          { return f.t }
+func (f *fakeClock) advance(d time.Duration) { f.t = f.t.Add(d) }
+
+func newWithClock[K comparable, V any](opts Options[K, V]) (*Cache[K, V], *fakeClock) {
+	c := New(opts)
+	clk := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	c.now = clk.now
+	return c, clk
+}
+
+func TestGetSetDelete(t *testing.T) {
+	c := New(Options[string, int]{})
+	if _, ok := c.Get("a"); ok {
+		t.Fatalf("expected miss on empty cache")
+	}
+	c.Set("a", 1)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("expected 1, got %v %v", v, ok)
+	}
+	c.Set("a", 2)
+	if v, _ := c.Get("a"); v != 2 {
+		t.Fatalf("expected overwrite to 2, got %v", v)
+	}
+	if c.Len() != 1 {
+		t.Fatalf("expected 1 entry, got %d", c.Len())
+	}
+	if !c.Delete("a") || c.Delete("a") {
+		t.Fatalf("expected Delete to report presence once")
+	}
+	if c.Len() != 0 {
+		t.Fatalf("expected empty cache")
+	}
+}
+
+func TestLRUEviction(t *testing.T) {
+	var evicted []string
+	c := New(Options[string, int]{
+		MaxEntries: 2,
+		OnEvict: func(k string, _ int, r EvictReason) {
+			if r != EvictCapacity {
+				t.Fatalf("unexpected reason %v", r)
+			}
+			evicted = append(evicted, k)
+		},
+	})
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a") // a is now most recently used
+	c.Set("c", 3)
+	if _, ok := c.Peek("b"); ok {
+		t.Fatalf("expected b to be evicted")
+	}
+	if _, ok := c.Peek("a"); !ok {
+		t.Fatalf("expected a to survive")
+	}
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Fatalf("unexpected evictions %v", evicted)
+	}
+	if s := c.Stats(); s.Evictions != 1 || s.Entries != 2 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+func TestPeekDoesNotPromote(t *testing.T) {
+	c := New(Options[string, int]{MaxEntries: 2})
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Peek("a")
+	c.Set("c", 3)
+	if _, ok := c.Peek("a"); ok {
+		t.Fatalf("Peek must not update recency")
+	}
+	if s := c.Stats(); s.Hits != 0 || s.Misses != 0 {
+		t.Fatalf("Peek must not update counters: %+v", s)
+	}
+}
+
+func TestMaxCost(t *testing.T) {
+	c := New(Options[string, string]{
+		MaxCost: 10,
+		Cost:    func(_ string, v string) int64 { return int64(len(v)) },
+	})
+	c.Set("a", "xxxx")
+	c.Set("b", "xxxx")
+	c.Set("c", "xxxx") // 12 > 10: a goes
+	if _, ok := c.Peek("a"); ok {
+		t.Fatalf("expected a to be evicted by cost")
+	}
+	if s := c.Stats(); s.Cost != 8 || s.Entries != 2 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+
+	// An oversized entry evicts everything else but is kept itself.
+	c.Set("big", "xxxxxxxxxxxxxxxx")
+	if c.Len() != 1 {
+		t.Fatalf("expected only the oversized entry, got %d entries", c.Len())
+	}
+	if _, ok := c.Peek("big"); !ok {
+		t.Fatalf("the most recent entry must never be evicted")
+	}
+}
+
+func TestTTL(t *testing.T) {
+	var reasons []EvictReason
+	c, clk := newWithClock(Options[string, int]{
+		TTL:     time.Minute,
+		OnEvict: func(_ string, _ int, r EvictReason) { reasons = append(reasons, r) },
+	})
+	c.Set("a", 1)
+	c.SetWithTTL("forever", 2, 0)
+	clk.advance(30 * time.Second)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatalf("expected a before expiry")
+	}
+	clk.advance(31 * time.Second)
+	if _, ok := c.Peek("a"); ok {
+		t.Fatalf("Peek must not return expired entries")
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Fatalf("expected a to be expired")
+	}
+	if c.Len() != 1 {
+		t.Fatalf("expected expired entry to be removed on Get, len=%d", c.Len())
+	}
+	if _, ok := c.Get("forever"); !ok {
+		t.Fatalf("entries without TTL must not expire")
+	}
+	if len(reasons) != 1 || reasons[0] != EvictExpired {
+		t.Fatalf("unexpected evictions %v", reasons)
+	}
+	if s := c.Stats(); s.Expirations != 1 || s.Hits != 2 || s.Misses != 1 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+func TestDeleteExpired(t *testing.T) {
+	c, clk := newWithClock(Options[int, int]{})
+	for i := 0; i < 10; i++ {
+		c.SetWithTTL(i, i, time.Duration(i+1)*time.Second)
+	}
+	clk.advance(5*time.Second + time.Millisecond)
+	if n := c.DeleteExpired(); n != 5 {
+		t.Fatalf("expected 5 expired, got %d", n)
+	}
+	if c.Len() != 5 {
+		t.Fatalf("expected 5 left, got %d", c.Len())
+	}
+	if _, ok := c.Get(9); !ok {
+		t.Fatalf("expected 9 to be live")
+	}
+}
+
+func TestClear(t *testing.T) {
+	called := false
+	c := New(Options[string, int]{OnEvict: func(string, int, EvictReason) { called = true }})
+	c.Set("a", 1)
+	c.Get("a")
+	c.Clear()
+	if c.Len() != 0 || called {
+		t.Fatalf("Clear must empty the cache without OnEvict")
+	}
+	if s := c.Stats(); s.Hits != 1 || s.Cost != 0 {
+		t.Fatalf("Clear must keep counters: %+v", s)
+	}
+	c.Set("b", 2)
+	if v, ok := c.Get("b"); !ok || v != 2 {
+		t.Fatalf("cache unusable after Clear")
+	}
+}
+
+func TestEvictReasonString(t *testing.T) {
+	if EvictCapacity.String() != "capacity" || EvictExpired.String() != "expired" || EvictReason(0).String() != "unknown" {
+		t.Fatalf("unexpected reason strings")
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	c := New(Options[string, int]{MaxEntries: 64, TTL: time.Minute})
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				k := strconv.Itoa((g * i) % 100)
+				c.Set(k, i)
+				c.Get(k)
+				if i%50 == 0 {
+					c.Delete(k)
+					c.DeleteExpired()
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if c.Len() > 64 {
+		t.Fatalf("cache exceeded MaxEntries: %d", c.Len())
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"bytes"
 	"net"
 	"net/http"
+	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/goflash/flash/v2"
 )
@@ -23,7 +25,8 @@ import (
 //   - Set MaxSize to a sensible ceiling to avoid unbounded memory use for very
 //     large responses (MaxSize=0 means unbounded buffering).
 //   - This middleware is not suitable for server-sent events or long-lived
-//     streaming responses. Use it for bounded payloads (JSON, HTML, small files).
+//     streaming responses. Use it for bounded payloads (JSON, HTML, small files),
+//     or list streaming types in BypassContentTypes so they pass straight through.
 //   - For HEAD responses where no body is written, no buffer is allocated at all.
 //
 // Example:
@@ -32,15 +35,147 @@ import (
 //		InitialSize: 8 << 10, // 8KB
 //		MaxSize:     1 << 20, // 1MB
 //	}))
+//
+// Adaptive sizing, pool statistics and streaming bypass:
+//
+//	stats := &middleware.BufferStats{}
+//	app.Use(middleware.Buffer(middleware.BufferConfig{
+//		MaxSize:            1 << 20,
+//		Adaptive:           true, // preallocate the p90 response size of each route
+//		BypassContentTypes: middleware.DefaultStreamingContentTypes,
+//		Stats:              stats,
+//	}))
+//	// later: expvar/metrics export
+//	_ = stats.Hits(); _ = stats.Misses(); _ = stats.Discarded()
 type BufferConfig struct {
 	InitialSize int // preallocated buffer size
 	MaxSize     int // max buffer size before switching to streaming
+
+	// Adaptive preallocates each route's buffer from the observed distribution of
+	// its response sizes (see AdaptivePercentile) instead of InitialSize. Until a
+	// route has enough samples, InitialSize is used. The estimate is capped by MaxSize.
+	Adaptive bool
+	// AdaptivePercentile selects the percentile of observed sizes used when
+	// Adaptive is set, in (0, 1]. Defaults to 0.9.
+	AdaptivePercentile float64
+	// MaxPooledSize is the largest buffer capacity returned to the pool; bigger
+	// buffers are discarded so one huge response does not pin memory. Defaults to 1MB.
+	MaxPooledSize int
+	// BypassContentTypes lists Content-Type prefixes (e.g., "text/event-stream")
+	// that are streamed straight through without buffering. The Content-Type must
+	// be set before the first write. See DefaultStreamingContentTypes.
+	BypassContentTypes []string
+	// Stats, when non-nil, receives pool and bypass counters.
+	Stats *BufferStats
+}
+
+// DefaultStreamingContentTypes are common streaming content types suitable for
+// BufferConfig.BypassContentTypes.
+var DefaultStreamingContentTypes = []string{
+	"text/event-stream",
+	"application/x-ndjson",
+	"multipart/x-mixed-replace",
+}
+
+const (
+	defaultMaxPooledSize      = 1 << 20
+	defaultAdaptivePercentile = 0.9
+	adaptiveMinSamples        = 16   // samples before the estimate is trusted
+	adaptiveRecompute         = 16   // recompute the estimate every N samples
+	adaptiveWindow            = 4096 // halve counts beyond this to follow recent traffic
+)
+
+// BufferStats collects counters from the Buffer middleware. All methods are safe
+// for concurrent use; a zero BufferStats is ready to use.
+type BufferStats struct {
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	discarded atomic.Uint64
+	bypassed  atomic.Uint64
+	overflows atomic.Uint64
+}
+
+// Hits returns how many buffers were reused from the pool.
+func (s *BufferStats) Hits() uint64 { return s.hits.Load() }
+
+// Misses returns how many buffers had to be allocated because the pool was empty.
+func (s *BufferStats) Misses() uint64 { return s.misses.Load() }
+
+// Discarded returns how many buffers exceeded MaxPooledSize and were not pooled.
+func (s *BufferStats) Discarded() uint64 { return s.discarded.Load() }
+
+// Bypassed returns how many responses skipped buffering due to BypassContentTypes.
+func (s *BufferStats) Bypassed() uint64 { return s.bypassed.Load() }
+
+// Overflows returns how many responses exceeded MaxSize and switched to streaming.
+func (s *BufferStats) Overflows() uint64 { return s.overflows.Load() }
+
+// record increments the counter selected by pick when stats collection is enabled.
+func (s *BufferStats) record(pick func(*BufferStats) *atomic.Uint64) {
+	if s != nil {
+		pick(s).Add(1)
+	}
+}
+
+func statHits(s *BufferStats) *atomic.Uint64      { return &s.hits }
+func statMisses(s *BufferStats) *atomic.Uint64    { return &s.misses }
+func statDiscarded(s *BufferStats) *atomic.Uint64 { return &s.discarded }
+func statBypassed(s *BufferStats) *atomic.Uint64  { return &s.bypassed }
+func statOverflows(s *BufferStats) *atomic.Uint64 { return &s.overflows }
+
+// sizeTracker keeps a log2 histogram of response sizes for one route and a
+// cached percentile estimate read lock-free on the hot path.
+type sizeTracker struct {
+	mu      sync.Mutex
+	buckets [33]uint32 // bucket i counts sizes in (2^(i-1), 2^i]
+	total   uint32
+	hint    atomic.Int64
+}
+
+// observe records a response size and periodically refreshes the estimate.
+func (t *sizeTracker) observe(n int, pct float64) {
+	i := 0
+	for i < len(t.buckets)-1 && 1<<i < n {
+		i++
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buckets[i]++
+	t.total++
+	if t.total >= adaptiveWindow {
+		t.total = 0
+		for j := range t.buckets {
+			t.buckets[j] /= 2
+			t.total += t.buckets[j]
+		}
+	}
+	if t.total < adaptiveMinSamples || t.total%adaptiveRecompute != 0 {
+		return
+	}
+	want := uint32(float64(t.total)*pct + 0.5)
+	var seen uint32
+	for j, c := range t.buckets {
+		seen += c
+		if seen >= want {
+			t.hint.Store(int64(1) << j)
+			return
+		}
+	}
+}
+
+// initialSize returns the estimated buffer size, or def without enough samples.
+func (t *sizeTracker) initialSize(def int) int {
+	if h := t.hint.Load(); h > 0 {
+		return int(h)
+	}
+	return def
 }
 
 // bufPool is a global sync.Pool for *bytes.Buffer used by the Buffer middleware.
 // This reduces allocations and GC pressure for each request, especially for small/medium responses.
 // Buffers are always Reset before reuse, and never shared between requests.
-var bufPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+// It has no New func so Get reports misses (nil) for BufferStats.
+var bufPool sync.Pool
 
 // Buffer returns middleware that wraps the ResponseWriter with a pooled buffer
 // to reduce syscalls and to set an accurate Content-Length when possible.
@@ -51,6 +186,9 @@ var bufPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
 //   - Buffers writes in-memory up to MaxSize; beyond that, switches to streaming
 //   - Sets Content-Length on close when safe (no Content-Encoding)
 //   - Supports Flush passthrough and zero-allocation HEAD responses
+//   - Optionally sizes buffers per route from observed response sizes (Adaptive)
+//     and skips buffering for streaming content types (BypassContentTypes)
+//   - Skips routes that opt out with Route.Bypass("buffer")
 //
 // Example:
 //
@@ -64,14 +202,32 @@ func Buffer(cfgs ...BufferConfig) flash.Middleware {
 	if len(cfgs) > 0 {
 		cfg = cfgs[0]
 	}
-	return func(next flash.Handler) flash.Handler {
+	if cfg.AdaptivePercentile <= 0 || cfg.AdaptivePercentile > 1 {
+		cfg.AdaptivePercentile = defaultAdaptivePercentile
+	}
+	var trackers sync.Map // route pattern -> *sizeTracker
+	return flash.Describe("buffer", cfg, func(next flash.Handler) flash.Handler {
 		return func(c flash.Ctx) error {
-			brw := &bufferedRW{rw: c.ResponseWriter(), cfg: cfg}
+			if c.Bypassed("buffer") {
+				return next(c)
+			}
+			brw := &bufferedRW{rw: c.ResponseWriter(), cfg: cfg, initial: cfg.InitialSize}
+			if cfg.Adaptive {
+				t, ok := trackers.Load(c.Route())
+				if !ok {
+					t, _ = trackers.LoadOrStore(c.Route(), &sizeTracker{})
+				}
+				brw.tracker = t.(*sizeTracker)
+				brw.initial = brw.tracker.initialSize(cfg.InitialSize)
+				if cfg.MaxSize > 0 && brw.initial > cfg.MaxSize {
+					brw.initial = cfg.MaxSize
+				}
+			}
 			c.SetResponseWriter(brw)
 			defer brw.Close()
 			return next(c)
 		}
-	}
+	})
 }
 
 type bufferedRW struct {
@@ -81,6 +237,10 @@ type bufferedRW struct {
 	status      int
 	headWritten bool // whether we've written header to underlying
 	streaming   bool // switched to passthrough
+	started     bool // first write seen (bypass check done)
+	initial     int  // buffer size to preallocate
+	written     int  // total body bytes written, for adaptive sizing
+	tracker     *sizeTracker
 }
 
 // Header returns the underlying response headers map.
@@ -96,10 +256,16 @@ func (b *bufferedRW) ensureBuf() {
 		return
 	}
 	// Get a buffer from the pool (or allocate if pool is empty)
-	bb := bufPool.Get().(*bytes.Buffer)
+	bb, _ := bufPool.Get().(*bytes.Buffer)
+	if bb == nil {
+		b.cfg.Stats.record(statMisses)
+		bb = new(bytes.Buffer)
+	} else {
+		b.cfg.Stats.record(statHits)
+	}
 	bb.Reset() // always reset before use
-	if b.cfg.InitialSize > 0 {
-		bb.Grow(b.cfg.InitialSize)
+	if b.initial > 0 {
+		bb.Grow(b.initial)
 	}
 	b.buf = bb
 }
@@ -115,6 +281,14 @@ func (b *bufferedRW) WriteHeader(status int) { b.status = status }
 // Example (switching to streaming): if MaxSize is 1MB and the handler writes
 // 600KB then 600KB, the second write triggers a flush and streaming.
 func (b *bufferedRW) Write(p []byte) (int, error) {
+	b.written += len(p)
+	if !b.started {
+		b.started = true
+		if b.bypass() {
+			b.cfg.Stats.record(statBypassed)
+			b.streaming = true
+		}
+	}
 	if b.streaming {
 		b.writeHeaderIfNeeded()
 		return b.rw.Write(p)
@@ -123,6 +297,7 @@ func (b *bufferedRW) Write(p []byte) (int, error) {
 	// If exceeding MaxSize, switch to streaming
 	if b.cfg.MaxSize > 0 && b.buf.Len()+len(p) > b.cfg.MaxSize {
 		// flush buffered content without Content-Length
+		b.cfg.Stats.record(statOverflows)
 		b.writeHeaderIfNeeded()
 		if b.buf.Len() > 0 {
 			if _, err := b.rw.Write(b.buf.Bytes()); err != nil {
@@ -143,6 +318,9 @@ func (b *bufferedRW) Write(p []byte) (int, error) {
 // is set unless Content-Encoding is present. This is a key optimization for API
 // and static routes.
 func (b *bufferedRW) Close() error {
+	if b.tracker != nil {
+		b.tracker.observe(b.written, b.cfg.AdaptivePercentile)
+	}
 	if b.streaming {
 		b.release()
 		return nil
@@ -165,6 +343,23 @@ func (b *bufferedRW) Close() error {
 	return nil
 }
 
+// bypass reports whether the response Content-Type is configured to stream.
+func (b *bufferedRW) bypass() bool {
+	if len(b.cfg.BypassContentTypes) == 0 {
+		return false
+	}
+	ct := b.Header().Get("Content-Type")
+	if ct == "" {
+		return false
+	}
+	for _, prefix := range b.cfg.BypassContentTypes {
+		if len(ct) >= len(prefix) && strings.EqualFold(ct[:len(prefix)], prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 // writeHeaderIfNeeded writes the header once, defaulting status to 200.
 func (b *bufferedRW) writeHeaderIfNeeded() {
 	if b.headWritten {
@@ -222,9 +417,18 @@ func (b *bufferedRW) Push(target string, opts *http.PushOptions) error {
 
 func (b *bufferedRW) release() {
 	if b.buf != nil {
-		// Always reset before putting back to pool to avoid data leaks
-		b.buf.Reset()
-		bufPool.Put(b.buf)
+		maxPooled := b.cfg.MaxPooledSize
+		if maxPooled <= 0 {
+			maxPooled = defaultMaxPooledSize
+		}
+		if b.buf.Cap() > maxPooled {
+			// Let oversize buffers be collected instead of pinning them in the pool
+			b.cfg.Stats.record(statDiscarded)
+		} else {
+			// Always reset before putting back to pool to avoid data leaks
+			b.buf.Reset()
+			bufPool.Put(b.buf)
+		}
 		b.buf = nil
 	}
 }
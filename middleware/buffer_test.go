@@ -6,6 +6,7 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -430,3 +431,136 @@ func TestBufferPushDelegationAndUnsupported(t *testing.T) {
 		}
 	})
 }
+
+func TestBufferStatsHitsMissesAndDiscarded(t *testing.T) {
+	stats := &BufferStats{}
+	a := flash.New()
+	a.Use(Buffer(BufferConfig{MaxPooledSize: 512, Stats: stats}))
+	a.GET("/small", func(c flash.Ctx) error { return c.String(http.StatusOK, "hi") })
+	a.GET("/big", func(c flash.Ctx) error { return c.String(http.StatusOK, strings.Repeat("x", 1024)) })
+
+	for i := 0; i < 5; i++ {
+		a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/small", nil))
+	}
+	// the pool is shared across tests, so only the total and reuse are deterministic
+	if stats.Hits()+stats.Misses() != 5 || stats.Hits() == 0 {
+		t.Fatalf("unexpected hits=%d misses=%d", stats.Hits(), stats.Misses())
+	}
+	before := stats.Discarded()
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/big", nil))
+	if rec.Body.Len() != 1024 || stats.Discarded() != before+1 {
+		t.Fatalf("expected oversize buffer to be discarded: body=%d discarded=%d", rec.Body.Len(), stats.Discarded())
+	}
+}
+
+func TestBufferStatsOverflows(t *testing.T) {
+	stats := &BufferStats{}
+	a := flash.New()
+	a.Use(Buffer(BufferConfig{MaxSize: 4, Stats: stats}))
+	a.GET("/", func(c flash.Ctx) error { return c.String(http.StatusOK, "hello world") })
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if stats.Overflows() != 1 || rec.Body.String() != "hello world" {
+		t.Fatalf("overflows=%d body=%q", stats.Overflows(), rec.Body.String())
+	}
+}
+
+func TestBufferBypassesStreamingContentTypes(t *testing.T) {
+	stats := &BufferStats{}
+	a := flash.New()
+	a.Use(Buffer(BufferConfig{BypassContentTypes: DefaultStreamingContentTypes, Stats: stats}))
+	a.GET("/sse", func(c flash.Ctx) error {
+		c.Header("Content-Type", "Text/Event-Stream; charset=utf-8")
+		w := c.ResponseWriter()
+		_, _ = w.Write([]byte("data: 1\n\n"))
+		return nil
+	})
+	a.GET("/json", func(c flash.Ctx) error { return c.JSON(map[string]int{"a": 1}) })
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sse", nil))
+	if rec.Header().Get("Content-Length") != "" || rec.Body.String() != "data: 1\n\n" || stats.Bypassed() != 1 {
+		t.Fatalf("sse should bypass buffering: cl=%q bypassed=%d", rec.Header().Get("Content-Length"), stats.Bypassed())
+	}
+	if stats.Hits()+stats.Misses() != 0 {
+		t.Fatalf("bypassed response should not take a buffer")
+	}
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/json", nil))
+	if rec.Header().Get("Content-Length") == "" || stats.Bypassed() != 1 {
+		t.Fatalf("json should be buffered")
+	}
+}
+
+func TestBufferAdaptiveSizing(t *testing.T) {
+	var got int
+	a := flash.New()
+	a.Use(Buffer(BufferConfig{Adaptive: true, InitialSize: 32, MaxSize: 2048, AdaptivePercentile: 5}))
+	a.Use(func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			got = c.ResponseWriter().(*bufferedRW).initial
+			return next(c)
+		}
+	})
+	a.GET("/r", func(c flash.Ctx) error { return c.String(http.StatusOK, strings.Repeat("x", 900)) })
+	a.GET("/huge", func(c flash.Ctx) error { return c.String(http.StatusOK, strings.Repeat("x", 5000)) })
+
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/r", nil))
+	if got != 32 {
+		t.Fatalf("expected InitialSize before samples, got %d", got)
+	}
+	for i := 0; i < adaptiveMinSamples; i++ {
+		a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/r", nil))
+	}
+	if got != 1024 {
+		t.Fatalf("expected adaptive size 1024, got %d", got)
+	}
+	for i := 0; i <= adaptiveMinSamples; i++ {
+		a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/huge", nil))
+	}
+	if got != 2048 {
+		t.Fatalf("expected estimate capped by MaxSize, got %d", got)
+	}
+}
+
+func TestSizeTrackerPercentileAndDecay(t *testing.T) {
+	var tr sizeTracker
+	for i := 0; i < 90; i++ {
+		tr.observe(100, 0.9)
+	}
+	for i := 0; i < 6; i++ {
+		tr.observe(10000, 0.9)
+	}
+	if got := tr.initialSize(1); got != 128 {
+		t.Fatalf("p90 = %d, want 128", got)
+	}
+	for i := 0; i < adaptiveWindow; i++ {
+		tr.observe(0, 0.5)
+	}
+	if tr.total >= adaptiveWindow {
+		t.Fatalf("window not decayed: %d", tr.total)
+	}
+	if got := tr.initialSize(1); got != 1 {
+		t.Fatalf("expected estimate to follow recent traffic, got %d", got)
+	}
+}
+
+func TestBufferBypassedRoute(t *testing.T) {
+	a := flash.New()
+	a.Use(Buffer(BufferConfig{InitialSize: 128, MaxSize: 1024}))
+	buffered := true
+	a.GET("/events", func(c flash.Ctx) error {
+		_, buffered = c.ResponseWriter().(*bufferedRW)
+		return c.String(http.StatusOK, "data")
+	}).Bypass("buffer")
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "data" {
+		t.Fatalf("unexpected response %d %q", rec.Code, rec.Body.String())
+	}
+	if buffered {
+		t.Fatalf("bypassed route must not be buffered")
+	}
+}
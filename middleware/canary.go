@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/goflash/flash/v2"
+	"github.com/goflash/flash/v2/ctx"
+)
+
+// CanaryConfig configures the Canary middleware.
+//
+// A request is routed to the canary path when, in order of precedence:
+//  1. the override Header or Cookie says so ("1", "true", "always" force the
+//     canary; "0", "false", "never" force the stable path);
+//  2. the sticky cookie records an earlier canary assignment;
+//  3. the request's subject (KeyFunc, or a random subject) hashes into Percent.
+//
+// The canary path is Handler when set, otherwise a reverse proxy to Upstream.
+// Both paths are counted in Stats.
+//
+// Example:
+//
+//	stats := &middleware.CanaryStats{}
+//	api.Use(middleware.Canary(middleware.CanaryConfig{
+//		Percent: 5,
+//		Handler: ordersV2.Handle, // or Upstream: "http://orders-canary:8080"
+//		Header:  "X-Canary",      // testers opt in with X-Canary: always
+//		KeyFunc: func(c flash.Ctx) string { return userID(c) },
+//		Stats:   stats,
+//	}))
+//
+//	// Promote or roll back based on stats.Canary().ErrorRate() vs stats.Stable().ErrorRate()
+type CanaryConfig struct {
+	// Percent of traffic (0-100) routed to the canary.
+	Percent float64
+
+	// Handler serves canary requests in-process. Takes precedence over Upstream.
+	Handler flash.Handler
+
+	// Upstream is the base URL of a canary backend, used when Handler is nil.
+	Upstream string
+
+	// Header and Cookie name optional overrides that force either path.
+	Header string
+	Cookie string
+
+	// KeyFunc returns the subject used for sticky hashing (user ID, session ID...).
+	// When nil or empty, a random subject is used and the sticky cookie keeps
+	// the client on its path.
+	KeyFunc func(c flash.Ctx) string
+
+	// StickyCookie stores the assignment. Defaults to "canary"; "-" disables it.
+	StickyCookie string
+
+	// StickyMaxAge is the sticky cookie lifetime. Defaults to 24 hours.
+	StickyMaxAge time.Duration
+
+	// Stats, when non-nil, records request, error and latency counters per path.
+	Stats *CanaryStats
+}
+
+// CanaryStats accumulates per-path counters. It is safe for concurrent use;
+// the zero value is ready to use.
+type CanaryStats struct {
+	stable, canary canaryCounters
+}
+
+type canaryCounters struct {
+	requests, errors, nanos atomic.Uint64
+}
+
+// CanaryPathStats is a snapshot of one path's counters.
+type CanaryPathStats struct {
+	Requests uint64
+	Errors   uint64 // handler errors and 5xx responses
+	Latency  time.Duration
+}
+
+// ErrorRate returns Errors/Requests, or 0 with no requests.
+func (s CanaryPathStats) ErrorRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Requests)
+}
+
+// AvgLatency returns the mean latency, or 0 with no requests.
+func (s CanaryPathStats) AvgLatency() time.Duration {
+	if s.Requests == 0 {
+		return 0
+	}
+	return s.Latency / time.Duration(s.Requests)
+}
+
+// Stable returns a snapshot of the stable path's counters.
+func (s *CanaryStats) Stable() CanaryPathStats { return s.stable.snapshot() }
+
+// Canary returns a snapshot of the canary path's counters.
+func (s *CanaryStats) Canary() CanaryPathStats { return s.canary.snapshot() }
+
+func (c *canaryCounters) snapshot() CanaryPathStats {
+	return CanaryPathStats{Requests: c.requests.Load(), Errors: c.errors.Load(), Latency: time.Duration(c.nanos.Load())}
+}
+
+func (c *canaryCounters) observe(d time.Duration, failed bool) {
+	c.requests.Add(1)
+	c.nanos.Add(uint64(d))
+	if failed {
+		c.errors.Add(1)
+	}
+}
+
+type canaryContextKey struct{}
+
+// Canary returns middleware that routes a share of traffic to an alternate
+// handler or upstream with sticky assignment. Stable requests continue down
+// the chain. It panics if neither Handler nor a valid Upstream is set.
+func Canary(cfg CanaryConfig) flash.Middleware {
+	alt := cfg.Handler
+	if alt == nil {
+		u, err := url.Parse(cfg.Upstream)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			panic("middleware: Canary requires a Handler or an absolute Upstream URL")
+		}
+		proxy := httputil.NewSingleHostReverseProxy(u)
+		alt = func(c flash.Ctx) error {
+			cw := &captureWriter{ResponseWriter: c.ResponseWriter()}
+			proxy.ServeHTTP(cw, c.Request())
+			c.Status(cw.statusOr(0)) // record upstream status for stats and logging
+			return nil
+		}
+	}
+	if cfg.StickyCookie == "" {
+		cfg.StickyCookie = "canary"
+	}
+	if cfg.StickyMaxAge <= 0 {
+		cfg.StickyMaxAge = 24 * time.Hour
+	}
+	const buckets = 10000
+	threshold := uint64(cfg.Percent / 100 * buckets)
+
+	return flash.Describe("canary", cfg, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			r := c.Request()
+			canary, decided := canaryOverride(r, cfg.Header, cfg.Cookie)
+			sticky := cfg.StickyCookie != "-"
+			if !decided && sticky {
+				if ck, err := r.Cookie(cfg.StickyCookie); err == nil && (ck.Value == "canary" || ck.Value == "stable") {
+					canary, decided = ck.Value == "canary", true
+				}
+			}
+			if !decided {
+				subject := ""
+				if cfg.KeyFunc != nil {
+					subject = cfg.KeyFunc(c)
+				}
+				if subject == "" {
+					subject = newSessionID()
+				}
+				canary = hashBucket("canary", subject, buckets) < threshold
+				if sticky {
+					val := "stable"
+					if canary {
+						val = "canary"
+					}
+					http.SetCookie(c.ResponseWriter(), &http.Cookie{
+						Name:     cfg.StickyCookie,
+						Value:    val,
+						Path:     "/",
+						MaxAge:   int(cfg.StickyMaxAge / time.Second),
+						HttpOnly: true,
+						SameSite: http.SameSiteLaxMode,
+					})
+				}
+			}
+
+			rc := context.WithValue(r.Context(), canaryContextKey{}, canary)
+			rc = ctx.ContextWithLogger(rc, ctx.LoggerFromContext(rc).With("canary", canary))
+			c.SetRequest(r.WithContext(rc))
+
+			h, counters := next, (*canaryCounters)(nil)
+			if cfg.Stats != nil {
+				counters = &cfg.Stats.stable
+			}
+			if canary {
+				h = alt
+				if cfg.Stats != nil {
+					counters = &cfg.Stats.canary
+				}
+			}
+			start := time.Now()
+			err := h(c)
+			if counters != nil {
+				counters.observe(time.Since(start), err != nil || c.StatusCode() >= 500)
+			}
+			return err
+		}
+	})
+}
+
+// IsCanary reports whether the Canary middleware routed this request to the
+// canary path.
+func IsCanary(c flash.Ctx) bool {
+	v, _ := c.Context().Value(canaryContextKey{}).(bool)
+	return v
+}
+
+// canaryOverride inspects the override header, then cookie.
+func canaryOverride(r *http.Request, header, cookie string) (canary, ok bool) {
+	var v string
+	if header != "" {
+		v = r.Header.Get(header)
+	}
+	if v == "" && cookie != "" {
+		if ck, err := r.Cookie(cookie); err == nil {
+			v = ck.Value
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "1", "true", "always":
+		return true, true
+	case "0", "false", "never":
+		return false, true
+	}
+	return false, false
+}
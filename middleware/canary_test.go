@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/goflash/flash/v2"
+)
+
+func canaryApp(cfg CanaryConfig) flash.App {
+	if cfg.Handler == nil && cfg.Upstream == "" {
+		cfg.Handler = func(c flash.Ctx) error {
+			if c.Query("fail") != "" {
+				return c.String(http.StatusInternalServerError, "boom")
+			}
+			return c.String(http.StatusOK, "canary")
+		}
+	}
+	a := flash.New()
+	a.Use(Canary(cfg))
+	a.GET("/", func(c flash.Ctx) error { return c.String(http.StatusOK, "stable") })
+	return a
+}
+
+func TestCanary_PercentSplitAndStats(t *testing.T) {
+	stats := &CanaryStats{}
+	a := canaryApp(CanaryConfig{
+		Percent:      20,
+		KeyFunc:      func(c flash.Ctx) string { return c.Request().Header.Get("X-User") },
+		StickyCookie: "-",
+		Stats:        stats,
+	})
+	for i := 0; i < 500; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-User", "u"+strconv.Itoa(i))
+		a.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	st, cn := stats.Stable(), stats.Canary()
+	if st.Requests+cn.Requests != 500 || cn.Requests < 60 || cn.Requests > 140 {
+		t.Fatalf("unexpected split stable=%d canary=%d", st.Requests, cn.Requests)
+	}
+	if st.ErrorRate() != 0 || (CanaryPathStats{}).ErrorRate() != 0 || (CanaryPathStats{}).AvgLatency() != 0 {
+		t.Fatalf("unexpected error rate")
+	}
+
+	// Same user is always routed the same way.
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-User", "u1")
+	first := httptest.NewRecorder()
+	a.ServeHTTP(first, req)
+	for i := 0; i < 5; i++ {
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		if rec.Body.String() != first.Body.String() {
+			t.Fatalf("assignment not deterministic")
+		}
+	}
+}
+
+func TestCanary_OverridesStickyAndErrors(t *testing.T) {
+	stats := &CanaryStats{}
+	a := canaryApp(CanaryConfig{Percent: 0, Header: "X-Canary", Cookie: "force", Stats: stats})
+	serve := func(path string, hdr string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if hdr != "" {
+			req.Header.Set("X-Canary", hdr)
+		}
+		for _, ck := range cookies {
+			req.AddCookie(ck)
+		}
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("/", "")
+	if rec.Body.String() != "stable" || len(rec.Result().Cookies()) != 1 || rec.Result().Cookies()[0].Value != "stable" {
+		t.Fatalf("0%% should route stable and set sticky cookie: %q %+v", rec.Body.String(), rec.Result().Cookies())
+	}
+	if rec := serve("/", "always"); rec.Body.String() != "canary" || len(rec.Result().Cookies()) != 0 {
+		t.Fatalf("header override failed: %q", rec.Body.String())
+	}
+	if rec := serve("/", "", &http.Cookie{Name: "force", Value: "1"}); rec.Body.String() != "canary" {
+		t.Fatalf("cookie override failed")
+	}
+	if rec := serve("/", "never", &http.Cookie{Name: "canary", Value: "canary"}); rec.Body.String() != "stable" {
+		t.Fatalf("override should beat sticky cookie")
+	}
+	if rec := serve("/", "", &http.Cookie{Name: "canary", Value: "canary"}); rec.Body.String() != "canary" {
+		t.Fatalf("sticky cookie not honored")
+	}
+	serve("/?fail=1", "true")
+	if cn := stats.Canary(); cn.Requests != 4 || cn.Errors != 1 || cn.ErrorRate() != 0.25 {
+		t.Fatalf("unexpected canary stats: %+v", cn)
+	}
+}
+
+func TestCanary_UpstreamAndIsCanary(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("upstream " + r.URL.Path))
+	}))
+	defer upstream.Close()
+	a := flash.New()
+	var flagged bool
+	a.Use(func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			err := next(c)
+			flagged = IsCanary(c)
+			return err
+		}
+	})
+	stats := &CanaryStats{}
+	a.Use(Canary(CanaryConfig{Percent: 100, Upstream: upstream.URL, Stats: stats}))
+	a.GET("/x", func(c flash.Ctx) error { return c.String(http.StatusOK, "stable") })
+	a.GET("/fail", func(c flash.Ctx) error { return c.String(http.StatusOK, "stable") })
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/x", nil))
+	if rec.Body.String() != "upstream /x" || !flagged {
+		t.Fatalf("got %q canary=%v", rec.Body.String(), flagged)
+	}
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	if rec.Code != http.StatusBadGateway || stats.Canary().Errors != 1 {
+		t.Fatalf("upstream 5xx not recorded: %d %+v", rec.Code, stats.Canary())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic without Handler or Upstream")
+		}
+	}()
+	Canary(CanaryConfig{})
+}
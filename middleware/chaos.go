@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+// ChaosEnv is the environment variable that enables Chaos when
+// ChaosConfig.Enabled is false, e.g. FLASH_CHAOS=1.
+const ChaosEnv = "FLASH_CHAOS"
+
+// ChaosConfig configures the Chaos middleware.
+type ChaosConfig struct {
+	// Enabled turns fault injection on. When false, faults are injected only
+	// if the ChaosEnv environment variable is set to a true value ("1",
+	// "true") when the middleware is created; otherwise Chaos is a no-op.
+	Enabled bool
+
+	// Match selects the requests eligible for faults. If nil, all requests
+	// are eligible.
+	Match func(c flash.Ctx) bool
+
+	// Percent of matching requests (0-100) that get faults. Defaults to 100.
+	Percent float64
+
+	// LatencyP50 is the median artificial delay added to affected requests.
+	// Delays follow an exponential distribution, so most are short and a few
+	// are long, like real tail latency. Zero disables latency injection.
+	LatencyP50 time.Duration
+
+	// LatencyMax caps a single injected delay. Defaults to 10 * LatencyP50.
+	LatencyMax time.Duration
+
+	// ErrorRate is the fraction (0-1) of affected requests answered with
+	// AbortStatus instead of reaching the handler.
+	ErrorRate float64
+
+	// AbortStatus is the status used for injected errors. Defaults to 503.
+	AbortStatus int
+
+	// ResetRate is the fraction (0-1) of affected requests whose connection is
+	// closed without a response, simulating a connection reset.
+	ResetRate float64
+}
+
+// ChaosHeader is set on responses to requests that had faults injected,
+// listing them ("latency", "error").
+const ChaosHeader = "X-Chaos-Injected"
+
+// Chaos returns middleware that injects artificial latency, errors or
+// connection resets into a percentage of matching requests for game-day and
+// resilience testing. It is a no-op unless explicitly enabled with
+// ChaosConfig.Enabled or the FLASH_CHAOS environment variable, so it can be
+// left in the middleware chain of production builds. Routes can opt out with
+// Route.Bypass("chaos").
+//
+// For each affected request, latency is injected first (honoring request
+// cancellation), then the request is either reset, failed with AbortStatus
+// or passed to the handler.
+//
+// Example:
+//
+//	// FLASH_CHAOS=1 ./server
+//	app.Use(middleware.Chaos(middleware.ChaosConfig{
+//		Percent:    10,
+//		LatencyP50: 200 * time.Millisecond,
+//		ErrorRate:  0.2,
+//		Match: func(c flash.Ctx) bool {
+//			return strings.HasPrefix(c.Path(), "/api/")
+//		},
+//	}))
+func Chaos(cfg ChaosConfig) flash.Middleware {
+	if !cfg.Enabled {
+		cfg.Enabled, _ = strconv.ParseBool(os.Getenv(ChaosEnv))
+	}
+	if !cfg.Enabled {
+		return func(next flash.Handler) flash.Handler { return next }
+	}
+	if cfg.Percent == 0 {
+		cfg.Percent = 100
+	}
+	if cfg.LatencyMax <= 0 {
+		cfg.LatencyMax = 10 * cfg.LatencyP50
+	}
+	if cfg.AbortStatus == 0 {
+		cfg.AbortStatus = http.StatusServiceUnavailable
+	}
+	slog.Warn("chaos: fault injection enabled", "percent", cfg.Percent, "latency_p50", cfg.LatencyP50,
+		"error_rate", cfg.ErrorRate, "reset_rate", cfg.ResetRate)
+
+	return flash.Describe("chaos", cfg, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			if c.Bypassed("chaos") || (cfg.Match != nil && !cfg.Match(c)) {
+				return next(c)
+			}
+			if cfg.Percent < 100 && rand.Float64()*100 >= cfg.Percent {
+				return next(c)
+			}
+
+			if cfg.LatencyP50 > 0 {
+				c.ResponseWriter().Header().Add(ChaosHeader, "latency")
+				t := time.NewTimer(chaosDelay(cfg.LatencyP50, cfg.LatencyMax, rand.Float64()))
+				select {
+				case <-t.C:
+				case <-c.Context().Done():
+					t.Stop()
+					return c.Context().Err()
+				}
+			}
+
+			roll := rand.Float64()
+			switch {
+			case roll < cfg.ResetRate:
+				chaosReset(c)
+				return nil
+			case roll < cfg.ResetRate+cfg.ErrorRate:
+				c.ResponseWriter().Header().Add(ChaosHeader, "error")
+				return c.String(cfg.AbortStatus, http.StatusText(cfg.AbortStatus))
+			}
+			return next(c)
+		}
+	})
+}
+
+// chaosDelay maps u in [0, 1) to an exponentially distributed delay with
+// median p50, capped at limit.
+func chaosDelay(p50, limit time.Duration, u float64) time.Duration {
+	d := time.Duration(float64(p50) * -math.Log(1-u) / math.Ln2)
+	if d > limit {
+		return limit
+	}
+	return d
+}
+
+// chaosReset closes the client connection without a response. Writers that
+// cannot be hijacked (HTTP/2) abort the handler instead, which resets the
+// stream.
+func chaosReset(c flash.Ctx) {
+	if hj, ok := c.ResponseWriter().(http.Hijacker); ok {
+		if conn, _, err := hj.Hijack(); err == nil {
+			_ = conn.Close()
+			return
+		}
+	}
+	panic(http.ErrAbortHandler)
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+func chaosApp(cfg ChaosConfig) flash.App {
+	a := flash.New()
+	a.Use(Recover(), Chaos(cfg))
+	a.GET("/api/x", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+	a.GET("/health", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+	return a
+}
+
+func chaosGet(a flash.App, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestChaosDisabledByDefault(t *testing.T) {
+	t.Setenv(ChaosEnv, "")
+	a := chaosApp(ChaosConfig{ErrorRate: 1})
+	if rec := chaosGet(a, "/api/x"); rec.Code != http.StatusOK || rec.Header().Get(ChaosHeader) != "" {
+		t.Fatalf("chaos must be opt-in: %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestChaosErrorsForMatchingRequests(t *testing.T) {
+	t.Setenv(ChaosEnv, "true")
+	a := chaosApp(ChaosConfig{
+		ErrorRate:   1,
+		AbortStatus: http.StatusBadGateway,
+		Match:       func(c flash.Ctx) bool { return strings.HasPrefix(c.Path(), "/api/") },
+	})
+	rec := chaosGet(a, "/api/x")
+	if rec.Code != http.StatusBadGateway || rec.Header().Get(ChaosHeader) != "error" {
+		t.Fatalf("expected injected error, got %d %v", rec.Code, rec.Header())
+	}
+	if rec := chaosGet(a, "/health"); rec.Code != http.StatusOK {
+		t.Fatalf("non-matching request affected: %d", rec.Code)
+	}
+}
+
+func TestChaosLatency(t *testing.T) {
+	a := chaosApp(ChaosConfig{Enabled: true, LatencyP50: 2 * time.Millisecond, LatencyMax: 20 * time.Millisecond})
+	rec := chaosGet(a, "/api/x")
+	if rec.Code != http.StatusOK || rec.Header().Get(ChaosHeader) != "latency" {
+		t.Fatalf("got %d %v", rec.Code, rec.Header())
+	}
+
+	slow := chaosApp(ChaosConfig{Enabled: true, LatencyP50: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "/api/x", nil).WithContext(ctx)
+	start := time.Now()
+	slow.ServeHTTP(httptest.NewRecorder(), req)
+	if time.Since(start) > time.Second {
+		t.Fatalf("injected latency must stop when the request is canceled")
+	}
+}
+
+func TestChaosDelayDistribution(t *testing.T) {
+	if d := chaosDelay(100*time.Millisecond, time.Second, 0.5); d < 99*time.Millisecond || d > 101*time.Millisecond {
+		t.Fatalf("median delay = %v", d)
+	}
+	if d := chaosDelay(100*time.Millisecond, time.Second, 0); d != 0 {
+		t.Fatalf("u=0 delay = %v", d)
+	}
+	if d := chaosDelay(100*time.Millisecond, 300*time.Millisecond, 0.999); d != 300*time.Millisecond {
+		t.Fatalf("delay not capped: %v", d)
+	}
+}
+
+func TestChaosResetAndBypass(t *testing.T) {
+	a := flash.New()
+	a.Use(Recover(), Chaos(ChaosConfig{Enabled: true, ResetRate: 1}))
+	a.GET("/", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+	a.GET("/safe", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") }).Bypass("chaos")
+	srv := httptest.NewServer(a)
+	defer srv.Close()
+
+	if resp, err := http.Get(srv.URL); err == nil {
+		resp.Body.Close()
+		t.Fatalf("expected a connection reset, got %d", resp.StatusCode)
+	}
+	resp, err := http.Get(srv.URL + "/safe")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("bypassed route: %v", err)
+	}
+	resp.Body.Close()
+
+	// Writers that cannot be hijacked abort the handler through Recover.
+	defer func() {
+		if r := recover(); r != http.ErrAbortHandler {
+			t.Fatalf("expected http.ErrAbortHandler, got %v", r)
+		}
+	}()
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,410 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/goflash/flash/v2"
+)
+
+// ClientCertInfo describes the client certificate of a request.
+type ClientCertInfo struct {
+	// Subject is the certificate subject DN, e.g. "CN=billing,O=Example".
+	Subject string
+	// CommonName is the subject common name.
+	CommonName string
+	// SPIFFEID is the first "spiffe://" URI SAN, or "".
+	SPIFFEID string
+	// URIs, DNSNames and EmailAddresses are the subject alternative names.
+	URIs           []string
+	DNSNames       []string
+	EmailAddresses []string
+	// Hash is the hex SHA-256 of the DER certificate, when known.
+	Hash string
+	// Certificate is the parsed leaf, or nil when a proxy only forwarded its
+	// fields (XFCC without Cert).
+	Certificate *x509.Certificate
+	// Forwarded reports whether the certificate came from a proxy header
+	// rather than the TLS connection.
+	Forwarded bool
+}
+
+// Client certificate errors passed to ClientCertConfig.ErrorResponse.
+var (
+	// ErrClientCertRequired means the request presented no certificate.
+	ErrClientCertRequired = errors.New("client certificate required")
+	// ErrClientCertInvalid means the certificate failed verification or the
+	// forwarded header could not be parsed.
+	ErrClientCertInvalid = errors.New("invalid client certificate")
+	// ErrClientCertForbidden means the certificate is valid but its identity
+	// is not allowed.
+	ErrClientCertForbidden = errors.New("client certificate not allowed")
+)
+
+// ClientCertConfig configures the ClientCert middleware.
+//
+// Example (TLS terminated in-process):
+//
+//	srv := &http.Server{Handler: app, TLSConfig: &tls.Config{
+//		ClientCAs:  pool,
+//		ClientAuth: tls.RequireAndVerifyClientCert,
+//	}}
+//	app.Use(middleware.ClientCert(middleware.ClientCertConfig{
+//		AllowedSPIFFEIDs: []string{"spiffe://example.org/ns/prod/*"},
+//	}))
+//
+// Example (behind Envoy or Istio):
+//
+//	app.Use(middleware.ClientCert(middleware.ClientCertConfig{
+//		TrustedProxies:   []string{"10.0.0.0/8"},
+//		AllowedSPIFFEIDs: []string{"spiffe://example.org/ns/prod/sa/billing"},
+//	}))
+//
+//	app.POST("/charge", func(c flash.Ctx) error {
+//		cert, _ := middleware.CertFromCtx(c)
+//		log.Println("caller", cert.SPIFFEID)
+//		...
+//	})
+type ClientCertConfig struct {
+	// Roots verifies client certificates that the TLS server did not verify
+	// itself (tls.RequestClientCert or tls.RequireAnyClientCert) and
+	// certificates forwarded in full by a proxy. When nil, only chains
+	// verified by the TLS server are accepted from connections, and forwarded
+	// certificates are trusted as the proxy reports them.
+	Roots *x509.CertPool
+
+	// Header is the proxy header carrying the client certificate. It may use
+	// the Envoy/Istio X-Forwarded-Client-Cert format or hold a URL-encoded
+	// PEM certificate (nginx $ssl_client_escaped_cert). Defaults to
+	// "X-Forwarded-Client-Cert".
+	Header string
+
+	// TrustedProxies lists the CIDRs allowed to send Header. The header is
+	// ignored from any other peer; when empty, it is never read.
+	TrustedProxies []string
+
+	// AllowedSPIFFEIDs restricts callers to these SPIFFE IDs. An entry ending
+	// in "/*" matches every ID under that path.
+	AllowedSPIFFEIDs []string
+	// AllowedCNs restricts callers to these subject common names. A request
+	// passes when it matches either list; with both empty, any verified
+	// certificate passes.
+	AllowedCNs []string
+
+	// Authorize runs after the allow lists for custom checks; a non-nil error
+	// rejects the request through ErrorResponse.
+	Authorize func(c flash.Ctx, cert *ClientCertInfo) error
+
+	// Optional lets requests without a certificate through; CertFromCtx then
+	// reports false. Invalid or disallowed certificates are still rejected.
+	Optional bool
+
+	// ErrorResponse produces the response for rejected requests. If nil,
+	// ErrClientCertForbidden and Authorize errors map to 403 and other errors
+	// to 401.
+	ErrorResponse func(c flash.Ctx, err error) error
+}
+
+type clientCertContextKey struct{}
+
+// ClientCert returns middleware that authenticates callers by their client
+// certificate, taken from the TLS connection or, from trusted proxies, from
+// a forwarded header. The certificate's identity is checked against the
+// allow lists and stored on the request context for CertFromCtx.
+func ClientCert(cfg ClientCertConfig) flash.Middleware {
+	if cfg.Header == "" {
+		cfg.Header = "X-Forwarded-Client-Cert"
+	}
+	if cfg.ErrorResponse == nil {
+		cfg.ErrorResponse = defaultClientCertErrorResponse
+	}
+	var proxies []*net.IPNet
+	for _, p := range cfg.TrustedProxies {
+		if _, n, err := net.ParseCIDR(p); err == nil {
+			proxies = append(proxies, n)
+		}
+	}
+
+	return flash.Describe("clientcert", cfg, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			r := c.Request()
+			info, err := clientCertFromRequest(r, &cfg, proxies)
+			if err != nil {
+				return cfg.ErrorResponse(c, err)
+			}
+			if info == nil {
+				if cfg.Optional {
+					return next(c)
+				}
+				return cfg.ErrorResponse(c, ErrClientCertRequired)
+			}
+			if !clientCertAllowed(info, cfg.AllowedSPIFFEIDs, cfg.AllowedCNs) {
+				return cfg.ErrorResponse(c, ErrClientCertForbidden)
+			}
+			if cfg.Authorize != nil {
+				if err := cfg.Authorize(c, info); err != nil {
+					return cfg.ErrorResponse(c, err)
+				}
+			}
+			c.SetRequest(r.WithContext(context.WithValue(r.Context(), clientCertContextKey{}, info)))
+			return next(c)
+		}
+	})
+}
+
+// CertFromCtx returns the client certificate accepted by the ClientCert
+// middleware.
+func CertFromCtx(c flash.Ctx) (*ClientCertInfo, bool) {
+	info, ok := c.Context().Value(clientCertContextKey{}).(*ClientCertInfo)
+	return info, ok && info != nil
+}
+
+func defaultClientCertErrorResponse(c flash.Ctx, err error) error {
+	if errors.Is(err, ErrClientCertRequired) || errors.Is(err, ErrClientCertInvalid) {
+		return c.String(http.StatusUnauthorized, err.Error())
+	}
+	return c.String(http.StatusForbidden, ErrClientCertForbidden.Error())
+}
+
+// clientCertFromRequest returns the connection's certificate, else the
+// forwarded one from a trusted proxy, else nil.
+func clientCertFromRequest(r *http.Request, cfg *ClientCertConfig, proxies []*net.IPNet) (*ClientCertInfo, error) {
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		leaf := r.TLS.PeerCertificates[0]
+		if len(r.TLS.VerifiedChains) == 0 {
+			if cfg.Roots == nil || verifyClientCert(leaf, r.TLS.PeerCertificates[1:], cfg.Roots) != nil {
+				return nil, ErrClientCertInvalid
+			}
+		}
+		return certInfo(leaf), nil
+	}
+
+	v := r.Header.Get(cfg.Header)
+	if v == "" || !fromTrustedProxy(r, proxies) {
+		return nil, nil
+	}
+	info, chain, err := parseForwardedCert(v)
+	if err != nil {
+		return nil, ErrClientCertInvalid
+	}
+	if cfg.Roots != nil {
+		if info.Certificate == nil || verifyClientCert(info.Certificate, chain, cfg.Roots) != nil {
+			return nil, ErrClientCertInvalid
+		}
+	}
+	info.Forwarded = true
+	return info, nil
+}
+
+func verifyClientCert(leaf *x509.Certificate, intermediates []*x509.Certificate, roots *x509.CertPool) error {
+	pool := x509.NewCertPool()
+	for _, c := range intermediates {
+		pool.AddCert(c)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{Roots: roots, Intermediates: pool, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+	return err
+}
+
+// fromTrustedProxy reports whether the peer address is in proxies.
+func fromTrustedProxy(r *http.Request, proxies []*net.IPNet) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range proxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func certInfo(cert *x509.Certificate) *ClientCertInfo {
+	sum := sha256.Sum256(cert.Raw)
+	info := &ClientCertInfo{
+		Subject:        cert.Subject.String(),
+		CommonName:     cert.Subject.CommonName,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		Hash:           hex.EncodeToString(sum[:]),
+		Certificate:    cert,
+	}
+	for _, u := range cert.URIs {
+		info.URIs = append(info.URIs, u.String())
+	}
+	info.SPIFFEID = firstSPIFFEID(info.URIs)
+	return info
+}
+
+func firstSPIFFEID(uris []string) string {
+	for _, u := range uris {
+		if strings.HasPrefix(u, "spiffe://") {
+			return u
+		}
+	}
+	return ""
+}
+
+// parseForwardedCert parses a URL-encoded PEM certificate or the last
+// element of an X-Forwarded-Client-Cert header, which describes the client
+// of the closest proxy. It returns the chain forwarded alongside the leaf.
+func parseForwardedCert(v string) (*ClientCertInfo, []*x509.Certificate, error) {
+	if unescaped, err := url.QueryUnescape(v); err == nil && strings.HasPrefix(strings.TrimSpace(unescaped), "-----BEGIN") {
+		certs, err := parsePEMCerts(unescaped)
+		if err != nil {
+			return nil, nil, err
+		}
+		return certInfo(certs[0]), certs[1:], nil
+	}
+
+	elems := splitXFCC(v, ',')
+	fields := map[string][]string{}
+	for _, kv := range splitXFCC(elems[len(elems)-1], ';') {
+		k, val, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, nil, ErrClientCertInvalid
+		}
+		fields[strings.ToLower(strings.TrimSpace(k))] = append(fields[strings.ToLower(strings.TrimSpace(k))], unquoteXFCC(val))
+	}
+	first := func(k string) string {
+		if vs := fields[k]; len(vs) > 0 {
+			return vs[0]
+		}
+		return ""
+	}
+
+	var chain []*x509.Certificate
+	if s := first("chain"); s != "" {
+		p, err := url.QueryUnescape(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		if chain, err = parsePEMCerts(p); err != nil {
+			return nil, nil, err
+		}
+		chain = chain[1:] // the chain starts with the leaf
+	}
+	if s := first("cert"); s != "" {
+		p, err := url.QueryUnescape(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		certs, err := parsePEMCerts(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		return certInfo(certs[0]), chain, nil
+	}
+
+	info := &ClientCertInfo{Subject: first("subject"), Hash: first("hash"), URIs: fields["uri"], DNSNames: fields["dns"]}
+	if info.Subject == "" && info.Hash == "" && len(info.URIs) == 0 && len(info.DNSNames) == 0 {
+		return nil, nil, ErrClientCertInvalid
+	}
+	for _, rdn := range strings.Split(info.Subject, ",") {
+		if cn, ok := strings.CutPrefix(strings.TrimSpace(rdn), "CN="); ok {
+			info.CommonName = cn
+			break
+		}
+	}
+	info.SPIFFEID = firstSPIFFEID(info.URIs)
+	return info, nil, nil
+}
+
+func parsePEMCerts(s string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(s)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, c)
+	}
+	if len(certs) == 0 {
+		return nil, ErrClientCertInvalid
+	}
+	return certs, nil
+}
+
+// splitXFCC splits s on sep outside double-quoted sections.
+func splitXFCC(s string, sep byte) []string {
+	var parts []string
+	quoted, escaped, start := false, false, 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case s[i] == '\\':
+			escaped = true
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unquoteXFCC strips surrounding quotes and backslash escapes.
+func unquoteXFCC(v string) string {
+	v = strings.TrimSpace(v)
+	if len(v) < 2 || v[0] != '"' || v[len(v)-1] != '"' {
+		return v
+	}
+	v = v[1 : len(v)-1]
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] == '\\' && i+1 < len(v) {
+			i++
+		}
+		b.WriteByte(v[i])
+	}
+	return b.String()
+}
+
+// clientCertAllowed checks the identity against the allow lists.
+func clientCertAllowed(info *ClientCertInfo, spiffeIDs, cns []string) bool {
+	if len(spiffeIDs) == 0 && len(cns) == 0 {
+		return true
+	}
+	if info.SPIFFEID != "" {
+		for _, id := range spiffeIDs {
+			if prefix, ok := strings.CutSuffix(id, "/*"); ok {
+				if strings.HasPrefix(info.SPIFFEID, prefix+"/") {
+					return true
+				}
+			} else if info.SPIFFEID == id {
+				return true
+			}
+		}
+	}
+	if info.CommonName != "" {
+		for _, cn := range cns {
+			if info.CommonName == cn {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,231 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+// testCA issues client certificates for the tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+func (ca *testCA) issue(t *testing.T, cn, spiffeID string) tls.Certificate {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn, Organization: []string{"Example"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if spiffeID != "" {
+		u, _ := url.Parse(spiffeID)
+		tmpl.URIs = []*url.URL{u}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := x509.ParseCertificate(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func certPEM(c tls.Certificate) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Certificate[0]}))
+}
+
+func clientCertApp(cfg ClientCertConfig) flash.App {
+	a := flash.New()
+	a.Use(ClientCert(cfg))
+	a.GET("/", func(c flash.Ctx) error {
+		info, ok := CertFromCtx(c)
+		if !ok {
+			return c.String(http.StatusOK, "anonymous")
+		}
+		return c.String(http.StatusOK, info.CommonName+"|"+info.SPIFFEID)
+	})
+	return a
+}
+
+// tlsGet serves app over TLS with clientAuth and requests "/" presenting cert.
+func tlsGet(t *testing.T, app flash.App, clientAuth tls.ClientAuthType, pool *x509.CertPool, cert *tls.Certificate) (int, string) {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(app)
+	srv.TLS = &tls.Config{ClientAuth: clientAuth, ClientCAs: pool}
+	srv.StartTLS()
+	defer srv.Close()
+	client := srv.Client()
+	tr := client.Transport.(*http.Transport)
+	if cert != nil {
+		tr.TLSClientConfig.Certificates = []tls.Certificate{*cert}
+	}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(b)
+}
+
+func TestClientCertTLSVerifiedByServer(t *testing.T) {
+	ca := newTestCA(t)
+	cert := ca.issue(t, "billing", "spiffe://example.org/ns/prod/sa/billing")
+	app := clientCertApp(ClientCertConfig{AllowedSPIFFEIDs: []string{"spiffe://example.org/ns/prod/*"}})
+	code, body := tlsGet(t, app, tls.RequireAndVerifyClientCert, ca.pool, &cert)
+	if code != http.StatusOK || body != "billing|spiffe://example.org/ns/prod/sa/billing" {
+		t.Fatalf("got %d %q", code, body)
+	}
+
+	other := ca.issue(t, "jobs", "spiffe://example.org/ns/dev/sa/jobs")
+	if code, _ := tlsGet(t, app, tls.RequireAndVerifyClientCert, ca.pool, &other); code != http.StatusForbidden {
+		t.Fatalf("disallowed SPIFFE ID: %d", code)
+	}
+}
+
+func TestClientCertTLSVerifiedByMiddleware(t *testing.T) {
+	ca := newTestCA(t)
+	cert := ca.issue(t, "billing", "")
+	app := clientCertApp(ClientCertConfig{Roots: ca.pool, AllowedCNs: []string{"billing"}})
+	if code, body := tlsGet(t, app, tls.RequestClientCert, nil, &cert); code != http.StatusOK || body != "billing|" {
+		t.Fatalf("got %d %q", code, body)
+	}
+
+	rogue := newTestCA(t).issue(t, "billing", "")
+	if code, _ := tlsGet(t, app, tls.RequestClientCert, nil, &rogue); code != http.StatusUnauthorized {
+		t.Fatalf("untrusted issuer: %d", code)
+	}
+	if code, _ := tlsGet(t, app, tls.RequestClientCert, nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("no certificate: %d", code)
+	}
+
+	noRoots := clientCertApp(ClientCertConfig{})
+	if code, _ := tlsGet(t, noRoots, tls.RequestClientCert, nil, &cert); code != http.StatusUnauthorized {
+		t.Fatalf("unverified chain without Roots: %d", code)
+	}
+}
+
+func clientCertGet(app flash.App, remote, header, value string) (int, string) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remote
+	if value != "" {
+		req.Header.Set(header, value)
+	}
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+func TestClientCertXFCCFields(t *testing.T) {
+	app := clientCertApp(ClientCertConfig{TrustedProxies: []string{"10.0.0.0/8"}, AllowedSPIFFEIDs: []string{"spiffe://example.org/sa/billing"}})
+	xfcc := `By=spiffe://example.org/sa/gateway;Hash=abc;Subject="CN=edge,O=Example";URI=spiffe://example.org/sa/edge,` +
+		`By=spiffe://example.org/sa/api;Hash=def;Subject="CN=billing,O=Example, Inc.";URI=spiffe://example.org/sa/billing`
+
+	if code, body := clientCertGet(app, "10.1.2.3:5000", "X-Forwarded-Client-Cert", xfcc); code != http.StatusOK || body != "billing|spiffe://example.org/sa/billing" {
+		t.Fatalf("trusted proxy: %d %q", code, body)
+	}
+	if code, _ := clientCertGet(app, "203.0.113.9:5000", "X-Forwarded-Client-Cert", xfcc); code != http.StatusUnauthorized {
+		t.Fatalf("header from untrusted peer must be ignored: %d", code)
+	}
+	if code, _ := clientCertGet(app, "10.1.2.3:5000", "X-Forwarded-Client-Cert", "garbage"); code != http.StatusUnauthorized {
+		t.Fatalf("malformed header: %d", code)
+	}
+}
+
+func TestClientCertXFCCWithCertVerified(t *testing.T) {
+	ca := newTestCA(t)
+	cert := ca.issue(t, "billing", "spiffe://example.org/sa/billing")
+	app := clientCertApp(ClientCertConfig{Roots: ca.pool, TrustedProxies: []string{"10.0.0.0/8"}})
+
+	xfcc := `Hash=x;Cert="` + url.QueryEscape(certPEM(cert)) + `";URI=spiffe://example.org/sa/forged`
+	if code, body := clientCertGet(app, "10.0.0.1:1", "X-Forwarded-Client-Cert", xfcc); code != http.StatusOK || body != "billing|spiffe://example.org/sa/billing" {
+		t.Fatalf("got %d %q", code, body)
+	}
+	// With Roots set, field-only headers cannot be verified.
+	if code, _ := clientCertGet(app, "10.0.0.1:1", "X-Forwarded-Client-Cert", "URI=spiffe://example.org/sa/billing"); code != http.StatusUnauthorized {
+		t.Fatalf("unverifiable header: %d", code)
+	}
+	rogue := newTestCA(t).issue(t, "billing", "")
+	if code, _ := clientCertGet(app, "10.0.0.1:1", "X-Forwarded-Client-Cert", "Cert="+url.QueryEscape(certPEM(rogue))); code != http.StatusUnauthorized {
+		t.Fatalf("untrusted forwarded cert: %d", code)
+	}
+}
+
+func TestClientCertEscapedPEMHeader(t *testing.T) {
+	cert := newTestCA(t).issue(t, "reports", "")
+	app := clientCertApp(ClientCertConfig{Header: "X-SSL-Client-Cert", TrustedProxies: []string{"127.0.0.1/32"}, AllowedCNs: []string{"reports"}})
+	if code, body := clientCertGet(app, "127.0.0.1:9", "X-SSL-Client-Cert", url.QueryEscape(certPEM(cert))); code != http.StatusOK || body != "reports|" {
+		t.Fatalf("got %d %q", code, body)
+	}
+}
+
+func TestClientCertOptionalAndAuthorize(t *testing.T) {
+	denied := errors.New("denied")
+	app := clientCertApp(ClientCertConfig{
+		Optional:       true,
+		TrustedProxies: []string{"10.0.0.0/8"},
+		Authorize: func(_ flash.Ctx, cert *ClientCertInfo) error {
+			if cert.CommonName == "blocked" {
+				return denied
+			}
+			return nil
+		},
+	})
+	if code, body := clientCertGet(app, "10.0.0.1:1", "", ""); code != http.StatusOK || body != "anonymous" {
+		t.Fatalf("optional: %d %q", code, body)
+	}
+	if code, _ := clientCertGet(app, "10.0.0.1:1", "X-Forwarded-Client-Cert", `Subject="CN=blocked"`); code != http.StatusForbidden {
+		t.Fatalf("authorize: %d", code)
+	}
+}
+
+func TestSplitXFCC(t *testing.T) {
+	parts := splitXFCC(`a="x,y";b=\"c,d`, ',')
+	if len(parts) != 2 || parts[0] != `a="x,y";b=\"c` || parts[1] != "d" {
+		t.Fatalf("parts = %q", parts)
+	}
+	if got := unquoteXFCC(`"CN=a \"b\""`); got != `CN=a "b"` {
+		t.Fatalf("unquote = %q", got)
+	}
+}
@@ -0,0 +1,383 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/goflash/flash/v2"
+)
+
+// CompressionMetaKey is the route metadata key holding a route's
+// CompressionPolicy. Set it on a route with Route.Meta or on every route of a
+// group with Group.Meta.
+//
+// Example:
+//
+//	app.GET("/events", StreamEvents).Meta(middleware.CompressionMetaKey, middleware.CompressionPolicy{Disable: true})
+//	api := app.Group("/api").Meta(middleware.CompressionMetaKey, middleware.CompressionPolicy{MinSize: 4096})
+const CompressionMetaKey = "compression"
+
+// DefaultCompressionMinSize is the response size, in bytes, below which
+// responses are not compressed unless a policy sets MinSize.
+const DefaultCompressionMinSize = 1024
+
+// DefaultCompressionExcludes lists media types that are already compressed
+// or streamed, so compressing them wastes CPU or delays events. A trailing
+// "/*" matches a whole top-level type.
+var DefaultCompressionExcludes = []string{
+	"image/png", "image/jpeg", "image/gif", "image/webp", "image/avif",
+	"video/*", "audio/*",
+	"font/woff", "font/woff2",
+	"application/zip", "application/gzip", "application/x-gzip", "application/zstd",
+	"text/event-stream",
+}
+
+// CompressionDecider has the final say on compressing a response with
+// encoding (e.g. "gzip", "br"). contentType is the response media type and
+// size its length in bytes, or -1 when unknown (streamed responses).
+type CompressionDecider func(c flash.Ctx, encoding, contentType string, size int) bool
+
+// CompressionPolicy controls which responses compression middleware
+// compress. The middleware configuration is the base policy; a policy in the
+// route metadata (see CompressionMetaKey) refines it, and the most specific
+// level wins: a route's policy replaces its group's (see Route.Meta), its
+// Disable or Enable overrides the base Disable, Decide replaces the base one
+// when set, a positive MinSize replaces the base threshold and ExcludeTypes
+// are added to the base ones.
+type CompressionPolicy struct {
+	// Disable turns compression off.
+	Disable bool
+
+	// Enable turns compression back on for a route or group when the base
+	// policy disables it. It has no effect in the base policy.
+	Enable bool
+
+	// MinSize skips responses of known size below it. Defaults to
+	// DefaultCompressionMinSize.
+	MinSize int
+
+	// ExcludeTypes lists media types, or "type/*" patterns, never
+	// compressed, in addition to DefaultCompressionExcludes.
+	ExcludeTypes []string
+
+	// Decide, when set, is consulted after the other rules pass and may
+	// refuse compression, e.g. for clients known to mishandle it.
+	Decide CompressionDecider
+}
+
+// RouteCompressionPolicy returns the CompressionPolicy of the matched route,
+// declared with Route.Meta or Group.Meta under CompressionMetaKey. Both
+// CompressionPolicy and *CompressionPolicy values are accepted.
+func RouteCompressionPolicy(c flash.Ctx) (CompressionPolicy, bool) {
+	v, ok := c.RouteMeta(CompressionMetaKey)
+	if !ok {
+		return CompressionPolicy{}, false
+	}
+	switch p := v.(type) {
+	case CompressionPolicy:
+		return p, true
+	case *CompressionPolicy:
+		if p != nil {
+			return *p, true
+		}
+	}
+	return CompressionPolicy{}, false
+}
+
+// ShouldCompress reports whether the response of c may be compressed with
+// encoding under base refined by the route's policy. Compression middleware
+// (Compress, or e.g. goflash/compression) call it once the response content type and, if
+// known, size are available, so routes and groups can tune compression
+// without their own middleware chain. Responses that already carry a
+// Content-Encoding are never compressed again.
+//
+// Example (inside a compression middleware):
+//
+//	ct := w.Header().Get("Content-Type")
+//	if !middleware.ShouldCompress(c, cfg.Policy, "gzip", ct, len(buf)) {
+//		return writeIdentity(w, buf)
+//	}
+func ShouldCompress(c flash.Ctx, base CompressionPolicy, encoding, contentType string, size int) bool {
+	if c.ResponseWriter().Header().Get("Content-Encoding") != "" {
+		return false
+	}
+	return effectivePolicy(c, base).allows(c, encoding, contentType, size)
+}
+
+// effectivePolicy refines base with the route's policy. The result has
+// Disable resolved and MinSize defaulted.
+func effectivePolicy(c flash.Ctx, base CompressionPolicy) CompressionPolicy {
+	p := base
+	if rp, ok := RouteCompressionPolicy(c); ok {
+		switch {
+		case rp.Disable:
+			p.Disable = true
+		case rp.Enable:
+			p.Disable = false
+		}
+		if rp.MinSize > 0 {
+			p.MinSize = rp.MinSize
+		}
+		if len(rp.ExcludeTypes) > 0 {
+			p.ExcludeTypes = append(p.ExcludeTypes[:len(p.ExcludeTypes):len(p.ExcludeTypes)], rp.ExcludeTypes...)
+		}
+		if rp.Decide != nil {
+			p.Decide = rp.Decide
+		}
+	}
+	if p.MinSize <= 0 {
+		p.MinSize = DefaultCompressionMinSize
+	}
+	return p
+}
+
+// allows applies an effective policy to a response.
+func (p CompressionPolicy) allows(c flash.Ctx, encoding, contentType string, size int) bool {
+	if p.Disable {
+		return false
+	}
+	if size >= 0 && size < p.MinSize {
+		return false
+	}
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mt
+	}
+	contentType = strings.ToLower(contentType)
+	if matchMediaType(contentType, DefaultCompressionExcludes) || matchMediaType(contentType, p.ExcludeTypes) {
+		return false
+	}
+	if p.Decide != nil {
+		return p.Decide(c, encoding, contentType, size)
+	}
+	return true
+}
+
+// matchMediaType reports whether mt matches one of patterns ("type/sub" or
+// "type/*").
+func matchMediaType(mt string, patterns []string) bool {
+	for _, pat := range patterns {
+		pat = strings.ToLower(pat)
+		if major, ok := strings.CutSuffix(pat, "/*"); ok {
+			if strings.HasPrefix(mt, major+"/") {
+				return true
+			}
+		} else if mt == pat {
+			return true
+		}
+	}
+	return false
+}
+
+// CompressConfig configures the Compress middleware.
+type CompressConfig struct {
+	// Policy is the base CompressionPolicy, refined per route and group with
+	// CompressionMetaKey.
+	Policy CompressionPolicy
+
+	// Level is the gzip compression level, from gzip.BestSpeed to
+	// gzip.BestCompression. Defaults to gzip.DefaultCompression.
+	Level int
+}
+
+// Compress returns middleware gzip-compressing responses for clients that
+// accept it, following the CompressionPolicy of each route (see
+// CompressionMetaKey and ShouldCompress). The response is held back until
+// MinSize bytes are written, the handler returns or it flushes, so small
+// responses are sent as-is with their Content-Length and streamed responses
+// are compressed chunk by chunk. "Vary: Accept-Encoding" is added to every
+// response the policy allows to compress. Routes can skip it with
+// Route.Bypass("compress").
+//
+// Example:
+//
+//	app.Use(middleware.Compress(middleware.CompressConfig{Policy: middleware.CompressionPolicy{MinSize: 512}}))
+//	app.GET("/events", StreamEvents).Meta(middleware.CompressionMetaKey, middleware.CompressionPolicy{Disable: true})
+func Compress(cfgs ...CompressConfig) flash.Middleware {
+	var cfg CompressConfig
+	if len(cfgs) > 0 {
+		cfg = cfgs[0]
+	}
+	if cfg.Level == 0 || cfg.Level < gzip.HuffmanOnly || cfg.Level > gzip.BestCompression {
+		cfg.Level = gzip.DefaultCompression
+	}
+	pool := &sync.Pool{New: func() any {
+		w, _ := gzip.NewWriterLevel(io.Discard, cfg.Level)
+		return w
+	}}
+	return flash.Describe("compress", cfg, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			if c.Bypassed("compress") || c.Method() == http.MethodHead {
+				return next(c)
+			}
+			orig := c.ResponseWriter()
+			cw := &compressWriter{
+				ResponseWriter: orig,
+				c:              c,
+				policy:         effectivePolicy(c, cfg.Policy),
+				accepts:        acceptsGzip(c),
+				pool:           pool,
+			}
+			c.SetResponseWriter(cw)
+			err := next(c)
+			closeErr := cw.Close()
+			c.SetResponseWriter(orig)
+			if err != nil {
+				return err
+			}
+			return closeErr
+		}
+	})
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding allows gzip. An
+// explicit gzip element takes precedence over "*".
+func acceptsGzip(c flash.Ctx) bool {
+	star := false
+	for _, e := range c.HeaderList("Accept-Encoding") {
+		switch {
+		case strings.EqualFold(e.Value, "gzip"):
+			return e.Quality > 0
+		case e.Value == "*":
+			star = e.Quality > 0
+		}
+	}
+	return star
+}
+
+// compressWriter buffers the start of a response until the compression
+// decision can be made, then writes it gzip-compressed or as-is.
+type compressWriter struct {
+	http.ResponseWriter
+	c       flash.Ctx
+	policy  CompressionPolicy // effective policy of the route
+	accepts bool              // client accepts gzip
+	pool    *sync.Pool
+
+	status  int
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	switch {
+	case w.decided:
+		w.ResponseWriter.WriteHeader(code)
+	case code < http.StatusOK:
+		w.ResponseWriter.WriteHeader(code) // informational, e.g. 103 Early Hints
+	case code == http.StatusNoContent || code == http.StatusNotModified:
+		w.status = code
+		w.decide(0)
+	default:
+		w.status = code
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		if w.policy.Disable || w.Header().Get("Content-Encoding") != "" {
+			w.decide(-1)
+		} else {
+			w.buf = append(w.buf, p...)
+			if len(w.buf) < w.policy.MinSize {
+				return len(p), nil
+			}
+			size := -1
+			if n, err := strconv.Atoi(w.Header().Get("Content-Length")); err == nil {
+				size = n
+			}
+			if err := w.decide(size); err != nil {
+				return 0, err
+			}
+			return len(p), nil
+		}
+	}
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush sends what was written so far, compressed if decided so.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if err := w.decide(-1); err != nil {
+			return
+		}
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *compressWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// decide writes the header, compressed or not, and the buffered body. size is
+// the response size, or -1 when unknown.
+func (w *compressWriter) decide(size int) error {
+	w.decided = true
+	h := w.Header()
+	ct := h.Get("Content-Type")
+	if ct == "" && len(w.buf) > 0 {
+		ct = http.DetectContentType(w.buf)
+		h.Set("Content-Type", ct)
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	compress := false
+	if size != 0 && w.status != http.StatusNoContent && w.status != http.StatusNotModified &&
+		h.Get("Content-Encoding") == "" && w.policy.allows(w.c, "gzip", ct, size) {
+		h.Add("Vary", "Accept-Encoding")
+		compress = w.accepts
+	}
+	if compress {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", "gzip")
+		w.gz = w.pool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Close decides a response still held back, now of known size, and
+// finishes the gzip stream. A response nothing was written to is left
+// untouched, so an error handler can still write it.
+func (w *compressWriter) Close() error {
+	if !w.decided {
+		if len(w.buf) == 0 && w.status == 0 {
+			w.decided = true
+			return nil
+		}
+		if err := w.decide(len(w.buf)); err != nil {
+			return err
+		}
+	}
+	if w.gz == nil {
+		return nil
+	}
+	err := w.gz.Close()
+	w.gz.Reset(io.Discard)
+	w.pool.Put(w.gz)
+	w.gz = nil
+	return err
+}
@@ -0,0 +1,251 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goflash/flash/v2"
+)
+
+// compressDecisions serves path and returns ShouldCompress for each case.
+func compressDecisions(t *testing.T, a flash.App, path string, base CompressionPolicy, cases []struct {
+	ct   string
+	size int
+}) []bool {
+	t.Helper()
+	var out []bool
+	a.GET(path, func(c flash.Ctx) error {
+		for _, tc := range cases {
+			out = append(out, ShouldCompress(c, base, "gzip", tc.ct, tc.size))
+		}
+		return nil
+	})
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	return out
+}
+
+func TestShouldCompress_Defaults(t *testing.T) {
+	cases := []struct {
+		ct   string
+		size int
+	}{
+		{"application/json; charset=utf-8", 4096},
+		{"text/html", 100},
+		{"text/html", -1}, // unknown size
+		{"image/png", 1 << 20},
+		{"image/svg+xml", 4096},
+		{"video/mp4", 1 << 20},
+		{"text/event-stream", -1},
+	}
+	got := compressDecisions(t, flash.New(), "/", CompressionPolicy{}, cases)
+	want := []bool{true, false, true, false, true, false, false}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("%s/%d: got %v", cases[i].ct, cases[i].size, got[i])
+		}
+	}
+}
+
+func TestShouldCompress_RouteAndGroupPolicies(t *testing.T) {
+	a := flash.New()
+	api := a.Group("/api").Meta(CompressionMetaKey, CompressionPolicy{MinSize: 4096, ExcludeTypes: []string{"application/pdf"}})
+	var big, small, pdf, csv, sse, decided bool
+	api.GET("/report", func(c flash.Ctx) error {
+		base := CompressionPolicy{ExcludeTypes: []string{"text/csv"}}
+		big = ShouldCompress(c, base, "gzip", "application/json", 8192)
+		small = ShouldCompress(c, base, "gzip", "application/json", 2048)
+		pdf = ShouldCompress(c, base, "gzip", "application/pdf", 8192)
+		csv = ShouldCompress(c, base, "gzip", "text/csv", 8192)
+		return nil
+	})
+	api.GET("/events", func(c flash.Ctx) error {
+		sse = ShouldCompress(c, CompressionPolicy{}, "gzip", "application/json", 8192)
+		return nil
+	}).Meta(CompressionMetaKey, &CompressionPolicy{Disable: true})
+	a.GET("/legacy", func(c flash.Ctx) error {
+		decided = ShouldCompress(c, CompressionPolicy{}, "br", "text/html", 8192)
+		return nil
+	}).Meta(CompressionMetaKey, CompressionPolicy{Decide: func(c flash.Ctx, enc, ct string, size int) bool {
+		return enc != "br" || ct != "text/html"
+	}})
+
+	for _, p := range []string{"/api/report", "/api/events", "/legacy"} {
+		a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, p, nil))
+	}
+	if !big || small || pdf || csv {
+		t.Fatalf("group policy: big=%v small=%v pdf=%v csv=%v", big, small, pdf, csv)
+	}
+	if sse {
+		t.Fatalf("disabled route compressed")
+	}
+	if decided {
+		t.Fatalf("Decide hook ignored")
+	}
+}
+
+func TestShouldCompress_AlreadyEncoded(t *testing.T) {
+	a := flash.New()
+	var got bool
+	a.GET("/", func(c flash.Ctx) error {
+		c.Header("Content-Encoding", "br")
+		got = ShouldCompress(c, CompressionPolicy{}, "gzip", "text/html", 8192)
+		return nil
+	})
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if got {
+		t.Fatalf("encoded response compressed again")
+	}
+}
+
+func TestShouldCompress_RouteEnableOverridesBase(t *testing.T) {
+	a := flash.New()
+	off := a.Group("/off").Meta(CompressionMetaKey, CompressionPolicy{Disable: true})
+	var group, route bool
+	base := CompressionPolicy{Disable: true}
+	off.GET("/a", func(c flash.Ctx) error {
+		group = ShouldCompress(c, CompressionPolicy{}, "gzip", "text/html", 8192)
+		return nil
+	})
+	off.GET("/b", func(c flash.Ctx) error {
+		route = ShouldCompress(c, base, "gzip", "text/html", 8192)
+		return nil
+	}).Meta(CompressionMetaKey, CompressionPolicy{Enable: true})
+	for _, p := range []string{"/off/a", "/off/b"} {
+		a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, p, nil))
+	}
+	if group || !route {
+		t.Fatalf("group=%v route=%v", group, route)
+	}
+}
+
+func serveCompressed(a flash.App, path, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	return rec
+}
+
+func gunzip(t *testing.T, b []byte) string {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	return string(out)
+}
+
+func TestCompress_RoutePolicies(t *testing.T) {
+	big := strings.Repeat("hello compression ", 200)
+	a := flash.New()
+	a.Use(Compress())
+	page := func(c flash.Ctx) error { return c.String(http.StatusOK, big) }
+	a.GET("/page", page)
+	a.GET("/small", func(c flash.Ctx) error { return c.String(http.StatusOK, "tiny") })
+	a.GET("/png", func(c flash.Ctx) error { _, err := c.Send(http.StatusOK, "image/png", []byte(big)); return err })
+	a.GET("/off", page).Meta(CompressionMetaKey, CompressionPolicy{Disable: true})
+	api := a.Group("/api").Meta(CompressionMetaKey, CompressionPolicy{MinSize: 1 << 20})
+	api.GET("/report", page)
+	api.GET("/export", page).Meta(CompressionMetaKey, CompressionPolicy{MinSize: 64})
+	a.GET("/fail", func(c flash.Ctx) error { return flash.NewError(http.StatusTeapot, big) })
+
+	rec := serveCompressed(a, "/page", "br;q=1, gzip;q=0.8")
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("/page headers = %v", rec.Header())
+	}
+	if got := gunzip(t, rec.Body.Bytes()); got != big {
+		t.Fatalf("/page body = %q", got)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Fatalf("/page Content-Type = %q", ct)
+	}
+
+	for path, wantVary := range map[string]bool{"/small": false, "/png": false, "/off": false, "/api/report": false} {
+		rec := serveCompressed(a, path, "gzip")
+		if rec.Header().Get("Content-Encoding") != "" || (rec.Header().Get("Vary") != "") != wantVary {
+			t.Fatalf("%s: headers = %v", path, rec.Header())
+		}
+		if path != "/small" && rec.Body.String() != big {
+			t.Fatalf("%s: body altered", path)
+		}
+	}
+
+	rec = serveCompressed(a, "/api/export", "gzip")
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("route MinSize did not override the group: %v", rec.Header())
+	}
+
+	rec = serveCompressed(a, "/page", "")
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != big || rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("identity response: headers = %v", rec.Header())
+	}
+	rec = serveCompressed(a, "/page", "gzip;q=0, *")
+	if rec.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("gzip;q=0 compressed")
+	}
+
+	rec = serveCompressed(a, "/fail", "gzip")
+	if rec.Code != http.StatusTeapot || rec.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("/fail: code %d headers %v", rec.Code, rec.Header())
+	}
+}
+
+func TestCompress_BaseDisabledRouteEnabled(t *testing.T) {
+	big := strings.Repeat("x", 4096)
+	a := flash.New()
+	a.Use(Compress(CompressConfig{Policy: CompressionPolicy{Disable: true}, Level: gzip.BestSpeed}))
+	a.GET("/default", func(c flash.Ctx) error { return c.String(http.StatusOK, big) })
+	a.GET("/on", func(c flash.Ctx) error { return c.String(http.StatusOK, big) }).
+		Meta(CompressionMetaKey, CompressionPolicy{Enable: true})
+
+	if rec := serveCompressed(a, "/default", "gzip"); rec.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("base Disable ignored")
+	}
+	rec := serveCompressed(a, "/on", "gzip")
+	if rec.Header().Get("Content-Encoding") != "gzip" || gunzip(t, rec.Body.Bytes()) != big {
+		t.Fatalf("route Enable ignored: %v", rec.Header())
+	}
+}
+
+func TestCompress_Streaming(t *testing.T) {
+	a := flash.New()
+	a.Use(Compress())
+	a.GET("/stream", func(c flash.Ctx) error {
+		c.Header("Content-Type", "application/x-ndjson")
+		w := c.ResponseWriter()
+		_, _ = w.Write([]byte(`{"n":1}` + "\n"))
+		http.NewResponseController(w).Flush()
+		_, _ = w.Write([]byte(`{"n":2}` + "\n"))
+		return nil
+	})
+	a.GET("/sse", func(c flash.Ctx) error {
+		c.Header("Content-Type", "text/event-stream")
+		w := c.ResponseWriter()
+		_, _ = w.Write([]byte("data: hi\n\n"))
+		http.NewResponseController(w).Flush()
+		return nil
+	})
+
+	rec := serveCompressed(a, "/stream", "gzip")
+	if rec.Header().Get("Content-Encoding") != "gzip" || !rec.Flushed {
+		t.Fatalf("/stream headers = %v flushed = %v", rec.Header(), rec.Flushed)
+	}
+	if got := gunzip(t, rec.Body.Bytes()); got != "{\"n\":1}\n{\"n\":2}\n" {
+		t.Fatalf("/stream body = %q", got)
+	}
+	rec = serveCompressed(a, "/sse", "gzip")
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != "data: hi\n\n" || !rec.Flushed {
+		t.Fatalf("/sse: headers = %v body = %q", rec.Header(), rec.Body.String())
+	}
+}
@@ -0,0 +1,226 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+// ConcurrencyLimitConfig configures the ConcurrencyLimit middleware.
+//
+// Unlike RateLimit, which bounds how many requests a client may start per time
+// window, ConcurrencyLimit bounds how many requests a client may have in flight
+// at the same moment. It protects against clients that hold many slow requests
+// open (large uploads, long polls, expensive reports) while staying within their
+// request rate.
+//
+// Requests over the limit may wait in a bounded per-key queue for up to
+// QueueTimeout; once the queue is full, or the wait times out, or the client
+// goes away, the request is rejected with ErrorResponse (default 429 with
+// Retry-After).
+//
+// Keys are extracted exactly like RateLimit: by default the client IP, honoring
+// X-Forwarded-For only from TrustedProxies, then truncated to MaxKeyLength and
+// sanitized.
+//
+// Example:
+//
+//	// At most 4 in-flight requests per client IP, queueing 8 more for up to 2s
+//	app.Use(middleware.ConcurrencyLimit(middleware.ConcurrencyLimitConfig{
+//		Max:            4,
+//		QueueSize:      8,
+//		QueueTimeout:   2 * time.Second,
+//		TrustedProxies: []string{"10.0.0.0/8"},
+//	}))
+//
+//	// Per authenticated user, combined with a request-rate limit
+//	api.Use(
+//		middleware.RateLimit(middleware.WithKeyFunc(userKey)),
+//		middleware.ConcurrencyLimit(middleware.ConcurrencyLimitConfig{Max: 2, KeyFunc: userKey}),
+//	)
+type ConcurrencyLimitConfig struct {
+	// Max is the number of requests per key allowed to run concurrently.
+	// If <= 0, defaults to 10.
+	Max int
+
+	// QueueSize is the number of additional requests per key that may wait for
+	// a free slot. 0 disables queueing: requests over Max are rejected at once.
+	QueueSize int
+
+	// QueueTimeout bounds how long a queued request waits for a slot.
+	// If <= 0, defaults to 5 seconds.
+	QueueTimeout time.Duration
+
+	// KeyFunc extracts the client key. If nil, ClientIPKeyFunc(TrustedProxies) is used.
+	KeyFunc func(c flash.Ctx) string
+
+	// TrustedProxies lists CIDRs whose X-Forwarded-For headers are honored by the
+	// default KeyFunc.
+	TrustedProxies []string
+
+	// MaxKeyLength bounds key length to prevent memory exhaustion. If <= 0, defaults to 256.
+	MaxKeyLength int
+
+	// SkipFunc, when it returns true, lets the request bypass the limiter.
+	SkipFunc func(c flash.Ctx) bool
+
+	// ErrorResponse produces the response for rejected requests. retryAfter is
+	// the suggested wait (QueueTimeout). If nil, a 429 with Retry-After is sent.
+	ErrorResponse func(c flash.Ctx, retryAfter time.Duration) error
+}
+
+// ConcurrencyLimiter tracks in-flight requests per key. It is created by
+// NewConcurrencyLimiter and may be shared between several middleware instances
+// (for example, to apply one per-user budget across route groups).
+type ConcurrencyLimiter struct {
+	mu      sync.Mutex
+	slots   map[string]*concurrencySlot
+	max     int
+	queue   int
+	timeout time.Duration
+}
+
+// concurrencySlot is the per-key state. refs counts holders and waiters so the
+// entry can be dropped once the key goes idle.
+type concurrencySlot struct {
+	sem     chan struct{}
+	waiting int
+	refs    int
+}
+
+// NewConcurrencyLimiter returns a limiter allowing limit concurrent holders per
+// key with up to queue waiters, each waiting at most timeout. Non-positive limit
+// and timeout fall back to 10 and 5 seconds; negative queue is treated as 0.
+func NewConcurrencyLimiter(limit, queue int, timeout time.Duration) *ConcurrencyLimiter {
+	if limit <= 0 {
+		limit = 10
+	}
+	if queue < 0 {
+		queue = 0
+	}
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &ConcurrencyLimiter{slots: make(map[string]*concurrencySlot), max: limit, queue: queue, timeout: timeout}
+}
+
+// Acquire obtains a slot for key, waiting in the queue if allowed. It returns
+// false when the queue is full, the wait times out, or done is closed. On
+// success the caller must call Release(key) exactly once.
+func (l *ConcurrencyLimiter) Acquire(key string, done <-chan struct{}) bool {
+	l.mu.Lock()
+	s := l.slots[key]
+	if s == nil {
+		s = &concurrencySlot{sem: make(chan struct{}, l.max)}
+		l.slots[key] = s
+	}
+	select {
+	case s.sem <- struct{}{}:
+		s.refs++
+		l.mu.Unlock()
+		return true
+	default:
+	}
+	if s.waiting >= l.queue {
+		l.mu.Unlock()
+		return false
+	}
+	s.waiting++
+	s.refs++
+	l.mu.Unlock()
+
+	t := time.NewTimer(l.timeout)
+	defer t.Stop()
+	ok := false
+	select {
+	case s.sem <- struct{}{}:
+		ok = true
+	case <-t.C:
+	case <-done:
+	}
+
+	l.mu.Lock()
+	s.waiting--
+	if !ok {
+		l.unref(key, s)
+	}
+	l.mu.Unlock()
+	return ok
+}
+
+// Release frees a slot obtained by Acquire.
+func (l *ConcurrencyLimiter) Release(key string) {
+	l.mu.Lock()
+	if s := l.slots[key]; s != nil {
+		<-s.sem
+		l.unref(key, s)
+	}
+	l.mu.Unlock()
+}
+
+// InFlight reports the number of requests currently holding a slot for key.
+func (l *ConcurrencyLimiter) InFlight(key string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if s := l.slots[key]; s != nil {
+		return len(s.sem)
+	}
+	return 0
+}
+
+// unref drops a reference and deletes idle entries. l.mu must be held.
+func (l *ConcurrencyLimiter) unref(key string, s *concurrencySlot) {
+	s.refs--
+	if s.refs == 0 {
+		delete(l.slots, key)
+	}
+}
+
+// ConcurrencyLimit returns middleware limiting simultaneous in-flight requests
+// per client key. See ConcurrencyLimitConfig for details.
+func ConcurrencyLimit(cfg ConcurrencyLimitConfig) flash.Middleware {
+	return ConcurrencyLimitWith(NewConcurrencyLimiter(cfg.Max, cfg.QueueSize, cfg.QueueTimeout), cfg)
+}
+
+// ConcurrencyLimitWith is like ConcurrencyLimit but uses a shared limiter; the
+// Max, QueueSize and QueueTimeout fields of cfg are ignored in favor of l's.
+//
+// Example:
+//
+//	perUser := middleware.NewConcurrencyLimiter(2, 0, 0)
+//	cfg := middleware.ConcurrencyLimitConfig{KeyFunc: userKey}
+//	reports.Use(middleware.ConcurrencyLimitWith(perUser, cfg))
+//	exports.Use(middleware.ConcurrencyLimitWith(perUser, cfg))
+func ConcurrencyLimitWith(l *ConcurrencyLimiter, cfg ConcurrencyLimitConfig) flash.Middleware {
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = ClientIPKeyFunc(cfg.TrustedProxies)
+	}
+	if cfg.MaxKeyLength <= 0 {
+		cfg.MaxKeyLength = 256
+	}
+	if cfg.ErrorResponse == nil {
+		cfg.ErrorResponse = defaultConcurrencyErrorResponse
+	}
+
+	return flash.Describe("concurrency", cfg, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			if cfg.SkipFunc != nil && cfg.SkipFunc(c) {
+				return next(c)
+			}
+			key := normalizeKey(cfg.KeyFunc(c), cfg.MaxKeyLength)
+			if !l.Acquire(key, c.Context().Done()) {
+				return cfg.ErrorResponse(c, l.timeout)
+			}
+			defer l.Release(key)
+			return next(c)
+		}
+	})
+}
+
+// defaultConcurrencyErrorResponse sends 429 with a Retry-After hint.
+func defaultConcurrencyErrorResponse(c flash.Ctx, retryAfter time.Duration) error {
+	c.Header("Retry-After", formatSeconds(retryAfter))
+	return c.String(http.StatusTooManyRequests, "Too Many Concurrent Requests")
+}
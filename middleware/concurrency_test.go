@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+func TestConcurrencyLimiter_AcquireRelease(t *testing.T) {
+	l := NewConcurrencyLimiter(2, 0, 0)
+	if !l.Acquire("a", nil) || !l.Acquire("a", nil) {
+		t.Fatalf("first two acquires should succeed")
+	}
+	if l.Acquire("a", nil) {
+		t.Fatalf("third acquire should fail without a queue")
+	}
+	if !l.Acquire("b", nil) {
+		t.Fatalf("keys must be independent")
+	}
+	if l.InFlight("a") != 2 {
+		t.Fatalf("InFlight = %d", l.InFlight("a"))
+	}
+	l.Release("a")
+	l.Release("a")
+	l.Release("b")
+	if len(l.slots) != 0 {
+		t.Fatalf("idle keys should be dropped: %v", l.slots)
+	}
+}
+
+func TestConcurrencyLimiter_QueueTimeoutAndCancel(t *testing.T) {
+	l := NewConcurrencyLimiter(1, 1, 20*time.Millisecond)
+	l.Acquire("k", nil)
+
+	start := time.Now()
+	if l.Acquire("k", nil) {
+		t.Fatalf("queued acquire should time out")
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Fatalf("queued acquire returned before timeout")
+	}
+
+	done := make(chan struct{})
+	close(done)
+	if l.Acquire("k", done) {
+		t.Fatalf("canceled acquire should fail")
+	}
+
+	got := make(chan bool)
+	go func() { got <- l.Acquire("k", nil) }()
+	time.Sleep(5 * time.Millisecond)
+	l.Release("k")
+	if !<-got {
+		t.Fatalf("queued acquire should get the released slot")
+	}
+	l.Release("k")
+	if len(l.slots) != 0 {
+		t.Fatalf("idle keys should be dropped")
+	}
+}
+
+func TestConcurrencyLimit_Middleware(t *testing.T) {
+	a := flash.New()
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	a.Use(ConcurrencyLimit(ConcurrencyLimitConfig{Max: 1, QueueTimeout: time.Second}))
+	a.GET("/slow", func(c flash.Ctx) error {
+		started.Done()
+		<-release
+		return c.String(http.StatusOK, "done")
+	})
+
+	started.Add(1)
+	first := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		first <- rec.Code
+	}()
+	started.Wait()
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("expected 429 with Retry-After, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// A different client is not affected.
+	started.Add(1)
+	other := httptest.NewRequest(http.MethodGet, "/slow", nil)
+	other.RemoteAddr = "192.0.2.9:1234"
+	second := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, other)
+		second <- rec.Code
+	}()
+	started.Wait()
+	close(release)
+	if <-first != http.StatusOK || <-second != http.StatusOK {
+		t.Fatalf("in-flight requests should complete")
+	}
+}
+
+func TestConcurrencyLimit_SharedLimiterSkipAndCustomResponse(t *testing.T) {
+	l := NewConcurrencyLimiter(1, 0, 0)
+	cfg := ConcurrencyLimitConfig{
+		KeyFunc:  func(c flash.Ctx) string { return c.Request().Header.Get("X-User") },
+		SkipFunc: func(c flash.Ctx) bool { return c.Path() == "/health" },
+		ErrorResponse: func(c flash.Ctx, _ time.Duration) error {
+			return c.String(http.StatusServiceUnavailable, "busy")
+		},
+	}
+	a := flash.New()
+	a.Use(ConcurrencyLimitWith(l, cfg))
+	a.GET("/x", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+	a.GET("/health", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+
+	l.Acquire("alice", nil) // simulate a request held by another route group
+	do := func(path, user string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req = req.WithContext(context.Background())
+		req.Header.Set("X-User", user)
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := do("/x", "alice"); code != http.StatusServiceUnavailable {
+		t.Fatalf("alice should be limited, got %d", code)
+	}
+	if code := do("/health", "alice"); code != http.StatusOK {
+		t.Fatalf("skipped path should pass, got %d", code)
+	}
+	if code := do("/x", "bob"); code != http.StatusOK {
+		t.Fatalf("bob should pass, got %d", code)
+	}
+	if l.InFlight("bob") != 0 {
+		t.Fatalf("slot not released after request")
+	}
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+// FromConfig builds the middleware enabled in cfg, in a safe order: CORS first
+// (so preflights are answered before rate limiting), then RateLimit using
+// cfg.TrustedProxies for client IP extraction, then Compress with
+// cfg.Compression's level and minimum length. Disabled sections are skipped.
+//
+// Example:
+//
+//	cfg, _ := flash.LoadConfigEnv("FLASH", flash.Config{})
+//	a, _ := flash.NewFromConfig(cfg)
+//	mws, err := middleware.FromConfig(cfg)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	a.Use(mws...)
+func FromConfig(cfg flash.Config) ([]flash.Middleware, error) {
+	var mws []flash.Middleware
+	if c := cfg.CORS; c.Enabled {
+		mws = append(mws, CORS(CORSConfig{
+			Origins:     c.Origins,
+			Methods:     c.Methods,
+			Headers:     c.Headers,
+			Expose:      c.Expose,
+			Credentials: c.Credentials,
+			MaxAge:      c.MaxAge,
+		}))
+	}
+	if rl := cfg.RateLimit; rl.Enabled {
+		strategy, err := strategyFromSettings(rl.Strategy, rl.Limit, rl.Window.Std())
+		if err != nil {
+			return nil, err
+		}
+		mws = append(mws, RateLimit(WithStrategy(strategy), WithTrustedProxies(cfg.TrustedProxies)))
+	}
+	if cc := cfg.Compression; cc.Enabled {
+		mws = append(mws, Compress(CompressConfig{
+			Policy: CompressionPolicy{MinSize: cc.MinLength},
+			Level:  cc.Level,
+		}))
+	}
+	return mws, nil
+}
+
+// strategyFromSettings maps a serializable strategy name to a RateLimitStrategy.
+func strategyFromSettings(name string, limit int, window time.Duration) (RateLimitStrategy, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if window <= 0 {
+		window = time.Minute
+	}
+	switch strings.ToLower(strings.ReplaceAll(name, "-", "_")) {
+	case "", "token_bucket":
+		return NewTokenBucketStrategy(limit, window), nil
+	case "fixed_window":
+		return NewFixedWindowStrategy(limit, window), nil
+	case "sliding_window":
+		return NewSlidingWindowStrategy(limit, window), nil
+	default:
+		return nil, fmt.Errorf("middleware: unknown rate limit strategy %q", name)
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+func TestFromConfig_DisabledSectionsYieldNothing(t *testing.T) {
+	mws, err := FromConfig(flash.Config{})
+	if err != nil || len(mws) != 0 {
+		t.Fatalf("expected no middleware, got %d (%v)", len(mws), err)
+	}
+}
+
+func TestFromConfig_CORSAndRateLimit(t *testing.T) {
+	cfg := flash.Config{TrustedProxies: []string{"10.0.0.0/8"}}
+	cfg.CORS.Enabled = true
+	cfg.CORS.Origins = []string{"https://a.com"}
+	cfg.RateLimit.Enabled = true
+	cfg.RateLimit.Strategy = "fixed-window"
+	cfg.RateLimit.Limit = 1
+	cfg.RateLimit.Window = flash.Duration(time.Hour)
+
+	mws, err := FromConfig(cfg)
+	if err != nil || len(mws) != 2 {
+		t.Fatalf("expected 2 middleware, got %d (%v)", len(mws), err)
+	}
+	a := flash.New()
+	a.Use(mws...)
+	a.GET("/", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Origin", "https://a.com")
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		return rec
+	}
+	rec := send()
+	if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "https://a.com" {
+		t.Fatalf("first request: %d %v", rec.Code, rec.Header())
+	}
+	if rec := send(); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request should be limited, got %d", rec.Code)
+	}
+}
+
+func TestFromConfig_Compression(t *testing.T) {
+	cfg := flash.Config{}
+	cfg.Compression.Enabled = true
+	cfg.Compression.MinLength = 16
+	mws, err := FromConfig(cfg)
+	if err != nil || len(mws) != 1 {
+		t.Fatalf("expected 1 middleware, got %d (%v)", len(mws), err)
+	}
+	a := flash.New()
+	a.Use(mws...)
+	a.GET("/small", func(c flash.Ctx) error { return c.String(http.StatusOK, "tiny") })
+	a.GET("/large", func(c flash.Ctx) error { return c.String(http.StatusOK, strings.Repeat("compress me ", 10)) })
+
+	for path, want := range map[string]string{"/small": "", "/large": "gzip"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Content-Encoding"); got != want {
+			t.Fatalf("%s: Content-Encoding=%q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestStrategyFromSettings(t *testing.T) {
+	for _, name := range []string{"", "token_bucket", "fixed_window", "Sliding_Window"} {
+		if s, err := strategyFromSettings(name, 0, 0); err != nil || s == nil {
+			t.Fatalf("%q: %v", name, err)
+		}
+	}
+	cfg := flash.Config{}
+	cfg.RateLimit.Enabled = true
+	cfg.RateLimit.Strategy = "leaky"
+	if _, err := FromConfig(cfg); err == nil {
+		t.Fatalf("expected unknown strategy error")
+	}
+}
@@ -0,0 +1,533 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+// ConnLimitConfig configures the ConnLimit middleware.
+//
+// RateLimit and ConcurrencyLimit reason about requests; a WebSocket or
+// Server-Sent Events stream is a single request that stays open for minutes
+// or hours. ConnLimit tracks such long-lived connections in a ConnRegistry:
+// it bounds how many a client may hold open, closes connections that go idle,
+// and counts them for monitoring. Other requests pass through untouched.
+//
+// Keys are extracted exactly like RateLimit: by default the client IP,
+// honoring X-Forwarded-For only from TrustedProxies, then truncated to
+// MaxKeyLength and sanitized.
+//
+// Example:
+//
+//	conns := middleware.NewConnRegistry(middleware.ConnRegistryConfig{
+//		MaxPerKey:   5,
+//		MaxTotal:    10000,
+//		IdleTimeout: 2 * time.Minute,
+//	})
+//	app.Use(middleware.ConnLimit(middleware.ConnLimitConfig{Registry: conns}))
+//	app.GET("/events", StreamEvents).Meta(middleware.ConnMetaKey, true)
+//	app.GET("/metrics/conns", func(c flash.Ctx) error { return c.JSON(conns.Stats()) })
+type ConnLimitConfig struct {
+	// Registry tracks the connections. Share one registry between several
+	// middleware instances for a common budget. If nil, a registry with the
+	// ConnRegistryConfig defaults is created.
+	Registry *ConnRegistry
+
+	// IsConn reports whether a request opens a long-lived connection. If
+	// nil, IsConnRoute is used: only routes declared with
+	// Route.Meta(ConnMetaKey, true) are tracked. IsLongLived decides from the
+	// Upgrade and Accept headers instead, which clients control, so a client
+	// can leave a stream untracked by omitting them; use it only when every
+	// route it may match is meant to be tracked.
+	IsConn func(c flash.Ctx) bool
+
+	// KeyFunc extracts the client key. If nil, ClientIPKeyFunc(TrustedProxies) is used.
+	KeyFunc func(c flash.Ctx) string
+
+	// TrustedProxies lists CIDRs whose X-Forwarded-For headers are honored by the
+	// default KeyFunc.
+	TrustedProxies []string
+
+	// MaxKeyLength bounds key length to prevent memory exhaustion. If <= 0, defaults to 256.
+	MaxKeyLength int
+
+	// SkipFunc, when it returns true, lets the request bypass the limit.
+	SkipFunc func(c flash.Ctx) bool
+
+	// ErrorResponse produces the response for rejected connections. If nil,
+	// a 429 "Too Many Connections" is sent.
+	ErrorResponse func(c flash.Ctx) error
+}
+
+// ConnRegistryConfig configures a ConnRegistry.
+type ConnRegistryConfig struct {
+	// MaxPerKey is the number of connections a key may hold open.
+	// If <= 0, defaults to 10.
+	MaxPerKey int
+
+	// MaxTotal caps the connections across all keys. 0 means no cap.
+	MaxTotal int
+
+	// IdleTimeout closes connections with no traffic for this long. Writes
+	// to the response, reads and writes on a hijacked connection, and
+	// TrackedConn.Touch count as traffic. Idle connections are found by a
+	// sweep every quarter of IdleTimeout, but no more than once a second.
+	// 0 disables idle closing.
+	IdleTimeout time.Duration
+
+	// Janitor runs the idle sweep. If nil, DefaultJanitor is used.
+	Janitor *Janitor
+}
+
+// ConnRegistry tracks open long-lived connections per key. It is safe for
+// concurrent use. Create it with NewConnRegistry.
+type ConnRegistry struct {
+	mu        sync.Mutex
+	conns     map[string]map[*TrackedConn]struct{}
+	total     int
+	maxPerKey int
+	maxTotal  int
+	idle      time.Duration
+	task      *JanitorTask
+
+	opened     atomic.Uint64
+	rejected   atomic.Uint64
+	idleClosed atomic.Uint64
+}
+
+// TrackedConn is a connection registered in a ConnRegistry. Handlers get it
+// with ConnFromCtx, e.g. to Touch it on inbound messages or Close it.
+type TrackedConn struct {
+	Key     string    // client key the connection counts against
+	Started time.Time // when the connection was opened
+
+	reg      *ConnRegistry
+	last     atomic.Int64 // unix nanoseconds of the latest traffic
+	mu       sync.Mutex
+	closers  []func()
+	released bool
+}
+
+// ConnStats is a snapshot of a ConnRegistry.
+type ConnStats struct {
+	Active     int    `json:"active"`      // connections open now
+	Keys       int    `json:"keys"`        // keys holding at least one connection
+	Opened     uint64 `json:"opened"`      // connections accepted since creation
+	Rejected   uint64 `json:"rejected"`    // connections refused by MaxPerKey or MaxTotal
+	IdleClosed uint64 `json:"idle_closed"` // connections closed by IdleTimeout
+}
+
+// connCtxKey is the request context key of the *TrackedConn.
+type connCtxKey struct{}
+
+// NewConnRegistry returns an empty registry. With an IdleTimeout, an idle
+// sweep is scheduled on the janitor until Stop is called.
+func NewConnRegistry(cfg ConnRegistryConfig) *ConnRegistry {
+	if cfg.MaxPerKey <= 0 {
+		cfg.MaxPerKey = 10
+	}
+	r := &ConnRegistry{
+		conns:     make(map[string]map[*TrackedConn]struct{}),
+		maxPerKey: cfg.MaxPerKey,
+		maxTotal:  cfg.MaxTotal,
+		idle:      cfg.IdleTimeout,
+	}
+	if r.idle > 0 {
+		j := cfg.Janitor
+		if j == nil {
+			j = DefaultJanitor
+		}
+		interval := r.idle / 4
+		if interval < time.Second {
+			interval = time.Second
+		}
+		r.task = j.Schedule(interval, r.sweep)
+	}
+	return r
+}
+
+// Open registers a connection for key. closeFn is called when the
+// connection must end early (idle timeout, CloseKey, CloseAll), typically to
+// cancel the request context. It returns false, counting a rejection, when
+// key or the registry is at capacity. On success the caller must Close the
+// connection when it ends.
+func (r *ConnRegistry) Open(key string, closeFn func()) (*TrackedConn, bool) {
+	now := time.Now()
+	tc := &TrackedConn{Key: key, Started: now, reg: r}
+	tc.last.Store(now.UnixNano())
+	if closeFn != nil {
+		tc.closers = append(tc.closers, closeFn)
+	}
+	r.mu.Lock()
+	set := r.conns[key]
+	if len(set) >= r.maxPerKey || r.maxTotal > 0 && r.total >= r.maxTotal {
+		r.mu.Unlock()
+		r.rejected.Add(1)
+		return nil, false
+	}
+	if set == nil {
+		set = make(map[*TrackedConn]struct{})
+		r.conns[key] = set
+	}
+	set[tc] = struct{}{}
+	r.total++
+	r.mu.Unlock()
+	r.opened.Add(1)
+	return tc, true
+}
+
+// Active reports the number of open connections for key.
+func (r *ConnRegistry) Active(key string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.conns[key])
+}
+
+// Stats returns a snapshot of the registry counters.
+func (r *ConnRegistry) Stats() ConnStats {
+	r.mu.Lock()
+	s := ConnStats{Active: r.total, Keys: len(r.conns)}
+	r.mu.Unlock()
+	s.Opened = r.opened.Load()
+	s.Rejected = r.rejected.Load()
+	s.IdleClosed = r.idleClosed.Load()
+	return s
+}
+
+// WritePrometheus writes the registry counters in the Prometheus text
+// exposition format under the given metric name prefix (e.g. "myapp_ws").
+func (r *ConnRegistry) WritePrometheus(w io.Writer, prefix string) error {
+	s := r.Stats()
+	_, err := fmt.Fprintf(w, "# TYPE %[1]s_connections gauge\n%[1]s_connections %[2]d\n"+
+		"# TYPE %[1]s_connection_keys gauge\n%[1]s_connection_keys %[3]d\n"+
+		"# TYPE %[1]s_connections_opened_total counter\n%[1]s_connections_opened_total %[4]d\n"+
+		"# TYPE %[1]s_connections_rejected_total counter\n%[1]s_connections_rejected_total %[5]d\n"+
+		"# TYPE %[1]s_connections_idle_closed_total counter\n%[1]s_connections_idle_closed_total %[6]d\n",
+		prefix, s.Active, s.Keys, s.Opened, s.Rejected, s.IdleClosed)
+	return err
+}
+
+// CloseKey ends every connection of key and returns how many were open, e.g.
+// when a user logs out or is banned.
+func (r *ConnRegistry) CloseKey(key string) int {
+	r.mu.Lock()
+	list := make([]*TrackedConn, 0, len(r.conns[key]))
+	for tc := range r.conns[key] {
+		list = append(list, tc)
+	}
+	r.mu.Unlock()
+	for _, tc := range list {
+		tc.Close()
+	}
+	return len(list)
+}
+
+// CloseAll ends every connection, e.g. during shutdown, since
+// http.Server.Shutdown does not wait for hijacked connections and waits
+// for streaming responses indefinitely.
+func (r *ConnRegistry) CloseAll() {
+	r.mu.Lock()
+	var list []*TrackedConn
+	for _, set := range r.conns {
+		for tc := range set {
+			list = append(list, tc)
+		}
+	}
+	r.mu.Unlock()
+	for _, tc := range list {
+		tc.Close()
+	}
+}
+
+// Stop unschedules the idle sweep. Open connections are not closed.
+func (r *ConnRegistry) Stop() {
+	if r.task != nil {
+		r.task.Stop()
+	}
+}
+
+// sweep closes connections idle for longer than the idle timeout.
+func (r *ConnRegistry) sweep(now time.Time) {
+	cutoff := now.Add(-r.idle).UnixNano()
+	var idle []*TrackedConn
+	r.mu.Lock()
+	for _, set := range r.conns {
+		for tc := range set {
+			if tc.last.Load() < cutoff {
+				idle = append(idle, tc)
+			}
+		}
+	}
+	r.mu.Unlock()
+	for _, tc := range idle {
+		if tc.Close() {
+			r.idleClosed.Add(1)
+		}
+	}
+}
+
+// release removes tc from the registry.
+func (r *ConnRegistry) release(tc *TrackedConn) {
+	r.mu.Lock()
+	if set := r.conns[tc.Key]; set != nil {
+		if _, ok := set[tc]; ok {
+			delete(set, tc)
+			r.total--
+			if len(set) == 0 {
+				delete(r.conns, tc.Key)
+			}
+		}
+	}
+	r.mu.Unlock()
+}
+
+// Touch records traffic on the connection, postponing its idle timeout.
+func (tc *TrackedConn) Touch() { tc.last.Store(time.Now().UnixNano()) }
+
+// LastActivity returns the time of the latest traffic.
+func (tc *TrackedConn) LastActivity() time.Time { return time.Unix(0, tc.last.Load()) }
+
+// Close ends the connection: it is removed from the registry and its close
+// functions run. It reports whether this call closed it; later calls do
+// nothing.
+func (tc *TrackedConn) Close() bool { return tc.end(true) }
+
+// end removes tc from the registry once, running the close functions if
+// run is set.
+func (tc *TrackedConn) end(run bool) bool {
+	tc.mu.Lock()
+	if tc.released {
+		tc.mu.Unlock()
+		return false
+	}
+	tc.released = true
+	closers := tc.closers
+	tc.closers = nil
+	tc.mu.Unlock()
+	tc.reg.release(tc)
+	if run {
+		for _, fn := range closers {
+			fn()
+		}
+	}
+	return true
+}
+
+// onClose adds fn to the close functions, running it at once if the
+// connection is already closed.
+func (tc *TrackedConn) onClose(fn func()) {
+	tc.mu.Lock()
+	if !tc.released {
+		tc.closers = append(tc.closers, fn)
+		tc.mu.Unlock()
+		return
+	}
+	tc.mu.Unlock()
+	fn()
+}
+
+// ConnFromCtx returns the connection tracked by ConnLimit for the request.
+//
+// Example:
+//
+//	for {
+//		msg, err := ws.Read(ctx)
+//		if err != nil {
+//			return nil
+//		}
+//		if tc, ok := middleware.ConnFromCtx(c); ok {
+//			tc.Touch() // messages read from a library's own buffer count too
+//		}
+//		handle(msg)
+//	}
+func ConnFromCtx(c flash.Ctx) (*TrackedConn, bool) {
+	tc, ok := c.Context().Value(connCtxKey{}).(*TrackedConn)
+	return tc, ok
+}
+
+// ConnMetaKey is the route metadata key marking a route as a long-lived
+// connection tracked by ConnLimit. The value is a bool.
+//
+// Example:
+//
+//	app.GET("/ws", ServeWS).Meta(middleware.ConnMetaKey, true)
+//	live := app.Group("/live").Meta(middleware.ConnMetaKey, true)
+const ConnMetaKey = "conn"
+
+// IsConnRoute reports whether the matched route is declared long-lived with
+// Route.Meta or Group.Meta under ConnMetaKey. It is the default
+// ConnLimitConfig.IsConn.
+func IsConnRoute(c flash.Ctx) bool {
+	v, _ := c.RouteMeta(ConnMetaKey)
+	b, _ := v.(bool)
+	return b
+}
+
+// IsLongLived reports whether the request opens a WebSocket (an "Upgrade:
+// websocket" request) or a Server-Sent Events stream (Accept includes
+// text/event-stream). The decision rests on client headers; see
+// ConnLimitConfig.IsConn.
+func IsLongLived(c flash.Ctx) bool {
+	r := c.Request()
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return true
+	}
+	return strings.Contains(strings.ToLower(r.Header.Get("Accept")), "text/event-stream")
+}
+
+// ConnLimit returns middleware tracking long-lived connections in
+// cfg.Registry. See ConnLimitConfig for details.
+//
+// A connection counts until its handler returns, so WebSocket handlers
+// should serve the connection before returning rather than hand it to a
+// goroutine. Closing it (idle timeout, CloseKey, CloseAll) cancels the
+// request context, so SSE handlers selecting on c.Context().Done() return,
+// and closes a hijacked WebSocket connection.
+func ConnLimit(cfg ConnLimitConfig) flash.Middleware {
+	if cfg.Registry == nil {
+		cfg.Registry = NewConnRegistry(ConnRegistryConfig{})
+	}
+	if cfg.IsConn == nil {
+		cfg.IsConn = IsConnRoute
+	}
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = ClientIPKeyFunc(cfg.TrustedProxies)
+	}
+	if cfg.MaxKeyLength <= 0 {
+		cfg.MaxKeyLength = 256
+	}
+	if cfg.ErrorResponse == nil {
+		cfg.ErrorResponse = defaultConnLimitErrorResponse
+	}
+	reg := cfg.Registry
+
+	return flash.Describe("connlimit", cfg, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			if cfg.SkipFunc != nil && cfg.SkipFunc(c) || !cfg.IsConn(c) {
+				return next(c)
+			}
+			key := normalizeKey(cfg.KeyFunc(c), cfg.MaxKeyLength)
+			ctx, cancel := context.WithCancel(c.Context())
+			tc, ok := reg.Open(key, cancel)
+			if !ok {
+				cancel()
+				return cfg.ErrorResponse(c)
+			}
+			defer func() {
+				tc.end(false)
+				cancel()
+			}()
+			c.SetRequest(c.Request().WithContext(context.WithValue(ctx, connCtxKey{}, tc)))
+			c.SetResponseWriter(&connWriter{ResponseWriter: c.ResponseWriter(), tc: tc})
+			return next(c)
+		}
+	})
+}
+
+// defaultConnLimitErrorResponse sends 429.
+func defaultConnLimitErrorResponse(c flash.Ctx) error {
+	return c.String(http.StatusTooManyRequests, "Too Many Connections")
+}
+
+// connWriter records response writes as connection traffic and tracks
+// hijacked connections.
+type connWriter struct {
+	http.ResponseWriter
+	tc *TrackedConn
+}
+
+func (w *connWriter) Write(p []byte) (int, error) {
+	w.tc.Touch()
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush forwards to the underlying writer when supported.
+func (w *connWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack forwards to the underlying writer when supported. Reads and writes
+// on the returned connection and bufio.ReadWriter count as traffic, and
+// closing the tracked connection closes it.
+func (w *connWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("middleware: hijacking not supported")
+	}
+	conn, brw, err := h.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	w.tc.onClose(func() { _ = conn.Close() })
+	brw = bufio.NewReadWriter(
+		bufio.NewReader(&trackedReader{r: brw.Reader, tc: w.tc}),
+		bufio.NewWriter(&trackedWriter{w: brw.Writer, tc: w.tc}),
+	)
+	return &trackedNetConn{Conn: conn, tc: w.tc}, brw, nil
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *connWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// trackedNetConn is a hijacked connection whose I/O touches its TrackedConn.
+type trackedNetConn struct {
+	net.Conn
+	tc *TrackedConn
+}
+
+func (c *trackedNetConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.tc.Touch()
+	}
+	return n, err
+}
+
+func (c *trackedNetConn) Write(p []byte) (int, error) {
+	c.tc.Touch()
+	return c.Conn.Write(p)
+}
+
+// trackedReader touches its TrackedConn on reads from the hijacked
+// connection's buffered reader, which holds data read ahead by the server.
+type trackedReader struct {
+	r  io.Reader
+	tc *TrackedConn
+}
+
+func (r *trackedReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.tc.Touch()
+	}
+	return n, err
+}
+
+// trackedWriter touches its TrackedConn on writes to the hijacked
+// connection's buffered writer and flushes them through to the connection.
+type trackedWriter struct {
+	w  *bufio.Writer
+	tc *TrackedConn
+}
+
+func (w *trackedWriter) Write(p []byte) (int, error) {
+	w.tc.Touch()
+	n, err := w.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, w.w.Flush()
+}
@@ -0,0 +1,239 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+func TestConnRegistry_OpenClose(t *testing.T) {
+	r := NewConnRegistry(ConnRegistryConfig{MaxPerKey: 2, MaxTotal: 3})
+	a1, ok1 := r.Open("a", nil)
+	_, ok2 := r.Open("a", nil)
+	if !ok1 || !ok2 {
+		t.Fatalf("first two opens should succeed")
+	}
+	if _, ok := r.Open("a", nil); ok {
+		t.Fatalf("third open for a key should be rejected")
+	}
+	if _, ok := r.Open("b", nil); !ok {
+		t.Fatalf("keys must be independent")
+	}
+	if _, ok := r.Open("c", nil); ok {
+		t.Fatalf("MaxTotal should reject")
+	}
+	if r.Active("a") != 2 {
+		t.Fatalf("Active = %d", r.Active("a"))
+	}
+	if !a1.Close() || a1.Close() {
+		t.Fatalf("Close should report only the first call")
+	}
+	s := r.Stats()
+	if s.Active != 2 || s.Keys != 2 || s.Opened != 3 || s.Rejected != 2 {
+		t.Fatalf("Stats = %+v", s)
+	}
+	if n := r.CloseKey("a"); n != 1 {
+		t.Fatalf("CloseKey = %d", n)
+	}
+	r.CloseAll()
+	if s := r.Stats(); s.Active != 0 || s.Keys != 0 || len(r.conns) != 0 {
+		t.Fatalf("registry should be empty: %+v", s)
+	}
+}
+
+func TestConnRegistry_IdleSweep(t *testing.T) {
+	j := NewJanitor()
+	r := NewConnRegistry(ConnRegistryConfig{IdleTimeout: time.Minute, Janitor: j})
+	defer r.Stop()
+	if j.Len() != 1 {
+		t.Fatalf("idle sweep should be scheduled")
+	}
+	closed := 0
+	idle, _ := r.Open("k", func() { closed++ })
+	busy, _ := r.Open("k", func() { closed++ })
+	idle.last.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+	busy.Touch()
+
+	r.sweep(time.Now())
+	if closed != 1 || r.Active("k") != 1 || r.Stats().IdleClosed != 1 {
+		t.Fatalf("closed=%d active=%d stats=%+v", closed, r.Active("k"), r.Stats())
+	}
+	if busy.LastActivity().Before(time.Now().Add(-time.Second)) {
+		t.Fatalf("Touch should update LastActivity")
+	}
+	r.Stop()
+	if j.Len() != 0 {
+		t.Fatalf("Stop should unschedule the sweep")
+	}
+}
+
+func TestConnRegistry_WritePrometheus(t *testing.T) {
+	r := NewConnRegistry(ConnRegistryConfig{MaxPerKey: 1})
+	r.Open("k", nil)
+	r.Open("k", nil)
+	var buf bytes.Buffer
+	if err := r.WritePrometheus(&buf, "app_ws"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"app_ws_connections 1\n", "app_ws_connections_rejected_total 1\n", "# TYPE app_ws_connections_opened_total counter\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("missing %q in:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestIsLongLived(t *testing.T) {
+	a := flash.New()
+	var got []bool
+	a.GET("/", func(c flash.Ctx) error {
+		got = append(got, IsLongLived(c))
+		return nil
+	})
+	for _, h := range []http.Header{
+		{"Upgrade": {"WebSocket"}},
+		{"Accept": {"text/event-stream"}},
+		{"Accept": {"application/json"}},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header = h
+		a.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if len(got) != 3 || !got[0] || !got[1] || got[2] {
+		t.Fatalf("IsLongLived = %v", got)
+	}
+}
+
+func TestConnLimit_SSE(t *testing.T) {
+	reg := NewConnRegistry(ConnRegistryConfig{MaxPerKey: 1})
+	a := flash.New()
+	a.Use(ConnLimit(ConnLimitConfig{Registry: reg}))
+	started := make(chan *TrackedConn)
+	a.GET("/events", func(c flash.Ctx) error {
+		tc, _ := ConnFromCtx(c)
+		_, _ = c.ResponseWriter().Write([]byte("data: hi\n\n"))
+		started <- tc
+		<-c.Context().Done()
+		return nil
+	}).Meta(ConnMetaKey, true)
+	a.GET("/plain", func(c flash.Ctx) error {
+		if _, ok := ConnFromCtx(c); ok {
+			t.Errorf("plain requests should not be tracked")
+		}
+		return c.String(http.StatusOK, "ok")
+	})
+	sse := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/events", nil)
+		req.Header.Set("Accept", "text/event-stream")
+		return req
+	}
+
+	done := make(chan struct{})
+	go func() {
+		a.ServeHTTP(httptest.NewRecorder(), sse())
+		close(done)
+	}()
+	tc := <-started
+	if tc == nil || reg.Active(tc.Key) != 1 {
+		t.Fatalf("connection should be tracked")
+	}
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, sse())
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("second stream: status = %d, want 429", rec.Code)
+	}
+	for _, req := range []*http.Request{httptest.NewRequest(http.MethodGet, "/plain", nil), sse()} {
+		req.URL.Path = "/plain" // stream headers alone do not opt a route in
+		rec = httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("plain request: status = %d", rec.Code)
+		}
+	}
+
+	if reg.CloseKey(tc.Key) != 1 {
+		t.Fatalf("CloseKey should find the stream")
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("closing the connection should cancel the handler context")
+	}
+	if reg.Stats().Active != 0 {
+		t.Fatalf("stream should be released: %+v", reg.Stats())
+	}
+}
+
+// hijackRecorder is a ResponseRecorder that can be hijacked.
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	conn net.Conn
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return h.conn, bufio.NewReadWriter(bufio.NewReader(h.conn), bufio.NewWriter(h.conn)), nil
+}
+
+func TestConnLimit_HijackedConn(t *testing.T) {
+	reg := NewConnRegistry(ConnRegistryConfig{})
+	a := flash.New()
+	a.Use(ConnLimit(ConnLimitConfig{Registry: reg, KeyFunc: func(flash.Ctx) string { return "u1" }}))
+	var wg sync.WaitGroup
+	wg.Add(1)
+	a.GET("/ws", func(c flash.Ctx) error {
+		defer wg.Done()
+		conn, brw, err := c.ResponseWriter().(http.Hijacker).Hijack()
+		if err != nil {
+			return err
+		}
+		tc, _ := ConnFromCtx(c)
+		before := tc.LastActivity()
+		time.Sleep(time.Millisecond)
+		_, _ = conn.Write([]byte("x"))
+		if !tc.LastActivity().After(before) {
+			t.Errorf("writes should touch the connection")
+		}
+		before = tc.LastActivity()
+		time.Sleep(time.Millisecond)
+		if _, err := brw.ReadByte(); err != nil {
+			t.Errorf("read: %v", err)
+		}
+		if !tc.LastActivity().After(before) {
+			t.Errorf("buffered reads should touch the connection")
+		}
+		before = tc.LastActivity()
+		time.Sleep(time.Millisecond)
+		if _, err := brw.WriteString("y"); err != nil || brw.Flush() != nil {
+			t.Errorf("buffered write failed")
+		}
+		if !tc.LastActivity().After(before) {
+			t.Errorf("buffered writes should touch the connection")
+		}
+		reg.CloseAll()
+		if _, err := conn.Read(make([]byte, 1)); err == nil {
+			t.Errorf("closing the tracked connection should close the hijacked one")
+		}
+		return nil
+	}).Meta(ConnMetaKey, true)
+
+	server, client := net.Pipe()
+	defer client.Close()
+	go func() {
+		_, _ = client.Read(make([]byte, 1))
+		_, _ = client.Write([]byte("z"))
+		_, _ = client.Read(make([]byte, 1))
+	}()
+	a.ServeHTTP(&hijackRecorder{ResponseRecorder: httptest.NewRecorder(), conn: server}, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	wg.Wait()
+	if reg.Active("u1") != 0 {
+		t.Fatalf("connection should be released")
+	}
+}
@@ -133,7 +133,7 @@ func CORS(cfg CORSConfig) flash.Middleware {
 		panic("CORS: cannot use wildcard origin (*) with credentials=true for security reasons")
 	}
 
-	return func(next flash.Handler) flash.Handler {
+	return flash.Describe("cors", cfg, func(next flash.Handler) flash.Handler {
 		return func(c flash.Ctx) error {
 			origin := c.Request().Header.Get("Origin")
 
@@ -219,7 +219,7 @@ func CORS(cfg CORSConfig) flash.Middleware {
 			}
 			return next(c)
 		}
-	}
+	})
 }
 
 // uniqOrDefault returns the input slice with duplicates removed, or the default
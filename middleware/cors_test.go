@@ -321,3 +321,35 @@ func TestCORSPreflightWithInvalidHeaders(t *testing.T) {
 		t.Errorf("expected 403, got %d", rec.Code)
 	}
 }
+
+func TestCORSPreflightMountedMux(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/users", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("users")) })
+
+	a := flash.New(flash.WithMountMiddleware())
+	a.Use(CORS(CORSConfig{Origins: []string{"https://app.example.com"}, Methods: []string{"GET", "POST"}}))
+	a.Mount("/admin/*path", mux)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodOptions, "/admin/users", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	a.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("preflight status %d", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Fatalf("allow-origin %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(got, "POST") {
+		t.Fatalf("allow-methods %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	a.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "users" || rec.Header().Get("Access-Control-Allow-Origin") == "" {
+		t.Fatalf("get: %d %q %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+}
@@ -144,7 +144,7 @@ func CSRF(cfgs ...CSRFConfig) flash.Middleware {
 	if len(cfgs) > 0 {
 		cfg = cfgs[0]
 	}
-	return func(next flash.Handler) flash.Handler {
+	return flash.Describe("csrf", cfg, func(next flash.Handler) flash.Handler {
 		return func(c flash.Ctx) error {
 			// Only protect unsafe methods
 			if c.Method() == http.MethodGet || c.Method() == http.MethodHead || c.Method() == http.MethodOptions {
@@ -161,7 +161,7 @@ func CSRF(cfgs ...CSRFConfig) flash.Middleware {
 			}
 			return next(c)
 		}
-	}
+	})
 }
 
 // ensureCSRFCookie sets a CSRF cookie if one doesn't already exist.
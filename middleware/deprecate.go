@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+// DeprecationInfo configures the Deprecate middleware.
+//
+// Example:
+//
+//	usage := &middleware.DeprecationUsage{}
+//	v1 := app.Group("/v1", middleware.Deprecate(middleware.DeprecationInfo{
+//		Date:      time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+//		Sunset:    time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+//		Link:      "https://example.com/docs/v1-migration",
+//		Successor: "/v2",
+//		Consumer:  func(c flash.Ctx) string { return c.Request().Header.Get("X-API-Key-ID") },
+//		Usage:     usage,
+//	}))
+//
+//	// later: who still calls v1?
+//	for _, u := range usage.Snapshot() {
+//		log.Printf("%s %s: %d requests, last %s", u.Consumer, u.Route, u.Requests, u.LastSeen)
+//	}
+type DeprecationInfo struct {
+	// Date is when the endpoint was deprecated, sent in the Deprecation
+	// header (RFC 9745). When zero, "Deprecation: true" is sent.
+	Date time.Time
+
+	// Sunset is when the endpoint stops working, sent in the Sunset header
+	// (RFC 8594). Omitted when zero.
+	Sunset time.Time
+
+	// Link points to documentation about the deprecation, sent as
+	// Link: <...>; rel="deprecation".
+	Link string
+
+	// Successor is the URL of the replacement endpoint, sent as
+	// Link: <...>; rel="successor-version".
+	Successor string
+
+	// Enforce answers 410 Gone instead of calling the handler once Sunset has
+	// passed.
+	Enforce bool
+
+	// Consumer identifies the caller (API key, client ID...) for Usage. When
+	// nil or empty, usage is counted under "".
+	Consumer func(c flash.Ctx) string
+
+	// Usage, when non-nil, counts requests per route and consumer.
+	Usage *DeprecationUsage
+}
+
+// DeprecationUsage counts requests to deprecated endpoints. It is safe for
+// concurrent use; the zero value is ready to use and may be shared by several
+// Deprecate middleware.
+type DeprecationUsage struct {
+	mu   sync.Mutex
+	uses map[deprecationKey]*DeprecatedUse
+}
+
+type deprecationKey struct{ route, consumer string }
+
+// DeprecatedUse is a snapshot of the requests one consumer made to one
+// deprecated route.
+type DeprecatedUse struct {
+	Route    string // route pattern, e.g. "/v1/users/:id"
+	Consumer string
+	Requests uint64
+	LastSeen time.Time
+}
+
+func (u *DeprecationUsage) observe(route, consumer string, now time.Time) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.uses == nil {
+		u.uses = make(map[deprecationKey]*DeprecatedUse)
+	}
+	k := deprecationKey{route, consumer}
+	e := u.uses[k]
+	if e == nil {
+		e = &DeprecatedUse{Route: route, Consumer: consumer}
+		u.uses[k] = e
+	}
+	e.Requests++
+	e.LastSeen = now
+}
+
+// Snapshot returns the counters, most requested first.
+func (u *DeprecationUsage) Snapshot() []DeprecatedUse {
+	u.mu.Lock()
+	out := make([]DeprecatedUse, 0, len(u.uses))
+	for _, e := range u.uses {
+		out = append(out, *e)
+	}
+	u.mu.Unlock()
+	slices.SortFunc(out, func(a, b DeprecatedUse) int {
+		if c := cmp.Compare(b.Requests, a.Requests); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.Route, b.Route); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Consumer, b.Consumer)
+	})
+	return out
+}
+
+// Total returns the number of requests counted.
+func (u *DeprecationUsage) Total() uint64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var n uint64
+	for _, e := range u.uses {
+		n += e.Requests
+	}
+	return n
+}
+
+// Deprecate returns middleware that marks the routes it wraps as deprecated.
+// Every response carries the Deprecation header and, when configured, the
+// Sunset header and Link headers to the documentation and the successor, so
+// clients and API gateways can warn their owners. Requests are counted in
+// Usage so API owners can track the remaining consumers before removing the
+// endpoint.
+//
+// Attach it per route or per group:
+//
+//	app.GET("/v1/users/:id", getUserV1, middleware.Deprecate(middleware.DeprecationInfo{
+//		Sunset:    time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+//		Successor: "/v2/users/:id",
+//	}))
+func Deprecate(info DeprecationInfo) flash.Middleware {
+	deprecation := "true"
+	if !info.Date.IsZero() {
+		deprecation = "@" + strconv.FormatInt(info.Date.Unix(), 10)
+	}
+	var sunset string
+	if !info.Sunset.IsZero() {
+		sunset = info.Sunset.UTC().Format(http.TimeFormat)
+	}
+	var links []string
+	if info.Link != "" {
+		links = append(links, "<"+info.Link+`>; rel="deprecation"; type="text/html"`)
+	}
+	if info.Successor != "" {
+		links = append(links, "<"+info.Successor+`>; rel="successor-version"`)
+	}
+
+	return flash.Describe("deprecate", info, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			h := c.ResponseWriter().Header()
+			h.Set("Deprecation", deprecation)
+			if sunset != "" {
+				h.Set("Sunset", sunset)
+			}
+			for _, l := range links {
+				h.Add("Link", l)
+			}
+			now := time.Now()
+			if info.Usage != nil {
+				var consumer string
+				if info.Consumer != nil {
+					consumer = info.Consumer(c)
+				}
+				info.Usage.observe(c.Route(), consumer, now)
+			}
+			if info.Enforce && !info.Sunset.IsZero() && !now.Before(info.Sunset) {
+				return c.String(http.StatusGone, "this endpoint was retired on "+sunset)
+			}
+			return next(c)
+		}
+	})
+}
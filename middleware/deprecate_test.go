@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+func TestDeprecate_Headers(t *testing.T) {
+	a := flash.New()
+	date := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Now().Add(24 * time.Hour)
+	a.GET("/v1/users/:id", func(c flash.Ctx) error { return c.String(http.StatusOK, "v1") }, Deprecate(DeprecationInfo{
+		Date:      date,
+		Sunset:    sunset,
+		Link:      "https://example.com/migrate",
+		Successor: "/v2/users",
+		Enforce:   true,
+	}))
+	a.GET("/v1/plain", func(c flash.Ctx) error { return c.String(http.StatusOK, "v1") }, Deprecate(DeprecationInfo{}))
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/users/7", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "v1" {
+		t.Fatalf("code=%d body=%q", rec.Code, rec.Body.String())
+	}
+	h := rec.Header()
+	if h.Get("Deprecation") != "@1735689600" {
+		t.Fatalf("Deprecation=%q", h.Get("Deprecation"))
+	}
+	if h.Get("Sunset") != sunset.UTC().Format(http.TimeFormat) {
+		t.Fatalf("Sunset=%q", h.Get("Sunset"))
+	}
+	links := h.Values("Link")
+	if len(links) != 2 || links[0] != `<https://example.com/migrate>; rel="deprecation"; type="text/html"` || links[1] != `</v2/users>; rel="successor-version"` {
+		t.Fatalf("Link=%q", links)
+	}
+
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/plain", nil))
+	if rec.Header().Get("Deprecation") != "true" || rec.Header().Get("Sunset") != "" || rec.Header().Get("Link") != "" {
+		t.Fatalf("headers=%v", rec.Header())
+	}
+}
+
+func TestDeprecate_EnforceAfterSunset(t *testing.T) {
+	a := flash.New()
+	past := time.Now().Add(-time.Hour)
+	a.GET("/gone", func(c flash.Ctx) error { return c.String(http.StatusOK, "still here") }, Deprecate(DeprecationInfo{Sunset: past, Enforce: true}))
+	a.GET("/lenient", func(c flash.Ctx) error { return c.String(http.StatusOK, "still here") }, Deprecate(DeprecationInfo{Sunset: past}))
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/gone", nil))
+	if rec.Code != http.StatusGone || rec.Header().Get("Sunset") == "" {
+		t.Fatalf("code=%d headers=%v", rec.Code, rec.Header())
+	}
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/lenient", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("without Enforce code=%d", rec.Code)
+	}
+}
+
+func TestDeprecate_Usage(t *testing.T) {
+	usage := &DeprecationUsage{}
+	mw := Deprecate(DeprecationInfo{
+		Consumer: func(c flash.Ctx) string { return c.Request().Header.Get("X-Client") },
+		Usage:    usage,
+	})
+	a := flash.New()
+	ok := func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") }
+	a.GET("/v1/a", ok, mw)
+	a.GET("/v1/b/:id", ok, mw)
+
+	for _, r := range []struct{ path, client string }{
+		{"/v1/a", "mobile"}, {"/v1/a", "mobile"}, {"/v1/b/1", "mobile"}, {"/v1/b/2", "web"}, {"/v1/b/3", "web"}, {"/v1/b/4", "web"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, r.path, nil)
+		req.Header.Set("X-Client", r.client)
+		a.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if usage.Total() != 6 {
+		t.Fatalf("total=%d", usage.Total())
+	}
+	snap := usage.Snapshot()
+	want := []DeprecatedUse{
+		{Route: "/v1/b/:id", Consumer: "web", Requests: 3},
+		{Route: "/v1/a", Consumer: "mobile", Requests: 2},
+		{Route: "/v1/b/:id", Consumer: "mobile", Requests: 1},
+	}
+	if len(snap) != len(want) {
+		t.Fatalf("snapshot=%+v", snap)
+	}
+	for i, w := range want {
+		got := snap[i]
+		if got.Route != w.Route || got.Consumer != w.Consumer || got.Requests != w.Requests || got.LastSeen.IsZero() {
+			t.Fatalf("snapshot[%d]=%+v want %+v", i, got, w)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"context"
+	"hash/fnv"
+	"net/http"
+	"time"
+
+	"github.com/goflash/flash/v2"
+	"github.com/goflash/flash/v2/ctx"
+)
+
+// Variant is one arm of an experiment. Weight is relative to the other
+// variants' weights; variants with Weight <= 0 are never assigned but are
+// still honored when a client already holds them in its sticky cookie.
+type Variant struct {
+	Name   string
+	Weight int
+}
+
+// Experiment is the assignment made by the ABTest middleware.
+type Experiment struct {
+	Name    string // experiment name, e.g. "checkout-button"
+	Variant string // assigned variant, e.g. "green"
+}
+
+// ExperimentConfig configures the ABTest middleware.
+//
+// Assignment is deterministic: the variant is chosen by hashing the experiment
+// name together with the subject key (KeyFunc, typically a user ID), so the
+// same user lands in the same bucket on every instance. Anonymous clients get
+// a random subject. The assignment is then persisted in a sticky cookie so it
+// survives weight changes and key changes (e.g. logging in).
+//
+// Example:
+//
+//	app.Use(middleware.ABTest(middleware.ExperimentConfig{
+//		Name:     "checkout-button",
+//		Variants: []middleware.Variant{{Name: "control", Weight: 90}, {Name: "green", Weight: 10}},
+//		KeyFunc:  func(c flash.Ctx) string { return userID(c) }, // "" for anonymous
+//		OnAssign: func(c flash.Ctx, e middleware.Experiment) {
+//			experimentCounter.WithLabelValues(e.Name, e.Variant).Inc()
+//			trace.SpanFromContext(c.Context()).SetAttributes(attribute.String("exp."+e.Name, e.Variant))
+//		},
+//	}))
+//
+//	app.GET("/checkout", func(c flash.Ctx) error {
+//		if middleware.VariantFor(c, "checkout-button") == "green" {
+//			return renderGreen(c)
+//		}
+//		return renderControl(c)
+//	})
+type ExperimentConfig struct {
+	// Name identifies the experiment. It seeds the hash and names the cookie
+	// and log attribute. Required.
+	Name string
+
+	// Variants lists the arms and their relative weights. Required.
+	Variants []Variant
+
+	// KeyFunc returns the subject to bucket (user ID, account ID, ...). When it
+	// is nil or returns "", a random subject is used and the cookie provides
+	// stickiness.
+	KeyFunc func(c flash.Ctx) string
+
+	// CookieName stores the sticky assignment. Defaults to "exp_" + Name.
+	// Set to "-" to disable the cookie (purely hash-based assignment).
+	CookieName string
+
+	// CookieMaxAge is the cookie lifetime. Defaults to 30 days.
+	CookieMaxAge time.Duration
+
+	// CookiePath defaults to "/".
+	CookiePath string
+
+	// Secure sets the Secure flag on the cookie.
+	Secure bool
+
+	// OnAssign is called for every request after assignment; use it to tag
+	// metrics and tracing spans with the variant.
+	OnAssign func(c flash.Ctx, e Experiment)
+}
+
+type experimentContextKey struct{}
+
+// ABTest returns middleware that assigns each request to a variant of the
+// configured experiment, makes it available via ExperimentFromCtx and
+// VariantFor, and adds an "exp.<name>" attribute to the request logger.
+// It panics if Name is empty or no variant has a positive weight.
+func ABTest(cfg ExperimentConfig) flash.Middleware {
+	if cfg.Name == "" {
+		panic("middleware: ABTest requires a Name")
+	}
+	total := 0
+	known := make(map[string]bool, len(cfg.Variants))
+	for _, v := range cfg.Variants {
+		known[v.Name] = true
+		if v.Weight > 0 {
+			total += v.Weight
+		}
+	}
+	if total == 0 {
+		panic("middleware: ABTest experiment " + cfg.Name + " needs a variant with positive weight")
+	}
+	if cfg.CookieName == "" {
+		cfg.CookieName = "exp_" + cfg.Name
+	}
+	if cfg.CookieMaxAge <= 0 {
+		cfg.CookieMaxAge = 30 * 24 * time.Hour
+	}
+	if cfg.CookiePath == "" {
+		cfg.CookiePath = "/"
+	}
+
+	pick := func(subject string) string {
+		n := int(hashBucket(cfg.Name, subject, uint64(total)))
+		for _, v := range cfg.Variants {
+			if v.Weight <= 0 {
+				continue
+			}
+			if n < v.Weight {
+				return v.Name
+			}
+			n -= v.Weight
+		}
+		return cfg.Variants[len(cfg.Variants)-1].Name
+	}
+
+	return flash.Describe("abtest", cfg, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			r := c.Request()
+			variant := ""
+			useCookie := cfg.CookieName != "-"
+			if useCookie {
+				if ck, err := r.Cookie(cfg.CookieName); err == nil && known[ck.Value] {
+					variant = ck.Value
+				}
+			}
+			if variant == "" {
+				subject := ""
+				if cfg.KeyFunc != nil {
+					subject = cfg.KeyFunc(c)
+				}
+				if subject == "" {
+					subject = newSessionID()
+				}
+				variant = pick(subject)
+				if useCookie {
+					http.SetCookie(c.ResponseWriter(), &http.Cookie{
+						Name:     cfg.CookieName,
+						Value:    variant,
+						Path:     cfg.CookiePath,
+						MaxAge:   int(cfg.CookieMaxAge / time.Second),
+						Secure:   cfg.Secure,
+						HttpOnly: true,
+						SameSite: http.SameSiteLaxMode,
+					})
+				}
+			}
+
+			e := Experiment{Name: cfg.Name, Variant: variant}
+			prev, _ := r.Context().Value(experimentContextKey{}).([]Experiment)
+			all := append(prev[:len(prev):len(prev)], e)
+			rc := context.WithValue(r.Context(), experimentContextKey{}, all)
+			rc = ctx.ContextWithLogger(rc, ctx.LoggerFromContext(rc).With("exp."+cfg.Name, variant))
+			c.SetRequest(r.WithContext(rc))
+
+			if cfg.OnAssign != nil {
+				cfg.OnAssign(c, e)
+			}
+			return next(c)
+		}
+	})
+}
+
+// ExperimentFromCtx returns the assignment made by the innermost ABTest
+// middleware for this request.
+func ExperimentFromCtx(c flash.Ctx) (Experiment, bool) {
+	all := ExperimentsFromCtx(c)
+	if len(all) == 0 {
+		return Experiment{}, false
+	}
+	return all[len(all)-1], true
+}
+
+// ExperimentsFromCtx returns all experiment assignments for this request, in
+// middleware order.
+func ExperimentsFromCtx(c flash.Ctx) []Experiment {
+	all, _ := c.Context().Value(experimentContextKey{}).([]Experiment)
+	return all
+}
+
+// VariantFor returns the variant assigned for the named experiment, or "" if
+// the request did not pass through that experiment.
+func VariantFor(c flash.Ctx, name string) string {
+	for _, e := range ExperimentsFromCtx(c) {
+		if e.Name == name {
+			return e.Variant
+		}
+	}
+	return ""
+}
+
+// hashBucket deterministically maps subject into [0, n) for the given seed.
+func hashBucket(seed, subject string, n uint64) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(seed))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(subject))
+	return h.Sum64() % n
+}
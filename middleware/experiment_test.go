@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/goflash/flash/v2"
+)
+
+func experimentApp(cfg ExperimentConfig) flash.App {
+	a := flash.New()
+	a.Use(ABTest(cfg))
+	a.GET("/", func(c flash.Ctx) error {
+		e, _ := ExperimentFromCtx(c)
+		return c.String(http.StatusOK, e.Variant)
+	})
+	return a
+}
+
+func TestExperiment_DeterministicByKey(t *testing.T) {
+	cfg := ExperimentConfig{
+		Name:       "btn",
+		Variants:   []Variant{{Name: "a", Weight: 50}, {Name: "b", Weight: 50}},
+		KeyFunc:    func(c flash.Ctx) string { return c.Request().Header.Get("X-User") },
+		CookieName: "-",
+	}
+	a := experimentApp(cfg)
+	counts := map[string]int{}
+	for i := 0; i < 400; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-User", "u"+strconv.Itoa(i))
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		first := rec.Body.String()
+		counts[first]++
+
+		rec = httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		if rec.Body.String() != first {
+			t.Fatalf("assignment for u%d not deterministic", i)
+		}
+		if len(rec.Result().Cookies()) != 0 {
+			t.Fatalf("cookie should be disabled")
+		}
+	}
+	if counts["a"] < 150 || counts["b"] < 150 {
+		t.Fatalf("unbalanced split: %v", counts)
+	}
+}
+
+func TestExperiment_StickyCookie(t *testing.T) {
+	var assigned []Experiment
+	a := experimentApp(ExperimentConfig{
+		Name:     "btn",
+		Variants: []Variant{{Name: "a", Weight: 1}, {Name: "retired", Weight: 0}},
+		OnAssign: func(c flash.Ctx, e Experiment) { assigned = append(assigned, e) },
+	})
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := rec.Result().Cookies()
+	if rec.Body.String() != "a" || len(cookies) != 1 || cookies[0].Name != "exp_btn" || cookies[0].Value != "a" || !cookies[0].HttpOnly {
+		t.Fatalf("unexpected first response: %q %+v", rec.Body.String(), cookies)
+	}
+
+	// A client holding a zero-weight variant keeps it; unknown values are reassigned.
+	for _, tc := range []struct{ val, want string }{{"retired", "retired"}, {"bogus", "a"}} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: "exp_btn", Value: tc.val})
+		rec = httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		if rec.Body.String() != tc.want {
+			t.Fatalf("cookie %q: got %q", tc.val, rec.Body.String())
+		}
+	}
+	if len(assigned) != 3 || assigned[1] != (Experiment{Name: "btn", Variant: "retired"}) {
+		t.Fatalf("OnAssign calls: %+v", assigned)
+	}
+}
+
+func TestExperiment_MultipleAndAccessors(t *testing.T) {
+	a := flash.New()
+	a.Use(
+		ABTest(ExperimentConfig{Name: "one", Variants: []Variant{{Name: "x", Weight: 1}}}),
+		ABTest(ExperimentConfig{Name: "two", Variants: []Variant{{Name: "y", Weight: 1}}}),
+	)
+	var got string
+	a.GET("/", func(c flash.Ctx) error {
+		e, _ := ExperimentFromCtx(c)
+		got = VariantFor(c, "one") + VariantFor(c, "two") + VariantFor(c, "none") + e.Name +
+			strconv.Itoa(len(ExperimentsFromCtx(c)))
+		return nil
+	})
+	a.GET("/plain", func(c flash.Ctx) error { return nil })
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if got != "xytwo2" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestExperiment_PanicsOnBadConfig(t *testing.T) {
+	for _, cfg := range []ExperimentConfig{
+		{Variants: []Variant{{Name: "a", Weight: 1}}},
+		{Name: "x", Variants: []Variant{{Name: "a"}}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected panic for %+v", cfg)
+				}
+			}()
+			ABTest(cfg)
+		}()
+	}
+}
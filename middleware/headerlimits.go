@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/goflash/flash/v2"
+)
+
+// HeaderLimitsConfig configures the HeaderLimits middleware.
+//
+// http.Server.MaxHeaderBytes caps the raw header block, but within that budget
+// a client can still send thousands of tiny headers or a single huge value
+// (for example a bloated Cookie) that downstream parsing, logging or binding
+// then has to process. HeaderLimits rejects such requests with 431 Request
+// Header Fields Too Large before any handler code runs.
+//
+// Zero fields take the defaults below; a negative value disables that check.
+//
+// Example:
+//
+//	app.Use(middleware.HeaderLimits(middleware.HeaderLimitsConfig{
+//		MaxHeaders:     50,
+//		MaxValueBytes:  4 << 10,
+//		MaxTotalBytes:  16 << 10,
+//	}))
+//
+//	// Pair with a hardened server for slowloris protection
+//	srv := flash.HardenedServer(app, flash.HardenConfig{Addr: ":8080"})
+type HeaderLimitsConfig struct {
+	// MaxHeaders is the maximum number of header values (repeated headers count
+	// once per value). Default 100.
+	MaxHeaders int
+
+	// MaxValueBytes is the maximum length of a single header value. Default 8KB.
+	MaxValueBytes int
+
+	// MaxTotalBytes is the maximum combined size of all header names and values.
+	// Default 32KB.
+	MaxTotalBytes int
+
+	// ErrorResponse produces the response for rejected requests; reason is a
+	// short description such as "too many headers". If nil, a plain-text 431
+	// is sent.
+	ErrorResponse func(c flash.Ctx, reason string) error
+}
+
+// HeaderLimits returns middleware that rejects requests with abusive header
+// counts or sizes. See HeaderLimitsConfig for details.
+func HeaderLimits(cfg HeaderLimitsConfig) flash.Middleware {
+	if cfg.MaxHeaders == 0 {
+		cfg.MaxHeaders = 100
+	}
+	if cfg.MaxValueBytes == 0 {
+		cfg.MaxValueBytes = 8 << 10
+	}
+	if cfg.MaxTotalBytes == 0 {
+		cfg.MaxTotalBytes = 32 << 10
+	}
+	if cfg.ErrorResponse == nil {
+		cfg.ErrorResponse = func(c flash.Ctx, reason string) error {
+			c.Header("Connection", "close")
+			return c.String(http.StatusRequestHeaderFieldsTooLarge, http.StatusText(http.StatusRequestHeaderFieldsTooLarge)+": "+reason)
+		}
+	}
+
+	return flash.Describe("headerlimits", cfg, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			if r := c.Request(); r != nil {
+				if reason := checkHeaderLimits(r.Header, cfg); reason != "" {
+					return cfg.ErrorResponse(c, reason)
+				}
+			}
+			return next(c)
+		}
+	})
+}
+
+// checkHeaderLimits returns a non-empty reason when h violates cfg.
+func checkHeaderLimits(h http.Header, cfg HeaderLimitsConfig) string {
+	count, total := 0, 0
+	for name, values := range h {
+		for _, v := range values {
+			count++
+			if cfg.MaxHeaders > 0 && count > cfg.MaxHeaders {
+				return "too many headers"
+			}
+			if cfg.MaxValueBytes > 0 && len(v) > cfg.MaxValueBytes {
+				return "header " + name + " too large"
+			}
+			total += len(name) + len(v)
+			if cfg.MaxTotalBytes > 0 && total > cfg.MaxTotalBytes {
+				return "headers too large"
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/goflash/flash/v2"
+)
+
+func headerLimitsApp(cfg HeaderLimitsConfig) flash.App {
+	a := flash.New()
+	a.Use(HeaderLimits(cfg))
+	a.GET("/", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+	return a
+}
+
+func TestHeaderLimits_Rejects(t *testing.T) {
+	a := headerLimitsApp(HeaderLimitsConfig{MaxHeaders: 5, MaxValueBytes: 16, MaxTotalBytes: 64})
+	cases := map[string]func(h http.Header){
+		"too many headers": func(h http.Header) {
+			for i := 0; i < 6; i++ {
+				h.Add("X-N", strconv.Itoa(i))
+			}
+		},
+		"header X-Big too large": func(h http.Header) { h.Set("X-Big", strings.Repeat("a", 17)) },
+		"headers too large": func(h http.Header) {
+			for i := 0; i < 5; i++ {
+				h.Set("X-H"+strconv.Itoa(i), strings.Repeat("b", 15))
+			}
+		},
+	}
+	for reason, fill := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		fill(req.Header)
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		if rec.Code != http.StatusRequestHeaderFieldsTooLarge || !strings.HasSuffix(rec.Body.String(), reason) {
+			t.Fatalf("%s: got %d %q", reason, rec.Code, rec.Body.String())
+		}
+		if rec.Header().Get("Connection") != "close" {
+			t.Fatalf("%s: expected Connection: close", reason)
+		}
+	}
+}
+
+func TestHeaderLimits_AllowsAndDisables(t *testing.T) {
+	a := headerLimitsApp(HeaderLimitsConfig{})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Cookie", strings.Repeat("c", 4<<10))
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("defaults should allow normal headers, got %d", rec.Code)
+	}
+
+	a = headerLimitsApp(HeaderLimitsConfig{MaxHeaders: -1, MaxValueBytes: -1, MaxTotalBytes: -1})
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	for i := 0; i < 200; i++ {
+		req.Header.Add("X-N", strings.Repeat("v", 100))
+	}
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("negative limits should disable checks, got %d", rec.Code)
+	}
+}
+
+func TestHeaderLimits_CustomResponse(t *testing.T) {
+	var got string
+	a := headerLimitsApp(HeaderLimitsConfig{MaxHeaders: 1, ErrorResponse: func(c flash.Ctx, reason string) error {
+		got = reason
+		return c.String(http.StatusBadRequest, "no")
+	}})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("A", "1")
+	req.Header.Set("B", "2")
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest || got != "too many headers" {
+		t.Fatalf("custom response not used: %d %q", rec.Code, got)
+	}
+}
@@ -0,0 +1,325 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"hash"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goflash/flash/v2"
+	"github.com/goflash/flash/v2/ctx"
+)
+
+// ResponseSigner signs responses with HTTP Message Signatures (RFC 9421).
+// Build one with NewEd25519ResponseSigner or NewHMACResponseSigner, or fill
+// in Sign for other algorithms or keys held in an HSM or KMS.
+type ResponseSigner struct {
+	// KeyID is sent as the "keyid" parameter so clients can select the key.
+	KeyID string
+	// Alg is sent as the "alg" parameter, e.g. "ed25519" or "hmac-sha256".
+	// Leave empty to omit it.
+	Alg string
+	// Sign signs the signature base. Required.
+	Sign func(base []byte) ([]byte, error)
+	// Label names the signature in Signature-Input and Signature. Defaults to
+	// "sig1".
+	Label string
+	// Components lists the covered components: derived components such as
+	// "@status" and lowercase header names. Headers missing from a response
+	// are skipped. Defaults to "@status", "content-type" and
+	// "content-digest".
+	Components []string
+}
+
+// NewEd25519ResponseSigner returns a ResponseSigner using an Ed25519 key.
+func NewEd25519ResponseSigner(keyID string, key ed25519.PrivateKey) *ResponseSigner {
+	return &ResponseSigner{KeyID: keyID, Alg: "ed25519", Sign: func(base []byte) ([]byte, error) {
+		return ed25519.Sign(key, base), nil
+	}}
+}
+
+// NewHMACResponseSigner returns a ResponseSigner using HMAC-SHA256 with a
+// shared secret.
+func NewHMACResponseSigner(keyID string, secret []byte) *ResponseSigner {
+	return &ResponseSigner{KeyID: keyID, Alg: "hmac-sha256", Sign: func(base []byte) ([]byte, error) {
+		m := hmac.New(sha256.New, secret)
+		m.Write(base)
+		return m.Sum(nil), nil
+	}}
+}
+
+// IntegrityConfig configures the Integrity middleware.
+//
+// Example:
+//
+//	_, priv, _ := ed25519.GenerateKey(nil)
+//	app.Use(middleware.Integrity(middleware.IntegrityConfig{
+//		Signer: middleware.NewEd25519ResponseSigner("api-2024", priv),
+//	}))
+//	// Content-Digest: sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:
+//	// Signature-Input: sig1=("@status" "content-type" "content-digest");created=1700000000;keyid="api-2024";alg="ed25519"
+//	// Signature: sig1=:<base64>:
+type IntegrityConfig struct {
+	// Algorithms lists the digest algorithms, "sha-256" and/or "sha-512".
+	// Defaults to "sha-256".
+	Algorithms []string
+	// LegacyDigest also emits the RFC 3230 Digest header ("SHA-256=...") for
+	// older consumers.
+	LegacyDigest bool
+	// Signer, when set, adds Signature-Input and Signature headers.
+	Signer *ResponseSigner
+	// MaxSize caps the buffered body. Larger responses are streamed without
+	// digests or signature. Defaults to 8 MiB.
+	MaxSize int
+}
+
+// Integrity returns middleware that buffers the response, computes digests of
+// the body and emits them as Content-Digest and Repr-Digest (RFC 9530),
+// optionally signing the response (RFC 9421) so downstream consumers can
+// verify its integrity and origin.
+//
+// Content-Digest covers the bytes as written below this middleware; place it
+// outside (before) compression middleware to digest the compressed body.
+// Repr-Digest, which covers the unencoded representation, is only emitted when
+// the response has no Content-Encoding. HEAD, 204 and 304 responses, flushed
+// (streaming) responses and bodies over MaxSize are passed through unsigned,
+// as are error responses written by the app's error handler. Routes can opt
+// out with Route.Bypass("integrity").
+func Integrity(cfg IntegrityConfig) flash.Middleware {
+	if len(cfg.Algorithms) == 0 {
+		cfg.Algorithms = []string{"sha-256"}
+	}
+	for _, a := range cfg.Algorithms {
+		if newDigestHash(a) == nil {
+			panic("middleware: unsupported digest algorithm " + a)
+		}
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 8 << 20
+	}
+	if s := cfg.Signer; s != nil {
+		if s.Sign == nil {
+			panic("middleware: ResponseSigner.Sign is required")
+		}
+		if s.Label == "" {
+			s.Label = "sig1"
+		}
+		if len(s.Components) == 0 {
+			s.Components = []string{"@status", "content-type", "content-digest"}
+		}
+	}
+
+	described := cfg
+	described.Signer = nil // signs with the private key
+	return flash.Describe("integrity", described, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			if c.Bypassed("integrity") || c.Method() == http.MethodHead {
+				return next(c)
+			}
+			orig := c.ResponseWriter()
+			w := &integrityRW{rw: orig, max: cfg.MaxSize}
+			c.SetResponseWriter(w)
+			err := next(c)
+			c.SetResponseWriter(orig)
+			if err != nil && !w.started {
+				return err
+			}
+			if serr := w.finish(&cfg); serr != nil {
+				ctx.LoggerFromContext(c.Context()).Warn("integrity: signing failed", "error", serr)
+			}
+			return err
+		}
+	})
+}
+
+// newDigestHash returns the hash for an RFC 9530 algorithm name.
+func newDigestHash(alg string) hash.Hash {
+	switch alg {
+	case "sha-256":
+		return sha256.New()
+	case "sha-512":
+		return sha512.New()
+	}
+	return nil
+}
+
+// integrityRW buffers the body until finish, or streams once it overflows
+// or is flushed.
+type integrityRW struct {
+	rw        http.ResponseWriter
+	buf       bytes.Buffer
+	status    int
+	max       int
+	started   bool // WriteHeader or Write called
+	streaming bool
+}
+
+func (w *integrityRW) Header() http.Header { return w.rw.Header() }
+
+func (w *integrityRW) WriteHeader(status int) {
+	if w.streaming {
+		return
+	}
+	if w.status == 0 {
+		w.status = status
+	}
+	w.started = true
+}
+
+func (w *integrityRW) Write(p []byte) (int, error) {
+	w.started = true
+	if w.streaming {
+		return w.rw.Write(p)
+	}
+	if w.buf.Len()+len(p) > w.max {
+		w.stream()
+		return w.rw.Write(p)
+	}
+	return w.buf.Write(p)
+}
+
+// stream writes the header and buffered bytes and passes further writes
+// through.
+func (w *integrityRW) stream() {
+	if w.streaming {
+		return
+	}
+	w.streaming = true
+	w.rw.WriteHeader(w.statusCode())
+	if w.buf.Len() > 0 {
+		_, _ = w.rw.Write(w.buf.Bytes())
+		w.buf = bytes.Buffer{}
+	}
+}
+
+func (w *integrityRW) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Flush switches to streaming; streamed responses are not signed.
+func (w *integrityRW) Flush() {
+	w.started = true
+	w.stream()
+	if f, ok := w.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *integrityRW) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hj, ok := w.rw.(http.Hijacker); ok {
+		return hj.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+func (w *integrityRW) Unwrap() http.ResponseWriter { return w.rw }
+
+// finish sets the digest and signature headers and writes the response.
+func (w *integrityRW) finish(cfg *IntegrityConfig) error {
+	if w.streaming || !w.started {
+		return nil
+	}
+	status := w.statusCode()
+	h := w.Header()
+	var err error
+	if status != http.StatusNoContent && status != http.StatusNotModified {
+		var digests, legacy []string
+		for _, alg := range cfg.Algorithms {
+			d := newDigestHash(alg)
+			d.Write(w.buf.Bytes())
+			sum := base64.StdEncoding.EncodeToString(d.Sum(nil))
+			digests = append(digests, alg+"=:"+sum+":")
+			legacy = append(legacy, strings.ToUpper(alg)+"="+sum)
+		}
+		h.Set("Content-Digest", strings.Join(digests, ", "))
+		if h.Get("Content-Encoding") == "" {
+			h.Set("Repr-Digest", strings.Join(digests, ", "))
+		}
+		if cfg.LegacyDigest {
+			h.Set("Digest", strings.Join(legacy, ","))
+		}
+		if h.Get("Content-Length") == "" && h.Get("Content-Encoding") == "" {
+			h.Set("Content-Length", strconv.Itoa(w.buf.Len()))
+		}
+		if cfg.Signer != nil {
+			err = signResponse(cfg.Signer, status, h, time.Now())
+		}
+	}
+	w.rw.WriteHeader(status)
+	if w.buf.Len() > 0 {
+		_, _ = w.rw.Write(w.buf.Bytes())
+	}
+	return err
+}
+
+// signResponse adds Signature-Input and Signature headers.
+func signResponse(s *ResponseSigner, status int, h http.Header, now time.Time) error {
+	var covered []string
+	for _, comp := range s.Components {
+		if comp == "@status" || len(h.Values(comp)) > 0 {
+			covered = append(covered, comp)
+		}
+	}
+	params := signatureParams(covered, now.Unix(), s.KeyID, s.Alg)
+	sig, err := s.Sign([]byte(signatureBase(covered, params, status, h)))
+	if err != nil {
+		return err
+	}
+	h.Set("Signature-Input", s.Label+"="+params)
+	h.Set("Signature", s.Label+"=:"+base64.StdEncoding.EncodeToString(sig)+":")
+	return nil
+}
+
+// signatureParams serializes the covered components and parameters as an
+// RFC 8941 inner list.
+func signatureParams(covered []string, created int64, keyID, alg string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for i, comp := range covered {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(strconv.Quote(comp))
+	}
+	b.WriteString(");created=" + strconv.FormatInt(created, 10))
+	if keyID != "" {
+		b.WriteString(";keyid=" + strconv.Quote(keyID))
+	}
+	if alg != "" {
+		b.WriteString(";alg=" + strconv.Quote(alg))
+	}
+	return b.String()
+}
+
+// signatureBase builds the RFC 9421 signature base for a response.
+func signatureBase(covered []string, params string, status int, h http.Header) string {
+	var b strings.Builder
+	for _, comp := range covered {
+		b.WriteString(strconv.Quote(comp) + ": ")
+		if comp == "@status" {
+			b.WriteString(strconv.Itoa(status))
+		} else {
+			for i, v := range h.Values(comp) {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				b.WriteString(strings.TrimSpace(v))
+			}
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString(`"@signature-params": ` + params)
+	return b.String()
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+func integrityGet(a flash.App, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func TestIntegrityDigests(t *testing.T) {
+	a := flash.New()
+	a.Use(Integrity(IntegrityConfig{Algorithms: []string{"sha-256", "sha-512"}, LegacyDigest: true}))
+	a.GET("/", func(c flash.Ctx) error {
+		_, err := c.Send(http.StatusOK, "application/json", []byte(`{"hello": "world"}`))
+		return err
+	})
+
+	rec := integrityGet(a, http.MethodGet, "/")
+	// Example digest from RFC 9530.
+	const sha256Digest = "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:"
+	cd := rec.Header().Get("Content-Digest")
+	if !strings.HasPrefix(cd, sha256Digest+", sha-512=:") || rec.Header().Get("Repr-Digest") != cd {
+		t.Fatalf("Content-Digest = %q, Repr-Digest = %q", cd, rec.Header().Get("Repr-Digest"))
+	}
+	if !strings.HasPrefix(rec.Header().Get("Digest"), "SHA-256=X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=,SHA-512=") {
+		t.Fatalf("Digest = %q", rec.Header().Get("Digest"))
+	}
+	if rec.Body.String() != `{"hello": "world"}` {
+		t.Fatalf("body = %q", rec.Body.String())
+	}
+}
+
+func TestIntegrityEd25519Signature(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	a := flash.New()
+	a.Use(Integrity(IntegrityConfig{Signer: NewEd25519ResponseSigner("api-1", priv)}))
+	a.POST("/orders", func(c flash.Ctx) error { return c.Status(http.StatusCreated).JSON(map[string]int{"id": 7}) })
+
+	rec := integrityGet(a, http.MethodPost, "/orders")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	input, ok := strings.CutPrefix(rec.Header().Get("Signature-Input"), "sig1=")
+	if !ok || !strings.HasPrefix(input, `("@status" "content-type" "content-digest");created=`) ||
+		!strings.HasSuffix(input, `;keyid="api-1";alg="ed25519"`) {
+		t.Fatalf("Signature-Input = %q", rec.Header().Get("Signature-Input"))
+	}
+	sig, ok := strings.CutPrefix(rec.Header().Get("Signature"), "sig1=:")
+	if !ok {
+		t.Fatalf("Signature = %q", rec.Header().Get("Signature"))
+	}
+	raw, _ := base64.StdEncoding.DecodeString(strings.TrimSuffix(sig, ":"))
+
+	base := `"@status": 201` + "\n" +
+		`"content-type": application/json; charset=utf-8` + "\n" +
+		`"content-digest": ` + rec.Header().Get("Content-Digest") + "\n" +
+		`"@signature-params": ` + input
+	if !ed25519.Verify(pub, []byte(base), raw) {
+		t.Fatalf("signature does not verify over\n%s", base)
+	}
+}
+
+func TestSignatureBaseHMAC(t *testing.T) {
+	h := http.Header{}
+	h.Add("Cache-Control", " max-age=60 ")
+	h.Add("Cache-Control", "public")
+	s := NewHMACResponseSigner("k", []byte("secret"))
+	s.Label, s.Components = "resp", []string{"@status", "cache-control", "x-missing"}
+	if err := signResponse(s, 200, h, time.Unix(1618884475, 0)); err != nil {
+		t.Fatal(err)
+	}
+	params := `("@status" "cache-control");created=1618884475;keyid="k";alg="hmac-sha256"`
+	if h.Get("Signature-Input") != "resp="+params {
+		t.Fatalf("Signature-Input = %q", h.Get("Signature-Input"))
+	}
+	base := "\"@status\": 200\n\"cache-control\": max-age=60, public\n\"@signature-params\": " + params
+	m := hmac.New(sha256.New, []byte("secret"))
+	m.Write([]byte(base))
+	if h.Get("Signature") != "resp=:"+base64.StdEncoding.EncodeToString(m.Sum(nil))+":" {
+		t.Fatalf("Signature = %q", h.Get("Signature"))
+	}
+	if h.Values("Cache-Control")[0] != " max-age=60 " {
+		t.Fatalf("header values must not be modified")
+	}
+}
+
+func TestIntegrityPassThrough(t *testing.T) {
+	a := flash.New()
+	a.Use(Integrity(IntegrityConfig{MaxSize: 4, Signer: NewHMACResponseSigner("k", []byte("s"))}))
+	a.GET("/big", func(c flash.Ctx) error { return c.String(http.StatusOK, "larger than four") })
+	a.GET("/stream", func(c flash.Ctx) error {
+		c.ResponseWriter().Write([]byte("a"))
+		c.ResponseWriter().(http.Flusher).Flush()
+		return nil
+	})
+	a.GET("/empty", func(c flash.Ctx) error { c.ResponseWriter().WriteHeader(http.StatusNoContent); return nil })
+	a.GET("/err", func(c flash.Ctx) error { return errors.New("boom") })
+	a.GET("/small", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+	a.HEAD("/small", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+
+	for _, tc := range []struct{ method, path, body string }{
+		{http.MethodGet, "/big", "larger than four"},
+		{http.MethodGet, "/stream", "a"},
+		{http.MethodGet, "/empty", ""},
+		{http.MethodHead, "/small", "ok"}, // the recorder keeps HEAD bodies
+	} {
+		rec := integrityGet(a, tc.method, tc.path)
+		if rec.Header().Get("Content-Digest") != "" || rec.Header().Get("Signature") != "" || rec.Body.String() != tc.body {
+			t.Fatalf("%s %s: headers %v body %q", tc.method, tc.path, rec.Header(), rec.Body.String())
+		}
+	}
+	if rec := integrityGet(a, http.MethodGet, "/err"); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("error responses go to the error handler: %d", rec.Code)
+	}
+	if rec := integrityGet(a, http.MethodGet, "/small"); rec.Header().Get("Signature") == "" || rec.Header().Get("Content-Length") != "2" {
+		t.Fatalf("small response should be signed: %v", rec.Header())
+	}
+}
+
+func TestIntegrityPanics(t *testing.T) {
+	for name, cfg := range map[string]IntegrityConfig{
+		"algorithm": {Algorithms: []string{"md5"}},
+		"signer":    {Signer: &ResponseSigner{KeyID: "k"}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s: expected panic", name)
+				}
+			}()
+			Integrity(cfg)
+		}()
+	}
+}
@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+)
+
+// Janitor runs periodic cleanup tasks for in-memory stores (rate limit
+// strategies, session stores, ...) on a single shared goroutine instead of one
+// ticker goroutine per store.
+//
+// The goroutine starts when the first task is scheduled and exits when the
+// last task is stopped, so an idle Janitor costs nothing. Tasks run one after
+// another; a cleanup callback should be short and must not block.
+//
+// Built-in stores use DefaultJanitor. Custom stores can register with it too:
+//
+//	task := middleware.DefaultJanitor.Schedule(time.Minute, func(now time.Time) {
+//		store.deleteExpired(now)
+//	})
+//	defer task.Stop()
+type Janitor struct {
+	mu      sync.Mutex
+	tasks   map[*JanitorTask]struct{}
+	wake    chan struct{}
+	running bool
+}
+
+// JanitorTask is a cleanup callback registered with a Janitor.
+type JanitorTask struct {
+	j        *Janitor
+	fn       func(now time.Time)
+	interval time.Duration
+	next     time.Time
+}
+
+// DefaultJanitor is the janitor shared by the built-in middleware stores.
+var DefaultJanitor = NewJanitor()
+
+// defaultCleanupInterval is used when a store is created without an explicit
+// cleanup interval.
+const defaultCleanupInterval = 5 * time.Minute
+
+// NewJanitor returns a Janitor with no tasks.
+func NewJanitor() *Janitor {
+	return &Janitor{tasks: make(map[*JanitorTask]struct{}), wake: make(chan struct{}, 1)}
+}
+
+// Schedule registers fn to run every interval (defaults to 5 minutes when not
+// positive) until the returned task is stopped. fn receives the current time.
+func (j *Janitor) Schedule(interval time.Duration, fn func(now time.Time)) *JanitorTask {
+	if interval <= 0 {
+		interval = defaultCleanupInterval
+	}
+	t := &JanitorTask{j: j, fn: fn, interval: interval, next: time.Now().Add(interval)}
+	j.mu.Lock()
+	j.add(t)
+	j.mu.Unlock()
+	j.notify()
+	return t
+}
+
+// add registers t and starts the janitor goroutine if it is idle. Callers
+// hold j.mu.
+func (j *Janitor) add(t *JanitorTask) {
+	j.tasks[t] = struct{}{}
+	if !j.running {
+		j.running = true
+		go j.run()
+	}
+}
+
+// Len returns the number of scheduled tasks.
+func (j *Janitor) Len() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.tasks)
+}
+
+// SetInterval changes how often the task runs. The next run is rescheduled
+// relative to now. A stopped task is scheduled again. Non-positive values are
+// ignored.
+func (t *JanitorTask) SetInterval(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	t.j.mu.Lock()
+	t.interval = interval
+	t.next = time.Now().Add(interval)
+	if _, ok := t.j.tasks[t]; !ok {
+		t.j.add(t)
+	}
+	t.j.mu.Unlock()
+	t.j.notify()
+}
+
+// Interval returns how often the task runs.
+func (t *JanitorTask) Interval() time.Duration {
+	t.j.mu.Lock()
+	defer t.j.mu.Unlock()
+	return t.interval
+}
+
+// Stop unregisters the task. It is safe to call more than once. A run that
+// has already started is not interrupted.
+func (t *JanitorTask) Stop() {
+	t.j.mu.Lock()
+	delete(t.j.tasks, t)
+	t.j.mu.Unlock()
+	t.j.notify()
+}
+
+// notify wakes the janitor goroutine so it recomputes its next deadline.
+func (j *Janitor) notify() {
+	select {
+	case j.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (j *Janitor) run() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	var due []*JanitorTask
+	for {
+		j.mu.Lock()
+		if len(j.tasks) == 0 {
+			j.running = false
+			j.mu.Unlock()
+			return
+		}
+		now := time.Now()
+		due = due[:0]
+		var next time.Time
+		for t := range j.tasks {
+			if !now.Before(t.next) {
+				t.next = now.Add(t.interval)
+				due = append(due, t)
+			}
+			if next.IsZero() || t.next.Before(next) {
+				next = t.next
+			}
+		}
+		j.mu.Unlock()
+
+		for _, t := range due {
+			t.fn(now)
+		}
+		if len(due) > 0 {
+			continue // time has passed while running tasks
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(next.Sub(now))
+		select {
+		case <-timer.C:
+		case <-j.wake:
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met in time")
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+}
+
+func TestJanitorRunsTasksOnOneGoroutine(t *testing.T) {
+	j := NewJanitor()
+	var a, b int32
+	ta := j.Schedule(5*time.Millisecond, func(time.Time) { atomic.AddInt32(&a, 1) })
+	tb := j.Schedule(10*time.Millisecond, func(time.Time) { atomic.AddInt32(&b, 1) })
+	if j.Len() != 2 {
+		t.Fatalf("expected 2 tasks, got %d", j.Len())
+	}
+	waitFor(t, func() bool { return atomic.LoadInt32(&a) >= 4 && atomic.LoadInt32(&b) >= 2 })
+
+	ta.Stop()
+	ta.Stop() // idempotent
+	n := atomic.LoadInt32(&a)
+	time.Sleep(30 * time.Millisecond)
+	if got := atomic.LoadInt32(&a); got > n+1 {
+		t.Fatalf("stopped task kept running: %d -> %d", n, got)
+	}
+
+	tb.Stop()
+	waitFor(t, func() bool {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		return !j.running
+	})
+}
+
+func TestJanitorRestartsAfterIdle(t *testing.T) {
+	j := NewJanitor()
+	j.Schedule(time.Hour, func(time.Time) {}).Stop()
+	waitFor(t, func() bool {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		return !j.running
+	})
+	var ran int32
+	task := j.Schedule(5*time.Millisecond, func(time.Time) { atomic.StoreInt32(&ran, 1) })
+	defer task.Stop()
+	waitFor(t, func() bool { return atomic.LoadInt32(&ran) == 1 })
+}
+
+func TestJanitorTaskSetInterval(t *testing.T) {
+	j := NewJanitor()
+	var ran int32
+	task := j.Schedule(time.Hour, func(time.Time) { atomic.AddInt32(&ran, 1) })
+	defer task.Stop()
+	if task.Interval() != time.Hour {
+		t.Fatalf("unexpected interval %v", task.Interval())
+	}
+	task.SetInterval(5 * time.Millisecond)
+	task.SetInterval(0) // ignored
+	if task.Interval() != 5*time.Millisecond {
+		t.Fatalf("unexpected interval %v", task.Interval())
+	}
+	waitFor(t, func() bool { return atomic.LoadInt32(&ran) >= 2 })
+}
+
+func TestSetCleanupIntervalReschedulesStoppedTask(t *testing.T) {
+	j := NewJanitor()
+	var ran int32
+	task := j.Schedule(time.Hour, func(time.Time) { atomic.AddInt32(&ran, 1) })
+	defer task.Stop()
+	setCleanupInterval(task, -1)
+	if j.Len() != 0 {
+		t.Fatalf("expected task to be stopped, got %d tasks", j.Len())
+	}
+	setCleanupInterval(task, 5*time.Millisecond)
+	if j.Len() != 1 {
+		t.Fatalf("expected task to be rescheduled, got %d tasks", j.Len())
+	}
+	waitFor(t, func() bool { return atomic.LoadInt32(&ran) >= 1 })
+}
+
+func TestJanitorDefaultInterval(t *testing.T) {
+	j := NewJanitor()
+	task := j.Schedule(0, func(time.Time) {})
+	defer task.Stop()
+	if task.Interval() != defaultCleanupInterval {
+		t.Fatalf("expected default interval, got %v", task.Interval())
+	}
+}
+
+func TestStrategiesRegisterWithDefaultJanitor(t *testing.T) {
+	before := DefaultJanitor.Len()
+	tb := NewTokenBucketStrategy(1, time.Minute)
+	fw := NewFixedWindowStrategy(1, time.Minute)
+	sw := NewSlidingWindowStrategy(1, time.Minute)
+	lb := NewLeakyBucketStrategy(1, 1)
+	as := NewAdaptiveStrategy(1, 0.1, 10, time.Minute)
+	if got := DefaultJanitor.Len(); got != before+5 {
+		t.Fatalf("expected %d tasks, got %d", before+5, got)
+	}
+	for _, c := range []interface{ Close() }{tb, fw, sw, lb, as} {
+		c.Close()
+		c.Close() // must not panic
+	}
+	if got := DefaultJanitor.Len(); got != before {
+		t.Fatalf("expected tasks to be unregistered, got %d", got)
+	}
+}
+
+func TestMemoryStoreCleanupUsesJanitor(t *testing.T) {
+	m := NewMemoryStore()
+	_ = m.Save("old", map[string]any{}, time.Millisecond)
+	m.StartCleanup(5 * time.Millisecond)
+	defer m.StopCleanup()
+	waitFor(t, func() bool { return m.Len() == 0 })
+	m.StopCleanup()
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// LogDedupConfig configures a LogDeduper.
+type LogDedupConfig struct {
+	// Window is the period over which identical signatures are collapsed.
+	// Defaults to 10 seconds.
+	Window time.Duration
+	// Burst is how many lines per signature are logged in full within a
+	// window before further ones are suppressed. Defaults to 1.
+	Burst int
+	// MaxKeys caps the number of tracked signatures. Signatures beyond the
+	// cap are logged without de-duplication. Defaults to 1024.
+	MaxKeys int
+}
+
+// LogDeduper collapses log lines with identical signatures (e.g. route,
+// status and error message) so that a failing dependency produces one line
+// per window with a counter instead of one line per request:
+//
+//	level=ERROR msg=request route=/orders status=502 error="dial tcp: connection refused"
+//	level=ERROR msg=request route=/orders status=502 error="dial tcp: connection refused" repeated=412
+//
+// The first Burst lines of a signature in a window are logged as usual. Later
+// ones are counted, and when the window ends the last suppressed line is
+// logged once with a "repeated" attribute holding the count. Summaries are
+// emitted by DefaultJanitor, so they appear even when the storm stops.
+//
+// A LogDeduper is safe for concurrent use and can be shared by Logger and
+// Recover. Call Close to flush pending summaries and stop the cleanup task.
+//
+// Example:
+//
+//	dedup := middleware.NewLogDeduper(middleware.LogDedupConfig{Window: 30 * time.Second})
+//	defer dedup.Close()
+//	app.Use(
+//		middleware.Logger(middleware.WithLogDedup(dedup)),
+//		middleware.Recover(middleware.RecoverConfig{Dedup: dedup}),
+//	)
+type LogDeduper struct {
+	mu      sync.Mutex
+	cfg     LogDedupConfig
+	entries map[string]*dedupEntry
+	task    *JanitorTask
+	now     func() time.Time
+}
+
+// dedupEntry tracks one signature in the current window and the last
+// suppressed line, which is replayed as the summary.
+type dedupEntry struct {
+	start      time.Time
+	logged     int
+	suppressed int
+	logger     *slog.Logger
+	level      slog.Level
+	msg        string
+	attrs      []any
+}
+
+// NewLogDeduper returns a LogDeduper and registers its summary task with
+// DefaultJanitor.
+func NewLogDeduper(cfg LogDedupConfig) *LogDeduper {
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Second
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = 1
+	}
+	if cfg.MaxKeys <= 0 {
+		cfg.MaxKeys = 1024
+	}
+	d := &LogDeduper{cfg: cfg, entries: make(map[string]*dedupEntry), now: time.Now}
+	d.task = DefaultJanitor.Schedule(cfg.Window, func(time.Time) { d.sweep(false) })
+	return d
+}
+
+// Log logs msg with attrs on l at level unless the signature sig has already
+// been logged Burst times in the current window, in which case the line is
+// counted and reported later in a summary.
+func (d *LogDeduper) Log(ctx context.Context, l *slog.Logger, level slog.Level, sig, msg string, attrs ...any) {
+	now := d.now()
+	d.mu.Lock()
+	e := d.entries[sig]
+	if e != nil && now.Sub(e.start) >= d.cfg.Window {
+		d.emit(e)
+		e.start, e.logged, e.suppressed = now, 0, 0
+	}
+	switch {
+	case e == nil && len(d.entries) >= d.cfg.MaxKeys:
+		// Too many distinct signatures to track: log as is.
+	case e == nil:
+		d.entries[sig] = &dedupEntry{start: now, logged: 1}
+	case e.logged < d.cfg.Burst:
+		e.logged++
+	default:
+		e.suppressed++
+		e.logger, e.level, e.msg, e.attrs = l, level, msg, attrs
+		d.mu.Unlock()
+		return
+	}
+	d.mu.Unlock()
+	l.Log(ctx, level, msg, attrs...)
+}
+
+// Flush logs the summaries of all pending suppressed lines now.
+func (d *LogDeduper) Flush() { d.sweep(true) }
+
+// Close flushes pending summaries and stops the cleanup task.
+func (d *LogDeduper) Close() {
+	d.task.Stop()
+	d.Flush()
+}
+
+// sweep emits summaries for windows that have ended (or all, when force is
+// set) and forgets signatures that were not repeated.
+func (d *LogDeduper) sweep(force bool) {
+	now := d.now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for sig, e := range d.entries {
+		if !force && now.Sub(e.start) < d.cfg.Window {
+			continue
+		}
+		if e.suppressed == 0 {
+			delete(d.entries, sig)
+			continue
+		}
+		d.emit(e)
+		// Keep counting into a fresh window so a continuing storm still
+		// produces at most one summary per window.
+		e.start, e.logged, e.suppressed = now, d.cfg.Burst, 0
+	}
+}
+
+// emit logs the summary line for e, if any lines were suppressed. Callers
+// hold d.mu; slog handlers must not call back into the deduper.
+func (d *LogDeduper) emit(e *dedupEntry) {
+	if e.suppressed == 0 {
+		return
+	}
+	attrs := append(append(make([]any, 0, len(e.attrs)+2), e.attrs...), "repeated", e.suppressed)
+	e.logger.Log(context.Background(), e.level, e.msg, attrs...)
+	e.logger, e.attrs = nil, nil
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+func recordAttr(r slog.Record, key string) (slog.Value, bool) {
+	var v slog.Value
+	found := false
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == key {
+			v, found = a.Value, true
+			return false
+		}
+		return true
+	})
+	return v, found
+}
+
+func newTestDeduper(cfg LogDedupConfig) (*LogDeduper, *time.Time) {
+	d := NewLogDeduper(cfg)
+	d.task.Stop() // summaries are driven by the test
+	now := time.Unix(1000, 0)
+	d.now = func() time.Time { return now }
+	return d, &now
+}
+
+func TestLogDeduperCollapsesRepeats(t *testing.T) {
+	h := &captureHandler{}
+	l := slog.New(h)
+	d, now := newTestDeduper(LogDedupConfig{Window: 10 * time.Second})
+
+	for i := 0; i < 5; i++ {
+		d.Log(context.Background(), l, slog.LevelError, "db down", "query failed", "attempt", i)
+	}
+	d.Log(context.Background(), l, slog.LevelError, "cache down", "cache failed")
+	if len(h.rec) != 2 {
+		t.Fatalf("expected first line per signature only, got %d", len(h.rec))
+	}
+
+	*now = now.Add(10 * time.Second)
+	d.sweep(false)
+	if len(h.rec) != 3 {
+		t.Fatalf("expected one summary, got %d lines", len(h.rec))
+	}
+	sum := h.rec[2]
+	if n, _ := recordAttr(sum, "repeated"); sum.Message != "query failed" || n.Int64() != 4 {
+		t.Fatalf("summary %q repeated=%v", sum.Message, n)
+	}
+	if a, _ := recordAttr(sum, "attempt"); a.Int64() != 4 {
+		t.Fatalf("summary should carry the last suppressed attrs, got attempt=%v", a)
+	}
+	if len(d.entries) != 1 {
+		t.Fatalf("unrepeated signatures should be forgotten, %d tracked", len(d.entries))
+	}
+
+	// The storm continues in the new window: still suppressed.
+	d.Log(context.Background(), l, slog.LevelError, "db down", "query failed")
+	if len(h.rec) != 3 {
+		t.Fatalf("continuing storm must stay collapsed")
+	}
+	d.Close()
+	if n, _ := recordAttr(h.rec[len(h.rec)-1], "repeated"); len(h.rec) != 4 || n.Int64() != 1 {
+		t.Fatalf("Close should flush pending summaries, got %d lines", len(h.rec))
+	}
+}
+
+func TestLogDeduperBurstAndMaxKeys(t *testing.T) {
+	h := &captureHandler{}
+	l := slog.New(h)
+	d, now := newTestDeduper(LogDedupConfig{Window: time.Second, Burst: 2, MaxKeys: 1})
+
+	for i := 0; i < 4; i++ {
+		d.Log(context.Background(), l, slog.LevelWarn, "a", "msg")
+	}
+	if len(h.rec) != 2 {
+		t.Fatalf("burst of 2 expected, got %d", len(h.rec))
+	}
+	for i := 0; i < 3; i++ {
+		d.Log(context.Background(), l, slog.LevelWarn, "b", "untracked")
+	}
+	if len(h.rec) != 5 {
+		t.Fatalf("signatures beyond MaxKeys are logged as is, got %d", len(h.rec))
+	}
+
+	// The next line after the window reports the previous window's count.
+	*now = now.Add(time.Second)
+	d.Log(context.Background(), l, slog.LevelWarn, "a", "msg")
+	if n, _ := recordAttr(h.rec[5], "repeated"); len(h.rec) != 7 || n.Int64() != 2 {
+		t.Fatalf("expected summary then fresh line, got %d lines", len(h.rec))
+	}
+}
+
+func TestLoggerAndRecoverDedup(t *testing.T) {
+	h := &captureHandler{}
+	d, _ := newTestDeduper(LogDedupConfig{})
+	a := flash.New()
+	a.SetLogger(slog.New(h))
+	a.Use(Logger(WithLogDedup(d)), Recover(RecoverConfig{Dedup: d}))
+	a.GET("/ok", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+	a.GET("/fail", func(c flash.Ctx) error { return errors.New("upstream unavailable") })
+	a.GET("/panic", func(c flash.Ctx) error { panic("boom") })
+
+	for _, path := range []string{"/ok", "/ok", "/fail", "/fail", "/fail", "/panic", "/panic"} {
+		a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	// 2 successes, 1 failure line, 1 panic line and 1 request line for the panic.
+	if len(h.rec) != 5 {
+		t.Fatalf("got %d lines", len(h.rec))
+	}
+	var panics int
+	for _, r := range h.rec {
+		if r.Message == "panic recovered" {
+			panics++
+			if v, _ := recordAttr(r, "panic"); r.Level != slog.LevelError || v.String() != "boom" {
+				t.Fatalf("panic line: %v %v", r.Level, v)
+			}
+		}
+	}
+	if panics != 1 {
+		t.Fatalf("panic lines = %d", panics)
+	}
+	d.Flush()
+	if len(h.rec) != 8 {
+		t.Fatalf("expected 3 summaries, got %d lines", len(h.rec))
+	}
+}
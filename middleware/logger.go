@@ -2,6 +2,10 @@ package middleware
 
 import (
 	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/goflash/flash/v2"
@@ -62,7 +66,7 @@ func LoggerAttributesFromContext(ctx context.Context) *LoggerAttributes {
 // LoggerConfig holds configuration options for the Logger middleware.
 type LoggerConfig struct {
 	// ExcludeFields specifies which standard fields to exclude from logging.
-	// Valid values: "method", "path", "route", "status", "duration_ms", "remote", "user_agent", "request_id"
+	// Valid values: "method", "path", "route", "status", "duration_ms", "remote", "user_agent", "request_id", "slo"
 	ExcludeFields []string
 
 	// CustomAttributesFunc is an optional function that can add custom attributes
@@ -72,6 +76,28 @@ type LoggerConfig struct {
 
 	// Message is the log message to use. Defaults to "request".
 	Message string
+
+	// Redactor masks sensitive data before it is logged. When nil, the
+	// built-in DefaultRedactionRules are used for headers, query and body.
+	Redactor *Redactor
+
+	// Headers lists request headers to log under "headers" (redacted).
+	Headers []string
+
+	// LogQuery logs the raw query string under "query" (redacted).
+	LogQuery bool
+
+	// BodyMaxBytes, when > 0, logs up to that many bytes of a JSON request body
+	// under "body" (redacted). The body remains fully readable by handlers.
+	BodyMaxBytes int64
+
+	// Dedup, when set, collapses repeated lines for failed requests (see
+	// WithLogDedup).
+	Dedup *LogDeduper
+
+	// OnSLOViolation, when set, is called for requests that exceed their
+	// route's latency budget (see WithSLOViolationHook).
+	OnSLOViolation func(c flash.Ctx, budget, elapsed time.Duration)
 }
 
 // LoggerOption is a function that configures the Logger middleware.
@@ -137,6 +163,95 @@ func WithMessage(message string) LoggerOption {
 	}
 }
 
+// WithRedactor sets the Redactor used to mask headers, query parameters, JSON
+// bodies and string attribute values before logging. Patterns configured on the
+// redactor are applied to every string attribute, including custom ones.
+//
+// Usage Examples:
+//
+//	rules := middleware.DefaultRedactionRules()
+//	rules.BodyPaths = append(rules.BodyPaths, "user.dob")
+//	app.Use(middleware.Logger(middleware.WithRedactor(middleware.NewRedactor(rules))))
+func WithRedactor(r *Redactor) LoggerOption {
+	return func(cfg *LoggerConfig) {
+		cfg.Redactor = r
+	}
+}
+
+// WithLogHeaders logs the named request headers under the "headers" group.
+// Sensitive headers such as Authorization and Cookie are masked.
+//
+// Usage Examples:
+//
+//	app.Use(middleware.Logger(middleware.WithLogHeaders("Authorization", "X-Forwarded-For")))
+//	// headers.Authorization="[REDACTED]" headers.X-Forwarded-For="203.0.113.7"
+func WithLogHeaders(names ...string) LoggerOption {
+	return func(cfg *LoggerConfig) {
+		cfg.Headers = append(cfg.Headers, names...)
+	}
+}
+
+// WithLogQuery logs the request query string under "query" with sensitive
+// parameters such as token masked.
+//
+// Usage Examples:
+//
+//	app.Use(middleware.Logger(middleware.WithLogQuery()))
+//	// GET /cb?code=1&token=abc  ->  query="code=1&token=%5BREDACTED%5D"
+func WithLogQuery() LoggerOption {
+	return func(cfg *LoggerConfig) {
+		cfg.LogQuery = true
+	}
+}
+
+// WithLogRequestBody logs up to maxBytes of JSON request bodies under "body",
+// masking sensitive paths such as password and ssn. Non-JSON bodies are not
+// logged. The body is restored so handlers can still read it in full.
+//
+// Usage Examples:
+//
+//	app.Use(middleware.Logger(middleware.WithLogRequestBody(4 << 10)))
+//	// POST {"user":"bob","password":"hunter2"}  ->  body={"password":"[REDACTED]","user":"bob"}
+func WithLogRequestBody(maxBytes int64) LoggerOption {
+	return func(cfg *LoggerConfig) {
+		cfg.BodyMaxBytes = maxBytes
+	}
+}
+
+// WithLogDedup collapses log lines of failed requests (a handler error or a
+// status >= 500) that share method, route, status and error message, so an
+// outage logs one line per window with a "repeated" count instead of one line
+// per request. Successful requests are always logged.
+//
+// Usage Examples:
+//
+//	dedup := middleware.NewLogDeduper(middleware.LogDedupConfig{Window: time.Minute})
+//	app.Use(middleware.Logger(middleware.WithLogDedup(dedup)))
+//	// msg=request route=/orders status=502
+//	// msg=request route=/orders status=502 repeated=412
+func WithLogDedup(d *LogDeduper) LoggerOption {
+	return func(cfg *LoggerConfig) {
+		cfg.Dedup = d
+	}
+}
+
+// WithSLOViolationHook calls fn for every request that exceeds the latency
+// budget its route declared with Route.Meta("slo", ...), e.g. to increment an
+// SLO burn counter. The log line of such requests carries slo.violated=true
+// with or without a hook.
+//
+// Usage Examples:
+//
+//	app.Use(middleware.Logger(middleware.WithSLOViolationHook(func(c flash.Ctx, budget, elapsed time.Duration) {
+//	    sloViolations.WithLabelValues(c.Route()).Inc()
+//	})))
+//	app.GET("/search", Search).Meta("slo", "100ms")
+func WithSLOViolationHook(fn func(c flash.Ctx, budget, elapsed time.Duration)) LoggerOption {
+	return func(cfg *LoggerConfig) {
+		cfg.OnSLOViolation = fn
+	}
+}
+
 // Logger returns middleware that logs each HTTP request using structured logging (slog).
 //
 // This middleware automatically captures and logs the following request information:
@@ -149,6 +264,10 @@ func WithMessage(message string) LoggerOption {
 //   - User agent string
 //   - Request ID (if available via RequestID middleware)
 //   - Custom attributes (if provided via context or CustomAttributesFunc)
+//   - For routes with a latency budget (Route.Meta("slo", "100ms")), the
+//     budget as "slo_ms" and whether it was exceeded as "slo.violated"
+//   - Optionally, redacted request headers, query string and JSON body
+//     (see WithLogHeaders, WithLogQuery, WithLogRequestBody and WithRedactor)
 //
 // The logger is retrieved from the request context or application context.
 // If no status code is set by the handler, it defaults to 200 (OK).
@@ -261,8 +380,18 @@ func Logger(options ...LoggerOption) flash.Middleware {
 		excludeMap[field] = true
 	}
 
-	return func(next flash.Handler) flash.Handler {
+	red := cfg.Redactor
+	if red == nil {
+		red = NewRedactor(DefaultRedactionRules())
+	}
+
+	return flash.Describe("logger", *cfg, func(next flash.Handler) flash.Handler {
 		return func(c flash.Ctx) error {
+			var body []byte
+			if cfg.BodyMaxBytes > 0 {
+				body = peekJSONBody(c, cfg.BodyMaxBytes)
+			}
+
 			start := time.Now()
 			err := next(c)
 			dur := time.Since(start)
@@ -308,6 +437,16 @@ func Logger(options ...LoggerOption) flash.Middleware {
 				attrs = append(attrs, "user_agent", ua)
 			}
 
+			if budget, ok := SLOBudget(c); ok {
+				violated := dur > budget
+				if !excludeMap["slo"] {
+					attrs = append(attrs, "slo_ms", float64(budget.Microseconds())/1000.0, "slo.violated", violated)
+				}
+				if violated && cfg.OnSLOViolation != nil {
+					cfg.OnSLOViolation(c, budget, dur)
+				}
+			}
+
 			// Add request_id if available and not excluded
 			if !excludeMap["request_id"] {
 				if rid, ok := RequestIDFromContext(c.Context()); ok {
@@ -327,8 +466,86 @@ func Logger(options ...LoggerOption) flash.Middleware {
 				}
 			}
 
+			if r := c.Request(); r != nil {
+				if len(cfg.Headers) > 0 {
+					hdrs := make([]any, 0, len(cfg.Headers))
+					for _, name := range cfg.Headers {
+						if v := r.Header.Get(name); v != "" {
+							hdrs = append(hdrs, slog.String(http.CanonicalHeaderKey(name), red.HeaderValue(name, v)))
+						}
+					}
+					attrs = append(attrs, slog.Group("headers", hdrs...))
+				}
+				if cfg.LogQuery && r.URL != nil && r.URL.RawQuery != "" {
+					attrs = append(attrs, "query", red.Query(r.URL.RawQuery))
+				}
+			}
+			if len(body) > 0 {
+				attrs = append(attrs, "body", string(red.JSON(body)))
+			}
+
+			if len(red.patterns) > 0 {
+				redactAttrValues(red, attrs)
+			}
+
+			if cfg.Dedup != nil && (err != nil || status >= http.StatusInternalServerError) {
+				cfg.Dedup.Log(c.Context(), l, slog.LevelInfo, requestSignature(c, status, err), cfg.Message, attrs...)
+				return err
+			}
 			l.Info(cfg.Message, attrs...)
 			return err
 		}
+	})
+}
+
+// requestSignature identifies a failed request for de-duplication.
+func requestSignature(c flash.Ctx, status int, err error) string {
+	route := c.Route()
+	if route == "" {
+		route = c.Path()
+	}
+	sig := c.Method() + " " + route + " " + strconv.Itoa(status)
+	if err != nil {
+		sig += " " + err.Error()
+	}
+	return sig
+}
+
+// peekJSONBody reads up to limit bytes of a JSON request body and restores the
+// body so downstream handlers see it unchanged. It returns nil for other
+// content types or when the body is truncated (partial JSON cannot be redacted
+// reliably).
+func peekJSONBody(c flash.Ctx, limit int64) []byte {
+	r := c.Request()
+	if r == nil || r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	if !strings.Contains(strings.ToLower(r.Header.Get("Content-Type")), "json") {
+		return nil
+	}
+	buf, ok := bufferRequestBody(r, limit)
+	if !ok {
+		return nil
+	}
+	return buf
+}
+
+// redactAttrValues applies the redactor's value patterns to string values in
+// an slog key/value list in place. Elements that are slog.Attr occupy a single
+// slot, mirroring slog's own argument handling.
+func redactAttrValues(red *Redactor, attrs []any) {
+	for i := 0; i < len(attrs); i++ {
+		if a, ok := attrs[i].(slog.Attr); ok {
+			if a.Value.Kind() == slog.KindString {
+				attrs[i] = slog.String(a.Key, red.Value(a.Value.String()))
+			}
+			continue
+		}
+		if i+1 < len(attrs) {
+			if s, ok := attrs[i+1].(string); ok {
+				attrs[i+1] = red.Value(s)
+			}
+		}
+		i++
 	}
 }
@@ -2,9 +2,12 @@ package middleware
 
 import (
 	"context"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/goflash/flash/v2"
@@ -452,3 +455,87 @@ func BenchmarkLoggerWithExcludeFields(b *testing.B) {
 		a.ServeHTTP(rec, req)
 	}
 }
+
+func TestLoggerRedaction(t *testing.T) {
+	a := flash.New()
+	h := &captureHandler{}
+	a.SetLogger(slog.New(h))
+	rules := DefaultRedactionRules()
+	rules.Patterns = []*regexp.Regexp{regexp.MustCompile(`secret-\w+`)}
+	a.Use(Logger(
+		WithRedactor(NewRedactor(rules)),
+		WithLogHeaders("Authorization", "X-Trace"),
+		WithLogQuery(),
+		WithLogRequestBody(1024),
+		WithCustomAttributes(func(c flash.Ctx) []any { return []any{"note", "has secret-xyz"} }),
+	))
+	var seen string
+	a.POST("/login", func(c flash.Ctx) error {
+		b, _ := io.ReadAll(c.Request().Body)
+		seen = string(b)
+		return c.String(http.StatusOK, "ok")
+	})
+	body := `{"user":"bob","password":"hunter2"}`
+	req := httptest.NewRequest(http.MethodPost, "/login?next=/&token=abc", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer abc")
+	req.Header.Set("X-Trace", "t1")
+	a.ServeHTTP(httptest.NewRecorder(), req)
+
+	if seen != body {
+		t.Fatalf("handler saw %q", seen)
+	}
+	got := map[string]string{}
+	h.rec[len(h.rec)-1].Attrs(func(at slog.Attr) bool {
+		if at.Value.Kind() == slog.KindGroup {
+			for _, g := range at.Value.Group() {
+				got[at.Key+"."+g.Key] = g.Value.String()
+			}
+			return true
+		}
+		got[at.Key] = at.Value.String()
+		return true
+	})
+	if got["headers.Authorization"] != "[REDACTED]" || got["headers.X-Trace"] != "t1" {
+		t.Fatalf("headers not redacted: %v", got)
+	}
+	if got["query"] != "next=/&token=%5BREDACTED%5D" {
+		t.Fatalf("query = %q", got["query"])
+	}
+	if strings.Contains(got["body"], "hunter2") || !strings.Contains(got["body"], `"user":"bob"`) {
+		t.Fatalf("body = %q", got["body"])
+	}
+	if got["note"] != "has [REDACTED]" {
+		t.Fatalf("pattern not applied to custom attrs: %q", got["note"])
+	}
+}
+
+func TestLoggerRequestBodySkipsNonJSONAndOversized(t *testing.T) {
+	a := flash.New()
+	h := &captureHandler{}
+	a.SetLogger(slog.New(h))
+	a.Use(Logger(WithLogRequestBody(8)))
+	var seen string
+	a.POST("/", func(c flash.Ctx) error {
+		b, _ := io.ReadAll(c.Request().Body)
+		seen = string(b)
+		return nil
+	})
+	for _, tc := range []struct{ ct, body string }{
+		{"text/plain", "password=x"},
+		{"application/json", `{"password":"long enough"}`},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", tc.ct)
+		a.ServeHTTP(httptest.NewRecorder(), req)
+		if seen != tc.body {
+			t.Fatalf("%s: handler saw %q", tc.ct, seen)
+		}
+		h.rec[len(h.rec)-1].Attrs(func(at slog.Attr) bool {
+			if at.Key == "body" {
+				t.Fatalf("%s: body should not be logged", tc.ct)
+			}
+			return true
+		})
+	}
+}
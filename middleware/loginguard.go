@@ -0,0 +1,362 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+// LoginAttempts is the failure state of one identifier+IP pair.
+type LoginAttempts struct {
+	Failures    int       // consecutive failures
+	NextAllowed time.Time // backoff or lockout end; zero when not throttled
+	Locked      bool      // NextAllowed is a lockout rather than a backoff
+}
+
+// LoginAttemptStore holds LoginAttempts by key. Implementations must be safe
+// for concurrent use; share one between instances (e.g. Redis) to apply
+// lockouts cluster-wide.
+type LoginAttemptStore interface {
+	// Get returns the attempts recorded for key.
+	Get(key string) (LoginAttempts, bool)
+	// Update atomically replaces the attempts for key (the zero value if
+	// none are recorded) with the result of fn and keeps them for the
+	// returned ttl. Concurrent updates of a key must not interleave, so
+	// parallel failures are all counted; networked stores can implement it
+	// with a transaction, a script or a compare-and-swap retry loop.
+	Update(key string, fn func(a LoginAttempts) (LoginAttempts, time.Duration)) (LoginAttempts, error)
+	// Delete forgets key.
+	Delete(key string) error
+}
+
+// MemoryLoginAttemptStore is an in-process LoginAttemptStore. Like the rate
+// limit strategies, it keeps entries in a map bounded to a maximum number of
+// keys and removes expired ones on the DefaultJanitor.
+type MemoryLoginAttemptStore struct {
+	mu          sync.Mutex
+	entries     map[string]*loginAttemptEntry
+	maxKeys     int
+	cleanupTask *JanitorTask
+}
+
+type loginAttemptEntry struct {
+	attempts LoginAttempts
+	expires  time.Time
+}
+
+// NewMemoryLoginAttemptStore returns a store holding at most maxKeys keys
+// (default 100,000); when full, an arbitrary key is evicted for a new one.
+func NewMemoryLoginAttemptStore(maxKeys int) *MemoryLoginAttemptStore {
+	if maxKeys <= 0 {
+		maxKeys = 100_000
+	}
+	s := &MemoryLoginAttemptStore{entries: make(map[string]*loginAttemptEntry), maxKeys: maxKeys}
+	s.cleanupTask = DefaultJanitor.Schedule(defaultCleanupInterval, s.cleanup)
+	return s
+}
+
+// Get implements LoginAttemptStore.
+func (s *MemoryLoginAttemptStore) Get(key string) (LoginAttempts, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entries[key]
+	if e == nil || !time.Now().Before(e.expires) {
+		return LoginAttempts{}, false
+	}
+	return e.attempts, true
+}
+
+// Update implements LoginAttemptStore.
+func (s *MemoryLoginAttemptStore) Update(key string, fn func(LoginAttempts) (LoginAttempts, time.Duration)) (LoginAttempts, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entries[key]
+	if e == nil {
+		evictForNewKey(s.entries, s.maxKeys)
+		e = &loginAttemptEntry{}
+		s.entries[key] = e
+	} else if !now.Before(e.expires) {
+		e.attempts = LoginAttempts{}
+	}
+	a, ttl := fn(e.attempts)
+	e.attempts, e.expires = a, now.Add(ttl)
+	return a, nil
+}
+
+// Delete implements LoginAttemptStore.
+func (s *MemoryLoginAttemptStore) Delete(key string) error {
+	s.mu.Lock()
+	delete(s.entries, key)
+	s.mu.Unlock()
+	return nil
+}
+
+// cleanup removes expired entries.
+func (s *MemoryLoginAttemptStore) cleanup(now time.Time) {
+	s.mu.Lock()
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+	s.mu.Unlock()
+}
+
+// Close unregisters the store from the cleanup janitor. It is safe to call
+// more than once.
+func (s *MemoryLoginAttemptStore) Close() {
+	s.cleanupTask.Stop()
+}
+
+// SetCleanupInterval changes how often expired entries are removed. A
+// negative interval disables cleanup.
+func (s *MemoryLoginAttemptStore) SetCleanupInterval(d time.Duration) {
+	setCleanupInterval(s.cleanupTask, d)
+}
+
+// SetMaxKeys caps the number of tracked keys (0 means unlimited).
+func (s *MemoryLoginAttemptStore) SetMaxKeys(n int) {
+	s.mu.Lock()
+	s.maxKeys = n
+	s.mu.Unlock()
+}
+
+// LoginGuardConfig configures LoginGuardMiddleware.
+type LoginGuardConfig struct {
+	// Store holds failure state. Defaults to a MemoryLoginAttemptStore.
+	Store LoginAttemptStore
+
+	// Identifier extracts the account identifier (e.g. the username) before
+	// the handler runs, letting the middleware reject throttled attempts
+	// itself. If nil, handlers pass the identifier to LoginGuard.SetIdentifier
+	// after parsing the request and check LoginGuard.Allowed.
+	Identifier func(c flash.Ctx) string
+
+	// BaseDelay is the wait imposed after the first failure; it doubles with
+	// each further failure up to MaxDelay. Defaults to 1 second and 1 minute.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// MaxFailures is the number of consecutive failures that locks the
+	// identifier+IP pair for LockoutDuration. Defaults to 5 and 15 minutes.
+	MaxFailures     int
+	LockoutDuration time.Duration
+
+	// ResetAfter forgets failures after this long without a new one.
+	// Defaults to 24 hours.
+	ResetAfter time.Duration
+
+	// OnFailure is called after each recorded failure.
+	OnFailure func(c flash.Ctx, identifier, ip string, a LoginAttempts)
+	// OnLockout is called when a pair becomes locked, e.g. to email the
+	// account owner or alert security.
+	OnLockout func(c flash.Ctx, identifier, ip string, until time.Time)
+
+	// TrustedProxies are the proxy ranges whose X-Forwarded-For is trusted
+	// when determining the client IP, as in RateLimitConfig.
+	TrustedProxies []string
+
+	// ErrorResponse renders throttled attempts rejected by the middleware.
+	// Defaults to 429 with Retry-After.
+	ErrorResponse func(c flash.Ctx, retryAfter time.Duration) error
+
+	now func() time.Time // for tests
+}
+
+// LoginGuard tracks the authentication attempt of one request. Obtain it with
+// LoginGuardFromCtx and report the outcome with Success or Failure.
+type LoginGuard struct {
+	cfg        *LoginGuardConfig
+	c          flash.Ctx
+	ip         string
+	identifier string
+
+	mu       sync.Mutex
+	reported bool
+}
+
+type loginGuardContextKey struct{}
+
+// LoginGuardMiddleware returns middleware that throttles failed logins per
+// identifier and client IP. Each failure delays the next attempt with
+// exponential backoff, and MaxFailures consecutive failures lock the pair out
+// temporarily. Keying on the pair keeps an attacker from locking a victim out
+// from elsewhere, while the backoff still slows guessing.
+//
+// The handler reports the outcome through LoginGuardFromCtx:
+//
+//	auth.Use(middleware.LoginGuardMiddleware(middleware.LoginGuardConfig{
+//		OnLockout: func(c flash.Ctx, user, ip string, until time.Time) {
+//			notifyUser(user, ip, until)
+//		},
+//	}))
+//	auth.POST("/login", func(c flash.Ctx) error {
+//		var in credentials
+//		if err := c.BindJSON(&in); err != nil {
+//			return err
+//		}
+//		g := middleware.LoginGuardFromCtx(c)
+//		g.SetIdentifier(in.Username)
+//		if ok, retry := g.Allowed(); !ok {
+//			c.Header("Retry-After", strconv.Itoa(int(retry.Seconds())))
+//			return c.String(http.StatusTooManyRequests, "try again later")
+//		}
+//		if !checkPassword(in) {
+//			g.Failure()
+//			return c.String(http.StatusUnauthorized, "invalid credentials")
+//		}
+//		g.Success()
+//		return c.String(http.StatusOK, "welcome")
+//	})
+func LoginGuardMiddleware(cfg LoginGuardConfig) flash.Middleware {
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryLoginAttemptStore(0)
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = time.Second
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = time.Minute
+	}
+	if cfg.MaxFailures <= 0 {
+		cfg.MaxFailures = 5
+	}
+	if cfg.LockoutDuration <= 0 {
+		cfg.LockoutDuration = 15 * time.Minute
+	}
+	if cfg.ResetAfter <= 0 {
+		cfg.ResetAfter = 24 * time.Hour
+	}
+	if cfg.ErrorResponse == nil {
+		cfg.ErrorResponse = defaultErrorResponse
+	}
+	if cfg.now == nil {
+		cfg.now = time.Now
+	}
+	ipKey := ClientIPKeyFunc(cfg.TrustedProxies)
+
+	return flash.Describe("loginguard", cfg, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			g := &LoginGuard{cfg: &cfg, c: c, ip: ipKey(c)}
+			if cfg.Identifier != nil {
+				g.identifier = cfg.Identifier(c)
+				if ok, retry := g.Allowed(); !ok {
+					return cfg.ErrorResponse(c, retry)
+				}
+			}
+			r := c.Request()
+			c.SetRequest(r.WithContext(context.WithValue(r.Context(), loginGuardContextKey{}, g)))
+			return next(c)
+		}
+	})
+}
+
+// LoginGuardFromCtx returns the request's LoginGuard. Without the
+// LoginGuardMiddleware it returns a guard whose methods do nothing.
+func LoginGuardFromCtx(c flash.Ctx) *LoginGuard {
+	if g, ok := c.Context().Value(loginGuardContextKey{}).(*LoginGuard); ok {
+		return g
+	}
+	return &LoginGuard{}
+}
+
+// SetIdentifier sets the account identifier for this attempt.
+func (g *LoginGuard) SetIdentifier(identifier string) {
+	g.mu.Lock()
+	g.identifier = identifier
+	g.mu.Unlock()
+}
+
+// Allowed reports whether an attempt may proceed now, and otherwise how long
+// the client must wait.
+func (g *LoginGuard) Allowed() (bool, time.Duration) {
+	if g.cfg == nil {
+		return true, 0
+	}
+	a, ok := g.cfg.Store.Get(g.key())
+	if !ok {
+		return true, 0
+	}
+	if wait := a.NextAllowed.Sub(g.cfg.now()); wait > 0 {
+		return false, wait
+	}
+	return true, 0
+}
+
+// Failure records a failed attempt and returns the resulting state. Once
+// MaxFailures is reached, every further failure before a Success or
+// ResetAfter locks the pair again. Only the first Success or Failure of a
+// request is recorded. Failures are counted with Store.Update, so parallel
+// attempts cannot outrun the lockout; if the store fails, the zero
+// LoginAttempts is returned.
+func (g *LoginGuard) Failure() LoginAttempts {
+	if g.cfg == nil || !g.report() {
+		return LoginAttempts{}
+	}
+	now := g.cfg.now()
+	a, err := g.cfg.Store.Update(g.key(), func(a LoginAttempts) (LoginAttempts, time.Duration) {
+		a.Failures++
+		a.Locked = a.Failures >= g.cfg.MaxFailures
+		if a.Locked {
+			a.NextAllowed = now.Add(g.cfg.LockoutDuration)
+		} else {
+			a.NextAllowed = now.Add(backoffDelay(g.cfg.BaseDelay, g.cfg.MaxDelay, a.Failures))
+		}
+		ttl := g.cfg.ResetAfter
+		if d := a.NextAllowed.Sub(now); d > ttl {
+			ttl = d
+		}
+		return a, ttl
+	})
+	if err != nil {
+		return LoginAttempts{}
+	}
+
+	if g.cfg.OnFailure != nil {
+		g.cfg.OnFailure(g.c, g.identifier, g.ip, a)
+	}
+	if a.Locked && g.cfg.OnLockout != nil {
+		g.cfg.OnLockout(g.c, g.identifier, g.ip, a.NextAllowed)
+	}
+	return a
+}
+
+// Success clears the failures of the identifier+IP pair.
+func (g *LoginGuard) Success() {
+	if g.cfg == nil || !g.report() {
+		return
+	}
+	_ = g.cfg.Store.Delete(g.key())
+}
+
+// report marks the attempt reported, returning false if it already was.
+func (g *LoginGuard) report() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.reported {
+		return false
+	}
+	g.reported = true
+	return true
+}
+
+func (g *LoginGuard) key() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return "login:" + normalizeKey(g.identifier, 256) + "|" + g.ip
+}
+
+// backoffDelay returns base * 2^(failures-1), capped at limit.
+func backoffDelay(base, limit time.Duration, failures int) time.Duration {
+	d := base
+	for i := 1; i < failures && d < limit; i++ {
+		d *= 2
+	}
+	if d > limit {
+		d = limit
+	}
+	return d
+}
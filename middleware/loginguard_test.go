@@ -0,0 +1,245 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+// loginApp reports a failure unless the password query parameter is "ok".
+func loginApp(cfg LoginGuardConfig) flash.App {
+	app := flash.New()
+	app.Use(LoginGuardMiddleware(cfg))
+	app.POST("/login", func(c flash.Ctx) error {
+		g := LoginGuardFromCtx(c)
+		g.SetIdentifier(c.Query("user"))
+		if ok, _ := g.Allowed(); !ok {
+			return c.String(http.StatusTooManyRequests, "wait")
+		}
+		if c.Query("password") != "ok" {
+			g.Failure()
+			return c.String(http.StatusUnauthorized, "no")
+		}
+		g.Success()
+		return c.String(http.StatusOK, "welcome")
+	})
+	return app
+}
+
+func attempt(app flash.App, user, password, ip string) int {
+	req := httptest.NewRequest(http.MethodPost, "/login?user="+user+"&password="+password, nil)
+	req.RemoteAddr = ip + ":1234"
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestLoginGuardBackoffAndLockout(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	var failures []int
+	var lockedUntil time.Time
+	app := loginApp(LoginGuardConfig{
+		BaseDelay:       time.Second,
+		MaxDelay:        4 * time.Second,
+		MaxFailures:     4,
+		LockoutDuration: time.Hour,
+		OnFailure: func(_ flash.Ctx, id, ip string, a LoginAttempts) {
+			if id != "alice" || ip != "198.51.100.1" {
+				t.Errorf("unexpected hook args %q %q", id, ip)
+			}
+			failures = append(failures, a.Failures)
+		},
+		OnLockout: func(_ flash.Ctx, _, _ string, until time.Time) { lockedUntil = until },
+		now:       func() time.Time { return now },
+	})
+
+	for i, wait := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		if code := attempt(app, "alice", "bad", "198.51.100.1"); code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", i, code)
+		}
+		if code := attempt(app, "alice", "ok", "198.51.100.1"); code != http.StatusTooManyRequests {
+			t.Fatalf("attempt %d: expected backoff, got %d", i, code)
+		}
+		now = now.Add(wait)
+	}
+	if code := attempt(app, "alice", "bad", "198.51.100.1"); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", code)
+	}
+	if !lockedUntil.Equal(now.Add(time.Hour)) {
+		t.Fatalf("expected lockout hook, got %v", lockedUntil)
+	}
+	now = now.Add(30 * time.Minute)
+	if code := attempt(app, "alice", "ok", "198.51.100.1"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected lockout, got %d", code)
+	}
+	if len(failures) != 4 || failures[3] != 4 {
+		t.Fatalf("unexpected failure counts %v", failures)
+	}
+
+	// Another IP and another user are unaffected.
+	if code := attempt(app, "alice", "ok", "203.0.113.9"); code != http.StatusOK {
+		t.Fatalf("other IP should not be locked, got %d", code)
+	}
+	if code := attempt(app, "bob", "ok", "198.51.100.1"); code != http.StatusOK {
+		t.Fatalf("other user should not be locked, got %d", code)
+	}
+
+	now = now.Add(time.Hour)
+	if code := attempt(app, "alice", "ok", "198.51.100.1"); code != http.StatusOK {
+		t.Fatalf("expected login after lockout, got %d", code)
+	}
+	// Success reset the count: the next failure only backs off.
+	attempt(app, "alice", "bad", "198.51.100.1")
+	if failures[len(failures)-1] != 1 {
+		t.Fatalf("expected count reset by success, got %v", failures)
+	}
+}
+
+func TestLoginGuardIdentifierFunc(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	handled := 0
+	app := flash.New()
+	app.Use(LoginGuardMiddleware(LoginGuardConfig{
+		Identifier: func(c flash.Ctx) string { return c.Query("user") },
+		now:        func() time.Time { return now },
+	}))
+	app.POST("/login", func(c flash.Ctx) error {
+		handled++
+		LoginGuardFromCtx(c).Failure()
+		return c.String(http.StatusUnauthorized, "no")
+	})
+
+	attempt(app, "alice", "bad", "198.51.100.1")
+	req := httptest.NewRequest(http.MethodPost, "/login?user=alice", nil)
+	req.RemoteAddr = "198.51.100.1:1234"
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" || handled != 1 {
+		t.Fatalf("expected middleware rejection, got %d %q handled=%d", rec.Code, rec.Header().Get("Retry-After"), handled)
+	}
+}
+
+func TestLoginGuardReportsOnce(t *testing.T) {
+	var calls int
+	app := flash.New()
+	app.Use(LoginGuardMiddleware(LoginGuardConfig{OnFailure: func(flash.Ctx, string, string, LoginAttempts) { calls++ }}))
+	app.POST("/login", func(c flash.Ctx) error {
+		g := LoginGuardFromCtx(c)
+		g.Failure()
+		g.Failure()
+		g.Success()
+		return nil
+	})
+	attempt(app, "alice", "", "198.51.100.1")
+	if calls != 1 {
+		t.Fatalf("expected one recorded outcome, got %d", calls)
+	}
+}
+
+func TestLoginGuardFromCtxWithoutMiddleware(t *testing.T) {
+	app := flash.New()
+	app.POST("/login", func(c flash.Ctx) error {
+		g := LoginGuardFromCtx(c)
+		g.SetIdentifier("alice")
+		g.Failure()
+		g.Success()
+		if ok, _ := g.Allowed(); !ok {
+			t.Errorf("no-op guard must allow")
+		}
+		return c.String(http.StatusOK, "ok")
+	})
+	if code := attempt(app, "alice", "", "198.51.100.1"); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+}
+
+func TestLoginGuardConcurrentFailures(t *testing.T) {
+	const n = 50
+	store := NewMemoryLoginAttemptStore(0)
+	defer store.Close()
+	var mu sync.Mutex
+	var counts []int
+	app := flash.New()
+	app.Use(LoginGuardMiddleware(LoginGuardConfig{
+		Store:       store,
+		MaxFailures: 5,
+		OnFailure: func(_ flash.Ctx, _, _ string, a LoginAttempts) {
+			mu.Lock()
+			counts = append(counts, a.Failures)
+			mu.Unlock()
+		},
+	}))
+	app.POST("/login", func(c flash.Ctx) error {
+		g := LoginGuardFromCtx(c)
+		g.SetIdentifier("alice")
+		g.Failure()
+		return c.String(http.StatusUnauthorized, "no")
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attempt(app, "alice", "bad", "198.51.100.1")
+		}()
+	}
+	wg.Wait()
+
+	a, ok := store.Get("login:alice|198.51.100.1")
+	if !ok || a.Failures != n || !a.Locked {
+		t.Fatalf("expected %d counted failures and a lockout, got %+v", n, a)
+	}
+	seen := map[int]bool{}
+	for _, c := range counts {
+		seen[c] = true
+	}
+	if len(seen) != n {
+		t.Fatalf("failures were not counted one by one: %v", counts)
+	}
+}
+
+func TestMemoryLoginAttemptStore(t *testing.T) {
+	s := NewMemoryLoginAttemptStore(2)
+	defer s.Close()
+	inc := func(a LoginAttempts) (LoginAttempts, time.Duration) {
+		a.Failures++
+		return a, time.Hour
+	}
+	for _, key := range []string{"a", "a", "b", "c"} {
+		if _, err := s.Update(key, inc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(s.entries) != 2 {
+		t.Fatalf("expected the store bounded to 2 keys, got %d", len(s.entries))
+	}
+	if a, ok := s.Get("c"); !ok || a.Failures != 1 {
+		t.Fatalf("Get(c) = %+v %v", a, ok)
+	}
+
+	_, _ = s.Update("d", func(a LoginAttempts) (LoginAttempts, time.Duration) { return a, -time.Second })
+	if _, ok := s.Get("d"); ok {
+		t.Fatalf("expired entry returned")
+	}
+	if a, _ := s.Update("d", inc); a.Failures != 1 {
+		t.Fatalf("expired entry not reset: %+v", a)
+	}
+	s.cleanup(time.Now().Add(2 * time.Hour))
+	if len(s.entries) != 0 {
+		t.Fatalf("cleanup left %d entries", len(s.entries))
+	}
+	_ = s.Delete("missing")
+}
+
+func TestBackoffDelay(t *testing.T) {
+	for failures, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 10: 5 * time.Second} {
+		if got := backoffDelay(time.Second, 5*time.Second, failures); got != want {
+			t.Fatalf("backoffDelay(%d) = %v, want %v", failures, got, want)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goflash/flash/v2"
+	"github.com/goflash/flash/v2/ctx"
+)
+
+// UsageRecord is the metered usage of one key (API key, tenant...) on one
+// route over a period. A single request is a record with Requests == 1.
+type UsageRecord struct {
+	Key           string        `json:"key"`
+	Route         string        `json:"route,omitempty"` // route pattern; empty unless MeterConfig.PerRoute
+	Requests      uint64        `json:"requests"`
+	Errors        uint64        `json:"errors"` // handler errors and 5xx responses
+	RequestBytes  int64         `json:"request_bytes"`
+	ResponseBytes int64         `json:"response_bytes"`
+	Compute       time.Duration `json:"compute_ns"` // time spent in the handler chain
+	Start         time.Time     `json:"start"`      // first request of the period
+	End           time.Time     `json:"end"`        // last request of the period
+}
+
+// UsageAggregator accumulates usage between flushes. Implementations must be
+// safe for concurrent use.
+type UsageAggregator interface {
+	// Add merges r into the running totals.
+	Add(r UsageRecord)
+	// Drain returns the totals accumulated since the last Drain and resets them.
+	Drain() []UsageRecord
+}
+
+// UsageSink exports drained usage, e.g. to a billing system. A returned error
+// keeps the records for the next flush.
+type UsageSink func(ctx context.Context, records []UsageRecord) error
+
+// MeterConfig configures a Meter.
+type MeterConfig struct {
+	// KeyFunc returns the billed key of a request. Requests with an empty key
+	// are not metered. Defaults to the tenant ID resolved by Tenant.
+	KeyFunc func(c flash.Ctx) string
+
+	// PerRoute splits usage by route pattern in addition to key.
+	PerRoute bool
+
+	// Aggregator accumulates usage. Defaults to an in-memory aggregator.
+	Aggregator UsageAggregator
+
+	// Sink receives the aggregated usage every Interval and on Close. Required.
+	Sink UsageSink
+
+	// Interval between flushes. Defaults to one minute.
+	Interval time.Duration
+
+	// OnError is called when Sink fails. Defaults to logging a warning
+	// through the logger of the metered requests (see ctx.LoggerFromContext).
+	OnError func(err error)
+}
+
+// Meter records per-key request counts, bytes and compute time and exports
+// them periodically, so SaaS products can bill usage without a separate
+// gateway. Flushes run on DefaultJanitor's schedule in their own goroutine.
+// Call Close at shutdown to flush the remaining usage.
+//
+// Example:
+//
+//	meter := middleware.NewMeter(middleware.MeterConfig{
+//		KeyFunc: func(c flash.Ctx) string {
+//			k, _ := apikeys.KeyFromCtx(c)
+//			return k.Owner
+//		},
+//		Sink:     middleware.UsageHTTPSink("https://billing.internal/usage", nil),
+//		Interval: 5 * time.Minute,
+//	})
+//	defer meter.Close()
+//	api := app.Group("/api", keys.Middleware(), meter.Middleware())
+type Meter struct {
+	cfg      MeterConfig
+	task     *JanitorTask
+	flushing sync.Mutex
+	now      func() time.Time
+	logger   atomic.Pointer[slog.Logger] // request logger, for the default OnError
+}
+
+// NewMeter returns a Meter and schedules its flushes. It panics if cfg.Sink
+// is nil.
+func NewMeter(cfg MeterConfig) *Meter {
+	if cfg.Sink == nil {
+		panic("middleware: MeterConfig.Sink is required")
+	}
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = func(c flash.Ctx) string {
+			if t, ok := TenantFromCtx(c); ok {
+				return t.ID
+			}
+			return ""
+		}
+	}
+	if cfg.Aggregator == nil {
+		cfg.Aggregator = NewUsageAggregator()
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	m := &Meter{cfg: cfg, now: time.Now}
+	if m.cfg.OnError == nil {
+		m.cfg.OnError = func(err error) { m.log().Warn("meter: flush failed", "error", err) }
+	}
+	m.task = DefaultJanitor.Schedule(cfg.Interval, func(time.Time) {
+		go func() { _ = m.Flush(context.Background()) }()
+	})
+	return m
+}
+
+// Middleware returns the metering middleware. Place it after the middleware
+// that authenticates the billed key. Usage is recorded once the response has
+// been sent, so error responses written by the app's error handler are
+// counted with their final status and size.
+func (m *Meter) Middleware() flash.Middleware {
+	return flash.Describe("meter", m.cfg, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			r := c.Request()
+			body := &countingBody{ReadCloser: r.Body}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = body
+			}
+			w := &countingWriter{ResponseWriter: c.ResponseWriter()}
+			c.SetResponseWriter(w)
+			start := m.now()
+			err := next(c)
+			end := m.now()
+
+			key := m.cfg.KeyFunc(c)
+			if key == "" {
+				c.SetResponseWriter(w.ResponseWriter)
+				return err
+			}
+			rec := UsageRecord{
+				Key:          key,
+				Requests:     1,
+				RequestBytes: body.n,
+				Compute:      end.Sub(start),
+				Start:        start,
+				End:          end,
+			}
+			if m.cfg.PerRoute {
+				rec.Route = c.Route()
+			}
+			// The error handler writes its response after the chain returns,
+			// so w stays in place and the record is completed once the
+			// response is sent.
+			failed := err != nil
+			c.AfterResponse(func(rc context.Context, status int) {
+				rec.ResponseBytes = w.n
+				if failed || status >= 500 {
+					rec.Errors = 1
+				}
+				if m.logger.Load() == nil {
+					m.logger.Store(ctx.LoggerFromContext(rc))
+				}
+				m.cfg.Aggregator.Add(rec)
+			})
+			return err
+		}
+	})
+}
+
+// Flush drains the aggregator into the sink now. On failure the records are
+// kept for the next flush. Concurrent flushes run one at a time.
+func (m *Meter) Flush(ctx context.Context) error {
+	m.flushing.Lock()
+	defer m.flushing.Unlock()
+	records := m.cfg.Aggregator.Drain()
+	if len(records) == 0 {
+		return nil
+	}
+	if err := m.cfg.Sink(ctx, records); err != nil {
+		for _, r := range records {
+			m.cfg.Aggregator.Add(r)
+		}
+		m.cfg.OnError(err)
+		return err
+	}
+	return nil
+}
+
+// log returns the logger of the metered requests, or slog.Default before the
+// first one completes.
+func (m *Meter) log() *slog.Logger {
+	if l := m.logger.Load(); l != nil {
+		return l
+	}
+	return slog.Default()
+}
+
+// Close stops the periodic flush and flushes the remaining usage.
+func (m *Meter) Close() error {
+	m.task.Stop()
+	return m.Flush(context.Background())
+}
+
+// memoryAggregator sums records by key and route.
+type memoryAggregator struct {
+	mu      sync.Mutex
+	records map[[2]string]*UsageRecord
+}
+
+// NewUsageAggregator returns an in-memory UsageAggregator that sums usage by
+// key and route.
+func NewUsageAggregator() UsageAggregator {
+	return &memoryAggregator{records: make(map[[2]string]*UsageRecord)}
+}
+
+func (a *memoryAggregator) Add(r UsageRecord) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	k := [2]string{r.Key, r.Route}
+	t := a.records[k]
+	if t == nil {
+		a.records[k] = &r
+		return
+	}
+	t.Requests += r.Requests
+	t.Errors += r.Errors
+	t.RequestBytes += r.RequestBytes
+	t.ResponseBytes += r.ResponseBytes
+	t.Compute += r.Compute
+	if r.Start.Before(t.Start) {
+		t.Start = r.Start
+	}
+	if r.End.After(t.End) {
+		t.End = r.End
+	}
+}
+
+func (a *memoryAggregator) Drain() []UsageRecord {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make([]UsageRecord, 0, len(a.records))
+	for _, r := range a.records {
+		out = append(out, *r)
+	}
+	clear(a.records)
+	return out
+}
+
+// UsageChannelSink sends each flush to ch. It fails when ctx ends before ch
+// accepts the records.
+func UsageChannelSink(ch chan<- []UsageRecord) UsageSink {
+	return func(ctx context.Context, records []UsageRecord) error {
+		select {
+		case ch <- records:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// UsageHTTPSink POSTs each flush to url as a JSON array. Responses other
+// than 2xx are errors. A nil client uses one with a 30 second timeout.
+func UsageHTTPSink(url string, client *http.Client) UsageSink {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return func(ctx context.Context, records []UsageRecord) error {
+		body, err := json.Marshal(records)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("meter: usage sink answered %s", resp.Status)
+		}
+		return nil
+	}
+}
+
+// UsageFileSink appends each record to the file at path as one JSON line.
+func UsageFileSink(path string) UsageSink {
+	var mu sync.Mutex
+	return func(_ context.Context, records []UsageRecord) error {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(buf.Bytes()); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+}
+
+// countingBody counts the request body bytes read by the handler.
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// countingWriter counts the response body bytes written.
+type countingWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// Flush forwards to the underlying writer when supported.
+func (w *countingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack forwards to the underlying writer when supported.
+func (w *countingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("middleware: hijacking not supported")
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *countingWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+func meterApp(m *Meter) flash.App {
+	a := flash.New()
+	a.Use(m.Middleware())
+	a.POST("/upload/:id", func(c flash.Ctx) error {
+		b, _ := io.ReadAll(c.Request().Body)
+		return c.String(http.StatusOK, strings.Repeat("x", len(b)*2))
+	})
+	a.GET("/fail", func(c flash.Ctx) error { return errors.New("boom") })
+	return a
+}
+
+// sendMetered serves one request and waits until its usage is recorded.
+func sendMetered(a flash.App, method, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("X-Customer", key)
+	}
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	_ = a.WaitResponseHooks(context.Background())
+	return rec
+}
+
+func TestMeter_AggregatesPerKey(t *testing.T) {
+	ch := make(chan []UsageRecord, 1)
+	m := NewMeter(MeterConfig{
+		KeyFunc:  func(c flash.Ctx) string { return c.Request().Header.Get("X-Customer") },
+		Sink:     UsageChannelSink(ch),
+		Interval: time.Hour,
+	})
+	defer m.Close()
+	if name := m.Middleware().Name(); name != "meter" {
+		t.Fatalf("Name()=%q", name)
+	}
+	a := meterApp(m)
+	sendMetered(a, http.MethodPost, "/upload/1", "acme", "hello")
+	sendMetered(a, http.MethodPost, "/upload/2", "acme", "abc")
+	sendMetered(a, http.MethodGet, "/fail", "acme", "")
+	sendMetered(a, http.MethodPost, "/upload/3", "globex", "z")
+	sendMetered(a, http.MethodPost, "/upload/4", "", "not billed")
+
+	if err := m.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	records := <-ch
+	slices.SortFunc(records, func(a, b UsageRecord) int { return strings.Compare(a.Key, b.Key) })
+	if len(records) != 2 {
+		t.Fatalf("records=%+v", records)
+	}
+	acme := records[0]
+	if acme.Key != "acme" || acme.Requests != 3 || acme.Errors != 1 || acme.RequestBytes != 8 || acme.ResponseBytes < 16 {
+		t.Fatalf("acme=%+v", acme)
+	}
+	if acme.Compute <= 0 || acme.Start.IsZero() || acme.End.Before(acme.Start) || acme.Route != "" {
+		t.Fatalf("acme=%+v", acme)
+	}
+	if g := records[1]; g.Key != "globex" || g.Requests != 1 || g.RequestBytes != 1 || g.ResponseBytes != 2 {
+		t.Fatalf("globex=%+v", g)
+	}
+
+	// Drained: the next flush has nothing to send.
+	if err := m.Flush(context.Background()); err != nil || len(ch) != 0 {
+		t.Fatalf("second flush err=%v pending=%d", err, len(ch))
+	}
+}
+
+func TestMeter_PerRouteAndRetry(t *testing.T) {
+	var fail bool
+	var got []UsageRecord
+	var reported error
+	m := NewMeter(MeterConfig{
+		KeyFunc:  func(flash.Ctx) string { return "acme" },
+		PerRoute: true,
+		Sink: func(_ context.Context, records []UsageRecord) error {
+			if fail {
+				return errors.New("billing down")
+			}
+			got = append(got, records...)
+			return nil
+		},
+		Interval: time.Hour,
+		OnError:  func(err error) { reported = err },
+	})
+	defer m.Close()
+	a := meterApp(m)
+	sendMetered(a, http.MethodPost, "/upload/1", "", "a")
+
+	fail = true
+	if err := m.Flush(context.Background()); err == nil || reported == nil {
+		t.Fatalf("err=%v reported=%v", err, reported)
+	}
+	sendMetered(a, http.MethodPost, "/upload/2", "", "b")
+	sendMetered(a, http.MethodGet, "/fail", "", "")
+	fail = false
+	if err := m.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	slices.SortFunc(got, func(a, b UsageRecord) int { return strings.Compare(a.Route, b.Route) })
+	if len(got) != 2 || got[0].Route != "/fail" || got[1].Route != "/upload/:id" || got[1].Requests != 2 {
+		t.Fatalf("records=%+v", got)
+	}
+}
+
+func TestMeter_CountsErrorResponses(t *testing.T) {
+	ch := make(chan []UsageRecord, 1)
+	m := NewMeter(MeterConfig{KeyFunc: func(flash.Ctx) string { return "acme" }, Sink: UsageChannelSink(ch), Interval: time.Hour})
+	defer m.Close()
+	a := flash.New()
+	a.Use(m.Middleware())
+	a.GET("/missing", func(c flash.Ctx) error { return flash.NewError(http.StatusNotFound, "no such widget") })
+	a.GET("/fail", func(c flash.Ctx) error { return errors.New("boom") })
+
+	var written int
+	for _, path := range []string{"/missing", "/fail"} {
+		rec := sendMetered(a, http.MethodGet, path, "", "")
+		if rec.Body.Len() == 0 {
+			t.Fatalf("%s: error handler wrote no body", path)
+		}
+		written += rec.Body.Len()
+	}
+	if err := m.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	records := <-ch
+	if len(records) != 1 || records[0].Requests != 2 || records[0].Errors != 2 || records[0].ResponseBytes != int64(written) {
+		t.Fatalf("records=%+v, written=%d", records, written)
+	}
+}
+
+func TestMeter_DefaultOnErrorUsesRequestLogger(t *testing.T) {
+	var logs strings.Builder
+	m := NewMeter(MeterConfig{
+		KeyFunc:  func(flash.Ctx) string { return "acme" },
+		Sink:     func(context.Context, []UsageRecord) error { return errors.New("billing down") },
+		Interval: time.Hour,
+	})
+	defer m.task.Stop()
+	a := flash.New()
+	a.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	a.Use(m.Middleware())
+	a.GET("/", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+	sendMetered(a, http.MethodGet, "/", "", "")
+	if err := m.Flush(context.Background()); err == nil {
+		t.Fatalf("expected flush error")
+	}
+	if !strings.Contains(logs.String(), "meter: flush failed") || !strings.Contains(logs.String(), "billing down") {
+		t.Fatalf("logs=%q", logs.String())
+	}
+}
+
+func TestMeter_DefaultKeyIsTenant(t *testing.T) {
+	ch := make(chan []UsageRecord, 1)
+	m := NewMeter(MeterConfig{Sink: UsageChannelSink(ch), Interval: time.Hour})
+	a := flash.New()
+	a.Use(Tenant(TenantConfig{Resolver: TenantFromHeader("X-Tenant"), Optional: true}), m.Middleware())
+	a.GET("/", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+	for _, tenant := range []string{"t1", ""} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tenant != "" {
+			req.Header.Set("X-Tenant", tenant)
+		}
+		a.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	_ = a.WaitResponseHooks(context.Background())
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if records := <-ch; len(records) != 1 || records[0].Key != "t1" {
+		t.Fatalf("records=%+v", records)
+	}
+}
+
+func TestUsageSinks(t *testing.T) {
+	records := []UsageRecord{{Key: "acme", Requests: 2}, {Key: "globex", Requests: 1}}
+
+	var received []UsageRecord
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer srv.Close()
+	if err := UsageHTTPSink(srv.URL, nil)(context.Background(), records); err != nil || len(received) != 2 {
+		t.Fatalf("http sink err=%v received=%+v", err, received)
+	}
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusBadGateway) }))
+	defer bad.Close()
+	if err := UsageHTTPSink(bad.URL, nil)(context.Background(), records); err == nil {
+		t.Fatalf("expected error for 502")
+	}
+
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	sink := UsageFileSink(path)
+	for i := 0; i < 2; i++ {
+		if err := sink(context.Background(), records); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[0], `"key":"acme"`) {
+		t.Fatalf("file=%q", b)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := UsageChannelSink(make(chan []UsageRecord))(ctx, records); !errors.Is(err, context.Canceled) {
+		t.Fatalf("channel sink err=%v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+// Limit is one rule enforced by MultiLimit.
+type Limit struct {
+	// Strategy enforces the limit. Required.
+	Strategy RateLimitStrategy
+
+	// KeyFunc extracts the key for this limit. If nil, the client IP is used;
+	// it is extracted once per request and shared by all limits without a
+	// KeyFunc.
+	KeyFunc func(c flash.Ctx) string
+
+	// PerRoute scopes the key to the matched route pattern and method, so each
+	// route gets its own budget (e.g. "GET /users/:id|203.0.113.7").
+	PerRoute bool
+}
+
+// MultiLimitConfig holds the settings shared by all limits of MultiLimit.
+// They have the same meaning as the corresponding RateLimitConfig fields.
+type MultiLimitConfig struct {
+	ErrorResponse  func(c flash.Ctx, retryAfter time.Duration) error
+	SkipFunc       func(c flash.Ctx) bool
+	TrustedProxies []string
+	MaxKeyLength   int // defaults to 256
+}
+
+// MultiLimit returns middleware that enforces several rate limits in one pass,
+// e.g. a per-second burst limit, an hourly quota and a per-route limit. Every
+// limit is evaluated for each request; if any rejects it, the request gets a
+// 429 whose Retry-After is the longest wait among the rejecting limits, so the
+// client does not retry into another exhausted limit.
+//
+// Because all limits are evaluated, a rejected request still counts against
+// the limits that allowed it, as it would with stacked RateLimit middleware.
+//
+// Example:
+//
+//	burst := middleware.NewTokenBucketStrategy(10, time.Second)
+//	quota := middleware.NewFixedWindowStrategy(5000, time.Hour)
+//	routes := middleware.NewSlidingWindowStrategy(100, time.Minute)
+//	api.Use(middleware.MultiLimit(
+//		middleware.Limit{Strategy: burst},
+//		middleware.Limit{Strategy: quota, KeyFunc: apiKey},
+//		middleware.Limit{Strategy: routes, PerRoute: true},
+//	))
+func MultiLimit(limits ...Limit) flash.Middleware {
+	return MultiLimitWithConfig(MultiLimitConfig{}, limits...)
+}
+
+// MultiLimitWithConfig is MultiLimit with a custom error response, skip
+// function, trusted proxies or key length.
+//
+// Example:
+//
+//	api.Use(middleware.MultiLimitWithConfig(middleware.MultiLimitConfig{
+//		TrustedProxies: []string{"10.0.0.0/8"},
+//		ErrorResponse: func(c flash.Ctx, retry time.Duration) error {
+//			return c.Status(429).JSON(map[string]any{"retry_after": retry.Seconds()})
+//		},
+//	}, middleware.Limit{Strategy: burst}, middleware.Limit{Strategy: quota}))
+func MultiLimitWithConfig(cfg MultiLimitConfig, limits ...Limit) flash.Middleware {
+	for _, l := range limits {
+		if l.Strategy == nil {
+			panic("middleware: MultiLimit requires a Strategy for every Limit")
+		}
+	}
+	if cfg.ErrorResponse == nil {
+		cfg.ErrorResponse = defaultErrorResponse
+	}
+	if cfg.MaxKeyLength <= 0 {
+		cfg.MaxKeyLength = 256
+	}
+	ipKey := ClientIPKeyFunc(cfg.TrustedProxies)
+
+	return flash.Describe("multilimit", cfg, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			if cfg.SkipFunc != nil && cfg.SkipFunc(c) {
+				return next(c)
+			}
+
+			var (
+				ip       string
+				haveIP   bool
+				rejected bool
+				retry    time.Duration
+			)
+			for _, l := range limits {
+				var key string
+				if l.KeyFunc != nil {
+					key = l.KeyFunc(c)
+				} else {
+					if !haveIP {
+						ip, haveIP = ipKey(c), true
+					}
+					key = ip
+				}
+				if l.PerRoute {
+					key = c.Method() + " " + c.Route() + "|" + key
+				}
+				if ok, ra := l.Strategy.Allow(normalizeKey(key, cfg.MaxKeyLength)); !ok {
+					rejected = true
+					if ra > retry {
+						retry = ra
+					}
+				}
+			}
+			if rejected {
+				return cfg.ErrorResponse(c, retry)
+			}
+			return next(c)
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+// stubLimit records keys and answers with a fixed decision.
+type stubLimit struct {
+	allow bool
+	retry time.Duration
+	keys  []string
+}
+
+func (s *stubLimit) Allow(key string) (bool, time.Duration) {
+	s.keys = append(s.keys, key)
+	return s.allow, s.retry
+}
+
+func (s *stubLimit) Name() string { return "stub" }
+
+func TestMultiLimitAllowsWhenAllAllow(t *testing.T) {
+	a, b := &stubLimit{allow: true}, &stubLimit{allow: true}
+	app := flash.New()
+	app.Use(MultiLimit(Limit{Strategy: a}, Limit{Strategy: b, KeyFunc: func(c flash.Ctx) string { return "user-1" }}))
+	app.GET("/x", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/x", nil)
+	req.RemoteAddr = "203.0.113.7:1234"
+	app.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if len(a.keys) != 1 || a.keys[0] != "203.0.113.7" {
+		t.Fatalf("unexpected default key %v", a.keys)
+	}
+	if len(b.keys) != 1 || b.keys[0] != "user-1" {
+		t.Fatalf("unexpected custom key %v", b.keys)
+	}
+}
+
+func TestMultiLimitUsesLongestRetryAfter(t *testing.T) {
+	burst := &stubLimit{allow: false, retry: time.Second}
+	quota := &stubLimit{allow: false, retry: 30 * time.Minute}
+	ok := &stubLimit{allow: true}
+	app := flash.New()
+	app.Use(MultiLimit(Limit{Strategy: burst}, Limit{Strategy: ok}, Limit{Strategy: quota}))
+	app.GET("/x", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/x", nil))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1800" {
+		t.Fatalf("expected Retry-After 1800, got %q", got)
+	}
+	if len(ok.keys) != 1 || len(quota.keys) != 1 {
+		t.Fatalf("expected every limit to be evaluated")
+	}
+}
+
+func TestMultiLimitPerRouteKeys(t *testing.T) {
+	s := &stubLimit{allow: true}
+	app := flash.New()
+	app.Use(MultiLimit(Limit{Strategy: s, PerRoute: true, KeyFunc: func(flash.Ctx) string { return "k" }}))
+	app.GET("/users/:id", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+
+	for _, p := range []string{"/users/1", "/users/2"} {
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, p, nil))
+	}
+	if len(s.keys) != 2 || s.keys[0] != s.keys[1] || s.keys[0] != "GET /users/:id|k" {
+		t.Fatalf("expected keys scoped to the route pattern, got %v", s.keys)
+	}
+}
+
+func TestMultiLimitWithConfig(t *testing.T) {
+	s := &stubLimit{allow: false, retry: time.Second}
+	var gotRetry time.Duration
+	app := flash.New()
+	app.Use(MultiLimitWithConfig(MultiLimitConfig{
+		SkipFunc: func(c flash.Ctx) bool { return c.Path() == "/health" },
+		ErrorResponse: func(c flash.Ctx, retry time.Duration) error {
+			gotRetry = retry
+			return c.String(http.StatusServiceUnavailable, "slow down")
+		},
+		MaxKeyLength: 4,
+	}, Limit{Strategy: s, KeyFunc: func(flash.Ctx) string { return "abcdefgh" }}))
+	app.GET("/health", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+	app.GET("/x", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK || len(s.keys) != 0 {
+		t.Fatalf("expected skipped request, got %d with %v", rec.Code, s.keys)
+	}
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/x", nil))
+	if rec.Code != http.StatusServiceUnavailable || gotRetry != time.Second {
+		t.Fatalf("expected custom error response, got %d retry=%v", rec.Code, gotRetry)
+	}
+	if s.keys[0] != "abcd" {
+		t.Fatalf("expected key truncated to 4, got %q", s.keys[0])
+	}
+}
+
+func TestMultiLimitRequiresStrategy(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic")
+		}
+	}()
+	MultiLimit(Limit{})
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+func TestBuiltinMiddlewareNames(t *testing.T) {
+	cases := []struct {
+		mw   flash.Middleware
+		want string
+	}{
+		{Recover(), "recover"},
+		{RequestID(), "requestid"},
+		{Logger(), "logger"},
+		{CORS(CORSConfig{Origins: []string{"*"}}), "cors"},
+		{CSRF(), "csrf"},
+		{Timeout(TimeoutConfig{Duration: time.Second}), "timeout"},
+		{Buffer(), "buffer"},
+		{RateLimit(), "ratelimit"},
+		{RequestSize(RequestSizeConfig{MaxSize: 1 << 10}), "requestsize"},
+		{HeaderLimits(HeaderLimitsConfig{}), "headerlimits"},
+		{RequirePreconditions(PreconditionConfig{}), "preconditions"},
+		{Deprecate(DeprecationInfo{}), "deprecate"},
+		{ConnLimit(ConnLimitConfig{}), "connlimit"},
+		{Compress(), "compress"},
+	}
+	for _, tc := range cases {
+		if got := tc.mw.Name(); got != tc.want {
+			t.Fatalf("Name()=%q want %q", got, tc.want)
+		}
+		if tc.mw.Config() == nil {
+			t.Fatalf("%s: nil Config", tc.want)
+		}
+	}
+	if cfg, ok := Timeout(TimeoutConfig{Duration: 3 * time.Second}).Config().(TimeoutConfig); !ok || cfg.Duration != 3*time.Second {
+		t.Fatalf("timeout config=%#v", cfg)
+	}
+	if cfg, ok := Logger(WithMessage("hit")).Config().(LoggerConfig); !ok || cfg.Message != "hit" {
+		t.Fatalf("logger config=%#v", cfg)
+	}
+}
+
+func TestBuiltinMiddlewareConfigsOmitSecrets(t *testing.T) {
+	store := NewMemoryStore()
+	if cfg, ok := Sessions(SessionConfig{Store: store, Secure: true}).Config().(SessionConfig); !ok || cfg.Store != nil {
+		t.Fatalf("sessions config=%#v", cfg)
+	}
+	signer := &ResponseSigner{KeyID: "k", Sign: func([]byte) ([]byte, error) { return nil, nil }}
+	if cfg, ok := Integrity(IntegrityConfig{Signer: signer}).Config().(IntegrityConfig); !ok || cfg.Signer != nil {
+		t.Fatalf("integrity config=%#v", cfg)
+	}
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/goflash/flash/v2"
+)
+
+// PreconditionConfig configures RequirePreconditions.
+type PreconditionConfig struct {
+	// Methods are the methods that must be conditional. Default PUT, PATCH
+	// and DELETE.
+	Methods []string
+
+	// Skip exempts requests, e.g. creation by PUT of a resource that does not
+	// exist yet (which clients make conditional with If-None-Match: *).
+	Skip func(c flash.Ctx) bool
+
+	// ErrorResponse produces the response for unconditional writes. If nil, a
+	// 428 *flash.HTTPError wrapping flash.ErrPreconditionRequired is returned
+	// and rendered by the app's error handler.
+	ErrorResponse func(c flash.Ctx) error
+}
+
+// RequirePreconditions returns middleware that rejects unconditional writes
+// with 428 Precondition Required (RFC 6585), forcing clients to send the ETag
+// they last read so concurrent updates cannot silently overwrite each other
+// (the "lost update" problem, RFC 9110 section 13). A request is conditional
+// when it carries If-Match, the header c.RequireIfMatch checks; whether the
+// precondition holds is still up to the handler, and c.RequireIfMatch answers
+// 412 Precondition Failed on a mismatch.
+//
+// Apply it to the routes or groups whose writes must be conditional.
+//
+// Example:
+//
+//	docs := app.Group("/docs", middleware.RequirePreconditions(middleware.PreconditionConfig{}))
+//	docs.PUT("/:id", func(c flash.Ctx) error {
+//		doc := store.Get(c.Param("id"))
+//		if err := c.RequireIfMatch(doc.ETag); err != nil {
+//			return err
+//		}
+//		// ... update
+//	})
+func RequirePreconditions(cfg PreconditionConfig) flash.Middleware {
+	if cfg.Methods == nil {
+		cfg.Methods = []string{http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	methods := make([]string, len(cfg.Methods))
+	for i, m := range cfg.Methods {
+		methods[i] = strings.ToUpper(m)
+	}
+	if cfg.ErrorResponse == nil {
+		cfg.ErrorResponse = func(flash.Ctx) error {
+			return flash.NewError(http.StatusPreconditionRequired, "this request requires an If-Match header").WithErr(flash.ErrPreconditionRequired)
+		}
+	}
+
+	return flash.Describe("preconditions", cfg, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			r := c.Request()
+			if !slices.Contains(methods, r.Method) || r.Header.Get("If-Match") != "" {
+				return next(c)
+			}
+			if cfg.Skip != nil && cfg.Skip(c) {
+				return next(c)
+			}
+			return cfg.ErrorResponse(c)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goflash/flash/v2"
+)
+
+func TestRequirePreconditions(t *testing.T) {
+	a := flash.New()
+	var gotErr error
+	a.SetErrorHandler(func(c flash.Ctx, err error) {
+		gotErr = err
+		var he *flash.HTTPError
+		if errors.As(err, &he) {
+			_ = c.String(he.Code, he.Message)
+		}
+	})
+	a.Use(RequirePreconditions(PreconditionConfig{
+		Skip: func(c flash.Ctx) bool { return c.Request().Header.Get("If-None-Match") == "*" },
+	}))
+	ok := func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") }
+	a.GET("/docs/:id", ok)
+	a.PUT("/docs/:id", ok)
+	a.POST("/docs", ok)
+	a.DELETE("/docs/:id", ok)
+
+	cases := []struct {
+		method string
+		path   string
+		header map[string]string
+		want   int
+	}{
+		{http.MethodGet, "/docs/1", nil, http.StatusOK},
+		{http.MethodPost, "/docs", nil, http.StatusOK},
+		{http.MethodPut, "/docs/1", nil, http.StatusPreconditionRequired},
+		{http.MethodDelete, "/docs/1", nil, http.StatusPreconditionRequired},
+		{http.MethodPut, "/docs/1", map[string]string{"If-Match": `"v1"`}, http.StatusOK},
+		{http.MethodPut, "/docs/1", map[string]string{"If-Unmodified-Since": "Sat, 01 Jan 2000 00:00:00 GMT"}, http.StatusPreconditionRequired},
+		{http.MethodPut, "/docs/1", map[string]string{"If-None-Match": "*"}, http.StatusOK},
+	}
+	for _, tc := range cases {
+		gotErr = nil
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		for k, v := range tc.header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("%s %s %v: code=%d want %d", tc.method, tc.path, tc.header, rec.Code, tc.want)
+		}
+		if tc.want == http.StatusPreconditionRequired && !errors.Is(gotErr, flash.ErrPreconditionRequired) {
+			t.Fatalf("err=%v", gotErr)
+		}
+	}
+}
+
+func TestRequirePreconditions_CustomMethodsAndResponse(t *testing.T) {
+	a := flash.New()
+	a.Use(RequirePreconditions(PreconditionConfig{
+		Methods:       []string{"post"},
+		ErrorResponse: func(c flash.Ctx) error { return c.String(http.StatusConflict, "send If-Match") },
+	}))
+	ok := func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") }
+	a.POST("/x", ok)
+	a.PUT("/x", ok)
+	for method, want := range map[string]int{http.MethodPost: http.StatusConflict, http.MethodPut: http.StatusOK} {
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, httptest.NewRequest(method, "/x", nil))
+		if rec.Code != want {
+			t.Fatalf("%s code=%d want %d", method, rec.Code, want)
+		}
+	}
+}
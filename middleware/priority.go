@@ -0,0 +1,306 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+// PriorityClass is a traffic class scheduled by PriorityLimit.
+type PriorityClass struct {
+	// Name identifies the class, as returned by the classifier.
+	Name string
+	// Weight is the class's share of freed slots while requests are queued.
+	// A class with weight 4 is served four times as often as one with weight 1.
+	// If <= 0, defaults to 1.
+	Weight int
+	// QueueSize bounds how many requests of this class may wait. If <= 0,
+	// defaults to 100.
+	QueueSize int
+}
+
+// PriorityConfig configures the PriorityLimit middleware.
+type PriorityConfig struct {
+	// MaxConcurrent is the number of requests allowed in flight across all
+	// classes. If <= 0, defaults to 100.
+	MaxConcurrent int
+
+	// Classes lists the traffic classes. If empty, DefaultPriorityClasses is used.
+	Classes []PriorityClass
+
+	// Classify returns the class name for a request. Unknown or empty names
+	// use DefaultClass. If nil, every request uses DefaultClass; classifying by
+	// a client header (PriorityFromHeader) must be chosen explicitly.
+	Classify func(c flash.Ctx) string
+
+	// DefaultClass is the class for unclassified requests. If empty, the class
+	// named "default" is used when present, otherwise the first class.
+	DefaultClass string
+
+	// QueueTimeout bounds how long a queued request waits. If <= 0, defaults
+	// to 5 seconds.
+	QueueTimeout time.Duration
+
+	// SkipFunc, when it returns true, lets the request bypass the scheduler.
+	SkipFunc func(c flash.Ctx) bool
+
+	// ErrorResponse produces the response for rejected requests. If nil, a
+	// 503 with Retry-After is sent.
+	ErrorResponse func(c flash.Ctx, retryAfter time.Duration) error
+}
+
+// DefaultPriorityClasses are used when PriorityConfig.Classes is empty.
+var DefaultPriorityClasses = []PriorityClass{
+	{Name: "interactive", Weight: 8},
+	{Name: "default", Weight: 4},
+	{Name: "batch", Weight: 1},
+}
+
+// PriorityFromHeader classifies requests by the value of header. Clients
+// control their headers, so use it only behind a proxy that sets or strips
+// the header, or for classes a client gains nothing by claiming.
+func PriorityFromHeader(header string) func(c flash.Ctx) string {
+	return func(c flash.Ctx) string { return c.Request().Header.Get(header) }
+}
+
+// PriorityByRoute classifies requests by their route pattern, e.g.
+// {"/exports/:id": "batch", "/search": "interactive"}.
+func PriorityByRoute(routes map[string]string) func(c flash.Ctx) string {
+	m := make(map[string]string, len(routes))
+	for k, v := range routes {
+		m[k] = v
+	}
+	return func(c flash.Ctx) string { return m[c.Route()] }
+}
+
+// PriorityFromValue classifies requests by a string stored on the context
+// under key, e.g. a subscription tier set by authentication middleware.
+func PriorityFromValue(key string) func(c flash.Ctx) string {
+	return func(c flash.Ctx) string {
+		s, _ := c.Get(key).(string)
+		return s
+	}
+}
+
+// PriorityLimit returns middleware that bounds the number of in-flight
+// requests and, once that bound is reached, queues requests per class and
+// serves the queues by weighted fair queuing. Interactive or premium traffic
+// keeps flowing while bulk endpoints are saturated, and low-weight classes
+// still progress instead of starving.
+//
+// Requests are rejected when their class queue is full, the wait exceeds
+// QueueTimeout, or the client goes away.
+//
+// Example:
+//
+//	app.Use(middleware.PriorityLimit(middleware.PriorityConfig{
+//		MaxConcurrent: 200,
+//		Classes: []middleware.PriorityClass{
+//			{Name: "premium", Weight: 10},
+//			{Name: "default", Weight: 3},
+//			{Name: "batch", Weight: 1, QueueSize: 20},
+//		},
+//		Classify: middleware.PriorityFromValue("tier"),
+//	}))
+func PriorityLimit(cfg PriorityConfig) flash.Middleware {
+	s := NewPriorityScheduler(cfg.MaxConcurrent, cfg.Classes...)
+	if cfg.QueueTimeout <= 0 {
+		cfg.QueueTimeout = 5 * time.Second
+	}
+	if cfg.ErrorResponse == nil {
+		cfg.ErrorResponse = defaultPriorityErrorResponse
+	}
+	def := s.class(cfg.DefaultClass)
+	if def == nil {
+		if def = s.class("default"); def == nil {
+			def = s.classes[0]
+		}
+	}
+
+	return flash.Describe("priority", cfg, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			if cfg.SkipFunc != nil && cfg.SkipFunc(c) {
+				return next(c)
+			}
+			var pc *priorityQueue
+			if cfg.Classify != nil {
+				pc = s.class(cfg.Classify(c))
+			}
+			if pc == nil {
+				pc = def
+			}
+			if !s.acquire(pc, cfg.QueueTimeout, c.Context().Done()) {
+				return cfg.ErrorResponse(c, cfg.QueueTimeout)
+			}
+			defer s.Release()
+			return next(c)
+		}
+	})
+}
+
+// PriorityScheduler hands out a fixed number of slots, queuing waiters per
+// class and granting freed slots by weighted fair queuing (stride scheduling).
+type PriorityScheduler struct {
+	mu       sync.Mutex
+	max      int
+	inFlight int
+	classes  []*priorityQueue
+	pass     uint64 // virtual time: pass of the last served class
+}
+
+type priorityQueue struct {
+	PriorityClass
+	stride  uint64
+	pass    uint64
+	waiters []*priorityWaiter
+}
+
+type priorityWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// strideBase is divided by class weights to get their strides.
+const strideBase = 1 << 20
+
+// NewPriorityScheduler returns a scheduler with limit slots (default 100) and
+// the given classes (default DefaultPriorityClasses).
+func NewPriorityScheduler(limit int, classes ...PriorityClass) *PriorityScheduler {
+	if limit <= 0 {
+		limit = 100
+	}
+	if len(classes) == 0 {
+		classes = DefaultPriorityClasses
+	}
+	s := &PriorityScheduler{max: limit}
+	for _, pc := range classes {
+		if pc.Weight <= 0 {
+			pc.Weight = 1
+		}
+		if pc.QueueSize <= 0 {
+			pc.QueueSize = 100
+		}
+		s.classes = append(s.classes, &priorityQueue{PriorityClass: pc, stride: strideBase / uint64(pc.Weight)})
+	}
+	return s
+}
+
+// Acquire obtains a slot for the named class (unknown names use the first
+// class), waiting up to timeout. It returns false when the class queue is
+// full, the wait times out, or done is closed. On success the caller must
+// call Release exactly once.
+func (s *PriorityScheduler) Acquire(class string, timeout time.Duration, done <-chan struct{}) bool {
+	pc := s.class(class)
+	if pc == nil {
+		pc = s.classes[0]
+	}
+	return s.acquire(pc, timeout, done)
+}
+
+func (s *PriorityScheduler) acquire(pc *priorityQueue, timeout time.Duration, done <-chan struct{}) bool {
+	s.mu.Lock()
+	if s.inFlight < s.max && s.queued() == 0 {
+		s.inFlight++
+		s.mu.Unlock()
+		return true
+	}
+	if len(pc.waiters) >= pc.QueueSize {
+		s.mu.Unlock()
+		return false
+	}
+	if len(pc.waiters) == 0 && pc.pass < s.pass {
+		pc.pass = s.pass // no credit for time spent idle
+	}
+	w := &priorityWaiter{ready: make(chan struct{})}
+	pc.waiters = append(pc.waiters, w)
+	s.mu.Unlock()
+
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-w.ready:
+		return true
+	case <-t.C:
+	case <-done:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if w.granted {
+		return true // granted while timing out: keep the slot
+	}
+	for i, x := range pc.waiters {
+		if x == w {
+			pc.waiters = append(pc.waiters[:i], pc.waiters[i+1:]...)
+			break
+		}
+	}
+	return false
+}
+
+// Release frees a slot, handing it to the next waiter if any.
+func (s *PriorityScheduler) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var next *priorityQueue
+	for _, pc := range s.classes {
+		if len(pc.waiters) > 0 && (next == nil || pc.pass < next.pass) {
+			next = pc
+		}
+	}
+	if next == nil {
+		s.inFlight--
+		return
+	}
+	w := next.waiters[0]
+	next.waiters = next.waiters[1:]
+	s.pass = next.pass
+	next.pass += next.stride
+	w.granted = true
+	close(w.ready)
+}
+
+// InFlight returns the number of slots in use.
+func (s *PriorityScheduler) InFlight() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inFlight
+}
+
+// Queued returns the number of requests waiting in the named class.
+func (s *PriorityScheduler) Queued(class string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if pc := s.class(class); pc != nil {
+		return len(pc.waiters)
+	}
+	return 0
+}
+
+// class returns the queue for name or nil. Classes are fixed after
+// construction, so no lock is needed.
+func (s *PriorityScheduler) class(name string) *priorityQueue {
+	for _, pc := range s.classes {
+		if pc.Name == name {
+			return pc
+		}
+	}
+	return nil
+}
+
+// queued returns the total number of waiters. s.mu must be held.
+func (s *PriorityScheduler) queued() int {
+	n := 0
+	for _, pc := range s.classes {
+		n += len(pc.waiters)
+	}
+	return n
+}
+
+// defaultPriorityErrorResponse sends 503 with a Retry-After hint.
+func defaultPriorityErrorResponse(c flash.Ctx, retryAfter time.Duration) error {
+	c.Header("Retry-After", formatSeconds(retryAfter))
+	return c.String(http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable))
+}
@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+func TestPrioritySchedulerWeightedOrder(t *testing.T) {
+	s := NewPriorityScheduler(1,
+		PriorityClass{Name: "high", Weight: 3},
+		PriorityClass{Name: "low", Weight: 1},
+	)
+	if !s.Acquire("high", time.Second, nil) {
+		t.Fatalf("expected free slot")
+	}
+
+	var mu sync.Mutex
+	var order []string
+	granted := make(chan struct{}, 16)
+	enqueue := func(class string, n int) {
+		for i := 0; i < n; i++ {
+			go func() {
+				if s.Acquire(class, 5*time.Second, nil) {
+					mu.Lock()
+					order = append(order, class)
+					mu.Unlock()
+					granted <- struct{}{}
+				}
+			}()
+		}
+		waitFor(t, func() bool { return s.Queued(class) == n })
+	}
+	enqueue("low", 4)
+	enqueue("high", 8)
+
+	for i := 0; i < 8; i++ {
+		s.Release()
+		<-granted
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	high := 0
+	for _, c := range order {
+		if c == "high" {
+			high++
+		}
+	}
+	if high != 6 {
+		t.Fatalf("expected a 3:1 share (6 high of 8), got order %v", order)
+	}
+	if order[0] != "high" && order[1] != "high" {
+		t.Fatalf("high priority should be served early, got %v", order)
+	}
+}
+
+func TestPrioritySchedulerQueueFullAndTimeout(t *testing.T) {
+	s := NewPriorityScheduler(1, PriorityClass{Name: "a", QueueSize: 1})
+	if !s.Acquire("a", time.Second, nil) {
+		t.Fatalf("expected free slot")
+	}
+	res := make(chan bool)
+	go func() { res <- s.Acquire("a", 20*time.Millisecond, nil) }()
+	waitFor(t, func() bool { return s.Queued("a") == 1 })
+	if s.Acquire("a", time.Second, nil) {
+		t.Fatalf("expected rejection when the queue is full")
+	}
+	if <-res {
+		t.Fatalf("expected timeout")
+	}
+	if s.Queued("a") != 0 {
+		t.Fatalf("timed out waiter must leave the queue")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { res <- s.Acquire("unknown", time.Minute, ctx.Done()) }()
+	waitFor(t, func() bool { return s.Queued("a") == 1 })
+	cancel()
+	if <-res {
+		t.Fatalf("expected cancellation")
+	}
+
+	s.Release()
+	if s.InFlight() != 0 {
+		t.Fatalf("expected no slots in use, got %d", s.InFlight())
+	}
+}
+
+func TestPriorityLimitMiddleware(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 4)
+	app := flash.New()
+	app.Use(PriorityLimit(PriorityConfig{
+		MaxConcurrent: 1,
+		Classes:       []PriorityClass{{Name: "default", QueueSize: 1}, {Name: "batch"}},
+		Classify:      PriorityByRoute(map[string]string{"/batch": "batch"}),
+		QueueTimeout:  20 * time.Millisecond,
+		SkipFunc:      func(c flash.Ctx) bool { return c.Path() == "/health" },
+	}))
+	app.GET("/slow", func(c flash.Ctx) error {
+		started <- struct{}{}
+		<-release
+		return c.String(http.StatusOK, "ok")
+	})
+	app.GET("/batch", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+	app.GET("/health", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+
+	done := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		done <- rec.Code
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/batch", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 503 with Retry-After while saturated, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("skipped request should pass, got %d", rec.Code)
+	}
+
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/batch", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 once idle, got %d", rec.Code)
+	}
+}
+
+func TestPriorityLimitIgnoresHeaderByDefault(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	app := flash.New()
+	app.Use(PriorityLimit(PriorityConfig{
+		MaxConcurrent: 1,
+		Classes:       []PriorityClass{{Name: "default", QueueSize: 1}, {Name: "interactive"}},
+		QueueTimeout:  5 * time.Second,
+	}))
+	app.GET("/slow", func(c flash.Ctx) error {
+		started <- struct{}{}
+		<-release
+		return c.String(http.StatusOK, "ok")
+	})
+	app.GET("/fast", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+
+	done := make(chan int, 2)
+	serve := func(path string) {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		done <- rec.Code
+	}
+	go serve("/slow")
+	<-started
+	go serve("/fast") // fills the default queue
+	time.Sleep(20 * time.Millisecond)
+
+	// Without Classify the header must not move the request into the
+	// interactive queue, so the full default queue rejects it at once.
+	req := httptest.NewRequest(http.MethodGet, "/fast", nil)
+	req.Header.Set("X-Priority", "interactive")
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	app.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable || time.Since(start) > time.Second {
+		t.Fatalf("expected immediate 503, got %d after %v", rec.Code, time.Since(start))
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if code := <-done; code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", code)
+		}
+	}
+}
+
+func TestPriorityClassifiers(t *testing.T) {
+	app := flash.New()
+	var got []string
+	header := PriorityFromHeader("X-Priority")
+	value := PriorityFromValue("tier")
+	app.GET("/x", func(c flash.Ctx) error {
+		c.Set("tier", "premium")
+		got = append(got, header(c), value(c))
+		return nil
+	})
+	req := httptest.NewRequest(http.MethodGet, "/x", nil)
+	req.Header.Set("X-Priority", "interactive")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+	if len(got) != 2 || got[0] != "interactive" || got[1] != "premium" {
+		t.Fatalf("unexpected classes %v", got)
+	}
+}
@@ -0,0 +1,201 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Quota is a per-key limit: Limit requests per Window.
+type Quota struct {
+	Limit  int           `json:"limit"`
+	Window time.Duration `json:"window"`
+}
+
+// QuotaStore persists quota overrides so they survive restarts and can be
+// shared by instances. Implementations must be safe for concurrent use.
+type QuotaStore interface {
+	// LoadQuotas returns all stored overrides keyed by rate limit key.
+	LoadQuotas(ctx context.Context) (map[string]Quota, error)
+	// SaveQuota creates or replaces the override for key.
+	SaveQuota(ctx context.Context, key string, q Quota) error
+	// DeleteQuota removes the override for key. Missing keys are not an error.
+	DeleteQuota(ctx context.Context, key string) error
+}
+
+// ErrInvalidQuota is returned by SetOverride for a non-positive limit or window.
+var ErrInvalidQuota = errors.New("middleware: quota limit and window must be positive")
+
+// QuotaLimiter is a RateLimitStrategy that applies a base strategy to most
+// keys and per-key quota overrides to others, e.g. per-customer plans in a
+// SaaS API. Overrides can be changed at runtime and, with a QuotaStore,
+// persisted.
+//
+// Overridden keys are counted in fixed windows. Keys are matched after the
+// RateLimit middleware has normalized them, so use keys made of printable
+// ASCII and no longer than MaxKeyLength.
+//
+// Example:
+//
+//	limiter := middleware.NewQuotaLimiter(middleware.NewTokenBucketStrategy(100, time.Hour), store)
+//	if err := limiter.Load(ctx); err != nil {
+//		log.Fatal(err)
+//	}
+//	api.Use(middleware.RateLimit(
+//		middleware.WithStrategy(limiter),
+//		middleware.WithKeyFunc(func(c flash.Ctx) string { return "api:" + c.Get("account").(string) }),
+//	))
+//
+//	// On plan upgrade:
+//	_ = limiter.SetOverride("api:enterprise-123", middleware.Quota{Limit: 10_000, Window: time.Hour})
+type QuotaLimiter struct {
+	base  RateLimitStrategy
+	store QuotaStore
+
+	mu        sync.Mutex
+	overrides map[string]*quotaCounter
+	now       func() time.Time
+}
+
+type quotaCounter struct {
+	quota Quota
+	count int
+	reset time.Time
+}
+
+// NewQuotaLimiter returns a QuotaLimiter applying base to keys without an
+// override. store may be nil to keep overrides in memory only.
+func NewQuotaLimiter(base RateLimitStrategy, store QuotaStore) *QuotaLimiter {
+	if base == nil {
+		panic("middleware: NewQuotaLimiter requires a base strategy")
+	}
+	return &QuotaLimiter{base: base, store: store, overrides: make(map[string]*quotaCounter), now: time.Now}
+}
+
+// Name identifies the strategy.
+func (q *QuotaLimiter) Name() string { return "quota(" + q.base.Name() + ")" }
+
+// Allow applies the key's override if it has one, and the base strategy
+// otherwise.
+func (q *QuotaLimiter) Allow(key string) (bool, time.Duration) {
+	q.mu.Lock()
+	c := q.overrides[key]
+	if c == nil {
+		q.mu.Unlock()
+		return q.base.Allow(key)
+	}
+	defer q.mu.Unlock()
+	now := q.now()
+	if !now.Before(c.reset) {
+		c.count = 0
+		c.reset = now.Add(c.quota.Window)
+	}
+	if c.count < c.quota.Limit {
+		c.count++
+		return true, 0
+	}
+	return false, c.reset.Sub(now)
+}
+
+// Load replaces the in-memory overrides with those in the store. Call it at
+// start-up, and periodically if other instances change overrides.
+func (q *QuotaLimiter) Load(ctx context.Context) error {
+	if q.store == nil {
+		return nil
+	}
+	stored, err := q.store.LoadQuotas(ctx)
+	if err != nil {
+		return err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	next := make(map[string]*quotaCounter, len(stored))
+	for key, quota := range stored {
+		key = sanitizeKey(key)
+		if quota.Limit <= 0 || quota.Window <= 0 {
+			continue
+		}
+		if c := q.overrides[key]; c != nil && c.quota == quota {
+			next[key] = c // keep the running count
+			continue
+		}
+		next[key] = &quotaCounter{quota: quota}
+	}
+	q.overrides = next
+	return nil
+}
+
+// SetOverride sets the quota for key, persisting it first when a store is
+// configured. Changing an override restarts the key's window.
+func (q *QuotaLimiter) SetOverride(key string, quota Quota) error {
+	if quota.Limit <= 0 || quota.Window <= 0 {
+		return ErrInvalidQuota
+	}
+	key = sanitizeKey(key)
+	if q.store != nil {
+		if err := q.store.SaveQuota(context.Background(), key, quota); err != nil {
+			return err
+		}
+	}
+	q.mu.Lock()
+	q.overrides[key] = &quotaCounter{quota: quota}
+	q.mu.Unlock()
+	return nil
+}
+
+// RemoveOverride returns key to the base strategy.
+func (q *QuotaLimiter) RemoveOverride(key string) error {
+	key = sanitizeKey(key)
+	if q.store != nil {
+		if err := q.store.DeleteQuota(context.Background(), key); err != nil {
+			return err
+		}
+	}
+	q.mu.Lock()
+	delete(q.overrides, key)
+	q.mu.Unlock()
+	return nil
+}
+
+// Override returns the quota set for key, if any.
+func (q *QuotaLimiter) Override(key string) (Quota, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if c := q.overrides[sanitizeKey(key)]; c != nil {
+		return c.quota, true
+	}
+	return Quota{}, false
+}
+
+// Overrides returns a copy of all active overrides.
+func (q *QuotaLimiter) Overrides() map[string]Quota {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make(map[string]Quota, len(q.overrides))
+	for k, c := range q.overrides {
+		out[k] = c.quota
+	}
+	return out
+}
+
+// Close closes the base strategy if it has a Close method.
+func (q *QuotaLimiter) Close() {
+	if c, ok := q.base.(interface{ Close() }); ok {
+		c.Close()
+	}
+}
+
+// SetCleanupInterval forwards to the base strategy.
+func (q *QuotaLimiter) SetCleanupInterval(d time.Duration) {
+	if s, ok := q.base.(CleanupIntervalSetter); ok {
+		s.SetCleanupInterval(d)
+	}
+}
+
+// SetMaxKeys forwards to the base strategy; overrides are not counted.
+func (q *QuotaLimiter) SetMaxKeys(n int) {
+	if s, ok := q.base.(MaxKeysSetter); ok {
+		s.SetMaxKeys(n)
+	}
+}
@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memQuotaStore struct {
+	mu   sync.Mutex
+	m    map[string]Quota
+	fail error
+}
+
+func (s *memQuotaStore) LoadQuotas(context.Context) (map[string]Quota, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail != nil {
+		return nil, s.fail
+	}
+	out := make(map[string]Quota, len(s.m))
+	for k, v := range s.m {
+		out[k] = v
+	}
+	return out, nil
+}
+
+func (s *memQuotaStore) SaveQuota(_ context.Context, key string, q Quota) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail != nil {
+		return s.fail
+	}
+	s.m[key] = q
+	return nil
+}
+
+func (s *memQuotaStore) DeleteQuota(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail != nil {
+		return s.fail
+	}
+	delete(s.m, key)
+	return nil
+}
+
+func TestQuotaLimiterOverrides(t *testing.T) {
+	base := NewFixedWindowStrategy(1, time.Hour)
+	q := NewQuotaLimiter(base, nil)
+	defer q.Close()
+	now := time.Unix(1_700_000_000, 0)
+	q.now = func() time.Time { return now }
+
+	if err := q.SetOverride("api:enterprise", Quota{Limit: 3, Window: time.Hour}); err != nil {
+		t.Fatalf("SetOverride: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if ok, _ := q.Allow("api:enterprise"); !ok {
+			t.Fatalf("request %d should be allowed by the override", i)
+		}
+	}
+	ok, retry := q.Allow("api:enterprise")
+	if ok || retry != time.Hour {
+		t.Fatalf("expected rejection with 1h retry, got %v %v", ok, retry)
+	}
+	now = now.Add(time.Hour)
+	if ok, _ := q.Allow("api:enterprise"); !ok {
+		t.Fatalf("expected a new window")
+	}
+
+	// Other keys use the base strategy.
+	if ok, _ := q.Allow("api:free"); !ok {
+		t.Fatalf("first base request should pass")
+	}
+	if ok, _ := q.Allow("api:free"); ok {
+		t.Fatalf("base limit should apply")
+	}
+
+	if got, ok := q.Override("api:enterprise"); !ok || got.Limit != 3 {
+		t.Fatalf("unexpected override %+v %v", got, ok)
+	}
+	if all := q.Overrides(); len(all) != 1 {
+		t.Fatalf("unexpected overrides %v", all)
+	}
+	if err := q.RemoveOverride("api:enterprise"); err != nil {
+		t.Fatalf("RemoveOverride: %v", err)
+	}
+	if _, ok := q.Override("api:enterprise"); ok {
+		t.Fatalf("override should be removed")
+	}
+	if q.Name() != "quota(fixed_window)" {
+		t.Fatalf("unexpected name %q", q.Name())
+	}
+}
+
+func TestQuotaLimiterRejectsInvalidQuota(t *testing.T) {
+	q := NewQuotaLimiter(&stubLimit{allow: true}, nil)
+	if err := q.SetOverride("k", Quota{Limit: 0, Window: time.Hour}); !errors.Is(err, ErrInvalidQuota) {
+		t.Fatalf("expected ErrInvalidQuota, got %v", err)
+	}
+	if err := q.SetOverride("k", Quota{Limit: 1}); !errors.Is(err, ErrInvalidQuota) {
+		t.Fatalf("expected ErrInvalidQuota, got %v", err)
+	}
+}
+
+func TestQuotaLimiterStore(t *testing.T) {
+	store := &memQuotaStore{m: map[string]Quota{
+		"a":   {Limit: 1, Window: time.Minute},
+		"bad": {Limit: 0, Window: time.Minute},
+	}}
+	q := NewQuotaLimiter(&stubLimit{allow: true}, store)
+	if err := q.Load(context.Background()); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if all := q.Overrides(); len(all) != 1 || all["a"].Limit != 1 {
+		t.Fatalf("unexpected overrides after load %v", all)
+	}
+
+	// Reloading an unchanged quota keeps its running count.
+	q.Allow("a")
+	if err := q.Load(context.Background()); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if ok, _ := q.Allow("a"); ok {
+		t.Fatalf("expected count to survive reload")
+	}
+
+	if err := q.SetOverride("b", Quota{Limit: 5, Window: time.Hour}); err != nil {
+		t.Fatalf("SetOverride: %v", err)
+	}
+	if store.m["b"].Limit != 5 {
+		t.Fatalf("override not persisted")
+	}
+	if err := q.RemoveOverride("a"); err != nil {
+		t.Fatalf("RemoveOverride: %v", err)
+	}
+	if _, ok := store.m["a"]; ok {
+		t.Fatalf("override not deleted from store")
+	}
+
+	store.fail = errors.New("db down")
+	if err := q.SetOverride("c", Quota{Limit: 1, Window: time.Hour}); err == nil {
+		t.Fatalf("expected store error")
+	}
+	if _, ok := q.Override("c"); ok {
+		t.Fatalf("override must not be applied when persisting fails")
+	}
+	if err := q.RemoveOverride("b"); err == nil {
+		t.Fatalf("expected store error")
+	}
+	if err := q.Load(context.Background()); err == nil {
+		t.Fatalf("expected store error")
+	}
+	if _, ok := q.Override("b"); !ok {
+		t.Fatalf("failed load must keep current overrides")
+	}
+}
+
+func TestQuotaLimiterForwardsToBase(t *testing.T) {
+	base := NewTokenBucketStrategy(1, time.Minute)
+	q := NewQuotaLimiter(base, nil)
+	_ = RateLimit(WithStrategy(q), WithCleanupInterval(time.Minute), WithMaxKeys(7))
+	if got := base.cleanupTask.Interval(); got != time.Minute {
+		t.Fatalf("cleanup interval not forwarded, got %v", got)
+	}
+	base.mu.RLock()
+	maxKeys := base.maxKeys
+	base.mu.RUnlock()
+	if maxKeys != 7 {
+		t.Fatalf("max keys not forwarded, got %d", maxKeys)
+	}
+	before := DefaultJanitor.Len()
+	q.Close()
+	if DefaultJanitor.Len() != before-1 {
+		t.Fatalf("Close not forwarded")
+	}
+}
+
+func TestNewQuotaLimiterRequiresBase(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic")
+		}
+	}()
+	NewQuotaLimiter(nil, nil)
+}
@@ -80,6 +80,7 @@
 //		middleware.WithTrustedProxies([]string{"10.0.0.0/8", "172.16.0.0/12"}),
 //		middleware.WithMaxKeyLength(128),
 //		middleware.WithCleanupInterval(5 * time.Minute),
+//		middleware.WithMaxKeys(100_000),
 //		middleware.WithKeyFunc(func(c flash.Ctx) string {
 //			// Custom key extraction logic
 //			return extractClientKey(c)
@@ -173,6 +174,23 @@ type RateLimitStrategy interface {
 	Name() string
 }
 
+// CleanupIntervalSetter is implemented by strategies that keep per-key state
+// in memory and clean it up periodically. RateLimit passes the configured
+// CleanupInterval to strategies implementing it.
+type CleanupIntervalSetter interface {
+	// SetCleanupInterval changes the cleanup interval; negative disables cleanup
+	// and a later positive interval enables it again.
+	SetCleanupInterval(d time.Duration)
+}
+
+// MaxKeysSetter is implemented by strategies that can cap the number of keys
+// they track. RateLimit passes the configured MaxKeys to strategies
+// implementing it.
+type MaxKeysSetter interface {
+	// SetMaxKeys caps the number of tracked keys; 0 means unlimited.
+	SetMaxKeys(n int)
+}
+
 // RateLimitConfig holds configuration for the RateLimit middleware.
 // It provides comprehensive options to customize rate limiting behavior,
 // security settings, and performance characteristics.
@@ -287,7 +305,19 @@ type RateLimitConfig struct {
 	//   - Low traffic: 10-30 minutes (less frequent cleanup)
 	//   - Memory constrained: 1-2 minutes (aggressive cleanup)
 	//   - Performance critical: 10+ minutes (less CPU overhead)
+	//
+	// It is applied to strategies implementing CleanupIntervalSetter (all
+	// built-in in-memory strategies).
 	CleanupInterval time.Duration
+
+	// MaxKeys caps how many distinct keys the strategy tracks, bounding memory
+	// when an attacker floods the limiter with unique keys. When the cap is
+	// reached, an arbitrary tracked key is evicted for each new one, which
+	// resets that client's limit. If 0, the number of keys is unlimited.
+	//
+	// It is applied to strategies implementing MaxKeysSetter (all built-in
+	// in-memory strategies).
+	MaxKeys int
 }
 
 // RateLimitOption is a function that configures the RateLimit middleware.
@@ -552,14 +582,29 @@ func WithMaxKeyLength(maxLength int) RateLimitOption {
 //	// Disable cleanup (not recommended for production)
 //	middleware.WithCleanupInterval(-1)
 //
-// Note: Each rate limiting strategy runs its own cleanup goroutine.
-// The cleanup process is lightweight and runs in the background.
+// The interval is applied to strategies implementing CleanupIntervalSetter.
+// Built-in strategies share the DefaultJanitor goroutine for cleanup.
 func WithCleanupInterval(interval time.Duration) RateLimitOption {
 	return func(cfg *RateLimitConfig) {
 		cfg.CleanupInterval = interval
 	}
 }
 
+// WithMaxKeys caps how many distinct keys the strategy tracks. When the cap is
+// reached, an arbitrary tracked key is evicted for each new key, so memory
+// stays bounded under key-flood attacks at the cost of occasionally resetting
+// a client's limit. Size it well above the expected number of active clients.
+//
+// Examples:
+//
+//	// At most 100k tracked clients (a few MB for token buckets)
+//	middleware.WithMaxKeys(100_000)
+func WithMaxKeys(n int) RateLimitOption {
+	return func(cfg *RateLimitConfig) {
+		cfg.MaxKeys = n
+	}
+}
+
 // =============================================================================
 // Token Bucket Strategy
 // =============================================================================
@@ -572,8 +617,8 @@ type TokenBucketStrategy struct {
 	capacity    int
 	refill      time.Duration
 	lastCleanup int64 // atomic timestamp
-	cleanupDone chan struct{}
-	cleanupOnce sync.Once
+	cleanupTask *JanitorTask
+	maxKeys     int // 0 = unlimited; guarded by mu
 }
 
 type tokenBucket struct {
@@ -605,16 +650,13 @@ func NewTokenBucketStrategy(capacity int, refill time.Duration) *TokenBucketStra
 	}
 
 	tb := &TokenBucketStrategy{
-		buckets:     make(map[string]*tokenBucket),
-		capacity:    capacity,
-		refill:      refill,
-		cleanupDone: make(chan struct{}),
+		buckets:  make(map[string]*tokenBucket),
+		capacity: capacity,
+		refill:   refill,
 	}
 
-	// Start cleanup goroutine
-	tb.cleanupOnce.Do(func() {
-		go tb.cleanup()
-	})
+	// Register periodic cleanup with the shared janitor
+	tb.cleanupTask = DefaultJanitor.Schedule(defaultCleanupInterval, tb.cleanup)
 
 	return tb
 }
@@ -633,6 +675,9 @@ func (tb *TokenBucketStrategy) Allow(key string) (bool, time.Duration) {
 
 	// Handle new bucket or expired bucket
 	if bucket == nil || now.After(bucket.reset) {
+		if bucket == nil {
+			evictForNewKey(tb.buckets, tb.maxKeys)
+		}
 		bucket = &tokenBucket{
 			remaining: tb.capacity,
 			reset:     now.Add(tb.refill),
@@ -654,32 +699,36 @@ func (tb *TokenBucketStrategy) Allow(key string) (bool, time.Duration) {
 }
 
 // cleanup removes expired buckets to prevent memory leaks
-func (tb *TokenBucketStrategy) cleanup() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			now := time.Now()
-			atomic.StoreInt64(&tb.lastCleanup, now.Unix())
-
-			tb.mu.Lock()
-			for key, bucket := range tb.buckets {
-				if now.After(bucket.reset.Add(tb.refill)) {
-					delete(tb.buckets, key)
-				}
-			}
-			tb.mu.Unlock()
-		case <-tb.cleanupDone:
-			return
+func (tb *TokenBucketStrategy) cleanup(now time.Time) {
+	atomic.StoreInt64(&tb.lastCleanup, now.Unix())
+
+	tb.mu.Lock()
+	for key, bucket := range tb.buckets {
+		if now.After(bucket.reset.Add(tb.refill)) {
+			delete(tb.buckets, key)
 		}
 	}
+	tb.mu.Unlock()
 }
 
-// Close stops the cleanup goroutine
+// Close unregisters the strategy from the cleanup janitor. It is safe to call
+// more than once.
 func (tb *TokenBucketStrategy) Close() {
-	close(tb.cleanupDone)
+	tb.cleanupTask.Stop()
+}
+
+// SetCleanupInterval changes how often expired entries are removed. A
+// negative interval disables cleanup.
+func (tb *TokenBucketStrategy) SetCleanupInterval(d time.Duration) {
+	setCleanupInterval(tb.cleanupTask, d)
+}
+
+// SetMaxKeys caps the number of tracked keys (0 means unlimited). When the cap
+// is reached, an arbitrary tracked key is evicted to make room for a new one.
+func (tb *TokenBucketStrategy) SetMaxKeys(n int) {
+	tb.mu.Lock()
+	tb.maxKeys = n
+	tb.mu.Unlock()
 }
 
 // =============================================================================
@@ -694,8 +743,8 @@ type FixedWindowStrategy struct {
 	limit       int
 	window      time.Duration
 	lastCleanup int64 // atomic timestamp
-	cleanupDone chan struct{}
-	cleanupOnce sync.Once
+	cleanupTask *JanitorTask
+	maxKeys     int // 0 = unlimited; guarded by mu
 }
 
 type fixedWindow struct {
@@ -727,16 +776,13 @@ func NewFixedWindowStrategy(limit int, window time.Duration) *FixedWindowStrateg
 	}
 
 	fw := &FixedWindowStrategy{
-		windows:     make(map[string]*fixedWindow),
-		limit:       limit,
-		window:      window,
-		cleanupDone: make(chan struct{}),
+		windows: make(map[string]*fixedWindow),
+		limit:   limit,
+		window:  window,
 	}
 
-	// Start cleanup goroutine
-	fw.cleanupOnce.Do(func() {
-		go fw.cleanup()
-	})
+	// Register periodic cleanup with the shared janitor
+	fw.cleanupTask = DefaultJanitor.Schedule(defaultCleanupInterval, fw.cleanup)
 
 	return fw
 }
@@ -758,6 +804,9 @@ func (fw *FixedWindowStrategy) Allow(key string) (bool, time.Duration) {
 		// Double-check after acquiring write lock
 		window = fw.windows[key]
 		if window == nil || now.After(window.reset) {
+			if window == nil {
+				evictForNewKey(fw.windows, fw.maxKeys)
+			}
 			// Start new window
 			window = &fixedWindow{
 				count: 1,
@@ -775,6 +824,9 @@ func (fw *FixedWindowStrategy) Allow(key string) (bool, time.Duration) {
 	// Re-check window state after acquiring lock
 	window = fw.windows[key]
 	if window == nil || now.After(window.reset) {
+		if window == nil {
+			evictForNewKey(fw.windows, fw.maxKeys)
+		}
 		window = &fixedWindow{
 			count: 1,
 			reset: now.Add(fw.window),
@@ -796,32 +848,36 @@ func (fw *FixedWindowStrategy) Allow(key string) (bool, time.Duration) {
 }
 
 // cleanup removes expired windows to prevent memory leaks
-func (fw *FixedWindowStrategy) cleanup() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			now := time.Now()
-			atomic.StoreInt64(&fw.lastCleanup, now.Unix())
-
-			fw.mu.Lock()
-			for key, window := range fw.windows {
-				if now.After(window.reset.Add(fw.window)) {
-					delete(fw.windows, key)
-				}
-			}
-			fw.mu.Unlock()
-		case <-fw.cleanupDone:
-			return
+func (fw *FixedWindowStrategy) cleanup(now time.Time) {
+	atomic.StoreInt64(&fw.lastCleanup, now.Unix())
+
+	fw.mu.Lock()
+	for key, window := range fw.windows {
+		if now.After(window.reset.Add(fw.window)) {
+			delete(fw.windows, key)
 		}
 	}
+	fw.mu.Unlock()
 }
 
-// Close stops the cleanup goroutine
+// Close unregisters the strategy from the cleanup janitor. It is safe to call
+// more than once.
 func (fw *FixedWindowStrategy) Close() {
-	close(fw.cleanupDone)
+	fw.cleanupTask.Stop()
+}
+
+// SetCleanupInterval changes how often expired entries are removed. A
+// negative interval disables cleanup.
+func (fw *FixedWindowStrategy) SetCleanupInterval(d time.Duration) {
+	setCleanupInterval(fw.cleanupTask, d)
+}
+
+// SetMaxKeys caps the number of tracked keys (0 means unlimited). When the cap
+// is reached, an arbitrary tracked key is evicted to make room for a new one.
+func (fw *FixedWindowStrategy) SetMaxKeys(n int) {
+	fw.mu.Lock()
+	fw.maxKeys = n
+	fw.mu.Unlock()
 }
 
 // =============================================================================
@@ -836,8 +892,8 @@ type SlidingWindowStrategy struct {
 	limit       int
 	window      time.Duration
 	lastCleanup int64 // atomic timestamp
-	cleanupDone chan struct{}
-	cleanupOnce sync.Once
+	cleanupTask *JanitorTask
+	maxKeys     int // 0 = unlimited; guarded by mu
 }
 
 // NewSlidingWindowStrategy creates a new sliding window rate limiter.
@@ -864,16 +920,13 @@ func NewSlidingWindowStrategy(limit int, window time.Duration) *SlidingWindowStr
 	}
 
 	sw := &SlidingWindowStrategy{
-		windows:     make(map[string][]time.Time),
-		limit:       limit,
-		window:      window,
-		cleanupDone: make(chan struct{}),
+		windows: make(map[string][]time.Time),
+		limit:   limit,
+		window:  window,
 	}
 
-	// Start cleanup goroutine
-	sw.cleanupOnce.Do(func() {
-		go sw.cleanup()
-	})
+	// Register periodic cleanup with the shared janitor
+	sw.cleanupTask = DefaultJanitor.Schedule(defaultCleanupInterval, sw.cleanup)
 
 	return sw
 }
@@ -918,49 +971,56 @@ func (sw *SlidingWindowStrategy) Allow(key string) (bool, time.Duration) {
 	}
 
 	// Add current request
+	if timestamps == nil {
+		evictForNewKey(sw.windows, sw.maxKeys)
+	}
 	valid = append(valid, now)
 	sw.windows[key] = valid
 	return true, 0
 }
 
 // cleanup removes expired timestamps to prevent memory leaks
-func (sw *SlidingWindowStrategy) cleanup() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			now := time.Now()
-			atomic.StoreInt64(&sw.lastCleanup, now.Unix())
-			cutoff := now.Add(-sw.window * 2) // Extra buffer for cleanup
-
-			sw.mu.Lock()
-			for key, timestamps := range sw.windows {
-				// Filter out very old timestamps
-				valid := timestamps[:0]
-				for _, t := range timestamps {
-					if t.After(cutoff) {
-						valid = append(valid, t)
-					}
-				}
+func (sw *SlidingWindowStrategy) cleanup(now time.Time) {
+	atomic.StoreInt64(&sw.lastCleanup, now.Unix())
+	cutoff := now.Add(-sw.window * 2) // Extra buffer for cleanup
 
-				if len(valid) == 0 {
-					delete(sw.windows, key)
-				} else {
-					sw.windows[key] = valid
-				}
+	sw.mu.Lock()
+	for key, timestamps := range sw.windows {
+		// Filter out very old timestamps
+		valid := timestamps[:0]
+		for _, t := range timestamps {
+			if t.After(cutoff) {
+				valid = append(valid, t)
 			}
-			sw.mu.Unlock()
-		case <-sw.cleanupDone:
-			return
+		}
+
+		if len(valid) == 0 {
+			delete(sw.windows, key)
+		} else {
+			sw.windows[key] = valid
 		}
 	}
+	sw.mu.Unlock()
 }
 
-// Close stops the cleanup goroutine
+// Close unregisters the strategy from the cleanup janitor. It is safe to call
+// more than once.
 func (sw *SlidingWindowStrategy) Close() {
-	close(sw.cleanupDone)
+	sw.cleanupTask.Stop()
+}
+
+// SetCleanupInterval changes how often expired entries are removed. A
+// negative interval disables cleanup.
+func (sw *SlidingWindowStrategy) SetCleanupInterval(d time.Duration) {
+	setCleanupInterval(sw.cleanupTask, d)
+}
+
+// SetMaxKeys caps the number of tracked keys (0 means unlimited). When the cap
+// is reached, an arbitrary tracked key is evicted to make room for a new one.
+func (sw *SlidingWindowStrategy) SetMaxKeys(n int) {
+	sw.mu.Lock()
+	sw.maxKeys = n
+	sw.mu.Unlock()
 }
 
 // =============================================================================
@@ -975,8 +1035,8 @@ type LeakyBucketStrategy struct {
 	rate        float64 // requests per second
 	capacity    int
 	lastCleanup int64 // atomic timestamp
-	cleanupDone chan struct{}
-	cleanupOnce sync.Once
+	cleanupTask *JanitorTask
+	maxKeys     int // 0 = unlimited; guarded by mu
 }
 
 type leakyBucket struct {
@@ -1008,16 +1068,13 @@ func NewLeakyBucketStrategy(rate float64, capacity int) *LeakyBucketStrategy {
 	}
 
 	lb := &LeakyBucketStrategy{
-		buckets:     make(map[string]*leakyBucket),
-		rate:        rate,
-		capacity:    capacity,
-		cleanupDone: make(chan struct{}),
+		buckets:  make(map[string]*leakyBucket),
+		rate:     rate,
+		capacity: capacity,
 	}
 
-	// Start cleanup goroutine
-	lb.cleanupOnce.Do(func() {
-		go lb.cleanup()
-	})
+	// Register periodic cleanup with the shared janitor
+	lb.cleanupTask = DefaultJanitor.Schedule(defaultCleanupInterval, lb.cleanup)
 
 	return lb
 }
@@ -1039,6 +1096,7 @@ func (lb *LeakyBucketStrategy) Allow(key string) (bool, time.Duration) {
 		// Double-check after acquiring write lock
 		bucket = lb.buckets[key]
 		if bucket == nil {
+			evictForNewKey(lb.buckets, lb.maxKeys)
 			bucket = &leakyBucket{
 				lastLeak: now,
 				level:    1, // Start with 1 since we're allowing this request
@@ -1072,33 +1130,37 @@ func (lb *LeakyBucketStrategy) Allow(key string) (bool, time.Duration) {
 }
 
 // cleanup removes inactive buckets to prevent memory leaks
-func (lb *LeakyBucketStrategy) cleanup() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			now := time.Now()
-			atomic.StoreInt64(&lb.lastCleanup, now.Unix())
-			cutoff := now.Add(-10 * time.Minute) // Remove buckets inactive for 10 minutes
-
-			lb.mu.Lock()
-			for key, bucket := range lb.buckets {
-				if bucket.lastLeak.Before(cutoff) && bucket.level == 0 {
-					delete(lb.buckets, key)
-				}
-			}
-			lb.mu.Unlock()
-		case <-lb.cleanupDone:
-			return
+func (lb *LeakyBucketStrategy) cleanup(now time.Time) {
+	atomic.StoreInt64(&lb.lastCleanup, now.Unix())
+	cutoff := now.Add(-10 * time.Minute) // Remove buckets inactive for 10 minutes
+
+	lb.mu.Lock()
+	for key, bucket := range lb.buckets {
+		if bucket.lastLeak.Before(cutoff) && bucket.level == 0 {
+			delete(lb.buckets, key)
 		}
 	}
+	lb.mu.Unlock()
 }
 
-// Close stops the cleanup goroutine
+// Close unregisters the strategy from the cleanup janitor. It is safe to call
+// more than once.
 func (lb *LeakyBucketStrategy) Close() {
-	close(lb.cleanupDone)
+	lb.cleanupTask.Stop()
+}
+
+// SetCleanupInterval changes how often expired entries are removed. A
+// negative interval disables cleanup.
+func (lb *LeakyBucketStrategy) SetCleanupInterval(d time.Duration) {
+	setCleanupInterval(lb.cleanupTask, d)
+}
+
+// SetMaxKeys caps the number of tracked keys (0 means unlimited). When the cap
+// is reached, an arbitrary tracked key is evicted to make room for a new one.
+func (lb *LeakyBucketStrategy) SetMaxKeys(n int) {
+	lb.mu.Lock()
+	lb.maxKeys = n
+	lb.mu.Unlock()
 }
 
 // =============================================================================
@@ -1115,8 +1177,8 @@ type AdaptiveStrategy struct {
 	maxRate     float64
 	window      time.Duration
 	lastCleanup int64 // atomic timestamp
-	cleanupDone chan struct{}
-	cleanupOnce sync.Once
+	cleanupTask *JanitorTask
+	maxKeys     int // 0 = unlimited; guarded by mu
 }
 
 type adaptiveClient struct {
@@ -1154,18 +1216,15 @@ func NewAdaptiveStrategy(baseRate, minRate, maxRate float64, window time.Duratio
 	}
 
 	as := &AdaptiveStrategy{
-		clients:     make(map[string]*adaptiveClient),
-		baseRate:    baseRate,
-		minRate:     minRate,
-		maxRate:     maxRate,
-		window:      window,
-		cleanupDone: make(chan struct{}),
+		clients:  make(map[string]*adaptiveClient),
+		baseRate: baseRate,
+		minRate:  minRate,
+		maxRate:  maxRate,
+		window:   window,
 	}
 
-	// Start cleanup goroutine
-	as.cleanupOnce.Do(func() {
-		go as.cleanup()
-	})
+	// Register periodic cleanup with the shared janitor
+	as.cleanupTask = DefaultJanitor.Schedule(defaultCleanupInterval, as.cleanup)
 
 	return as
 }
@@ -1187,6 +1246,7 @@ func (as *AdaptiveStrategy) Allow(key string) (bool, time.Duration) {
 		// Double-check after acquiring write lock
 		client = as.clients[key]
 		if client == nil {
+			evictForNewKey(as.clients, as.maxKeys)
 			client = &adaptiveClient{
 				lastRequest: now,
 				currentRate: as.baseRate,
@@ -1239,33 +1299,37 @@ func (as *AdaptiveStrategy) UpdateRate(key string, isGood bool) {
 }
 
 // cleanup removes inactive clients to prevent memory leaks
-func (as *AdaptiveStrategy) cleanup() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			now := time.Now()
-			atomic.StoreInt64(&as.lastCleanup, now.Unix())
-			cutoff := now.Add(-as.window * 2) // Remove clients inactive for 2x window duration
-
-			as.mu.Lock()
-			for key, client := range as.clients {
-				if client.lastRequest.Before(cutoff) {
-					delete(as.clients, key)
-				}
-			}
-			as.mu.Unlock()
-		case <-as.cleanupDone:
-			return
+func (as *AdaptiveStrategy) cleanup(now time.Time) {
+	atomic.StoreInt64(&as.lastCleanup, now.Unix())
+	cutoff := now.Add(-as.window * 2) // Remove clients inactive for 2x window duration
+
+	as.mu.Lock()
+	for key, client := range as.clients {
+		if client.lastRequest.Before(cutoff) {
+			delete(as.clients, key)
 		}
 	}
+	as.mu.Unlock()
 }
 
-// Close stops the cleanup goroutine
+// Close unregisters the strategy from the cleanup janitor. It is safe to call
+// more than once.
 func (as *AdaptiveStrategy) Close() {
-	close(as.cleanupDone)
+	as.cleanupTask.Stop()
+}
+
+// SetCleanupInterval changes how often expired entries are removed. A
+// negative interval disables cleanup.
+func (as *AdaptiveStrategy) SetCleanupInterval(d time.Duration) {
+	setCleanupInterval(as.cleanupTask, d)
+}
+
+// SetMaxKeys caps the number of tracked keys (0 means unlimited). When the cap
+// is reached, an arbitrary tracked key is evicted to make room for a new one.
+func (as *AdaptiveStrategy) SetMaxKeys(n int) {
+	as.mu.Lock()
+	as.maxKeys = n
+	as.mu.Unlock()
 }
 
 // =============================================================================
@@ -1427,9 +1491,7 @@ func RateLimit(options ...RateLimitOption) flash.Middleware {
 		cfg.Strategy = NewTokenBucketStrategy(100, time.Minute)
 	}
 	if cfg.KeyFunc == nil {
-		cfg.KeyFunc = func(c flash.Ctx) string {
-			return secureClientIP(c.Request(), cfg.TrustedProxies)
-		}
+		cfg.KeyFunc = ClientIPKeyFunc(cfg.TrustedProxies)
 	}
 	if cfg.ErrorResponse == nil {
 		cfg.ErrorResponse = defaultErrorResponse
@@ -1440,30 +1502,25 @@ func RateLimit(options ...RateLimitOption) flash.Middleware {
 	if cfg.CleanupInterval == 0 {
 		cfg.CleanupInterval = 5 * time.Minute
 	}
+	if s, ok := cfg.Strategy.(CleanupIntervalSetter); ok {
+		s.SetCleanupInterval(cfg.CleanupInterval)
+	}
+	if s, ok := cfg.Strategy.(MaxKeysSetter); ok && cfg.MaxKeys > 0 {
+		s.SetMaxKeys(cfg.MaxKeys)
+	}
 
 	// Parse trusted proxies (validation is done in secureClientIP)
 	_ = cfg.TrustedProxies
 
-	return func(next flash.Handler) flash.Handler {
+	return flash.Describe("ratelimit", *cfg, func(next flash.Handler) flash.Handler {
 		return func(c flash.Ctx) error {
 			// Check if rate limiting should be skipped
 			if cfg.SkipFunc != nil && cfg.SkipFunc(c) {
 				return next(c)
 			}
 
-			// Extract key
-			key := cfg.KeyFunc(c)
-			if key == "" {
-				key = "unknown"
-			}
-
-			// Validate key length to prevent memory exhaustion attacks
-			if len(key) > cfg.MaxKeyLength {
-				key = key[:cfg.MaxKeyLength]
-			}
-
-			// Sanitize key to prevent injection attacks
-			key = sanitizeKey(key)
+			// Extract, bound and sanitize the key
+			key := normalizeKey(cfg.KeyFunc(c), cfg.MaxKeyLength)
 
 			// Check if request is allowed
 			allowed, retryAfter := cfg.Strategy.Allow(key)
@@ -1473,7 +1530,62 @@ func RateLimit(options ...RateLimitOption) flash.Middleware {
 
 			return next(c)
 		}
+	})
+}
+
+// setCleanupInterval applies a configured cleanup interval to a janitor task.
+func setCleanupInterval(task *JanitorTask, d time.Duration) {
+	if d < 0 {
+		task.Stop()
+		return
+	}
+	task.SetInterval(d)
+}
+
+// evictForNewKey makes room for one more key in m when it already holds
+// maxKeys entries by deleting arbitrary keys. Callers hold the write lock.
+func evictForNewKey[V any](m map[string]V, maxKeys int) {
+	if maxKeys <= 0 {
+		return
+	}
+	n := 0
+	for k := range m {
+		if len(m) < maxKeys {
+			break
+		}
+		delete(m, k)
+		n++
+	}
+	if n > 0 {
+		flash.DefaultMetrics.RateLimitEvicted(n)
+	}
+}
+
+// ClientIPKeyFunc returns the key extractor used by RateLimit and
+// ConcurrencyLimit when no KeyFunc is configured: the client IP, honoring
+// X-Forwarded-For only from trustedProxies. Use it to compose custom keys that
+// stay consistent with the default behavior.
+//
+// Example:
+//
+//	ipKey := middleware.ClientIPKeyFunc([]string{"10.0.0.0/8"})
+//	keyFunc := func(c flash.Ctx) string { return c.Param("tenant") + ":" + ipKey(c) }
+func ClientIPKeyFunc(trustedProxies []string) func(c flash.Ctx) string {
+	return func(c flash.Ctx) string {
+		return secureClientIP(c.Request(), trustedProxies)
+	}
+}
+
+// normalizeKey substitutes "unknown" for an empty key, truncates it to
+// maxLen to prevent memory exhaustion and sanitizes it against injection.
+func normalizeKey(key string, maxLen int) string {
+	if key == "" {
+		key = "unknown"
+	}
+	if len(key) > maxLen {
+		key = key[:maxLen]
 	}
+	return sanitizeKey(key)
 }
 
 // defaultKeyFunc extracts the client IP address as the rate limiting key.
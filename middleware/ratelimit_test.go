@@ -2,7 +2,12 @@ package middleware
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"mime/multipart"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -14,6 +19,7 @@ import (
 
 	"github.com/goflash/flash/v2"
 	"github.com/goflash/flash/v2/ctx"
+	"github.com/goflash/flash/v2/storage"
 )
 
 func TestRateLimitBlocksAfterCapacity(t *testing.T) {
@@ -898,22 +904,66 @@ func (m *mockCtx) ParamAlphaNum(string) string                               { r
 func (m *mockCtx) QueryAlphaNum(string) string                               { return "" }
 func (m *mockCtx) ParamFilename(string) string                               { return "" }
 func (m *mockCtx) QueryFilename(string) string                               { return "" }
+func (m *mockCtx) ParamSanitized(string, string) string                      { return "" }
+func (m *mockCtx) QuerySanitized(string, string) string                      { return "" }
 func (m *mockCtx) Header(string, string)                                     {}
+func (m *mockCtx) TeeBody(io.Writer)                                         {}
+func (m *mockCtx) Cookies() map[string]string                                { return nil }
+func (m *mockCtx) SetCookie(*http.Cookie) error                              { return nil }
+func (m *mockCtx) ClearCookie(string)                                        {}
+func (m *mockCtx) AppendHeader(string, string)                               {}
+func (m *mockCtx) SetHeaders(map[string]string)                              {}
+func (m *mockCtx) RequestHeader(k string) string                             { return m.req.Header.Get(k) }
+func (m *mockCtx) HeaderInt(string, ...int) int                              { return 0 }
+func (m *mockCtx) HeaderTime(string, ...time.Time) time.Time                 { return time.Time{} }
+func (m *mockCtx) HeaderList(string) []ctx.HeaderValue                       { return nil }
 func (m *mockCtx) Status(int) flash.Ctx                                      { return m }
 func (m *mockCtx) StatusCode() int                                           { return 200 }
 func (m *mockCtx) JSON(any) error                                            { return nil }
 func (m *mockCtx) String(int, string) error                                  { return nil }
 func (m *mockCtx) Send(int, string, []byte) (int, error)                     { return 0, nil }
+func (m *mockCtx) Image(image.Image, string, int) error                      { return nil }
 func (m *mockCtx) WroteHeader() bool                                         { return false }
 func (m *mockCtx) BindJSON(any, ...ctx.BindJSONOptions) error                { return nil }
+func (m *mockCtx) BindJSONPatch(any, ...ctx.PatchOptions) error              { return nil }
+func (m *mockCtx) BindMergePatch(any, ...ctx.PatchOptions) error             { return nil }
+func (m *mockCtx) RequireIfMatch(string) error                               { return nil }
+func (m *mockCtx) Trace(string, time.Duration, ...any)                       {}
+func (m *mockCtx) TraceEvents() []ctx.TraceEvent                             { return nil }
 func (m *mockCtx) BindMap(any, map[string]any, ...ctx.BindJSONOptions) error { return nil }
 func (m *mockCtx) BindForm(any, ...ctx.BindJSONOptions) error                { return nil }
 func (m *mockCtx) BindQuery(any, ...ctx.BindJSONOptions) error               { return nil }
 func (m *mockCtx) BindPath(any, ...ctx.BindJSONOptions) error                { return nil }
 func (m *mockCtx) BindAny(any, ...ctx.BindJSONOptions) error                 { return nil }
+func (m *mockCtx) JSONDecoder() *json.Decoder                                { return nil }
 func (m *mockCtx) Get(any, ...any) any                                       { return nil }
 func (m *mockCtx) Set(any, any) flash.Ctx                                    { return m }
 func (m *mockCtx) Clone() flash.Ctx                                          { return m }
+func (m *mockCtx) Detach() *ctx.Detached                                     { return nil }
+func (m *mockCtx) AssetPath(name string) string                              { return name }
+func (m *mockCtx) Feed(ctx.Feed) error                                       { return nil }
+func (m *mockCtx) LongPoll(context.Context, time.Duration, ctx.PollFunc, ...ctx.LongPollOptions) error {
+	return nil
+}
+func (m *mockCtx) Redirect(int, string) error                                         { return nil }
+func (m *mockCtx) RedirectBack(string) error                                          { return nil }
+func (m *mockCtx) RedirectWithQuery(int, string) error                                { return nil }
+func (m *mockCtx) RedirectToRoute(string, ...any) error                               { return nil }
+func (m *mockCtx) TempFile(string) (*ctx.TempFile, error)                             { return nil, nil }
+func (m *mockCtx) TempDir() (string, error)                                           { return "", nil }
+func (m *mockCtx) Flash(string, string) error                                         { return nil }
+func (m *mockCtx) Flashes() []ctx.FlashMessage                                        { return nil }
+func (m *mockCtx) BypassMiddleware(...string)                                         {}
+func (m *mockCtx) Bypassed(string) bool                                               { return false }
+func (m *mockCtx) RouteMeta(string) (any, bool)                                       { return nil, false }
+func (m *mockCtx) OnCommit(func(context.Context))                                     {}
+func (m *mockCtx) ValidateUploads(ctx.UploadPolicy) error                             { return nil }
+func (m *mockCtx) SaveUploadedFile(*multipart.FileHeader, string) error               { return nil }
+func (m *mockCtx) SaveUploadedFileFS(*multipart.FileHeader, storage.FS, string) error { return nil }
+func (m *mockCtx) FileFS(fs.FS, string) error                                         { return nil }
+func (m *mockCtx) AfterResponse(func(context.Context, int))                           {}
+func (m *mockCtx) CacheControl(ctx.CacheDirectives) flash.Ctx                         { return m }
+func (m *mockCtx) NoCache() flash.Ctx                                                 { return m }
 
 func TestCleanupFunctions(t *testing.T) {
 	// Test cleanup functions by creating strategies with very short intervals
@@ -1738,3 +1788,89 @@ func TestRateLimitCleanupEdgeCases(t *testing.T) {
 		tb.Allow(fmt.Sprintf("post_cleanup_%d", i))
 	}
 }
+
+func TestRateLimitAppliesCleanupIntervalToStrategy(t *testing.T) {
+	tb := NewTokenBucketStrategy(1, time.Minute)
+	defer tb.Close()
+	if got := tb.cleanupTask.Interval(); got != 5*time.Minute {
+		t.Fatalf("expected default interval, got %v", got)
+	}
+	_ = RateLimit(WithStrategy(tb), WithCleanupInterval(time.Minute))
+	if got := tb.cleanupTask.Interval(); got != time.Minute {
+		t.Fatalf("expected configured interval, got %v", got)
+	}
+
+	before := DefaultJanitor.Len()
+	_ = RateLimit(WithStrategy(tb), WithCleanupInterval(-1))
+	if got := DefaultJanitor.Len(); got != before-1 {
+		t.Fatalf("expected cleanup to be disabled, janitor tasks %d -> %d", before, got)
+	}
+}
+
+func TestStrategiesMaxKeys(t *testing.T) {
+	tb := NewTokenBucketStrategy(1, time.Minute)
+	fw := NewFixedWindowStrategy(1, time.Minute)
+	sw := NewSlidingWindowStrategy(1, time.Minute)
+	lb := NewLeakyBucketStrategy(1, 1)
+	as := NewAdaptiveStrategy(1, 0.1, 10, time.Minute)
+	strategies := []RateLimitStrategy{tb, fw, sw, lb, as}
+	count := func(s RateLimitStrategy) int {
+		switch v := s.(type) {
+		case *TokenBucketStrategy:
+			v.mu.RLock()
+			defer v.mu.RUnlock()
+			return len(v.buckets)
+		case *FixedWindowStrategy:
+			v.mu.RLock()
+			defer v.mu.RUnlock()
+			return len(v.windows)
+		case *SlidingWindowStrategy:
+			v.mu.RLock()
+			defer v.mu.RUnlock()
+			return len(v.windows)
+		case *LeakyBucketStrategy:
+			v.mu.RLock()
+			defer v.mu.RUnlock()
+			return len(v.buckets)
+		case *AdaptiveStrategy:
+			v.mu.RLock()
+			defer v.mu.RUnlock()
+			return len(v.clients)
+		}
+		return -1
+	}
+	evictions := flash.DefaultMetrics.Snapshot().RateLimitEvictions
+	for _, s := range strategies {
+		_ = RateLimit(WithStrategy(s), WithMaxKeys(10))
+		for i := 0; i < 100; i++ {
+			s.Allow(fmt.Sprintf("flood-%d", i))
+		}
+		if n := count(s); n != 10 {
+			t.Fatalf("%s: expected 10 tracked keys, got %d", s.Name(), n)
+		}
+		// Existing keys keep being limited without evictions.
+		s.Allow("flood-99")
+		if n := count(s); n != 10 {
+			t.Fatalf("%s: expected 10 tracked keys after reuse, got %d", s.Name(), n)
+		}
+		s.(interface{ Close() }).Close()
+	}
+	if got := flash.DefaultMetrics.Snapshot().RateLimitEvictions - evictions; got < 90*uint64(len(strategies)) {
+		t.Fatalf("expected evictions to be counted, got %d", got)
+	}
+}
+
+func TestRateLimitMaxKeysUnlimitedByDefault(t *testing.T) {
+	tb := NewTokenBucketStrategy(1, time.Minute)
+	defer tb.Close()
+	_ = RateLimit(WithStrategy(tb))
+	for i := 0; i < 50; i++ {
+		tb.Allow(fmt.Sprintf("k%d", i))
+	}
+	tb.mu.RLock()
+	n := len(tb.buckets)
+	tb.mu.RUnlock()
+	if n != 50 {
+		t.Fatalf("expected 50 keys, got %d", n)
+	}
+}
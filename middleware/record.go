@@ -0,0 +1,272 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/goflash/flash/v2"
+	"github.com/goflash/flash/v2/ctx"
+)
+
+// RecordMode selects what the Recorder middleware does.
+type RecordMode int
+
+const (
+	// RecordModeOff passes requests through untouched.
+	RecordModeOff RecordMode = iota
+	// RecordModeRecord runs the handlers and writes every request/response
+	// pair to a golden file, overwriting earlier recordings.
+	RecordModeRecord
+	// RecordModeReplay serves recorded responses without running the
+	// handlers. Requests without a recording get 501 Not Implemented.
+	RecordModeReplay
+	// RecordModeAuto replays existing recordings and records the rest.
+	RecordModeAuto
+)
+
+// RecordEnv is the environment variable that overrides RecorderConfig.Mode
+// with "record", "replay", "auto" or "off", so a test suite can re-record its
+// golden files with e.g. FLASH_RECORD=record go test ./...
+const RecordEnv = "FLASH_RECORD"
+
+// RecordedHeader is set on replayed responses.
+const RecordedHeader = "X-Flash-Replayed"
+
+// RecorderConfig configures the Recorder middleware.
+type RecorderConfig struct {
+	// Dir holds the golden files, one JSON file per recorded request.
+	// Required.
+	Dir string
+
+	// Mode selects recording or replay. RecordEnv overrides it when set.
+	Mode RecordMode
+
+	// MatchHeaders lists request headers that are part of the match key, in
+	// addition to the method, path, query (order-insensitive) and body.
+	MatchHeaders []string
+
+	// Redactor masks credentials in the recorded request (headers, query and
+	// JSON body) and response headers, so golden files can be committed.
+	// Masked response headers are not replayed. Defaults to
+	// NewRedactor(DefaultRedactionRules()).
+	Redactor *Redactor
+
+	// MaxBodySize caps recorded request and response bodies; larger
+	// exchanges are passed through and not recorded. Defaults to 1 MiB.
+	MaxBodySize int64
+}
+
+// Recording is the golden file format: one request/response pair.
+type Recording struct {
+	Request  RecordedMessage `json:"request"`
+	Response RecordedMessage `json:"response"`
+}
+
+// RecordedMessage is a recorded request or response. Bodies that are not
+// valid UTF-8 are stored base64-encoded in BodyBase64.
+type RecordedMessage struct {
+	Method     string      `json:"method,omitempty"`
+	URL        string      `json:"url,omitempty"`
+	Status     int         `json:"status,omitempty"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	BodyBase64 string      `json:"body_base64,omitempty"`
+}
+
+func (m *RecordedMessage) setBody(b []byte) {
+	if utf8.Valid(b) {
+		m.Body = string(b)
+	} else {
+		m.BodyBase64 = base64.StdEncoding.EncodeToString(b)
+	}
+}
+
+// BodyBytes returns the message body.
+func (m RecordedMessage) BodyBytes() []byte {
+	if m.BodyBase64 != "" {
+		b, _ := base64.StdEncoding.DecodeString(m.BodyBase64)
+		return b
+	}
+	return []byte(m.Body)
+}
+
+// Recorder returns middleware that records request/response pairs to golden
+// files and replays them, for deterministic integration and contract tests:
+// record once against the real backends, commit the files, and test clients
+// against a replaying flash service without the backends. It panics if Dir is
+// empty.
+//
+// Requests are matched by method, path, query parameters (in any order), the
+// MatchHeaders and a hash of the body. File names are derived from the same
+// key, so re-recording only changes the files of requests that changed.
+//
+// Example:
+//
+//	rec := middleware.Recorder(middleware.RecorderConfig{
+//		Dir:  "testdata/golden",
+//		Mode: middleware.RecordModeReplay, // FLASH_RECORD=record to refresh
+//	})
+//	app.Use(rec)
+func Recorder(cfg RecorderConfig) flash.Middleware {
+	if cfg.Dir == "" {
+		panic("middleware: Recorder requires Dir")
+	}
+	switch os.Getenv(RecordEnv) {
+	case "off":
+		cfg.Mode = RecordModeOff
+	case "record":
+		cfg.Mode = RecordModeRecord
+	case "replay":
+		cfg.Mode = RecordModeReplay
+	case "auto":
+		cfg.Mode = RecordModeAuto
+	}
+	if cfg.Redactor == nil {
+		cfg.Redactor = NewRedactor(DefaultRedactionRules())
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = 1 << 20
+	}
+
+	return flash.Describe("recorder", cfg, func(next flash.Handler) flash.Handler {
+		if cfg.Mode == RecordModeOff {
+			return next
+		}
+		return func(c flash.Ctx) error {
+			r := c.Request()
+			body, ok := bufferRequestBody(r, cfg.MaxBodySize)
+			if !ok {
+				if cfg.Mode == RecordModeReplay {
+					return c.String(http.StatusRequestEntityTooLarge, "request body too large to replay")
+				}
+				return next(c)
+			}
+			path := filepath.Join(cfg.Dir, recordingName(r, body, cfg.MatchHeaders))
+
+			if cfg.Mode != RecordModeRecord {
+				rec, err := readRecording(path)
+				switch {
+				case err == nil:
+					return replayRecording(c, rec, cfg.Redactor.mask)
+				case !errors.Is(err, fs.ErrNotExist):
+					return err
+				case cfg.Mode == RecordModeReplay:
+					return c.String(http.StatusNotImplemented, "no recording for "+r.Method+" "+r.URL.RequestURI())
+				}
+			}
+
+			w := &captureWriter{ResponseWriter: c.ResponseWriter(), limit: cfg.MaxBodySize}
+			c.SetResponseWriter(w)
+			err := next(c)
+			c.SetResponseWriter(w.ResponseWriter)
+			if err != nil || w.truncated {
+				return err // error responses are rendered later; not recorded
+			}
+			red := cfg.Redactor
+			u := *r.URL
+			u.RawQuery = red.Query(u.RawQuery)
+			rec := Recording{
+				Request:  RecordedMessage{Method: r.Method, URL: u.RequestURI(), Header: red.Header(r.Header)},
+				Response: RecordedMessage{Status: c.StatusCode(), Header: red.Header(w.Header())},
+			}
+			if rec.Response.Status == 0 {
+				rec.Response.Status = http.StatusOK
+			}
+			rec.Response.Header.Del("Date")
+			if strings.Contains(r.Header.Get("Content-Type"), "json") {
+				rec.Request.setBody(red.JSON(body))
+			} else {
+				rec.Request.setBody(body)
+			}
+			rec.Response.setBody(w.buf.Bytes())
+			if werr := writeRecording(path, rec); werr != nil {
+				ctx.LoggerFromContext(c.Context()).Warn("recorder: write failed", "path", path, "error", werr)
+			}
+			return nil
+		}
+	})
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// recordingName derives the golden file name for a request: a readable
+// prefix from the method and path plus a hash of the full match key.
+func recordingName(r *http.Request, body []byte, headers []string) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + "\n" + r.URL.Path + "\n"))
+	q := r.URL.Query()
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		vs := append([]string(nil), q[k]...)
+		sort.Strings(vs)
+		h.Write([]byte(k + "=" + strings.Join(vs, ",") + "\n"))
+	}
+	for _, name := range headers {
+		h.Write([]byte(http.CanonicalHeaderKey(name) + ":" + strings.Join(r.Header.Values(name), ",") + "\n"))
+	}
+	bh := sha256.Sum256(body)
+	h.Write(bh[:])
+
+	slug := strings.Trim(unsafeNameChars.ReplaceAllString(r.URL.Path, "_"), "_")
+	if len(slug) > 80 {
+		slug = slug[:80]
+	}
+	return r.Method + "_" + slug + "_" + hex.EncodeToString(h.Sum(nil)[:6]) + ".json"
+}
+
+func readRecording(path string) (Recording, error) {
+	var rec Recording
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return rec, err
+	}
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return rec, errors.New("middleware: invalid recording " + path + ": " + err.Error())
+	}
+	return rec, nil
+}
+
+func writeRecording(path string, rec Recording) error {
+	b, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// replayRecording writes a recorded response. Masked headers are not
+// replayed.
+func replayRecording(c flash.Ctx, rec Recording, mask string) error {
+	h := c.ResponseWriter().Header()
+	for k, vs := range rec.Response.Header {
+		if len(vs) > 0 && vs[0] == mask {
+			continue
+		}
+		h[k] = vs
+	}
+	h.Set(RecordedHeader, "1")
+	status := rec.Response.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	_, err := c.Send(status, h.Get("Content-Type"), rec.Response.BodyBytes())
+	return err
+}
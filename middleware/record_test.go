@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goflash/flash/v2"
+)
+
+func recorderApp(cfg RecorderConfig, calls *int) flash.App {
+	a := flash.New()
+	a.Use(Recorder(cfg))
+	a.GET("/orders/:id", func(c flash.Ctx) error {
+		*calls++
+		http.SetCookie(c.ResponseWriter(), &http.Cookie{Name: "sid", Value: "secret"})
+		c.Header("X-Order", c.Param("id"))
+		return c.JSON(map[string]string{"id": c.Param("id"), "lang": c.Request().Header.Get("Accept-Language")})
+	})
+	a.POST("/blob", func(c flash.Ctx) error {
+		*calls++
+		_, err := c.Send(http.StatusCreated, "application/octet-stream", []byte{0xff, 0x00, 0xfe})
+		return err
+	})
+	a.GET("/big", func(c flash.Ctx) error {
+		*calls++
+		return c.String(http.StatusOK, strings.Repeat("x", 64))
+	})
+	return a
+}
+
+func recorderDo(a flash.App, method, target, body string, hdr map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for k, v := range hdr {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRecorderRecordThenReplay(t *testing.T) {
+	t.Setenv(RecordEnv, "")
+	dir := t.TempDir()
+	var calls int
+	rec := recorderApp(RecorderConfig{Dir: dir, Mode: RecordModeRecord, MatchHeaders: []string{"Accept-Language"}}, &calls)
+	hdr := map[string]string{"Authorization": "Bearer s3cret", "Accept-Language": "de"}
+	first := recorderDo(rec, http.MethodGet, "/orders/7?b=2&a=1&token=abc", "", hdr)
+	recorderDo(rec, http.MethodPost, "/blob", "payload", nil)
+	if calls != 2 {
+		t.Fatalf("record mode must run handlers, calls=%d", calls)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 2 {
+		t.Fatalf("expected 2 golden files, got %v", files)
+	}
+	for _, f := range files {
+		b, _ := os.ReadFile(f)
+		if strings.Contains(string(b), "s3cret") || strings.Contains(string(b), "token=abc") || strings.Contains(string(b), "sid=secret") {
+			t.Fatalf("credentials leaked into %s:\n%s", f, b)
+		}
+	}
+
+	calls = 0
+	replay := recorderApp(RecorderConfig{Dir: dir, Mode: RecordModeReplay, MatchHeaders: []string{"Accept-Language"}}, &calls)
+	got := recorderDo(replay, http.MethodGet, "/orders/7?a=1&b=2&token=abc", "", hdr)
+	if calls != 0 || got.Code != first.Code || got.Body.String() != first.Body.String() {
+		t.Fatalf("replay: calls=%d %d %q", calls, got.Code, got.Body.String())
+	}
+	if got.Header().Get("X-Order") != "7" || got.Header().Get(RecordedHeader) != "1" || got.Header().Get("Set-Cookie") != "" {
+		t.Fatalf("replayed headers %v", got.Header())
+	}
+	blob := recorderDo(replay, http.MethodPost, "/blob", "payload", nil)
+	if blob.Code != http.StatusCreated || blob.Body.String() != "\xff\x00\xfe" {
+		t.Fatalf("binary replay %d %q", blob.Code, blob.Body.String())
+	}
+
+	for _, miss := range []*httptest.ResponseRecorder{
+		recorderDo(replay, http.MethodGet, "/orders/7?a=1&b=2&token=abc", "", map[string]string{"Accept-Language": "fr"}),
+		recorderDo(replay, http.MethodPost, "/blob", "other", nil),
+	} {
+		if miss.Code != http.StatusNotImplemented {
+			t.Fatalf("unmatched request should get 501, got %d", miss.Code)
+		}
+	}
+}
+
+func TestRecorderAutoAndLimits(t *testing.T) {
+	dir := t.TempDir()
+	var calls int
+	t.Setenv(RecordEnv, "auto")
+	a := recorderApp(RecorderConfig{Dir: dir, Mode: RecordModeReplay, MaxBodySize: 32}, &calls)
+	recorderDo(a, http.MethodGet, "/orders/1", "", nil)
+	recorderDo(a, http.MethodGet, "/orders/1", "", nil)
+	if calls != 1 {
+		t.Fatalf("auto mode should record once then replay, calls=%d", calls)
+	}
+	if rec := recorderDo(a, http.MethodGet, "/big", "", nil); rec.Body.Len() != 64 {
+		t.Fatalf("large response must pass through, got %d bytes", rec.Body.Len())
+	}
+	recorderDo(a, http.MethodGet, "/big", "", nil)
+	if calls != 3 {
+		t.Fatalf("responses over MaxBodySize must not be recorded, calls=%d", calls)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "GET_orders_1_*.json"))
+	if len(files) != 1 {
+		t.Fatalf("files %v", files)
+	}
+	b, _ := os.ReadFile(files[0])
+	var r Recording
+	if err := json.Unmarshal(b, &r); err != nil || r.Request.URL != "/orders/1" || r.Response.Status != http.StatusOK {
+		t.Fatalf("recording %+v %v", r, err)
+	}
+
+	t.Setenv(RecordEnv, "off")
+	calls = 0
+	off := recorderApp(RecorderConfig{Dir: dir, Mode: RecordModeReplay}, &calls)
+	if recorderDo(off, http.MethodGet, "/orders/1", "", nil); calls != 1 {
+		t.Fatalf("off mode must run handlers")
+	}
+}
+
+func TestRecordingNameIgnoresQueryOrder(t *testing.T) {
+	a := recordingName(httptest.NewRequest(http.MethodGet, "/x?a=1&b=2&a=0", nil), nil, nil)
+	b := recordingName(httptest.NewRequest(http.MethodGet, "/x?b=2&a=0&a=1", nil), nil, nil)
+	c := recordingName(httptest.NewRequest(http.MethodGet, "/x?b=3&a=0&a=1", nil), nil, nil)
+	if a != b || a == c || !strings.HasPrefix(a, "GET_x_") {
+		t.Fatalf("names %q %q %q", a, b, c)
+	}
+}
@@ -1,16 +1,22 @@
 package middleware
 
 import (
+	"fmt"
+	"log/slog"
 	"net/http"
+	"runtime/debug"
 
 	"github.com/goflash/flash/v2"
+	"github.com/goflash/flash/v2/ctx"
 )
 
 // RecoverConfig configures the panic recovery middleware.
 //
-// EnableStack controls whether stack traces are logged (disabled in production for security).
+// EnableStack controls whether the stack trace is captured into PanicError.Stack
+// (disabled by default; never send it to clients).
 // OnPanic is called when a panic occurs, useful for custom logging or alerting.
-// ErrorResponse allows customizing the error response sent to clients.
+// ErrorResponse allows customizing the error response sent to clients. When nil,
+// the panic is returned as a *flash.PanicError and handled by the app's error handler.
 //
 // Security considerations:
 //   - Never expose stack traces to clients in production
@@ -40,9 +46,15 @@ import (
 //	}
 //	app.Use(middleware.Recover(cfg))
 type RecoverConfig struct {
-	EnableStack   bool                               // whether to log stack traces (disable in production)
+	EnableStack   bool                               // whether to capture stack traces into PanicError.Stack
 	OnPanic       func(flash.Ctx, interface{})       // optional callback when panic occurs
 	ErrorResponse func(flash.Ctx, interface{}) error // optional custom error response
+
+	// Dedup, when set, logs each panic at error level ("panic recovered")
+	// through the request logger, collapsing repeats of the same panic value
+	// on the same route into one line per window with a "repeated" count.
+	// OnPanic is still called for every panic.
+	Dedup *LogDeduper
 }
 
 // Recover returns middleware that recovers from panics in HTTP handlers with enhanced security and logging.
@@ -56,8 +68,13 @@ type RecoverConfig struct {
 // This middleware is essential for production applications as it prevents panics from crashing the entire server.
 // When a panic occurs in any handler, the middleware catches it and returns a generic HTTP 500 error response
 // to the client while allowing the server to continue processing other requests.
+// Panics with http.ErrAbortHandler, which deliberately abort the response, are
+// re-panicked so net/http closes the connection as documented.
 //
-// The middleware uses Go's built-in recover() mechanism to catch panics and converts them to HTTP errors.
+// The middleware uses Go's built-in recover() mechanism to catch panics and converts them into a
+// *flash.PanicError{Value, Stack} returned down the chain, so the app's error handler (SetErrorHandler
+// or SetErrorHandlerV2) renders, reports and classifies panics consistently with other errors. The
+// default error handler responds with a generic 500.
 // It's recommended to use this middleware early in the middleware chain, typically as one of the first
 // middleware applied to your application.
 //
@@ -93,14 +110,18 @@ type RecoverConfig struct {
 //
 //	// Development configuration (with stack traces)
 //	if os.Getenv("ENV") == "development" {
-//		app.Use(middleware.Recover(middleware.RecoverConfig{
-//			EnableStack: true,
-//			OnPanic: func(c flash.Ctx, err interface{}) {
-//				log.Printf("PANIC: %v\nStack: %s", err, debug.Stack())
-//			},
-//		}))
+//		app.Use(middleware.Recover(middleware.RecoverConfig{EnableStack: true}))
 //	}
 //
+//	// Centralized handling of panics alongside other errors
+//	app.SetErrorHandler(func(c flash.Ctx, err error) {
+//		var pe *flash.PanicError
+//		if errors.As(err, &pe) {
+//			log.Printf("PANIC: %v\nStack: %s", pe.Value, pe.Stack)
+//		}
+//		_ = c.String(http.StatusInternalServerError, "internal error")
+//	})
+//
 //	// With other essential middleware
 //	app.Use(
 //	    middleware.RequestID(),
@@ -201,10 +222,15 @@ func Recover(cfgs ...RecoverConfig) flash.Middleware {
 		cfg = cfgs[0]
 	}
 
-	return func(next flash.Handler) flash.Handler {
+	return flash.Describe("recover", cfg, func(next flash.Handler) flash.Handler {
 		return func(c flash.Ctx) (err error) {
 			defer func() {
 				if r := recover(); r != nil {
+					if r == http.ErrAbortHandler {
+						panic(r) // deliberate abort: let net/http drop the connection
+					}
+					flash.DefaultMetrics.PanicRecovered()
+
 					// Execute panic callback if provided
 					if cfg.OnPanic != nil {
 						// Execute in a separate goroutine to prevent blocking
@@ -215,18 +241,28 @@ func Recover(cfgs ...RecoverConfig) flash.Middleware {
 						}()
 					}
 
+					if cfg.Dedup != nil {
+						msg := fmt.Sprint(r)
+						cfg.Dedup.Log(c.Context(), ctx.LoggerFromContext(c.Context()), slog.LevelError,
+							"panic "+c.Method()+" "+c.Route()+" "+msg, "panic recovered",
+							"panic", msg, "method", c.Method(), "route", c.Route())
+					}
+
 					// Use custom error response if provided
 					if cfg.ErrorResponse != nil {
 						err = cfg.ErrorResponse(c, r)
 						return
 					}
 
-					// Default secure error response
-					c.Header("X-Content-Type-Options", "nosniff") // Prevent MIME sniffing
-					_ = c.String(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+					// Hand the panic to the app's error handler as a typed error
+					pe := &flash.PanicError{Value: r}
+					if cfg.EnableStack {
+						pe.Stack = debug.Stack()
+					}
+					err = pe
 				}
 			}()
 			return next(c)
 		}
-	}
+	})
 }
@@ -1,8 +1,10 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"runtime"
 	"sync"
 	"testing"
 	"time"
@@ -14,12 +16,16 @@ func TestRecoverMiddleware(t *testing.T) {
 	a := flash.New()
 	a.Use(Recover())
 	a.GET("/panic", func(c flash.Ctx) error { panic("boom") })
+	before := flash.DefaultMetrics.Snapshot().PanicsRecovered
 	rec := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
 	a.ServeHTTP(rec, req)
 	if rec.Code != http.StatusInternalServerError {
 		t.Fatalf("expected 500, got %d", rec.Code)
 	}
+	if flash.DefaultMetrics.Snapshot().PanicsRecovered == before {
+		t.Fatalf("expected the panic to be counted")
+	}
 }
 
 func TestRecoverMiddlewareWithCustomErrorResponse(t *testing.T) {
@@ -134,3 +140,57 @@ func TestRecoverMiddlewareNoPanic(t *testing.T) {
 		t.Fatalf("expected 'normal response', got %q", rec.Body.String())
 	}
 }
+
+func TestRecoverMiddlewareReturnsPanicError(t *testing.T) {
+	a := flash.New()
+	var got *flash.PanicError
+	a.SetErrorHandler(func(c flash.Ctx, err error) {
+		if !errors.As(err, &got) {
+			t.Errorf("expected *PanicError, got %T", err)
+		}
+		_ = c.String(http.StatusServiceUnavailable, "handled")
+	})
+	a.Use(Recover(RecoverConfig{EnableStack: true}))
+	a.GET("/panic", func(c flash.Ctx) error { panic("boom") })
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != "handled" {
+		t.Fatalf("error handler not used: %d %q", rec.Code, rec.Body.String())
+	}
+	if got == nil || got.Value != "boom" || len(got.Stack) == 0 {
+		t.Fatalf("unexpected panic error: %+v", got)
+	}
+}
+
+func TestRecoverMiddlewareDefaultOmitsStack(t *testing.T) {
+	a := flash.New()
+	var got *flash.PanicError
+	a.SetErrorHandler(func(c flash.Ctx, err error) { errors.As(err, &got) })
+	a.Use(Recover())
+	a.GET("/panic", func(c flash.Ctx) error {
+		var m map[string]int
+		m["x"] = 1 // runtime error
+		return nil
+	})
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if got == nil || got.Stack != nil {
+		t.Fatalf("unexpected panic error: %+v", got)
+	}
+	var re runtime.Error
+	if !errors.As(got, &re) {
+		t.Fatalf("expected runtime.Error to be unwrappable, got %v", got.Value)
+	}
+}
+
+func TestRecoverMiddlewareDefaultResponse(t *testing.T) {
+	a := flash.New()
+	a.Use(Recover())
+	a.GET("/panic", func(c flash.Ctx) error { panic("secret detail") })
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if rec.Code != http.StatusInternalServerError || rec.Body.String() != "Internal Server Error" ||
+		rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatalf("unexpected response: %d %q %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+}
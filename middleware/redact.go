@@ -0,0 +1,215 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// RedactionRules declares which request data must be masked before it is
+// logged. Header names and query keys match case-insensitively.
+//
+// BodyPaths are dot-separated JSON paths into request bodies ("user.ssn",
+// "items.card" also matches inside arrays). A single-segment path such as
+// "password" matches that key at any depth.
+//
+// Patterns mask matching substrings inside any logged string value, which is
+// useful for secrets that may appear in free-form fields (e.g., card numbers).
+//
+// Example:
+//
+//	rules := middleware.DefaultRedactionRules()
+//	rules.Headers = append(rules.Headers, "X-Internal-Token")
+//	rules.BodyPaths = append(rules.BodyPaths, "payment.card.number")
+//	rules.Patterns = append(rules.Patterns, regexp.MustCompile(`\b\d{13,16}\b`))
+//	app.Use(middleware.Logger(
+//		middleware.WithRedactor(middleware.NewRedactor(rules)),
+//		middleware.WithLogHeaders("Authorization", "User-Agent"),
+//		middleware.WithLogQuery(),
+//	))
+type RedactionRules struct {
+	Headers     []string         // header names, e.g. "Authorization"
+	QueryParams []string         // query keys, e.g. "token"
+	BodyPaths   []string         // JSON paths, e.g. "password" or "user.ssn"
+	Patterns    []*regexp.Regexp // value patterns masked anywhere
+	Mask        string           // replacement text; defaults to "[REDACTED]"
+}
+
+// DefaultRedactionRules returns the built-in rules covering common credentials
+// and personal data. The result is a fresh copy that callers may extend.
+func DefaultRedactionRules() RedactionRules {
+	return RedactionRules{
+		Headers: []string{
+			"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie",
+			"X-Api-Key", "X-Auth-Token", "X-CSRF-Token",
+		},
+		QueryParams: []string{
+			"token", "access_token", "refresh_token", "id_token", "api_key", "apikey",
+			"key", "password", "secret", "signature", "sig",
+		},
+		BodyPaths: []string{
+			"password", "new_password", "old_password", "secret", "token",
+			"access_token", "refresh_token", "api_key", "ssn", "credit_card", "card_number", "cvv",
+		},
+	}
+}
+
+// Redactor applies RedactionRules. It is immutable after construction and safe
+// for concurrent use.
+type Redactor struct {
+	headers  map[string]struct{}
+	query    map[string]struct{}
+	anyDepth map[string]struct{}
+	paths    [][]string
+	patterns []*regexp.Regexp
+	mask     string
+}
+
+// NewRedactor compiles rules into a Redactor.
+func NewRedactor(rules RedactionRules) *Redactor {
+	r := &Redactor{
+		headers:  make(map[string]struct{}, len(rules.Headers)),
+		query:    make(map[string]struct{}, len(rules.QueryParams)),
+		anyDepth: make(map[string]struct{}),
+		patterns: rules.Patterns,
+		mask:     rules.Mask,
+	}
+	if r.mask == "" {
+		r.mask = "[REDACTED]"
+	}
+	for _, h := range rules.Headers {
+		r.headers[http.CanonicalHeaderKey(h)] = struct{}{}
+	}
+	for _, q := range rules.QueryParams {
+		r.query[strings.ToLower(q)] = struct{}{}
+	}
+	for _, p := range rules.BodyPaths {
+		segs := strings.Split(strings.ToLower(p), ".")
+		if len(segs) == 1 {
+			r.anyDepth[segs[0]] = struct{}{}
+			continue
+		}
+		r.paths = append(r.paths, segs)
+	}
+	return r
+}
+
+// Value masks substrings of s matching the configured patterns.
+func (r *Redactor) Value(s string) string {
+	for _, p := range r.patterns {
+		s = p.ReplaceAllString(s, r.mask)
+	}
+	return s
+}
+
+// HeaderValue returns the value to log for header name.
+func (r *Redactor) HeaderValue(name, value string) string {
+	if _, ok := r.headers[http.CanonicalHeaderKey(name)]; ok {
+		return r.mask
+	}
+	return r.Value(value)
+}
+
+// Header returns a copy of h with sensitive header values masked.
+func (r *Redactor) Header(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for k, vs := range h {
+		masked := make([]string, len(vs))
+		for i, v := range vs {
+			masked[i] = r.HeaderValue(k, v)
+		}
+		out[k] = masked
+	}
+	return out
+}
+
+// Query returns rawQuery with sensitive parameter values masked. Parameter order
+// is preserved; unparsable segments are kept as-is after pattern masking.
+func (r *Redactor) Query(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	parts := strings.Split(rawQuery, "&")
+	for i, part := range parts {
+		k, _, hasValue := strings.Cut(part, "=")
+		key, err := url.QueryUnescape(k)
+		if err != nil {
+			key = k
+		}
+		if _, ok := r.query[strings.ToLower(key)]; ok && hasValue {
+			parts[i] = k + "=" + url.QueryEscape(r.mask)
+			continue
+		}
+		parts[i] = r.Value(part)
+	}
+	return strings.Join(parts, "&")
+}
+
+// JSON returns body with values at sensitive paths masked. Bodies that are not
+// valid JSON are returned with pattern masking only.
+func (r *Redactor) JSON(body []byte) []byte {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return []byte(r.Value(string(body)))
+	}
+	v = r.redactJSON(v, nil)
+	out, err := json.Marshal(v)
+	if err != nil {
+		return []byte(r.mask)
+	}
+	return out
+}
+
+// redactJSON walks v, masking keys that match any-depth names or full paths.
+func (r *Redactor) redactJSON(v any, path []string) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			lk := strings.ToLower(k)
+			p := append(path[:len(path):len(path)], lk)
+			if r.matchPath(lk, p) {
+				t[k] = r.mask
+				continue
+			}
+			t[k] = r.redactJSON(child, p)
+		}
+		return t
+	case []any:
+		for i, child := range t {
+			t[i] = r.redactJSON(child, path)
+		}
+		return t
+	case string:
+		return r.Value(t)
+	default:
+		return v
+	}
+}
+
+// matchPath reports whether key (at full path p) is sensitive.
+func (r *Redactor) matchPath(key string, p []string) bool {
+	if _, ok := r.anyDepth[key]; ok {
+		return true
+	}
+	for _, rp := range r.paths {
+		if len(rp) != len(p) {
+			continue
+		}
+		match := true
+		for i := range rp {
+			if rp[i] != p[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRedactor_HeaderAndQuery(t *testing.T) {
+	r := NewRedactor(DefaultRedactionRules())
+	h := http.Header{}
+	h.Set("Authorization", "Bearer abc")
+	h.Add("Cookie", "sid=1")
+	h.Set("Accept", "text/html")
+	got := r.Header(h)
+	if got.Get("Authorization") != "[REDACTED]" || got.Get("Cookie") != "[REDACTED]" || got.Get("Accept") != "text/html" {
+		t.Fatalf("unexpected headers: %v", got)
+	}
+	if h.Get("Authorization") != "Bearer abc" {
+		t.Fatalf("input header modified")
+	}
+	if v := r.HeaderValue("authorization", "x"); v != "[REDACTED]" {
+		t.Fatalf("header match should be case-insensitive: %q", v)
+	}
+
+	q := r.Query("page=2&TOKEN=abc&access_token=x%20y&flag")
+	if q != "page=2&TOKEN=%5BREDACTED%5D&access_token=%5BREDACTED%5D&flag" {
+		t.Fatalf("unexpected query: %s", q)
+	}
+	if r.Query("") != "" {
+		t.Fatalf("empty query should stay empty")
+	}
+}
+
+func TestRedactor_JSONPaths(t *testing.T) {
+	rules := DefaultRedactionRules()
+	rules.BodyPaths = append(rules.BodyPaths, "profile.dob")
+	rules.Mask = "***"
+	r := NewRedactor(rules)
+	in := `{"user":"bob","Password":"p","profile":{"dob":"1990","ssn":"123","name":"B"},` +
+		`"items":[{"token":"t","id":1.50}],"dob":"keep"}`
+	var got map[string]any
+	if err := json.Unmarshal(r.JSON([]byte(in)), &got); err != nil {
+		t.Fatal(err)
+	}
+	prof := got["profile"].(map[string]any)
+	item := got["items"].([]any)[0].(map[string]any)
+	if got["Password"] != "***" || prof["dob"] != "***" || prof["ssn"] != "***" || item["token"] != "***" {
+		t.Fatalf("sensitive values not masked: %v", got)
+	}
+	if got["user"] != "bob" || prof["name"] != "B" || got["dob"] != "keep" || item["id"] != 1.5 {
+		t.Fatalf("non-sensitive values changed: %v", got)
+	}
+	if out := string(r.JSON([]byte("not json"))); out != "not json" {
+		t.Fatalf("invalid JSON should pass through: %s", out)
+	}
+}
+
+func TestRedactor_Patterns(t *testing.T) {
+	rules := RedactionRules{Patterns: []*regexp.Regexp{regexp.MustCompile(`\b\d{16}\b`)}}
+	r := NewRedactor(rules)
+	if got := r.Value("card 4111111111111111 ok"); got != "card [REDACTED] ok" {
+		t.Fatalf("Value = %q", got)
+	}
+	if got := r.Query("note=4111111111111111"); got != "note=[REDACTED]" {
+		t.Fatalf("Query = %q", got)
+	}
+	if got := string(r.JSON([]byte(`{"memo":"pay 4111111111111111"}`))); !strings.Contains(got, "pay [REDACTED]") {
+		t.Fatalf("JSON = %s", got)
+	}
+}
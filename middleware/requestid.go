@@ -23,7 +23,7 @@ func RequestID(cfgs ...RequestIDConfig) flash.Middleware {
 	if len(cfgs) > 0 && cfgs[0].Header != "" {
 		cfg.Header = cfgs[0].Header
 	}
-	return func(next flash.Handler) flash.Handler {
+	return flash.Describe("requestid", cfg, func(next flash.Handler) flash.Handler {
 		return func(c flash.Ctx) error {
 			id := c.Request().Header.Get(cfg.Header)
 			if id == "" {
@@ -35,7 +35,7 @@ func RequestID(cfgs ...RequestIDConfig) flash.Middleware {
 			c.SetRequest(r)
 			return next(c)
 		}
-	}
+	})
 }
 
 // RequestIDFromContext returns the request ID from the context, if available.
@@ -162,12 +162,12 @@ func RequestSize(cfg RequestSizeConfig) flash.Middleware {
 	if cfg.MaxSize <= 0 {
 		// Allow unlimited size if MaxSize is 0 or negative
 		// This is not recommended for production but may be useful for development
-		return func(next flash.Handler) flash.Handler {
+		return flash.Describe("requestsize", cfg, func(next flash.Handler) flash.Handler {
 			return next // No-op middleware
-		}
+		})
 	}
 
-	return func(next flash.Handler) flash.Handler {
+	return flash.Describe("requestsize", cfg, func(next flash.Handler) flash.Handler {
 		return func(c flash.Ctx) error {
 			// Check Content-Length header for efficiency
 			// Note: This won't catch chunked requests without Content-Length (-1),
@@ -192,5 +192,5 @@ func RequestSize(cfg RequestSizeConfig) flash.Middleware {
 			// Request size is within limits, continue processing
 			return next(c)
 		}
-	}
+	})
 }
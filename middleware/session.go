@@ -80,16 +80,22 @@ import (
 	"crypto/subtle"
 	"encoding/base64"
 	"errors"
+	"log/slog"
+	"net"
 	"net/http"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/goflash/flash/v2"
+	"github.com/goflash/flash/v2/ctx"
+	"github.com/goflash/flash/v2/kvcache"
 )
 
 type sessionContextKey struct{}
 
+// sessionFlashKey is the session value holding pending flash messages.
+const sessionFlashKey = "_flash"
+
 // Store abstracts session persistence for the session middleware.
 // Implementations must provide thread-safe Get, Save, and Delete operations for session data by ID.
 //
@@ -154,8 +160,9 @@ type Store interface {
 // Suitable for development, testing, and single-instance production deployments.
 //
 // Features:
-//   - Thread-safe operations with optimized read-write locking
-//   - Automatic cleanup of expired sessions via background goroutine
+//   - Thread-safe operations backed by the shared kvcache package
+//   - Optional session limit with least-recently-used eviction
+//   - Automatic cleanup of expired sessions via the shared janitor
 //   - Timing attack protection for session ID lookups
 //   - Memory-efficient storage with lazy expiration checking
 //   - Configurable cleanup intervals
@@ -179,17 +186,9 @@ type Store interface {
 //		TTL:   24 * time.Hour,
 //	}))
 type MemoryStore struct {
-	mu            sync.RWMutex
-	data          map[string]entry
-	cleanupTicker *time.Ticker
-	cleanupDone   chan struct{}
-	cleanupOnce   sync.Once
-}
-
-type entry struct {
-	v        map[string]any
-	exp      time.Time
-	accessed int64 // atomic timestamp for LRU-style cleanup
+	cache       *kvcache.Cache[string, map[string]any]
+	cleanupTask *JanitorTask
+	cleanupOnce sync.Once
 }
 
 // NewMemoryStore creates a new in-memory session store.
@@ -201,20 +200,29 @@ type entry struct {
 //	store.StartCleanup(10 * time.Minute) // Clean up every 10 minutes
 //	defer store.StopCleanup()
 func NewMemoryStore() *MemoryStore {
+	return NewMemoryStoreWithLimit(0)
+}
+
+// NewMemoryStoreWithLimit creates an in-memory session store holding at most
+// maxSessions sessions (0 means unlimited). When full, the least recently used
+// session is evicted, bounding memory under session-creation floods.
+//
+// Example:
+//
+//	store := middleware.NewMemoryStoreWithLimit(100_000)
+//	store.StartCleanup(5 * time.Minute)
+//	defer store.StopCleanup()
+func NewMemoryStoreWithLimit(maxSessions int) *MemoryStore {
 	return &MemoryStore{
-		data:        make(map[string]entry),
-		cleanupDone: make(chan struct{}),
+		cache: kvcache.New(kvcache.Options[string, map[string]any]{MaxEntries: maxSessions}),
 	}
 }
 
 // Get retrieves session data by ID with timing attack protection.
 // Returns a copy of the session data to prevent external modification.
 func (m *MemoryStore) Get(id string) (map[string]any, bool) {
-	now := time.Now()
-
-	m.mu.RLock()
-	e, ok := m.data[id]
-	m.mu.RUnlock()
+	// Expired sessions are removed lazily and reported as missing
+	v, ok := m.cache.Get(id)
 
 	// Use timing-safe comparison to prevent session enumeration attacks
 	if !ok {
@@ -223,18 +231,8 @@ func (m *MemoryStore) Get(id string) (map[string]any, bool) {
 		return nil, false
 	}
 
-	// Check expiration
-	if !e.exp.IsZero() && now.After(e.exp) {
-		// Lazy cleanup - remove expired session
-		_ = m.Delete(id)
-		return nil, false
-	}
-
-	// Update access time for LRU-style cleanup (atomic to avoid lock contention)
-	atomic.StoreInt64(&e.accessed, now.Unix())
-
 	// Return a copy to prevent external modification
-	return copyMapEfficient(e.v), true
+	return copyMapEfficient(v), true
 }
 
 // Save persists session data with the given ID and TTL.
@@ -243,37 +241,20 @@ func (m *MemoryStore) Save(id string, data map[string]any, ttl time.Duration) er
 	if id == "" {
 		return errors.New("session: empty session id")
 	}
-
-	now := time.Now()
-	var exp time.Time
-	if ttl > 0 {
-		exp = now.Add(ttl)
-	}
-
-	// Create entry with current access time
-	e := entry{
-		v:        copyMapEfficient(data),
-		exp:      exp,
-		accessed: now.Unix(),
-	}
-
-	m.mu.Lock()
-	m.data[id] = e
-	m.mu.Unlock()
+	m.cache.SetWithTTL(id, copyMapEfficient(data), ttl)
 	return nil
 }
 
 // Delete removes session data by ID.
 // Idempotent operation - no error if the ID doesn't exist.
 func (m *MemoryStore) Delete(id string) error {
-	m.mu.Lock()
-	delete(m.data, id)
-	m.mu.Unlock()
+	m.cache.Delete(id)
 	return nil
 }
 
-// StartCleanup starts a background goroutine that periodically removes expired sessions.
-// This prevents memory leaks in long-running applications.
+// StartCleanup registers the store with DefaultJanitor to periodically remove
+// expired sessions. This prevents memory leaks in long-running applications.
+// Only the first call has an effect.
 //
 // Example:
 //
@@ -286,67 +267,27 @@ func (m *MemoryStore) StartCleanup(interval time.Duration) {
 	}
 
 	m.cleanupOnce.Do(func() {
-		m.cleanupTicker = time.NewTicker(interval)
-		go m.cleanupLoop()
+		m.cleanupTask = DefaultJanitor.Schedule(interval, func(time.Time) { m.cleanupExpired() })
 	})
 }
 
-// StopCleanup stops the background cleanup goroutine.
-// Should be called when the store is no longer needed to prevent goroutine leaks.
+// StopCleanup stops periodic cleanup. It is safe to call more than once.
 func (m *MemoryStore) StopCleanup() {
-	if m.cleanupTicker != nil {
-		m.cleanupTicker.Stop()
-		close(m.cleanupDone)
-	}
-}
-
-// cleanupLoop runs in a background goroutine to remove expired sessions.
-func (m *MemoryStore) cleanupLoop() {
-	for {
-		select {
-		case <-m.cleanupTicker.C:
-			m.cleanupExpired()
-		case <-m.cleanupDone:
-			return
-		}
+	if m.cleanupTask != nil {
+		m.cleanupTask.Stop()
 	}
 }
 
 // cleanupExpired removes all expired sessions from the store.
-// This method is called periodically by the cleanup goroutine.
+// This method is called periodically by the janitor.
 func (m *MemoryStore) cleanupExpired() {
-	now := time.Now()
-	toDelete := make([]string, 0, 16) // Pre-allocate for efficiency
-
-	// First pass: collect expired session IDs (with read lock)
-	m.mu.RLock()
-	for id, e := range m.data {
-		if !e.exp.IsZero() && now.After(e.exp) {
-			toDelete = append(toDelete, id)
-		}
-	}
-	m.mu.RUnlock()
-
-	// Second pass: delete expired sessions (with write lock)
-	if len(toDelete) > 0 {
-		m.mu.Lock()
-		for _, id := range toDelete {
-			// Double-check expiration in case of concurrent updates
-			if e, exists := m.data[id]; exists && !e.exp.IsZero() && now.After(e.exp) {
-				delete(m.data, id)
-			}
-		}
-		m.mu.Unlock()
-	}
+	m.cache.DeleteExpired()
 }
 
 // Len returns the current number of sessions in the store.
 // Useful for monitoring and debugging.
 func (m *MemoryStore) Len() int {
-	m.mu.RLock()
-	count := len(m.data)
-	m.mu.RUnlock()
-	return count
+	return m.cache.Len()
 }
 
 // copyMap creates a shallow copy of a map (kept for backward compatibility).
@@ -591,11 +532,29 @@ type SessionConfig struct {
 	// Should be true in production environments.
 	Secure bool
 
-	// HTTPOnly sets the HttpOnly attribute of the session cookie.
-	// When true, cookie cannot be accessed via JavaScript.
-	// Recommended to be true for security (prevents XSS attacks).
+	// HTTPOnly is kept for compatibility: session cookies are always HttpOnly
+	// (not readable by JavaScript) unless DisableHTTPOnly is set.
 	HTTPOnly bool
 
+	// DisableHTTPOnly lets JavaScript read the session cookie. Not recommended:
+	// any XSS flaw can then steal sessions.
+	DisableHTTPOnly bool
+
+	// AutoSecure sets the Secure attribute per request: when the request came
+	// over TLS, or from one of TrustedProxies with "X-Forwarded-Proto: https".
+	// Use it when the same app serves HTTP in development and HTTPS behind a
+	// TLS-terminating proxy in production. Secure=true always wins.
+	AutoSecure bool
+
+	// TrustedProxies lists CIDRs whose X-Forwarded-Proto header is honored by
+	// AutoSecure.
+	TrustedProxies []string
+
+	// Partitioned sets the Partitioned attribute (CHIPS), storing the cookie
+	// per top-level site when the app is embedded cross-site. It requires
+	// Secure (or AutoSecure) and is usually combined with SameSite=None.
+	Partitioned bool
+
 	// SameSite sets the SameSite attribute of the session cookie.
 	// Controls when cookies are sent with cross-site requests.
 	// Options: http.SameSiteDefaultMode, http.SameSiteLaxMode, http.SameSiteStrictMode, http.SameSiteNoneMode
@@ -622,6 +581,8 @@ type SessionConfig struct {
 	// When true, calls session.Regenerate() when certain conditions are met.
 	// Helps prevent session fixation attacks.
 	RegenerateOnAuth bool
+
+	trustedNets []*net.IPNet // parsed TrustedProxies
 }
 
 func defaultSessionConfig() SessionConfig {
@@ -646,7 +607,8 @@ func defaultSessionConfig() SessionConfig {
 //   - Secure session ID generation with cryptographic randomness
 //   - Session regeneration to prevent fixation attacks
 //   - Flexible transport via cookies and/or headers
-//   - Configurable security attributes (Secure, HTTPOnly, SameSite)
+//   - Configurable security attributes (Secure/AutoSecure, HttpOnly, SameSite, Partitioned)
+//   - Configuration validation: invalid cookie settings panic, insecure ones are logged
 //   - Support for both web applications and APIs
 //   - Efficient change tracking to minimize storage operations
 //   - Integration with the flash.Ctx context system
@@ -753,8 +715,18 @@ func Sessions(cfg SessionConfig) flash.Middleware {
 	if cfg.SameSite == 0 {
 		cfg.SameSite = def.SameSite
 	}
+	warnings, err := cfg.Validate()
+	if err != nil {
+		panic("middleware: invalid session config: " + err.Error())
+	}
+	for _, w := range warnings {
+		slog.Default().Warn("insecure session config", "cookie", cfg.CookieName, "warning", w)
+	}
+	cfg.trustedNets = parseCIDRs(cfg.TrustedProxies)
 
-	return func(next flash.Handler) flash.Handler {
+	described := cfg
+	described.Store = nil // holds every session's data
+	return flash.Describe("sessions", described, func(next flash.Handler) flash.Handler {
 		return func(c flash.Ctx) error {
 			r := c.Request()
 			id := readSessionID(r, cfg)
@@ -771,9 +743,10 @@ func Sessions(cfg SessionConfig) flash.Middleware {
 				sess = Session{ID: "", Values: map[string]any{}, new: true}
 			}
 
-			// put into request context
-			ctx := context.WithValue(r.Context(), sessionContextKey{}, &sess)
-			r = r.WithContext(ctx)
+			// put into request context; flash messages live in the session too
+			rc := context.WithValue(r.Context(), sessionContextKey{}, &sess)
+			rc = ctx.ContextWithFlashStore(rc, sessionFlashStore{&sess})
+			r = r.WithContext(rc)
 			c.SetRequest(r)
 
 			// Wrap ResponseWriter to ensure Set-Cookie header is written before headers are sent
@@ -806,7 +779,7 @@ func Sessions(cfg SessionConfig) flash.Middleware {
 			flush()
 			return err
 		}
-	}
+	})
 }
 
 // SessionFromCtx retrieves the Session previously loaded by Sessions middleware.
@@ -847,6 +820,45 @@ func SessionFromCtx(c flash.Ctx) *Session {
 	return &Session{Values: make(map[string]any)}
 }
 
+// sessionFlashStore keeps flash messages in the session under sessionFlashKey,
+// so c.Flash and c.Flashes need no extra cookie when Sessions is used.
+type sessionFlashStore struct{ s *Session }
+
+func (f sessionFlashStore) AddFlash(_ flash.Ctx, m ctx.FlashMessage) error {
+	prev := f.pending()
+	f.s.Set(sessionFlashKey, append(prev[:len(prev):len(prev)], m))
+	return nil
+}
+
+func (f sessionFlashStore) PopFlashes(flash.Ctx) ([]ctx.FlashMessage, error) {
+	msgs := f.pending()
+	if _, ok := f.s.Get(sessionFlashKey); ok {
+		f.s.Delete(sessionFlashKey)
+	}
+	return msgs, nil
+}
+
+// pending decodes the stored messages. Stores that serialize sessions (e.g. as
+// JSON) return them as []any of maps rather than []ctx.FlashMessage.
+func (f sessionFlashStore) pending() []ctx.FlashMessage {
+	v, _ := f.s.Get(sessionFlashKey)
+	switch v := v.(type) {
+	case []ctx.FlashMessage:
+		return v
+	case []any:
+		out := make([]ctx.FlashMessage, 0, len(v))
+		for _, e := range v {
+			if m, ok := e.(map[string]any); ok {
+				kind, _ := m["k"].(string)
+				msg, _ := m["m"].(string)
+				out = append(out, ctx.FlashMessage{Kind: kind, Message: msg})
+			}
+		}
+		return out
+	}
+	return nil
+}
+
 func readSessionID(r *http.Request, cfg SessionConfig) string {
 	if cfg.HeaderName != "" {
 		if hv := r.Header.Get(cfg.HeaderName); hv != "" {
@@ -867,14 +879,15 @@ func writeSessionID(c flash.Ctx, id string, cfg SessionConfig) {
 	}
 	if cfg.CookieName != "" {
 		http.SetCookie(c.ResponseWriter(), &http.Cookie{
-			Name:     cfg.CookieName,
-			Value:    id,
-			Path:     cfg.CookiePath,
-			Domain:   cfg.Domain,
-			Secure:   cfg.Secure,
-			HttpOnly: cfg.HTTPOnly,
-			SameSite: cfg.SameSite,
-			Expires:  time.Now().Add(cfg.TTL),
+			Name:        cfg.CookieName,
+			Value:       id,
+			Path:        cfg.CookiePath,
+			Domain:      cfg.Domain,
+			Secure:      cfg.Secure || (cfg.AutoSecure && isHTTPSRequest(c.Request(), cfg.trustedNets)),
+			HttpOnly:    !cfg.DisableHTTPOnly,
+			SameSite:    cfg.SameSite,
+			Partitioned: cfg.Partitioned,
+			Expires:     time.Now().Add(cfg.TTL),
 		})
 	}
 }
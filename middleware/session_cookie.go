@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/goflash/flash/v2/ctx"
+)
+
+// Validate checks the cookie settings of cfg. It returns an error for
+// combinations browsers reject outright, so the session cookie would be
+// silently dropped, and warnings for combinations that work but weaken
+// security. Sessions panics on errors and logs warnings with slog.Default.
+//
+// Errors are those of ctx.ValidateCookie for the cookie Sessions would set,
+// with AutoSecure counting as Secure:
+//   - SameSite=None, Partitioned or a "__Secure-" name without Secure/AutoSecure
+//   - a "__Host-" name without Secure, with a Domain, or with a Path other than "/"
+//   - an invalid name, Path or Domain
+//
+// Example:
+//
+//	cfg := middleware.SessionConfig{SameSite: http.SameSiteNoneMode, AutoSecure: true}
+//	warnings, err := cfg.Validate()
+func (cfg SessionConfig) Validate() (warnings []string, err error) {
+	if cfg.CookieName == "" {
+		return nil, nil
+	}
+	secure := cfg.Secure || cfg.AutoSecure
+	path := cfg.CookiePath
+	if path == "" {
+		path = "/"
+	}
+	err = ctx.ValidateCookie(&http.Cookie{
+		Name:        cfg.CookieName,
+		Value:       "id", // stands in for the generated session ID
+		Path:        path,
+		Domain:      cfg.Domain,
+		Secure:      secure,
+		HttpOnly:    !cfg.DisableHTTPOnly,
+		SameSite:    cfg.SameSite,
+		Partitioned: cfg.Partitioned,
+	})
+
+	if !secure {
+		warnings = append(warnings, "cookie is sent over plain HTTP; set Secure or AutoSecure in production")
+	}
+	if cfg.DisableHTTPOnly {
+		warnings = append(warnings, "cookie is readable by JavaScript (DisableHTTPOnly)")
+	}
+	if cfg.SameSite == http.SameSiteNoneMode {
+		warnings = append(warnings, "cookie is sent on cross-site requests (SameSite=None); protect state-changing routes against CSRF")
+	}
+	if cfg.Domain != "" {
+		warnings = append(warnings, "cookie is shared with all subdomains of "+cfg.Domain)
+	}
+	return warnings, err
+}
+
+// isHTTPSRequest reports whether r arrived over TLS, directly or through a
+// trusted proxy that set X-Forwarded-Proto.
+func isHTTPSRequest(r *http.Request, trusted []*net.IPNet) bool {
+	if r == nil {
+		return false
+	}
+	if r.TLS != nil {
+		return true
+	}
+	proto := r.Header.Get("X-Forwarded-Proto")
+	if proto == "" || len(trusted) == 0 {
+		return false
+	}
+	if first, _, ok := strings.Cut(proto, ","); ok {
+		proto = first
+	}
+	if !strings.EqualFold(strings.TrimSpace(proto), "https") {
+		return false
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCIDRs parses CIDR strings, skipping invalid entries.
+func parseCIDRs(cidrs []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, s := range cidrs {
+		if _, n, err := net.ParseCIDR(s); err == nil {
+			nets = append(nets, n)
+		}
+	}
+	return nets
+}
@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goflash/flash/v2"
+)
+
+func sessionCookie(t *testing.T, cfg SessionConfig, prep func(r *http.Request)) *http.Cookie {
+	t.Helper()
+	a := flash.New()
+	a.Use(Sessions(cfg))
+	a.GET("/", func(c flash.Ctx) error {
+		SessionFromCtx(c).Set("k", "v")
+		return c.String(http.StatusOK, "ok")
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if prep != nil {
+		prep(req)
+	}
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	for _, ck := range rec.Result().Cookies() {
+		if ck.Name == cfg.CookieName || (cfg.CookieName == "" && ck.Name == "flash.sid") {
+			return ck
+		}
+	}
+	t.Fatalf("no session cookie in %v", rec.Header()["Set-Cookie"])
+	return nil
+}
+
+func TestSessionCookieSecureDefaults(t *testing.T) {
+	ck := sessionCookie(t, SessionConfig{}, nil)
+	if !ck.HttpOnly || ck.SameSite != http.SameSiteLaxMode || ck.Path != "/" || ck.Secure {
+		t.Fatalf("unexpected default cookie %+v", ck)
+	}
+	ck = sessionCookie(t, SessionConfig{DisableHTTPOnly: true}, nil)
+	if ck.HttpOnly {
+		t.Fatalf("expected HttpOnly to be disabled")
+	}
+}
+
+func TestSessionCookieAutoSecure(t *testing.T) {
+	cfg := SessionConfig{AutoSecure: true, TrustedProxies: []string{"10.0.0.0/8"}}
+	cases := []struct {
+		name string
+		prep func(r *http.Request)
+		want bool
+	}{
+		{"plain", nil, false},
+		{"tls", func(r *http.Request) { r.TLS = &tls.ConnectionState{} }, true},
+		{"trusted proxy", func(r *http.Request) {
+			r.RemoteAddr = "10.1.2.3:443"
+			r.Header.Set("X-Forwarded-Proto", "HTTPS, http")
+		}, true},
+		{"untrusted proxy", func(r *http.Request) {
+			r.RemoteAddr = "203.0.113.9:443"
+			r.Header.Set("X-Forwarded-Proto", "https")
+		}, false},
+		{"trusted proxy over http", func(r *http.Request) {
+			r.RemoteAddr = "10.1.2.3:443"
+			r.Header.Set("X-Forwarded-Proto", "http")
+		}, false},
+	}
+	for _, tc := range cases {
+		if got := sessionCookie(t, cfg, tc.prep).Secure; got != tc.want {
+			t.Fatalf("%s: Secure=%v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSessionCookiePartitionedAndDomain(t *testing.T) {
+	ck := sessionCookie(t, SessionConfig{
+		CookieName:  "__Host-sid",
+		Secure:      true,
+		SameSite:    http.SameSiteNoneMode,
+		Partitioned: true,
+	}, nil)
+	if !ck.Partitioned || !ck.Secure || ck.SameSite != http.SameSiteNoneMode {
+		t.Fatalf("unexpected cookie %+v", ck)
+	}
+	ck = sessionCookie(t, SessionConfig{Domain: "example.com", Secure: true}, nil)
+	if ck.Domain != "example.com" {
+		t.Fatalf("unexpected domain %q", ck.Domain)
+	}
+}
+
+func TestSessionConfigValidate(t *testing.T) {
+	cases := []struct {
+		cfg     SessionConfig
+		wantErr string
+	}{
+		{SessionConfig{CookieName: "sid", SameSite: http.SameSiteNoneMode}, "SameSite=None"},
+		{SessionConfig{CookieName: "sid", Partitioned: true}, "Partitioned"},
+		{SessionConfig{CookieName: "__Secure-sid"}, "__Secure-"},
+		{SessionConfig{CookieName: "__Host-sid"}, "require Secure"},
+		{SessionConfig{CookieName: "__Host-sid", Secure: true, Domain: "example.com"}, "must not set Domain"},
+		{SessionConfig{CookieName: "__Host-sid", Secure: true, CookiePath: "/app"}, `Path "/"`},
+		{SessionConfig{CookieName: "bad name", Secure: true}, "invalid Cookie.Name"},
+	}
+	for _, tc := range cases {
+		_, err := tc.cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) || !errors.Is(err, flash.ErrInvalidCookie) {
+			t.Fatalf("%+v: expected error containing %q, got %v", tc.cfg, tc.wantErr, err)
+		}
+	}
+
+	w, err := SessionConfig{CookieName: "sid", SameSite: http.SameSiteNoneMode, AutoSecure: true, Domain: "example.com", DisableHTTPOnly: true}.Validate()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(w) != 3 {
+		t.Fatalf("expected 3 warnings, got %q", w)
+	}
+	if w, err := (SessionConfig{CookieName: "sid", Secure: true}).Validate(); err != nil || len(w) != 0 {
+		t.Fatalf("secure config should be clean, got %q %v", w, err)
+	}
+	if w, err := (SessionConfig{HeaderName: "X-Session"}).Validate(); err != nil || w != nil {
+		t.Fatalf("cookie-less config should not be checked")
+	}
+}
+
+func TestSessionsPanicsOnInvalidConfig(t *testing.T) {
+	defer func() {
+		r := recover()
+		if r == nil || !strings.Contains(r.(string), "SameSite=None") {
+			t.Fatalf("expected panic, got %v", r)
+		}
+	}()
+	Sessions(SessionConfig{SameSite: http.SameSiteNoneMode})
+}
+
+func TestIsHTTPSRequestEdgeCases(t *testing.T) {
+	nets := parseCIDRs([]string{"10.0.0.0/8", "bogus"})
+	if len(nets) != 1 {
+		t.Fatalf("expected invalid CIDRs to be skipped")
+	}
+	if isHTTPSRequest(nil, nets) {
+		t.Fatalf("nil request")
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "not-an-ip"
+	r.Header.Set("X-Forwarded-Proto", "https")
+	if isHTTPSRequest(r, nets) {
+		t.Fatalf("unparseable remote address must not be trusted")
+	}
+}
@@ -73,6 +73,25 @@ func TestMemoryStoreSaveGetDelete(t *testing.T) {
 	}
 }
 
+func TestMemoryStoreWithLimitEvictsLeastRecentlyUsed(t *testing.T) {
+	m := NewMemoryStoreWithLimit(2)
+	_ = m.Save("a", map[string]any{"n": 1}, 0)
+	_ = m.Save("b", map[string]any{"n": 2}, 0)
+	if _, ok := m.Get("a"); !ok {
+		t.Fatalf("expected a")
+	}
+	_ = m.Save("c", map[string]any{"n": 3}, 0)
+	if m.Len() != 2 {
+		t.Fatalf("expected 2 sessions, got %d", m.Len())
+	}
+	if _, ok := m.Get("b"); ok {
+		t.Fatalf("expected least recently used session b to be evicted")
+	}
+	if _, ok := m.Get("a"); !ok {
+		t.Fatalf("expected a to survive")
+	}
+}
+
 func TestMemoryStoreExpiredDeletesOnGet(t *testing.T) {
 	m := NewMemoryStore()
 	id := "id2"
@@ -1070,3 +1089,61 @@ func TestNewSessionIDEdgeCasesAndLength(t *testing.T) {
 		}
 	}
 }
+
+func TestSessionsBackFlashMessages(t *testing.T) {
+	a := flash.New()
+	a.Use(Sessions(SessionConfig{Store: NewMemoryStore(), TTL: time.Hour}))
+	a.POST("/save", func(c flash.Ctx) error {
+		if err := c.Flash("success", "Saved!"); err != nil {
+			return err
+		}
+		_ = c.Flash("info", "Indexed")
+		http.Redirect(c.ResponseWriter(), c.Request(), "/", http.StatusSeeOther)
+		return nil
+	})
+	a.GET("/", func(c flash.Ctx) error {
+		var b strings.Builder
+		for _, f := range c.Flashes() {
+			b.WriteString(f.Kind + ":" + f.Message + ";")
+		}
+		return c.String(http.StatusOK, b.String())
+	})
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/save", nil))
+	cookies := rec.Result().Cookies()
+	if rec.Code != http.StatusSeeOther || len(cookies) != 1 || cookies[0].Name != "flash.sid" {
+		t.Fatalf("expected redirect with only the session cookie: code=%d cookies=%v", rec.Code, cookies)
+	}
+
+	get := func() string {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(cookies[0])
+		a.ServeHTTP(rec, req)
+		return rec.Body.String()
+	}
+	if got := get(); got != "success:Saved!;info:Indexed;" {
+		t.Fatalf("unexpected flashes: %q", got)
+	}
+	if got := get(); got != "" {
+		t.Fatalf("flashes not cleared after read: %q", got)
+	}
+}
+
+func TestSessionFlashStoreDecodesSerializedValues(t *testing.T) {
+	s := &Session{Values: map[string]any{sessionFlashKey: []any{
+		map[string]any{"k": "error", "m": "nope"},
+		"ignored",
+	}}}
+	got, _ := sessionFlashStore{s}.PopFlashes(nil)
+	if len(got) != 1 || got[0].Kind != "error" || got[0].Message != "nope" {
+		t.Fatalf("unexpected: %+v", got)
+	}
+	if _, ok := s.Get(sessionFlashKey); ok || !s.IsChanged() {
+		t.Fatalf("flashes not removed from session")
+	}
+	if got, _ := (sessionFlashStore{&Session{}}).PopFlashes(nil); got != nil {
+		t.Fatalf("expected nil, got %+v", got)
+	}
+}
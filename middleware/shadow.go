@@ -0,0 +1,265 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+// ShadowResponse describes one side of a mirrored request: the primary
+// response produced by the app, or the shadow backend's response.
+type ShadowResponse struct {
+	Status   int
+	Header   http.Header
+	Body     []byte // truncated to ShadowConfig.MaxBodyBytes
+	Duration time.Duration
+	Err      error // shadow only: transport or read error
+}
+
+// ShadowConfig configures the Shadow middleware.
+type ShadowConfig struct {
+	// Percent of requests (0-100) to mirror. Values <= 0 mirror nothing;
+	// values >= 100 mirror everything.
+	Percent float64
+
+	// Async sends the shadow request in the background so the primary request
+	// never waits for it. When false, the shadow request is sent after the
+	// primary handler returns, which delays request completion (but not the
+	// bytes already written) by the shadow round trip; useful in tests.
+	Async bool
+
+	// CompareFn, when set, receives the primary and shadow responses for each
+	// mirrored request. The primary response body is captured (up to
+	// MaxBodyBytes) only when CompareFn is set. In Async mode it runs on a
+	// background goroutine and must not touch the request context.
+	CompareFn func(r *http.Request, primary, shadow ShadowResponse)
+
+	// Client sends shadow requests. Defaults to a client with a 5s timeout.
+	Client *http.Client
+
+	// MaxBodyBytes bounds request and response bodies that are buffered for
+	// mirroring and comparison. Requests with larger bodies are not mirrored.
+	// Defaults to 1MB.
+	MaxBodyBytes int64
+
+	// MaxInFlight bounds concurrent async shadow requests; further requests are
+	// not mirrored while the limit is reached. Defaults to 100.
+	MaxInFlight int
+}
+
+// ShadowHeader is set on every mirrored request so the shadow backend can
+// recognize (and e.g. avoid side effects for) shadow traffic.
+const ShadowHeader = "X-Shadow-Request"
+
+// Shadow returns middleware that duplicates a percentage of requests, bodies
+// included, to the backend at targetURL without affecting the primary
+// response. The request path and query are appended to targetURL's path.
+// Shadow responses are discarded unless CompareFn is set. It panics if
+// targetURL is not an absolute URL.
+//
+// Example:
+//
+//	app.Use(middleware.Shadow("http://orders-v2.internal:8080", middleware.ShadowConfig{
+//		Percent: 5,
+//		Async:   true,
+//		CompareFn: func(r *http.Request, primary, shadow middleware.ShadowResponse) {
+//			if shadow.Err != nil || primary.Status != shadow.Status || !bytes.Equal(primary.Body, shadow.Body) {
+//				slog.Warn("shadow mismatch", "path", r.URL.Path, "primary", primary.Status, "shadow", shadow.Status)
+//			}
+//		},
+//	}))
+//
+// Security note: mirrored requests carry the original headers, including
+// credentials; only point Shadow at backends you trust with that data.
+func Shadow(targetURL string, cfg ShadowConfig) flash.Middleware {
+	target, err := url.Parse(targetURL)
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		panic("middleware: Shadow requires an absolute target URL, got " + targetURL)
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 5 * time.Second}
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = 1 << 20
+	}
+	if cfg.MaxInFlight <= 0 {
+		cfg.MaxInFlight = 100
+	}
+	inflight := make(chan struct{}, cfg.MaxInFlight)
+
+	return flash.Describe("shadow", cfg, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			if cfg.Percent <= 0 || (cfg.Percent < 100 && rand.Float64()*100 >= cfg.Percent) {
+				return next(c)
+			}
+			r := c.Request()
+			body, ok := bufferRequestBody(r, cfg.MaxBodyBytes)
+			if !ok {
+				return next(c)
+			}
+			if cfg.Async {
+				select {
+				case inflight <- struct{}{}:
+				default:
+					return next(c)
+				}
+			}
+			shadowReq := newShadowRequest(r, target, body)
+
+			var capture *captureWriter
+			if cfg.CompareFn != nil {
+				capture = &captureWriter{ResponseWriter: c.ResponseWriter(), limit: cfg.MaxBodyBytes}
+				c.SetResponseWriter(capture)
+			}
+			start := time.Now()
+			err := next(c)
+
+			var primary ShadowResponse
+			if capture != nil {
+				c.SetResponseWriter(capture.ResponseWriter)
+				primary = ShadowResponse{
+					Status:   capture.statusOr(c.StatusCode()),
+					Header:   capture.Header().Clone(),
+					Body:     capture.buf.Bytes(),
+					Duration: time.Since(start),
+				}
+			}
+			send := func() {
+				shadow := doShadow(cfg.Client, shadowReq, cfg.MaxBodyBytes)
+				if cfg.CompareFn != nil {
+					cfg.CompareFn(r, primary, shadow)
+				}
+			}
+			if cfg.Async {
+				go func() {
+					defer func() { <-inflight }()
+					send()
+				}()
+			} else {
+				send()
+			}
+			return err
+		}
+	})
+}
+
+// bufferRequestBody reads r.Body (up to limit) and restores it. It reports
+// false if the body exceeds limit or cannot be read; the body is still
+// restored so the primary handler sees it unchanged.
+func bufferRequestBody(r *http.Request, limit int64) ([]byte, bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, true
+	}
+	buf, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(buf), r.Body), r.Body}
+	return buf, err == nil && int64(len(buf)) <= limit
+}
+
+// newShadowRequest builds the mirrored request. It is detached from the
+// incoming request's context so client cancellation does not abort it.
+func newShadowRequest(r *http.Request, target *url.URL, body []byte) *http.Request {
+	u := *target
+	u.Path = strings.TrimSuffix(target.Path, "/") + r.URL.Path
+	u.RawPath = ""
+	u.RawQuery = r.URL.RawQuery
+	req, _ := http.NewRequestWithContext(context.WithoutCancel(r.Context()), r.Method, u.String(), bytes.NewReader(body))
+	req.Header = r.Header.Clone()
+	for _, h := range []string{"Connection", "Keep-Alive", "Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade"} {
+		req.Header.Del(h)
+	}
+	req.Header.Set(ShadowHeader, "1")
+	if ip, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		req.Header.Set("X-Forwarded-For", ip)
+	}
+	req.ContentLength = int64(len(body))
+	return req
+}
+
+// doShadow sends req and captures up to limit bytes of the response.
+func doShadow(client *http.Client, req *http.Request, limit int64) ShadowResponse {
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return ShadowResponse{Err: err, Duration: time.Since(start)}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return ShadowResponse{Status: resp.StatusCode, Header: resp.Header, Body: body, Duration: time.Since(start), Err: err}
+}
+
+// captureWriter passes writes through while recording the status and the
+// first limit bytes of the body.
+type captureWriter struct {
+	http.ResponseWriter
+	status    int
+	buf       bytes.Buffer
+	limit     int64
+	truncated bool // more than limit bytes were written
+}
+
+func (w *captureWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	room := w.limit - int64(w.buf.Len())
+	if int64(len(p)) > room {
+		w.truncated = true
+	}
+	if room > 0 {
+		if int64(len(p)) < room {
+			room = int64(len(p))
+		}
+		w.buf.Write(p[:room])
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush forwards to the underlying writer when supported.
+func (w *captureWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack forwards to the underlying writer when supported.
+func (w *captureWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("middleware: hijacking not supported")
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *captureWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// statusOr returns the captured status, falling back to def (or 200).
+func (w *captureWriter) statusOr(def int) int {
+	if w.status != 0 {
+		return w.status
+	}
+	if def != 0 {
+		return def
+	}
+	return http.StatusOK
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+type shadowHit struct {
+	method, path, query, body, shadowHdr string
+}
+
+func shadowBackend(t *testing.T, status int, reply string) (*httptest.Server, chan shadowHit) {
+	t.Helper()
+	hits := make(chan shadowHit, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		hits <- shadowHit{r.Method, r.URL.Path, r.URL.RawQuery, string(b), r.Header.Get(ShadowHeader)}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(reply))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, hits
+}
+
+func TestShadow_MirrorsWithBodyAndCompares(t *testing.T) {
+	srv, hits := shadowBackend(t, http.StatusCreated, "v2")
+	var primary, shadow ShadowResponse
+	a := flash.New()
+	a.Use(Shadow(srv.URL+"/mirror/", ShadowConfig{
+		Percent: 100,
+		CompareFn: func(r *http.Request, p, s ShadowResponse) {
+			primary, shadow = p, s
+		},
+	}))
+	var seen string
+	a.POST("/orders", func(c flash.Ctx) error {
+		b, _ := io.ReadAll(c.Request().Body)
+		seen = string(b)
+		return c.String(http.StatusOK, "v1")
+	})
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders?x=1", strings.NewReader(`{"id":7}`)))
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "v1" || seen != `{"id":7}` {
+		t.Fatalf("primary affected: %d %q seen=%q", rec.Code, rec.Body.String(), seen)
+	}
+	hit := <-hits
+	if hit != (shadowHit{"POST", "/mirror/orders", "x=1", `{"id":7}`, "1"}) {
+		t.Fatalf("unexpected shadow request: %+v", hit)
+	}
+	if primary.Status != 200 || string(primary.Body) != "v1" || shadow.Status != 201 || string(shadow.Body) != "v2" || shadow.Err != nil {
+		t.Fatalf("compare got primary=%+v shadow=%+v", primary, shadow)
+	}
+}
+
+func TestShadow_AsyncDoesNotBlock(t *testing.T) {
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-block }))
+	defer srv.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	var shadow ShadowResponse
+	a := flash.New()
+	a.Use(Shadow(srv.URL, ShadowConfig{
+		Percent:   100,
+		Async:     true,
+		CompareFn: func(r *http.Request, p, s ShadowResponse) { shadow = s; wg.Done() },
+	}))
+	a.GET("/", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+
+	done := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		done <- rec.Code
+	}()
+	select {
+	case code := <-done:
+		if code != http.StatusOK {
+			t.Fatalf("primary status %d", code)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("async shadow blocked the primary response")
+	}
+	close(block)
+	wg.Wait()
+	if shadow.Err != nil || shadow.Status != http.StatusOK {
+		t.Fatalf("unexpected shadow result: %+v", shadow)
+	}
+}
+
+func TestShadow_ReportsTransportErrors(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+	var shadowErr error
+	a := flash.New()
+	a.Use(Shadow(url, ShadowConfig{Percent: 100, CompareFn: func(r *http.Request, p, s ShadowResponse) { shadowErr = s.Err }}))
+	a.GET("/", func(c flash.Ctx) error { return c.String(http.StatusOK, "ok") })
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || shadowErr == nil {
+		t.Fatalf("expected primary 200 and shadow error, got %d %v", rec.Code, shadowErr)
+	}
+}
+
+func TestShadow_PercentAndBodyLimit(t *testing.T) {
+	srv, hits := shadowBackend(t, 200, "")
+	a := flash.New()
+	a.Use(Shadow(srv.URL, ShadowConfig{Percent: 0}))
+	a.POST("/", func(c flash.Ctx) error { return nil })
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+
+	b := flash.New()
+	b.Use(Shadow(srv.URL, ShadowConfig{Percent: 100, MaxBodyBytes: 4}))
+	var seen string
+	b.POST("/", func(c flash.Ctx) error {
+		body, _ := io.ReadAll(c.Request().Body)
+		seen = string(body)
+		return nil
+	})
+	b.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader("too large")))
+	if seen != "too large" {
+		t.Fatalf("oversized body not restored: %q", seen)
+	}
+	select {
+	case h := <-hits:
+		t.Fatalf("unexpected shadow request: %+v", h)
+	default:
+	}
+}
+
+func TestShadow_PanicsOnRelativeURL(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic")
+		}
+	}()
+	Shadow("/relative", ShadowConfig{})
+}
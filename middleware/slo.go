@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+// SLOMetaKey is the route metadata key holding a route's latency budget. The
+// value is a time.Duration or a string accepted by time.ParseDuration.
+//
+// Example:
+//
+//	app.GET("/search", Search).Meta(middleware.SLOMetaKey, "100ms")
+const SLOMetaKey = "slo"
+
+// SLOBudget returns the latency budget of the matched route, declared with
+// Route.Meta("slo", ...). Invalid or non-positive values are ignored.
+//
+// Tracing middleware (e.g. goflash/otel) use it together with SLOViolated to
+// annotate spans the same way Logger annotates log lines.
+func SLOBudget(c flash.Ctx) (time.Duration, bool) {
+	v, ok := c.RouteMeta(SLOMetaKey)
+	if !ok {
+		return 0, false
+	}
+	var d time.Duration
+	switch b := v.(type) {
+	case time.Duration:
+		d = b
+	case string:
+		var err error
+		if d, err = time.ParseDuration(b); err != nil {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	return d, d > 0
+}
+
+// SLOViolated reports whether a request that took elapsed exceeded the
+// latency budget of its route. Routes without a budget never violate.
+//
+// Example (tracing middleware):
+//
+//	if budget, ok := middleware.SLOBudget(c); ok {
+//		span.SetAttributes(attribute.Bool("slo.violated", time.Since(start) > budget))
+//	}
+func SLOViolated(c flash.Ctx, elapsed time.Duration) bool {
+	budget, ok := SLOBudget(c)
+	return ok && elapsed > budget
+}
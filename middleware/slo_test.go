@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2"
+)
+
+func TestSLOBudget(t *testing.T) {
+	a := flash.New()
+	var got []string
+	probe := func(c flash.Ctx) error {
+		b, ok := SLOBudget(c)
+		if ok {
+			got = append(got, b.String())
+		} else {
+			got = append(got, "none")
+		}
+		return nil
+	}
+	a.GET("/str", probe).Meta(SLOMetaKey, "150ms")
+	a.GET("/dur", probe).Meta(SLOMetaKey, 2*time.Second)
+	a.GET("/bad", probe).Meta(SLOMetaKey, "fast")
+	a.GET("/zero", probe).Meta(SLOMetaKey, "0s")
+	a.GET("/none", probe)
+	for _, p := range []string{"/str", "/dur", "/bad", "/zero", "/none"} {
+		a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, p, nil))
+	}
+	want := []string{"150ms", "2s", "none", "none", "none"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("budgets = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestLoggerSLOViolation(t *testing.T) {
+	h := &captureHandler{}
+	var violations []string
+	a := flash.New()
+	a.SetLogger(slog.New(h))
+	a.Use(Logger(WithSLOViolationHook(func(c flash.Ctx, budget, elapsed time.Duration) {
+		if elapsed <= budget {
+			t.Errorf("hook called within budget: %v <= %v", elapsed, budget)
+		}
+		violations = append(violations, c.Route())
+	})))
+	a.GET("/slow", func(c flash.Ctx) error {
+		time.Sleep(5 * time.Millisecond)
+		if !SLOViolated(c, 5*time.Millisecond) {
+			t.Errorf("SLOViolated should report the exceeded budget")
+		}
+		return nil
+	}).Meta("slo", "1ms")
+	a.GET("/fast", func(c flash.Ctx) error { return nil }).Meta("slo", "10s")
+	a.GET("/plain", func(c flash.Ctx) error { return nil })
+
+	for _, p := range []string{"/slow", "/fast", "/plain"} {
+		a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, p, nil))
+	}
+	if len(violations) != 1 || violations[0] != "/slow" {
+		t.Fatalf("violations = %v", violations)
+	}
+	if len(h.rec) != 3 {
+		t.Fatalf("got %d log lines", len(h.rec))
+	}
+	if v, _ := recordAttr(h.rec[0], "slo.violated"); !v.Bool() {
+		t.Fatalf("slow request should be marked violated")
+	}
+	if v, ok := recordAttr(h.rec[1], "slo.violated"); !ok || v.Bool() {
+		t.Fatalf("fast request should be marked within budget")
+	}
+	if ms, _ := recordAttr(h.rec[1], "slo_ms"); ms.Float64() != 10000 {
+		t.Fatalf("slo_ms = %v", ms)
+	}
+	if _, ok := recordAttr(h.rec[2], "slo.violated"); ok {
+		t.Fatalf("routes without a budget carry no slo fields")
+	}
+}
@@ -0,0 +1,276 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/goflash/flash/v2"
+	"github.com/goflash/flash/v2/ctx"
+)
+
+// TenantInfo is the tenant resolved for a request by the Tenant middleware.
+type TenantInfo struct {
+	// ID is the stable tenant identifier used for keys (rate limits, caches, logs).
+	ID string
+	// Name is an optional display name filled in by TenantConfig.Lookup.
+	Name string
+	// Metadata carries application-defined data (plan, region, feature flags...).
+	Metadata map[string]any
+}
+
+// Tenant resolution errors passed to TenantConfig.ErrorResponse.
+var (
+	// ErrTenantRequired is returned when no tenant could be resolved and the
+	// tenant is not optional.
+	ErrTenantRequired = errors.New("tenant required")
+	// ErrTenantInvalid is returned when the resolved ID contains characters
+	// other than letters, digits, '.', '_' and '-', or exceeds 64 bytes.
+	ErrTenantInvalid = errors.New("invalid tenant")
+	// ErrTenantNotFound should be returned by TenantConfig.Lookup for unknown tenants.
+	ErrTenantNotFound = errors.New("tenant not found")
+)
+
+// TenantResolver extracts a tenant ID from the request. It returns "" when the
+// request carries no tenant; a non-nil error aborts the request.
+type TenantResolver func(c flash.Ctx) (string, error)
+
+// TenantConfig configures the Tenant middleware.
+//
+// Example:
+//
+//	app.Use(middleware.Tenant(middleware.TenantConfig{
+//		Resolver: middleware.FirstTenant(
+//			middleware.TenantFromSubdomain("example.com"), // acme.example.com -> acme
+//			middleware.TenantFromHeader("X-Tenant-ID"),
+//		),
+//		Lookup: func(ctx context.Context, id string) (*middleware.TenantInfo, error) {
+//			t, ok := tenants[id]
+//			if !ok {
+//				return nil, middleware.ErrTenantNotFound
+//			}
+//			return &middleware.TenantInfo{ID: id, Name: t.Name, Metadata: map[string]any{"plan": t.Plan}}, nil
+//		},
+//	}))
+//
+//	// Per-tenant rate limits and concurrency budgets
+//	app.Use(middleware.RateLimit(middleware.WithKeyFunc(middleware.TenantKeyFunc(nil))))
+//
+//	app.GET("/me", func(c flash.Ctx) error {
+//		t, _ := middleware.TenantFromCtx(c)
+//		return c.JSON(map[string]string{"tenant": t.ID})
+//	})
+type TenantConfig struct {
+	// Resolver extracts the tenant ID. If nil, TenantFromHeader("X-Tenant-ID") is used.
+	Resolver TenantResolver
+
+	// Lookup loads tenant details for a resolved ID. Return ErrTenantNotFound
+	// for unknown tenants. If nil, a Tenant with only ID set is used.
+	Lookup func(ctx context.Context, id string) (*TenantInfo, error)
+
+	// Optional lets requests without a tenant through; TenantFromCtx then
+	// reports false. By default such requests are rejected with ErrTenantRequired.
+	Optional bool
+
+	// LogAttr is the attribute key added to the request-scoped logger so every
+	// log line carries the tenant ID. Defaults to "tenant"; set to "-" to disable.
+	LogAttr string
+
+	// ErrorResponse produces the response for resolution failures. If nil,
+	// ErrTenantNotFound maps to 404, ErrTenantRequired and ErrTenantInvalid to
+	// 400, and any other error (e.g. a failing Lookup) is returned to the app's
+	// error handler.
+	ErrorResponse func(c flash.Ctx, err error) error
+}
+
+type tenantContextKey struct{}
+
+// Tenant returns middleware that resolves the request's tenant, stores it on
+// the request context for TenantFromCtx, and attaches a logger carrying the
+// tenant ID so Logger and handler logs are tenant-scoped. With
+// flash.WithProfilingLabels, the tenant is also added as a "tenant" pprof label.
+func Tenant(cfg TenantConfig) flash.Middleware {
+	if cfg.Resolver == nil {
+		cfg.Resolver = TenantFromHeader("X-Tenant-ID")
+	}
+	if cfg.LogAttr == "" {
+		cfg.LogAttr = "tenant"
+	}
+	if cfg.ErrorResponse == nil {
+		cfg.ErrorResponse = defaultTenantErrorResponse
+	}
+
+	return flash.Describe("tenant", cfg, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			id, err := cfg.Resolver(c)
+			if err != nil {
+				return cfg.ErrorResponse(c, err)
+			}
+			if id == "" {
+				if cfg.Optional {
+					return next(c)
+				}
+				return cfg.ErrorResponse(c, ErrTenantRequired)
+			}
+			if !validTenantID(id) {
+				return cfg.ErrorResponse(c, ErrTenantInvalid)
+			}
+
+			r := c.Request()
+			t := &TenantInfo{ID: id}
+			if cfg.Lookup != nil {
+				if t, err = cfg.Lookup(r.Context(), id); err != nil {
+					return cfg.ErrorResponse(c, err)
+				}
+				if t == nil {
+					return cfg.ErrorResponse(c, ErrTenantNotFound)
+				}
+			}
+
+			rc := context.WithValue(r.Context(), tenantContextKey{}, t)
+			if cfg.LogAttr != "-" {
+				rc = ctx.ContextWithLogger(rc, ctx.LoggerFromContext(rc).With(cfg.LogAttr, t.ID))
+			}
+			rc = ctx.WithProfilingLabels(rc, "tenant", t.ID)
+			c.SetRequest(r.WithContext(rc))
+			return next(c)
+		}
+	})
+}
+
+// TenantFromCtx returns the tenant resolved by the Tenant middleware.
+func TenantFromCtx(c flash.Ctx) (*TenantInfo, bool) {
+	t, ok := c.Context().Value(tenantContextKey{}).(*TenantInfo)
+	return t, ok && t != nil
+}
+
+// TenantKeyFunc returns a key extractor for RateLimit, ConcurrencyLimit and
+// similar middleware that scopes keys by tenant ("tenant:<id>"). Requests
+// without a tenant use fallback, or the client IP when fallback is nil.
+//
+// Example:
+//
+//	// Each tenant shares one budget of 1000 requests per minute
+//	app.Use(middleware.RateLimit(
+//		middleware.WithStrategy(middleware.NewTokenBucketStrategy(1000, time.Minute)),
+//		middleware.WithKeyFunc(middleware.TenantKeyFunc(nil)),
+//	))
+func TenantKeyFunc(fallback func(c flash.Ctx) string) func(c flash.Ctx) string {
+	if fallback == nil {
+		fallback = ClientIPKeyFunc(nil)
+	}
+	return func(c flash.Ctx) string {
+		if t, ok := TenantFromCtx(c); ok {
+			return "tenant:" + t.ID
+		}
+		return fallback(c)
+	}
+}
+
+// TenantFromHeader resolves the tenant from a request header.
+func TenantFromHeader(name string) TenantResolver {
+	return func(c flash.Ctx) (string, error) {
+		return strings.TrimSpace(c.Request().Header.Get(name)), nil
+	}
+}
+
+// TenantFromSubdomain resolves the tenant from the first label of the host
+// under baseDomain: "acme.example.com" yields "acme" for base "example.com".
+// The bare base domain and deeper names ("a.b.example.com") yield no tenant.
+func TenantFromSubdomain(baseDomain string) TenantResolver {
+	suffix := "." + strings.ToLower(strings.Trim(baseDomain, "."))
+	return func(c flash.Ctx) (string, error) {
+		host := strings.ToLower(requestHost(c.Request()))
+		sub, ok := strings.CutSuffix(host, suffix)
+		if !ok || sub == "" || strings.Contains(sub, ".") {
+			return "", nil
+		}
+		return sub, nil
+	}
+}
+
+// TenantFromHost resolves the tenant from an exact host mapping, for tenants
+// served on their own domains ("shop.acme.com" -> "acme").
+func TenantFromHost(hosts map[string]string) TenantResolver {
+	m := make(map[string]string, len(hosts))
+	for h, id := range hosts {
+		m[strings.ToLower(h)] = id
+	}
+	return func(c flash.Ctx) (string, error) {
+		return m[strings.ToLower(requestHost(c.Request()))], nil
+	}
+}
+
+// TenantFromJWTClaim resolves the tenant from a string claim of a token that
+// authentication middleware has already verified. claims returns the
+// verified claims stored on the request, such as oidc.ClaimsFromCtx;
+// requests without them resolve no tenant. The Authorization header itself is
+// never read, so register Tenant after the authentication middleware.
+//
+// Example:
+//
+//	app.Use(sessions, provider.Middleware())
+//	app.Use(middleware.Tenant(middleware.TenantConfig{
+//		Resolver: middleware.TenantFromJWTClaim("tid", oidc.ClaimsFromCtx),
+//	}))
+func TenantFromJWTClaim[M ~map[string]any](claim string, claims func(c flash.Ctx) (M, bool)) TenantResolver {
+	return func(c flash.Ctx) (string, error) {
+		m, ok := claims(c)
+		if !ok {
+			return "", nil
+		}
+		id, _ := m[claim].(string)
+		return id, nil
+	}
+}
+
+// FirstTenant tries resolvers in order and returns the first non-empty ID.
+func FirstTenant(resolvers ...TenantResolver) TenantResolver {
+	return func(c flash.Ctx) (string, error) {
+		for _, r := range resolvers {
+			id, err := r(c)
+			if err != nil || id != "" {
+				return id, err
+			}
+		}
+		return "", nil
+	}
+}
+
+// requestHost returns the request host without port.
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return host
+}
+
+// validTenantID reports whether id is 1-64 bytes of [A-Za-z0-9._-].
+func validTenantID(id string) bool {
+	if len(id) == 0 || len(id) > 64 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		b := id[i]
+		if !(b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '.' || b == '_' || b == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultTenantErrorResponse maps known resolution errors to 404 or 400 and
+// passes anything else through.
+func defaultTenantErrorResponse(c flash.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrTenantNotFound):
+		return c.String(http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrTenantRequired), errors.Is(err, ErrTenantInvalid):
+		return c.String(http.StatusBadRequest, err.Error())
+	default:
+		return err
+	}
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/goflash/flash/v2"
+	"github.com/goflash/flash/v2/ctx"
+)
+
+func tenantApp(cfg TenantConfig) flash.App {
+	a := flash.New()
+	a.Use(Tenant(cfg))
+	a.GET("/", func(c flash.Ctx) error {
+		t, ok := TenantFromCtx(c)
+		if !ok {
+			return c.String(http.StatusOK, "none")
+		}
+		return c.String(http.StatusOK, t.ID+"|"+t.Name)
+	})
+	return a
+}
+
+func serveTenant(a flash.App, host string, hdr map[string]string) (int, string) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = host
+	for k, v := range hdr {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+func TestTenant_HeaderDefaultAndErrors(t *testing.T) {
+	a := tenantApp(TenantConfig{})
+	if code, body := serveTenant(a, "x", map[string]string{"X-Tenant-ID": "acme"}); code != 200 || body != "acme|" {
+		t.Fatalf("got %d %q", code, body)
+	}
+	if code, body := serveTenant(a, "x", nil); code != 400 || body != ErrTenantRequired.Error() {
+		t.Fatalf("missing tenant: %d %q", code, body)
+	}
+	if code, _ := serveTenant(a, "x", map[string]string{"X-Tenant-ID": "a/b"}); code != 400 {
+		t.Fatalf("invalid tenant should be 400, got %d", code)
+	}
+	if code, body := serveTenant(tenantApp(TenantConfig{Optional: true}), "x", nil); code != 200 || body != "none" {
+		t.Fatalf("optional: %d %q", code, body)
+	}
+}
+
+func TestTenant_Lookup(t *testing.T) {
+	boom := errors.New("db down")
+	a := tenantApp(TenantConfig{Lookup: func(_ context.Context, id string) (*TenantInfo, error) {
+		switch id {
+		case "acme":
+			return &TenantInfo{ID: id, Name: "Acme"}, nil
+		case "broken":
+			return nil, boom
+		case "nil":
+			return nil, nil
+		}
+		return nil, ErrTenantNotFound
+	}})
+	h := func(id string) map[string]string { return map[string]string{"X-Tenant-ID": id} }
+	if code, body := serveTenant(a, "x", h("acme")); code != 200 || body != "acme|Acme" {
+		t.Fatalf("got %d %q", code, body)
+	}
+	for _, id := range []string{"other", "nil"} {
+		if code, _ := serveTenant(a, "x", h(id)); code != 404 {
+			t.Fatalf("%s: expected 404, got %d", id, code)
+		}
+	}
+	if code, _ := serveTenant(a, "x", h("broken")); code != 500 {
+		t.Fatalf("lookup failure should reach the error handler, got %d", code)
+	}
+}
+
+type tenantTestClaims map[string]any
+
+func TestTenant_Resolvers(t *testing.T) {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"tid":"forged"}`))
+	// verified stands in for claims stored by authentication middleware.
+	verified := func(c flash.Ctx) (tenantTestClaims, bool) {
+		if c.Request().Header.Get("Authorization") != "Bearer verified" {
+			return nil, false
+		}
+		return tenantTestClaims{"tid": "jwt-co"}, true
+	}
+	resolver := FirstTenant(
+		TenantFromHost(map[string]string{"Shop.Custom.com": "custom"}),
+		TenantFromSubdomain("example.com"),
+		TenantFromJWTClaim("tid", verified),
+	)
+	a := tenantApp(TenantConfig{Resolver: resolver, Optional: true})
+	cases := []struct {
+		host, auth, want string
+		code             int
+	}{
+		{"shop.custom.com:8443", "", "custom|", 200},
+		{"acme.example.com", "", "acme|", 200},
+		{"ACME.Example.com:80", "", "acme|", 200},
+		{"example.com", "", "none", 200},
+		{"a.b.example.com", "", "none", 200},
+		{"api.local", "Bearer verified", "jwt-co|", 200},
+		{"api.local", "Bearer h." + payload + ".s", "none", 200},
+		{"api.local", "Bearer garbage", "none", 200},
+		{"api.local", "Basic xyz", "none", 200},
+	}
+	for _, tc := range cases {
+		code, body := serveTenant(a, tc.host, map[string]string{"Authorization": tc.auth})
+		if code != tc.code || body != tc.want {
+			t.Fatalf("%s %q: got %d %q", tc.host, tc.auth, code, body)
+		}
+	}
+}
+
+func TestTenant_LoggerAndKeyFunc(t *testing.T) {
+	a := flash.New()
+	h := &captureHandler{}
+	a.SetLogger(slog.New(h))
+	var key string
+	a.Use(Tenant(TenantConfig{Optional: true}))
+	a.GET("/", func(c flash.Ctx) error {
+		key = TenantKeyFunc(func(flash.Ctx) string { return "anon" })(c)
+		ctx.LoggerFromContext(c.Context()).Info("hello")
+		return nil
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Tenant-ID", "acme")
+	a.ServeHTTP(httptest.NewRecorder(), req)
+	if key != "tenant:acme" {
+		t.Fatalf("key = %q", key)
+	}
+	if len(h.rec) == 0 {
+		t.Fatalf("no log captured")
+	}
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if key != "anon" {
+		t.Fatalf("fallback key = %q", key)
+	}
+}
+
+func TestTenant_ProfilingLabel(t *testing.T) {
+	a := flash.New(flash.WithProfilingLabels())
+	a.Use(Tenant(TenantConfig{}))
+	var label string
+	a.GET("/", func(c flash.Ctx) error {
+		label, _ = pprof.Label(c.Context(), "tenant")
+		return nil
+	})
+	if code, _ := serveTenant(a, "", map[string]string{"X-Tenant-ID": "acme"}); code != http.StatusOK || label != "acme" {
+		t.Fatalf("tenant label = %q (%d)", label, code)
+	}
+}
@@ -0,0 +1,254 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/goflash/flash/v2"
+	"github.com/goflash/flash/v2/ctx"
+	"github.com/goflash/flash/v2/kvcache"
+)
+
+// Thumbnail is an encoded thumbnail held by a ThumbnailCache.
+type Thumbnail struct {
+	ContentType string
+	Data        []byte
+}
+
+// ThumbnailCache stores encoded thumbnails by key. Keys include the source
+// file's modification time and size, so entries for changed files are simply
+// never requested again. Implementations must be safe for concurrent use.
+type ThumbnailCache interface {
+	// Get returns the thumbnail stored under key.
+	Get(key string) (Thumbnail, bool)
+	// Set stores t under key.
+	Set(key string, t Thumbnail)
+}
+
+// MemoryThumbnailCache is an in-process ThumbnailCache bounded by the total
+// size of the stored thumbnails, evicting the least recently used.
+type MemoryThumbnailCache struct {
+	cache *kvcache.Cache[string, Thumbnail]
+}
+
+// NewMemoryThumbnailCache returns a cache holding at most maxBytes of
+// thumbnail data (default 64 MB).
+func NewMemoryThumbnailCache(maxBytes int64) *MemoryThumbnailCache {
+	if maxBytes <= 0 {
+		maxBytes = 64 << 20
+	}
+	return &MemoryThumbnailCache{cache: kvcache.New(kvcache.Options[string, Thumbnail]{
+		MaxCost: maxBytes,
+		Cost:    func(k string, t Thumbnail) int64 { return int64(len(k) + len(t.Data)) },
+	})}
+}
+
+// Get implements ThumbnailCache.
+func (m *MemoryThumbnailCache) Get(key string) (Thumbnail, bool) { return m.cache.Get(key) }
+
+// Set implements ThumbnailCache.
+func (m *MemoryThumbnailCache) Set(key string, t Thumbnail) { m.cache.Set(key, t) }
+
+// ThumbnailConfig configures ThumbnailMiddleware.
+type ThumbnailConfig struct {
+	// FS holds the source images. Required.
+	FS fs.FS
+
+	// Param is the route parameter holding the file path. Defaults to
+	// "filepath", as in "/media/*filepath".
+	Param string
+
+	// Cache stores generated thumbnails. Defaults to a 64 MB
+	// MemoryThumbnailCache.
+	Cache ThumbnailCache
+
+	// MaxWidth and MaxHeight bound the requested size. Defaults to 2048.
+	MaxWidth  int
+	MaxHeight int
+
+	// Sizes, if set, lists the only accepted sizes as "WxH" (e.g. "200x200",
+	// or "320x0" for a width alone), so clients cannot fill the cache with
+	// arbitrary variants.
+	Sizes []string
+
+	// MaxSourcePixels rejects source images larger than this many pixels
+	// before decoding them. Defaults to 40 million.
+	MaxSourcePixels int
+
+	// Quality is the JPEG quality of thumbnails. Defaults to
+	// ctx.DefaultImageQuality.
+	Quality int
+}
+
+// ThumbnailMiddleware returns middleware that serves resized images for
+// requests carrying "w" and/or "h" query parameters, with an optional "fit"
+// of "contain" (default), "cover" or "fill" (see ctx.ResizeImage). Requests
+// without them, and paths that are missing or not decodable images, fall
+// through to the next handler, which serves the original file.
+//
+// Thumbnails keep the source format (GIF sources are served as PNG), are
+// cached in Cache and carry an ETag so browsers can revalidate cheaply.
+// Invalid or disallowed sizes are answered with 400.
+//
+// Example:
+//
+//	media := os.DirFS("./media")
+//	a.GET("/media/*filepath", func(c flash.Ctx) error {
+//		http.ServeFileFS(c.ResponseWriter(), c.Request(), media, strings.TrimPrefix(c.Param("filepath"), "/"))
+//		return nil
+//	}, middleware.ThumbnailMiddleware(middleware.ThumbnailConfig{
+//		FS:    media,
+//		Sizes: []string{"200x200", "640x0"},
+//	}))
+//	// GET /media/cat.jpg?w=200&h=200&fit=cover
+func ThumbnailMiddleware(cfg ThumbnailConfig) flash.Middleware {
+	if cfg.FS == nil {
+		panic("middleware: ThumbnailConfig.FS is required")
+	}
+	if cfg.Param == "" {
+		cfg.Param = "filepath"
+	}
+	if cfg.Cache == nil {
+		cfg.Cache = NewMemoryThumbnailCache(0)
+	}
+	if cfg.MaxWidth <= 0 {
+		cfg.MaxWidth = 2048
+	}
+	if cfg.MaxHeight <= 0 {
+		cfg.MaxHeight = 2048
+	}
+	if cfg.MaxSourcePixels <= 0 {
+		cfg.MaxSourcePixels = 40_000_000
+	}
+	var sizes map[string]bool
+	if len(cfg.Sizes) > 0 {
+		sizes = make(map[string]bool, len(cfg.Sizes))
+		for _, s := range cfg.Sizes {
+			sizes[strings.ToLower(strings.TrimSpace(s))] = true
+		}
+	}
+
+	return flash.Describe("thumbnail", cfg, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			wq, hq := c.Query("w"), c.Query("h")
+			if wq == "" && hq == "" {
+				return next(c)
+			}
+			w, werr := parseDimension(wq, cfg.MaxWidth)
+			h, herr := parseDimension(hq, cfg.MaxHeight)
+			if werr != nil || herr != nil || (w == 0 && h == 0) {
+				return c.String(http.StatusBadRequest, "invalid thumbnail size")
+			}
+			if sizes != nil && !sizes[strconv.Itoa(w)+"x"+strconv.Itoa(h)] {
+				return c.String(http.StatusBadRequest, "thumbnail size not allowed")
+			}
+			fit := ctx.ImageFit(strings.ToLower(c.Query("fit")))
+			switch fit {
+			case "":
+				fit = ctx.FitContain
+			case ctx.FitContain, ctx.FitCover, ctx.FitFill:
+			default:
+				return c.String(http.StatusBadRequest, "invalid thumbnail fit")
+			}
+
+			name := path.Clean(strings.TrimPrefix(c.Param(cfg.Param), "/"))
+			if !fs.ValidPath(name) {
+				return next(c)
+			}
+			fi, err := fs.Stat(cfg.FS, name)
+			if err != nil || fi.IsDir() {
+				return next(c)
+			}
+			key := name + "|" + strconv.FormatInt(fi.ModTime().UnixNano(), 10) + "|" + strconv.FormatInt(fi.Size(), 10) +
+				"|" + strconv.Itoa(w) + "x" + strconv.Itoa(h) + "|" + string(fit)
+			sum := sha256.Sum256([]byte(key))
+			etag := `"` + hex.EncodeToString(sum[:12]) + `"`
+			if etagMatches(c.Request().Header.Get("If-None-Match"), etag) {
+				c.Header("ETag", etag)
+				_, err := c.Send(http.StatusNotModified, "", nil)
+				return err
+			}
+
+			t, ok := cfg.Cache.Get(key)
+			if !ok {
+				var served bool
+				t, served, err = cfg.render(name, w, h, fit)
+				if err != nil {
+					return err
+				}
+				if !served {
+					return next(c)
+				}
+				cfg.Cache.Set(key, t)
+			}
+			c.Header("ETag", etag)
+			_, err = c.Send(http.StatusOK, t.ContentType, t.Data)
+			return err
+		}
+	})
+}
+
+// render decodes and resizes the named image. It reports false when the file
+// is not an image it can decode.
+func (cfg *ThumbnailConfig) render(name string, w, h int, fit ctx.ImageFit) (Thumbnail, bool, error) {
+	f, err := cfg.FS.Open(name)
+	if err != nil {
+		return Thumbnail{}, false, nil
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return Thumbnail{}, false, err
+	}
+	conf, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Thumbnail{}, false, nil
+	}
+	if conf.Width*conf.Height > cfg.MaxSourcePixels {
+		return Thumbnail{}, false, flash.NewError(http.StatusUnprocessableEntity, "source image too large")
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Thumbnail{}, false, nil
+	}
+	if format != "jpeg" {
+		format = "png"
+	}
+	var buf bytes.Buffer
+	ct, err := ctx.EncodeImage(&buf, ctx.ResizeImage(img, w, h, fit), format, cfg.Quality)
+	if err != nil {
+		return Thumbnail{}, false, err
+	}
+	return Thumbnail{ContentType: ct, Data: buf.Bytes()}, true, nil
+}
+
+// parseDimension parses a "w" or "h" query value; empty means 0 (derived).
+func parseDimension(s string, limit int) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > limit {
+		return 0, strconv.ErrRange
+	}
+	return n, nil
+}
+
+// etagMatches reports whether an If-None-Match header lists etag (or "*").
+func etagMatches(header, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == etag || t == "*" {
+			return true
+		}
+	}
+	return false
+}
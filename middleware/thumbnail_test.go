@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goflash/flash/v2"
+)
+
+func testPNG(w, h int) []byte {
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			m.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, m)
+	return buf.Bytes()
+}
+
+// countingCache wraps a ThumbnailCache and counts stores.
+type countingCache struct {
+	ThumbnailCache
+	sets atomic.Int32
+}
+
+func (c *countingCache) Set(key string, t Thumbnail) {
+	c.sets.Add(1)
+	c.ThumbnailCache.Set(key, t)
+}
+
+func thumbnailApp(cfg ThumbnailConfig) flash.App {
+	app := flash.New()
+	app.GET("/media/*filepath", func(c flash.Ctx) error {
+		return c.String(http.StatusOK, "original "+c.Param("filepath"))
+	}, ThumbnailMiddleware(cfg))
+	return app
+}
+
+func thumbGet(app flash.App, target string, hdr ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for i := 0; i+1 < len(hdr); i += 2 {
+		req.Header.Set(hdr[i], hdr[i+1])
+	}
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestThumbnailResizesAndCaches(t *testing.T) {
+	fsys := fstest.MapFS{"photos/cat.png": {Data: testPNG(200, 100)}}
+	cache := &countingCache{ThumbnailCache: NewMemoryThumbnailCache(0)}
+	app := thumbnailApp(ThumbnailConfig{FS: fsys, Cache: cache})
+
+	rec := thumbGet(app, "/media/photos/cat.png?w=50&h=50&fit=cover")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("code %d type %q body %q", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	img, err := png.Decode(rec.Body)
+	if err != nil || img.Bounds().Dx() != 50 || img.Bounds().Dy() != 50 {
+		t.Fatalf("thumbnail %v: %v", img.Bounds(), err)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("missing ETag")
+	}
+
+	if rec := thumbGet(app, "/media/photos/cat.png?w=50&h=50&fit=cover"); rec.Code != http.StatusOK || cache.sets.Load() != 1 {
+		t.Fatalf("second request should hit the cache (sets=%d)", cache.sets.Load())
+	}
+	if rec := thumbGet(app, "/media/photos/cat.png?w=50&h=50&fit=cover", "If-None-Match", etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("expected 304, got %d", rec.Code)
+	}
+
+	rec = thumbGet(app, "/media/photos/cat.png?w=40")
+	img, _ = png.Decode(rec.Body)
+	if img == nil || img.Bounds().Dx() != 40 || img.Bounds().Dy() != 20 {
+		t.Fatalf("width-only thumbnail should keep the aspect ratio")
+	}
+	if rec.Header().Get("ETag") == etag {
+		t.Fatalf("variants must have distinct ETags")
+	}
+}
+
+func TestThumbnailFallsThrough(t *testing.T) {
+	fsys := fstest.MapFS{
+		"cat.png":    {Data: testPNG(10, 10)},
+		"readme.txt": {Data: []byte("hello")},
+	}
+	app := thumbnailApp(ThumbnailConfig{FS: fsys})
+	for _, target := range []string{"/media/cat.png", "/media/readme.txt?w=10", "/media/missing.png?w=10"} {
+		if rec := thumbGet(app, target); rec.Code != http.StatusOK || !strings.HasPrefix(rec.Body.String(), "original") {
+			t.Fatalf("%s: expected original handler, got %d %q", target, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestThumbnailRejectsBadRequests(t *testing.T) {
+	fsys := fstest.MapFS{"cat.png": {Data: testPNG(10, 10)}}
+	app := thumbnailApp(ThumbnailConfig{FS: fsys, MaxWidth: 100, Sizes: []string{"20x20", "50x0"}})
+	for _, target := range []string{
+		"/media/cat.png?w=abc",
+		"/media/cat.png?w=-1",
+		"/media/cat.png?w=500",
+		"/media/cat.png?w=0&h=0",
+		"/media/cat.png?w=30&h=30",
+		"/media/cat.png?w=20&h=20&fit=stretch",
+	} {
+		if rec := thumbGet(app, target); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", target, rec.Code)
+		}
+	}
+	if rec := thumbGet(app, "/media/cat.png?w=50"); rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("allowed size rejected: %d", rec.Code)
+	}
+}
+
+func TestThumbnailSourcePixelLimit(t *testing.T) {
+	fsys := fstest.MapFS{"big.png": {Data: testPNG(100, 100)}}
+	app := thumbnailApp(ThumbnailConfig{FS: fsys, MaxSourcePixels: 5000})
+	if rec := thumbGet(app, "/media/big.png?w=10"); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", rec.Code)
+	}
+}
+
+func TestThumbnailRequiresFS(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic")
+		}
+	}()
+	ThumbnailMiddleware(ThumbnailConfig{})
+}
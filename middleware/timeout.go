@@ -176,6 +176,9 @@ func (tr *timeoutResponder) Write(b []byte) (int, error) {
 //   - Minimal overhead for requests that complete within timeout
 //   - Optimized header handling to reduce allocations
 //
+// Routes that opt out with Route.Bypass("timeout") (e.g. SSE streams) run
+// without a deadline.
+//
 // Example Usage:
 //
 //	// Basic timeout
@@ -212,8 +215,11 @@ func Timeout(cfg TimeoutConfig) flash.Middleware {
 		cfg.Duration = 5 * time.Second
 	}
 
-	return func(next flash.Handler) flash.Handler {
+	return flash.Describe("timeout", cfg, func(next flash.Handler) flash.Handler {
 		return func(c flash.Ctx) error {
+			if c.Bypassed("timeout") {
+				return next(c)
+			}
 			ctx, cancel := context.WithTimeout(c.Context(), cfg.Duration)
 			defer cancel()
 
@@ -273,5 +279,5 @@ func Timeout(cfg TimeoutConfig) flash.Middleware {
 				return nil
 			}
 		}
-	}
+	})
 }
@@ -365,3 +365,21 @@ func TestTimeoutMiddlewareWithCustomErrorResponse(t *testing.T) {
 		t.Errorf("expected custom timeout message, got %s", rec.Body.String())
 	}
 }
+
+func TestTimeoutBypassedRoute(t *testing.T) {
+	a := flash.New()
+	a.Use(Timeout(TimeoutConfig{Duration: 10 * time.Millisecond}))
+	a.GET("/stream", func(c flash.Ctx) error {
+		time.Sleep(30 * time.Millisecond)
+		if _, ok := c.Context().Deadline(); ok {
+			return c.String(http.StatusInternalServerError, "deadline set")
+		}
+		return c.String(http.StatusOK, "ok")
+	}).Bypass("timeout")
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected bypassed route to run without timeout, got %d %q", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/goflash/flash/v2"
+)
+
+// TxBeginner starts transactions. *sql.DB and *sql.Conn implement it.
+type TxBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// TxConfig configures the Tx middleware.
+type TxConfig struct {
+	// Isolation is the transaction isolation level. The zero value uses the
+	// driver's default.
+	Isolation sql.IsolationLevel
+
+	// ReadOnly starts read-only transactions.
+	ReadOnly bool
+
+	// RollbackOn decides, after the handler returns, whether to roll back
+	// instead of committing. status is the response status (200 if the handler
+	// wrote nothing). If nil, the transaction is rolled back when err is
+	// non-nil or status >= 400.
+	RollbackOn func(c flash.Ctx, status int, err error) bool
+
+	// SkipFunc, when it returns true, runs the request without a transaction.
+	SkipFunc func(c flash.Ctx) bool
+}
+
+// ErrTxCommit wraps commit failures returned by the Tx middleware.
+var ErrTxCommit = errors.New("middleware: transaction commit failed")
+
+type txContextKey struct{}
+
+// Tx returns middleware that runs each request in a database transaction.
+// The transaction is available to handlers via TxFromCtx. After the handler
+// returns, it is committed on success (no error and a 1xx-3xx status) and
+// rolled back on an error, a 4xx/5xx status or a panic (which is re-raised);
+// RollbackOn customizes the decision.
+//
+// The transaction is bound to the request context, so it is rolled back if
+// the client disconnects. Commit happens after the handler has run, so a
+// response that was already written cannot reflect a commit failure; commit
+// errors are returned, wrapped in ErrTxCommit, to the app's error handler for
+// logging. Handlers needing a guaranteed outcome may commit the transaction
+// themselves before responding; the middleware then skips its own commit.
+// Side effects that must only happen once the transaction is committed, such
+// as publishing outbox messages, belong in c.OnCommit.
+//
+// Example:
+//
+//	api.Use(middleware.Tx(db, middleware.TxConfig{Isolation: sql.LevelSerializable}))
+//
+//	api.POST("/orders", func(c flash.Ctx) error {
+//		tx, _ := middleware.TxFromCtx(c)
+//		if _, err := tx.ExecContext(c.Context(), "INSERT INTO orders ...", ...); err != nil {
+//			return err // rolled back
+//		}
+//		return c.Status(http.StatusCreated).JSON(order) // committed
+//	})
+func Tx(db TxBeginner, cfg TxConfig) flash.Middleware {
+	if db == nil {
+		panic("middleware: Tx requires a database")
+	}
+	rollbackOn := cfg.RollbackOn
+	if rollbackOn == nil {
+		rollbackOn = func(_ flash.Ctx, status int, err error) bool {
+			return err != nil || status >= http.StatusBadRequest
+		}
+	}
+	opts := &sql.TxOptions{Isolation: cfg.Isolation, ReadOnly: cfg.ReadOnly}
+
+	return flash.Describe("tx", cfg, func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) (err error) {
+			if cfg.SkipFunc != nil && cfg.SkipFunc(c) {
+				return next(c)
+			}
+			r := c.Request()
+			tx, err := db.BeginTx(r.Context(), opts)
+			if err != nil {
+				return fmt.Errorf("middleware: begin transaction: %w", err)
+			}
+			c.SetRequest(r.WithContext(context.WithValue(r.Context(), txContextKey{}, tx)))
+
+			done := false
+			defer func() {
+				if !done {
+					_ = tx.Rollback() // panic: roll back and let it propagate
+				}
+			}()
+			err = next(c)
+			done = true
+
+			status := c.StatusCode()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if rollbackOn(c, status, err) {
+				_ = tx.Rollback()
+				return err
+			}
+			if cerr := tx.Commit(); cerr != nil && !errors.Is(cerr, sql.ErrTxDone) {
+				return errors.Join(err, fmt.Errorf("%w: %w", ErrTxCommit, cerr))
+			}
+			return err
+		}
+	})
+}
+
+// TxFromCtx returns the request's transaction started by the Tx middleware.
+func TxFromCtx(c flash.Ctx) (*sql.Tx, bool) {
+	tx, ok := c.Context().Value(txContextKey{}).(*sql.Tx)
+	return tx, ok
+}
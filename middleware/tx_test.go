@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/goflash/flash/v2"
+)
+
+// txRecorder is a minimal database/sql driver that records transaction events.
+type txRecorder struct {
+	mu        sync.Mutex
+	events    []string
+	beginErr  error
+	commitErr error
+}
+
+func (d *txRecorder) record(ev string) {
+	d.mu.Lock()
+	d.events = append(d.events, ev)
+	d.mu.Unlock()
+}
+
+func (d *txRecorder) log() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return strings.Join(d.events, ",")
+}
+
+func (d *txRecorder) Connect(context.Context) (driver.Conn, error) { return txConn{d}, nil }
+func (d *txRecorder) Driver() driver.Driver                        { return nil }
+
+type txConn struct{ d *txRecorder }
+
+func (c txConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c txConn) Close() error                        { return nil }
+func (c txConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+func (c txConn) BeginTx(_ context.Context, o driver.TxOptions) (driver.Tx, error) {
+	if c.d.beginErr != nil {
+		return nil, c.d.beginErr
+	}
+	ev := "begin"
+	if sql.IsolationLevel(o.Isolation) == sql.LevelSerializable {
+		ev += "(serializable)"
+	}
+	if o.ReadOnly {
+		ev += "(ro)"
+	}
+	c.d.record(ev)
+	return txDriverTx{c.d}, nil
+}
+
+type txDriverTx struct{ d *txRecorder }
+
+func (t txDriverTx) Commit() error {
+	t.d.record("commit")
+	return t.d.commitErr
+}
+func (t txDriverTx) Rollback() error { t.d.record("rollback"); return nil }
+
+func txApp(rec *txRecorder, cfg TxConfig, h flash.Handler) flash.App {
+	db := sql.OpenDB(rec)
+	a := flash.New()
+	a.Use(Tx(db, cfg))
+	a.GET("/", h)
+	return a
+}
+
+func TestTx_CommitAndRollbackDecisions(t *testing.T) {
+	cases := []struct {
+		name string
+		h    flash.Handler
+		want string
+	}{
+		{"ok", func(c flash.Ctx) error {
+			if _, ok := TxFromCtx(c); !ok {
+				t.Errorf("no tx in context")
+			}
+			return c.String(http.StatusOK, "ok")
+		}, "begin,commit"},
+		{"no write", func(c flash.Ctx) error { return nil }, "begin,commit"},
+		{"redirect", func(c flash.Ctx) error { return c.String(http.StatusFound, "") }, "begin,commit"},
+		{"error", func(c flash.Ctx) error { return errors.New("boom") }, "begin,rollback"},
+		{"4xx", func(c flash.Ctx) error { return c.String(http.StatusConflict, "dup") }, "begin,rollback"},
+		{"handler committed", func(c flash.Ctx) error {
+			tx, _ := TxFromCtx(c)
+			return tx.Commit()
+		}, "begin,commit"},
+	}
+	for _, tc := range cases {
+		rec := &txRecorder{}
+		a := txApp(rec, TxConfig{}, tc.h)
+		a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		if got := rec.log(); got != tc.want {
+			t.Fatalf("%s: events=%s want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestTx_PanicRollsBackAndPropagates(t *testing.T) {
+	rec := &txRecorder{}
+	a := txApp(rec, TxConfig{}, func(c flash.Ctx) error { panic("kaboom") })
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("panic swallowed")
+			}
+		}()
+		a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+	if got := rec.log(); got != "begin,rollback" {
+		t.Fatalf("events=%s", got)
+	}
+}
+
+func TestTx_OptionsHooksAndErrors(t *testing.T) {
+	rec := &txRecorder{}
+	var sawStatus int
+	a := txApp(rec, TxConfig{
+		Isolation: sql.LevelSerializable,
+		ReadOnly:  true,
+		RollbackOn: func(c flash.Ctx, status int, err error) bool {
+			sawStatus = status
+			return false // commit even 4xx
+		},
+		SkipFunc: func(c flash.Ctx) bool { return c.Query("skip") != "" },
+	}, func(c flash.Ctx) error { return c.String(http.StatusNotFound, "") })
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := rec.log(); got != "begin(serializable)(ro),commit" || sawStatus != http.StatusNotFound {
+		t.Fatalf("events=%s status=%d", got, sawStatus)
+	}
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/?skip=1", nil))
+	if got := rec.log(); strings.Count(got, "begin") != 1 {
+		t.Fatalf("skipped request began a tx: %s", got)
+	}
+
+	// Begin and commit failures surface as errors.
+	var gotErr error
+	for _, fail := range []*txRecorder{{beginErr: errors.New("no conn")}, {commitErr: errors.New("disk full")}} {
+		a := flash.New(flash.WithErrorHandler(func(c flash.Ctx, err error) {
+			gotErr = err
+			_ = c.String(http.StatusInternalServerError, "")
+		}))
+		a.Use(Tx(sql.OpenDB(fail), TxConfig{}))
+		a.GET("/", func(c flash.Ctx) error { return nil })
+		w := httptest.NewRecorder()
+		a.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if gotErr == nil || w.Code != http.StatusInternalServerError {
+			t.Fatalf("expected error, got %v (code %d)", gotErr, w.Code)
+		}
+		if fail.commitErr != nil && !errors.Is(gotErr, ErrTxCommit) {
+			t.Fatalf("commit error not wrapped: %v", gotErr)
+		}
+		gotErr = nil
+	}
+}
+
+func TestTx_OnCommitRunsOnlyAfterCommit(t *testing.T) {
+	for _, commitErr := range []error{nil, errors.New("serialization failure")} {
+		rec := &txRecorder{commitErr: commitErr}
+		committed := make(chan string, 1)
+		a := txApp(rec, TxConfig{}, func(c flash.Ctx) error {
+			c.OnCommit(func(context.Context) { committed <- rec.log() })
+			return c.String(http.StatusOK, "ok")
+		})
+		a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		_ = a.WaitResponseHooks(context.Background())
+		select {
+		case got := <-committed:
+			if commitErr != nil || got != "begin,commit" {
+				t.Fatalf("hook ran with commitErr=%v after %q", commitErr, got)
+			}
+		default:
+			if commitErr == nil {
+				t.Fatalf("hook did not run after a successful commit")
+			}
+		}
+	}
+}
+
+func TestTx_PanicsWithoutDB(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic")
+		}
+	}()
+	Tx(nil, TxConfig{})
+}
+
+func TestTxFromCtx_Missing(t *testing.T) {
+	a := flash.New()
+	a.GET("/", func(c flash.Ctx) error {
+		if _, ok := TxFromCtx(c); ok {
+			t.Errorf("unexpected tx")
+		}
+		return nil
+	})
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Dir is an FS rooted at a local directory. Reads go through os.DirFS, so
+// files are *os.File values; writes go to a temporary file that is renamed
+// into place.
+//
+// Example:
+//
+//	uploads := storage.Dir("./uploads")
+//	err := uploads.Write(ctx, "reports/q3.pdf", r)
+type Dir string
+
+// Open implements fs.FS.
+func (d Dir) Open(name string) (fs.File, error) { return os.DirFS(string(d)).Open(name) }
+
+// Stat implements fs.StatFS.
+func (d Dir) Stat(name string) (fs.FileInfo, error) { return fs.Stat(os.DirFS(string(d)), name) }
+
+// ReadDir implements fs.ReadDirFS.
+func (d Dir) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(os.DirFS(string(d)), name)
+}
+
+// Write implements FS. Files are created with mode 0o600 and directories with
+// 0o700.
+func (d Dir) Write(ctx context.Context, name string, r io.Reader) error {
+	path, err := d.path("write", name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	if _, err := io.Copy(tmp, contextReader{ctx, r}); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Remove implements FS.
+func (d Dir) Remove(_ context.Context, name string) error {
+	path, err := d.path("remove", name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path maps a valid io/fs name to a path under the directory.
+func (d Dir) path(op, name string) (string, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return filepath.Join(string(d), filepath.FromSlash(name)), nil
+}
+
+// contextReader stops reading once ctx is done, so long copies can be
+// cancelled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDirWriteReadRemove(t *testing.T) {
+	d := Dir(t.TempDir())
+	ctx := context.Background()
+	if err := d.Write(ctx, "a/b/c.txt", strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+	b, err := fs.ReadFile(d, "a/b/c.txt")
+	if err != nil || string(b) != "hello" {
+		t.Fatalf("read = %q %v", b, err)
+	}
+	if info, err := d.Stat("a/b/c.txt"); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("stat = %v %v", info, err)
+	}
+	if entries, err := d.ReadDir("a/b"); err != nil || len(entries) != 1 {
+		t.Fatalf("temp files should not be left behind: %v %v", entries, err)
+	}
+	if err := fstest.TestFS(d, "a/b/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Remove(ctx, "a/b/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Remove(ctx, "a/b/c.txt"); err != nil {
+		t.Fatalf("removing a missing file: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(string(d), "a/b/c.txt")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("file should be removed: %v", err)
+	}
+}
+
+func TestDirRejectsInvalidNames(t *testing.T) {
+	d := Dir(t.TempDir())
+	for _, name := range []string{"../escape", "/abs", ".", "a/../b"} {
+		if err := d.Write(context.Background(), name, strings.NewReader("x")); !errors.Is(err, fs.ErrInvalid) {
+			t.Fatalf("%q: expected ErrInvalid, got %v", name, err)
+		}
+	}
+}
+
+func TestDirWriteHonoursContext(t *testing.T) {
+	d := Dir(t.TempDir())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := d.Write(ctx, "x.txt", strings.NewReader("data")); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, err := d.Stat("x.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("cancelled write should not create the file")
+	}
+}
@@ -0,0 +1,560 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultS3PartSize is the chunk size S3 uses for multipart writes.
+const DefaultS3PartSize = 8 << 20
+
+// minS3PartSize is the smallest part S3 accepts, except for the last one.
+const minS3PartSize = 5 << 20
+
+// S3Config configures an S3 file system.
+type S3Config struct {
+	// Endpoint is the service URL, such as "https://s3.eu-west-1.amazonaws.com"
+	// or "http://localhost:9000" for MinIO. Required.
+	Endpoint string
+	// Region is the signing region. Defaults to "us-east-1", which MinIO
+	// accepts.
+	Region string
+	// Bucket holds the files. Required.
+	Bucket string
+	// Prefix is prepended to every key, e.g. "media/", to serve part of a
+	// bucket.
+	Prefix string
+
+	// Credentials used to sign requests with AWS Signature Version 4.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// PathStyle addresses the bucket in the path ("endpoint/bucket/key")
+	// instead of the host name ("bucket.endpoint/key"). MinIO and most
+	// self-hosted services need it.
+	PathStyle bool
+
+	// PartSize is the chunk size of multipart writes, used for files larger
+	// than one part. Defaults to DefaultS3PartSize; values below the 5 MiB S3
+	// minimum are raised to it.
+	PartSize int64
+
+	// HTTPClient sends the requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// S3 is an FS backed by an S3-compatible bucket, spoken to directly over the
+// S3 REST API (no SDK required). Directories are derived from "/"-separated
+// key prefixes. Files are read lazily with ranged GETs, so seeking is cheap
+// and http.ServeContent answers Range requests without downloading whole
+// objects. Writes stream in parts of PartSize bytes.
+type S3 struct {
+	cfg    S3Config
+	base   *url.URL
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewS3 returns an S3 file system. It panics if Endpoint or Bucket is missing
+// or Endpoint is not a valid URL.
+func NewS3(cfg S3Config) *S3 {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		panic("storage: S3Config.Endpoint and Bucket are required")
+	}
+	base, err := url.Parse(cfg.Endpoint)
+	if err != nil || base.Host == "" {
+		panic("storage: invalid S3 endpoint " + cfg.Endpoint)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.PartSize <= 0 {
+		cfg.PartSize = DefaultS3PartSize
+	} else if cfg.PartSize < minS3PartSize {
+		cfg.PartSize = minS3PartSize
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &S3{cfg: cfg, base: base, client: client, now: time.Now}
+}
+
+// Open implements fs.FS.
+func (s *S3) Open(name string) (fs.File, error) {
+	info, err := s.stat(context.Background(), "open", name)
+	if err != nil {
+		return nil, err
+	}
+	return &s3File{s: s, name: name, key: s.key(name), info: info}, nil
+}
+
+// Stat implements fs.StatFS.
+func (s *S3) Stat(name string) (fs.FileInfo, error) {
+	info, err := s.stat(context.Background(), "stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (s *S3) ReadDir(name string) ([]fs.DirEntry, error) {
+	return s.readDir(context.Background(), name)
+}
+
+// Write implements FS. Content-Type is set from the name's extension.
+func (s *S3) Write(ctx context.Context, name string, r io.Reader) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	key := s.key(name)
+	hdr := http.Header{}
+	if ct := mime.TypeByExtension(path.Ext(name)); ct != "" {
+		hdr.Set("Content-Type", ct)
+	}
+
+	buf := make([]byte, s.cfg.PartSize)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		resp, err := s.do(ctx, http.MethodPut, key, nil, bytes.NewReader(buf[:n]), int64(n), hdr)
+		if err != nil {
+			return err
+		}
+		return s.check(resp, "write", name)
+	}
+	if err != nil {
+		return err
+	}
+	return s.writeMultipart(ctx, name, key, hdr, buf, r)
+}
+
+// writeMultipart uploads buf (a full first part) and the rest of r as a
+// multipart upload, aborting it on failure.
+func (s *S3) writeMultipart(ctx context.Context, name, key string, hdr http.Header, buf []byte, r io.Reader) error {
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, 0, hdr)
+	if err != nil {
+		return err
+	}
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := s.decode(resp, "write", name, &initiated); err != nil {
+		return err
+	}
+	uploadID := initiated.UploadID
+
+	type part struct {
+		PartNumber int
+		ETag       string
+	}
+	var parts []part
+	err = func() error {
+		n := len(buf)
+		for {
+			q := url.Values{"partNumber": {strconv.Itoa(len(parts) + 1)}, "uploadId": {uploadID}}
+			resp, err := s.do(ctx, http.MethodPut, key, q, bytes.NewReader(buf[:n]), int64(n), nil)
+			if err != nil {
+				return err
+			}
+			if err := s.check(resp, "write", name); err != nil {
+				return err
+			}
+			parts = append(parts, part{len(parts) + 1, resp.Header.Get("ETag")})
+
+			n, err = io.ReadFull(r, buf)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil && err != io.ErrUnexpectedEOF {
+				return err
+			}
+		}
+	}()
+	if err == nil {
+		body, _ := xml.Marshal(struct {
+			XMLName xml.Name `xml:"CompleteMultipartUpload"`
+			Parts   []part   `xml:"Part"`
+		}{Parts: parts})
+		var resp *http.Response
+		resp, err = s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, bytes.NewReader(body), int64(len(body)), nil)
+		if err == nil {
+			// S3 may report a failed completion in a 200 response body.
+			err = s.decode(resp, "write", name, &struct{}{})
+		}
+	}
+	if err != nil {
+		if resp, aerr := s.do(context.WithoutCancel(ctx), http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, 0, nil); aerr == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	return nil
+}
+
+// Remove implements FS.
+func (s *S3) Remove(ctx context.Context, name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+	}
+	resp, err := s.do(ctx, http.MethodDelete, s.key(name), nil, nil, 0, nil)
+	if err != nil {
+		return err
+	}
+	if err := s.check(resp, "remove", name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *S3) key(name string) string {
+	if name == "." {
+		return s.cfg.Prefix
+	}
+	return s.cfg.Prefix + name
+}
+
+// stat reports name as a file if an object has its key, and as a directory
+// if objects exist under key + "/".
+func (s *S3) stat(ctx context.Context, op, name string) (*fileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &fileInfo{name: ".", dir: true}, nil
+	}
+	resp, err := s.do(ctx, http.MethodHead, s.key(name), nil, nil, 0, nil)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		mod, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+		return &fileInfo{name: path.Base(name), size: resp.ContentLength, mod: mod}, nil
+	case resp.StatusCode != http.StatusNotFound:
+		return nil, &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("storage: s3 status %d", resp.StatusCode)}
+	}
+	res, err := s.list(ctx, s.key(name)+"/", "", 1)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if len(res.Contents) == 0 && len(res.CommonPrefixes) == 0 {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return &fileInfo{name: path.Base(name), dir: true}, nil
+}
+
+func (s *S3) readDir(ctx context.Context, name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	prefix := s.key(name)
+	if name != "." {
+		prefix += "/"
+	}
+	var entries []fs.DirEntry
+	token := ""
+	for {
+		res, err := s.list(ctx, prefix, token, 1000)
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+		}
+		for _, p := range res.CommonPrefixes {
+			entries = append(entries, &fileInfo{name: strings.TrimSuffix(strings.TrimPrefix(p.Prefix, prefix), "/"), dir: true})
+		}
+		for _, o := range res.Contents {
+			base := strings.TrimPrefix(o.Key, prefix)
+			if base == "" {
+				continue // directory marker object
+			}
+			entries = append(entries, &fileInfo{name: base, size: o.Size, mod: o.LastModified})
+		}
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			break
+		}
+		token = res.NextContinuationToken
+	}
+	if len(entries) == 0 && name != "." {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+type listResult struct {
+	IsTruncated           bool
+	NextContinuationToken string
+	Contents              []struct {
+		Key          string
+		Size         int64
+		LastModified time.Time
+	}
+	CommonPrefixes []struct{ Prefix string }
+}
+
+// list runs one ListObjectsV2 request for the keys directly under prefix.
+func (s *S3) list(ctx context.Context, prefix, token string, limit int) (*listResult, error) {
+	q := url.Values{"list-type": {"2"}, "prefix": {prefix}, "delimiter": {"/"}, "max-keys": {strconv.Itoa(limit)}}
+	if token != "" {
+		q.Set("continuation-token", token)
+	}
+	resp, err := s.do(ctx, http.MethodGet, "", q, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	var res listResult
+	if err := s.decode(resp, "list", prefix, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// do sends a signed request for key ("" for the bucket itself).
+func (s *S3) do(ctx context.Context, method, key string, q url.Values, body io.Reader, size int64, hdr http.Header) (*http.Response, error) {
+	u := *s.base
+	p := strings.TrimSuffix(u.Path, "/") + "/" + key
+	if s.cfg.PathStyle {
+		p = strings.TrimSuffix(u.Path, "/") + "/" + s.cfg.Bucket + "/" + key
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+	}
+	u.Path, u.RawPath = p, uriEncode(p, false)
+	u.RawQuery = canonicalQuery(q)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range hdr {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	signV4(req, s.cfg.AccessKeyID, s.cfg.SecretAccessKey, s.cfg.SessionToken, s.cfg.Region, s.now())
+	return s.client.Do(req)
+}
+
+// s3Error is the XML error body returned by S3.
+type s3Error struct {
+	Code    string
+	Message string
+}
+
+// check closes resp and converts an error status into an error; 404 wraps
+// fs.ErrNotExist.
+func (s *S3) check(resp *http.Response, op, name string) error {
+	defer resp.Body.Close()
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	var e s3Error
+	_ = xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&e)
+	return &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("storage: s3 status %d: %s %s", resp.StatusCode, e.Code, e.Message)}
+}
+
+// decode checks resp and decodes its XML body into v, detecting error bodies
+// sent with a 200 status.
+func (s *S3) decode(resp *http.Response, op, name string, v any) error {
+	if resp.StatusCode >= 300 {
+		return s.check(resp, op, name)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(b, []byte("<Error>")) {
+		var e s3Error
+		_ = xml.Unmarshal(b, &e)
+		return &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("storage: s3: %s %s", e.Code, e.Message)}
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil
+	}
+	return xml.Unmarshal(b, v)
+}
+
+// fileInfo implements fs.FileInfo and fs.DirEntry for S3 objects and
+// prefixes.
+type fileInfo struct {
+	name string
+	size int64
+	mod  time.Time
+	dir  bool
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) ModTime() time.Time { return fi.mod }
+func (fi *fileInfo) IsDir() bool        { return fi.dir }
+func (fi *fileInfo) Sys() any           { return nil }
+
+func (fi *fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+func (fi *fileInfo) Type() fs.FileMode          { return fi.Mode().Type() }
+func (fi *fileInfo) Info() (fs.FileInfo, error) { return fi, nil }
+
+// s3File is an open object or directory. Reads are served by a ranged GET
+// starting at the current offset, reopened after seeks.
+type s3File struct {
+	s    *S3
+	name string
+	key  string
+	info *fileInfo
+
+	off     int64
+	body    io.ReadCloser
+	bodyOff int64
+
+	entries []fs.DirEntry
+	listed  bool
+}
+
+func (f *s3File) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *s3File) Read(p []byte) (int, error) {
+	if f.info.dir {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: errors.New("is a directory")}
+	}
+	if f.off >= f.info.size {
+		return 0, io.EOF
+	}
+	if f.body == nil || f.bodyOff != f.off {
+		f.closeBody()
+		body, err := f.get(f.off, -1)
+		if err != nil {
+			return 0, err
+		}
+		f.body, f.bodyOff = body, f.off
+	}
+	n, err := f.body.Read(p)
+	f.off += int64(n)
+	f.bodyOff += int64(n)
+	if err == io.EOF && f.off < f.info.size {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// ReadAt implements io.ReaderAt with a single ranged GET.
+func (f *s3File) ReadAt(p []byte, off int64) (int, error) {
+	if off >= f.info.size {
+		return 0, io.EOF
+	}
+	end := off + int64(len(p))
+	if end > f.info.size {
+		end = f.info.size
+	}
+	body, err := f.get(off, end-1)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	n, err := io.ReadFull(body, p[:end-off])
+	if err == nil && end < off+int64(len(p)) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (f *s3File) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.off
+	case io.SeekEnd:
+		offset += f.info.size
+	default:
+		return 0, errors.New("storage: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("storage: negative position")
+	}
+	f.off = offset
+	return offset, nil
+}
+
+func (f *s3File) Close() error {
+	f.closeBody()
+	return nil
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (f *s3File) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !f.info.dir {
+		return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: errors.New("not a directory")}
+	}
+	if !f.listed {
+		entries, err := f.s.readDir(context.Background(), f.name)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		f.entries, f.listed = entries, true
+	}
+	if n <= 0 {
+		out := f.entries
+		f.entries = nil
+		return out, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(f.entries))
+	out := f.entries[:n]
+	f.entries = f.entries[n:]
+	return out, nil
+}
+
+// get opens the object from byte start to end inclusive (-1: to the end).
+func (f *s3File) get(start, end int64) (io.ReadCloser, error) {
+	rng := "bytes=" + strconv.FormatInt(start, 10) + "-"
+	if end >= 0 {
+		rng += strconv.FormatInt(end, 10)
+	}
+	resp, err := f.s.do(context.Background(), http.MethodGet, f.key, nil, nil, 0, http.Header{"Range": {rng}})
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: f.name, Err: err}
+	}
+	if resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusOK {
+		return nil, f.s.check(resp, "read", f.name)
+	}
+	if resp.StatusCode == http.StatusOK && start > 0 {
+		// The server ignored the range; skip to the requested offset.
+		if _, err := io.CopyN(io.Discard, resp.Body, start); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+	return resp.Body, nil
+}
+
+func (f *s3File) closeBody() {
+	if f.body != nil {
+		f.body.Close()
+		f.body = nil
+	}
+}
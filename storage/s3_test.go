@@ -0,0 +1,393 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// fakeS3Server is a minimal path-style S3 endpoint holding one bucket.
+type fakeS3Server struct {
+	t         *testing.T
+	mu        sync.Mutex
+	objects   map[string][]byte
+	types     map[string]string
+	uploads   map[string]map[int][]byte
+	ranges    []string
+	partSizes []int
+	aborted   int
+}
+
+func newS3Test(t *testing.T, partSize int64) (*S3, *fakeS3Server) {
+	t.Helper()
+	f := &fakeS3Server{t: t, objects: map[string][]byte{}, types: map[string]string{}, uploads: map[string]map[int][]byte{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	s := NewS3(S3Config{Endpoint: srv.URL, Bucket: "media", Prefix: "p/", PathStyle: true, AccessKeyID: "AK", SecretAccessKey: "SK", PartSize: partSize})
+	return s, f
+}
+
+func (f *fakeS3Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AK/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, "/media/")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	q := r.URL.Query()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && key == "" && q.Get("list-type") == "2":
+		f.list(w, q)
+	case r.Method == http.MethodHead, r.Method == http.MethodGet:
+		b, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Last-Modified", time.Unix(1e9, 0).UTC().Format(http.TimeFormat))
+		if rng := r.Header.Get("Range"); rng != "" && r.Method == http.MethodGet {
+			f.ranges = append(f.ranges, rng)
+			spec := strings.TrimPrefix(rng, "bytes=")
+			from, to, _ := strings.Cut(spec, "-")
+			start, _ := strconv.Atoi(from)
+			end := len(b) - 1
+			if to != "" {
+				end, _ = strconv.Atoi(to)
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(b)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(b[start : end+1])
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+		if r.Method == http.MethodGet {
+			w.Write(b)
+		}
+	case r.Method == http.MethodPut && q.Has("uploadId"):
+		b, _ := io.ReadAll(r.Body)
+		n, _ := strconv.Atoi(q.Get("partNumber"))
+		f.uploads[q.Get("uploadId")][n] = b
+		f.partSizes = append(f.partSizes, len(b))
+		w.Header().Set("ETag", fmt.Sprintf(`"e%d"`, n))
+	case r.Method == http.MethodPut:
+		b, _ := io.ReadAll(r.Body)
+		f.objects[key] = b
+		f.types[key] = r.Header.Get("Content-Type")
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		id := fmt.Sprintf("up%d", len(f.uploads)+1)
+		f.uploads[id] = map[int][]byte{}
+		f.types[key] = r.Header.Get("Content-Type")
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+	case r.Method == http.MethodPost && q.Has("uploadId"):
+		var req struct {
+			Parts []struct {
+				PartNumber int
+				ETag       string
+			} `xml:"Part"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var buf bytes.Buffer
+		for _, p := range req.Parts {
+			if p.ETag != fmt.Sprintf(`"e%d"`, p.PartNumber) {
+				fmt.Fprint(w, "<Error><Code>InvalidPart</Code></Error>")
+				return
+			}
+			buf.Write(f.uploads[q.Get("uploadId")][p.PartNumber])
+		}
+		f.objects[key] = buf.Bytes()
+		delete(f.uploads, q.Get("uploadId"))
+		fmt.Fprint(w, "<CompleteMultipartUploadResult/>")
+	case r.Method == http.MethodDelete && q.Has("uploadId"):
+		delete(f.uploads, q.Get("uploadId"))
+		f.aborted++
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// list implements ListObjectsV2 with a "/" delimiter, returning at most
+// max-keys entries per page.
+func (f *fakeS3Server) list(w http.ResponseWriter, q map[string][]string) {
+	get := func(k string) string {
+		if v := q[k]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	prefix := get("prefix")
+	limit, _ := strconv.Atoi(get("max-keys"))
+	var names []string
+	seen := map[string]bool{}
+	for k := range f.objects {
+		rest, ok := strings.CutPrefix(k, prefix)
+		if !ok {
+			continue
+		}
+		if i := strings.Index(rest, "/"); i >= 0 {
+			rest = rest[:i+1]
+		}
+		if !seen[rest] {
+			seen[rest] = true
+			names = append(names, rest)
+		}
+	}
+	sort.Strings(names)
+	start, _ := strconv.Atoi(get("continuation-token"))
+	names = names[start:]
+	type content struct {
+		Key          string
+		Size         int
+		LastModified string
+	}
+	var res struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		IsTruncated           bool
+		NextContinuationToken string    `xml:",omitempty"`
+		Contents              []content `xml:"Contents"`
+		CommonPrefixes        []struct{ Prefix string }
+	}
+	if limit > 0 && len(names) > limit {
+		names = names[:limit]
+		res.IsTruncated = true
+		res.NextContinuationToken = strconv.Itoa(start + limit)
+	}
+	for _, n := range names {
+		if strings.HasSuffix(n, "/") {
+			res.CommonPrefixes = append(res.CommonPrefixes, struct{ Prefix string }{prefix + n})
+		} else {
+			res.Contents = append(res.Contents, content{prefix + n, len(f.objects[prefix+n]), "2001-09-09T01:46:40.000Z"})
+		}
+	}
+	xml.NewEncoder(w).Encode(res)
+}
+
+func TestS3WriteReadAndStat(t *testing.T) {
+	s, f := newS3Test(t, 0)
+	ctx := context.Background()
+	if err := s.Write(ctx, "docs/a.txt", strings.NewReader("hello world")); err != nil {
+		t.Fatal(err)
+	}
+	if string(f.objects["p/docs/a.txt"]) != "hello world" || f.types["p/docs/a.txt"] != "text/plain; charset=utf-8" {
+		t.Fatalf("object = %q (%s)", f.objects["p/docs/a.txt"], f.types["p/docs/a.txt"])
+	}
+	b, err := fs.ReadFile(s, "docs/a.txt")
+	if err != nil || string(b) != "hello world" {
+		t.Fatalf("read = %q %v", b, err)
+	}
+	info, err := s.Stat("docs/a.txt")
+	if err != nil || info.Size() != 11 || info.IsDir() || info.Name() != "a.txt" || info.ModTime().Unix() != 1e9 {
+		t.Fatalf("stat = %+v %v", info, err)
+	}
+	if info, err := s.Stat("docs"); err != nil || !info.IsDir() {
+		t.Fatalf("docs should be a directory: %v", err)
+	}
+	if _, err := s.Open("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected ErrNotExist, got %v", err)
+	}
+	if _, err := s.Open("../x"); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("expected ErrInvalid, got %v", err)
+	}
+}
+
+func TestS3SeekAndReadAtUseRanges(t *testing.T) {
+	s, f := newS3Test(t, 0)
+	f.objects["p/v.bin"] = []byte("0123456789")
+	file, err := s.Open("v.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rs := file.(io.ReadSeeker)
+	if _, err := rs.Seek(6, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(rs)
+	if err != nil || string(b) != "6789" {
+		t.Fatalf("read after seek = %q %v", b, err)
+	}
+	p := make([]byte, 4)
+	if n, err := file.(io.ReaderAt).ReadAt(p, 2); err != nil || string(p[:n]) != "2345" {
+		t.Fatalf("ReadAt = %q %v", p[:n], err)
+	}
+	if n, err := file.(io.ReaderAt).ReadAt(p, 8); err != io.EOF || string(p[:n]) != "89" {
+		t.Fatalf("ReadAt at end = %q %v", p[:n], err)
+	}
+	if fmt.Sprint(f.ranges) != "[bytes=6- bytes=2-5 bytes=8-9]" {
+		t.Fatalf("ranges = %v", f.ranges)
+	}
+}
+
+func TestS3ReadDirPaginates(t *testing.T) {
+	s, f := newS3Test(t, 0)
+	for i := 0; i < 1002; i++ {
+		f.objects[fmt.Sprintf("p/many/%04d", i)] = nil
+	}
+	f.objects["p/top.txt"] = []byte("x")
+	f.objects["p/sub/"] = nil // directory marker
+	f.objects["p/sub/inner.txt"] = []byte("y")
+
+	entries, err := s.ReadDir("many")
+	if err != nil || len(entries) != 1002 || entries[1001].Name() != "1001" {
+		t.Fatalf("entries = %d %v", len(entries), err)
+	}
+	root, err := s.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range root {
+		names = append(names, fmt.Sprintf("%s:%v", e.Name(), e.IsDir()))
+	}
+	if strings.Join(names, " ") != "many:true sub:true top.txt:false" {
+		t.Fatalf("root = %v", names)
+	}
+	sub, err := s.ReadDir("sub")
+	if err != nil || len(sub) != 1 || sub[0].Name() != "inner.txt" {
+		t.Fatalf("sub = %v %v", sub, err)
+	}
+	if _, err := s.ReadDir("nope"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected ErrNotExist, got %v", err)
+	}
+}
+
+func TestS3MultipartWrite(t *testing.T) {
+	s, f := newS3Test(t, 1) // raised to the 5 MiB minimum
+	data := bytes.Repeat([]byte("abcdefghij"), (minS3PartSize*2+100)/10)
+	if err := s.Write(context.Background(), "big.bin", bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(f.objects["p/big.bin"], data) {
+		t.Fatalf("multipart object mismatch: %d bytes", len(f.objects["p/big.bin"]))
+	}
+	if fmt.Sprint(f.partSizes) != fmt.Sprint([]int{minS3PartSize, minS3PartSize, 100}) {
+		t.Fatalf("part sizes = %v", f.partSizes)
+	}
+	if f.types["p/big.bin"] != "application/octet-stream" {
+		t.Fatalf("content type = %q", f.types["p/big.bin"])
+	}
+}
+
+func TestS3MultipartWriteAbortsOnError(t *testing.T) {
+	s, f := newS3Test(t, 0)
+	r := io.MultiReader(bytes.NewReader(make([]byte, DefaultS3PartSize)), iotestErrReader{})
+	if err := s.Write(context.Background(), "big.bin", r); err == nil {
+		t.Fatalf("expected read error")
+	}
+	if f.aborted != 1 || len(f.uploads) != 0 {
+		t.Fatalf("upload should be aborted: %d aborted, %d open", f.aborted, len(f.uploads))
+	}
+	if _, ok := f.objects["p/big.bin"]; ok {
+		t.Fatalf("object should not exist")
+	}
+}
+
+type iotestErrReader struct{}
+
+func (iotestErrReader) Read([]byte) (int, error) { return 0, errors.New("boom") }
+
+func TestS3Remove(t *testing.T) {
+	s, f := newS3Test(t, 0)
+	f.objects["p/a"] = []byte("x")
+	if err := s.Remove(context.Background(), "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Remove(context.Background(), "a"); err != nil {
+		t.Fatalf("removing a missing file: %v", err)
+	}
+	if len(f.objects) != 0 {
+		t.Fatalf("objects = %v", f.objects)
+	}
+}
+
+func TestS3ServesRangesThroughHTTPFS(t *testing.T) {
+	s, f := newS3Test(t, 0)
+	f.objects["p/site/index.html"] = []byte("<h1>hi</h1>")
+	f.objects["p/site/movie.mp4"] = []byte("0123456789")
+	srv := httptest.NewServer(http.FileServerFS(s))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/site/movie.mp4", nil)
+	req.Header.Set("Range", "bytes=2-4")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent || string(body) != "234" {
+		t.Fatalf("range = %d %q", resp.StatusCode, body)
+	}
+
+	resp, err = http.Get(srv.URL + "/site/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "<h1>hi</h1>" {
+		t.Fatalf("index = %q", body)
+	}
+}
+
+func TestS3PassesFSTest(t *testing.T) {
+	s, f := newS3Test(t, 0)
+	f.objects["p/a.txt"] = []byte("a")
+	f.objects["p/dir/b.txt"] = []byte("bb")
+	if err := fstest.TestFS(s, "a.txt", "dir/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewS3Panics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic")
+		}
+	}()
+	NewS3(S3Config{Endpoint: "http://localhost"})
+}
+
+func TestS3VirtualHostedURL(t *testing.T) {
+	var gotHost, gotPath string
+	s := NewS3(S3Config{Endpoint: "https://s3.example.com", Bucket: "b", HTTPClient: &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			gotHost, gotPath = r.URL.Host, r.URL.EscapedPath()
+			return &http.Response{StatusCode: http.StatusNoContent, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}),
+	}})
+	if err := s.Remove(context.Background(), "a b/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if gotHost != "b.s3.example.com" || gotPath != "/a%20b/c.txt" {
+		t.Fatalf("url = %s %s", gotHost, gotPath)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
@@ -0,0 +1,47 @@
+// Package storage abstracts the file trees flash serves from and writes to,
+// so static files, file responses and uploads can live on local disk or in
+// S3-compatible object storage without changing handlers.
+//
+// An FS is an io/fs file system with Stat and ReadDir, plus Write and Remove.
+// It can therefore be passed anywhere an fs.FS is accepted, such as
+// app.StaticFS or Ctx.FileFS, and to Ctx.SaveUploadedFileFS for uploads. Files
+// opened from the provided implementations are seekable, so responses
+// support Range requests.
+//
+// Example:
+//
+//	media := storage.NewS3(storage.S3Config{
+//		Endpoint:        "http://localhost:9000",
+//		Bucket:          "media",
+//		AccessKeyID:     os.Getenv("S3_ACCESS_KEY"),
+//		SecretAccessKey: os.Getenv("S3_SECRET_KEY"),
+//		PathStyle:       true, // MinIO
+//	})
+//	app.StaticFS("/media", media)
+//	app.POST("/avatar", func(c flash.Ctx) error {
+//		fh := c.Request().MultipartForm.File["avatar"][0]
+//		return c.SaveUploadedFileFS(fh, media, "avatars/"+userID(c)+".png")
+//	})
+package storage
+
+import (
+	"context"
+	"io"
+	"io/fs"
+)
+
+// FS is a file tree that can be read through io/fs and written to. Names use
+// the io/fs conventions: slash-separated, unrooted and clean (see
+// fs.ValidPath).
+type FS interface {
+	fs.StatFS
+	fs.ReadDirFS
+
+	// Write stores the contents of r under name, replacing any existing file
+	// and creating parent directories as needed. Readers never observe a
+	// partially written file.
+	Write(ctx context.Context, name string, r io.Reader) error
+
+	// Remove deletes the file name. Removing a missing file is not an error.
+	Remove(ctx context.Context, name string) error
+}
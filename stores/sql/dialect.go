@@ -0,0 +1,93 @@
+// Package sqlstore provides database/sql backed stores for flash middleware,
+// for deployments that share state between instances through an existing
+// Postgres or MySQL database instead of Redis.
+//
+// The schema ships as embedded SQL; apply it once with Migrate (or copy the
+// files from Migrations into your own migration tool):
+//
+//	db, _ := sql.Open("pgx", dsn)
+//	if err := sqlstore.Migrate(ctx, db, sqlstore.Postgres); err != nil {
+//		log.Fatal(err)
+//	}
+//	limiter := sqlstore.NewRateLimitStrategy(db, sqlstore.Postgres, 100, time.Minute)
+//	app.Use(middleware.RateLimit(middleware.WithStrategy(limiter)))
+//
+// The package does not import any driver; register one (pgx, lib/pq,
+// go-sql-driver/mysql, ...) in your main package.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Dialect selects the SQL flavor used for queries and migrations.
+type Dialect int
+
+const (
+	// Postgres targets PostgreSQL 9.5+ ($n placeholders, ON CONFLICT).
+	Postgres Dialect = iota + 1
+	// MySQL targets MySQL 5.7+ and MariaDB (? placeholders, ON DUPLICATE KEY).
+	MySQL
+)
+
+// String returns the dialect name, which is also its migrations directory.
+func (d Dialect) String() string {
+	switch d {
+	case Postgres:
+		return "postgres"
+	case MySQL:
+		return "mysql"
+	}
+	return fmt.Sprintf("Dialect(%d)", int(d))
+}
+
+//go:embed migrations
+var migrations embed.FS
+
+// Migrations returns the embedded schema files for d, e.g. to feed them to an
+// external migration tool. Files are named NNN_description.sql.
+func Migrations(d Dialect) (fs.FS, error) {
+	if d != Postgres && d != MySQL {
+		return nil, fmt.Errorf("sqlstore: unsupported dialect %v", d)
+	}
+	return fs.Sub(migrations, path.Join("migrations", d.String()))
+}
+
+// Migrate applies the embedded schema for d in file order. Every statement is
+// written to be idempotent (CREATE ... IF NOT EXISTS), so Migrate is safe to
+// run on each start-up.
+func Migrate(ctx context.Context, db *sql.DB, d Dialect) error {
+	fsys, err := Migrations(d)
+	if err != nil {
+		return err
+	}
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	for _, name := range files {
+		raw, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		// Statements run one by one: MySQL drivers reject multi-statement
+		// strings unless explicitly enabled.
+		for _, stmt := range strings.Split(string(raw), ";") {
+			if stmt = strings.TrimSpace(stmt); stmt == "" {
+				continue
+			}
+			if _, err := db.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("sqlstore: migration %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
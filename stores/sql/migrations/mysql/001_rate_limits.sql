@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS flash_rate_limits (
+    rl_key       VARCHAR(255) NOT NULL PRIMARY KEY,
+    window_start BIGINT NOT NULL,
+    hits         INT    NOT NULL,
+    INDEX flash_rate_limits_window_idx (window_start)
+);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS flash_rate_limits (
+    rl_key       VARCHAR(255) PRIMARY KEY,
+    window_start BIGINT  NOT NULL,
+    hits         INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS flash_rate_limits_window_idx ON flash_rate_limits (window_start);
@@ -0,0 +1,140 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// RateLimitStrategy is a fixed-window rate limiter whose counters live in the
+// flash_rate_limits table, so every app instance sharing the database
+// enforces one limit. It implements middleware.RateLimitStrategy.
+//
+// Each Allow is a single atomic upsert (Postgres) or a short transaction
+// (MySQL). Call DeleteExpired periodically to drop stale rows.
+//
+// Example:
+//
+//	limiter := sqlstore.NewRateLimitStrategy(db, sqlstore.MySQL, 1000, time.Hour)
+//	api.Use(middleware.RateLimit(middleware.WithStrategy(limiter)))
+type RateLimitStrategy struct {
+	db      *sql.DB
+	dialect Dialect
+	limit   int
+	window  time.Duration
+
+	// Timeout bounds each database round trip. Defaults to 1 second.
+	Timeout time.Duration
+
+	// FailClosed rejects requests when the database is unavailable. By
+	// default requests are allowed (fail open), favoring availability.
+	FailClosed bool
+
+	// OnError, when set, is called with database errors (e.g. for logging).
+	OnError func(err error)
+
+	now func() time.Time
+}
+
+// NewRateLimitStrategy returns a strategy allowing limit requests per key in
+// each window. It panics on an unsupported dialect.
+func NewRateLimitStrategy(db *sql.DB, d Dialect, limit int, window time.Duration) *RateLimitStrategy {
+	if d != Postgres && d != MySQL {
+		panic("sqlstore: unsupported dialect " + d.String())
+	}
+	if limit <= 0 {
+		limit = 1
+	}
+	if window <= 0 {
+		window = time.Minute
+	}
+	return &RateLimitStrategy{db: db, dialect: d, limit: limit, window: window, Timeout: time.Second, now: time.Now}
+}
+
+// Name identifies the strategy.
+func (s *RateLimitStrategy) Name() string { return "sql_fixed_window" }
+
+const (
+	pgHit = `INSERT INTO flash_rate_limits (rl_key, window_start, hits) VALUES ($1, $2, 1)
+ON CONFLICT (rl_key) DO UPDATE SET
+  hits = CASE WHEN flash_rate_limits.window_start = EXCLUDED.window_start THEN flash_rate_limits.hits + 1 ELSE 1 END,
+  window_start = EXCLUDED.window_start
+RETURNING hits`
+
+	// MySQL evaluates assignments left to right, so hits sees the old window_start.
+	mysqlHit = `INSERT INTO flash_rate_limits (rl_key, window_start, hits) VALUES (?, ?, 1)
+ON DUPLICATE KEY UPDATE
+  hits = IF(window_start = VALUES(window_start), hits + 1, 1),
+  window_start = VALUES(window_start)`
+	mysqlHits = `SELECT hits FROM flash_rate_limits WHERE rl_key = ?`
+
+	pgDeleteExpired    = `DELETE FROM flash_rate_limits WHERE window_start < $1`
+	mysqlDeleteExpired = `DELETE FROM flash_rate_limits WHERE window_start < ?`
+)
+
+// Allow records a hit for key and reports whether it is within the limit.
+func (s *RateLimitStrategy) Allow(key string) (bool, time.Duration) {
+	now := s.now()
+	start := now.Truncate(s.window)
+	ctx, cancel := context.WithTimeout(context.Background(), s.Timeout)
+	defer cancel()
+
+	hits, err := s.hit(ctx, key, start.UnixMilli())
+	if err != nil {
+		if s.OnError != nil {
+			s.OnError(err)
+		}
+		if s.FailClosed {
+			return false, s.window
+		}
+		return true, 0
+	}
+	if hits <= s.limit {
+		return true, 0
+	}
+	return false, start.Add(s.window).Sub(now)
+}
+
+// hit increments the counter for key in the window starting at start (ms).
+func (s *RateLimitStrategy) hit(ctx context.Context, key string, start int64) (int, error) {
+	var hits int
+	if s.dialect == Postgres {
+		err := s.db.QueryRowContext(ctx, pgHit, key, start).Scan(&hits)
+		return hits, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.ExecContext(ctx, mysqlHit, key, start); err != nil {
+		return 0, err
+	}
+	if err := tx.QueryRowContext(ctx, mysqlHits, key).Scan(&hits); err != nil {
+		return 0, err
+	}
+	return hits, tx.Commit()
+}
+
+// DeleteExpired removes counters from windows that have ended and returns
+// the number of rows deleted.
+//
+// Example:
+//
+//	go func() {
+//		for range time.Tick(10 * time.Minute) {
+//			_, _ = limiter.DeleteExpired(context.Background())
+//		}
+//	}()
+func (s *RateLimitStrategy) DeleteExpired(ctx context.Context) (int64, error) {
+	q := pgDeleteExpired
+	if s.dialect == MySQL {
+		q = mysqlDeleteExpired
+	}
+	cutoff := s.now().Truncate(s.window).UnixMilli()
+	res, err := s.db.ExecContext(ctx, q, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
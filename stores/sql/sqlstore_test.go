@@ -0,0 +1,213 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goflash/flash/v2/middleware"
+)
+
+var _ middleware.RateLimitStrategy = (*RateLimitStrategy)(nil)
+
+// fakeDB emulates the flash_rate_limits table by recognizing the package's
+// statements. It records every statement so dialect-specific SQL can be checked.
+type fakeDB struct {
+	mu    sync.Mutex
+	rows  map[string][2]int64 // key -> window_start, hits
+	stmts []string
+	fail  error
+}
+
+func newFakeDB() (*fakeDB, *sql.DB) {
+	f := &fakeDB{rows: map[string][2]int64{}}
+	return f, sql.OpenDB(f)
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ f *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("prepare unsupported") }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func (c fakeConn) ExecContext(_ context.Context, q string, args []driver.NamedValue) (driver.Result, error) {
+	f := c.f
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stmts = append(f.stmts, q)
+	if f.fail != nil {
+		return nil, f.fail
+	}
+	switch {
+	case strings.HasPrefix(q, "CREATE"):
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(q, "INSERT"):
+		f.upsert(args[0].Value.(string), args[1].Value.(int64))
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(q, "DELETE"):
+		n := int64(0)
+		for k, v := range f.rows {
+			if v[0] < args[0].Value.(int64) {
+				delete(f.rows, k)
+				n++
+			}
+		}
+		return driver.RowsAffected(n), nil
+	}
+	return nil, errors.New("unexpected exec: " + q)
+}
+
+func (c fakeConn) QueryContext(_ context.Context, q string, args []driver.NamedValue) (driver.Rows, error) {
+	f := c.f
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stmts = append(f.stmts, q)
+	if f.fail != nil {
+		return nil, f.fail
+	}
+	key := args[0].Value.(string)
+	switch {
+	case strings.HasPrefix(q, "INSERT"):
+		f.upsert(key, args[1].Value.(int64))
+	case strings.HasPrefix(q, "SELECT"):
+	default:
+		return nil, errors.New("unexpected query: " + q)
+	}
+	return &fakeRows{vals: []int64{f.rows[key][1]}}, nil
+}
+
+func (f *fakeDB) upsert(key string, start int64) {
+	r, ok := f.rows[key]
+	if !ok || r[0] != start {
+		f.rows[key] = [2]int64{start, 1}
+		return
+	}
+	f.rows[key] = [2]int64{start, r[1] + 1}
+}
+
+type fakeRows struct{ vals []int64 }
+
+func (r *fakeRows) Columns() []string { return []string{"hits"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.vals) == 0 {
+		return io.EOF
+	}
+	dest[0], r.vals = r.vals[0], r.vals[1:]
+	return nil
+}
+
+func TestRateLimitStrategy_FixedWindow(t *testing.T) {
+	for _, d := range []Dialect{Postgres, MySQL} {
+		f, db := newFakeDB()
+		s := NewRateLimitStrategy(db, d, 2, time.Minute)
+		now := time.Date(2024, 1, 1, 10, 0, 10, 0, time.UTC)
+		s.now = func() time.Time { return now }
+
+		for i := 0; i < 2; i++ {
+			if ok, _ := s.Allow("ip1"); !ok {
+				t.Fatalf("%v: request %d denied", d, i)
+			}
+		}
+		ok, retry := s.Allow("ip1")
+		if ok || retry != 50*time.Second {
+			t.Fatalf("%v: third request: ok=%v retry=%v", d, ok, retry)
+		}
+		if ok, _ := s.Allow("ip2"); !ok {
+			t.Fatalf("%v: keys not independent", d)
+		}
+		now = now.Add(time.Minute)
+		if ok, _ := s.Allow("ip1"); !ok {
+			t.Fatalf("%v: new window denied", d)
+		}
+
+		placeholder := "$1"
+		if d == MySQL {
+			placeholder = "?"
+		}
+		if !strings.Contains(f.stmts[0], placeholder) {
+			t.Fatalf("%v: wrong dialect SQL: %s", d, f.stmts[0])
+		}
+
+		// ip2's row is from the previous window.
+		n, err := s.DeleteExpired(context.Background())
+		if err != nil || n != 1 {
+			t.Fatalf("%v: DeleteExpired = %d, %v", d, n, err)
+		}
+		if s.Name() != "sql_fixed_window" {
+			t.Fatalf("name = %s", s.Name())
+		}
+	}
+}
+
+func TestRateLimitStrategy_DatabaseErrors(t *testing.T) {
+	f, db := newFakeDB()
+	f.fail = errors.New("db down")
+	var seen error
+	s := NewRateLimitStrategy(db, Postgres, 1, time.Minute)
+	s.OnError = func(err error) { seen = err }
+	if ok, _ := s.Allow("k"); !ok || seen == nil {
+		t.Fatalf("expected fail-open with OnError, ok=%v err=%v", ok, seen)
+	}
+	s.FailClosed = true
+	if ok, retry := s.Allow("k"); ok || retry != time.Minute {
+		t.Fatalf("expected fail-closed, ok=%v retry=%v", ok, retry)
+	}
+	m := NewRateLimitStrategy(db, MySQL, 1, 0)
+	if ok, _ := m.Allow("k"); !ok {
+		t.Fatalf("mysql path should fail open")
+	}
+	if _, err := s.DeleteExpired(context.Background()); err == nil {
+		t.Fatalf("expected DeleteExpired error")
+	}
+}
+
+func TestNewRateLimitStrategy_PanicsOnDialect(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic")
+		}
+	}()
+	NewRateLimitStrategy(nil, Dialect(9), 1, time.Second)
+}
+
+func TestMigrate(t *testing.T) {
+	for _, d := range []Dialect{Postgres, MySQL} {
+		f, db := newFakeDB()
+		if err := Migrate(context.Background(), db, d); err != nil {
+			t.Fatalf("%v: %v", d, err)
+		}
+		if len(f.stmts) == 0 || !strings.Contains(f.stmts[0], "CREATE TABLE IF NOT EXISTS flash_rate_limits") {
+			t.Fatalf("%v: statements %q", d, f.stmts)
+		}
+		for _, st := range f.stmts {
+			if strings.Contains(st, ";") {
+				t.Fatalf("%v: multi-statement exec: %q", d, st)
+			}
+		}
+		f.fail = errors.New("denied")
+		if err := Migrate(context.Background(), db, d); err == nil || !strings.Contains(err.Error(), "001_rate_limits.sql") {
+			t.Fatalf("%v: expected migration error, got %v", d, err)
+		}
+	}
+	if err := Migrate(context.Background(), nil, Dialect(0)); err == nil {
+		t.Fatalf("expected unsupported dialect error")
+	}
+	if Dialect(7).String() != "Dialect(7)" {
+		t.Fatalf("String")
+	}
+}
@@ -0,0 +1,161 @@
+package tus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DiskStore keeps uploads in a local directory: the data of upload ID in
+// "ID.bin" and its state in "ID.info" (JSON). It suits single instances or
+// directories on shared storage.
+type DiskStore struct {
+	dir string
+}
+
+// NewDiskStore returns a store writing to dir, which is created on first use.
+func NewDiskStore(dir string) *DiskStore { return &DiskStore{dir: dir} }
+
+// Path returns the file holding the data of upload id, for reading completed
+// uploads (e.g. from Config.OnComplete).
+func (s *DiskStore) Path(id string) string { return filepath.Join(s.dir, id+".bin") }
+
+func (s *DiskStore) infoPath(id string) string { return filepath.Join(s.dir, id+".info") }
+
+// Create implements Store.
+func (s *DiskStore) Create(_ context.Context, u Upload) error {
+	if !validID(u.ID) {
+		return ErrNotFound
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.Path(u.ID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return s.writeInfo(u)
+}
+
+// Get implements Store.
+func (s *DiskStore) Get(_ context.Context, id string) (Upload, error) {
+	if !validID(id) {
+		return Upload{}, ErrNotFound
+	}
+	return s.readInfo(s.infoPath(id))
+}
+
+// Append implements Store.
+func (s *DiskStore) Append(ctx context.Context, id string, offset int64, r io.Reader) (int64, error) {
+	u, err := s.Get(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if offset != u.Offset {
+		return 0, ErrOffsetMismatch
+	}
+	f, err := os.OpenFile(s.Path(id), os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(io.NewOffsetWriter(f, offset), r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if n > 0 {
+		u.Offset += n
+		if werr := s.writeInfo(u); werr != nil {
+			return 0, werr
+		}
+	}
+	return n, err
+}
+
+// Save implements Store.
+func (s *DiskStore) Save(ctx context.Context, u Upload) error {
+	cur, err := s.Get(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	cur.Size, cur.Metadata, cur.ExpiresAt = u.Size, u.Metadata, u.ExpiresAt
+	return s.writeInfo(cur)
+}
+
+// Delete implements Store.
+func (s *DiskStore) Delete(_ context.Context, id string) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+	err := os.Remove(s.infoPath(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(s.Path(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Expired implements Store.
+func (s *DiskStore) Expired(_ context.Context, t time.Time) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".info")
+		if !ok || e.IsDir() {
+			continue
+		}
+		u, err := s.readInfo(filepath.Join(s.dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		if !u.Done() && !u.ExpiresAt.IsZero() && u.ExpiresAt.Before(t) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (s *DiskStore) readInfo(path string) (Upload, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Upload{}, ErrNotFound
+	}
+	if err != nil {
+		return Upload{}, err
+	}
+	var u Upload
+	err = json.Unmarshal(b, &u)
+	return u, err
+}
+
+// writeInfo replaces the state file atomically.
+func (s *DiskStore) writeInfo(u Upload) error {
+	b, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	tmp := s.infoPath(u.ID) + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.infoPath(u.ID))
+}
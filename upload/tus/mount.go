@@ -0,0 +1,37 @@
+package tus
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/goflash/flash/v2"
+)
+
+// Mount serves the tus resumable upload protocol under prefix of a, storing
+// uploads in store: POST prefix creates an upload and HEAD, PATCH and DELETE
+// prefix/:id resume, append to and cancel it. cfg optionally tunes the
+// handler; its BasePath is set to prefix. The returned handler exposes
+// CleanupExpired for removing abandoned uploads.
+//
+// The routes are registered like any other, so global middleware and mws,
+// such as authentication, run for every tus request.
+//
+// Example:
+//
+//	store := tus.NewDiskStore("./uploads")
+//	h := tus.Mount(a, "/files", store, tus.Config{MaxSize: 1 << 30}, requireUser)
+//	// tus-js-client: new tus.Upload(file, {endpoint: "/files"})
+func Mount(a flash.App, prefix string, store Store, cfg Config, mws ...flash.Middleware) *Handler {
+	prefix = path.Clean("/" + strings.Trim(prefix, "/"))
+	cfg.BasePath = prefix
+	h := New(store, cfg)
+	fh := flash.WrapHTTP(h)
+	a.Handle(http.MethodPost, prefix, fh, mws...)
+	a.Handle(http.MethodOptions, prefix, fh, mws...)
+	item := path.Join(prefix, ":id")
+	for _, m := range []string{http.MethodHead, http.MethodPatch, http.MethodDelete, http.MethodOptions} {
+		a.Handle(m, item, fh, mws...)
+	}
+	return h
+}
@@ -0,0 +1,68 @@
+package tus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goflash/flash/v2"
+)
+
+func TestMount(t *testing.T) {
+	a := flash.New()
+	var global, route []string
+	a.Use(func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			global = append(global, c.Method())
+			return next(c)
+		}
+	})
+	requireToken := func(next flash.Handler) flash.Handler {
+		return func(c flash.Ctx) error {
+			route = append(route, c.Method())
+			if c.Request().Header.Get("Authorization") != "Bearer ok" {
+				return c.String(http.StatusUnauthorized, "no")
+			}
+			return next(c)
+		}
+	}
+	Mount(a, "/files/", NewDiskStore(t.TempDir()), Config{}, requireToken)
+
+	do := func(method, target, body string, hdr ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Tus-Resumable", Version)
+		req.Header.Set("Authorization", "Bearer ok")
+		for i := 0; i+1 < len(hdr); i += 2 {
+			req.Header.Set(hdr[i], hdr[i+1])
+		}
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodOptions, "/files", ""); rec.Code != http.StatusNoContent || rec.Header().Get("Tus-Version") == "" {
+		t.Fatalf("options: %d", rec.Code)
+	}
+	rec := do(http.MethodPost, "/files", "", "Upload-Length", "3")
+	loc := rec.Header().Get("Location")
+	if rec.Code != http.StatusCreated || !strings.HasPrefix(loc, "/files/") {
+		t.Fatalf("create: %d %q", rec.Code, loc)
+	}
+	rec = do(http.MethodPatch, loc, "abc", "Content-Type", "application/offset+octet-stream", "Upload-Offset", "0")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Upload-Offset") != "3" {
+		t.Fatalf("patch: %d", rec.Code)
+	}
+	if rec := do(http.MethodHead, loc, ""); rec.Code != http.StatusOK || rec.Header().Get("Upload-Length") != "3" {
+		t.Fatalf("head: %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, loc, "", "Authorization", "Bearer bad"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("unauthorized delete: %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, loc, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: %d", rec.Code)
+	}
+	if len(global) != 6 || len(route) != 6 {
+		t.Fatalf("middleware skipped: global=%v route=%v", global, route)
+	}
+}
@@ -0,0 +1,259 @@
+package tus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// DefaultS3PartSize is the part size used by S3Store, the minimum S3 accepts
+// for all but the last part of a multipart upload.
+const DefaultS3PartSize = 5 << 20
+
+// S3Part identifies an uploaded part of a multipart upload.
+type S3Part struct {
+	Number int    `json:"n"`
+	ETag   string `json:"etag"`
+}
+
+// S3Client is the subset of the S3 API used by S3Store. Adapt the AWS SDK or
+// any S3-compatible client (MinIO, R2, GCS interop) to it; keys are relative
+// to the bucket the adapter is bound to.
+type S3Client interface {
+	// PutObject stores a small object.
+	PutObject(ctx context.Context, key string, body io.Reader, size int64) error
+	// GetObject opens an object, returning ErrNotFound if it does not exist.
+	GetObject(ctx context.Context, key string) (io.ReadCloser, error)
+	// DeleteObject removes an object; missing objects are not an error.
+	DeleteObject(ctx context.Context, key string) error
+	// ListObjects returns the keys starting with prefix.
+	ListObjects(ctx context.Context, prefix string) ([]string, error)
+
+	// CreateMultipartUpload starts a multipart upload and returns its ID.
+	CreateMultipartUpload(ctx context.Context, key string) (string, error)
+	// UploadPart uploads part number (from 1) and returns its ETag.
+	UploadPart(ctx context.Context, key, uploadID string, number int, body io.Reader, size int64) (string, error)
+	// CompleteMultipartUpload assembles the parts into the object.
+	CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []S3Part) error
+	// AbortMultipartUpload discards a multipart upload and its parts.
+	AbortMultipartUpload(ctx context.Context, key, uploadID string) error
+}
+
+// S3Store keeps uploads in object storage as S3 multipart uploads. Data is
+// sent in parts of PartSize bytes; a trailing chunk smaller than a part is
+// parked in a temporary "ID.part" object until more data arrives, and the
+// object "ID" appears once the upload completes. State is kept in "ID.info".
+type S3Store struct {
+	client S3Client
+	prefix string
+
+	// PartSize is the size of each multipart part. Defaults to
+	// DefaultS3PartSize; set it before the store is used.
+	PartSize int64
+}
+
+// s3Info is the state object of an upload.
+type s3Info struct {
+	Upload      Upload   `json:"upload"`
+	MultipartID string   `json:"multipart_id"`
+	Parts       []S3Part `json:"parts,omitempty"`
+	Pending     int64    `json:"pending,omitempty"` // size of the ".part" object
+}
+
+// NewS3Store returns a store keeping its objects under prefix (e.g.
+// "uploads/").
+func NewS3Store(client S3Client, prefix string) *S3Store {
+	return &S3Store{client: client, prefix: prefix, PartSize: DefaultS3PartSize}
+}
+
+// Key returns the object key of a completed upload.
+func (s *S3Store) Key(id string) string { return s.prefix + id }
+
+func (s *S3Store) infoKey(id string) string    { return s.prefix + id + ".info" }
+func (s *S3Store) pendingKey(id string) string { return s.prefix + id + ".part" }
+
+// Create implements Store.
+func (s *S3Store) Create(ctx context.Context, u Upload) error {
+	mid, err := s.client.CreateMultipartUpload(ctx, s.Key(u.ID))
+	if err != nil {
+		return err
+	}
+	return s.save(ctx, s3Info{Upload: u, MultipartID: mid})
+}
+
+// Get implements Store.
+func (s *S3Store) Get(ctx context.Context, id string) (Upload, error) {
+	info, err := s.load(ctx, id)
+	return info.Upload, err
+}
+
+// Append implements Store. The state is only updated once the data is stored,
+// so a failed call leaves the upload as it was.
+func (s *S3Store) Append(ctx context.Context, id string, offset int64, r io.Reader) (int64, error) {
+	info, err := s.load(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if offset != info.Upload.Offset {
+		return 0, ErrOffsetMismatch
+	}
+	key := s.Key(id)
+	cr := &countingReader{r: r}
+	src := io.Reader(cr)
+	hadPending := info.Pending > 0
+	if hadPending {
+		body, err := s.client.GetObject(ctx, s.pendingKey(id))
+		if err != nil {
+			return 0, err
+		}
+		pending, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return 0, err
+		}
+		src = io.MultiReader(bytes.NewReader(pending), cr)
+	}
+
+	partSize := s.PartSize
+	if partSize <= 0 {
+		partSize = DefaultS3PartSize
+	}
+	buf := make([]byte, partSize)
+	var readErr error
+	for {
+		n, err := io.ReadFull(src, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			readErr = err
+		}
+		done := info.Upload.Size >= 0 && offset+cr.n == info.Upload.Size
+		switch {
+		case n == len(buf) || (done && (n > 0 || len(info.Parts) == 0)):
+			num := len(info.Parts) + 1
+			etag, err := s.client.UploadPart(ctx, key, info.MultipartID, num, bytes.NewReader(buf[:n]), int64(n))
+			if err != nil {
+				return 0, err
+			}
+			info.Parts = append(info.Parts, S3Part{Number: num, ETag: etag})
+			info.Pending = 0
+		case n > 0:
+			if err := s.client.PutObject(ctx, s.pendingKey(id), bytes.NewReader(buf[:n]), int64(n)); err != nil {
+				return 0, err
+			}
+			info.Pending = int64(n)
+		default:
+			info.Pending = 0
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	info.Upload.Offset = offset + cr.n
+	if info.Upload.Done() {
+		if err := s.client.CompleteMultipartUpload(ctx, key, info.MultipartID, info.Parts); err != nil {
+			return 0, err
+		}
+	}
+	if err := s.save(ctx, info); err != nil {
+		return 0, err
+	}
+	if hadPending && info.Pending == 0 {
+		_ = s.client.DeleteObject(ctx, s.pendingKey(id))
+	}
+	return cr.n, readErr
+}
+
+// Save implements Store.
+func (s *S3Store) Save(ctx context.Context, u Upload) error {
+	info, err := s.load(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	info.Upload.Size, info.Upload.Metadata, info.Upload.ExpiresAt = u.Size, u.Metadata, u.ExpiresAt
+	return s.save(ctx, info)
+}
+
+// Delete implements Store. Unfinished multipart uploads are aborted.
+func (s *S3Store) Delete(ctx context.Context, id string) error {
+	info, err := s.load(ctx, id)
+	if err != nil {
+		return err
+	}
+	if info.Upload.Done() {
+		err = s.client.DeleteObject(ctx, s.Key(id))
+	} else {
+		err = s.client.AbortMultipartUpload(ctx, s.Key(id), info.MultipartID)
+	}
+	if err != nil {
+		return err
+	}
+	if info.Pending > 0 {
+		if err := s.client.DeleteObject(ctx, s.pendingKey(id)); err != nil {
+			return err
+		}
+	}
+	return s.client.DeleteObject(ctx, s.infoKey(id))
+}
+
+// Expired implements Store.
+func (s *S3Store) Expired(ctx context.Context, t time.Time) ([]string, error) {
+	keys, err := s.client.ListObjects(ctx, s.prefix)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, k := range keys {
+		id, ok := strings.CutSuffix(strings.TrimPrefix(k, s.prefix), ".info")
+		if !ok || !validID(id) {
+			continue
+		}
+		info, err := s.load(ctx, id)
+		if err != nil {
+			continue
+		}
+		if u := info.Upload; !u.Done() && !u.ExpiresAt.IsZero() && u.ExpiresAt.Before(t) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (s *S3Store) load(ctx context.Context, id string) (s3Info, error) {
+	var info s3Info
+	if !validID(id) {
+		return info, ErrNotFound
+	}
+	body, err := s.client.GetObject(ctx, s.infoKey(id))
+	if err != nil {
+		return info, err
+	}
+	defer body.Close()
+	if err := json.NewDecoder(body).Decode(&info); err != nil {
+		return info, errors.Join(errors.New("tus: corrupt upload state"), err)
+	}
+	return info, nil
+}
+
+func (s *S3Store) save(ctx context.Context, info s3Info) error {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return s.client.PutObject(ctx, s.infoKey(info.Upload.ID), bytes.NewReader(b), int64(len(b)))
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
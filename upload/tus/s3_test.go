@@ -0,0 +1,194 @@
+package tus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is an in-memory S3Client.
+type fakeS3 struct {
+	mu        sync.Mutex
+	objects   map[string][]byte
+	multipart map[string]map[int][]byte // upload ID -> parts
+	keys      map[string]string         // upload ID -> key
+	partSizes []int
+	seq       int
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: map[string][]byte{}, multipart: map[string]map[int][]byte{}, keys: map[string]string{}}
+}
+
+func (f *fakeS3) PutObject(_ context.Context, key string, body io.Reader, _ int64) error {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[key] = b
+	return nil
+}
+
+func (f *fakeS3) GetObject(_ context.Context, key string) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b, ok := f.objects[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (f *fakeS3) DeleteObject(_ context.Context, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.objects, key)
+	return nil
+}
+
+func (f *fakeS3) ListObjects(_ context.Context, prefix string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var keys []string
+	for k := range f.objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (f *fakeS3) CreateMultipartUpload(_ context.Context, key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	id := fmt.Sprintf("mp%d", f.seq)
+	f.multipart[id] = map[int][]byte{}
+	f.keys[id] = key
+	return id, nil
+}
+
+func (f *fakeS3) UploadPart(_ context.Context, _, uploadID string, number int, body io.Reader, _ int64) (string, error) {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.multipart[uploadID][number] = b
+	f.partSizes = append(f.partSizes, len(b))
+	return fmt.Sprintf("etag%d", number), nil
+}
+
+func (f *fakeS3) CompleteMultipartUpload(_ context.Context, key, uploadID string, parts []S3Part) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var buf bytes.Buffer
+	for _, p := range parts {
+		buf.Write(f.multipart[uploadID][p.Number])
+	}
+	f.objects[key] = buf.Bytes()
+	delete(f.multipart, uploadID)
+	return nil
+}
+
+func (f *fakeS3) AbortMultipartUpload(_ context.Context, _, uploadID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.multipart, uploadID)
+	return nil
+}
+
+func TestS3StoreMultipart(t *testing.T) {
+	s3 := newFakeS3()
+	store := NewS3Store(s3, "uploads/")
+	store.PartSize = 4
+	var done []Upload
+	h := New(store, Config{BasePath: "/files", OnComplete: func(_ context.Context, u Upload) { done = append(done, u) }})
+	hs := &harness{t: t, h: h}
+
+	loc := hs.create("Upload-Length", "11")
+	id := strings.TrimPrefix(loc, "/files/")
+	for _, step := range []struct{ offset, chunk, want string }{
+		{"0", "he", "2"},    // parked as pending
+		{"2", "llo w", "7"}, // pending + chunk: one part, 3 bytes pending
+		{"7", "orld", "11"}, // completes
+	} {
+		rec := hs.do(http.MethodPatch, loc, step.chunk, patch(step.offset)...)
+		if rec.Code != http.StatusNoContent || rec.Header().Get("Upload-Offset") != step.want {
+			t.Fatalf("patch %s: %d offset %s", step.chunk, rec.Code, rec.Header().Get("Upload-Offset"))
+		}
+	}
+	if got := string(s3.objects[store.Key(id)]); got != "hello world" {
+		t.Fatalf("object = %q", got)
+	}
+	if fmt.Sprint(s3.partSizes) != "[4 4 3]" {
+		t.Fatalf("part sizes = %v", s3.partSizes)
+	}
+	if _, ok := s3.objects["uploads/"+id+".part"]; ok {
+		t.Fatalf("pending object should be removed")
+	}
+	if len(done) != 1 {
+		t.Fatalf("OnComplete not called")
+	}
+}
+
+func TestS3StoreEmptyUploadAndDelete(t *testing.T) {
+	s3 := newFakeS3()
+	store := NewS3Store(s3, "")
+	ctx := context.Background()
+
+	if err := store.Create(ctx, Upload{ID: "empty", Size: 0}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := store.Append(ctx, "empty", 0, strings.NewReader("")); err != nil || n != 0 {
+		t.Fatalf("append: %d %v", n, err)
+	}
+	if b, ok := s3.objects["empty"]; !ok || len(b) != 0 {
+		t.Fatalf("empty upload should complete")
+	}
+
+	if err := store.Create(ctx, Upload{ID: "partial", Size: 100, ExpiresAt: time.Unix(10, 0)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Append(ctx, "partial", 0, strings.NewReader("abc")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Append(ctx, "partial", 0, strings.NewReader("abc")); err != ErrOffsetMismatch {
+		t.Fatalf("expected ErrOffsetMismatch, got %v", err)
+	}
+	ids, err := store.Expired(ctx, time.Unix(20, 0))
+	if err != nil || len(ids) != 1 || ids[0] != "partial" {
+		t.Fatalf("expired = %v %v", ids, err)
+	}
+	if err := store.Delete(ctx, "partial"); err != nil {
+		t.Fatal(err)
+	}
+	if len(s3.multipart) != 0 || len(s3.objects) != 2 { // empty + empty.info
+		t.Fatalf("leftovers: %v, %d multipart", s3.objects, len(s3.multipart))
+	}
+	if _, err := store.Get(ctx, "partial"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestS3StoreEmptyUploadViaHandler(t *testing.T) {
+	s3 := newFakeS3()
+	store := NewS3Store(s3, "")
+	var done int
+	hs := &harness{t: t, h: New(store, Config{BasePath: "/files", OnComplete: func(context.Context, Upload) { done++ }})}
+	loc := hs.create("Upload-Length", "0")
+	if _, ok := s3.objects[strings.TrimPrefix(loc, "/files/")]; !ok || done != 1 {
+		t.Fatalf("empty upload should be completed on creation")
+	}
+}
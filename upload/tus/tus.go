@@ -0,0 +1,508 @@
+// Package tus implements the server side of the tus resumable upload protocol
+// (https://tus.io, version 1.0.0) as a plain http.Handler.
+//
+// Clients create an upload with POST, learn how much the server has received
+// with HEAD and send the remaining bytes with PATCH, so interrupted uploads
+// resume where they stopped instead of starting over. The creation,
+// creation-with-upload, creation-defer-length, expiration and termination
+// extensions are supported. Upload data and state live in a Store: DiskStore
+// keeps them in a local directory and S3Store in any S3-compatible object
+// storage reached through the S3Client interface.
+//
+// Example:
+//
+//	store := tus.NewDiskStore("./uploads")
+//	h := tus.Mount(app, "/files", store, tus.Config{
+//		MaxSize: 5 << 30,
+//		OnComplete: func(ctx context.Context, u tus.Upload) {
+//			process(store.Path(u.ID), u.Metadata["filename"])
+//		},
+//	}, requireUser)
+//	task := middleware.DefaultJanitor.Schedule(time.Hour, func(time.Time) {
+//		_, _ = h.CleanupExpired(context.Background())
+//	})
+//	defer task.Stop()
+//
+// The handler does not authenticate clients; Mount registers regular routes,
+// so authenticate them with global or route middleware as for any other
+// endpoint.
+package tus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version is the tus protocol version implemented by Handler.
+const Version = "1.0.0"
+
+// Extensions lists the tus extensions implemented by Handler.
+const Extensions = "creation,creation-with-upload,creation-defer-length,expiration,termination"
+
+// offsetContentType is the media type of PATCH bodies.
+const offsetContentType = "application/offset+octet-stream"
+
+// Errors returned by stores.
+var (
+	// ErrNotFound is returned for unknown upload IDs.
+	ErrNotFound = errors.New("tus: upload not found")
+	// ErrOffsetMismatch is returned by Store.Append when the given offset is
+	// not the upload's current offset.
+	ErrOffsetMismatch = errors.New("tus: offset mismatch")
+)
+
+// Upload describes an upload in progress or completed.
+type Upload struct {
+	ID        string            `json:"id"`
+	Size      int64             `json:"size"`   // total length in bytes; -1 while deferred
+	Offset    int64             `json:"offset"` // bytes received so far
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	ExpiresAt time.Time         `json:"expires_at,omitempty"` // zero when the upload does not expire
+}
+
+// Done reports whether all bytes of the upload have been received.
+func (u Upload) Done() bool { return u.Size >= 0 && u.Offset == u.Size }
+
+// Store persists uploads. Handler serializes requests for the same upload
+// within a process; implementations must be safe for concurrent use across
+// different uploads.
+type Store interface {
+	// Create records a new, empty upload.
+	Create(ctx context.Context, u Upload) error
+	// Get returns the upload with the given ID, or ErrNotFound.
+	Get(ctx context.Context, id string) (Upload, error)
+	// Append writes r at offset, which must equal the upload's current offset
+	// (ErrOffsetMismatch otherwise), and advances the offset by the number of
+	// bytes written. When r fails, the bytes received before the failure are
+	// kept and counted, so the client can resume after them.
+	Append(ctx context.Context, id string, offset int64, r io.Reader) (int64, error)
+	// Save updates the Size, Metadata and ExpiresAt of an upload. The offset
+	// is maintained by Append.
+	Save(ctx context.Context, u Upload) error
+	// Delete removes an upload and its data.
+	Delete(ctx context.Context, id string) error
+	// Expired returns the IDs of unfinished uploads that expired before t.
+	Expired(ctx context.Context, t time.Time) ([]string, error)
+}
+
+// Config configures a Handler.
+type Config struct {
+	// BasePath is the URL path the handler is mounted at, such as "/files".
+	// Upload URLs are BasePath + "/" + ID. Mount sets it.
+	BasePath string
+
+	// MaxSize caps the length of an upload in bytes. Zero means no limit.
+	MaxSize int64
+
+	// Expiration is how long an unfinished upload survives without receiving
+	// data. Defaults to 24 hours; negative disables expiration.
+	Expiration time.Duration
+
+	// OnComplete is called once an upload has received all of its bytes.
+	OnComplete func(ctx context.Context, u Upload)
+
+	now func() time.Time // for tests
+}
+
+// Handler serves the tus protocol for one Store.
+type Handler struct {
+	store Store
+	cfg   Config
+
+	mu   sync.Mutex
+	busy map[string]struct{} // uploads with a request in progress
+}
+
+// New returns a Handler serving store.
+func New(store Store, cfg Config) *Handler {
+	if store == nil {
+		panic("tus: store is required")
+	}
+	cfg.BasePath = "/" + strings.Trim(cfg.BasePath, "/")
+	if cfg.Expiration == 0 {
+		cfg.Expiration = 24 * time.Hour
+	}
+	if cfg.now == nil {
+		cfg.now = time.Now
+	}
+	return &Handler{store: store, cfg: cfg, busy: make(map[string]struct{})}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", Version)
+	if r.Method == http.MethodOptions {
+		h.options(w)
+		return
+	}
+	if r.Header.Get("Tus-Resumable") != Version {
+		w.Header().Set("Tus-Version", Version)
+		http.Error(w, "unsupported tus version", http.StatusPreconditionFailed)
+		return
+	}
+
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, h.cfg.BasePath), "/")
+	if id == "" {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST, OPTIONS")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		h.create(w, r)
+		return
+	}
+	if !validID(id) {
+		http.NotFound(w, r)
+		return
+	}
+	if !h.lock(id) {
+		http.Error(w, "upload is busy", http.StatusLocked)
+		return
+	}
+	defer h.unlock(id)
+
+	switch r.Method {
+	case http.MethodHead:
+		h.head(w, r, id)
+	case http.MethodPatch:
+		h.patch(w, r, id)
+	case http.MethodDelete:
+		h.delete(w, r, id)
+	default:
+		w.Header().Set("Allow", "HEAD, PATCH, DELETE, OPTIONS")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// CleanupExpired deletes unfinished uploads whose expiration has passed and
+// returns how many were removed. Run it periodically, e.g. with a
+// middleware.Janitor task.
+func (h *Handler) CleanupExpired(ctx context.Context) (int, error) {
+	ids, err := h.store.Expired(ctx, h.cfg.now())
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, id := range ids {
+		if !h.lock(id) {
+			continue // being written to; a later run will catch it if still expired
+		}
+		err := h.store.Delete(ctx, id)
+		h.unlock(id)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (h *Handler) options(w http.ResponseWriter) {
+	hdr := w.Header()
+	hdr.Set("Tus-Version", Version)
+	hdr.Set("Tus-Extension", Extensions)
+	if h.cfg.MaxSize > 0 {
+		hdr.Set("Tus-Max-Size", strconv.FormatInt(h.cfg.MaxSize, 10))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
+	u := Upload{Size: -1, CreatedAt: h.cfg.now()}
+	if v := r.Header.Get("Upload-Length"); v != "" {
+		size, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || size < 0 {
+			http.Error(w, "invalid Upload-Length", http.StatusBadRequest)
+			return
+		}
+		if h.cfg.MaxSize > 0 && size > h.cfg.MaxSize {
+			http.Error(w, "upload too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		u.Size = size
+	} else if r.Header.Get("Upload-Defer-Length") != "1" {
+		http.Error(w, "missing Upload-Length", http.StatusBadRequest)
+		return
+	}
+	md, err := parseMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		http.Error(w, "invalid Upload-Metadata", http.StatusBadRequest)
+		return
+	}
+	u.Metadata = md
+	id, err := newID()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	u.ID = id
+	h.touch(&u)
+	if err := h.store.Create(r.Context(), u); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Location", h.cfg.BasePath+"/"+id)
+	if u.Done() { // empty upload: let the store finalize it
+		if _, err := h.store.Append(r.Context(), id, 0, http.NoBody); err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		if h.cfg.OnComplete != nil {
+			h.cfg.OnComplete(r.Context(), u)
+		}
+	}
+
+	if r.Header.Get("Content-Type") == offsetContentType {
+		// creation-with-upload: the body holds the first chunk.
+		if !h.lock(id) {
+			http.Error(w, "upload is busy", http.StatusLocked)
+			return
+		}
+		defer h.unlock(id)
+		var ok bool
+		if u, ok = h.write(w, r, u); !ok {
+			return
+		}
+		w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	}
+	setExpires(w, u)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (h *Handler) head(w http.ResponseWriter, r *http.Request, id string) {
+	u, ok := h.get(w, r, id)
+	if !ok {
+		return
+	}
+	hdr := w.Header()
+	hdr.Set("Cache-Control", "no-store")
+	hdr.Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	if u.Size < 0 {
+		hdr.Set("Upload-Defer-Length", "1")
+	} else {
+		hdr.Set("Upload-Length", strconv.FormatInt(u.Size, 10))
+	}
+	if len(u.Metadata) > 0 {
+		hdr.Set("Upload-Metadata", formatMetadata(u.Metadata))
+	}
+	setExpires(w, u)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) patch(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Header.Get("Content-Type") != offsetContentType {
+		http.Error(w, "Content-Type must be "+offsetContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "invalid Upload-Offset", http.StatusBadRequest)
+		return
+	}
+	u, ok := h.get(w, r, id)
+	if !ok {
+		return
+	}
+	if offset != u.Offset {
+		http.Error(w, "Upload-Offset does not match", http.StatusConflict)
+		return
+	}
+	if u, ok = h.write(w, r, u); !ok {
+		return
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	setExpires(w, u)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// write appends the request body to u, applying a deferred Upload-Length
+// first. It writes the error response and returns false on failure.
+func (h *Handler) write(w http.ResponseWriter, r *http.Request, u Upload) (Upload, bool) {
+	ctx := r.Context()
+	if v := r.Header.Get("Upload-Length"); v != "" {
+		size, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || size < u.Offset || (u.Size >= 0 && size != u.Size) {
+			http.Error(w, "invalid Upload-Length", http.StatusBadRequest)
+			return u, false
+		}
+		if h.cfg.MaxSize > 0 && size > h.cfg.MaxSize {
+			http.Error(w, "upload too large", http.StatusRequestEntityTooLarge)
+			return u, false
+		}
+		if u.Size < 0 {
+			u.Size = size
+			if err := h.store.Save(ctx, u); err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return u, false
+			}
+		}
+	}
+
+	limit := int64(-1)
+	switch {
+	case u.Size >= 0:
+		limit = u.Size - u.Offset
+	case h.cfg.MaxSize > 0:
+		limit = h.cfg.MaxSize - u.Offset
+	}
+	var body io.Reader = r.Body
+	if limit >= 0 {
+		body = io.LimitReader(r.Body, limit)
+	}
+	n, err := h.store.Append(ctx, u.ID, u.Offset, body)
+	u.Offset += n
+	if err != nil && n == 0 {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrOffsetMismatch) {
+			status = http.StatusConflict
+		}
+		http.Error(w, http.StatusText(status), status)
+		return u, false
+	}
+	// A body cut short still advanced the offset; report it so the client
+	// resumes from there.
+	h.touch(&u)
+	if err := h.store.Save(context.WithoutCancel(ctx), u); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return u, false
+	}
+	if u.Done() && n > 0 && h.cfg.OnComplete != nil {
+		h.cfg.OnComplete(ctx, u)
+	}
+	return u, true
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request, id string) {
+	if _, ok := h.get(w, r, id); !ok {
+		return
+	}
+	if err := h.store.Delete(r.Context(), id); err != nil && !errors.Is(err, ErrNotFound) {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// get loads an upload, answering 404 for unknown and 410 for expired uploads.
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, id string) (Upload, bool) {
+	u, err := h.store.Get(r.Context(), id)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.NotFound(w, r)
+		return u, false
+	case err != nil:
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return u, false
+	case !u.ExpiresAt.IsZero() && !h.cfg.now().Before(u.ExpiresAt):
+		http.Error(w, "upload expired", http.StatusGone)
+		return u, false
+	}
+	return u, true
+}
+
+// touch extends the expiration of an unfinished upload; finished uploads do
+// not expire.
+func (h *Handler) touch(u *Upload) {
+	if h.cfg.Expiration < 0 || u.Done() {
+		u.ExpiresAt = time.Time{}
+		return
+	}
+	u.ExpiresAt = h.cfg.now().Add(h.cfg.Expiration)
+}
+
+func (h *Handler) lock(id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.busy[id]; ok {
+		return false
+	}
+	h.busy[id] = struct{}{}
+	return true
+}
+
+func (h *Handler) unlock(id string) {
+	h.mu.Lock()
+	delete(h.busy, id)
+	h.mu.Unlock()
+}
+
+func setExpires(w http.ResponseWriter, u Upload) {
+	if !u.ExpiresAt.IsZero() {
+		w.Header().Set("Upload-Expires", u.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+// newID returns a random 128-bit upload ID.
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validID accepts the IDs produced by newID and similar URL- and file-safe
+// identifiers.
+func validID(id string) bool {
+	if len(id) == 0 || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseMetadata decodes an Upload-Metadata header: comma-separated pairs of
+// a key and an optional base64 value.
+func parseMetadata(s string) (map[string]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	md := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		key, val, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" || strings.ContainsAny(key, " ,") {
+			return nil, errors.New("tus: invalid metadata key")
+		}
+		if _, dup := md[key]; dup {
+			return nil, errors.New("tus: duplicate metadata key")
+		}
+		b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(val))
+		if err != nil {
+			return nil, err
+		}
+		md[key] = string(b)
+	}
+	return md, nil
+}
+
+// formatMetadata encodes metadata for the Upload-Metadata header.
+func formatMetadata(md map[string]string) string {
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k
+		if v := md[k]; v != "" {
+			parts[i] += " " + base64.StdEncoding.EncodeToString([]byte(v))
+		}
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,249 @@
+package tus
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type harness struct {
+	t     *testing.T
+	h     *Handler
+	store *DiskStore
+	now   time.Time
+	done  []Upload
+}
+
+func newHarness(t *testing.T, cfg Config) *harness {
+	hs := &harness{t: t, store: NewDiskStore(t.TempDir()), now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cfg.BasePath = "/files"
+	cfg.now = func() time.Time { return hs.now }
+	cfg.OnComplete = func(_ context.Context, u Upload) { hs.done = append(hs.done, u) }
+	hs.h = New(hs.store, cfg)
+	return hs
+}
+
+func (hs *harness) do(method, target, body string, hdr ...string) *httptest.ResponseRecorder {
+	hs.t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Tus-Resumable", Version)
+	for i := 0; i+1 < len(hdr); i += 2 {
+		req.Header.Set(hdr[i], hdr[i+1])
+	}
+	rec := httptest.NewRecorder()
+	hs.h.ServeHTTP(rec, req)
+	return rec
+}
+
+func (hs *harness) create(hdr ...string) string {
+	hs.t.Helper()
+	rec := hs.do(http.MethodPost, "/files", "", hdr...)
+	if rec.Code != http.StatusCreated {
+		hs.t.Fatalf("create: %d %s", rec.Code, rec.Body.String())
+	}
+	loc := rec.Header().Get("Location")
+	if !strings.HasPrefix(loc, "/files/") {
+		hs.t.Fatalf("Location = %q", loc)
+	}
+	return loc
+}
+
+func patch(offset string, extra ...string) []string {
+	return append([]string{"Content-Type", offsetContentType, "Upload-Offset", offset}, extra...)
+}
+
+func TestOptions(t *testing.T) {
+	hs := newHarness(t, Config{MaxSize: 100})
+	req := httptest.NewRequest(http.MethodOptions, "/files", nil)
+	rec := httptest.NewRecorder()
+	hs.h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Tus-Version") != Version ||
+		rec.Header().Get("Tus-Extension") != Extensions || rec.Header().Get("Tus-Max-Size") != "100" {
+		t.Fatalf("options: %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestResumableUpload(t *testing.T) {
+	hs := newHarness(t, Config{})
+	loc := hs.create("Upload-Length", "11", "Upload-Metadata", "filename aGVsbG8udHh0,public")
+
+	rec := hs.do(http.MethodHead, loc, "")
+	if rec.Code != http.StatusOK || rec.Header().Get("Upload-Offset") != "0" || rec.Header().Get("Upload-Length") != "11" {
+		t.Fatalf("head: %d %v", rec.Code, rec.Header())
+	}
+	if got := rec.Header().Get("Upload-Metadata"); got != "filename aGVsbG8udHh0,public" {
+		t.Fatalf("metadata = %q", got)
+	}
+	if rec.Header().Get("Cache-Control") != "no-store" || rec.Header().Get("Upload-Expires") == "" {
+		t.Fatalf("head headers %v", rec.Header())
+	}
+
+	rec = hs.do(http.MethodPatch, loc, "hello", patch("0")...)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Upload-Offset") != "5" {
+		t.Fatalf("patch: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := hs.do(http.MethodPatch, loc, "xx", patch("3")...); rec.Code != http.StatusConflict {
+		t.Fatalf("stale offset should conflict, got %d", rec.Code)
+	}
+	if len(hs.done) != 0 {
+		t.Fatalf("completed too early")
+	}
+	// Extra bytes beyond Upload-Length are not stored.
+	rec = hs.do(http.MethodPatch, loc, " world!!!", patch("5")...)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Upload-Offset") != "11" || rec.Header().Get("Upload-Expires") != "" {
+		t.Fatalf("final patch: %d %v", rec.Code, rec.Header())
+	}
+	if len(hs.done) != 1 || hs.done[0].Metadata["filename"] != "hello.txt" {
+		t.Fatalf("OnComplete = %+v", hs.done)
+	}
+	b, _ := os.ReadFile(hs.store.Path(hs.done[0].ID))
+	if string(b) != "hello world" {
+		t.Fatalf("data = %q", b)
+	}
+}
+
+func TestCreationWithUploadAndDeferredLength(t *testing.T) {
+	hs := newHarness(t, Config{})
+	rec := hs.do(http.MethodPost, "/files", "abc", "Upload-Defer-Length", "1", "Content-Type", offsetContentType)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Upload-Offset") != "3" {
+		t.Fatalf("create with upload: %d %v", rec.Code, rec.Header())
+	}
+	loc := rec.Header().Get("Location")
+	if rec := hs.do(http.MethodHead, loc, ""); rec.Header().Get("Upload-Defer-Length") != "1" {
+		t.Fatalf("deferred length not reported")
+	}
+	rec = hs.do(http.MethodPatch, loc, "def", patch("3", "Upload-Length", "6")...)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Upload-Offset") != "6" || len(hs.done) != 1 {
+		t.Fatalf("final patch: %d, done %d", rec.Code, len(hs.done))
+	}
+	if rec := hs.do(http.MethodHead, loc, ""); rec.Header().Get("Upload-Length") != "6" {
+		t.Fatalf("length not recorded")
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	hs := newHarness(t, Config{MaxSize: 10})
+	loc := hs.create("Upload-Length", "4")
+	cases := []struct {
+		name   string
+		rec    *httptest.ResponseRecorder
+		status int
+	}{
+		{"no length", hs.do(http.MethodPost, "/files", ""), http.StatusBadRequest},
+		{"bad length", hs.do(http.MethodPost, "/files", "", "Upload-Length", "-1"), http.StatusBadRequest},
+		{"too large", hs.do(http.MethodPost, "/files", "", "Upload-Length", "11"), http.StatusRequestEntityTooLarge},
+		{"bad metadata", hs.do(http.MethodPost, "/files", "", "Upload-Length", "1", "Upload-Metadata", "k !!"), http.StatusBadRequest},
+		{"content type", hs.do(http.MethodPatch, loc, "x", "Upload-Offset", "0"), http.StatusUnsupportedMediaType},
+		{"no offset", hs.do(http.MethodPatch, loc, "x", "Content-Type", offsetContentType), http.StatusBadRequest},
+		{"length change", hs.do(http.MethodPatch, loc, "x", patch("0", "Upload-Length", "5")...), http.StatusBadRequest},
+		{"unknown", hs.do(http.MethodHead, "/files/nope", ""), http.StatusNotFound},
+		{"bad id", hs.do(http.MethodHead, "/files/..%2fetc", ""), http.StatusNotFound},
+		{"method", hs.do(http.MethodGet, loc, ""), http.StatusMethodNotAllowed},
+	}
+	for _, tc := range cases {
+		if tc.rec.Code != tc.status {
+			t.Errorf("%s: got %d, want %d", tc.name, tc.rec.Code, tc.status)
+		}
+		if tc.rec.Header().Get("Tus-Resumable") != Version {
+			t.Errorf("%s: missing Tus-Resumable", tc.name)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodHead, loc, nil)
+	rec := httptest.NewRecorder()
+	hs.h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPreconditionFailed || rec.Header().Get("Tus-Version") != Version {
+		t.Fatalf("missing Tus-Resumable: %d", rec.Code)
+	}
+}
+
+func TestBusyUploadIsLocked(t *testing.T) {
+	hs := newHarness(t, Config{})
+	loc := hs.create("Upload-Length", "4")
+	id := strings.TrimPrefix(loc, "/files/")
+	hs.h.lock(id)
+	if rec := hs.do(http.MethodPatch, loc, "x", patch("0")...); rec.Code != http.StatusLocked {
+		t.Fatalf("expected 423, got %d", rec.Code)
+	}
+	hs.h.unlock(id)
+}
+
+func TestTerminationAndExpiration(t *testing.T) {
+	hs := newHarness(t, Config{Expiration: time.Hour})
+	gone := hs.create("Upload-Length", "4")
+	if rec := hs.do(http.MethodDelete, gone, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: %d", rec.Code)
+	}
+	if rec := hs.do(http.MethodHead, gone, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("deleted upload: %d", rec.Code)
+	}
+
+	stale := hs.create("Upload-Length", "4")
+	hs.now = hs.now.Add(30 * time.Minute)
+	fresh := hs.create("Upload-Length", "4")
+	done := hs.create("Upload-Length", "1")
+	hs.do(http.MethodPatch, done, "x", patch("0")...)
+	hs.now = hs.now.Add(45 * time.Minute)
+
+	if rec := hs.do(http.MethodPatch, stale, "x", patch("0")...); rec.Code != http.StatusGone {
+		t.Fatalf("expired upload: %d", rec.Code)
+	}
+	n, err := hs.h.CleanupExpired(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("cleanup removed %d: %v", n, err)
+	}
+	for loc, want := range map[string]int{stale: http.StatusNotFound, fresh: http.StatusOK, done: http.StatusOK} {
+		if rec := hs.do(http.MethodHead, loc, ""); rec.Code != want {
+			t.Fatalf("%s: got %d, want %d", loc, rec.Code, want)
+		}
+	}
+}
+
+// failingReader returns some data and then an error, like a dropped
+// connection.
+type failingReader struct{ data string }
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if f.data == "" {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n := copy(p, f.data)
+	f.data = f.data[n:]
+	return n, nil
+}
+
+func TestInterruptedPatchKeepsReceivedBytes(t *testing.T) {
+	hs := newHarness(t, Config{})
+	loc := hs.create("Upload-Length", "10")
+	req := httptest.NewRequest(http.MethodPatch, loc, &failingReader{data: "abcd"})
+	req.Header.Set("Tus-Resumable", Version)
+	req.Header.Set("Content-Type", offsetContentType)
+	req.Header.Set("Upload-Offset", "0")
+	hs.h.ServeHTTP(httptest.NewRecorder(), req)
+
+	rec := hs.do(http.MethodHead, loc, "")
+	if rec.Header().Get("Upload-Offset") != "4" {
+		t.Fatalf("offset after interruption = %q", rec.Header().Get("Upload-Offset"))
+	}
+	if rec := hs.do(http.MethodPatch, loc, "efghij", patch("4")...); rec.Header().Get("Upload-Offset") != "10" {
+		t.Fatalf("resume failed: %d", rec.Code)
+	}
+}
+
+func TestMetadataRoundTrip(t *testing.T) {
+	md, err := parseMetadata("b dmFsdWU=, a ,c")
+	if err != nil || md["a"] != "" || md["b"] != "value" || len(md) != 3 {
+		t.Fatalf("parse: %v %v", md, err)
+	}
+	if got := formatMetadata(md); got != "a,b dmFsdWU=,c" {
+		t.Fatalf("format = %q", got)
+	}
+	if _, err := parseMetadata("a,a"); err == nil {
+		t.Fatalf("duplicate keys must fail")
+	}
+}